@@ -0,0 +1,124 @@
+package apierrors
+
+import "net/http"
+
+// Code is a stable, machine-readable error code. Clients should branch on the
+// code rather than on the English detail text, which may change.
+type Code string
+
+// Error codes. Never rename or reuse a code once it has shipped.
+const (
+	CodeInvalidRequest        Code = "INVALID_REQUEST"
+	CodeValidationFailed      Code = "VALIDATION_FAILED"
+	CodeInvalidID             Code = "INVALID_ID"
+	CodeUnauthenticated       Code = "UNAUTHENTICATED"
+	CodeCSRFTokenInvalid      Code = "CSRF_TOKEN_INVALID"
+	CodeTodoNotFound          Code = "TODO_NOT_FOUND"
+	CodeTodoBlocked           Code = "TODO_BLOCKED"
+	CodeTodoConflict          Code = "TODO_CONFLICT"
+	CodeChecklistIncomplete   Code = "CHECKLIST_INCOMPLETE"
+	CodeChecklistItemNotFound Code = "CHECKLIST_ITEM_NOT_FOUND"
+	CodeSessionNotFound       Code = "SESSION_NOT_FOUND"
+	CodeWorkspaceNotFound     Code = "WORKSPACE_NOT_FOUND"
+	CodeMemberNotFound        Code = "MEMBER_NOT_FOUND"
+	CodeMemberExists          Code = "MEMBER_EXISTS"
+	CodeShareNotFound         Code = "SHARE_NOT_FOUND"
+	CodePublicLinkNotFound    Code = "PUBLIC_LINK_NOT_FOUND"
+	CodeFeedNotFound          Code = "FEED_NOT_FOUND"
+	CodeImportSourceNotFound  Code = "IMPORT_SOURCE_NOT_FOUND"
+	CodeCustomFieldNotFound   Code = "CUSTOM_FIELD_NOT_FOUND"
+	CodeSavedFilterNotFound   Code = "SAVED_FILTER_NOT_FOUND"
+	CodeUndoNotFound          Code = "UNDO_NOT_FOUND"
+	CodeRevisionNotFound      Code = "REVISION_NOT_FOUND"
+	CodeSyncTokenExpired      Code = "SYNC_TOKEN_EXPIRED"
+	CodeSlackLinkNotFound     Code = "SLACK_LINK_NOT_FOUND"
+	CodeSchemaNotFound        Code = "SCHEMA_NOT_FOUND"
+	CodeTenantNotFound        Code = "TENANT_NOT_FOUND"
+	CodeForbidden             Code = "FORBIDDEN"
+	CodeQuotaExceeded         Code = "QUOTA_EXCEEDED"
+	CodeRouteNotFound         Code = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed      Code = "METHOD_NOT_ALLOWED"
+	CodePayloadTooLarge       Code = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMediaType  Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodePreconditionFailed    Code = "PRECONDITION_FAILED"
+	CodeRateLimited           Code = "RATE_LIMITED"
+	CodeInternal              Code = "INTERNAL_ERROR"
+	CodeStorageUnavailable    Code = "STORAGE_UNAVAILABLE"
+	CodeStorageThrottled      Code = "STORAGE_THROTTLED"
+	CodeTimeout               Code = "TIMEOUT"
+	CodeOverloaded            Code = "OVERLOADED"
+	CodeAuthUnavailable       Code = "AUTH_UNAVAILABLE"
+	CodeImportFailed          Code = "IMPORT_FAILED"
+)
+
+// definition is the registry entry for a code
+type definition struct {
+	status int
+	title  string
+}
+
+// registry maps every code to its HTTP status and fixed title
+var registry = map[Code]definition{
+	CodeInvalidRequest:        {http.StatusBadRequest, "Malformed request"},
+	CodeValidationFailed:      {http.StatusBadRequest, "Validation failed"},
+	CodeInvalidID:             {http.StatusBadRequest, "Invalid ID"},
+	CodeUnauthenticated:       {http.StatusUnauthorized, "Not authenticated"},
+	CodeCSRFTokenInvalid:      {http.StatusForbidden, "CSRF token missing or invalid"},
+	CodeTodoNotFound:          {http.StatusNotFound, "Todo not found"},
+	CodeTodoBlocked:           {http.StatusConflict, "Todo is blocked"},
+	CodeTodoConflict:          {http.StatusConflict, "Todo was changed concurrently"},
+	CodeChecklistIncomplete:   {http.StatusConflict, "Checklist is incomplete"},
+	CodeChecklistItemNotFound: {http.StatusNotFound, "Checklist item not found"},
+	CodeSessionNotFound:       {http.StatusNotFound, "Session not found"},
+	CodeWorkspaceNotFound:     {http.StatusNotFound, "Workspace not found"},
+	CodeMemberNotFound:        {http.StatusNotFound, "Member not found"},
+	CodeMemberExists:          {http.StatusConflict, "Already a member"},
+	CodeShareNotFound:         {http.StatusNotFound, "Share not found"},
+	CodePublicLinkNotFound:    {http.StatusNotFound, "Public link not found"},
+	CodeFeedNotFound:          {http.StatusNotFound, "Feed not found"},
+	CodeImportSourceNotFound:  {http.StatusNotFound, "Import source not found"},
+	CodeCustomFieldNotFound:   {http.StatusNotFound, "Custom field not found"},
+	CodeSavedFilterNotFound:   {http.StatusNotFound, "Saved filter not found"},
+	CodeUndoNotFound:          {http.StatusNotFound, "Undo token not found"},
+	CodeRevisionNotFound:      {http.StatusNotFound, "Revision not found"},
+	CodeSyncTokenExpired:      {http.StatusGone, "Sync token expired"},
+	CodeSlackLinkNotFound:     {http.StatusNotFound, "Slack link not found"},
+	CodeSchemaNotFound:        {http.StatusNotFound, "Schema not found"},
+	CodeTenantNotFound:        {http.StatusNotFound, "Tenant not found"},
+	CodeForbidden:             {http.StatusForbidden, "Forbidden"},
+	CodeQuotaExceeded:         {http.StatusForbidden, "Quota exceeded"},
+	CodeRouteNotFound:         {http.StatusNotFound, "Route not found"},
+	CodeMethodNotAllowed:      {http.StatusMethodNotAllowed, "Method not allowed"},
+	CodePayloadTooLarge:       {http.StatusRequestEntityTooLarge, "Payload too large"},
+	CodeUnsupportedMediaType:  {http.StatusUnsupportedMediaType, "Unsupported media type"},
+	CodePreconditionFailed:    {http.StatusPreconditionFailed, "Precondition failed"},
+	CodeRateLimited:           {http.StatusTooManyRequests, "Too many requests"},
+	CodeInternal:              {http.StatusInternalServerError, "Internal server error"},
+	CodeStorageUnavailable:    {http.StatusServiceUnavailable, "Storage temporarily unavailable"},
+	CodeStorageThrottled:      {http.StatusServiceUnavailable, "Storage throttled"},
+	CodeTimeout:               {http.StatusGatewayTimeout, "Request timed out"},
+	CodeOverloaded:            {http.StatusServiceUnavailable, "Server busy"},
+	CodeAuthUnavailable:       {http.StatusServiceUnavailable, "Authentication temporarily unavailable"},
+	CodeImportFailed:          {http.StatusBadGateway, "Import failed"},
+}
+
+// Status returns the HTTP status for the code, or 500 for unknown codes
+func (code Code) Status() int {
+	if def, ok := registry[code]; ok {
+		return def.status
+	}
+	return http.StatusInternalServerError
+}
+
+// Title returns the fixed summary for the code
+func (code Code) Title() string {
+	if def, ok := registry[code]; ok {
+		return def.title
+	}
+	return http.StatusText(code.Status())
+}
+
+// TypeURI returns the problem type URI for the code
+func (code Code) TypeURI() string {
+	return "urn:todo-api:problem:" + string(code)
+}
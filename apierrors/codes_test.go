@@ -0,0 +1,17 @@
+package apierrors
+
+import (
+	"testing"
+
+	"todo-api/i18n"
+)
+
+func TestTitlesTranslated(t *testing.T) {
+	for code := range registry {
+		for _, lang := range i18n.Supported()[1:] {
+			if i18n.TranslateTitle(lang, string(code), "") == "" {
+				t.Errorf("%s: no translation of the title of %s", lang, code)
+			}
+		}
+	}
+}
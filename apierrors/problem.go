@@ -0,0 +1,96 @@
+package apierrors
+
+import (
+	"todo-api/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object, the body of every error
+// response the API sends
+type Problem struct {
+	// Type is a URI identifying the kind of problem
+	Type string `json:"type"`
+	// Code is the stable machine-readable error code
+	Code Code `json:"code"`
+	// Title is a short summary that doesn't change between occurrences
+	Title string `json:"title"`
+	// Status is the HTTP status code
+	Status int `json:"status"`
+	// Detail explains this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+	// Instance is the path of the request that failed
+	Instance string `json:"instance,omitempty"`
+	// RequestID correlates the response with server logs
+	RequestID string `json:"request_id,omitempty"`
+	// Errors lists the offending fields of a VALIDATION_FAILED problem
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// New creates a problem for the given code with a human readable detail
+func New(code Code, detail string) *Problem {
+	return &Problem{
+		Type:   code.TypeURI(),
+		Code:   code,
+		Title:  code.Title(),
+		Status: code.Status(),
+		Detail: detail,
+	}
+}
+
+// Respond writes a problem response for the given code and detail
+func Respond(c *gin.Context, code Code, detail string) {
+	Write(c, New(code, detail))
+}
+
+// Abort writes a problem response and stops the handler chain. Middleware
+// should use it instead of Respond.
+func Abort(c *gin.Context, code Code, detail string) {
+	Write(c, New(code, detail))
+	c.Abort()
+}
+
+// Write fills in the request specific fields of p and writes it in the
+// language negotiated from the Accept-Language header
+func Write(c *gin.Context, p *Problem) {
+	lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+	c.Header("Content-Type", ContentType)
+	c.Header("Content-Language", lang)
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	c.JSON(p.Status, Localize(c, p))
+}
+
+// Localize fills in the request specific fields of p and returns it in the
+// language negotiated from the Accept-Language header, for problems reported
+// inside a larger response, such as one per item of a batch
+func Localize(c *gin.Context, p *Problem) *Problem {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = c.GetString("request_id")
+	}
+	return localize(p, i18n.Negotiate(c.GetHeader("Accept-Language")))
+}
+
+// localize returns a copy of p with its human readable text translated.
+// The code, type and field names stay as they are so clients can keep
+// branching on them.
+func localize(p *Problem, lang string) *Problem {
+	if lang == i18n.English {
+		return p
+	}
+	out := *p
+	out.Title = i18n.TranslateTitle(lang, string(p.Code), p.Title)
+	out.Detail = i18n.Translate(lang, p.Detail)
+	if p.Errors != nil {
+		out.Errors = make([]FieldError, len(p.Errors))
+		for i, fe := range p.Errors {
+			out.Errors[i] = FieldError{Field: fe.Field, Message: i18n.Translate(lang, fe.Message)}
+		}
+	}
+	return &out
+}
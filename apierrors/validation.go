@@ -0,0 +1,23 @@
+package apierrors
+
+import "github.com/gin-gonic/gin"
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// RespondValidation writes a VALIDATION_FAILED problem listing every
+// offending field
+func RespondValidation(c *gin.Context, errs []FieldError) {
+	Write(c, NewValidation(errs))
+}
+
+// NewValidation creates a VALIDATION_FAILED problem listing every offending
+// field
+func NewValidation(errs []FieldError) *Problem {
+	p := New(CodeValidationFailed, "One or more fields are invalid")
+	p.Errors = errs
+	return p
+}
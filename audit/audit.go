@@ -0,0 +1,148 @@
+// Package audit records the audit trail of requests in the background, so
+// writing it doesn't slow requests down
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"todo-api/logging"
+	"todo-api/models"
+	"todo-api/repository"
+	"todo-api/tenancy"
+)
+
+const (
+	// queueSize is how many entries may wait to be written; more are dropped
+	// while storage can't keep up
+	queueSize = 4096
+	// batchSize is the most entries written at once
+	batchSize = 100
+	// flushInterval is how long an entry may wait for a batch to fill
+	flushInterval = time.Second
+	// writeTimeout bounds writing one batch
+	writeTimeout = 10 * time.Second
+)
+
+// Redacted replaces the values of redacted fields
+const Redacted = logging.Redacted
+
+// Recorder queues entries and writes them in batches
+type Recorder struct {
+	audit   repository.AuditRepository
+	queue   chan *models.AuditEntry
+	dropped atomic.Int64
+}
+
+// NewRecorder creates a Recorder writing to audit. Call Run to start
+// writing.
+func NewRecorder(audit repository.AuditRepository) *Recorder {
+	return &Recorder{audit: audit, queue: make(chan *models.AuditEntry, queueSize)}
+}
+
+// Record queues an entry. When the queue is full the entry is dropped and
+// counted, rather than holding up the request.
+func (r *Recorder) Record(entry *models.AuditEntry) {
+	select {
+	case r.queue <- entry:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// Run writes queued entries until ctx is cancelled
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*models.AuditEntry, 0, batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			r.write(batch)
+			return
+		case entry := <-r.queue:
+			batch = append(batch, entry)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		r.write(batch)
+		batch = batch[:0]
+		if n := r.dropped.Swap(0); n > 0 {
+			log.Printf("Dropped %d audit entries because storage couldn't keep up", n)
+		}
+	}
+}
+
+// write stores a batch, each entry with its tenant's trail. Failed writes
+// are logged and dropped, as retrying them would only back up the queue
+// further.
+func (r *Recorder) write(batch []*models.AuditEntry) {
+	byTenant := make(map[string][]*models.AuditEntry)
+	for _, entry := range batch {
+		byTenant[entry.Tenant] = append(byTenant[entry.Tenant], entry)
+	}
+	for tenant, entries := range byTenant {
+		ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+		if tenant != "" {
+			ctx = tenancy.WithTenant(ctx, tenant)
+		}
+		if err := r.audit.Add(ctx, entries...); err != nil {
+			log.Printf("Failed to write %d audit entries: %v", len(entries), err)
+		}
+		cancel()
+	}
+}
+
+// RedactJSON returns body with the values of the named fields, at any
+// depth and in any case, replaced by Redacted. ok is false when body isn't
+// JSON.
+func RedactJSON(body []byte, fields []string) (redacted string, ok bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil || decoder.More() {
+		return "", false
+	}
+	redact(doc, fields)
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return "", false
+	}
+	return string(out), true
+}
+
+// redact replaces the values of the named fields in a decoded document
+func redact(doc any, fields []string) {
+	switch v := doc.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if isRedacted(key, fields) {
+				v[key] = Redacted
+			} else {
+				redact(value, fields)
+			}
+		}
+	case []any:
+		for _, item := range v {
+			redact(item, fields)
+		}
+	}
+}
+
+// isRedacted reports whether key is one of fields
+func isRedacted(key string, fields []string) bool {
+	for _, field := range fields {
+		if strings.EqualFold(key, field) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"sync/atomic"
+)
+
+// CookieSigner signs cookie values with HMAC-SHA256 so clients can't forge
+// another user's identity. Values are signed with the first key and verified
+// against all of them, which allows rotating keys without logging everyone out.
+type CookieSigner struct {
+	keys atomic.Pointer[[][]byte]
+}
+
+// NewCookieSigner creates a signer that signs with current and also accepts
+// values signed with any of the previous keys
+func NewCookieSigner(current string, previous ...string) *CookieSigner {
+	s := &CookieSigner{}
+	s.SetKeys(current, previous...)
+	return s
+}
+
+// SetKeys replaces the keys, e.g. after they were rotated in Key Vault. It is
+// safe to call while requests are being signed and verified.
+func (s *CookieSigner) SetKeys(current string, previous ...string) {
+	keys := [][]byte{[]byte(current)}
+	for _, key := range previous {
+		keys = append(keys, []byte(key))
+	}
+	s.keys.Store(&keys)
+}
+
+// Sign returns value with its signature appended as "value.signature"
+func (s *CookieSigner) Sign(value string) string {
+	return value + "." + s.mac((*s.keys.Load())[0], value)
+}
+
+// Verify checks a signed value and returns the original value. current is
+// false when it was signed with a previous key and should be re-issued.
+func (s *CookieSigner) Verify(signed string) (value string, current bool, ok bool) {
+	i := strings.LastIndexByte(signed, '.')
+	if i < 0 {
+		return "", false, false
+	}
+	value, sig := signed[:i], signed[i+1:]
+
+	for n, key := range *s.keys.Load() {
+		if hmac.Equal([]byte(sig), []byte(s.mac(key, value))) {
+			return value, n == 0, true
+		}
+	}
+	return "", false, false
+}
+
+func (s *CookieSigner) mac(key []byte, value string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testKey     = "0123456789abcdef0123456789abcdef"
+	previousKey = "fedcba9876543210fedcba9876543210"
+)
+
+func TestCookieSignerVerify(t *testing.T) {
+	signer := NewCookieSigner(testKey, previousKey)
+	signed := signer.Sign("user-1")
+	value, sig, _ := strings.Cut(signed, ".")
+
+	tests := []struct {
+		name        string
+		signed      string
+		wantValue   string
+		wantCurrent bool
+		wantOK      bool
+	}{
+		{"signed with the current key", signed, "user-1", true, true},
+		{"signed with a previous key", NewCookieSigner(previousKey).Sign("user-1"), "user-1", false, true},
+		{"signed with an unknown key", NewCookieSigner(strings.Repeat("x", 32)).Sign("user-1"), "", false, false},
+		{"value swapped for another user's", "user-2." + sig, "", false, false},
+		{"signature tampered with", value + "." + strings.ToUpper(sig), "", false, false},
+		{"signature missing", value, "", false, false},
+		{"signature empty", value + ".", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, current, ok := signer.Verify(tt.signed)
+			if value != tt.wantValue || current != tt.wantCurrent || ok != tt.wantOK {
+				t.Errorf("Verify(%q) = %q, %v, %v; want %q, %v, %v",
+					tt.signed, value, current, ok, tt.wantValue, tt.wantCurrent, tt.wantOK)
+			}
+		})
+	}
+}
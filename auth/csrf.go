@@ -0,0 +1,27 @@
+package auth
+
+import "crypto/hmac"
+
+// csrfPrefix separates CSRF token MACs from cookie signatures made with the
+// same keys
+const csrfPrefix = "csrf:"
+
+// CSRFToken returns the CSRF token for the user. Tokens are stateless: they
+// are a MAC of the user ID, so they only work alongside that user's cookie
+// and a cross-site attacker can't compute them.
+func (s *CookieSigner) CSRFToken(userID string) string {
+	return s.mac((*s.keys.Load())[0], csrfPrefix+userID)
+}
+
+// VerifyCSRFToken checks a token against the current and previous keys
+func (s *CookieSigner) VerifyCSRFToken(userID, token string) bool {
+	if token == "" {
+		return false
+	}
+	for _, key := range *s.keys.Load() {
+		if hmac.Equal([]byte(token), []byte(s.mac(key, csrfPrefix+userID))) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package auth
+
+import "testing"
+
+func TestVerifyCSRFToken(t *testing.T) {
+	signer := NewCookieSigner(testKey, previousKey)
+	token := signer.CSRFToken("user-1")
+
+	tests := []struct {
+		name   string
+		userID string
+		token  string
+		want   bool
+	}{
+		{"the user's token", "user-1", token, true},
+		{"made with a previous key", "user-1", NewCookieSigner(previousKey).CSRFToken("user-1"), true},
+		{"missing", "user-1", "", false},
+		{"another user's token", "user-2", token, false},
+		{"made with an unknown key", "user-1", NewCookieSigner("an attacker's key of 32 chars!!!").CSRFToken("user-1"), false},
+		// A cookie signature is a MAC under the same keys, but not of the
+		// same message
+		{"the cookie's signature", "user-1", signer.Sign("user-1")[len("user-1."):], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := signer.VerifyCSRFToken(tt.userID, tt.token); got != tt.want {
+				t.Errorf("VerifyCSRFToken(%q, %q) = %v, want %v", tt.userID, tt.token, got, tt.want)
+			}
+		})
+	}
+}
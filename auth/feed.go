@@ -0,0 +1,34 @@
+package auth
+
+import "strings"
+
+// feedTokenPrefix separates feed tokens from cookies and app passwords
+// signed with the same keys
+const feedTokenPrefix = "feed:"
+
+// FeedToken returns the token in the URL of an activity feed. Like app
+// passwords, tokens are signed session IDs, so revoking the session revokes
+// the feed. workspace is the hex ID of the workspace the feed follows, or
+// empty for the user's personal todos.
+func (s *CookieSigner) FeedToken(sessionID, workspace string) string {
+	value := feedTokenPrefix + sessionID
+	if workspace != "" {
+		value += ":" + workspace
+	}
+	return s.Sign(value)
+}
+
+// VerifyFeedToken returns the session and workspace a feed token was made
+// for
+func (s *CookieSigner) VerifyFeedToken(token string) (sessionID, workspace string, ok bool) {
+	value, _, ok := s.Verify(token)
+	if !ok {
+		return "", "", false
+	}
+	value, ok = strings.CutPrefix(value, feedTokenPrefix)
+	if !ok {
+		return "", "", false
+	}
+	sessionID, workspace, _ = strings.Cut(value, ":")
+	return sessionID, workspace, sessionID != ""
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksMaxAge is how long fetched keys are used before being refreshed
+	jwksMaxAge = 24 * time.Hour
+	// jwksMinRefresh stops tokens with unknown key IDs from making us
+	// refetch the key set on every request
+	jwksMinRefresh = 5 * time.Minute
+)
+
+// ErrKeysUnavailable is returned when the signing keys couldn't be fetched
+var ErrKeysUnavailable = errors.New("signing keys unavailable")
+
+// JWKS fetches and caches the RSA signing keys published at a JSON Web Key
+// Set URL. Keys are refetched daily, and early when a token names a key we
+// haven't seen, since identity providers rotate keys without notice.
+type JWKS struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewJWKS creates a key cache for the key set at url
+func NewJWKS(url string) *JWKS {
+	return &JWKS{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Key returns the public key with the given key ID
+func (j *JWKS) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key, ok := j.keys[kid]
+	age := time.Since(j.fetchedAt)
+	switch {
+	case ok && age < jwksMaxAge:
+		return key, nil
+	case !ok && j.keys != nil && age < jwksMinRefresh:
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+	}
+
+	if err := j.refresh(ctx); err != nil {
+		if ok {
+			// Keep using a stale key rather than rejecting every request
+			// while the identity provider is unreachable
+			return key, nil
+		}
+		return nil, fmt.Errorf("%w: %w", ErrKeysUnavailable, err)
+	}
+	if key, ok = j.keys[kid]; !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+func (j *JWKS) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch %s: %s", j.url, resp.Status)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decode %s: %w", j.url, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	return nil
+}
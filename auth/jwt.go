@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// clockSkew is how far token times may be off from our clock
+const clockSkew = time.Minute
+
+// ErrInvalidToken is returned for tokens that are malformed, forged, expired
+// or meant for someone else
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the token claims the API relies on
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	// ObjectID is the user's immutable ID in Entra ID
+	ObjectID string `json:"oid"`
+	TenantID string `json:"tid"`
+	Name     string `json:"name"`
+	// Email is only present when the app registration requests the
+	// optional claim; UPN is the sign-in name in v1.0 tokens
+	Email             string `json:"email"`
+	UPN               string `json:"upn"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// EmailAddress returns the user's email address from whichever claim carries
+// it, lowercased, or "" when the token has none
+func (c *Claims) EmailAddress() string {
+	for _, v := range []string{c.Email, c.UPN, c.PreferredUsername} {
+		if strings.Contains(v, "@") {
+			return strings.ToLower(v)
+		}
+	}
+	return ""
+}
+
+// audience accepts both forms of the aud claim: a string or an array
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// TokenValidator validates RS256 JWTs signed by keys from a JWKS and checks
+// their issuer, audience and lifetime
+type TokenValidator struct {
+	keys      *JWKS
+	issuers   []string
+	audiences []string
+}
+
+// NewTokenValidator creates a validator that accepts tokens from any of
+// issuers for any of audiences
+func NewTokenValidator(keys *JWKS, issuers, audiences []string) *TokenValidator {
+	return &TokenValidator{keys: keys, issuers: issuers, audiences: audiences}
+}
+
+// Validate verifies the token and returns its claims. Errors wrap
+// ErrInvalidToken, or ErrKeysUnavailable when the token couldn't be checked.
+func (v *TokenValidator) Validate(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	// Only accept the algorithm we expect so a token can't pick a weaker one
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+	}
+
+	key, err := v.keys.Key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, fmt.Errorf("%w: not yet valid", ErrInvalidToken)
+	}
+	if !slices.Contains(v.issuers, claims.Issuer) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if !slices.ContainsFunc(claims.Audience, func(aud string) bool { return slices.Contains(v.audiences, aud) }) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+	return &claims, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package auth
+
+import "strings"
+
+// appPasswordPrefix separates app passwords from identity cookies signed
+// with the same keys, so neither works as the other
+const appPasswordPrefix = "app:"
+
+// AppPassword returns the password that signs an app, such as a CalDAV
+// client, in to a session. Like cookies, passwords are signed session IDs,
+// so revoking the session revokes the password.
+func (s *CookieSigner) AppPassword(sessionID string) string {
+	return s.Sign(appPasswordPrefix + sessionID)
+}
+
+// VerifyAppPassword returns the session an app password signs in to.
+// Passwords can't be reissued the way cookies are, so they stop working
+// once the key they were signed with is retired.
+func (s *CookieSigner) VerifyAppPassword(password string) (sessionID string, ok bool) {
+	value, _, ok := s.Verify(password)
+	if !ok {
+		return "", false
+	}
+	sessionID, ok = strings.CutPrefix(value, appPasswordPrefix)
+	return sessionID, ok && sessionID != ""
+}
@@ -0,0 +1,121 @@
+package authz
+
+import "errors"
+
+// Role is a level of access to a workspace or todo. Roles are ordered; each
+// can do everything the roles below it can.
+type Role string
+
+const (
+	// RoleViewer can read
+	RoleViewer Role = "viewer"
+	// RoleEditor can also create, change and delete todos
+	RoleEditor Role = "editor"
+	// RoleAdmin can also manage the members below them
+	RoleAdmin Role = "admin"
+	// RoleOwner can do everything, including deleting the workspace and
+	// sharing todos
+	RoleOwner Role = "owner"
+)
+
+// Action is something a user attempts on a resource
+type Action string
+
+const (
+	// ActionRead views a workspace or todo
+	ActionRead Action = "read"
+	// ActionWrite creates, updates or deletes todos
+	ActionWrite Action = "write"
+	// ActionManageMembers adds, removes or changes the roles of workspace members
+	ActionManageMembers Action = "manage_members"
+	// ActionManageWorkspace renames or restyles the workspace
+	ActionManageWorkspace Action = "manage_workspace"
+	// ActionShare shares a todo with other users or through public links
+	ActionShare Action = "share"
+	// ActionDelete deletes the workspace itself
+	ActionDelete Action = "delete"
+)
+
+var (
+	// ErrNotFound means the user has no role on the resource; respond as if
+	// it didn't exist
+	ErrNotFound = errors.New("authz: no access to resource")
+	// ErrForbidden means the user can see the resource but their role
+	// doesn't allow the action
+	ErrForbidden = errors.New("authz: role does not allow action")
+)
+
+// rank orders the roles; unknown roles rank zero and can do nothing
+var rank = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+// minimum is the lowest role allowed to perform each action
+var minimum = map[Action]Role{
+	ActionRead:            RoleViewer,
+	ActionWrite:           RoleEditor,
+	ActionManageMembers:   RoleAdmin,
+	ActionManageWorkspace: RoleAdmin,
+	ActionShare:           RoleOwner,
+	ActionDelete:          RoleOwner,
+}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	return rank[r] > 0
+}
+
+// Allows reports whether the role may perform the action
+func (r Role) Allows(action Action) bool {
+	need, ok := minimum[action]
+	return ok && rank[r] > 0 && rank[r] >= rank[need]
+}
+
+// Outranks reports whether r is strictly higher than other. Members can only
+// manage members they outrank, and only grant roles below their own.
+func (r Role) Outranks(other Role) bool {
+	return rank[r] > rank[other]
+}
+
+// Authorize decides whether a user holding role may perform the action.
+// Handlers resolve the caller's role on a resource and call this before
+// acting, so denials follow one rule everywhere: with no role the resource
+// is reported as missing (ErrNotFound, a 404) so IDs can't be probed; with a
+// role too low for the action the user is told so (ErrForbidden, a 403).
+func Authorize(role Role, action Action) error {
+	if !role.Valid() {
+		return ErrNotFound
+	}
+	if !role.Allows(action) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// CanRead decides whether a user holding role may see a workspace or todo
+// and what's on it. It's the check every read goes through.
+func CanRead(role Role) error {
+	return Authorize(role, ActionRead)
+}
+
+// CanWrite decides whether a user holding role may create, change or
+// delete todos. It's the check every write goes through.
+func CanWrite(role Role) error {
+	return Authorize(role, ActionWrite)
+}
+
+// CanModerate decides whether a user holding role may remove something
+// added to a todo, such as a comment: their own if they can read the todo,
+// and anyone's if they can manage members
+func CanModerate(role Role, own bool) error {
+	if err := CanRead(role); err != nil {
+		return err
+	}
+	if !own && !role.Allows(ActionManageMembers) {
+		return ErrForbidden
+	}
+	return nil
+}
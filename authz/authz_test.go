@@ -0,0 +1,99 @@
+package authz
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		role   Role
+		action Action
+		want   error
+	}{
+		// No role: the resource doesn't exist as far as the user knows
+		{"", ActionRead, ErrNotFound},
+		{"stranger", ActionRead, ErrNotFound},
+		{"", ActionWrite, ErrNotFound},
+
+		{RoleViewer, ActionRead, nil},
+		{RoleViewer, ActionWrite, ErrForbidden},
+		{RoleViewer, ActionShare, ErrForbidden},
+
+		{RoleEditor, ActionRead, nil},
+		{RoleEditor, ActionWrite, nil},
+		{RoleEditor, ActionManageMembers, ErrForbidden},
+
+		{RoleAdmin, ActionWrite, nil},
+		{RoleAdmin, ActionManageMembers, nil},
+		{RoleAdmin, ActionManageWorkspace, nil},
+		{RoleAdmin, ActionShare, ErrForbidden},
+		{RoleAdmin, ActionDelete, ErrForbidden},
+
+		{RoleOwner, ActionShare, nil},
+		{RoleOwner, ActionDelete, nil},
+		// Unknown actions are allowed to nobody
+		{RoleOwner, "launch", ErrForbidden},
+	}
+	for _, tt := range tests {
+		if got := Authorize(tt.role, tt.action); !errors.Is(got, tt.want) || (tt.want == nil && got != nil) {
+			t.Errorf("Authorize(%q, %q) = %v, want %v", tt.role, tt.action, got, tt.want)
+		}
+	}
+}
+
+func TestCanReadAndCanWrite(t *testing.T) {
+	tests := []struct {
+		role      Role
+		wantRead  error
+		wantWrite error
+	}{
+		{"", ErrNotFound, ErrNotFound},
+		{RoleViewer, nil, ErrForbidden},
+		{RoleEditor, nil, nil},
+		{RoleAdmin, nil, nil},
+		{RoleOwner, nil, nil},
+	}
+	for _, tt := range tests {
+		if got := CanRead(tt.role); got != tt.wantRead {
+			t.Errorf("CanRead(%q) = %v, want %v", tt.role, got, tt.wantRead)
+		}
+		if got := CanWrite(tt.role); got != tt.wantWrite {
+			t.Errorf("CanWrite(%q) = %v, want %v", tt.role, got, tt.wantWrite)
+		}
+	}
+}
+
+func TestCanModerate(t *testing.T) {
+	tests := []struct {
+		role Role
+		own  bool
+		want error
+	}{
+		{"", true, ErrNotFound},
+		{RoleViewer, true, nil},
+		{RoleViewer, false, ErrForbidden},
+		{RoleEditor, false, ErrForbidden},
+		{RoleAdmin, false, nil},
+		{RoleOwner, false, nil},
+	}
+	for _, tt := range tests {
+		if got := CanModerate(tt.role, tt.own); got != tt.want {
+			t.Errorf("CanModerate(%q, own %v) = %v, want %v", tt.role, tt.own, got, tt.want)
+		}
+	}
+}
+
+func TestOutranks(t *testing.T) {
+	order := []Role{RoleViewer, RoleEditor, RoleAdmin, RoleOwner}
+	for i, lower := range order {
+		for _, higher := range order[i+1:] {
+			if !higher.Outranks(lower) || lower.Outranks(higher) {
+				t.Errorf("%s should outrank %s and not the other way round", higher, lower)
+			}
+		}
+		if lower.Outranks(lower) {
+			t.Errorf("%s outranks itself", lower)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// managementResource is the token audience for Azure Resource Manager
+	managementResource = "https://management.azure.com/"
+	cosmosAPIVersion   = "2024-05-15"
+)
+
+// CosmosAccount identifies a Cosmos DB account in Azure Resource Manager
+type CosmosAccount struct {
+	SubscriptionID string
+	ResourceGroup  string
+	Name           string
+}
+
+// CosmosConnectionString fetches the account's primary MongoDB connection
+// string through Azure Resource Manager, authenticating as the managed
+// identity. The identity needs a role that grants
+// Microsoft.DocumentDB/databaseAccounts/listConnectionStrings/action, such as
+// "Cosmos DB Account Reader Role".
+func CosmosConnectionString(ctx context.Context, identity *ManagedIdentity, account CosmosAccount) (string, error) {
+	accessToken, err := identity.Token(ctx, managementResource)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := fmt.Sprintf(
+		"https://management.azure.com/subscriptions/%s/resourceGroups/%s/providers/Microsoft.DocumentDB/databaseAccounts/%s/listConnectionStrings?api-version=%s",
+		url.PathEscape(account.SubscriptionID), url.PathEscape(account.ResourceGroup), url.PathEscape(account.Name), cosmosAPIVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := identity.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("list connection strings for %s: %s", account.Name, resp.Status)
+	}
+
+	var body struct {
+		ConnectionStrings []struct {
+			ConnectionString string `json:"connectionString"`
+			Description      string `json:"description"`
+		} `json:"connectionStrings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+
+	var fallback string
+	for _, cs := range body.ConnectionStrings {
+		// Read-only keys can't write todos
+		if !strings.HasPrefix(cs.ConnectionString, "mongodb") || strings.Contains(cs.Description, "Read-Only") {
+			continue
+		}
+		if cs.Description == "Primary MongoDB Connection String" {
+			return cs.ConnectionString, nil
+		}
+		if fallback == "" {
+			fallback = cs.ConnectionString
+		}
+	}
+	if fallback == "" {
+		return "", errors.New("account has no MongoDB connection string")
+	}
+	return fallback, nil
+}
@@ -0,0 +1,117 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// imdsEndpoint is the Instance Metadata Service token endpoint available
+	// on VMs, AKS and Container Apps
+	imdsEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+	// tokenRefreshMargin is how long before expiry a cached token is renewed
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+// ManagedIdentity gets access tokens for the Azure managed identity the
+// process runs as, so no credentials have to be stored in configuration.
+// Tokens are cached per resource until shortly before they expire.
+type ManagedIdentity struct {
+	clientID string
+	client   *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]token
+}
+
+type token struct {
+	value     string
+	expiresAt time.Time
+}
+
+// NewManagedIdentity creates a credential for the system-assigned identity,
+// or for the user-assigned identity with the given client ID when set
+func NewManagedIdentity(clientID string) *ManagedIdentity {
+	return &ManagedIdentity{
+		clientID: clientID,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		tokens:   make(map[string]token),
+	}
+}
+
+// Token returns an access token for resource, e.g.
+// https://management.azure.com/
+func (m *ManagedIdentity) Token(ctx context.Context, resource string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if t, ok := m.tokens[resource]; ok && time.Until(t.expiresAt) > tokenRefreshMargin {
+		return t.value, nil
+	}
+
+	t, err := m.fetch(ctx, resource)
+	if err != nil {
+		return "", fmt.Errorf("managed identity token for %s: %w", resource, err)
+	}
+	m.tokens[resource] = t
+	return t.value, nil
+}
+
+// fetch requests a token from App Service's identity endpoint when the
+// platform provides one, and from IMDS otherwise
+func (m *ManagedIdentity) fetch(ctx context.Context, resource string) (token, error) {
+	query := url.Values{"resource": {resource}}
+	if m.clientID != "" {
+		query.Set("client_id", m.clientID)
+	}
+
+	endpoint, secret := os.Getenv("IDENTITY_ENDPOINT"), os.Getenv("IDENTITY_HEADER")
+	var req *http.Request
+	var err error
+	if endpoint != "" && secret != "" {
+		query.Set("api-version", "2019-08-01")
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+		if err != nil {
+			return token{}, err
+		}
+		req.Header.Set("X-IDENTITY-HEADER", secret)
+	} else {
+		query.Set("api-version", "2018-02-01")
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, imdsEndpoint+"?"+query.Encode(), nil)
+		if err != nil {
+			return token{}, err
+		}
+		req.Header.Set("Metadata", "true")
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return token{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return token{}, fmt.Errorf("identity endpoint returned %s", resp.Status)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		// expires_on is a Unix time, sent as a string by some endpoints
+		ExpiresOn json.RawMessage `json:"expires_on"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return token{}, err
+	}
+	expiresOn, err := strconv.ParseInt(strings.Trim(string(body.ExpiresOn), `"`), 10, 64)
+	if err != nil || body.AccessToken == "" {
+		return token{}, fmt.Errorf("unexpected identity endpoint response")
+	}
+	return token{value: body.AccessToken, expiresAt: time.Unix(expiresOn, 0)}, nil
+}
@@ -0,0 +1,62 @@
+// Package buildinfo describes the build that's running. The commit and build
+// time are set at build time with
+//
+//	go build -ldflags "-X todo-api/buildinfo.Commit=$(git rev-parse HEAD) -X todo-api/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without them, the commit comes from the version control details the Go
+// toolchain stamps into binaries built inside a git checkout.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set with -ldflags -X
+var (
+	// Commit is the git SHA the binary was built from
+	Commit string
+	// BuildTime is when the binary was built, in RFC 3339
+	BuildTime string
+)
+
+// Info describes a build
+type Info struct {
+	// Version is the short commit, or "dev" when it isn't known
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+	// Modified is set when the checkout had uncommitted changes
+	Modified bool `json:"modified,omitempty"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the running build's details
+func Get() Info {
+	once.Do(func() {
+		info = Info{Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, s := range bi.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					if info.Commit == "" {
+						info.Commit = s.Value
+					}
+				case "vcs.modified":
+					info.Modified = s.Value == "true" && Commit == ""
+				}
+			}
+		}
+		info.Version = "dev"
+		if info.Commit != "" {
+			info.Version = info.Commit[:min(len(info.Commit), 12)]
+		}
+	})
+	return info
+}
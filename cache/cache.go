@@ -0,0 +1,23 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned by Get when the key isn't cached
+var ErrMiss = errors.New("cache miss")
+
+// Cache is a byte-oriented key/value cache with expiry
+type Cache interface {
+	// Get returns the cached value or ErrMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores a value that expires after ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys; missing keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+	// Incr adds one to the counter at key, which starts at zero and expires
+	// after ttl, and returns the new count
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"todo-api/fieldcrypt"
+)
+
+// encryptedCache encrypts values before they reach another cache
+type encryptedCache struct {
+	cache  Cache
+	cipher *fieldcrypt.Cipher
+}
+
+// Encrypted wraps c so values are encrypted with cipher, each bound to its
+// key, for caches holding the text of todos. Values cached before
+// encryption was turned on are read as they are; counters are left alone.
+func Encrypted(c Cache, cipher *fieldcrypt.Cipher) Cache {
+	return encryptedCache{cache: c, cipher: cipher}
+}
+
+func (e encryptedCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := e.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := e.cipher.Decrypt(string(value), "cache/"+key)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(plain), nil
+}
+
+func (e encryptedCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	sealed, err := e.cipher.Encrypt(string(value), "cache/"+key)
+	if err != nil {
+		return err
+	}
+	return e.cache.Set(ctx, key, []byte(sealed), ttl)
+}
+
+func (e encryptedCache) Delete(ctx context.Context, keys ...string) error {
+	return e.cache.Delete(ctx, keys...)
+}
+
+func (e encryptedCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return e.cache.Incr(ctx, key, ttl)
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryCache keeps values in the process. Entries are only visible to the
+// instance that set them, so it suits single-instance deployments.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value   []byte
+	count   int64
+	expires time.Time
+}
+
+// NewMemoryCache creates an empty in-process cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryEntry)}
+}
+
+// Get returns the cached value or ErrMiss
+func (m *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok || !time.Now().Before(entry.expires) {
+		delete(m.entries, key)
+		return nil, ErrMiss
+	}
+	return entry.value, nil
+}
+
+// Set stores a value that expires after ttl. Expired entries are dropped
+// along the way.
+func (m *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.dropExpired(now)
+	m.entries[key] = memoryEntry{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+// Incr adds one to the counter at key, which starts at zero and expires
+// after ttl, and returns the new count
+func (m *MemoryCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := m.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		m.dropExpired(now)
+		entry = memoryEntry{expires: now.Add(ttl)}
+	}
+	entry.count++
+	m.entries[key] = entry
+	return entry.count, nil
+}
+
+// dropExpired removes expired entries so the cache doesn't grow without
+// bound; the caller holds the lock
+func (m *MemoryCache) dropExpired(now time.Time) {
+	for k, entry := range m.entries {
+		if !now.Before(entry.expires) {
+			delete(m.entries, k)
+		}
+	}
+}
+
+// Delete removes keys; missing keys are ignored
+func (m *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
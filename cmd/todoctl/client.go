@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"todo-api/apierrors"
+)
+
+// client calls the admin API
+type client struct {
+	baseURL string
+	token   string
+	// tenant is sent in tenantHeader, for deployments that name the
+	// tenant in a header
+	tenant       string
+	tenantHeader string
+	http         *http.Client
+}
+
+// do sends a request and decodes the response into out, unless out is nil.
+// Responses wrapped in the standard envelope are unwrapped first, and error
+// responses are returned as errors.
+func (c *client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.baseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if c.tenant != "" {
+		req.Header.Set(c.tenantHeader, c.tenant)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	data, problem := unwrap(data)
+	if resp.StatusCode >= http.StatusBadRequest {
+		if problem == nil {
+			problem = &apierrors.Problem{Status: resp.StatusCode, Title: http.StatusText(resp.StatusCode)}
+			json.Unmarshal(data, problem)
+		}
+		if problem.Detail != "" {
+			return fmt.Errorf("%s %s: %s: %s", method, path, problem.Title, problem.Detail)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, problem.Title)
+	}
+	if out == nil {
+		return nil
+	}
+	if raw, ok := out.(*json.RawMessage); ok {
+		*raw = data
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// unwrap takes the data, or the problem, out of a response in the standard
+// envelope, which the server sends when RESPONSE_ENVELOPE is on. Other
+// responses are returned as they are.
+func unwrap(data []byte) ([]byte, *apierrors.Problem) {
+	var env map[string]json.RawMessage
+	if json.Unmarshal(data, &env) != nil || len(env) != 3 {
+		return data, nil
+	}
+	payload, hasData := env["data"]
+	_, hasMeta := env["meta"]
+	problem, hasError := env["error"]
+	if !hasData || !hasMeta || !hasError {
+		return data, nil
+	}
+	var p *apierrors.Problem
+	json.Unmarshal(problem, &p)
+	return payload, p
+}
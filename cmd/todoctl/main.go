@@ -0,0 +1,545 @@
+// Command todoctl runs operator tasks against a deployment through its
+// admin API: listing and purging users, exporting and importing their data,
+// creating storage indexes, purging inactive users, previewing the retention
+// rules and reading the audit trail. It reads the API's
+// address from TODOCTL_URL and the admin token from ADMIN_TOKEN, or from
+// the --url and --token flags. On deployments hosting several tenants, the
+// tenant is named with --tenant, or in the host of --url.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"todo-api/models"
+
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// newRootCommand builds the command tree
+func newRootCommand() *cobra.Command {
+	c := &client{http: &http.Client{}}
+	var timeout time.Duration
+
+	root := &cobra.Command{
+		Use:          "todoctl",
+		Short:        "Operate a Todo API deployment through its admin API",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if c.token == "" {
+				return errors.New("an admin token is required: set ADMIN_TOKEN or pass --token")
+			}
+			c.http.Timeout = timeout
+			return nil
+		},
+	}
+	root.PersistentFlags().StringVar(&c.baseURL, "url", envOr("TODOCTL_URL", "http://localhost:8080"), "base URL of the API (env TODOCTL_URL)")
+	root.PersistentFlags().StringVar(&c.token, "token", os.Getenv("ADMIN_TOKEN"), "admin API token (env ADMIN_TOKEN)")
+	root.PersistentFlags().StringVar(&c.tenant, "tenant", os.Getenv("TODOCTL_TENANT"), "tenant to operate on, when the API names tenants in a header (env TODOCTL_TENANT)")
+	root.PersistentFlags().StringVar(&c.tenantHeader, "tenant-header", envOr("TODOCTL_TENANT_HEADER", "X-Tenant-ID"), "header the API reads the tenant from (env TODOCTL_TENANT_HEADER)")
+	root.PersistentFlags().DurationVar(&timeout, "timeout", time.Minute, "time limit for each request")
+
+	root.AddCommand(
+		newStatsCommand(c),
+		newUsersCommand(c),
+		newIndexesCommand(c),
+		newRetentionCommand(c),
+		newAuditCommand(c),
+	)
+	return root
+}
+
+func newStatsCommand(c *client) *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Show storage totals and the state of the server",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var stats json.RawMessage
+			if err := c.do(cmd.Context(), http.MethodGet, "/admin/v1/stats", nil, &stats); err != nil {
+				return err
+			}
+			return printJSON(cmd.OutOrStdout(), stats)
+		},
+	}
+}
+
+func newUsersCommand(c *client) *cobra.Command {
+	users := &cobra.Command{
+		Use:   "users",
+		Short: "List, purge, export and import users",
+	}
+	users.AddCommand(
+		newUsersListCommand(c),
+		newUsersPurgeCommand(c),
+		newUsersExportCommand(c),
+		newUsersImportCommand(c),
+	)
+	return users
+}
+
+// adminUser is a user as the admin API lists them
+type adminUser struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	TodoCount int64     `json:"todo_count"`
+}
+
+func newUsersListCommand(c *client) *cobra.Command {
+	var (
+		limit  int
+		after  string
+		all    bool
+		asJSON bool
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List users ordered by ID with their number of personal todos",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var listed []adminUser
+			for {
+				query := url.Values{"limit": {strconv.Itoa(limit)}}
+				if after != "" {
+					query.Set("after", after)
+				}
+				var page struct {
+					Users     []adminUser `json:"users"`
+					NextAfter string      `json:"next_after"`
+				}
+				if err := c.do(cmd.Context(), http.MethodGet, "/admin/v1/users?"+query.Encode(), nil, &page); err != nil {
+					return err
+				}
+				listed = append(listed, page.Users...)
+				after = page.NextAfter
+				if !all || after == "" {
+					break
+				}
+			}
+
+			if asJSON {
+				data, err := json.Marshal(listed)
+				if err != nil {
+					return err
+				}
+				return printJSON(cmd.OutOrStdout(), data)
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tCREATED\tLAST SEEN\tTODOS")
+			for _, u := range listed {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", u.ID, u.CreatedAt.Format(time.RFC3339), u.LastSeen.Format(time.RFC3339), u.TodoCount)
+			}
+			if !all && after != "" {
+				fmt.Fprintf(w, "\nMore users follow; pass --after %s or --all\n", after)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 50, "users per page (1 to 200)")
+	cmd.Flags().StringVar(&after, "after", "", "list users after this ID")
+	cmd.Flags().BoolVar(&all, "all", false, "page through every user")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON instead of a table")
+	return cmd
+}
+
+func newUsersPurgeCommand(c *client) *cobra.Command {
+	var yes, dryRun bool
+	cmd := &cobra.Command{
+		Use:   "purge USER_ID...",
+		Short: "Delete everything stored about users; their workspaces are kept",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !yes && !dryRun {
+				return errors.New("purging can't be undone; pass --yes to confirm or --dry-run to preview")
+			}
+			for _, userID := range args {
+				var result struct {
+					TodosDeleted        int64 `json:"todos_deleted"`
+					CustomFieldsDeleted int   `json:"custom_fields_deleted"`
+					FiltersDeleted      int   `json:"filters_deleted"`
+				}
+				path := "/admin/v1/users/" + url.PathEscape(userID)
+				if dryRun {
+					path += "?dry_run=true"
+				}
+				if err := c.do(cmd.Context(), http.MethodDelete, path, nil, &result); err != nil {
+					return err
+				}
+				if dryRun {
+					fmt.Fprintf(cmd.OutOrStdout(), "Would purge %s (%d todos, %d custom fields, %d saved filters)\n", userID, result.TodosDeleted, result.CustomFieldsDeleted, result.FiltersDeleted)
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "Purged %s (%d todos)\n", userID, result.TodosDeleted)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&yes, "yes", false, "confirm the purge")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be deleted without deleting it")
+	return cmd
+}
+
+func newUsersExportCommand(c *client) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "export USER_ID",
+		Short: "Export a user's settings, todos, custom fields and saved filters as JSON",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var export json.RawMessage
+			if err := c.do(cmd.Context(), http.MethodGet, "/admin/v1/users/"+url.PathEscape(args[0])+"/export", nil, &export); err != nil {
+				return err
+			}
+			if output == "" || output == "-" {
+				return printJSON(cmd.OutOrStdout(), export)
+			}
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			if err := printJSON(f, export); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write to instead of stdout")
+	return cmd
+}
+
+func newUsersImportCommand(c *client) *cobra.Command {
+	var (
+		input  string
+		newIDs bool
+		dryRun bool
+	)
+	cmd := &cobra.Command{
+		Use:   "import USER_ID",
+		Short: "Import an export into a user's personal list, skipping items already there",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				data []byte
+				err  error
+			)
+			if input == "" || input == "-" {
+				data, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				data, err = os.ReadFile(input)
+			}
+			if err != nil {
+				return err
+			}
+			if !json.Valid(data) {
+				return errors.New("the export isn't valid JSON")
+			}
+
+			var result struct {
+				Todos        models.ImportCounts `json:"todos"`
+				CustomFields models.ImportCounts `json:"custom_fields"`
+				Filters      models.ImportCounts `json:"filters"`
+			}
+			query := url.Values{}
+			if newIDs {
+				query.Set("new_ids", "true")
+			}
+			if dryRun {
+				query.Set("dry_run", "true")
+			}
+			path := "/admin/v1/users/" + url.PathEscape(args[0]) + "/import"
+			if len(query) > 0 {
+				path += "?" + query.Encode()
+			}
+			if err := c.do(cmd.Context(), http.MethodPost, path, data, &result); err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			created := "created"
+			if dryRun {
+				created = "to create"
+			}
+			fmt.Fprintf(out, "Todos: %d %s, %d already there\n", result.Todos.Created, created, result.Todos.Skipped)
+			fmt.Fprintf(out, "Custom fields: %d %s, %d already there\n", result.CustomFields.Created, created, result.CustomFields.Skipped)
+			fmt.Fprintf(out, "Saved filters: %d %s, %d already there\n", result.Filters.Created, created, result.Filters.Skipped)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&input, "file", "f", "", "file to read instead of stdin")
+	cmd.Flags().BoolVar(&newIDs, "new-ids", false, "give everything new IDs, to copy a list to another user of the same deployment")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be created without importing it")
+	return cmd
+}
+
+func newIndexesCommand(c *client) *cobra.Command {
+	indexes := &cobra.Command{
+		Use:   "indexes",
+		Short: "Manage storage indexes",
+	}
+	indexes.AddCommand(&cobra.Command{
+		Use:   "ensure",
+		Short: "Create any missing storage indexes, as after a restore",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.do(cmd.Context(), http.MethodPost, "/admin/v1/indexes", nil, nil); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Indexes are in place")
+			return nil
+		},
+	})
+	return indexes
+}
+
+func newRetentionCommand(c *client) *cobra.Command {
+	retention := &cobra.Command{
+		Use:   "retention",
+		Short: "Manage the retention of inactive users and old data",
+	}
+	retention.AddCommand(newRetentionSweepCommand(c))
+	retention.AddCommand(newRetentionReportCommand(c))
+	return retention
+}
+
+func newRetentionSweepCommand(c *client) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "sweep",
+		Short: "Purge users inactive beyond the retention window now",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var result struct {
+				UsersPurged   int      `json:"users_purged"`
+				TodosDeleted  int64    `json:"todos_deleted"`
+				SampleUserIDs []string `json:"sample_user_ids"`
+			}
+			path := "/admin/v1/retention/sweep"
+			if dryRun {
+				path += "?dry_run=true"
+			}
+			if err := c.do(cmd.Context(), http.MethodPost, path, nil, &result); err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if !dryRun {
+				fmt.Fprintf(out, "Purged %d inactive users (%d todos)\n", result.UsersPurged, result.TodosDeleted)
+				return nil
+			}
+			fmt.Fprintf(out, "Would purge %d inactive users (%d todos)\n", result.UsersPurged, result.TodosDeleted)
+			for _, id := range result.SampleUserIDs {
+				fmt.Fprintf(out, "  %s\n", id)
+			}
+			if len(result.SampleUserIDs) < result.UsersPurged {
+				fmt.Fprintf(out, "  ...and %d more\n", result.UsersPurged-len(result.SampleUserIDs))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show who would be purged without purging them")
+	return cmd
+}
+
+func newRetentionReportCommand(c *client) *cobra.Command {
+	var (
+		limit  int
+		asJSON bool
+	)
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Show what the retention rules would delete now, without deleting it",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var raw json.RawMessage
+			path := "/admin/v1/retention/report?limit=" + strconv.Itoa(limit)
+			if err := c.do(cmd.Context(), http.MethodGet, path, nil, &raw); err != nil {
+				return err
+			}
+			if asJSON {
+				return printJSON(cmd.OutOrStdout(), raw)
+			}
+			var report struct {
+				Rules []struct {
+					Rule      string    `json:"rule"`
+					Cutoff    time.Time `json:"cutoff"`
+					Todos     int       `json:"todos"`
+					Revisions int       `json:"revisions"`
+					Items     []struct {
+						UserID    string    `json:"user_id"`
+						TodoID    string    `json:"todo_id"`
+						Time      time.Time `json:"time"`
+						Revisions int       `json:"revisions"`
+					} `json:"items"`
+				} `json:"rules"`
+				UsersOptedOut int `json:"users_opted_out"`
+			}
+			if err := json.Unmarshal(raw, &report); err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			for _, rule := range report.Rules {
+				fmt.Fprintf(w, "%s: %d todos, %d revisions from before %s\n", rule.Rule, rule.Todos, rule.Revisions, rule.Cutoff.Format(time.RFC3339))
+				if len(rule.Items) == 0 {
+					continue
+				}
+				fmt.Fprintln(w, "  USER\tTODO\tTIME\tREVISIONS")
+				for _, item := range rule.Items {
+					fmt.Fprintf(w, "  %s\t%s\t%s\t%d\n", item.UserID, item.TodoID, item.Time.Format(time.RFC3339), item.Revisions)
+				}
+				if len(rule.Items) < rule.Todos {
+					fmt.Fprintf(w, "  ... and %d more\n", rule.Todos-len(rule.Items))
+				}
+			}
+			fmt.Fprintf(w, "%d users opted out\n", report.UsersOptedOut)
+			return w.Flush()
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 50, "todos listed per rule (0 to 200)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON instead of a table")
+	return cmd
+}
+
+func newAuditCommand(c *client) *cobra.Command {
+	audit := &cobra.Command{
+		Use:   "audit",
+		Short: "Read the audit trail of requests",
+	}
+	audit.AddCommand(newAuditListCommand(c))
+	return audit
+}
+
+// auditEntry is an audit entry as the admin API lists them
+type auditEntry struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	UserID     string    `json:"user_id"`
+	AuthMethod string    `json:"auth_method"`
+	ClientIP   string    `json:"client_ip"`
+	Status     int       `json:"status"`
+	LatencyMs  int64     `json:"latency_ms"`
+}
+
+func newAuditListCommand(c *client) *cobra.Command {
+	var (
+		userID string
+		method string
+		path   string
+		status string
+		since  time.Duration
+		limit  int
+		before string
+		all    bool
+		asJSON bool
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List audit entries, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{"limit": {strconv.Itoa(limit)}}
+			for key, v := range map[string]string{"user_id": userID, "method": method, "path": path, "status": status} {
+				if v != "" {
+					query.Set(key, v)
+				}
+			}
+			if since > 0 {
+				query.Set("since", time.Now().Add(-since).UTC().Format(time.RFC3339))
+			}
+
+			var (
+				listed []json.RawMessage
+				rows   []auditEntry
+			)
+			for {
+				if before != "" {
+					query.Set("before", before)
+				}
+				var page struct {
+					Entries    []json.RawMessage `json:"entries"`
+					NextBefore string            `json:"next_before"`
+				}
+				if err := c.do(cmd.Context(), http.MethodGet, "/admin/v1/audit?"+query.Encode(), nil, &page); err != nil {
+					return err
+				}
+				for _, raw := range page.Entries {
+					var e auditEntry
+					if err := json.Unmarshal(raw, &e); err != nil {
+						return err
+					}
+					rows = append(rows, e)
+				}
+				listed = append(listed, page.Entries...)
+				before = page.NextBefore
+				if !all || before == "" {
+					break
+				}
+			}
+
+			if asJSON {
+				if listed == nil {
+					listed = []json.RawMessage{}
+				}
+				data, err := json.Marshal(listed)
+				if err != nil {
+					return err
+				}
+				return printJSON(cmd.OutOrStdout(), data)
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "TIME\tSTATUS\tMETHOD\tPATH\tUSER\tAUTH\tCLIENT\tMS")
+			for _, e := range rows {
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%d\n", e.Time.Format(time.RFC3339), e.Status, e.Method, e.Path, e.UserID, e.AuthMethod, e.ClientIP, e.LatencyMs)
+			}
+			if !all && before != "" {
+				fmt.Fprintf(w, "\nOlder entries follow; pass --before %s or --all\n", before)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&userID, "user", "", "only entries of this user")
+	cmd.Flags().StringVar(&method, "method", "", "only entries with this HTTP method")
+	cmd.Flags().StringVar(&path, "path", "", "only entries whose path starts with this")
+	cmd.Flags().StringVar(&status, "status", "", "only entries with this status code, or class like 4xx")
+	cmd.Flags().DurationVar(&since, "since", 0, "only entries from this long ago on, like 24h")
+	cmd.Flags().IntVar(&limit, "limit", 50, "entries per page (1 to 200)")
+	cmd.Flags().StringVar(&before, "before", "", "list entries before this ID")
+	cmd.Flags().BoolVar(&all, "all", false, "page through every matching entry")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON instead of a table")
+	return cmd
+}
+
+// printJSON writes JSON indented
+func printJSON(w io.Writer, data []byte) error {
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// envOr returns the environment variable, or def when it's unset
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
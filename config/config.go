@@ -0,0 +1,1243 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-api/fieldcrypt"
+	"todo-api/models"
+
+	"github.com/google/uuid"
+)
+
+// Config holds every setting the API needs, loaded once at startup
+type Config struct {
+	Port         string
+	MaxBodyBytes int64
+	// CompressMinBytes is the smallest response that gets gzipped
+	CompressMinBytes int
+	// ResponseEnvelope wraps JSON responses of v1 and of routes outside the
+	// versioned API in {data, meta, error}; v2 always wraps them
+	ResponseEnvelope bool
+	API              APIConfig
+	// RequestTimeout is the overall deadline for handling one request
+	RequestTimeout time.Duration
+	Storage        StorageConfig
+	Mongo          MongoConfig
+	Postgres       PostgresConfig
+	SQLite         SQLiteConfig
+	Auth           AuthConfig
+	Cookie         CookieConfig
+	CORS           CORSConfig
+	Retention      RetentionConfig
+	Cache          CacheConfig
+	TLS            TLSConfig
+	Azure          AzureConfig
+	Admin          AdminConfig
+	Quota          QuotaConfig
+	Events         EventsConfig
+	Digest         DigestConfig
+	Slack          SlackConfig
+	Stale          StaleConfig
+	LinkPreview    LinkPreviewConfig
+	CalDAV         CalDAVConfig
+	Feed           FeedConfig
+	Import         ImportConfig
+	Seed           SeedConfig
+	RateLimit      RateLimitConfig
+	Concurrency    ConcurrencyConfig
+	IPFilter       IPFilterConfig
+	Audit          AuditConfig
+	Encryption     EncryptionConfig
+	Tenancy        TenancyConfig
+
+	// UndoWindow is how long a delete can be undone; 0 turns undo off
+	UndoWindow time.Duration
+	// SyncWindow is how long deletes are remembered for syncing clients, and
+	// so how old a sync token may be
+	SyncWindow time.Duration
+	// DailyResetInterval is how often users' daily resets are checked for
+	// ones that are due
+	DailyResetInterval time.Duration
+}
+
+// Supported storage backends
+const (
+	BackendMongo    = "mongo"
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+	BackendSQLite   = "sqlite"
+)
+
+// StorageConfig selects where todos are persisted
+type StorageConfig struct {
+	Backend string
+	// OperationTimeout bounds the storage work done for a single request
+	OperationTimeout time.Duration
+}
+
+// How the API authenticates to MongoDB / Cosmos DB
+const (
+	// MongoAuthConnectionString uses the credentials embedded in MONGODB_URI
+	MongoAuthConnectionString = "connection_string"
+	// MongoAuthManagedIdentity fetches the connection string from Azure
+	// Resource Manager using the app's managed identity
+	MongoAuthManagedIdentity = "managed_identity"
+)
+
+// MongoConfig holds the database connection settings
+type MongoConfig struct {
+	Auth string
+	// URI is resolved at startup when using a managed identity
+	URI        string
+	Cosmos     CosmosConfig
+	Database   string
+	Collection string
+	Retry      RetryConfig
+	Breaker    BreakerConfig
+	Pool       PoolConfig
+}
+
+// CosmosConfig identifies the Cosmos DB account in Azure Resource Manager
+type CosmosConfig struct {
+	SubscriptionID string
+	ResourceGroup  string
+	Account        string
+}
+
+// PoolConfig tunes the Mongo driver's connection pool and timeouts
+type PoolConfig struct {
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	MaxConnIdleTime        time.Duration
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+}
+
+// RetryConfig controls retries of transient database errors
+type RetryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// BreakerConfig controls the database circuit breaker
+type BreakerConfig struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+// PostgresConfig holds the PostgreSQL connection settings
+type PostgresConfig struct {
+	URL string
+}
+
+// SQLiteConfig holds the SQLite database settings
+type SQLiteConfig struct {
+	Path string
+}
+
+// Supported authentication modes
+const (
+	// AuthModeCookie identifies anonymous users by a signed cookie
+	AuthModeCookie = "cookie"
+	// AuthModeAAD requires Microsoft Entra ID (Azure AD) bearer tokens
+	AuthModeAAD = "aad"
+)
+
+// AuthConfig selects how requests are authenticated
+type AuthConfig struct {
+	Mode string
+	AAD  AADConfig
+}
+
+// AADConfig holds the Microsoft Entra ID settings used in aad mode
+type AADConfig struct {
+	TenantID string
+	// Audiences are the accepted aud claims, usually the API's application
+	// ID URI and client ID
+	Audiences []string
+	// Issuers and KeysURL default to the tenant's endpoints
+	Issuers []string
+	KeysURL string
+}
+
+// CookieConfig holds the settings for the user identity cookie
+type CookieConfig struct {
+	Name   string
+	MaxAge time.Duration
+	Secure bool
+	// Secret signs the cookie; PreviousSecrets are still accepted so the
+	// secret can be rotated without logging everyone out
+	Secret          string
+	PreviousSecrets []string
+	// AcceptLegacy upgrades unsigned cookies issued before signing existed
+	AcceptLegacy bool
+	// CSRF requires a CSRF token on mutating cookie-authenticated requests
+	CSRF bool
+}
+
+// minCookieSecretLength is the shortest accepted cookie signing secret
+const minCookieSecretLength = 32
+
+// RetentionConfig controls how long data of inactive anonymous users is kept
+type RetentionConfig struct {
+	// InactiveAfter is how long a user may go unseen before their todos are
+	// purged; zero disables purging
+	InactiveAfter time.Duration
+	// SweepInterval is how often the purge runs
+	SweepInterval time.Duration
+	// TouchInterval is how often a user's last_seen is written while active
+	TouchInterval time.Duration
+	// Rules purge old data of every user who hasn't opted out, on the
+	// sweep interval
+	Rules []RetentionRule
+}
+
+// TLSConfig lets the server terminate TLS itself, for deployments without a
+// fronting proxy. Use either a certificate and key file or autocert domains.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// AutocertDomains obtains certificates from Let's Encrypt when set
+	AutocertDomains  []string
+	AutocertCacheDir string
+	AutocertEmail    string
+	// RedirectPort serves plain HTTP redirects to HTTPS when set
+	RedirectPort string
+}
+
+// Enabled reports whether the server should serve HTTPS
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || len(t.AutocertDomains) > 0
+}
+
+// AdminConfig protects the operator API
+type AdminConfig struct {
+	// Token is the bearer token operators authenticate with; empty disables
+	// the admin API
+	Token string
+}
+
+// minAdminTokenLength is the shortest accepted admin token
+const minAdminTokenLength = 32
+
+// QuotaConfig caps how much each user can store, mainly so anonymous cookie
+// users can't flood the database. Zero disables a cap.
+type QuotaConfig struct {
+	// MaxTodos caps a user's personal todos
+	MaxTodos int
+	// MaxWorkspaceTodos caps the todos of one workspace
+	MaxWorkspaceTodos int
+	// MaxWorkspaces caps the workspaces a user owns
+	MaxWorkspaces int
+	// MaxAttachments caps the links attached to one todo, below
+	// models.MaxAttachments; zero leaves that limit
+	MaxAttachments int
+	// MaxTags caps the tags on one todo, below models.MaxTags; zero leaves
+	// that limit
+	MaxTags int
+	// WarnPercent is how much of a cap, in percent, may be used before
+	// responses warn that it's running out; zero turns warnings off
+	WarnPercent int
+}
+
+// Supported event backends
+const (
+	EventsNone       = "none"
+	EventsServiceBus = "servicebus"
+	EventsEventGrid  = "eventgrid"
+)
+
+// EventsConfig selects where todo events are published
+type EventsConfig struct {
+	Backend string
+	// ServiceBusNamespace is the namespace host, e.g.
+	// mybus.servicebus.windows.net
+	ServiceBusNamespace string
+	// ServiceBusEntity is the queue or topic events are sent to
+	ServiceBusEntity string
+	// EventGridEndpoint is the topic endpoint, e.g.
+	// https://mytopic.westeurope-1.eventgrid.azure.net/api/events
+	EventGridEndpoint string
+	// EventGridKey is the topic access key; empty uses the managed identity
+	EventGridKey string
+	// RelayInterval is how often the outbox is checked for events to send
+	RelayInterval time.Duration
+}
+
+// Supported digest mailers
+const (
+	MailerNone = "none"
+	MailerSMTP = "smtp"
+	MailerACS  = "acs"
+)
+
+// DigestConfig sets up the emailed digests of open todos users can opt
+// into. Digests go out at Hour in each user's time zone, daily or on
+// Weekday.
+type DigestConfig struct {
+	// Mailer sends the digests; MailerNone turns them off
+	Mailer string
+	// From is the address digests are sent from
+	From string
+	// SMTPAddr is the SMTP server as host:port
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	// ACSEndpoint is the Communication Services resource endpoint, e.g.
+	// https://mycomms.europe.communication.azure.com
+	ACSEndpoint string
+	// ACSKey is the resource's access key; empty uses the managed identity
+	ACSKey  string
+	Hour    int
+	Weekday time.Weekday
+	// Interval is how often the scheduler looks for digests that are due
+	Interval time.Duration
+}
+
+// SlackConfig sets up the Slack integration, which is on when a signing
+// secret is set
+type SlackConfig struct {
+	// SigningSecret verifies slash command requests from the Slack app
+	SigningSecret string
+	// WebhookURL is an incoming webhook notifications are posted to; empty
+	// sends none
+	WebhookURL string
+	// OverdueInterval is how often todos are checked for ones that became
+	// overdue
+	OverdueInterval time.Duration
+	// RequestTolerance is how far a slash command's timestamp may be from
+	// now, either way
+	RequestTolerance time.Duration
+}
+
+// StaleConfig controls when todos count as stale and the nudges about
+// them. Nudges go out at Hour in each user's time zone.
+type StaleConfig struct {
+	// After is how long an open todo goes unchanged before it's stale
+	After time.Duration
+	// Nudge turns on nudges about todos that went stale
+	Nudge bool
+	Hour  int
+	// Interval is how often the nudger looks for nudges that are due
+	Interval time.Duration
+}
+
+// LinkPreviewConfig controls the previews fetched for links attached to
+// todos
+type LinkPreviewConfig struct {
+	Enabled bool
+	// Timeout bounds fetching one page, redirects included
+	Timeout time.Duration
+	// MaxBytes is how much of a page is read looking for its tags
+	MaxBytes int
+	// CacheTTL is how long a fetched preview is reused for the same link
+	CacheTTL time.Duration
+}
+
+// CalDAVConfig controls the CalDAV endpoint native task apps sync with
+type CalDAVConfig struct {
+	Enabled bool
+	// PasswordTTL is how long the passwords users create for their apps
+	// last, in cookie mode
+	PasswordTTL time.Duration
+}
+
+// FeedConfig controls the activity feeds users follow in feed readers
+type FeedConfig struct {
+	// TTL is how long a feed URL works once created
+	TTL time.Duration
+	// Entries is how many of the latest entries a feed lists
+	Entries int
+}
+
+// ImportConfig controls importing tasks from other services. Each source
+// is offered once its OAuth client is set.
+type ImportConfig struct {
+	// RedirectURL is the page of the app the services send users back to
+	// after they allow access. It must be registered with each of them.
+	RedirectURL string
+	Google      OAuthClient
+	Microsoft   OAuthClient
+	// Timeout bounds each request made to a service
+	Timeout time.Duration
+}
+
+// OAuthClient is an app registered with an OAuth 2.0 provider
+type OAuthClient struct {
+	ID     string
+	Secret string
+}
+
+// Configured reports whether the client is set
+func (c OAuthClient) Configured() bool {
+	return c.ID != ""
+}
+
+// SeedConfig fills a development database with sample data at startup
+type SeedConfig struct {
+	Enabled bool
+	// Users are the IDs of the users to seed. Users who already have todos
+	// are left alone, so restarts don't pile up samples.
+	Users []string
+	// Todos is how many personal todos each user gets
+	Todos int
+}
+
+// AuditConfig controls the audit trail of requests
+type AuditConfig struct {
+	Enabled bool
+	// Retention is how long entries are kept
+	Retention time.Duration
+	// RedactFields are the JSON fields, at any depth, whose values are
+	// replaced in recorded bodies; they're matched case-insensitively
+	RedactFields []string
+	// MaxBodyBytes is the largest body recorded; larger ones are left out
+	MaxBodyBytes int
+}
+
+// EncryptionConfig holds the keys that encrypt todo titles and descriptions
+// at rest. Keys are 32 random bytes, base64-encoded in the environment.
+type EncryptionConfig struct {
+	// Key encrypts what's written. Without one, what's written is left in
+	// plain text.
+	Key []byte
+	// PreviousKeys still decrypt what was written before the key was rotated
+	// or removed
+	PreviousKeys [][]byte
+}
+
+// Enabled reports whether todo fields are encrypted or decrypted
+func (c EncryptionConfig) Enabled() bool {
+	return len(c.Key) > 0 || len(c.PreviousKeys) > 0
+}
+
+// defaultAuditRedactFields are the fields redacted unless
+// AUDIT_REDACT_FIELDS names others: credentials, and the text of todos,
+// checklist items and comments, which field encryption keeps out of storage
+var defaultAuditRedactFields = []string{"password", "secret", "token", "access_token", "refresh_token", "api_key", "authorization", "cookie", "code", "title", "description", "body"}
+
+// DefaultSeedUsers are the users seeded unless SEED_USERS names others
+var DefaultSeedUsers = []string{
+	"00000000-0000-4000-8000-000000000001",
+	"00000000-0000-4000-8000-000000000002",
+}
+
+// MaxSeedTodos caps SEED_TODOS
+const MaxSeedTodos = 500
+
+// APIConfig describes the life cycle of the API versions
+type APIConfig struct {
+	// V1DeprecatedAt is when v1 was deprecated in favour of v2
+	V1DeprecatedAt time.Time
+	// V1Sunset is when v1 may stop working; zero announces no date
+	V1Sunset time.Time
+	// SchemaValidation picks the routes whose bodies are checked against
+	// their JSON Schema
+	SchemaValidation SchemaValidationConfig
+}
+
+// ConcurrencyConfig caps the requests being handled at once, beyond which
+// requests are turned away rather than queued. Zero turns a cap off.
+type ConcurrencyConfig struct {
+	// Global caps the requests in flight on the instance
+	Global int
+	// PerUser caps the requests in flight for each user, or each client
+	// address for requests without one
+	PerUser int
+}
+
+// AzureConfig holds settings shared by the Azure integrations
+type AzureConfig struct {
+	// ClientID selects a user-assigned managed identity; empty uses the
+	// system-assigned one
+	ClientID string
+}
+
+// CacheConfig controls the optional Redis cache in front of todo listings
+type CacheConfig struct {
+	// RedisURL enables the cache when set
+	RedisURL string
+	TTL      time.Duration
+}
+
+// CORSConfig holds the cross-origin settings. Origins may use a leading
+// wildcard label, e.g. https://*.azurewebsites.net.
+type CORSConfig struct {
+	AllowOrigins []string
+	AllowMethods []string
+	AllowHeaders []string
+}
+
+// defaultAllowOrigins are the origins allowed when none are configured
+var defaultAllowOrigins = []string{
+	"http://localhost:3000", // React default
+	"http://localhost:5173", // Vite default
+	"http://localhost:8080", // Same origin
+	"http://127.0.0.1:3000",
+	"http://127.0.0.1:5173",
+	"http://127.0.0.1:8080",
+	"https://todo-backend-app-2024.azurewebsites.net", // Azure App Service
+	"https://*.azurewebsites.net",                     // All Azure App Service domains
+}
+
+var defaultAllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+
+var defaultAllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-CSRF-Token"}
+
+// SecretKeys are the settings that hold credentials. They may be stored in
+// Azure Key Vault instead of app settings; add new secret settings here.
+var SecretKeys = []string{"MONGODB_URI", "POSTGRES_URL", "REDIS_URL", "COOKIE_SECRET", "COOKIE_PREVIOUS_SECRETS", "ADMIN_TOKEN", "EVENTGRID_KEY", "SMTP_PASSWORD", "ACS_KEY", "SLACK_SIGNING_SECRET", "SLACK_WEBHOOK_URL", "ENCRYPTION_KEY", "ENCRYPTION_PREVIOUS_KEYS"}
+
+// SecretsConfig locates the secret store. It's read before, and used to
+// load, the rest of the configuration.
+type SecretsConfig struct {
+	// KeyVaultURL enables Key Vault when set, e.g. https://myvault.vault.azure.net
+	KeyVaultURL string
+	// RefreshInterval is how often secrets are re-read from Key Vault
+	RefreshInterval time.Duration
+	// ClientID selects a user-assigned managed identity
+	ClientID string
+}
+
+// LoadSecrets reads the secret store settings from the environment
+func LoadSecrets() (SecretsConfig, error) {
+	l := &loader{getenv: os.Getenv}
+	cfg := SecretsConfig{
+		KeyVaultURL:     strings.TrimSuffix(l.string("KEYVAULT_URL", ""), "/"),
+		RefreshInterval: l.duration("KEYVAULT_REFRESH_INTERVAL", time.Hour),
+		ClientID:        l.string("AZURE_CLIENT_ID", ""),
+	}
+	if cfg.KeyVaultURL != "" && !strings.HasPrefix(cfg.KeyVaultURL, "https://") {
+		l.fail("KEYVAULT_URL must be an https:// URL, got %q", cfg.KeyVaultURL)
+	}
+	if cfg.RefreshInterval <= 0 {
+		l.fail("KEYVAULT_REFRESH_INTERVAL must be positive")
+	}
+
+	if len(l.errs) > 0 {
+		return cfg, fmt.Errorf("invalid configuration: %w", errors.Join(l.errs...))
+	}
+	return cfg, nil
+}
+
+// Load reads the configuration from the environment and validates it,
+// returning every problem found rather than stopping at the first one
+func Load() (*Config, error) {
+	return LoadFrom(os.Getenv)
+}
+
+// LoadFrom is like Load but looks settings up with getenv, which lets
+// secrets come from somewhere other than the environment
+func LoadFrom(getenv func(string) string) (*Config, error) {
+	l := &loader{getenv: getenv}
+
+	tlsConfig := TLSConfig{
+		CertFile:         l.string("TLS_CERT_FILE", ""),
+		KeyFile:          l.string("TLS_KEY_FILE", ""),
+		AutocertDomains:  l.list("TLS_AUTOCERT_DOMAINS", nil),
+		AutocertCacheDir: l.string("TLS_AUTOCERT_CACHE_DIR", "certs"),
+		AutocertEmail:    l.string("TLS_AUTOCERT_EMAIL", ""),
+		RedirectPort:     l.string("TLS_REDIRECT_PORT", ""),
+	}
+
+	cfg := &Config{
+		Port:               l.string("PORT", "8080"),
+		MaxBodyBytes:       int64(l.int("MAX_BODY_BYTES", 1<<20)),
+		RequestTimeout:     l.duration("REQUEST_TIMEOUT", 15*time.Second),
+		CompressMinBytes:   l.int("COMPRESS_MIN_BYTES", 1024),
+		ResponseEnvelope:   l.bool("RESPONSE_ENVELOPE", false),
+		UndoWindow:         l.duration("UNDO_WINDOW", 30*time.Second),
+		SyncWindow:         l.duration("SYNC_WINDOW", 30*24*time.Hour),
+		DailyResetInterval: l.duration("DAILY_RESET_INTERVAL", 5*time.Minute),
+		Concurrency: ConcurrencyConfig{
+			Global:  l.int("MAX_CONCURRENT_REQUESTS", 200),
+			PerUser: l.int("MAX_CONCURRENT_REQUESTS_PER_USER", 10),
+		},
+		API: APIConfig{
+			V1DeprecatedAt: l.date("API_V1_DEPRECATED_AT", time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)),
+			V1Sunset:       l.date("API_V1_SUNSET", time.Date(2027, time.April, 16, 0, 0, 0, 0, time.UTC)),
+		},
+		Auth: AuthConfig{
+			Mode: strings.ToLower(l.string("AUTH_MODE", AuthModeCookie)),
+		},
+		Storage: StorageConfig{
+			Backend:          strings.ToLower(l.string("STORAGE_BACKEND", BackendMongo)),
+			OperationTimeout: l.duration("DB_OPERATION_TIMEOUT", 10*time.Second),
+		},
+		Cookie: CookieConfig{
+			Name:   l.string("COOKIE_NAME", "todo_user_id"),
+			MaxAge: l.duration("COOKIE_MAX_AGE", 24*time.Hour),
+			// Cookies must be Secure when we serve HTTPS ourselves
+			Secure:          l.bool("COOKIE_SECURE", tlsConfig.Enabled()),
+			Secret:          l.string("COOKIE_SECRET", ""),
+			PreviousSecrets: l.list("COOKIE_PREVIOUS_SECRETS", nil),
+			AcceptLegacy:    l.bool("COOKIE_ACCEPT_LEGACY", false),
+			CSRF:            l.bool("CSRF_ENABLED", true),
+		},
+		CORS: CORSConfig{
+			AllowOrigins: l.list("CORS_ALLOW_ORIGINS", defaultAllowOrigins),
+			AllowMethods: l.list("CORS_ALLOW_METHODS", defaultAllowMethods),
+			AllowHeaders: l.list("CORS_ALLOW_HEADERS", defaultAllowHeaders),
+		},
+		TLS: tlsConfig,
+		Azure: AzureConfig{
+			ClientID: l.string("AZURE_CLIENT_ID", ""),
+		},
+		Admin: AdminConfig{
+			Token: l.string("ADMIN_TOKEN", ""),
+		},
+		Quota: QuotaConfig{
+			MaxTodos:          l.int("QUOTA_MAX_TODOS", 1000),
+			MaxWorkspaceTodos: l.int("QUOTA_MAX_WORKSPACE_TODOS", 5000),
+			MaxWorkspaces:     l.int("QUOTA_MAX_WORKSPACES", 20),
+			MaxAttachments:    l.int("QUOTA_MAX_ATTACHMENTS", models.MaxAttachments),
+			MaxTags:           l.int("QUOTA_MAX_TAGS", models.MaxTags),
+			WarnPercent:       l.int("QUOTA_WARN_PERCENT", 90),
+		},
+		Events: EventsConfig{
+			Backend:             l.string("EVENTS_BACKEND", EventsNone),
+			ServiceBusNamespace: l.string("SERVICEBUS_NAMESPACE", ""),
+			ServiceBusEntity:    l.string("SERVICEBUS_ENTITY", ""),
+			EventGridEndpoint:   l.string("EVENTGRID_ENDPOINT", ""),
+			EventGridKey:        l.string("EVENTGRID_KEY", ""),
+			RelayInterval:       l.duration("EVENTS_RELAY_INTERVAL", 5*time.Second),
+		},
+		Digest: DigestConfig{
+			Mailer:       strings.ToLower(l.string("DIGEST_MAILER", MailerNone)),
+			From:         l.string("DIGEST_FROM", ""),
+			SMTPAddr:     l.string("SMTP_ADDR", ""),
+			SMTPUsername: l.string("SMTP_USERNAME", ""),
+			SMTPPassword: l.string("SMTP_PASSWORD", ""),
+			ACSEndpoint:  l.string("ACS_ENDPOINT", ""),
+			ACSKey:       l.string("ACS_KEY", ""),
+			Hour:         l.int("DIGEST_HOUR", 7),
+			Weekday:      l.weekday("DIGEST_WEEKDAY", time.Monday),
+			Interval:     l.duration("DIGEST_INTERVAL", 15*time.Minute),
+		},
+		Slack: SlackConfig{
+			SigningSecret:    l.string("SLACK_SIGNING_SECRET", ""),
+			WebhookURL:       l.string("SLACK_WEBHOOK_URL", ""),
+			OverdueInterval:  l.duration("SLACK_OVERDUE_INTERVAL", 15*time.Minute),
+			RequestTolerance: l.duration("SLACK_REQUEST_TOLERANCE", 5*time.Minute),
+		},
+		Stale: StaleConfig{
+			After:    l.duration("STALE_AFTER", 30*24*time.Hour),
+			Nudge:    l.bool("STALE_NUDGES", false),
+			Hour:     l.int("STALE_NUDGE_HOUR", 9),
+			Interval: l.duration("STALE_NUDGE_INTERVAL", 15*time.Minute),
+		},
+		LinkPreview: LinkPreviewConfig{
+			Enabled:  l.bool("LINK_PREVIEWS_ENABLED", true),
+			Timeout:  l.duration("LINK_PREVIEW_TIMEOUT", 5*time.Second),
+			MaxBytes: l.int("LINK_PREVIEW_MAX_BYTES", 512<<10),
+			CacheTTL: l.duration("LINK_PREVIEW_CACHE_TTL", 24*time.Hour),
+		},
+		CalDAV: CalDAVConfig{
+			Enabled:     l.bool("CALDAV_ENABLED", false),
+			PasswordTTL: l.duration("CALDAV_PASSWORD_TTL", 365*24*time.Hour),
+		},
+		Feed: FeedConfig{
+			TTL:     l.duration("FEED_TTL", 365*24*time.Hour),
+			Entries: l.int("FEED_ENTRIES", 50),
+		},
+		Import: ImportConfig{
+			RedirectURL: l.string("IMPORT_REDIRECT_URL", ""),
+			Google: OAuthClient{
+				ID:     l.string("GOOGLE_TASKS_CLIENT_ID", ""),
+				Secret: l.string("GOOGLE_TASKS_CLIENT_SECRET", ""),
+			},
+			Microsoft: OAuthClient{
+				ID:     l.string("MICROSOFT_TODO_CLIENT_ID", ""),
+				Secret: l.string("MICROSOFT_TODO_CLIENT_SECRET", ""),
+			},
+			Timeout: l.duration("IMPORT_TIMEOUT", 10*time.Second),
+		},
+		Seed: SeedConfig{
+			Enabled: l.bool("SEED", false),
+			Users:   l.list("SEED_USERS", DefaultSeedUsers),
+			Todos:   l.int("SEED_TODOS", 30),
+		},
+		Audit: AuditConfig{
+			Enabled:      l.bool("AUDIT_ENABLED", false),
+			Retention:    l.duration("AUDIT_RETENTION", 90*24*time.Hour),
+			RedactFields: l.list("AUDIT_REDACT_FIELDS", defaultAuditRedactFields),
+			MaxBodyBytes: l.int("AUDIT_MAX_BODY_BYTES", 8<<10),
+		},
+		Encryption: EncryptionConfig{
+			PreviousKeys: l.keys("ENCRYPTION_PREVIOUS_KEYS"),
+		},
+		Tenancy: TenancyConfig{
+			Mode:      strings.ToLower(l.string("TENANT_MODE", TenantModeOff)),
+			Header:    l.string("TENANT_HEADER", "X-Tenant-ID"),
+			Domain:    strings.ToLower(strings.TrimPrefix(l.string("TENANT_DOMAIN", ""), ".")),
+			Tenants:   l.list("TENANTS", nil),
+			Isolation: strings.ToLower(l.string("TENANT_ISOLATION", TenantIsolationDatabase)),
+		},
+		Cache: CacheConfig{
+			RedisURL: l.string("REDIS_URL", ""),
+			TTL:      l.duration("CACHE_TTL", time.Minute),
+		},
+		Retention: RetentionConfig{
+			InactiveAfter: l.duration("RETENTION_INACTIVE_AFTER", 30*24*time.Hour),
+			SweepInterval: l.duration("RETENTION_SWEEP_INTERVAL", time.Hour),
+			TouchInterval: l.duration("RETENTION_TOUCH_INTERVAL", time.Hour),
+		},
+	}
+
+	switch cfg.Storage.Backend {
+	case BackendMongo:
+		cfg.Mongo = MongoConfig{
+			Auth:       strings.ToLower(l.string("MONGO_AUTH", MongoAuthConnectionString)),
+			Database:   l.required("DATABASE_NAME"),
+			Collection: l.string("COLLECTION_NAME", "todos"),
+			Retry: RetryConfig{
+				MaxAttempts: l.int("MONGO_RETRY_MAX_ATTEMPTS", 3),
+				BaseDelay:   l.duration("MONGO_RETRY_BASE_DELAY", 100*time.Millisecond),
+				MaxDelay:    l.duration("MONGO_RETRY_MAX_DELAY", 2*time.Second),
+			},
+			Breaker: BreakerConfig{
+				FailureThreshold: l.int("MONGO_BREAKER_FAILURE_THRESHOLD", 5),
+				OpenTimeout:      l.duration("MONGO_BREAKER_OPEN_TIMEOUT", 30*time.Second),
+			},
+			Pool: PoolConfig{
+				MaxPoolSize:            uint64(l.int("MONGO_MAX_POOL_SIZE", 100)),
+				MinPoolSize:            uint64(l.int("MONGO_MIN_POOL_SIZE", 0)),
+				MaxConnIdleTime:        l.duration("MONGO_MAX_CONN_IDLE_TIME", 2*time.Minute),
+				ConnectTimeout:         l.duration("MONGO_CONNECT_TIMEOUT", 10*time.Second),
+				ServerSelectionTimeout: l.duration("MONGO_SERVER_SELECTION_TIMEOUT", 10*time.Second),
+			},
+		}
+		switch cfg.Mongo.Auth {
+		case MongoAuthConnectionString:
+			cfg.Mongo.URI = l.required("MONGODB_URI")
+		case MongoAuthManagedIdentity:
+			cfg.Mongo.Cosmos = CosmosConfig{
+				SubscriptionID: l.required("AZURE_SUBSCRIPTION_ID"),
+				ResourceGroup:  l.required("AZURE_RESOURCE_GROUP"),
+				Account:        l.required("COSMOS_ACCOUNT_NAME"),
+			}
+		default:
+			l.fail("MONGO_AUTH must be one of %s, %s; got %q", MongoAuthConnectionString, MongoAuthManagedIdentity, cfg.Mongo.Auth)
+		}
+		if pool := cfg.Mongo.Pool; pool.MaxPoolSize < 1 || pool.MinPoolSize > pool.MaxPoolSize {
+			l.fail("MONGO_MAX_POOL_SIZE must be at least 1 and not less than MONGO_MIN_POOL_SIZE")
+		}
+		if pool := cfg.Mongo.Pool; pool.ConnectTimeout <= 0 || pool.ServerSelectionTimeout <= 0 || pool.MaxConnIdleTime < 0 {
+			l.fail("MONGO_CONNECT_TIMEOUT and MONGO_SERVER_SELECTION_TIMEOUT must be positive, MONGO_MAX_CONN_IDLE_TIME must not be negative")
+		}
+		if cfg.Mongo.Retry.MaxAttempts < 1 {
+			l.fail("MONGO_RETRY_MAX_ATTEMPTS must be at least 1")
+		}
+		if cfg.Mongo.Retry.BaseDelay < 0 || cfg.Mongo.Retry.MaxDelay < cfg.Mongo.Retry.BaseDelay {
+			l.fail("MONGO_RETRY_BASE_DELAY must not be negative or greater than MONGO_RETRY_MAX_DELAY")
+		}
+		if cfg.Mongo.Breaker.FailureThreshold < 1 {
+			l.fail("MONGO_BREAKER_FAILURE_THRESHOLD must be at least 1")
+		}
+		if cfg.Mongo.Breaker.OpenTimeout <= 0 {
+			l.fail("MONGO_BREAKER_OPEN_TIMEOUT must be positive")
+		}
+	case BackendPostgres:
+		cfg.Postgres = PostgresConfig{
+			URL: l.required("POSTGRES_URL"),
+		}
+	case BackendSQLite:
+		cfg.SQLite = SQLiteConfig{
+			Path: l.string("SQLITE_PATH", "todos.db"),
+		}
+	case BackendMemory:
+		// Nothing to configure
+	default:
+		l.fail("STORAGE_BACKEND must be one of %s, %s, %s, %s; got %q",
+			BackendMongo, BackendPostgres, BackendSQLite, BackendMemory, cfg.Storage.Backend)
+	}
+
+	switch cfg.Auth.Mode {
+	case AuthModeCookie:
+		if cfg.Cookie.Secret == "" {
+			l.fail("COOKIE_SECRET environment variable is not set")
+		}
+	case AuthModeAAD:
+		tenantID := l.required("AAD_TENANT_ID")
+		cfg.Auth.AAD = AADConfig{
+			TenantID:  tenantID,
+			Audiences: l.list("AAD_AUDIENCES", nil),
+			Issuers: l.list("AAD_ISSUERS", []string{
+				"https://sts.windows.net/" + tenantID + "/",
+				"https://login.microsoftonline.com/" + tenantID + "/v2.0",
+			}),
+			KeysURL: l.string("AAD_KEYS_URL", "https://login.microsoftonline.com/"+tenantID+"/discovery/v2.0/keys"),
+		}
+		if len(cfg.Auth.AAD.Audiences) == 0 {
+			l.fail("AAD_AUDIENCES must list at least one audience")
+		}
+	default:
+		l.fail("AUTH_MODE must be one of %s, %s; got %q", AuthModeCookie, AuthModeAAD, cfg.Auth.Mode)
+	}
+
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		l.fail("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if cfg.TLS.CertFile != "" && len(cfg.TLS.AutocertDomains) > 0 {
+		l.fail("set either TLS_CERT_FILE/TLS_KEY_FILE or TLS_AUTOCERT_DOMAINS, not both")
+	}
+	if cfg.TLS.RedirectPort != "" {
+		if !cfg.TLS.Enabled() {
+			l.fail("TLS_REDIRECT_PORT requires TLS to be configured")
+		}
+		if cfg.TLS.RedirectPort == cfg.Port {
+			l.fail("TLS_REDIRECT_PORT must differ from PORT")
+		}
+	}
+	if cfg.RequestTimeout <= 0 {
+		l.fail("REQUEST_TIMEOUT must be positive")
+	}
+	if cfg.Concurrency.Global < 0 {
+		l.fail("MAX_CONCURRENT_REQUESTS must not be negative")
+	}
+	if cfg.Concurrency.PerUser < 0 {
+		l.fail("MAX_CONCURRENT_REQUESTS_PER_USER must not be negative")
+	}
+	if cfg.UndoWindow < 0 {
+		l.fail("UNDO_WINDOW must not be negative")
+	}
+	if cfg.SyncWindow <= 0 {
+		l.fail("SYNC_WINDOW must be positive")
+	}
+	if cfg.DailyResetInterval <= 0 {
+		l.fail("DAILY_RESET_INTERVAL must be positive")
+	}
+	if !cfg.API.V1Sunset.IsZero() && !cfg.API.V1Sunset.After(cfg.API.V1DeprecatedAt) {
+		l.fail("API_V1_SUNSET must be after API_V1_DEPRECATED_AT")
+	}
+	if cfg.CompressMinBytes < 1 {
+		l.fail("COMPRESS_MIN_BYTES must be at least 1")
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		l.fail("MAX_BODY_BYTES must be positive")
+	}
+	if cfg.Storage.OperationTimeout <= 0 {
+		l.fail("DB_OPERATION_TIMEOUT must be positive")
+	}
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port < 1 || port > 65535 {
+		l.fail("PORT must be a number between 1 and 65535, got %q", cfg.Port)
+	}
+	if cfg.Cookie.MaxAge <= 0 {
+		l.fail("COOKIE_MAX_AGE must be positive")
+	}
+	if cfg.Cookie.Secret != "" && len(cfg.Cookie.Secret) < minCookieSecretLength {
+		l.fail("COOKIE_SECRET must be at least %d characters", minCookieSecretLength)
+	}
+	for _, secret := range cfg.Cookie.PreviousSecrets {
+		if len(secret) < minCookieSecretLength {
+			l.fail("COOKIE_PREVIOUS_SECRETS entries must be at least %d characters", minCookieSecretLength)
+			break
+		}
+	}
+	if cfg.Admin.Token != "" && len(cfg.Admin.Token) < minAdminTokenLength {
+		l.fail("ADMIN_TOKEN must be at least %d characters", minAdminTokenLength)
+	}
+	if cfg.Quota.MaxTodos < 0 || cfg.Quota.MaxWorkspaceTodos < 0 || cfg.Quota.MaxWorkspaces < 0 {
+		l.fail("QUOTA_MAX_TODOS, QUOTA_MAX_WORKSPACE_TODOS and QUOTA_MAX_WORKSPACES must not be negative")
+	}
+	if cfg.Quota.MaxAttachments < 0 || cfg.Quota.MaxAttachments > models.MaxAttachments {
+		l.fail("QUOTA_MAX_ATTACHMENTS must be between 0 and %d", models.MaxAttachments)
+	}
+	if cfg.Quota.MaxTags < 0 || cfg.Quota.MaxTags > models.MaxTags {
+		l.fail("QUOTA_MAX_TAGS must be between 0 and %d", models.MaxTags)
+	}
+	if cfg.Quota.WarnPercent < 0 || cfg.Quota.WarnPercent > 100 {
+		l.fail("QUOTA_WARN_PERCENT must be between 0 and 100")
+	}
+	switch cfg.Events.Backend {
+	case EventsNone:
+	case EventsServiceBus:
+		if cfg.Events.ServiceBusNamespace == "" || cfg.Events.ServiceBusEntity == "" {
+			l.fail("EVENTS_BACKEND=%s requires SERVICEBUS_NAMESPACE and SERVICEBUS_ENTITY", EventsServiceBus)
+		}
+	case EventsEventGrid:
+		if !strings.HasPrefix(cfg.Events.EventGridEndpoint, "https://") {
+			l.fail("EVENTS_BACKEND=%s requires EVENTGRID_ENDPOINT to be an https:// URL, got %q", EventsEventGrid, cfg.Events.EventGridEndpoint)
+		}
+	default:
+		l.fail("EVENTS_BACKEND must be one of %s, %s, %s; got %q", EventsNone, EventsServiceBus, EventsEventGrid, cfg.Events.Backend)
+	}
+	if cfg.Events.RelayInterval <= 0 {
+		l.fail("EVENTS_RELAY_INTERVAL must be positive")
+	}
+	switch cfg.Digest.Mailer {
+	case MailerNone:
+	case MailerSMTP:
+		if _, _, err := net.SplitHostPort(cfg.Digest.SMTPAddr); err != nil {
+			l.fail("DIGEST_MAILER=%s requires SMTP_ADDR as host:port, got %q", MailerSMTP, cfg.Digest.SMTPAddr)
+		}
+	case MailerACS:
+		if !strings.HasPrefix(cfg.Digest.ACSEndpoint, "https://") {
+			l.fail("DIGEST_MAILER=%s requires ACS_ENDPOINT to be an https:// URL, got %q", MailerACS, cfg.Digest.ACSEndpoint)
+		}
+	default:
+		l.fail("DIGEST_MAILER must be one of %s, %s, %s; got %q", MailerNone, MailerSMTP, MailerACS, cfg.Digest.Mailer)
+	}
+	if cfg.Digest.Mailer != MailerNone {
+		if addr, err := mail.ParseAddress(cfg.Digest.From); err != nil || addr.Address != cfg.Digest.From {
+			l.fail("DIGEST_MAILER=%s requires DIGEST_FROM to be an email address, got %q", cfg.Digest.Mailer, cfg.Digest.From)
+		}
+	}
+	if cfg.Digest.Hour < 0 || cfg.Digest.Hour > 23 {
+		l.fail("DIGEST_HOUR must be between 0 and 23")
+	}
+	if cfg.Digest.Interval <= 0 {
+		l.fail("DIGEST_INTERVAL must be positive")
+	}
+	if cfg.Slack.WebhookURL != "" {
+		// Notifications mention users, who link their accounts through
+		// slash commands
+		if cfg.Slack.SigningSecret == "" {
+			l.fail("SLACK_WEBHOOK_URL requires SLACK_SIGNING_SECRET")
+		}
+		if !strings.HasPrefix(cfg.Slack.WebhookURL, "https://") {
+			l.fail("SLACK_WEBHOOK_URL must be an https:// URL")
+		}
+	}
+	if cfg.Slack.OverdueInterval <= 0 {
+		l.fail("SLACK_OVERDUE_INTERVAL must be positive")
+	}
+	if cfg.Slack.RequestTolerance <= 0 {
+		l.fail("SLACK_REQUEST_TOLERANCE must be positive")
+	}
+	if cfg.Stale.After < 24*time.Hour {
+		l.fail("STALE_AFTER must be at least 24h")
+	}
+	if cfg.Stale.Hour < 0 || cfg.Stale.Hour > 23 {
+		l.fail("STALE_NUDGE_HOUR must be between 0 and 23")
+	}
+	if cfg.Stale.Interval <= 0 {
+		l.fail("STALE_NUDGE_INTERVAL must be positive")
+	}
+	if cfg.LinkPreview.Timeout <= 0 || cfg.LinkPreview.CacheTTL <= 0 {
+		l.fail("LINK_PREVIEW_TIMEOUT and LINK_PREVIEW_CACHE_TTL must be positive")
+	}
+	if cfg.LinkPreview.MaxBytes < 1024 {
+		l.fail("LINK_PREVIEW_MAX_BYTES must be at least 1024")
+	}
+	if cfg.CalDAV.PasswordTTL <= 0 {
+		l.fail("CALDAV_PASSWORD_TTL must be positive")
+	}
+	if cfg.Import.Google.Configured() && cfg.Import.Google.Secret == "" {
+		l.fail("GOOGLE_TASKS_CLIENT_ID requires GOOGLE_TASKS_CLIENT_SECRET")
+	}
+	if cfg.Import.Microsoft.Configured() && cfg.Import.Microsoft.Secret == "" {
+		l.fail("MICROSOFT_TODO_CLIENT_ID requires MICROSOFT_TODO_CLIENT_SECRET")
+	}
+	if cfg.Import.Google.Configured() || cfg.Import.Microsoft.Configured() {
+		u, err := url.Parse(cfg.Import.RedirectURL)
+		if err != nil || (u.Scheme != "https" && u.Hostname() != "localhost") || u.Host == "" {
+			l.fail("IMPORT_REDIRECT_URL must be an https:// URL, or http://localhost for development")
+		}
+	}
+	if cfg.Import.Timeout <= 0 {
+		l.fail("IMPORT_TIMEOUT must be positive")
+	}
+	if cfg.Feed.TTL <= 0 {
+		l.fail("FEED_TTL must be positive")
+	}
+	if cfg.Feed.Entries < 1 || cfg.Feed.Entries > 500 {
+		l.fail("FEED_ENTRIES must be between 1 and 500")
+	}
+	if cfg.Seed.Enabled {
+		// Seeded users sign in with the session cookies logged at startup
+		if cfg.Auth.Mode != AuthModeCookie {
+			l.fail("SEED is for development and requires AUTH_MODE=%s", AuthModeCookie)
+		}
+		if len(cfg.Seed.Users) == 0 {
+			l.fail("SEED_USERS must list at least one user")
+		}
+		for _, id := range cfg.Seed.Users {
+			if _, err := uuid.Parse(id); err != nil {
+				l.fail("SEED_USERS must list UUIDs, got %q", id)
+				break
+			}
+		}
+		if cfg.Seed.Todos < 1 || cfg.Seed.Todos > MaxSeedTodos {
+			l.fail("SEED_TODOS must be between 1 and %d", MaxSeedTodos)
+		}
+	}
+	if cfg.Cache.TTL <= 0 {
+		l.fail("CACHE_TTL must be positive")
+	}
+	if cfg.Retention.InactiveAfter < 0 {
+		l.fail("RETENTION_INACTIVE_AFTER must not be negative")
+	}
+	if cfg.Retention.InactiveAfter > 0 && cfg.Retention.InactiveAfter < cfg.Cookie.MaxAge {
+		l.fail("RETENTION_INACTIVE_AFTER (%s) must not be shorter than COOKIE_MAX_AGE (%s), or todos would be purged while still reachable",
+			cfg.Retention.InactiveAfter, cfg.Cookie.MaxAge)
+	}
+	if cfg.Retention.SweepInterval <= 0 {
+		l.fail("RETENTION_SWEEP_INTERVAL must be positive")
+	}
+	if cfg.Retention.TouchInterval <= 0 {
+		l.fail("RETENTION_TOUCH_INTERVAL must be positive")
+	}
+	for _, item := range l.list("RETENTION_RULES", nil) {
+		rule, err := ParseRetentionRule(item)
+		if err != nil {
+			l.fail("RETENTION_RULES: %v", err)
+			continue
+		}
+		if slices.ContainsFunc(cfg.Retention.Rules, func(r RetentionRule) bool { return r.Kind == rule.Kind }) {
+			l.fail("RETENTION_RULES: %s is listed more than once", rule.Kind)
+			continue
+		}
+		cfg.Retention.Rules = append(cfg.Retention.Rules, rule)
+	}
+	if v := strings.TrimSpace(l.getenv("RATE_LIMIT")); v != "off" {
+		if v == "" {
+			v = "600/1m"
+		}
+		rate, err := ParseRate(v)
+		if err != nil {
+			l.fail("RATE_LIMIT: %v, or off", err)
+		}
+		cfg.RateLimit.Default = rate
+		for _, item := range l.list("RATE_LIMIT_ROUTES", defaultRouteRates) {
+			if item == "off" {
+				break
+			}
+			route, err := ParseRouteRate(item)
+			if err != nil {
+				l.fail("RATE_LIMIT_ROUTES: %v", err)
+				continue
+			}
+			cfg.RateLimit.Routes = append(cfg.RateLimit.Routes, route)
+		}
+	}
+	for _, item := range l.list("SCHEMA_VALIDATION", nil) {
+		if item == "off" {
+			break
+		}
+		if item == "all" {
+			cfg.API.SchemaValidation.All = true
+			continue
+		}
+		route, err := ParseRoutePattern(item)
+		if err != nil {
+			l.fail("SCHEMA_VALIDATION: %v, all or off", err)
+			continue
+		}
+		cfg.API.SchemaValidation.Routes = append(cfg.API.SchemaValidation.Routes, route)
+	}
+	if cfg.Audit.Retention <= 0 {
+		l.fail("AUDIT_RETENTION must be positive")
+	}
+	if cfg.Audit.MaxBodyBytes < 0 {
+		l.fail("AUDIT_MAX_BODY_BYTES must not be negative")
+	}
+	switch keys := l.keys("ENCRYPTION_KEY"); {
+	case len(keys) > 1:
+		l.fail("ENCRYPTION_KEY must be a single key; list older keys in ENCRYPTION_PREVIOUS_KEYS")
+	case len(keys) == 1:
+		cfg.Encryption.Key = keys[0]
+	}
+	cfg.IPFilter.Deny = l.networks("IP_DENY_LIST")
+	cfg.IPFilter.Allow = l.networks("IP_ALLOW_LIST")
+	cfg.IPFilter.TrustedProxies = l.list("TRUSTED_PROXIES", nil)
+	for _, proxy := range cfg.IPFilter.TrustedProxies {
+		if _, err := ParseCIDR(proxy); err != nil {
+			l.fail("TRUSTED_PROXIES: %v", err)
+		}
+	}
+	switch cfg.Tenancy.Mode {
+	case TenantModeOff:
+	case TenantModeSubdomain, TenantModeHeader:
+		if len(cfg.Tenancy.Tenants) == 0 {
+			l.fail("TENANTS must list the tenants when TENANT_MODE is %s", cfg.Tenancy.Mode)
+		}
+		for _, tenant := range cfg.Tenancy.Tenants {
+			if err := ValidateTenantID(tenant); err != nil {
+				l.fail("TENANTS: %v", err)
+			}
+		}
+		if cfg.Tenancy.Mode == TenantModeSubdomain && cfg.Tenancy.Domain == "" {
+			l.fail("TENANT_DOMAIN must be set when TENANT_MODE is subdomain")
+		}
+		switch cfg.Tenancy.Isolation {
+		case TenantIsolationDatabase:
+		case TenantIsolationPrefix:
+			if cfg.Storage.Backend != BackendMongo {
+				l.fail("TENANT_ISOLATION=prefix needs STORAGE_BACKEND=mongo")
+			}
+		default:
+			l.fail("TENANT_ISOLATION must be %q or %q, got %q", TenantIsolationDatabase, TenantIsolationPrefix, cfg.Tenancy.Isolation)
+		}
+	default:
+		l.fail("TENANT_MODE must be %q, %q or %q, got %q", TenantModeOff, TenantModeSubdomain, TenantModeHeader, cfg.Tenancy.Mode)
+	}
+	if v := strings.TrimSpace(l.getenv("IP_SESSION_LIMIT")); v != "off" {
+		if v == "" {
+			v = "60/1h"
+		}
+		rate, err := ParseRate(v)
+		if err != nil {
+			l.fail("IP_SESSION_LIMIT: %v, or off", err)
+		}
+		cfg.IPFilter.NewSessions = rate
+	}
+	if len(cfg.CORS.AllowOrigins) == 0 {
+		l.fail("CORS_ALLOW_ORIGINS must list at least one origin")
+	}
+	for _, origin := range cfg.CORS.AllowOrigins {
+		if err := ValidateOriginPattern(origin); err != nil {
+			l.fail("CORS_ALLOW_ORIGINS: %v", err)
+		}
+	}
+
+	if len(l.errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %w", errors.Join(l.errs...))
+	}
+	return cfg, nil
+}
+
+// loader reads environment variables and collects validation errors
+type loader struct {
+	getenv func(string) string
+	errs   []error
+}
+
+func (l *loader) fail(format string, args ...any) {
+	l.errs = append(l.errs, fmt.Errorf(format, args...))
+}
+
+func (l *loader) string(key, def string) string {
+	if v := strings.TrimSpace(l.getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+func (l *loader) required(key string) string {
+	v := strings.TrimSpace(l.getenv(key))
+	if v == "" {
+		l.fail("%s environment variable is not set", key)
+	}
+	return v
+}
+
+func (l *loader) bool(key string, def bool) bool {
+	v := strings.TrimSpace(l.getenv(key))
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		l.fail("%s must be a boolean, got %q", key, v)
+		return def
+	}
+	return b
+}
+
+func (l *loader) int(key string, def int) int {
+	v := strings.TrimSpace(l.getenv(key))
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		l.fail("%s must be an integer, got %q", key, v)
+		return def
+	}
+	return n
+}
+
+func (l *loader) duration(key string, def time.Duration) time.Duration {
+	v := strings.TrimSpace(l.getenv(key))
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		l.fail("%s must be a duration like 30s or 24h, got %q", key, v)
+		return def
+	}
+	return d
+}
+
+func (l *loader) weekday(key string, def time.Weekday) time.Weekday {
+	v := strings.TrimSpace(l.getenv(key))
+	if v == "" {
+		return def
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(v, day.String()) {
+			return day
+		}
+	}
+	l.fail("%s must be a day of the week like monday, got %q", key, v)
+	return def
+}
+
+// date reads a YYYY-MM-DD date as the start of that day in UTC
+func (l *loader) date(key string, def time.Time) time.Time {
+	v := strings.TrimSpace(l.getenv(key))
+	if v == "" {
+		return def
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		l.fail("%s must be a date like 2027-04-16, got %q", key, v)
+		return def
+	}
+	return t
+}
+
+// networks reads a comma-separated list of IP addresses and CIDR networks
+func (l *loader) networks(key string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, item := range l.list(key, nil) {
+		n, err := ParseCIDR(item)
+		if err != nil {
+			l.fail("%s: %v", key, err)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// keys reads a comma-separated list of base64-encoded encryption keys
+func (l *loader) keys(key string) [][]byte {
+	var keys [][]byte
+	for _, item := range l.list(key, nil) {
+		k, err := base64.StdEncoding.DecodeString(item)
+		if err != nil || len(k) != fieldcrypt.KeySize {
+			l.fail("%s must be base64-encoded %d-byte keys, as made by openssl rand -base64 %d", key, fieldcrypt.KeySize, fieldcrypt.KeySize)
+			return nil
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (l *loader) list(key string, def []string) []string {
+	v := strings.TrimSpace(l.getenv(key))
+	if v == "" {
+		return def
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
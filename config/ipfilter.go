@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IPFilterConfig blocks client addresses and throttles the identities they
+// may create
+type IPFilterConfig struct {
+	// Deny lists addresses whose requests are refused
+	Deny []*net.IPNet
+	// Allow lists addresses that are never refused or throttled, even if
+	// Deny covers them, such as an office's NAT or a load test's agents
+	Allow []*net.IPNet
+	// NewSessions is how many identities one address may be given; zero
+	// Requests turns the throttle off
+	NewSessions Rate
+	// TrustedProxies are the proxies whose X-Forwarded-For header names the
+	// client; with none the header is ignored
+	TrustedProxies []string
+}
+
+// Denied reports whether requests from ip are refused
+func (c IPFilterConfig) Denied(ip net.IP) bool {
+	return containsIP(c.Deny, ip) && !containsIP(c.Allow, ip)
+}
+
+// Allowed reports whether ip is exempt from refusal and throttling
+func (c IPFilterConfig) Allowed(ip net.IP) bool {
+	return containsIP(c.Allow, ip)
+}
+
+// containsIP reports whether any of nets contains ip
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCIDR parses a network like 203.0.113.0/24 or 2001:db8::/32, or a
+// single address, which is taken as a network of its own
+func ParseCIDR(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("%q must be an IP address or a CIDR network like 203.0.113.0/24", s)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("%q must be an IP address or a CIDR network like 203.0.113.0/24", s)
+	}
+	return network, nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"net"
+	"testing"
+)
+
+func TestParseCIDR(t *testing.T) {
+	for _, s := range []string{"not an address", "203.0.113.0/33", "203.0.113"} {
+		if _, err := ParseCIDR(s); err == nil {
+			t.Errorf("ParseCIDR(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestIPFilterDenied(t *testing.T) {
+	networks := func(cidrs ...string) []*net.IPNet {
+		nets := make([]*net.IPNet, len(cidrs))
+		for i, s := range cidrs {
+			n, err := ParseCIDR(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nets[i] = n
+		}
+		return nets
+	}
+	cfg := IPFilterConfig{
+		Deny:  networks("198.51.100.0/24", "2001:db8::/32", "192.0.2.1"),
+		Allow: networks("198.51.100.7"),
+	}
+	for ip, denied := range map[string]bool{
+		"198.51.100.0":        true,
+		"198.51.100.255":      true,
+		"198.51.101.0":        false,
+		"198.51.100.7":        false,
+		"::ffff:198.51.100.8": true,
+		"2001:db8:ffff::1":    true,
+		"2001:db9::1":         false,
+		"192.0.2.1":           true,
+		"192.0.2.2":           false,
+		"203.0.113.1":         false,
+	} {
+		if got := cfg.Denied(net.ParseIP(ip)); got != denied {
+			t.Errorf("Denied(%s) = %v, want %v", ip, got, denied)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ValidateOriginPattern checks that an allowed-origin entry is a bare
+// scheme://host[:port] origin, optionally with a single leading "*." label
+func ValidateOriginPattern(pattern string) error {
+	if pattern == "*" {
+		return fmt.Errorf("%q is not allowed because the API sends credentials; list origins explicitly", pattern)
+	}
+
+	u, err := url.Parse(strings.Replace(pattern, "*.", "wildcard.", 1))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q is not a valid origin (expected scheme://host[:port])", pattern)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q must use http or https", pattern)
+	}
+	if u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("%q must not contain a path, query, fragment, or credentials", pattern)
+	}
+	if strings.Count(pattern, "*") > 1 || (strings.Contains(pattern, "*") && !strings.Contains(pattern, "://*.")) {
+		return fmt.Errorf("%q may only use a wildcard as the leftmost host label, e.g. https://*.example.com", pattern)
+	}
+	return nil
+}
+
+// MatchOrigin reports whether a request origin matches an allowed-origin
+// pattern. A wildcard label matches one or more subdomain labels but never the
+// bare parent domain, so https://*.example.com does not match https://example.com.
+func MatchOrigin(pattern, origin string) bool {
+	if !strings.Contains(pattern, "*") {
+		return strings.EqualFold(pattern, origin)
+	}
+
+	scheme, hostPattern, ok := strings.Cut(pattern, "://*.")
+	if !ok {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || !strings.EqualFold(u.Scheme, scheme) || u.Host == "" {
+		return false
+	}
+
+	host := strings.ToLower(u.Host)
+	suffix := "." + strings.ToLower(hostPattern)
+	if !strings.HasSuffix(host, suffix) {
+		return false
+	}
+
+	// The wildcard must cover at least one non-empty label
+	sub := strings.TrimSuffix(host, suffix)
+	return sub != "" && !strings.HasPrefix(sub, ".") && !strings.HasSuffix(sub, ".")
+}
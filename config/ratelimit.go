@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate is a budget of requests per window
+type Rate struct {
+	Requests int
+	Per      time.Duration
+}
+
+// String formats the rate as it's configured, e.g. 60/1m0s
+func (r Rate) String() string {
+	return strconv.Itoa(r.Requests) + "/" + r.Per.String()
+}
+
+// RouteRate is the budget of the requests matching a route pattern, on top
+// of the default one
+type RouteRate struct {
+	// Method is an HTTP method, or * for any
+	Method string
+	// Path is matched against the route a request was routed to, such as
+	// /api/v1/workspaces/:workspace_id/todos, with path.Match; * stands for
+	// one segment
+	Path string
+	// Param, when set, limits the pattern to requests carrying that query
+	// parameter, e.g. search for searches of GET /todos
+	Param string
+	Rate  Rate
+}
+
+// Pattern formats the route pattern as it's configured
+func (r RouteRate) Pattern() string {
+	pattern := r.Method + " " + r.Path
+	if r.Param != "" {
+		pattern += "?" + r.Param
+	}
+	return pattern
+}
+
+// Matches reports whether a request to the route is limited by r
+func (r RouteRate) Matches(method, route string, hasParam func(string) bool) bool {
+	if r.Method != "*" && r.Method != method {
+		return false
+	}
+	if ok, _ := path.Match(r.Path, route); !ok {
+		return false
+	}
+	return r.Param == "" || hasParam(r.Param)
+}
+
+// RateLimitConfig sets how many requests each user, or each address for
+// requests without a user, may make
+type RateLimitConfig struct {
+	// Default applies to every request; zero Requests turns limiting off
+	Default Rate
+	// Routes are stricter, or looser, budgets of some routes. A request
+	// counts against the first that matches as well as the default.
+	Routes []RouteRate
+}
+
+// Enabled reports whether requests are limited
+func (c RateLimitConfig) Enabled() bool {
+	return c.Default.Requests > 0
+}
+
+// defaultRouteRates are the route budgets used when none are configured:
+// searches and sync pushes cost more than plain listings
+var defaultRouteRates = []string{
+	"GET /api/*/todos?q=60/1m",
+	"GET /api/*/workspaces/*/todos?q=60/1m",
+	"POST /api/*/sync=60/1m",
+	"POST /api/*/workspaces/*/sync=60/1m",
+}
+
+// ParseRate parses a budget like 100/1m: a number of requests, a slash and
+// a window in time.ParseDuration syntax
+func ParseRate(s string) (Rate, error) {
+	requests, per, ok := strings.Cut(strings.TrimSpace(s), "/")
+	n, err := strconv.Atoi(requests)
+	if !ok || err != nil || n < 1 {
+		return Rate{}, fmt.Errorf("%q must be a number of requests per window like 100/1m", s)
+	}
+	window, err := time.ParseDuration(per)
+	if err != nil || window < time.Second {
+		return Rate{}, fmt.Errorf("%q must have a window of at least 1s, like 100/1m", s)
+	}
+	return Rate{Requests: n, Per: window}, nil
+}
+
+// ParseRouteRate parses a route budget like "GET /api/*/todos?q=60/1m"
+func ParseRouteRate(s string) (RouteRate, error) {
+	i := strings.LastIndex(s, "=")
+	if i < 0 {
+		return RouteRate{}, fmt.Errorf("%q must be a method, a route and a rate, like GET /api/*/todos?q=60/1m", s)
+	}
+	rate, err := ParseRate(s[i+1:])
+	if err != nil {
+		return RouteRate{}, err
+	}
+	method, route, ok := strings.Cut(strings.TrimSpace(s[:i]), " ")
+	route = strings.TrimSpace(route)
+	if !ok || method == "" || !strings.HasPrefix(route, "/") {
+		return RouteRate{}, fmt.Errorf("%q must be a method, a route and a rate, like GET /api/*/todos?q=60/1m", s)
+	}
+	r := RouteRate{Method: strings.ToUpper(method), Rate: rate}
+	r.Path, r.Param, _ = strings.Cut(route, "?")
+	if _, err := path.Match(r.Path, ""); err != nil {
+		return RouteRate{}, fmt.Errorf("%q has a malformed route pattern", s)
+	}
+	return r, nil
+}
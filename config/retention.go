@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// What retention rules purge
+const (
+	// RetentionCompleted deletes personal todos completed longer ago than
+	// the rule's age
+	RetentionCompleted = "completed"
+	// RetentionRevisions drops the revisions of personal todos recorded
+	// longer ago than the rule's age
+	RetentionRevisions = "revisions"
+)
+
+// RetentionRule purges one kind of data once it's older than After
+type RetentionRule struct {
+	Kind  string
+	After time.Duration
+}
+
+// String returns the rule as it's configured, e.g. completed=4320h
+func (r RetentionRule) String() string {
+	return r.Kind + "=" + r.After.String()
+}
+
+// ParseRetentionRule parses a rule like "completed=4320h"
+func ParseRetentionRule(s string) (RetentionRule, error) {
+	kind, age, ok := strings.Cut(s, "=")
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if !ok || (kind != RetentionCompleted && kind != RetentionRevisions) {
+		return RetentionRule{}, fmt.Errorf("%q must be %s or %s and an age, like completed=4320h", s, RetentionCompleted, RetentionRevisions)
+	}
+	after, err := time.ParseDuration(strings.TrimSpace(age))
+	if err != nil || after < time.Hour {
+		return RetentionRule{}, fmt.Errorf("%q must have an age of at least 1h, like completed=4320h", s)
+	}
+	return RetentionRule{Kind: kind, After: after}, nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// RoutePattern picks routes the way RouteRate does: a method, or * for
+// any, and a path.Match pattern of the route, such as PUT /api/*/todos/*
+type RoutePattern struct {
+	Method string
+	Path   string
+}
+
+// Matches reports whether the route is picked by p
+func (p RoutePattern) Matches(method, route string) bool {
+	if p.Method != "*" && p.Method != method {
+		return false
+	}
+	ok, _ := path.Match(p.Path, route)
+	return ok
+}
+
+// ParseRoutePattern parses a route pattern like "PUT /api/*/todos/*"
+func ParseRoutePattern(s string) (RoutePattern, error) {
+	method, route, ok := strings.Cut(strings.TrimSpace(s), " ")
+	route = strings.TrimSpace(route)
+	if !ok || method == "" || !strings.HasPrefix(route, "/") {
+		return RoutePattern{}, fmt.Errorf("%q must be a method and a route, like PUT /api/*/todos/*", s)
+	}
+	if _, err := path.Match(route, ""); err != nil {
+		return RoutePattern{}, fmt.Errorf("%q has a malformed route pattern", s)
+	}
+	return RoutePattern{Method: strings.ToUpper(method), Path: route}, nil
+}
+
+// SchemaValidationConfig picks the routes whose request bodies are checked
+// against their published JSON Schema before reaching the handler
+type SchemaValidationConfig struct {
+	// All checks every route that has a schema
+	All bool
+	// Routes are the routes checked when All isn't set
+	Routes []RoutePattern
+}
+
+// Validates reports whether requests to the route are checked
+func (c SchemaValidationConfig) Validates(method, route string) bool {
+	if c.All {
+		return true
+	}
+	for _, p := range c.Routes {
+		if p.Matches(method, route) {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled reports whether any route is checked
+func (c SchemaValidationConfig) Enabled() bool {
+	return c.All || len(c.Routes) > 0
+}
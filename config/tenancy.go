@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// How requests name their tenant
+const (
+	// TenantModeOff serves a single organization
+	TenantModeOff = "off"
+	// TenantModeSubdomain reads the tenant from the first label of the
+	// host, e.g. acme of acme.todos.example.com
+	TenantModeSubdomain = "subdomain"
+	// TenantModeHeader reads the tenant from a request header
+	TenantModeHeader = "header"
+)
+
+// How tenants' data is kept apart
+const (
+	// TenantIsolationDatabase gives each tenant its own MongoDB database,
+	// PostgreSQL schema or SQLite file
+	TenantIsolationDatabase = "database"
+	// TenantIsolationPrefix keeps every tenant in one MongoDB database, with
+	// the tenant's name in front of its collections' names
+	TenantIsolationPrefix = "prefix"
+)
+
+// tenantID is the form of a tenant's name. It's used in database, schema,
+// file and collection names, so it's kept to lowercase letters, digits and
+// dashes.
+var tenantID = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,30}[a-z0-9]$|^[a-z0-9]$`)
+
+// TenancyConfig hosts several organizations on one deployment, each with
+// its own data
+type TenancyConfig struct {
+	Mode string
+	// Header names the tenant in header mode
+	Header string
+	// Domain is the domain tenants' subdomains belong to in subdomain mode,
+	// e.g. todos.example.com
+	Domain string
+	// Tenants are the organizations served; requests naming any other are
+	// refused
+	Tenants []string
+	// Isolation is how tenants' data is kept apart
+	Isolation string
+}
+
+// Enabled reports whether requests are served per tenant
+func (c TenancyConfig) Enabled() bool {
+	return c.Mode != TenantModeOff
+}
+
+// Known reports whether tenant is one of those served
+func (c TenancyConfig) Known(tenant string) bool {
+	return slices.Contains(c.Tenants, tenant)
+}
+
+// ValidateTenantID checks the form of a tenant's name
+func ValidateTenantID(tenant string) error {
+	if !tenantID.MatchString(tenant) {
+		return fmt.Errorf("tenant %q must be 1 to 32 lowercase letters, digits and dashes, not starting or ending with a dash", tenant)
+	}
+	return nil
+}
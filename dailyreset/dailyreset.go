@@ -0,0 +1,167 @@
+// Package dailyreset runs the daily resets users can set up: at a time of
+// their choosing, open todos due in the day before roll over to the next
+// day and completed todos are archived.
+package dailyreset
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"todo-api/models"
+	"todo-api/repository"
+)
+
+// userBatchSize is how many users are read per query while looking for
+// resets that are due
+const userBatchSize = 100
+
+// resetTimeout bounds the work done for one user's reset
+const resetTimeout = 30 * time.Second
+
+// EditedBy is who the revisions of a reset's changes name as their editor
+const EditedBy = "daily_reset"
+
+// Saver applies a change to a todo and saves it, recording a revision by
+// editedBy and publishing the update, the way editing it through the API
+// does. If the todo changed since it was read, it's read again and change
+// applied afresh. change returns false when the todo doesn't need
+// changing; saved is false then, and when the todo was deleted.
+type Saver interface {
+	SaveTodo(ctx context.Context, todo *models.Todo, editedBy string, change func(*models.Todo) bool) (saved bool, err error)
+}
+
+// Scheduler runs daily resets as they fall due, at each user's reset time
+// in their time zone. Each reset is claimed before it runs, so several
+// instances can run a Scheduler without resetting twice. Only the latest
+// reset is run, so days missed while no instance was up aren't caught up.
+type Scheduler struct {
+	users    repository.UserRepository
+	todos    repository.TodoRepository
+	saver    Saver
+	interval time.Duration
+}
+
+// NewScheduler creates a Scheduler that checks every interval for resets
+// that are due, saving changes through saver
+func NewScheduler(users repository.UserRepository, todos repository.TodoRepository, saver Saver, interval time.Duration) *Scheduler {
+	return &Scheduler{users: users, todos: todos, saver: saver, interval: interval}
+}
+
+// Run runs due resets on every tick until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		changed, err := s.RunDue(ctx, time.Now())
+		if err != nil {
+			log.Println("Running daily resets failed:", err)
+		} else if changed > 0 {
+			log.Printf("Daily resets changed %d todos", changed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs every reset due by now that hasn't run yet and returns how
+// many todos it changed. A reset that fails is logged and skipped; err is
+// only set when users can't be listed.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) (int, error) {
+	changed := 0
+	after := ""
+	for {
+		users, err := s.users.List(ctx, after, userBatchSize)
+		if err != nil {
+			return changed, err
+		}
+		for _, user := range users {
+			if user.Settings.DailyReset == "" {
+				continue
+			}
+			n, err := s.reset(ctx, &user, now)
+			changed += n
+			if err != nil {
+				log.Printf("Failed to run daily reset of user %s: %v", user.ID, err)
+			}
+		}
+		if len(users) < userBatchSize {
+			return changed, nil
+		}
+		after = users[len(users)-1].ID
+	}
+}
+
+// reset runs the user's latest reset if no instance has yet, returning how
+// many of their personal todos it changed
+func (s *Scheduler) reset(ctx context.Context, user *models.User, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, resetTimeout)
+	defer cancel()
+
+	due, ok := Due(user.Settings.DailyReset, now.In(user.Settings.Location()))
+	if !ok {
+		return 0, nil
+	}
+	claimed, err := s.users.ClaimDailyReset(ctx, user.ID, due)
+	if err != nil || !claimed {
+		return 0, err
+	}
+
+	todos, err := s.todos.List(ctx, repository.Personal(user.ID))
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for i := range todos {
+		saved, err := s.saver.SaveTodo(ctx, &todos[i], EditedBy, func(todo *models.Todo) bool {
+			return Apply(todo, due)
+		})
+		if err != nil {
+			return changed, err
+		}
+		if saved {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// Due returns when the latest reset at clock, a time of day as
+// models.DailyResetLayout writes it, was due at or before now, in now's
+// time zone. ok is false if clock isn't a valid time.
+func Due(clock string, now time.Time) (due time.Time, ok bool) {
+	t, err := time.Parse(models.DailyResetLayout, clock)
+	if err != nil {
+		return time.Time{}, false
+	}
+	due = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if due.After(now) {
+		due = due.AddDate(0, 0, -1)
+	}
+	return due, true
+}
+
+// Apply makes the changes of the reset due at at to todo and reports
+// whether there were any. A completed todo is archived. An open one due in
+// the day up to at is moved a day later, keeping its time of day in at's
+// time zone.
+func Apply(todo *models.Todo, at time.Time) bool {
+	switch {
+	case todo.Completed:
+		if todo.Archived() {
+			return false
+		}
+		todo.SetArchived(true, at.UTC())
+		return true
+	case todo.DueDate != nil && !todo.DueDate.Before(at.AddDate(0, 0, -1)) && todo.DueDate.Before(at):
+		moved := todo.DueDate.In(at.Location()).AddDate(0, 0, 1).UTC()
+		todo.DueDate = &moved
+		return true
+	}
+	return false
+}
@@ -2,33 +2,57 @@ package database
 
 import (
 	"context"
-	"fmt"
 	"log"
-	"os"
-	"time"
+
+	"todo-api/config"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var DB *mongo.Database
-
-func Connect() {
-	mongoURI := os.Getenv("MONGODB_URI")
-	databaseName := os.Getenv("DATABASE_NAME")
-
-	if mongoURI == "" {
-		log.Fatal("MONGODB_URI environment variable is not set")
-	}
+// Collections are the handles of the collections the API stores its data
+// in. They're resolved once at startup and handed to the repositories, so
+// collection names live in one place.
+type Collections struct {
+	Todos        *mongo.Collection
+	Users        *mongo.Collection
+	Sessions     *mongo.Collection
+	Workspaces   *mongo.Collection
+	Shares       *mongo.Collection
+	PublicLinks  *mongo.Collection
+	CustomFields *mongo.Collection
+	SavedFilters *mongo.Collection
+	Revisions    *mongo.Collection
+	Outbox       *mongo.Collection
+	Tombstones   *mongo.Collection
+	SlackLinks   *mongo.Collection
+	AuditLog     *mongo.Collection
+	// Migrations records the startup migrations applied to the database
+	Migrations *mongo.Collection
+}
 
-	if databaseName == "" {
-		log.Fatal("DATABASE_NAME environment variable is not set")
-	}
+// Database is a connected MongoDB database and its collections
+type Database struct {
+	Collections
+	db *mongo.Database
+	// todos is the name of the todos collection
+	todos string
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Connect connects to the configured database and checks it can be reached,
+// exiting if it can't. Todos are stored in the collection named by
+// COLLECTION_NAME.
+func Connect(cfg config.MongoConfig) *Database {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Pool.ConnectTimeout+cfg.Pool.ServerSelectionTimeout)
 	defer cancel()
 
-	clientOptions := options.Client().ApplyURI(mongoURI)
+	clientOptions := options.Client().
+		ApplyURI(cfg.URI).
+		SetMaxPoolSize(cfg.Pool.MaxPoolSize).
+		SetMinPoolSize(cfg.Pool.MinPoolSize).
+		SetMaxConnIdleTime(cfg.Pool.MaxConnIdleTime).
+		SetConnectTimeout(cfg.Pool.ConnectTimeout).
+		SetServerSelectionTimeout(cfg.Pool.ServerSelectionTimeout)
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		log.Fatal("Failed to connect to MongoDB:", err)
@@ -40,10 +64,52 @@ func Connect() {
 		log.Fatal("Failed to ping MongoDB:", err)
 	}
 
-	DB = client.Database(databaseName)
-	fmt.Println("Successfully connected to Azure Cosmos DB!")
+	log.Printf("Connected to MongoDB database %s", cfg.Database)
+	return open(client.Database(cfg.Database), "", cfg.Collection)
+}
+
+// Tenant returns the database and collections of a tenant, sharing this
+// database's client. With database isolation the tenant has a database of
+// its own, named after this one and the tenant, e.g. todos-acme; with prefix
+// isolation its collections are in this database, named with the tenant in
+// front, e.g. acme_todos.
+func (d *Database) Tenant(tenant, isolation string) *Database {
+	if isolation == config.TenantIsolationPrefix {
+		return open(d.db, tenant+"_", d.todos)
+	}
+	return open(d.db.Client().Database(d.db.Name()+"-"+tenant), "", d.todos)
+}
+
+// open resolves the collections of db, with prefix in front of their names
+func open(db *mongo.Database, prefix, todos string) *Database {
+	return &Database{
+		db:    db,
+		todos: todos,
+		Collections: Collections{
+			Todos:        db.Collection(prefix + todos),
+			Users:        db.Collection(prefix + "users"),
+			Sessions:     db.Collection(prefix + "sessions"),
+			Workspaces:   db.Collection(prefix + "workspaces"),
+			Shares:       db.Collection(prefix + "shares"),
+			PublicLinks:  db.Collection(prefix + "public_links"),
+			CustomFields: db.Collection(prefix + "custom_fields"),
+			SavedFilters: db.Collection(prefix + "saved_filters"),
+			Revisions:    db.Collection(prefix + "revisions"),
+			Outbox:       db.Collection(prefix + "outbox"),
+			Tombstones:   db.Collection(prefix + "tombstones"),
+			SlackLinks:   db.Collection(prefix + "slack_links"),
+			AuditLog:     db.Collection(prefix + "audit_log"),
+			Migrations:   db.Collection(prefix + "migrations"),
+		},
+	}
+}
+
+// Client returns the client the database was opened with
+func (d *Database) Client() *mongo.Client {
+	return d.db.Client()
 }
 
-func GetCollection(collectionName string) *mongo.Collection {
-	return DB.Collection(collectionName)
+// Ping checks that the database is reachable
+func (d *Database) Ping(ctx context.Context) error {
+	return d.db.Client().Ping(ctx, nil)
 }
@@ -0,0 +1,126 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"todo-api/azure"
+)
+
+const (
+	// acsResource is the token audience for Communication Services
+	acsResource = "https://communication.azure.com"
+	// acsAPIVersion is the Email REST API version used
+	acsAPIVersion = "2023-03-31"
+)
+
+// ACS sends email through Azure Communication Services. It signs requests
+// with the resource's access key when one is given, else authenticates with
+// the app's managed identity.
+type ACS struct {
+	endpoint string
+	key      string
+	from     string
+	identity *azure.ManagedIdentity
+	client   *http.Client
+}
+
+// NewACS creates a mailer for the Communication Services resource at
+// endpoint, sending from the given verified sender address
+func NewACS(endpoint, key, from string, identity *azure.ManagedIdentity) *ACS {
+	return &ACS{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		key:      key,
+		from:     from,
+		identity: identity,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// acsEmail is the body of a send request
+type acsEmail struct {
+	SenderAddress string `json:"senderAddress"`
+	Content       struct {
+		Subject   string `json:"subject"`
+		PlainText string `json:"plainText"`
+		HTML      string `json:"html"`
+	} `json:"content"`
+	Recipients struct {
+		To []acsAddress `json:"to"`
+	} `json:"recipients"`
+}
+
+type acsAddress struct {
+	Address string `json:"address"`
+}
+
+// Send queues msg for delivery. Communication Services sends it
+// asynchronously, so a bounce isn't reported here.
+func (a *ACS) Send(ctx context.Context, msg Message) error {
+	var email acsEmail
+	email.SenderAddress = a.from
+	email.Content.Subject = msg.Subject
+	email.Content.PlainText = msg.Text
+	email.Content.HTML = msg.HTML
+	email.Recipients.To = []acsAddress{{Address: msg.To}}
+	payload, err := json.Marshal(email)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+"/emails:send?api-version="+acsAPIVersion, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.key != "" {
+		if err := a.sign(req, payload); err != nil {
+			return err
+		}
+	} else {
+		accessToken, err := a.identity.Token(ctx, acsResource)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("Communication Services returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sign adds the HMAC-SHA256 signature Communication Services expects for
+// requests made with an access key
+func (a *ACS) sign(req *http.Request, payload []byte) error {
+	key, err := base64.StdEncoding.DecodeString(a.key)
+	if err != nil {
+		return fmt.Errorf("ACS_KEY is not base64: %w", err)
+	}
+	hash := sha256.Sum256(payload)
+	contentHash := base64.StdEncoding.EncodeToString(hash[:])
+	date := time.Now().UTC().Format(http.TimeFormat)
+
+	toSign := req.Method + "\n" + req.URL.RequestURI() + "\n" + date + ";" + req.URL.Host + ";" + contentHash
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(toSign))
+
+	req.Header.Set("x-ms-date", date)
+	req.Header.Set("x-ms-content-sha256", contentHash)
+	req.Header.Set("Authorization", "HMAC-SHA256 SignedHeaders=x-ms-date;host;x-ms-content-sha256&Signature="+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return nil
+}
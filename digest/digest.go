@@ -0,0 +1,125 @@
+// Package digest sends users who opt in a daily or weekly summary of their
+// overdue todos and those due soon, and notifies users by email
+package digest
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	htmltemplate "html/template"
+	"sort"
+	"text/template"
+	"time"
+
+	"todo-api/duedate"
+	"todo-api/models"
+	"todo-api/notify"
+)
+
+// Message is one email to send
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	textTemplate = template.Must(template.ParseFS(templateFS, "templates/digest.txt"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/digest.html"))
+)
+
+// Digest is what one email summarizes
+type Digest struct {
+	// Date is the day the digest is for, in the user's time zone
+	Date   time.Time
+	Weekly bool
+	// Overdue are the open todos due before Date, and DueSoon those due on
+	// Date, or in the week from it for weekly digests
+	Overdue []Item
+	DueSoon []Item
+}
+
+// Item is one todo in a digest
+type Item struct {
+	Title    string
+	Due      time.Time
+	Priority models.Priority
+}
+
+// When formats the item's due date, with the time if it has one
+func (i Item) When() string {
+	if i.Due.Equal(duedate.StartOfDay(i.Due)) {
+		return i.Due.Format("Mon 2 Jan")
+	}
+	return i.Due.Format("Mon 2 Jan 15:04")
+}
+
+// Build picks the todos a digest covers from the user's todos. Completed
+// and snoozed todos are left out.
+func Build(todos []models.Todo, frequency string, now time.Time, loc *time.Location) *Digest {
+	start := duedate.StartOfDay(now.In(loc))
+	d := &Digest{Date: start, Weekly: frequency == models.DigestWeekly}
+	end := start.AddDate(0, 0, 1)
+	if d.Weekly {
+		end = start.AddDate(0, 0, 7)
+	}
+	for _, todo := range todos {
+		if todo.Completed || todo.Snoozed(now) || todo.DueDate == nil || !todo.DueDate.Before(end) {
+			continue
+		}
+		item := Item{Title: todo.Title, Due: todo.DueDate.In(loc), Priority: todo.Priority}
+		if todo.DueDate.Before(start) {
+			d.Overdue = append(d.Overdue, item)
+		} else {
+			d.DueSoon = append(d.DueSoon, item)
+		}
+	}
+	sortItems(d.Overdue)
+	sortItems(d.DueSoon)
+	return d
+}
+
+// Empty reports whether the digest has nothing to tell
+func (d *Digest) Empty() bool {
+	return len(d.Overdue) == 0 && len(d.DueSoon) == 0
+}
+
+// Notification renders the digest as a notification
+func (d *Digest) Notification() (notify.Notification, error) {
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, d); err != nil {
+		return notify.Notification{}, err
+	}
+	if err := htmlTemplate.Execute(&html, d); err != nil {
+		return notify.Notification{}, err
+	}
+	return notify.Notification{Kind: models.NotifyDigest, Subject: d.Subject(), Text: text.String(), HTML: html.String()}, nil
+}
+
+// Subject is the email's subject line
+func (d *Digest) Subject() string {
+	if d.Weekly {
+		return "Your todos for the week of " + d.Date.Format("Monday, 2 January")
+	}
+	return "Your todos for " + d.Date.Format("Monday, 2 January")
+}
+
+// sortItems orders items by due date, then by priority
+func sortItems(items []Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if !a.Due.Equal(b.Due) {
+			return a.Due.Before(b.Due)
+		}
+		return a.Priority.Rank() > b.Priority.Rank()
+	})
+}
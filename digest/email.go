@@ -0,0 +1,37 @@
+package digest
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	"todo-api/notify"
+)
+
+// EmailChannel notifies users by email, at the address in their settings
+type EmailChannel struct {
+	mailer Mailer
+}
+
+// NewEmailChannel creates an EmailChannel sending through mailer
+func NewEmailChannel(mailer Mailer) *EmailChannel {
+	return &EmailChannel{mailer: mailer}
+}
+
+// Channel names the channel "email"
+func (ch *EmailChannel) Channel() string {
+	return "email"
+}
+
+// Notify emails the notification. Notifications without HTML get the text
+// as HTML too.
+func (ch *EmailChannel) Notify(ctx context.Context, to notify.Recipient, n notify.Notification) error {
+	if to.Settings.Email == "" {
+		return notify.ErrUnreachable
+	}
+	body := n.HTML
+	if body == "" {
+		body = "<p>" + strings.ReplaceAll(html.EscapeString(n.Text), "\n", "<br>\n") + "</p>"
+	}
+	return ch.mailer.Send(ctx, Message{To: to.Settings.Email, Subject: n.Subject, Text: n.Text, HTML: body})
+}
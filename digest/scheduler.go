@@ -0,0 +1,132 @@
+package digest
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"todo-api/models"
+	"todo-api/notify"
+	"todo-api/repository"
+)
+
+// userBatchSize is how many users are read per query while looking for
+// digests that are due
+const userBatchSize = 100
+
+// sendTimeout bounds the work done for one user's digest
+const sendTimeout = 30 * time.Second
+
+// Scheduler sends digests as they fall due: daily ones at hour in each
+// user's time zone, and weekly ones at that hour on weekday, on the
+// channels users picked for them. Each digest is claimed before it's sent,
+// so several instances can run a Scheduler without sending it twice; one
+// that fails to send isn't retried.
+type Scheduler struct {
+	users    repository.UserRepository
+	todos    repository.TodoRepository
+	registry *notify.Registry
+	hour     int
+	weekday  time.Weekday
+	interval time.Duration
+}
+
+// NewScheduler creates a Scheduler that checks every interval for digests
+// that are due
+func NewScheduler(users repository.UserRepository, todos repository.TodoRepository, registry *notify.Registry, hour int, weekday time.Weekday, interval time.Duration) *Scheduler {
+	return &Scheduler{users: users, todos: todos, registry: registry, hour: hour, weekday: weekday, interval: interval}
+}
+
+// Run sends due digests on every tick until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := s.SendDue(ctx, time.Now())
+		if err != nil {
+			log.Println("Sending digests failed:", err)
+		} else if sent > 0 {
+			log.Printf("Sent %d digests", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends every digest due by now that hasn't been sent yet and
+// returns how many were sent. A digest that fails is logged and skipped;
+// err is only set when users can't be listed.
+func (s *Scheduler) SendDue(ctx context.Context, now time.Time) (int, error) {
+	sent := 0
+	after := ""
+	for {
+		users, err := s.users.List(ctx, after, userBatchSize)
+		if err != nil {
+			return sent, err
+		}
+		for _, user := range users {
+			if user.Settings.Digest == "" || len(s.registry.Enabled(user.Settings, models.NotifyDigest)) == 0 {
+				continue
+			}
+			ok, err := s.send(ctx, &user, now)
+			if err != nil {
+				log.Printf("Failed to send digest to user %s: %v", user.ID, err)
+			} else if ok {
+				sent++
+			}
+		}
+		if len(users) < userBatchSize {
+			return sent, nil
+		}
+		after = users[len(users)-1].ID
+	}
+}
+
+// send sends the user's latest digest if no instance has yet, returning
+// whether any channel delivered it. Digests with nothing in them are
+// claimed but not sent.
+func (s *Scheduler) send(ctx context.Context, user *models.User, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	loc := user.Settings.Location()
+	claimed, err := s.users.ClaimDigest(ctx, user.ID, s.Due(user.Settings.Digest, now.In(loc)))
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	todos, err := s.todos.List(ctx, repository.Personal(user.ID))
+	if err != nil {
+		return false, err
+	}
+	digest := Build(todos, user.Settings.Digest, now, loc)
+	if digest.Empty() {
+		return false, nil
+	}
+	n, err := digest.Notification()
+	if err != nil {
+		return false, err
+	}
+	delivered, err := s.registry.Notify(ctx, notify.Recipient{UserID: user.ID, Settings: user.Settings}, n)
+	return delivered > 0, err
+}
+
+// Due returns when the latest digest of the given frequency was due at or
+// before now, in now's time zone
+func (s *Scheduler) Due(frequency string, now time.Time) time.Time {
+	due := time.Date(now.Year(), now.Month(), now.Day(), s.hour, 0, 0, 0, now.Location())
+	if due.After(now) {
+		due = due.AddDate(0, 0, -1)
+	}
+	if frequency == models.DigestWeekly {
+		for due.Weekday() != s.weekday {
+			due = due.AddDate(0, 0, -1)
+		}
+	}
+	return due
+}
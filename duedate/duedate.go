@@ -0,0 +1,266 @@
+package duedate
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnrecognized is returned for text that isn't a due date phrase
+var ErrUnrecognized = errors.New("duedate: unrecognized phrase")
+
+// ErrOutOfRange is returned for phrases resolving to more than
+// MaxYearsAhead years from now
+var ErrOutOfRange = errors.New("duedate: too far ahead")
+
+// MaxYearsAhead is how far ahead a phrase can put a due date
+const MaxYearsAhead = 100
+
+// maxCount caps the count of a relative phrase at about the number of
+// minutes in MaxYearsAhead years. Any bigger count is out of range in every
+// unit, and capping it keeps the arithmetic from overflowing.
+const maxCount = MaxYearsAhead * 366 * 24 * 60
+
+// Parse resolves a phrase such as "tomorrow 5pm", "next friday", "in 3
+// days" or "at noon" relative to now, in now's location. A day without a
+// time means the start of that day; a time without a day means its next
+// occurrence. "Next week" is the next weekStart.
+func Parse(phrase string, now time.Time, weekStart time.Weekday) (time.Time, error) {
+	words := strings.Fields(strings.ToLower(strings.NewReplacer(",", " ", ".", " ").Replace(phrase)))
+	if len(words) > 0 && (words[0] == "on" || words[0] == "by" || words[0] == "due") {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return time.Time{}, ErrUnrecognized
+	}
+
+	p := parser{words: words, now: now, weekStart: weekStart}
+	if due, ok := p.relative(); ok {
+		if !p.done() {
+			return time.Time{}, ErrUnrecognized
+		}
+		if due.After(now.AddDate(MaxYearsAhead, 0, 0)) {
+			return time.Time{}, ErrOutOfRange
+		}
+		return due, nil
+	}
+
+	day, hasDay := p.day()
+	clock, hasClock := p.clock()
+	if !p.done() || (!hasDay && !hasClock) {
+		return time.Time{}, ErrUnrecognized
+	}
+	if !hasDay {
+		day = StartOfDay(now)
+		if due := day.Add(clock); !due.After(now) {
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+	return day.Add(clock), nil
+}
+
+// Find looks for a due date phrase at the end of text, such as a title like
+// "Pay rent by next friday", and returns the date and the text without the
+// phrase. Only the end is searched, where people put dates, so words that
+// merely look like dates earlier in the text are left alone.
+func Find(text string, now time.Time, weekStart time.Weekday) (due time.Time, rest string, ok bool) {
+	words := strings.Fields(text)
+	// Prefer the longest phrase, but leave at least one word of text
+	for i := 1; i < len(words); i++ {
+		if due, err := Parse(strings.Join(words[i:], " "), now, weekStart); err == nil {
+			return due, strings.Join(words[:i], " "), true
+		}
+	}
+	return time.Time{}, text, false
+}
+
+// parser consumes the words of a phrase from the front
+type parser struct {
+	words     []string
+	now       time.Time
+	weekStart time.Weekday
+}
+
+func (p *parser) peek(n int) string {
+	if n < len(p.words) {
+		return p.words[n]
+	}
+	return ""
+}
+
+func (p *parser) take(n int) {
+	p.words = p.words[n:]
+}
+
+func (p *parser) done() bool {
+	return len(p.words) == 0
+}
+
+// relative parses "in <n> <unit>", e.g. "in 3 days" or "in an hour"
+func (p *parser) relative() (time.Time, bool) {
+	if p.peek(0) != "in" {
+		return time.Time{}, false
+	}
+	n, err := strconv.Atoi(p.peek(1))
+	if p.peek(1) == "a" || p.peek(1) == "an" {
+		n, err = 1, nil
+	}
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+	n = min(n, maxCount)
+
+	var due time.Time
+	switch strings.TrimSuffix(p.peek(2), "s") {
+	case "minute", "min":
+		due = p.now.Truncate(time.Minute).Add(time.Duration(n) * time.Minute)
+	case "hour", "hr":
+		due = p.now.Truncate(time.Minute).Add(time.Duration(n) * time.Hour)
+	case "day":
+		due = StartOfDay(p.now).AddDate(0, 0, n)
+	case "week":
+		due = StartOfDay(p.now).AddDate(0, 0, 7*n)
+	case "month":
+		due = StartOfDay(p.now).AddDate(0, n, 0)
+	default:
+		return time.Time{}, false
+	}
+	p.take(3)
+	return due, true
+}
+
+// day parses the day part of a phrase: today, tonight, tomorrow, the day
+// after tomorrow, a weekday, next week or next month
+func (p *parser) day() (time.Time, bool) {
+	today := StartOfDay(p.now)
+	switch p.peek(0) {
+	case "today":
+		p.take(1)
+		return today, true
+	case "tonight":
+		// Keep the clock for "tonight 9pm", else default to the evening
+		p.take(1)
+		if p.done() {
+			p.words = []string{"evening"}
+		}
+		return today, true
+	case "tomorrow", "tmrw", "tmr":
+		p.take(1)
+		return today.AddDate(0, 0, 1), true
+	case "day":
+		if p.peek(1) == "after" && p.peek(2) == "tomorrow" {
+			p.take(3)
+			return today.AddDate(0, 0, 2), true
+		}
+		return time.Time{}, false
+	}
+
+	n := 0
+	if p.peek(0) == "next" || p.peek(0) == "this" {
+		n = 1
+	}
+	switch p.peek(n) {
+	case "week":
+		if n == 0 || p.peek(0) != "next" {
+			return time.Time{}, false
+		}
+		p.take(2)
+		ahead := (int(p.weekStart) - int(today.Weekday()) + 7) % 7
+		if ahead == 0 {
+			ahead = 7
+		}
+		return today.AddDate(0, 0, ahead), true
+	case "month":
+		if n == 0 || p.peek(0) != "next" {
+			return time.Time{}, false
+		}
+		p.take(2)
+		return time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location()), true
+	}
+	if weekday, ok := weekdays[p.peek(n)]; ok {
+		// "this friday" can be today; otherwise the next one after today
+		ahead := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if ahead == 0 && p.peek(0) != "this" {
+			ahead = 7
+		}
+		p.take(n + 1)
+		return today.AddDate(0, 0, ahead), true
+	}
+	return time.Time{}, false
+}
+
+// clock parses a time of day: "5pm", "5:30 pm", "17:00", "noon",
+// "midnight", "morning" or "evening", optionally after "at"
+func (p *parser) clock() (time.Duration, bool) {
+	n := 0
+	if p.peek(0) == "at" {
+		n = 1
+	}
+	word := p.peek(n)
+	if d, ok := namedTimes[word]; ok {
+		p.take(n + 1)
+		return d, true
+	}
+
+	// "5 pm" is the same as "5pm"
+	if next := p.peek(n + 1); next == "am" || next == "pm" {
+		word += next
+		n++
+	}
+	meridiem := ""
+	if strings.HasSuffix(word, "am") || strings.HasSuffix(word, "pm") {
+		meridiem = word[len(word)-2:]
+		word = word[:len(word)-2]
+	}
+	hourText, minuteText, hasMinutes := strings.Cut(word, ":")
+	if !hasMinutes && meridiem == "" {
+		return 0, false
+	}
+	hour, err := strconv.Atoi(hourText)
+	if err != nil {
+		return 0, false
+	}
+	minute := 0
+	if hasMinutes {
+		if minute, err = strconv.Atoi(minuteText); err != nil || len(minuteText) != 2 || minute > 59 {
+			return 0, false
+		}
+	}
+	switch {
+	case meridiem == "" && hour > 23:
+		return 0, false
+	case meridiem != "" && (hour < 1 || hour > 12):
+		return 0, false
+	case meridiem == "am" && hour == 12:
+		hour = 0
+	case meridiem == "pm" && hour < 12:
+		hour += 12
+	}
+	p.take(n + 1)
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, true
+}
+
+// StartOfDay is midnight at the start of t's day in t's location
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var namedTimes = map[string]time.Duration{
+	"midnight": 0,
+	"morning":  9 * time.Hour,
+	"noon":     12 * time.Hour,
+	"midday":   12 * time.Hour,
+	"evening":  18 * time.Hour,
+}
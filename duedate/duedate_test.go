@@ -0,0 +1,87 @@
+package duedate
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// now is a Friday afternoon
+var now = time.Date(2026, 10, 16, 14, 30, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		phrase    string
+		weekStart time.Weekday
+		want      time.Time
+	}{
+		{"tomorrow", time.Monday, time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"tomorrow 5pm", time.Monday, time.Date(2026, 10, 17, 17, 0, 0, 0, time.UTC)},
+		{"next friday", time.Monday, time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+		{"this friday", time.Monday, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{"next week", time.Monday, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"next week", time.Sunday, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"next week", time.Friday, time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+		{"in 3 days", time.Monday, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"in an hour", time.Monday, time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC)},
+		{"in 100 years", time.Monday, time.Time{}},
+		{"in 1200 months", time.Monday, time.Date(2126, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{"at noon", time.Monday, time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.phrase, now, tt.weekStart)
+		if tt.want.IsZero() {
+			if err == nil {
+				t.Errorf("Parse(%q) = %v, want an error", tt.phrase, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("Parse(%q, %s) = %v, %v; want %v", tt.phrase, tt.weekStart, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseOutOfRange(t *testing.T) {
+	for _, phrase := range []string{
+		"in 5000000 days",
+		"in 99999999999 minutes",
+		"in 99999999999 hours",
+		"in 99999999999 weeks",
+		"in 99999999999 months",
+		"in 1201 months",
+		"in 36600 days",
+	} {
+		got, err := Parse(phrase, now, time.Monday)
+		if !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Parse(%q) = %v, %v; want ErrOutOfRange", phrase, got, err)
+		}
+	}
+}
+
+func TestParseUnrecognized(t *testing.T) {
+	for _, phrase := range []string{"", "someday", "in many days", "in -1 days", "in 3 fortnights", "tomorrow please"} {
+		if got, err := Parse(phrase, now, time.Monday); !errors.Is(err, ErrUnrecognized) {
+			t.Errorf("Parse(%q) = %v, %v; want ErrUnrecognized", phrase, got, err)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		text     string
+		wantRest string
+		wantOK   bool
+	}{
+		{"Pay rent by next friday", "Pay rent", true},
+		{"Call mum tomorrow 5pm", "Call mum", true},
+		{"Tomorrow", "Tomorrow", false},
+		{"Plan the next century in 5000000 days", "Plan the next century in 5000000 days", false},
+	}
+	for _, tt := range tests {
+		_, rest, ok := Find(tt.text, now, time.Monday)
+		if rest != tt.wantRest || ok != tt.wantOK {
+			t.Errorf("Find(%q) = %q, %v; want %q, %v", tt.text, rest, ok, tt.wantRest, tt.wantOK)
+		}
+	}
+}
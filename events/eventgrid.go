@@ -0,0 +1,96 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"todo-api/azure"
+)
+
+// eventGridResource is the token audience for Event Grid
+const eventGridResource = "https://eventgrid.azure.net"
+
+// EventGrid sends events to an Event Grid topic as CloudEvents. It
+// authenticates with the topic's access key when one is given, else with the
+// app's managed identity.
+type EventGrid struct {
+	endpoint string
+	key      string
+	identity *azure.ManagedIdentity
+	client   *http.Client
+}
+
+// NewEventGrid creates a publisher for the topic at endpoint
+func NewEventGrid(endpoint, key string, identity *azure.ManagedIdentity) *EventGrid {
+	return &EventGrid{
+		endpoint: endpoint,
+		key:      key,
+		identity: identity,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// cloudEvent is an event in the CloudEvents 1.0 JSON format
+type cloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            any       `json:"data"`
+}
+
+// Publish sends the events as one batch
+func (g *EventGrid) Publish(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	batch := make([]cloudEvent, len(events))
+	for i, event := range events {
+		batch[i] = cloudEvent{
+			SpecVersion:     "1.0",
+			ID:              event.ID,
+			Source:          Source,
+			Type:            event.Type,
+			Subject:         event.Subject,
+			Time:            event.Time,
+			DataContentType: "application/json",
+			Data:            event.Data,
+		}
+	}
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/cloudevents-batch+json; charset=utf-8")
+	if g.key != "" {
+		req.Header.Set("aeg-sas-key", g.key)
+	} else {
+		accessToken, err := g.identity.Token(ctx, eventGridResource)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Event Grid returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Package events publishes domain events so other services, such as
+// notifications or analytics, can react to changes without polling the API
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Event types
+const (
+	TodoCreated   = "todo.created"
+	TodoUpdated   = "todo.updated"
+	TodoDeleted   = "todo.deleted"
+	TodoCompleted = "todo.completed"
+	// TodoAssigned is published when a todo is assigned to a member, for
+	// notifying them
+	TodoAssigned = "todo.assigned"
+	// CommentCreated is published when a comment is left on a todo, for
+	// notifying the users it mentions
+	CommentCreated = "comment.created"
+)
+
+// Source identifies this API as the origin of its events
+const Source = "todo-api"
+
+// Event is one change to publish. Subject names the resource that changed,
+// e.g. todos/<id>, and Data is serialized as JSON.
+type Event struct {
+	ID      string    `json:"id"`
+	Type    string    `json:"type"`
+	Subject string    `json:"subject"`
+	Time    time.Time `json:"time"`
+	Data    any       `json:"data"`
+}
+
+// New creates an event with a random ID, stamped with the current time
+func New(eventType, subject string, data any) Event {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return Event{ID: hex.EncodeToString(id), Type: eventType, Subject: subject, Time: time.Now().UTC(), Data: data}
+}
+
+// Publisher sends events to a message broker
+type Publisher interface {
+	Publish(ctx context.Context, events ...Event) error
+}
+
+// Nop discards events. It's used when no event backend is configured.
+type Nop struct{}
+
+// Publish does nothing
+func (Nop) Publish(context.Context, ...Event) error {
+	return nil
+}
@@ -0,0 +1,131 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"todo-api/models"
+	"todo-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// relayBatchSize is how many events the relay publishes per request to the
+// broker, keeping batches well under the brokers' size limits
+const relayBatchSize = 20
+
+// deliveredRetention is how long delivered entries are kept, for debugging,
+// before the relay removes them
+const deliveredRetention = 24 * time.Hour
+
+// Outbox is a Publisher that stores events in the outbox instead of sending
+// them. Handlers publish to it as part of saving a change; a Relay then
+// delivers the events, so an unavailable broker or a crash doesn't lose them.
+type Outbox struct {
+	entries repository.OutboxRepository
+}
+
+// NewOutbox creates a Publisher that writes to entries
+func NewOutbox(entries repository.OutboxRepository) *Outbox {
+	return &Outbox{entries: entries}
+}
+
+// Publish stores the events in the outbox
+func (o *Outbox) Publish(ctx context.Context, events ...Event) error {
+	entries := make([]*models.OutboxEntry, len(events))
+	for i, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		entries[i] = &models.OutboxEntry{Payload: payload, CreatedAt: event.Time}
+	}
+	return o.entries.Add(ctx, entries...)
+}
+
+// Relay publishes the events waiting in the outbox and marks them delivered.
+// Delivery is at least once: an event is sent again if the relay stops
+// between publishing it and marking it, or when several instances relay the
+// same outbox, so consumers should skip event IDs they've already seen.
+type Relay struct {
+	entries   repository.OutboxRepository
+	publisher Publisher
+	interval  time.Duration
+}
+
+// NewRelay creates a Relay that checks entries for new events every interval
+// and sends them to publisher
+func NewRelay(entries repository.OutboxRepository, publisher Publisher, interval time.Duration) *Relay {
+	return &Relay{entries: entries, publisher: publisher, interval: interval}
+}
+
+// Run relays on every tick until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Flush(ctx); err != nil {
+			log.Println("Event relay failed:", err)
+		}
+		if _, err := r.entries.DeleteDelivered(ctx, time.Now().Add(-deliveredRetention)); err != nil {
+			log.Println("Failed to delete delivered events:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush publishes every pending event, oldest first, and returns how many
+// were delivered. It stops at the first batch the broker doesn't accept,
+// which is retried on the next call.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		entries, err := r.entries.Pending(ctx, relayBatchSize)
+		if err != nil || len(entries) == 0 {
+			return delivered, err
+		}
+
+		batch := make([]Event, 0, len(entries))
+		ids := make([]primitive.ObjectID, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID
+			event, err := decodeEntry(entry)
+			if err != nil {
+				// It can never be sent, so don't let it hold up the rest
+				log.Printf("Dropping undecodable event %s: %v", entry.ID.Hex(), err)
+				continue
+			}
+			batch = append(batch, event)
+		}
+		if err := r.publisher.Publish(ctx, batch...); err != nil {
+			return delivered, err
+		}
+		if err := r.entries.MarkDelivered(ctx, ids, time.Now()); err != nil {
+			return delivered, err
+		}
+		delivered += len(batch)
+	}
+}
+
+// decodeEntry reads the event stored in an entry. Its data is kept as the
+// JSON it was stored as.
+func decodeEntry(entry models.OutboxEntry) (Event, error) {
+	var stored struct {
+		Event
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(entry.Payload, &stored); err != nil {
+		return Event{}, err
+	}
+	event := stored.Event
+	event.Data = stored.Data
+	return event, nil
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"todo-api/azure"
+)
+
+// serviceBusResource is the token audience for Service Bus
+const serviceBusResource = "https://servicebus.azure.net"
+
+// ServiceBus sends events to a Service Bus queue or topic over its REST API,
+// authenticating with the app's managed identity. Each event is one message
+// whose body is the event as JSON and whose label is the event type, so
+// subscriptions can filter on it.
+type ServiceBus struct {
+	url      string
+	identity *azure.ManagedIdentity
+	client   *http.Client
+}
+
+// NewServiceBus creates a publisher for entity, a queue or topic, in the
+// namespace at host, e.g. mybus.servicebus.windows.net
+func NewServiceBus(host, entity string, identity *azure.ManagedIdentity) *ServiceBus {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "https://"), "/")
+	return &ServiceBus{
+		url:      fmt.Sprintf("https://%s/%s/messages", host, entity),
+		identity: identity,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// serviceBusMessage is one message of a batch send
+type serviceBusMessage struct {
+	Body             string            `json:"Body"`
+	BrokerProperties map[string]string `json:"BrokerProperties"`
+}
+
+// Publish sends the events as one batch
+func (s *ServiceBus) Publish(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	messages := make([]serviceBusMessage, len(events))
+	for i, event := range events {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		messages[i] = serviceBusMessage{
+			Body:             string(body),
+			BrokerProperties: map[string]string{"MessageId": event.ID, "Label": event.Type, "ContentType": "application/json"},
+		}
+	}
+	payload, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := s.identity.Token(ctx, serviceBusResource)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/vnd.microsoft.servicebus.json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("Service Bus returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Package fieldcrypt encrypts single fields of stored documents with
+// AES-256-GCM, so their text isn't readable by anyone with raw access to the
+// database or its backups
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// KeySize is the length of a key in bytes
+const KeySize = 32
+
+// prefix marks encrypted values. An encrypted value reads
+// "enc:v1:<key ID>:<base64 of nonce and sealed text>".
+const prefix = "enc:v1:"
+
+// ErrUnknownKey is returned when a value was encrypted with a key that isn't
+// configured, as when an old key was dropped too early after a rotation
+var ErrUnknownKey = errors.New("value was encrypted with a key that isn't configured")
+
+// key is an AES-GCM key with the ID stored alongside its values
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// keyring holds the key values are encrypted with, nil when they're written
+// in plain text, and every key they can be decrypted with
+type keyring struct {
+	current *key
+	all     []key
+}
+
+// Cipher encrypts values with its current key and decrypts them with any of
+// its keys, which allows rotating keys without re-encrypting everything at
+// once
+type Cipher struct {
+	keys atomic.Pointer[keyring]
+}
+
+// NewCipher creates a cipher that encrypts with current and also decrypts
+// values encrypted with any of the previous keys. Without a current key,
+// values are written in plain text and only decrypted, for turning
+// encryption off.
+func NewCipher(current []byte, previous ...[]byte) (*Cipher, error) {
+	c := &Cipher{}
+	if err := c.SetKeys(current, previous...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetKeys replaces the keys, e.g. after they were rotated in Key Vault. It is
+// safe to call while values are being encrypted and decrypted.
+func (c *Cipher) SetKeys(current []byte, previous ...[]byte) error {
+	ring := &keyring{}
+	all := previous
+	if current != nil {
+		all = append([][]byte{current}, previous...)
+	}
+	for _, raw := range all {
+		if len(raw) != KeySize {
+			return fmt.Errorf("encryption keys must be %d bytes, got %d", KeySize, len(raw))
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		ring.all = append(ring.all, key{id: KeyID(raw), aead: aead})
+	}
+	if current != nil {
+		ring.current = &ring.all[0]
+	}
+	c.keys.Store(ring)
+	return nil
+}
+
+// Encrypt encrypts value with the current key. context names where the value
+// belongs, such as a todo's ID and field, and must be given again to decrypt
+// it, so a value copied to another document or field doesn't decrypt. Empty
+// values are left empty, and without a current key values are left as they
+// are.
+func (c *Cipher) Encrypt(value, context string) (string, error) {
+	k := c.keys.Load().current
+	if value == "" || k == nil {
+		return value, nil
+	}
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := k.aead.Seal(nonce, nonce, []byte(value), []byte(context))
+	return prefix + k.id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the text of a value made by Encrypt with the same context.
+// Values that aren't encrypted, such as ones written before encryption was
+// turned on, are returned as they are.
+func (c *Cipher) Decrypt(value, context string) (string, error) {
+	id, data, ok := parse(value)
+	if !ok {
+		return value, nil
+	}
+	for _, k := range c.keys.Load().all {
+		if k.id != id {
+			continue
+		}
+		size := k.aead.NonceSize()
+		if len(data) < size {
+			return "", errors.New("encrypted value is truncated")
+		}
+		text, err := k.aead.Open(nil, data[:size], data[size:], []byte(context))
+		if err != nil {
+			return "", fmt.Errorf("decrypt value: %w", err)
+		}
+		return string(text), nil
+	}
+	return "", ErrUnknownKey
+}
+
+// KeyID returns the ID of a key stored with the values it encrypts: the
+// start of its SHA-256 hash, which doesn't give the key away
+func KeyID(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:4])
+}
+
+// parse splits an encrypted value into its key ID and data. ok is false for
+// values that aren't encrypted.
+func parse(value string) (id string, data []byte, ok bool) {
+	rest, found := strings.CutPrefix(value, prefix)
+	if !found {
+		return "", nil, false
+	}
+	id, encoded, found := strings.Cut(rest, ":")
+	if !found || len(id) != 8 {
+		return "", nil, false
+	}
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, false
+	}
+	return id, data, true
+}
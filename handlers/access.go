@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// scopeRole returns the user's role on the todos a request works on: their
+// role in the workspace when the route is nested under one (set by
+// RequireMember), else owner of their personal todos
+func scopeRole(c *gin.Context) authz.Role {
+	if role, ok := c.Get("role"); ok {
+		return role.(authz.Role)
+	}
+	return authz.RoleOwner
+}
+
+// todoAccess loads a todo and the user's role on it: from the request's
+// scope, or, outside workspaces, a todo another user shared with them. A todo
+// the user has no role on is reported as repository.ErrNotFound.
+func todoAccess(ctx context.Context, c *gin.Context, todos repository.TodoRepository, shares repository.ShareRepository, id primitive.ObjectID) (*models.Todo, authz.Role, error) {
+	scope := todoScope(c, c.GetString("user_id"))
+	todo, err := todos.Get(ctx, scope, id)
+	if err == nil {
+		return todo, scopeRole(c), nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) || scope.IsWorkspace() {
+		return nil, "", err
+	}
+
+	share, err := grantedShare(ctx, c, shares, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if todo, err = todos.Get(ctx, repository.Personal(share.OwnerID), id); err != nil {
+		return nil, "", err
+	}
+	return todo, share.Role, nil
+}
+
+// grantedShare returns the share giving the current user the highest role on
+// the todo, or repository.ErrNotFound if it isn't shared with them
+func grantedShare(ctx context.Context, c *gin.Context, shares repository.ShareRepository, todoID primitive.ObjectID) (*models.Share, error) {
+	granted, err := shares.ListByGrantee(ctx, granteeKeys(c))
+	if err != nil {
+		return nil, err
+	}
+	var best *models.Share
+	for i := range granted {
+		if granted[i].TodoID == todoID && (best == nil || granted[i].Role.Outranks(best.Role)) {
+			best = &granted[i]
+		}
+	}
+	if best == nil {
+		return nil, repository.ErrNotFound
+	}
+	return best, nil
+}
+
+// granteeKeys returns the grantee keys that identify the current user: their
+// user ID, and their email when the identity provider supplied one
+func granteeKeys(c *gin.Context) []string {
+	keys := []string{models.GranteeUser(c.GetString("user_id"))}
+	if email := c.GetString("email"); email != "" {
+		keys = append(keys, models.GranteeEmail(email))
+	}
+	return keys
+}
+
+// respondTodoError reports a failure to load or authorize access to a todo.
+// Todos the user has no role on are reported as missing, and those they can
+// see but not change as forbidden.
+func respondTodoError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, authz.ErrNotFound):
+		apierrors.Respond(c, apierrors.CodeTodoNotFound, "Todo not found")
+	case errors.Is(err, authz.ErrForbidden):
+		apierrors.Respond(c, apierrors.CodeForbidden, "Your role on this todo doesn't allow this")
+	default:
+		respondStorageError(c, err, message)
+	}
+}
@@ -0,0 +1,523 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/buildinfo"
+	"todo-api/models"
+	"todo-api/repository"
+	"todo-api/retention"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Page sizes of the admin listings
+const (
+	defaultAdminPageSize = 50
+	maxAdminPageSize     = 200
+)
+
+// AdminHandler serves the operator API
+type AdminHandler struct {
+	stores        *repository.Stores
+	backend       string
+	sweeper       *retention.Sweeper
+	policy        *retention.Policy
+	ensureIndexes func(context.Context) error
+	syncWindow    time.Duration
+	startedAt     time.Time
+	timeout       time.Duration
+}
+
+// NewAdminHandler creates an AdminHandler over all of the backend's
+// repositories. backend is reported in the stats. sweeper is nil when
+// retention is off, and policy when no retention rules are configured;
+// ensureIndexes creates the backend's indexes. syncWindow is how long
+// tombstones of deleted todos are kept.
+func NewAdminHandler(stores *repository.Stores, backend string, sweeper *retention.Sweeper, policy *retention.Policy, ensureIndexes func(context.Context) error, syncWindow, timeout time.Duration) *AdminHandler {
+	return &AdminHandler{stores: stores, backend: backend, sweeper: sweeper, policy: policy, ensureIndexes: ensureIndexes, syncWindow: syncWindow, startedAt: time.Now(), timeout: timeout}
+}
+
+// adminUser is a user as listed to operators
+type adminUser struct {
+	models.User
+	TodoCount int64 `json:"todo_count"`
+}
+
+// ListUsers pages through users ordered by ID with the number of personal
+// todos each has. Pass the returned next_after as ?after= for the next page.
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	limit := defaultAdminPageSize
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAdminPageSize {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{
+				Field:   "limit",
+				Message: "must be a number between 1 and " + strconv.Itoa(maxAdminPageSize),
+			}})
+			return
+		}
+		limit = n
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	users, err := h.stores.Users.List(ctx, c.Query("after"), limit)
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch users")
+		return
+	}
+
+	result := make([]adminUser, len(users))
+	for i, user := range users {
+		n, err := h.stores.Todos.Count(ctx, repository.Personal(user.ID))
+		if err != nil {
+			respondStorageError(c, err, "Failed to count todos")
+			return
+		}
+		result[i] = adminUser{User: user, TodoCount: n}
+	}
+
+	response := gin.H{"users": result}
+	if len(users) == limit {
+		response["next_after"] = users[len(users)-1].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Stats reports storage totals and the state of the process. Totals may be
+// estimates on backends that can't count cheaply.
+func (h *AdminHandler) Stats(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	users, err := h.stores.Users.Count(ctx)
+	if err != nil {
+		respondStorageError(c, err, "Failed to count users")
+		return
+	}
+	todos, err := h.stores.Todos.CountAll(ctx)
+	if err != nil {
+		respondStorageError(c, err, "Failed to count todos")
+		return
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	c.JSON(http.StatusOK, gin.H{
+		"storage_backend": h.backend,
+		"users":           users,
+		"todos":           todos,
+		"uptime_seconds":  int64(time.Since(h.startedAt).Seconds()),
+		"build":           buildinfo.Get(),
+		"runtime": gin.H{
+			"go_version":       runtime.Version(),
+			"goroutines":       runtime.NumGoroutine(),
+			"heap_alloc_bytes": mem.HeapAlloc,
+			"gc_cycles":        mem.NumGC,
+		},
+	})
+}
+
+// PurgeUser deletes everything stored about a user: their personal todos
+// with their revisions, tombstones, custom fields and saved filters, the
+// shares and public links they made, their sessions, their Slack link and
+// their user record, in one transaction where the backend supports them.
+// Workspaces and workspace todos belong to their members and are left alone.
+// ?dry_run=true reports what would be deleted instead.
+func (h *AdminHandler) PurgeUser(c *gin.Context) {
+	userID := c.Param("user_id")
+	dryRun, ok := isDryRun(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	if dryRun {
+		h.previewPurge(ctx, c, userID)
+		return
+	}
+
+	var deleted int64
+	err := h.stores.Tx.WithTransaction(ctx, func(ctx context.Context) (err error) {
+		deleted, err = h.purge(ctx, userID)
+		return err
+	})
+	if err != nil {
+		respondStorageError(c, err, "Failed to purge user")
+		return
+	}
+
+	log.Printf("Admin purged user %s (%d todos)", userID, deleted)
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "todos_deleted": deleted})
+}
+
+// previewPurge responds with what purging the user would delete
+func (h *AdminHandler) previewPurge(ctx context.Context, c *gin.Context, userID string) {
+	todos, err := h.stores.Todos.List(ctx, repository.Personal(userID))
+	var fields []models.CustomField
+	if err == nil {
+		fields, err = h.stores.CustomFields.List(ctx, repository.Personal(userID))
+	}
+	var filters []models.SavedFilter
+	if err == nil {
+		filters, err = h.stores.Filters.List(ctx, repository.Personal(userID))
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to purge user")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":               userID,
+		"dry_run":               true,
+		"todos_deleted":         len(todos),
+		"custom_fields_deleted": len(fields),
+		"filters_deleted":       len(filters),
+		"sample_todo_ids":       sampleTodoIDs(todos),
+	})
+}
+
+// purge deletes what PurgeUser does and returns how many todos were
+// deleted
+func (h *AdminHandler) purge(ctx context.Context, userID string) (int64, error) {
+	// Without transactions, deleting the user record last means a failure
+	// part way leaves the user listed and the purge can be retried
+	deleted, err := h.stores.Todos.DeleteAll(ctx, repository.Personal(userID))
+	if err == nil {
+		err = h.stores.CustomFields.DeleteAll(ctx, repository.Personal(userID))
+	}
+	if err == nil {
+		err = h.stores.Filters.DeleteAll(ctx, repository.Personal(userID))
+	}
+	if err == nil {
+		err = h.stores.Revisions.DeleteAll(ctx, repository.Personal(userID))
+	}
+	if err == nil {
+		err = h.stores.Tombstones.DeleteAll(ctx, repository.Personal(userID))
+	}
+	if err == nil {
+		err = h.stores.Shares.DeleteByOwner(ctx, userID)
+	}
+	if err == nil {
+		err = h.stores.PublicLinks.DeleteByOwner(ctx, userID)
+	}
+	if err == nil {
+		err = h.stores.Sessions.DeleteByUser(ctx, userID)
+	}
+	if err == nil {
+		err = h.stores.SlackLinks.Delete(ctx, userID)
+	}
+	if err == nil {
+		err = h.stores.Users.Delete(ctx, userID)
+	}
+	return deleted, err
+}
+
+// ExportUser returns everything in a user's personal list: their settings,
+// todos, custom fields and saved filters
+func (h *AdminHandler) ExportUser(c *gin.Context) {
+	userID := c.Param("user_id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	export := models.UserExport{UserID: userID, ExportedAt: time.Now().UTC()}
+	var err error
+	export.Settings, err = h.stores.Users.GetSettings(ctx, userID)
+	if err == nil {
+		export.Todos, err = h.stores.Todos.List(ctx, repository.Personal(userID))
+	}
+	if err == nil {
+		export.CustomFields, err = h.stores.CustomFields.List(ctx, repository.Personal(userID))
+	}
+	if err == nil {
+		export.Filters, err = h.stores.Filters.List(ctx, repository.Personal(userID))
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to export user")
+		return
+	}
+	if export.Todos == nil {
+		export.Todos = []models.Todo{}
+	}
+	if export.CustomFields == nil {
+		export.CustomFields = []models.CustomField{}
+	}
+	if export.Filters == nil {
+		export.Filters = []models.SavedFilter{}
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// ImportUser adds an export to a user's personal list. Items keep their
+// IDs, so importing again skips what's already there; ?new_ids=true gives
+// them new ones instead, for copying a list to another user of the same
+// deployment. Settings are replaced when the export has any. Quotas don't
+// apply. ?dry_run=true reports what would be created and skipped instead.
+func (h *AdminHandler) ImportUser(c *gin.Context) {
+	userID := c.Param("user_id")
+
+	var export models.UserExport
+	if err := c.ShouldBindJSON(&export); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	switch c.Query("new_ids") {
+	case "", "false":
+	case "true":
+		renumber(&export)
+	default:
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "new_ids", Message: `must be "true" or "false"`}})
+		return
+	}
+	dryRun, ok := isDryRun(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	if dryRun {
+		h.previewImport(ctx, c, userID, &export)
+		return
+	}
+
+	var todos, fields, filters models.ImportCounts
+	count := func(counts *models.ImportCounts, err error) error {
+		switch {
+		case errors.Is(err, repository.ErrDuplicate):
+			counts.Skipped++
+		case err == nil:
+			counts.Created++
+		default:
+			return err
+		}
+		return nil
+	}
+	var err error
+	for i := 0; i < len(export.CustomFields) && err == nil; i++ {
+		field := &export.CustomFields[i]
+		field.UserID, field.WorkspaceID = userID, nil
+		err = count(&fields, h.stores.CustomFields.Create(ctx, field))
+	}
+	for i := 0; i < len(export.Filters) && err == nil; i++ {
+		filter := &export.Filters[i]
+		filter.UserID, filter.WorkspaceID = userID, nil
+		err = count(&filters, h.stores.Filters.Create(ctx, filter))
+	}
+	for i := 0; i < len(export.Todos) && err == nil; i++ {
+		todo := &export.Todos[i]
+		todo.UserID, todo.WorkspaceID = userID, nil
+		err = count(&todos, h.stores.Todos.Create(ctx, todo))
+	}
+	if err == nil && !export.Settings.IsZero() {
+		err = h.stores.Users.SaveSettings(ctx, userID, export.Settings)
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to import user")
+		return
+	}
+
+	log.Printf("Admin imported %d todos for user %s", todos.Created, userID)
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "todos": todos, "custom_fields": fields, "filters": filters})
+}
+
+// previewImport responds with what importing the export would create and
+// skip. Items are skipped when their ID is in the user's list already or
+// earlier in the export; an ID used in another user's list is counted as
+// created, though the import would skip it too.
+func (h *AdminHandler) previewImport(ctx context.Context, c *gin.Context, userID string, export *models.UserExport) {
+	scope := repository.Personal(userID)
+	todos, err := h.stores.Todos.List(ctx, scope)
+	var fields []models.CustomField
+	if err == nil {
+		fields, err = h.stores.CustomFields.List(ctx, scope)
+	}
+	var filters []models.SavedFilter
+	if err == nil {
+		filters, err = h.stores.Filters.List(ctx, scope)
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to import user")
+		return
+	}
+
+	// count adds an item to counts, reporting whether it would be created
+	count := func(counts *models.ImportCounts, seen map[primitive.ObjectID]bool, id primitive.ObjectID) bool {
+		if !id.IsZero() && seen[id] {
+			counts.Skipped++
+			return false
+		}
+		seen[id] = true
+		counts.Created++
+		return true
+	}
+	var todoCounts, fieldCounts, filterCounts models.ImportCounts
+	seen := map[primitive.ObjectID]bool{}
+	for _, field := range fields {
+		seen[field.ID] = true
+	}
+	for _, field := range export.CustomFields {
+		count(&fieldCounts, seen, field.ID)
+	}
+	seen = map[primitive.ObjectID]bool{}
+	for _, filter := range filters {
+		seen[filter.ID] = true
+	}
+	for _, filter := range export.Filters {
+		count(&filterCounts, seen, filter.ID)
+	}
+	seen = map[primitive.ObjectID]bool{}
+	for _, todo := range todos {
+		seen[todo.ID] = true
+	}
+	var created []models.Todo
+	for _, todo := range export.Todos {
+		if count(&todoCounts, seen, todo.ID) && !todo.ID.IsZero() {
+			created = append(created, todo)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":         userID,
+		"dry_run":         true,
+		"todos":           todoCounts,
+		"custom_fields":   fieldCounts,
+		"filters":         filterCounts,
+		"sample_todo_ids": sampleTodoIDs(created),
+	})
+}
+
+// renumber gives everything in an export new IDs, updating the references
+// todos make to custom fields and to each other
+func renumber(export *models.UserExport) {
+	ids := map[primitive.ObjectID]primitive.ObjectID{}
+	for i := range export.CustomFields {
+		id := primitive.NewObjectID()
+		ids[export.CustomFields[i].ID] = id
+		export.CustomFields[i].ID = id
+	}
+	for i := range export.Filters {
+		export.Filters[i].ID = primitive.NewObjectID()
+	}
+	for i := range export.Todos {
+		id := primitive.NewObjectID()
+		ids[export.Todos[i].ID] = id
+		export.Todos[i].ID = id
+	}
+	for i := range export.Todos {
+		todo := &export.Todos[i]
+		blockedBy := todo.BlockedBy[:0]
+		for _, id := range todo.BlockedBy {
+			if renumbered, ok := ids[id]; ok {
+				blockedBy = append(blockedBy, renumbered)
+			}
+		}
+		todo.BlockedBy = blockedBy
+		values := make(map[string]any, len(todo.CustomFields))
+		for key, value := range todo.CustomFields {
+			if id, err := primitive.ObjectIDFromHex(key); err == nil {
+				if renumbered, ok := ids[id]; ok {
+					key = renumbered.Hex()
+				}
+			}
+			values[key] = value
+		}
+		todo.CustomFields = values
+	}
+}
+
+// EnsureIndexes creates the storage indexes the API relies on, for after a
+// restore or a collection was recreated. Existing indexes are left as they
+// are.
+func (h *AdminHandler) EnsureIndexes(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	if err := h.ensureIndexes(ctx); err != nil {
+		respondStorageError(c, err, "Failed to create indexes")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Indexes are in place"})
+}
+
+// Sweep purges users inactive beyond the retention window now, rather than
+// at the next scheduled sweep, or reports who it would purge with
+// ?dry_run=true
+func (h *AdminHandler) Sweep(c *gin.Context) {
+	if h.sweeper == nil {
+		apierrors.Respond(c, apierrors.CodeRouteNotFound, "Retention is turned off")
+		return
+	}
+	dryRun, ok := isDryRun(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	if dryRun {
+		users, todos, sample, err := h.sweeper.DryRun(ctx, dryRunSampleSize)
+		if err != nil {
+			respondStorageError(c, err, "Failed to purge inactive users")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"dry_run": true, "users_purged": users, "todos_deleted": todos, "sample_user_ids": sample})
+		return
+	}
+
+	users, todos, err := h.sweeper.Sweep(ctx)
+	if err != nil {
+		respondStorageError(c, err, "Failed to purge inactive users")
+		return
+	}
+
+	log.Printf("Admin sweep purged %d inactive users (%d todos)", users, todos)
+	c.JSON(http.StatusOK, gin.H{"users_purged": users, "todos_deleted": todos})
+}
+
+// RetentionReport reports what the retention rules would delete if they ran
+// now, without deleting anything. Up to ?limit= todos are listed per rule;
+// the counts cover all of them.
+func (h *AdminHandler) RetentionReport(c *gin.Context) {
+	if h.policy == nil {
+		apierrors.Respond(c, apierrors.CodeRouteNotFound, "No retention rules are configured")
+		return
+	}
+	limit := defaultAdminPageSize
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxAdminPageSize {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{
+				Field:   "limit",
+				Message: "must be a number between 0 and " + strconv.Itoa(maxAdminPageSize),
+			}})
+			return
+		}
+		limit = n
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	report, err := h.policy.DryRun(ctx, time.Now(), limit)
+	if err != nil {
+		respondStorageError(c, err, "Failed to evaluate the retention rules")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
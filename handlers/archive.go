@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"todo-api/apierrors"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// includeArchived reads ?archived=, which adds archived todos to the list
+func includeArchived(c *gin.Context) (include, ok bool) {
+	switch c.Query("archived") {
+	case "", "false":
+		return false, true
+	case "true":
+		return true, true
+	}
+	apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "archived", Message: `must be "true" or "false"`}})
+	return false, false
+}
+
+// withoutArchived drops the archived todos, reusing the slice's storage
+func withoutArchived(todos []models.Todo) []models.Todo {
+	kept := todos[:0]
+	for _, todo := range todos {
+		if !todo.Archived() {
+			kept = append(kept, todo)
+		}
+	}
+	return kept
+}
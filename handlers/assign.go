@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/events"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AssignTodo handles POST /workspaces/:workspace_id/todos/:id/assign,
+// assigning a todo to a member who can edit todos, or unassigning it.
+// Members are told through a todo.assigned event.
+func (h *TodoHandler) AssignTodo(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	var req models.AssignTodoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+	workspace := c.MustGet("workspace").(*models.Workspace)
+	if req.AssigneeID != "" && authz.CanWrite(workspace.RoleOf(req.AssigneeID)) != nil {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "assignee_id", Message: "must be a member of the workspace who can edit todos"}})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	todo, before, role, ok := h.writeTodo(ctx, c, objectID, "Failed to assign todo", authz.CanWrite, func(todo *models.Todo, role authz.Role) bool {
+		// Assigning the todo to whoever has it already changes nothing
+		if todo.AssigneeID == req.AssigneeID {
+			linkTodo(c, todo, role)
+			c.JSON(http.StatusOK, gin.H{"todo": todo})
+			return false
+		}
+		todo.AssigneeID = req.AssigneeID
+		return true
+	}, func(_, todo *models.Todo) []events.Event {
+		batch := []events.Event{todoEvent(events.TodoUpdated, todo)}
+		if todo.AssigneeID != "" {
+			batch = append(batch, todoEvent(events.TodoAssigned, todo))
+		}
+		return batch
+	})
+	if !ok {
+		return
+	}
+	h.recordRevision(ctx, c.GetString("user_id"), &before, todo)
+
+	linkTodo(c, todo, role)
+	c.JSON(http.StatusOK, gin.H{"todo": todo})
+}
+
+// GetAssigned handles GET /todos/assigned, listing the open todos assigned
+// to the user in every workspace they can still read, most urgent first.
+// Snoozed todos are left out unless ?snoozed=true.
+func (h *TodoHandler) GetAssigned(c *gin.Context) {
+	html, ok := wantsHTML(c)
+	if !ok {
+		return
+	}
+	snoozed, ok := includeSnoozed(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	userID := c.GetString("user_id")
+	workspaces, err := h.quotas.workspaces.ListByMember(ctx, userID)
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch assigned todos")
+		return
+	}
+
+	todos := []models.Todo{}
+	base, now := apiBase(c), time.Now()
+	for _, workspace := range workspaces {
+		role := workspace.RoleOf(userID)
+		if authz.CanRead(role) != nil {
+			continue
+		}
+		list, err := h.todos.List(ctx, repository.Workspace(workspace.ID))
+		if err != nil {
+			respondStorageError(c, err, "Failed to fetch assigned todos")
+			return
+		}
+		for _, todo := range list {
+			if todo.AssigneeID != userID || todo.Completed || !snoozed && todo.Snoozed(now) {
+				continue
+			}
+			todo.Links = todoLinks(base, &todo, role)
+			todos = append(todos, todo)
+		}
+	}
+	sortByUrgency(todos)
+	if html {
+		renderDescriptions(todos)
+	}
+	c.JSON(http.StatusOK, gin.H{"todos": todos, "_links": collectionLinks(c)})
+}
+
+// assigneeFilter reads ?assignee=, which is a member's user ID, "me" for the
+// current user or "none" for unassigned todos, returning nil when the
+// parameter isn't sent
+func assigneeFilter(c *gin.Context) *string {
+	assignee, ok := c.GetQuery("assignee")
+	if !ok {
+		return nil
+	}
+	switch assignee {
+	case "me":
+		assignee = c.GetString("user_id")
+	case "none":
+		assignee = ""
+	}
+	return &assignee
+}
+
+// withAssignee keeps the todos assigned to assignee, or the unassigned ones
+// when it's empty, reusing the slice's storage
+func withAssignee(todos []models.Todo, assignee string) []models.Todo {
+	kept := todos[:0]
+	for _, todo := range todos {
+		if todo.AssigneeID == assignee {
+			kept = append(kept, todo)
+		}
+	}
+	return kept
+}
+
+// unassignMember clears the assignments of a member leaving the workspace,
+// so their todos can be picked up by others
+func unassignMember(ctx context.Context, todos repository.TodoRepository, workspaceID primitive.ObjectID, userID string) error {
+	list, err := todos.List(ctx, repository.Workspace(workspaceID))
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		_, _, err := rewriteTodo(ctx, todos, &list[i], func(todo *models.Todo) bool {
+			if todo.AssigneeID != userID {
+				return false
+			}
+			todo.AssigneeID = ""
+			return true
+		}, updateIfUnchanged(todos))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
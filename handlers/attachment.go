@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/linkpreview"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AttachmentHandler serves the links attached to todos
+type AttachmentHandler struct {
+	todos    *TodoHandler
+	previews *linkpreview.Fetcher
+	timeout  time.Duration
+}
+
+// NewAttachmentHandler creates an AttachmentHandler that changes todos
+// through todos. previews fetches a preview of each link attached, taking
+// up to timeout; without one, links are attached without previews.
+func NewAttachmentHandler(todos *TodoHandler, previews *linkpreview.Fetcher, timeout time.Duration) *AttachmentHandler {
+	return &AttachmentHandler{todos: todos, previews: previews, timeout: timeout}
+}
+
+// AddAttachment attaches a link to the todo named by :id, with a preview of
+// the page when one can be fetched. Attaching a link that's already there
+// returns the todo as it is.
+func (h *AttachmentHandler) AddAttachment(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	var req models.AddAttachmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+
+	// Check the user may edit the todo before fetching anything for them
+	todo, role, err := todoAccess(ctx, c, h.todos.todos, h.todos.shares, objectID)
+	if err == nil {
+		err = authz.CanWrite(role)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to attach link")
+		return
+	}
+	if attached(todo, req.URL) {
+		linkTodo(c, todo, role)
+		c.JSON(http.StatusOK, gin.H{"todo": todo})
+		return
+	}
+	if len(todo.Attachments) >= h.todos.quotas.maxAttachments() {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "url", Message: fmt.Sprintf("can't be attached; a todo can have at most %d links", h.todos.quotas.maxAttachments())}})
+		return
+	}
+
+	attachment := models.Attachment{
+		ID:      primitive.NewObjectID(),
+		URL:     req.URL,
+		Preview: h.preview(c.Request.Context(), req.URL),
+		AddedBy: userID.(string),
+		AddedAt: time.Now().UTC(),
+	}
+
+	// Fetching may have taken a while; the todo is read afresh so edits
+	// made meanwhile aren't lost
+	ctx, cancel = context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+	h.todos.modifyTodo(ctx, c, objectID, "Failed to attach link", func(todo *models.Todo) {
+		if !attached(todo, attachment.URL) && len(todo.Attachments) < h.todos.quotas.maxAttachments() {
+			todo.Attachments = append(slices.Clone(todo.Attachments), attachment)
+		}
+	})
+}
+
+// RemoveAttachment removes the attachment :attachment_id from the todo
+// named by :id. Removing an attachment that isn't there is a no-op.
+func (h *AttachmentHandler) RemoveAttachment(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+	attachmentID, err := primitive.ObjectIDFromHex(c.Param("attachment_id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid attachment ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+
+	h.todos.modifyTodo(ctx, c, objectID, "Failed to remove link", func(todo *models.Todo) {
+		todo.Attachments = slices.DeleteFunc(slices.Clone(todo.Attachments), func(a models.Attachment) bool {
+			return a.ID == attachmentID
+		})
+	})
+}
+
+// preview fetches the preview of link, or returns nil when previews are off
+// or the page has none. Failures leave the link without a preview rather
+// than failing the request, since links to intranet pages and sites that
+// are down are still worth attaching.
+func (h *AttachmentHandler) preview(ctx context.Context, link string) *models.LinkPreview {
+	if h.previews == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	preview, err := h.previews.Fetch(ctx, link)
+	switch {
+	case err == nil:
+		return preview
+	case errors.Is(err, linkpreview.ErrBlocked), errors.Is(err, linkpreview.ErrNoPreview):
+	default:
+		log.Printf("Link preview of %s failed: %v", linkpreview.Host(link), err)
+	}
+	return nil
+}
+
+// attached reports whether link is attached to todo already
+func attached(todo *models.Todo, link string) bool {
+	return slices.ContainsFunc(todo.Attachments, func(a models.Attachment) bool {
+		return a.URL == link
+	})
+}
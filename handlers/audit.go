@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListAudit pages through the audit trail, newest first. It filters by
+// ?user_id=, ?method=, ?path= (a prefix), ?status= (a code like 404 or a
+// class like 4xx), and ?since= and ?until= (RFC 3339 times). Pass the
+// returned next_before as ?before= for the next page.
+func (h *AdminHandler) ListAudit(c *gin.Context) {
+	q := models.AuditQuery{
+		UserID:     c.Query("user_id"),
+		Method:     strings.ToUpper(c.Query("method")),
+		PathPrefix: c.Query("path"),
+		Limit:      defaultAdminPageSize,
+	}
+
+	var errs []apierrors.FieldError
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAdminPageSize {
+			errs = append(errs, apierrors.FieldError{Field: "limit", Message: "must be a number between 1 and " + strconv.Itoa(maxAdminPageSize)})
+		}
+		q.Limit = n
+	}
+	if v := c.Query("status"); v != "" {
+		var ok bool
+		if q.MinStatus, q.MaxStatus, ok = parseStatusFilter(v); !ok {
+			errs = append(errs, apierrors.FieldError{Field: "status", Message: "must be a status code like 404 or a class like 4xx"})
+		}
+	}
+	for _, bound := range []struct {
+		field string
+		t     *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		if v := c.Query(bound.field); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				errs = append(errs, apierrors.FieldError{Field: bound.field, Message: "must be an RFC 3339 time like 2026-10-16T09:30:00Z"})
+			}
+			*bound.t = t
+		}
+	}
+	if v := c.Query("before"); v != "" {
+		id, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			errs = append(errs, apierrors.FieldError{Field: "before", Message: "must be the next_before of an earlier page"})
+		}
+		q.Before = id
+	}
+	if len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	entries, err := h.stores.Audit.List(ctx, q)
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch audit entries")
+		return
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	response := gin.H{"entries": entries}
+	if len(entries) == q.Limit {
+		response["next_before"] = entries[len(entries)-1].ID.Hex()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// parseStatusFilter reads a status code like 404, or a class like 4xx, as
+// an inclusive range
+func parseStatusFilter(v string) (lo, hi int, ok bool) {
+	if len(v) == 3 && strings.EqualFold(v[1:], "xx") && v[0] >= '1' && v[0] <= '5' {
+		lo = int(v[0]-'0') * 100
+		return lo, lo + 99, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 100 || n > 599 {
+		return 0, 0, false
+	}
+	return n, n, true
+}
@@ -0,0 +1,693 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/auth"
+	"todo-api/ical"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Paths of the CalDAV resources. The root is both the user's principal and
+// their calendar home, which holds a single calendar of their personal
+// todos.
+const (
+	calDAVRoot     = "/caldav/"
+	calDAVCalendar = calDAVRoot + "todos/"
+)
+
+// calDAVProdID names the API in the calendars it serves
+const calDAVProdID = "-//todo-api//CalDAV//EN"
+
+// calDAVObjectType is the content type of each todo's resource
+const calDAVObjectType = ical.ContentType + "; charset=utf-8; component=VTODO"
+
+// calDAVAppName is what app passwords' sessions are listed as
+const calDAVAppName = "CalDAV app password"
+
+// Properties served, by name
+var (
+	propResourceType         = xml.Name{Space: nsDAV, Local: "resourcetype"}
+	propDisplayName          = xml.Name{Space: nsDAV, Local: "displayname"}
+	propCurrentUserPrincipal = xml.Name{Space: nsDAV, Local: "current-user-principal"}
+	propPrincipalURL         = xml.Name{Space: nsDAV, Local: "principal-URL"}
+	propOwner                = xml.Name{Space: nsDAV, Local: "owner"}
+	propPrivileges           = xml.Name{Space: nsDAV, Local: "current-user-privilege-set"}
+	propSupportedReports     = xml.Name{Space: nsDAV, Local: "supported-report-set"}
+	propETag                 = xml.Name{Space: nsDAV, Local: "getetag"}
+	propContentType          = xml.Name{Space: nsDAV, Local: "getcontenttype"}
+	propContentLength        = xml.Name{Space: nsDAV, Local: "getcontentlength"}
+	propLastModified         = xml.Name{Space: nsDAV, Local: "getlastmodified"}
+	propCalendarHome         = xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}
+	propComponents           = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}
+	propCalendarData         = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propCTag                 = xml.Name{Space: nsCalendarServer, Local: "getctag"}
+)
+
+// CalDAVHandler serves the user's personal todos to CalDAV clients, such as
+// Apple Reminders and Thunderbird, as VTODO resources in a calendar. It
+// covers the part of WebDAV and CalDAV (RFC 4918 and 4791) those clients
+// sync with: PROPFIND, the calendar-query and calendar-multiget reports,
+// and GET, PUT and DELETE of single todos.
+type CalDAVHandler struct {
+	todos       *TodoHandler
+	sessions    repository.SessionRepository
+	signer      *auth.CookieSigner
+	passwordTTL time.Duration
+}
+
+// NewCalDAVHandler creates a CalDAVHandler that reads and changes todos
+// through todos. Clients sign in with app passwords, which are sessions
+// signed by signer lasting passwordTTL; signer is nil when users sign in
+// some other way.
+func NewCalDAVHandler(todos *TodoHandler, sessions repository.SessionRepository, signer *auth.CookieSigner, passwordTTL time.Duration) *CalDAVHandler {
+	return &CalDAVHandler{todos: todos, sessions: sessions, signer: signer, passwordTTL: passwordTTL}
+}
+
+// calDAVTodo is a todo as a CalDAV resource
+type calDAVTodo struct {
+	todo *models.Todo
+	name string
+	data []byte
+	etag string
+}
+
+// newCalDAVTodo renders a todo as an iCalendar object. Its ETag is a hash
+// of the object, so it changes exactly when what clients see does.
+func newCalDAVTodo(todo *models.Todo) calDAVTodo {
+	data := todoCalendar(todo).Encode()
+	sum := sha256.Sum256(data)
+	return calDAVTodo{todo: todo, name: calDAVName(todo), data: data, etag: `"` + hex.EncodeToString(sum[:16]) + `"`}
+}
+
+// calDAVName returns the name of a todo's resource in the calendar
+func calDAVName(todo *models.Todo) string {
+	if todo.ICalName != "" {
+		return todo.ICalName
+	}
+	return todo.ID.Hex() + ".ics"
+}
+
+// href returns the path of the resource
+func (t calDAVTodo) href() string {
+	return calDAVCalendar + url.PathEscape(t.name)
+}
+
+// props returns the properties of the resource
+func (t calDAVTodo) props() []davProp {
+	return []davProp{
+		{propResourceType, ""},
+		{propETag, xmlText(t.etag)},
+		{propContentType, calDAVObjectType},
+		{propContentLength, strconv.Itoa(len(t.data))},
+		{propLastModified, t.todo.UpdatedAt.UTC().Format(http.TimeFormat)},
+		{propCalendarData, xmlText(string(t.data))},
+	}
+}
+
+// CreatePassword creates an app password CalDAV clients sign in with,
+// along with the user name and server URL to enter. The password is shown
+// only once; it's listed among the user's sessions, where it can be
+// revoked.
+func (h *CalDAVHandler) CreatePassword(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+
+	now := time.Now()
+	session := &models.Session{
+		ID:        uuid.New().String(),
+		UserID:    userID.(string),
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(h.passwordTTL),
+		UserAgent: calDAVAppName,
+		IP:        c.ClientIP(),
+	}
+	if err := h.sessions.Create(ctx, session); err != nil {
+		respondStorageError(c, err, "Failed to create app password")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"url":        baseURL(c) + calDAVRoot,
+		"username":   session.UserID,
+		"password":   h.signer.AppPassword(session.ID),
+		"session_id": session.ID,
+		"expires_at": session.ExpiresAt,
+	})
+}
+
+// WellKnown points clients looking for the CalDAV service at it (RFC 6764)
+func (h *CalDAVHandler) WellKnown(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, calDAVRoot)
+}
+
+// Options tells clients which WebDAV features are served
+func (h *CalDAVHandler) Options(c *gin.Context) {
+	c.Header("DAV", "1, 3, calendar-access")
+	c.Header("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+	c.Status(http.StatusOK)
+}
+
+// PropfindRoot describes the user's principal and calendar home, and with
+// Depth: 1 the calendar in it
+func (h *CalDAVHandler) PropfindRoot(c *gin.Context) {
+	body, ok := davBody(c, "propfind")
+	if !ok {
+		return
+	}
+	names, all := requestedProps(body)
+
+	self := hrefValue(calDAVRoot)
+	responses := []davResponse{pickProps(calDAVRoot, []davProp{
+		{propResourceType, "<d:collection/><d:principal/>"},
+		{propDisplayName, xmlText(c.GetString("user_id"))},
+		{propCurrentUserPrincipal, self},
+		{propPrincipalURL, self},
+		{propOwner, self},
+		{propCalendarHome, self},
+	}, names, all)}
+
+	if c.GetHeader("Depth") != "0" {
+		todos, ok := h.listTodos(c)
+		if !ok {
+			return
+		}
+		responses = append(responses, pickProps(calDAVCalendar, calendarProps(todos), names, all))
+	}
+	respondMultistatus(c, responses)
+}
+
+// PropfindCalendar describes the calendar, and with Depth: 1 each todo in
+// it
+func (h *CalDAVHandler) PropfindCalendar(c *gin.Context) {
+	body, ok := davBody(c, "propfind")
+	if !ok {
+		return
+	}
+	names, all := requestedProps(body)
+
+	todos, ok := h.listTodos(c)
+	if !ok {
+		return
+	}
+	responses := []davResponse{pickProps(calDAVCalendar, calendarProps(todos), names, all)}
+	if c.GetHeader("Depth") != "0" {
+		for _, t := range todos {
+			responses = append(responses, pickProps(t.href(), t.props(), names, all, propCalendarData))
+		}
+	}
+	respondMultistatus(c, responses)
+}
+
+// PropfindTodo describes one todo
+func (h *CalDAVHandler) PropfindTodo(c *gin.Context) {
+	body, ok := davBody(c, "propfind")
+	if !ok {
+		return
+	}
+	names, all := requestedProps(body)
+
+	t, ok := h.findTodo(c, c.Param("name"))
+	if !ok {
+		return
+	}
+	if t == nil {
+		apierrors.Respond(c, apierrors.CodeTodoNotFound, "Todo not found")
+		return
+	}
+	respondMultistatus(c, []davResponse{pickProps(t.href(), t.props(), names, all, propCalendarData)})
+}
+
+// calendarProps returns the properties of the calendar holding todos. Its
+// ctag changes whenever any todo in it does, so clients know to sync.
+func calendarProps(todos []calDAVTodo) []davProp {
+	tag := sha256.New()
+	for _, t := range todos {
+		tag.Write([]byte(t.name + "\x00" + t.etag + "\x00"))
+	}
+	owner := hrefValue(calDAVRoot)
+	return []davProp{
+		{propResourceType, "<d:collection/><c:calendar/>"},
+		{propDisplayName, "Todos"},
+		{propComponents, `<c:comp name="VTODO"/>`},
+		{propCTag, hex.EncodeToString(tag.Sum(nil)[:16])},
+		{propCurrentUserPrincipal, owner},
+		{propOwner, owner},
+		{propPrivileges, "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>" +
+			"<d:privilege><d:write-content/></d:privilege><d:privilege><d:bind/></d:privilege><d:privilege><d:unbind/></d:privilege>"},
+		{propSupportedReports, "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>"},
+	}
+}
+
+// Report answers the calendar-query and calendar-multiget reports on the
+// calendar. Queries are only filtered by component and by whether todos
+// are completed, the filter clients use to fetch open tasks; clients apply
+// other filters, such as time ranges, to what they get.
+func (h *CalDAVHandler) Report(c *gin.Context) {
+	body, ok := davBody(c, "")
+	if !ok {
+		return
+	}
+	if body.name.Space != nsCalDAV || (body.name.Local != "calendar-query" && body.name.Local != "calendar-multiget") {
+		apierrors.Respond(c, apierrors.CodeForbidden, "Only the calendar-query and calendar-multiget reports are supported")
+		return
+	}
+	names, all := requestedProps(body)
+
+	todos, ok := h.listTodos(c)
+	if !ok {
+		return
+	}
+	var responses []davResponse
+	if body.name.Local == "calendar-multiget" {
+		byName := make(map[string]calDAVTodo, len(todos))
+		for _, t := range todos {
+			byName[t.name] = t
+		}
+		for _, href := range body.children {
+			if href.name != (xml.Name{Space: nsDAV, Local: "href"}) {
+				continue
+			}
+			t, found := byName[hrefName(href.text)]
+			if !found {
+				responses = append(responses, davResponse{href: strings.TrimSpace(href.text), status: http.StatusNotFound})
+				continue
+			}
+			responses = append(responses, pickProps(t.href(), t.props(), names, all, propCalendarData))
+		}
+	} else {
+		component, openOnly := queryFilter(body)
+		for _, t := range todos {
+			if component == "VTODO" && !(openOnly && t.todo.Completed) {
+				responses = append(responses, pickProps(t.href(), t.props(), names, all, propCalendarData))
+			}
+		}
+	}
+	respondMultistatus(c, responses)
+}
+
+// queryFilter reads the component a calendar-query asks for, and whether
+// it only wants todos that aren't completed
+func queryFilter(query *xmlNode) (component string, openOnly bool) {
+	filter := query.child(nsCalDAV, "filter")
+	if filter == nil {
+		return "VTODO", false
+	}
+	calendar := filter.child(nsCalDAV, "comp-filter")
+	if calendar == nil {
+		return "VTODO", false
+	}
+	inner := calendar.child(nsCalDAV, "comp-filter")
+	if inner == nil {
+		return "VTODO", false
+	}
+	for _, f := range inner.children {
+		if f.name != (xml.Name{Space: nsCalDAV, Local: "prop-filter"}) {
+			continue
+		}
+		switch strings.ToUpper(f.attr("name")) {
+		case "COMPLETED":
+			openOnly = openOnly || f.child(nsCalDAV, "is-not-defined") != nil
+		case "STATUS":
+			match := f.child(nsCalDAV, "text-match")
+			openOnly = openOnly || match != nil && match.attr("negate-condition") == "yes" &&
+				strings.EqualFold(strings.TrimSpace(match.text), "COMPLETED")
+		}
+	}
+	return strings.ToUpper(inner.attr("name")), openOnly
+}
+
+// hrefName returns the name of the resource an href in the calendar points
+// at, or "" for one elsewhere
+func hrefName(href string) string {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	name, ok := strings.CutPrefix(u.Path, calDAVCalendar)
+	if !ok || strings.Contains(name, "/") {
+		return ""
+	}
+	return name
+}
+
+// GetTodo returns a todo as an iCalendar object
+func (h *CalDAVHandler) GetTodo(c *gin.Context) {
+	t, ok := h.findTodo(c, c.Param("name"))
+	if !ok {
+		return
+	}
+	if t == nil {
+		apierrors.Respond(c, apierrors.CodeTodoNotFound, "Todo not found")
+		return
+	}
+	c.Header("ETag", t.etag)
+	c.Header("Last-Modified", t.todo.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, calDAVObjectType, t.data)
+}
+
+// PutTodo creates or replaces a todo from the VTODO a client sends. The
+// title, description, due date, priority and completion are taken from it;
+// other properties of the VTODO, such as alarms and recurrence, aren't
+// kept, and the todo's other fields, such as its color, are left as they
+// are. If-Match and If-None-Match are honoured, so clients don't overwrite
+// changes they haven't seen.
+func (h *CalDAVHandler) PutTodo(c *gin.Context) {
+	userID := c.GetString("user_id")
+	name := c.Param("name")
+	if !strings.HasSuffix(name, ".ics") || len(name) > 255 {
+		apierrors.Respond(c, apierrors.CodeInvalidRequest, "Todo resource names must end in .ics and be at most 255 bytes")
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type")); mediaType != ical.ContentType {
+		apierrors.Respond(c, apierrors.CodeUnsupportedMediaType, "Request body must be sent as "+ical.ContentType)
+		return
+	}
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	calendar, err := ical.Parse(data)
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidRequest, "Request body must be an iCalendar object: "+err.Error())
+		return
+	}
+	vtodo := calendar.Child("VTODO")
+	if vtodo == nil {
+		apierrors.Respond(c, apierrors.CodeInvalidRequest, "The calendar only holds todos; send a VTODO")
+		return
+	}
+	uid := vtodo.Text("UID")
+	if uid == "" {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "UID", Message: "is required"}})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+
+	loc, ok := h.todos.settings.location(ctx, c)
+	if !ok {
+		return
+	}
+	req, errs := todoFromVTODO(vtodo, loc)
+	if len(errs) == 0 {
+		req.Normalize()
+		errs = req.Validate()
+	}
+	if len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	existing, ok := h.findTodo(c, name)
+	if !ok || !checkPreconditions(c, existing) {
+		return
+	}
+
+	if existing == nil {
+		scope := repository.Personal(userID)
+		u, ok := h.todos.quotas.allowTodo(ctx, c, scope)
+		if !ok {
+			return
+		}
+		now := time.Now()
+		todo := &models.Todo{UserID: userID, ICalUID: uid, ICalName: name, CreatedAt: now, UpdatedAt: now}
+		applyUpdate(todo, req, loc)
+		if err := h.todos.createTodo(ctx, todo); err != nil {
+			respondStorageError(c, err, "Failed to create todo")
+			return
+		}
+		// Only the header, as CalDAV responses have no body
+		h.todos.quotas.todoWarnings(c, scope, u.add(1))
+		c.Status(http.StatusCreated)
+		return
+	}
+
+	todo := existing.todo
+	if req.Completed != nil && *req.Completed && (!checkChecklist(c, todo) || !h.todos.checkBlockers(ctx, c, todo)) {
+		return
+	}
+	before := models.RevisionSnapshot(todo)
+	applyUpdate(todo, req, loc)
+	todo.UpdatedAt = time.Now()
+	// The preconditions were checked against the todo as read, so a change
+	// made since can't be written over
+	err = h.todos.saveEdit(ctx, &before, todo)
+	if errors.Is(err, repository.ErrConflict) {
+		apierrors.Respond(c, apierrors.CodeTodoConflict, "The todo was changed while it was being updated; fetch it and try again")
+		return
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodeTodoNotFound, "Todo not found")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to update todo")
+		return
+	}
+	h.todos.recordRevision(ctx, c.GetString("user_id"), &before, todo)
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteTodo deletes a todo. Deletes made through CalDAV can't be undone.
+func (h *CalDAVHandler) DeleteTodo(c *gin.Context) {
+	t, ok := h.findTodo(c, c.Param("name"))
+	if !ok {
+		return
+	}
+	if t == nil {
+		apierrors.Respond(c, apierrors.CodeTodoNotFound, "Todo not found")
+		return
+	}
+	if !checkPreconditions(c, t) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+
+	if _, err := h.todos.removeTodo(ctx, t.todo); err != nil {
+		respondTodoError(c, err, "Failed to delete todo")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// checkPreconditions responds with 412 and returns false when the request's
+// If-Match or If-None-Match header doesn't hold for t, which is nil when the
+// resource doesn't exist
+func checkPreconditions(c *gin.Context, t *calDAVTodo) bool {
+	if match := c.GetHeader("If-Match"); match != "" {
+		if t == nil || (match != "*" && !strings.Contains(match, t.etag)) {
+			apierrors.Respond(c, apierrors.CodePreconditionFailed, "The todo was changed or deleted since it was fetched")
+			return false
+		}
+	}
+	if c.GetHeader("If-None-Match") == "*" && t != nil {
+		apierrors.Respond(c, apierrors.CodePreconditionFailed, "A todo with this name exists already")
+		return false
+	}
+	return true
+}
+
+// listTodos returns the user's personal todos as resources, or responds
+// with an error and returns false
+func (h *CalDAVHandler) listTodos(c *gin.Context) ([]calDAVTodo, bool) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+
+	todos, err := h.todos.todos.List(ctx, repository.Personal(c.GetString("user_id")))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch todos")
+		return nil, false
+	}
+	sort.Slice(todos, func(i, j int) bool { return todos[i].CreatedAt.Before(todos[j].CreatedAt) })
+	resources := make([]calDAVTodo, len(todos))
+	for i := range todos {
+		resources[i] = newCalDAVTodo(&todos[i])
+	}
+	return resources, true
+}
+
+// findTodo returns the user's todo whose resource is named name, or nil if
+// there is none. Todos created through the API are named by their ID, so
+// they're fetched directly; others are looked for among all of them.
+func (h *CalDAVHandler) findTodo(c *gin.Context, name string) (*calDAVTodo, bool) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+
+	if id, err := primitive.ObjectIDFromHex(strings.TrimSuffix(name, ".ics")); err == nil {
+		todo, err := h.todos.todos.Get(ctx, repository.Personal(c.GetString("user_id")), id)
+		switch {
+		case err == nil && calDAVName(todo) == name:
+			t := newCalDAVTodo(todo)
+			return &t, true
+		case err != nil && !errors.Is(err, repository.ErrNotFound):
+			respondStorageError(c, err, "Failed to fetch todo")
+			return nil, false
+		}
+	}
+
+	todos, ok := h.listTodos(c)
+	if !ok {
+		return nil, false
+	}
+	for i := range todos {
+		if todos[i].name == name {
+			return &todos[i], true
+		}
+	}
+	return nil, true
+}
+
+// davBody parses the XML body of a request, which must be a root element
+// named root in the DAV: namespace unless root is "". A request without a
+// body yields nil.
+func davBody(c *gin.Context, root string) (*xmlNode, bool) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondBindError(c, err)
+		return nil, false
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		if root == "" {
+			apierrors.Respond(c, apierrors.CodeInvalidRequest, "Request body must name a report")
+			return nil, false
+		}
+		return nil, true
+	}
+	node, err := parseXML(data)
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidRequest, "Request body must be valid XML")
+		return nil, false
+	}
+	if root != "" && node.name != (xml.Name{Space: nsDAV, Local: root}) {
+		apierrors.Respond(c, apierrors.CodeInvalidRequest, "Request body must be a "+root+" element")
+		return nil, false
+	}
+	return node, true
+}
+
+// todoCalendar returns a todo as a VCALENDAR holding a VTODO. DTSTAMP is
+// when the todo last changed, so the object only changes when the todo
+// does.
+func todoCalendar(todo *models.Todo) *ical.Component {
+	vtodo := ical.NewComponent("VTODO")
+	uid := todo.ICalUID
+	if uid == "" {
+		uid = todo.ID.Hex()
+	}
+	vtodo.AddText("UID", uid)
+	vtodo.AddTime("DTSTAMP", todo.UpdatedAt)
+	vtodo.AddTime("CREATED", todo.CreatedAt)
+	vtodo.AddTime("LAST-MODIFIED", todo.UpdatedAt)
+	vtodo.AddText("SUMMARY", todo.Title)
+	if todo.Description != "" {
+		vtodo.AddText("DESCRIPTION", todo.Description)
+	}
+	if todo.DueDate != nil {
+		vtodo.AddTime("DUE", *todo.DueDate)
+	}
+	if priority := icalPriority(todo.Priority); priority != 0 {
+		vtodo.Add("PRIORITY", strconv.Itoa(priority))
+	}
+	if todo.Completed {
+		vtodo.Add("STATUS", "COMPLETED")
+		vtodo.Add("PERCENT-COMPLETE", "100")
+		if todo.CompletedAt != nil {
+			vtodo.AddTime("COMPLETED", *todo.CompletedAt)
+		}
+	} else {
+		vtodo.Add("STATUS", "NEEDS-ACTION")
+	}
+
+	calendar := ical.NewComponent("VCALENDAR")
+	calendar.Add("VERSION", "2.0")
+	calendar.Add("PRODID", calDAVProdID)
+	calendar.Components = append(calendar.Components, vtodo)
+	return calendar
+}
+
+// todoFromVTODO reads the fields of a VTODO the API keeps as an update that
+// sets each of them, clearing those the VTODO leaves out. Dates without a
+// time, as all-day tasks have, are read in loc.
+func todoFromVTODO(vtodo *ical.Component, loc *time.Location) (*models.UpdateTodoRequest, []apierrors.FieldError) {
+	title, description := vtodo.Text("SUMMARY"), vtodo.Text("DESCRIPTION")
+	completed := strings.EqualFold(vtodo.Text("STATUS"), "COMPLETED") || vtodo.Prop("COMPLETED") != nil
+	req := &models.UpdateTodoRequest{Title: &title, Description: &description, Completed: &completed}
+
+	var errs []apierrors.FieldError
+	dueDate := ""
+	if due := vtodo.Prop("DUE"); due != nil {
+		t, dateOnly, err := due.Time(loc)
+		switch {
+		case err != nil:
+			errs = append(errs, apierrors.FieldError{Field: "DUE", Message: "must be a DATE or DATE-TIME"})
+		case dateOnly:
+			dueDate = t.Format(time.DateOnly)
+		default:
+			dueDate = t.UTC().Format(time.RFC3339)
+		}
+	}
+	req.DueDate = &dueDate
+
+	priority := models.Priority("")
+	if value := vtodo.Text("PRIORITY"); value != "" {
+		n, err := strconv.Atoi(value)
+		switch {
+		case err != nil || n < 0 || n > 9:
+			errs = append(errs, apierrors.FieldError{Field: "PRIORITY", Message: "must be a number from 0 to 9"})
+		case n >= 1 && n <= 4:
+			priority = models.PriorityHigh
+		case n == 5:
+			priority = models.PriorityMedium
+		case n >= 6:
+			priority = models.PriorityLow
+		}
+	}
+	req.Priority = &priority
+	return req, errs
+}
+
+// icalPriority maps a priority onto iCalendar's scale, where 1 is the
+// highest, 9 the lowest and 0 undefined
+func icalPriority(p models.Priority) int {
+	switch p {
+	case models.PriorityHigh:
+		return 1
+	case models.PriorityMedium:
+		return 5
+	case models.PriorityLow:
+		return 9
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// exportPageSize is how many todos or tombstones a change export reads at
+// a time
+const exportPageSize = 500
+
+// Ops of the lines of a change export
+const (
+	changeDelete = "delete"
+	changeUpsert = "upsert"
+	changeEnd    = "end"
+)
+
+// exportedChange is a line of a change export: a todo as it is now, or the
+// deletion of one
+type exportedChange struct {
+	Op          string              `json:"op"`
+	ID          primitive.ObjectID  `json:"id"`
+	UserID      string              `json:"user_id"`
+	WorkspaceID *primitive.ObjectID `json:"workspace_id,omitempty"`
+	// At is when the todo was last updated, or deleted
+	At   time.Time    `json:"at"`
+	Todo *models.Todo `json:"todo,omitempty"`
+}
+
+// ExportChanges streams the todos of every user and workspace created,
+// edited or deleted since ?since= (an RFC 3339 time) as newline-delimited
+// JSON, for loading into a warehouse. Deletes come first, so a todo that
+// was deleted and restored ends up present. Without since every todo is
+// exported and no deletes. The last line is an "end" line whose next_since
+// is what to pass as since next time; a stream without one was cut short
+// and should be fetched again.
+func (h *AdminHandler) ExportChanges(c *gin.Context) {
+	var since time.Time
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "since", Message: "must be an RFC 3339 time like 2026-10-16T09:30:00Z"}})
+			return
+		}
+		if time.Since(t) > h.syncWindow {
+			apierrors.Respond(c, apierrors.CodeSyncTokenExpired, "Deletes this far back are no longer known; export again without since")
+			return
+		}
+		since = t
+	}
+
+	// Taken before reading, so whatever changes during the export is
+	// included next time
+	next := time.Now().Add(-syncOverlap).UTC().Truncate(time.Second)
+
+	started := false
+	enc := json.NewEncoder(c.Writer)
+	write := func(line any) {
+		if !started {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+			started = true
+		}
+		enc.Encode(line)
+	}
+	// fail responds with an error if nothing was sent yet; otherwise the
+	// stream ends without its end line
+	fail := func(err error) {
+		if !started {
+			respondStorageError(c, err, "Failed to export changes")
+			return
+		}
+		log.Printf("Change export since %s failed: %v", since.Format(time.RFC3339), err)
+	}
+
+	if !since.IsZero() {
+		var after primitive.ObjectID
+		for {
+			ctx, cancel := streamContext(c, h.timeout)
+			tombstones, err := h.stores.Tombstones.ListAllSince(ctx, since, after, exportPageSize)
+			cancel()
+			if err != nil {
+				fail(err)
+				return
+			}
+			for _, tombstone := range tombstones {
+				write(exportedChange{Op: changeDelete, ID: tombstone.TodoID, UserID: tombstone.UserID, WorkspaceID: tombstone.WorkspaceID, At: tombstone.DeletedAt})
+			}
+			if len(tombstones) < exportPageSize {
+				break
+			}
+			after = tombstones[len(tombstones)-1].TodoID
+			c.Writer.Flush()
+		}
+	}
+
+	var after primitive.ObjectID
+	for {
+		ctx, cancel := streamContext(c, h.timeout)
+		todos, err := h.stores.Todos.ListChanged(ctx, since, after, exportPageSize)
+		cancel()
+		if err != nil {
+			fail(err)
+			return
+		}
+		for i := range todos {
+			todo := &todos[i]
+			write(exportedChange{Op: changeUpsert, ID: todo.ID, UserID: todo.UserID, WorkspaceID: todo.WorkspaceID, At: todo.UpdatedAt, Todo: todo})
+		}
+		if len(todos) < exportPageSize {
+			break
+		}
+		after = todos[len(todos)-1].ID
+		c.Writer.Flush()
+	}
+
+	write(struct {
+		Op        string `json:"op"`
+		NextSince string `json:"next_since"`
+	}{changeEnd, next.Format(time.RFC3339)})
+	c.Writer.Flush()
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AddChecklistItem adds an item to the end of the checklist of the todo
+// named by :id. Under a checklist rule, a completed todo is reopened.
+func (h *TodoHandler) AddChecklistItem(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	var req models.AddChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	item := models.ChecklistItem{ID: primitive.NewObjectID(), Title: req.Title}
+	h.saveChecklist(ctx, c, objectID, "Failed to add checklist item", func(todo *models.Todo) bool {
+		if len(todo.Checklist) >= models.MaxChecklistItems {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "title", Message: fmt.Sprintf("can't be added; a checklist has at most %d items", models.MaxChecklistItems)}})
+			return false
+		}
+		todo.Checklist = append(slices.Clone(todo.Checklist), item)
+		return true
+	})
+}
+
+// UpdateChecklistItem renames, completes or reopens the item :item_id of
+// the todo named by :id, completing or reopening the todo as its checklist
+// rule says in the same write
+func (h *TodoHandler) UpdateChecklistItem(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+	itemID, err := primitive.ObjectIDFromHex(c.Param("item_id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid checklist item ID")
+		return
+	}
+
+	var req models.UpdateChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	h.saveChecklist(ctx, c, objectID, "Failed to update checklist item", func(todo *models.Todo) bool {
+		i := slices.IndexFunc(todo.Checklist, func(item models.ChecklistItem) bool { return item.ID == itemID })
+		if i < 0 {
+			apierrors.Respond(c, apierrors.CodeChecklistItemNotFound, "Checklist item not found")
+			return false
+		}
+		// Items may share their array with the store's copy
+		todo.Checklist = slices.Clone(todo.Checklist)
+		item := &todo.Checklist[i]
+		if req.Title != nil {
+			item.Title = *req.Title
+		}
+		if req.Completed != nil {
+			item.SetCompleted(*req.Completed, time.Now().UTC())
+		}
+		return true
+	})
+}
+
+// RemoveChecklistItem removes the item :item_id from the checklist of the
+// todo named by :id. Removing the last open item completes a todo under
+// ChecklistRuleAutoComplete. Removing an item that isn't there is a no-op.
+func (h *TodoHandler) RemoveChecklistItem(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+	itemID, err := primitive.ObjectIDFromHex(c.Param("item_id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid checklist item ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	h.modifyTodo(ctx, c, objectID, "Failed to remove checklist item", func(todo *models.Todo) {
+		n := len(todo.Checklist)
+		todo.Checklist = slices.DeleteFunc(slices.Clone(todo.Checklist), func(item models.ChecklistItem) bool {
+			return item.ID == itemID
+		})
+		if len(todo.Checklist) < n {
+			todo.ApplyChecklistRule(time.Now().UTC())
+		}
+	})
+}
+
+// saveChecklist applies change to the checklist of a todo the user may
+// edit, brings the todo's completion in line with its checklist rule and
+// saves both together, then responds with the todo. Like saveTodo, it
+// applies change afresh to a todo changed since it was read; change may
+// refuse by responding with an error and returning false.
+func (h *TodoHandler) saveChecklist(ctx context.Context, c *gin.Context, id primitive.ObjectID, message string, change func(*models.Todo) bool) {
+	todo, role, ok := h.saveTodoIf(ctx, c, id, message, func(todo *models.Todo) bool {
+		if !change(todo) {
+			return false
+		}
+		todo.ApplyChecklistRule(time.Now().UTC())
+		return true
+	})
+	if !ok {
+		return
+	}
+	linkTodo(c, todo, role)
+	c.JSON(http.StatusOK, gin.H{"todo": todo})
+}
+
+// checkChecklist refuses to complete a todo under ChecklistRuleBlock while
+// items of its checklist are open. It responds with an error and returns
+// false if the todo can't be completed.
+func checkChecklist(c *gin.Context, todo *models.Todo) bool {
+	if problem := checklistProblem(todo); problem != nil {
+		apierrors.Write(c, problem)
+		return false
+	}
+	return true
+}
+
+// checklistProblem reports a todo under ChecklistRuleBlock that can't be
+// completed while items of its checklist are open, or returns nil
+func checklistProblem(todo *models.Todo) *apierrors.Problem {
+	if todo.ChecklistRule != models.ChecklistRuleBlock {
+		return nil
+	}
+	var open []string
+	for _, item := range todo.Checklist {
+		if !item.Completed {
+			open = append(open, item.ID.Hex())
+		}
+	}
+	if len(open) == 0 {
+		return nil
+	}
+	return apierrors.New(apierrors.CodeChecklistIncomplete, "Complete the open checklist items first: "+strings.Join(open, ", "))
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/events"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxInboxMentions is how many mentions the inbox lists
+const maxInboxMentions = 100
+
+// CommentHandler serves the comments left on todos and the inbox of
+// comments mentioning the user
+type CommentHandler struct {
+	todos *TodoHandler
+}
+
+// NewCommentHandler creates a CommentHandler that reads and changes todos
+// through todos
+func NewCommentHandler(todos *TodoHandler) *CommentHandler {
+	return &CommentHandler{todos: todos}
+}
+
+// ListComments returns the comments on a todo the user can read, oldest
+// first
+func (h *CommentHandler) ListComments(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+
+	todo, role, err := todoAccess(ctx, c, h.todos.todos, h.todos.shares, objectID)
+	if err == nil {
+		err = authz.CanRead(role)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to fetch comments")
+		return
+	}
+
+	comments := todo.Comments
+	if comments == nil {
+		comments = []models.Comment{}
+	}
+	c.JSON(http.StatusOK, gin.H{"comments": comments})
+}
+
+// AddComment leaves a comment on a todo. Anyone who can read the todo can
+// comment, viewers included. Users mentioned as @<user ID> have to be able
+// to read it too; they're told through a comment.created event.
+func (h *CommentHandler) AddComment(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	var req models.AddCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+
+	userID := c.GetString("user_id")
+	// Mentioning yourself tells no one
+	mentions := slices.DeleteFunc(models.ParseMentions(req.Body), func(id string) bool { return id == userID })
+	comment := models.Comment{
+		ID:        primitive.NewObjectID(),
+		AuthorID:  userID,
+		Body:      req.Body,
+		Mentions:  mentions,
+		CreatedAt: time.Now().UTC(),
+	}
+	// Commenting needs only read access, and the comment is written like
+	// any other change, so one made to the todo meanwhile isn't lost
+	_, _, _, ok := h.todos.writeTodo(ctx, c, objectID, "Failed to add comment", authz.CanRead, func(todo *models.Todo, _ authz.Role) bool {
+		if len(todo.Comments) >= models.MaxComments {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "body", Message: fmt.Sprintf("can't be added; a todo keeps at most %d comments", models.MaxComments)}})
+			return false
+		}
+		denied, err := h.withoutAccess(ctx, todo, mentions)
+		if err != nil {
+			respondStorageError(c, err, "Failed to add comment")
+			return false
+		}
+		if len(denied) > 0 {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "body", Message: "mentions users who can't see this todo: @" + strings.Join(denied, ", @")}})
+			return false
+		}
+		todo.Comments = append(slices.Clone(todo.Comments), comment)
+		return true
+	}, func(_, todo *models.Todo) []events.Event {
+		return []events.Event{commentEvent(events.CommentCreated, todo, comment)}
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"comment": comment})
+}
+
+// DeleteComment removes a comment. Authors can remove their own comments,
+// and admins and owners anyone's. Removing a comment that isn't there is a
+// no-op.
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+	commentID, err := primitive.ObjectIDFromHex(c.Param("comment_id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid comment ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+
+	_, _, _, ok := h.todos.writeTodo(ctx, c, objectID, "Failed to delete comment", authz.CanRead, func(todo *models.Todo, role authz.Role) bool {
+		i := slices.IndexFunc(todo.Comments, func(comment models.Comment) bool { return comment.ID == commentID })
+		if i < 0 {
+			c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+			return false
+		}
+		if authz.CanModerate(role, todo.Comments[i].AuthorID == c.GetString("user_id")) != nil {
+			apierrors.Respond(c, apierrors.CodeForbidden, "Only the author, admins and owners can delete a comment")
+			return false
+		}
+		todo.Comments = slices.Delete(slices.Clone(todo.Comments), i, i+1)
+		return true
+	}, nil)
+	if ok {
+		c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+	}
+}
+
+// GetMentions handles GET /mentions, the inbox of comments mentioning the
+// user on the todos they can still read: their own, those shared with them
+// and their workspaces'. The latest 100 are listed, newest first.
+func (h *CommentHandler) GetMentions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+
+	userID := c.GetString("user_id")
+	todos, err := h.todos.todos.List(ctx, repository.Personal(userID))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch mentions")
+		return
+	}
+	shares, err := h.todos.shares.ListByGrantee(ctx, granteeKeys(c))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch mentions")
+		return
+	}
+	seen := map[primitive.ObjectID]bool{}
+	for _, share := range shares {
+		if seen[share.TodoID] {
+			continue
+		}
+		seen[share.TodoID] = true
+		todo, err := h.todos.todos.Get(ctx, repository.Personal(share.OwnerID), share.TodoID)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			respondStorageError(c, err, "Failed to fetch mentions")
+			return
+		}
+		todos = append(todos, *todo)
+	}
+	workspaces, err := h.todos.quotas.workspaces.ListByMember(ctx, userID)
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch mentions")
+		return
+	}
+	for _, workspace := range workspaces {
+		if authz.CanRead(workspace.RoleOf(userID)) != nil {
+			continue
+		}
+		list, err := h.todos.todos.List(ctx, repository.Workspace(workspace.ID))
+		if err != nil {
+			respondStorageError(c, err, "Failed to fetch mentions")
+			return
+		}
+		todos = append(todos, list...)
+	}
+
+	mentions := []models.TodoComment{}
+	base := apiBase(c)
+	for i := range todos {
+		todo := &todos[i]
+		for _, comment := range todo.Comments {
+			if !comment.Mentioned(userID) {
+				continue
+			}
+			mention := todoComment(todo, comment)
+			mention.Links = map[string]models.Link{"todo": todoLinks(base, todo, authz.RoleViewer)["self"]}
+			mentions = append(mentions, mention)
+		}
+	}
+	slices.SortFunc(mentions, func(a, b models.TodoComment) int {
+		return b.Comment.CreatedAt.Compare(a.Comment.CreatedAt)
+	})
+	mentions = mentions[:min(len(mentions), maxInboxMentions)]
+	c.JSON(http.StatusOK, gin.H{"mentions": mentions, "_links": collectionLinks(c)})
+}
+
+// withoutAccess returns the users among ids who can't read the todo: for a
+// workspace todo those who aren't members, and for a personal todo anyone
+// but its owner and the users it's shared with by ID
+func (h *CommentHandler) withoutAccess(ctx context.Context, todo *models.Todo, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	allowed := map[string]bool{}
+	if todo.WorkspaceID != nil {
+		workspace, err := h.todos.quotas.workspaces.Get(ctx, *todo.WorkspaceID)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range workspace.Members {
+			allowed[member.UserID] = true
+		}
+	} else {
+		allowed[todo.UserID] = true
+		shares, err := h.todos.shares.ListByTodo(ctx, todo.UserID, todo.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			if share.UserID != "" {
+				allowed[share.UserID] = true
+			}
+		}
+	}
+	var denied []string
+	for _, id := range ids {
+		if !allowed[id] {
+			denied = append(denied, id)
+		}
+	}
+	return denied, nil
+}
+
+// todoComment pairs a comment with the todo it's on
+func todoComment(todo *models.Todo, comment models.Comment) models.TodoComment {
+	return models.TodoComment{TodoID: todo.ID, WorkspaceID: todo.WorkspaceID, TodoTitle: todo.Title, Comment: comment}
+}
+
+// commentEvent describes a change to a comment on todo
+func commentEvent(eventType string, todo *models.Todo, comment models.Comment) events.Event {
+	return events.New(eventType, "todos/"+todo.ID.Hex()+"/comments/"+comment.ID.Hex(), todoComment(todo, comment))
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// completedAfterFilter reads ?completed_after=, a date or timestamp like
+// due_date, returning nil when the parameter isn't sent. Plain dates mean
+// the start of that day in the user's time zone. On an invalid value it
+// responds with an error and ok is false.
+func (h *TodoHandler) completedAfterFilter(ctx context.Context, c *gin.Context) (after *time.Time, ok bool) {
+	v := c.Query("completed_after")
+	if v == "" {
+		return nil, true
+	}
+	if _, err := models.ParseDueDate(v, time.UTC); err != nil {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "completed_after", Message: "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"}})
+		return nil, false
+	}
+	loc, ok := h.settings.location(ctx, c)
+	if !ok {
+		return nil, false
+	}
+	t, _ := models.ParseDueDate(v, loc)
+	return &t, true
+}
+
+// withCompletedAfter keeps the completed todos whose CompletedAt is at or
+// after since, reusing the slice's storage
+func withCompletedAfter(todos []models.Todo, since time.Time) []models.Todo {
+	kept := todos[:0]
+	for _, todo := range todos {
+		if todo.Completed && todo.CompletedAt != nil && !todo.CompletedAt.Before(since) {
+			kept = append(kept, todo)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/http"
+
+	"todo-api/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CSRFToken returns the CSRF token SPAs must send in the X-CSRF-Token header
+// of mutating requests
+func CSRFToken(signer *auth.CookieSigner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, gin.H{"csrf_token": signer.CSRFToken(c.GetString("user_id"))})
+	}
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CustomFieldHandler serves the endpoints that define custom fields. Values
+// are set through the todo endpoints.
+type CustomFieldHandler struct {
+	fields  repository.CustomFieldRepository
+	todos   *TodoHandler
+	timeout time.Duration
+}
+
+// NewCustomFieldHandler creates a CustomFieldHandler. todos is needed to
+// clear a field's values when it's deleted, as an edit of each todo.
+func NewCustomFieldHandler(fields repository.CustomFieldRepository, todos *TodoHandler, timeout time.Duration) *CustomFieldHandler {
+	return &CustomFieldHandler{fields: fields, todos: todos, timeout: timeout}
+}
+
+// ListCustomFields returns the fields defined for the request's scope
+func (h *CustomFieldHandler) ListCustomFields(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	fields, err := h.fields.List(ctx, todoScope(c, userID.(string)))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch custom fields")
+		return
+	}
+	if fields == nil {
+		fields = []models.CustomField{}
+	}
+	c.JSON(http.StatusOK, gin.H{"custom_fields": fields})
+}
+
+// CreateCustomField defines a field. In a workspace this changes what every
+// member sees, so only owners and admins may do it.
+func (h *CustomFieldHandler) CreateCustomField(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+	if err := authz.Authorize(scopeRole(c), authz.ActionManageWorkspace); err != nil {
+		apierrors.Respond(c, apierrors.CodeForbidden, "Only owners and admins can change custom fields")
+		return
+	}
+
+	var req models.CreateCustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	scope := todoScope(c, userID.(string))
+	existing, err := h.fields.List(ctx, scope)
+	if err != nil {
+		respondStorageError(c, err, "Failed to create custom field")
+		return
+	}
+	if len(existing) >= models.MaxCustomFields {
+		apierrors.Respond(c, apierrors.CodeQuotaExceeded,
+			fmt.Sprintf("There can be at most %d custom fields; delete one to add another", models.MaxCustomFields))
+		return
+	}
+	// Names have to be unique, since values can be set and filtered by name
+	if fieldByKey(existing, req.Name) != nil {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "name", Message: "is already used by another custom field"}})
+		return
+	}
+
+	field := models.CustomField{
+		UserID:    userID.(string),
+		Name:      req.Name,
+		Type:      req.Type,
+		Options:   req.Options,
+		CreatedAt: time.Now(),
+	}
+	if scope.IsWorkspace() {
+		field.WorkspaceID = &scope.WorkspaceID
+	}
+	if err := h.fields.Create(ctx, &field); err != nil {
+		respondStorageError(c, err, "Failed to create custom field")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"custom_field": field})
+}
+
+// DeleteCustomField deletes a field along with its values on every todo
+func (h *CustomFieldHandler) DeleteCustomField(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+	objectID, err := primitive.ObjectIDFromHex(c.Param("field_id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid custom field ID")
+		return
+	}
+	if err := authz.Authorize(scopeRole(c), authz.ActionManageWorkspace); err != nil {
+		apierrors.Respond(c, apierrors.CodeForbidden, "Only owners and admins can change custom fields")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	scope := todoScope(c, userID.(string))
+	fields, err := h.fields.List(ctx, scope)
+	if err != nil {
+		respondStorageError(c, err, "Failed to delete custom field")
+		return
+	}
+	if fieldByKey(fields, objectID.Hex()) == nil {
+		apierrors.Respond(c, apierrors.CodeCustomFieldNotFound, "Custom field not found")
+		return
+	}
+
+	// Clear the values first so a failure leaves the field in place to
+	// retry, rather than orphaning its values
+	todos, err := h.todos.todos.List(ctx, scope)
+	if err != nil {
+		respondStorageError(c, err, "Failed to delete custom field")
+		return
+	}
+	for i := range todos {
+		todo, before, err := rewriteTodo(ctx, h.todos.todos, &todos[i], func(todo *models.Todo) bool {
+			if _, ok := todo.CustomFields[objectID.Hex()]; !ok {
+				return false
+			}
+			todo.CustomFields = maps.Clone(todo.CustomFields)
+			delete(todo.CustomFields, objectID.Hex())
+			return true
+		}, h.todos.saveEdit)
+		if err != nil {
+			respondStorageError(c, err, "Failed to delete custom field")
+			return
+		}
+		if todo != nil {
+			h.todos.recordRevision(ctx, userID.(string), &before, todo)
+		}
+	}
+	if err := h.fields.Delete(ctx, scope, objectID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		respondStorageError(c, err, "Failed to delete custom field")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Custom field deleted successfully"})
+}
+
+// fieldByKey finds a field by its ID or, ignoring case, its name
+func fieldByKey(fields []models.CustomField, key string) *models.CustomField {
+	for i := range fields {
+		if fields[i].ID.Hex() == key || strings.EqualFold(fields[i].Name, key) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// customFieldValues checks the custom field values sent for a todo against
+// the fields defined for its scope and merges them into current, returning
+// the todo's new values. A null or empty value clears the field. On invalid
+// values it responds with a validation error and returns false.
+func customFieldValues(c *gin.Context, fields []models.CustomField, current, values map[string]any) (map[string]any, bool) {
+	merged, errs := mergeCustomFields(fields, current, values)
+	if len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return nil, false
+	}
+	return merged, true
+}
+
+// mergeCustomFields is customFieldValues without the response: it returns
+// the todo's new values, or the errors of the invalid ones
+func mergeCustomFields(fields []models.CustomField, current, values map[string]any) (map[string]any, []apierrors.FieldError) {
+	merged := maps.Clone(current)
+	if merged == nil {
+		merged = make(map[string]any, len(values))
+	}
+	var errs []apierrors.FieldError
+	// Sorted so errors come out in a stable order
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		v := values[key]
+		name := "custom_fields." + key
+		field := fieldByKey(fields, key)
+		if field == nil {
+			errs = append(errs, apierrors.FieldError{Field: name, Message: "is not a custom field"})
+			continue
+		}
+		if s, ok := v.(string); v == nil || ok && strings.TrimSpace(s) == "" {
+			delete(merged, field.ID.Hex())
+			continue
+		}
+		value, message := field.Value(v)
+		if message != "" {
+			errs = append(errs, apierrors.FieldError{Field: name, Message: message})
+			continue
+		}
+		merged[field.ID.Hex()] = value
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if len(merged) == 0 {
+		return nil, nil
+	}
+	return merged, nil
+}
+
+// customFieldFilter is a ?cf[<field>]=<value> condition on listings
+type customFieldFilter struct {
+	field *models.CustomField
+	value any
+}
+
+// customFieldFilters reads the ?cf[<field>]=<value> parameters, where the
+// field is named by ID or name, responding with a validation error and
+// returning false if one is invalid
+func customFieldFilters(c *gin.Context, fields []models.CustomField, params map[string]string) ([]customFieldFilter, bool) {
+	var filters []customFieldFilter
+	var errs []apierrors.FieldError
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		raw := params[key]
+		name := "cf[" + key + "]"
+		field := fieldByKey(fields, key)
+		if field == nil {
+			errs = append(errs, apierrors.FieldError{Field: name, Message: "is not a custom field"})
+			continue
+		}
+		value, message := field.Value(raw)
+		if message != "" {
+			errs = append(errs, apierrors.FieldError{Field: name, Message: message})
+			continue
+		}
+		filters = append(filters, customFieldFilter{field: field, value: value})
+	}
+	if len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return nil, false
+	}
+	return filters, true
+}
+
+// withCustomFields keeps the todos matching every filter
+func withCustomFields(todos []models.Todo, filters []customFieldFilter) []models.Todo {
+	var kept []models.Todo
+	for _, todo := range todos {
+		matches := true
+		for _, f := range filters {
+			stored, ok := todo.CustomFields[f.field.ID.Hex()]
+			if !ok || !f.field.Matches(stored, f.value) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			kept = append(kept, todo)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"expvar"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// startedAt is when the process started, for the uptime in /debug/vars
+var startedAt = time.Now()
+
+func init() {
+	// memstats and cmdline are published by expvar itself
+	expvar.Publish("goroutines", expvar.Func(func() any {
+		return runtime.NumGoroutine()
+	}))
+	expvar.Publish("uptime_seconds", expvar.Func(func() any {
+		return int64(time.Since(startedAt).Seconds())
+	}))
+	expvar.Publish("go_version", expvar.Func(func() any {
+		return runtime.Version()
+	}))
+}
+
+// DebugVars serves the expvar variables: memory and GC statistics, the
+// number of goroutines and the uptime
+func DebugVars(c *gin.Context) {
+	expvar.Handler().ServeHTTP(c.Writer, c.Request)
+}
+
+// Pprof serves the net/http/pprof profiles under the :profile parameter,
+// such as /debug/pprof/heap or /debug/pprof/profile?seconds=30. The bare
+// path lists them.
+func Pprof(c *gin.Context) {
+	switch strings.TrimPrefix(c.Param("profile"), "/") {
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		// Index serves named profiles by the end of the path
+		pprof.Index(c.Writer, c.Request)
+	}
+}
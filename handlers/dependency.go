@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AddBlocker makes the todo named by :id wait for another todo in the same
+// list. Links that would close a cycle are refused, since neither todo
+// could ever be unblocked. Adding a blocker twice is a no-op.
+func (h *TodoHandler) AddBlocker(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	var req models.AddBlockerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+	blockerID, _ := primitive.ObjectIDFromHex(req.TodoID)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	todo, role, err := todoAccess(ctx, c, h.todos, h.shares, objectID)
+	if err == nil {
+		err = authz.CanWrite(role)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to add dependency")
+		return
+	}
+	if slices.Contains(todo.BlockedBy, blockerID) {
+		linkTodo(c, todo, role)
+		c.JSON(http.StatusOK, gin.H{"todo": todo})
+		return
+	}
+
+	// Dependencies link todos in one list. Todos shared with the user live
+	// in their owner's list, which the user can't see into.
+	scope := todoScope(c, userID.(string))
+	if !scope.Contains(todo) || blockerID == objectID {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "todo_id", Message: "must be another todo in the same list"}})
+		return
+	}
+	if len(todo.BlockedBy) >= models.MaxBlockers {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "todo_id", Message: fmt.Sprintf("can't be added; a todo can wait for at most %d others", models.MaxBlockers)}})
+		return
+	}
+	blockers, err := h.blockerGraph(ctx, scope)
+	if err != nil {
+		respondStorageError(c, err, "Failed to add dependency")
+		return
+	}
+	if _, ok := blockers[blockerID]; !ok {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "todo_id", Message: "must be another todo in the same list"}})
+		return
+	}
+	if waitsFor(blockers, blockerID, objectID) {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "todo_id", Message: "already waits for this todo; the dependency would form a cycle"}})
+		return
+	}
+
+	todo, role, ok := h.saveTodo(ctx, c, objectID, "Failed to add dependency", func(todo *models.Todo) {
+		if !slices.Contains(todo.BlockedBy, blockerID) {
+			todo.BlockedBy = append(slices.Clone(todo.BlockedBy), blockerID)
+		}
+	})
+	if !ok {
+		return
+	}
+
+	// The list was checked before the write, so a dependency added the
+	// other way round in the meantime may have closed a cycle. Each request
+	// checks again once written and takes its own dependency back if so.
+	if blockers, err = h.blockerGraph(ctx, scope); err != nil {
+		respondStorageError(c, err, "Failed to add dependency")
+		return
+	}
+	if waitsFor(blockers, blockerID, objectID) {
+		if _, _, ok := h.saveTodo(ctx, c, objectID, "Failed to add dependency", withoutBlocker(blockerID)); ok {
+			apierrors.Respond(c, apierrors.CodeTodoConflict, "A dependency added at the same time would have formed a cycle; try again")
+		}
+		return
+	}
+	linkTodo(c, todo, role)
+	c.JSON(http.StatusOK, gin.H{"todo": todo})
+}
+
+// blockerGraph maps each todo in the scope to the todos it waits for
+func (h *TodoHandler) blockerGraph(ctx context.Context, scope repository.Scope) (map[primitive.ObjectID][]primitive.ObjectID, error) {
+	todos, err := h.todos.ListFields(ctx, scope, []string{"blocked_by"})
+	if err != nil {
+		return nil, err
+	}
+	blockers := make(map[primitive.ObjectID][]primitive.ObjectID, len(todos))
+	for _, t := range todos {
+		blockers[t.ID] = t.BlockedBy
+	}
+	return blockers, nil
+}
+
+// withoutBlocker returns a change that stops a todo waiting for blockerID
+func withoutBlocker(blockerID primitive.ObjectID) func(*models.Todo) {
+	return func(todo *models.Todo) {
+		todo.BlockedBy = slices.DeleteFunc(slices.Clone(todo.BlockedBy), func(id primitive.ObjectID) bool {
+			return id == blockerID
+		})
+	}
+}
+
+// RemoveBlocker stops the todo named by :id waiting for :blocker_id.
+// Removing a blocker that isn't there is a no-op.
+func (h *TodoHandler) RemoveBlocker(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+	blockerID, err := primitive.ObjectIDFromHex(c.Param("blocker_id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	h.modifyTodo(ctx, c, objectID, "Failed to remove dependency", withoutBlocker(blockerID))
+}
+
+// waitsFor reports whether from, directly or through other todos, is
+// blocked by target. blockers maps each todo to the todos it waits for.
+func waitsFor(blockers map[primitive.ObjectID][]primitive.ObjectID, from, target primitive.ObjectID) bool {
+	seen := map[primitive.ObjectID]bool{from: true}
+	stack := []primitive.ObjectID{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, next := range blockers[id] {
+			if next == target {
+				return true
+			}
+			if !seen[next] {
+				seen[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+	return false
+}
+
+// markBlocked sets Blocked on each todo waiting for an open todo. todos has
+// to be the whole list, as blockers are looked up in it; blockers that no
+// longer exist don't block.
+func markBlocked(todos []models.Todo) {
+	open := make(map[primitive.ObjectID]bool, len(todos))
+	for _, todo := range todos {
+		open[todo.ID] = !todo.Completed
+	}
+	for i := range todos {
+		todos[i].Blocked = slices.ContainsFunc(todos[i].BlockedBy, func(id primitive.ObjectID) bool {
+			return open[id]
+		})
+	}
+}
+
+// blockedFilter reads ?blocked=true or ?blocked=false, returning nil when
+// the parameter isn't sent. Any other value gets a validation error and ok
+// is false.
+func blockedFilter(c *gin.Context) (blocked *bool, ok bool) {
+	switch v := c.Query("blocked"); v {
+	case "":
+		return nil, true
+	case "true", "false":
+		want := v == "true"
+		return &want, true
+	}
+	apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "blocked", Message: `must be "true" or "false"`}})
+	return nil, false
+}
+
+// withBlocked keeps the todos whose Blocked flag matches blocked, reusing
+// the slice's storage
+func withBlocked(todos []models.Todo, blocked bool) []models.Todo {
+	kept := todos[:0]
+	for _, todo := range todos {
+		if todo.Blocked == blocked {
+			kept = append(kept, todo)
+		}
+	}
+	return kept
+}
+
+// checkBlockers refuses to complete a todo while one of its blockers is
+// open, for users who turned on enforce_blockers. It responds with an error
+// and returns false if the todo can't be completed.
+func (h *TodoHandler) checkBlockers(ctx context.Context, c *gin.Context, todo *models.Todo) bool {
+	if len(todo.BlockedBy) == 0 || todo.Completed {
+		return true
+	}
+	enforce, ok := h.settings.enforceBlockers(ctx, c)
+	if !enforce || !ok {
+		return ok
+	}
+
+	open, err := h.openBlockers(ctx, todo)
+	if err != nil {
+		respondStorageError(c, err, "Failed to update todo")
+		return false
+	}
+	if len(open) > 0 {
+		apierrors.Write(c, blockedProblem(open))
+		return false
+	}
+	return true
+}
+
+// openBlockers returns the IDs of the todo's blockers that are still open
+func (h *TodoHandler) openBlockers(ctx context.Context, todo *models.Todo) ([]string, error) {
+	if len(todo.BlockedBy) == 0 {
+		return nil, nil
+	}
+	todos, err := h.todos.List(ctx, repository.ScopeOf(todo))
+	if err != nil {
+		return nil, err
+	}
+	var open []string
+	for _, t := range todos {
+		if !t.Completed && slices.Contains(todo.BlockedBy, t.ID) {
+			open = append(open, t.ID.Hex())
+		}
+	}
+	return open, nil
+}
+
+// blockedProblem reports a todo that can't be completed while the open
+// blockers are
+func blockedProblem(open []string) *apierrors.Problem {
+	return apierrors.New(apierrors.CodeTodoBlocked, "Complete the todos this one waits for first: "+strings.Join(open, ", "))
+}
+
+// dropBlocker removes a deleted todo from the blockers of the others in its
+// list, returning the todos it was removed from. It's best effort: blockers
+// that no longer exist don't block.
+func (h *TodoHandler) dropBlocker(ctx context.Context, deleted *models.Todo) []primitive.ObjectID {
+	todos, err := h.todos.List(ctx, repository.ScopeOf(deleted))
+	if err != nil {
+		log.Printf("Failed to remove todo %s from dependencies: %v", deleted.ID.Hex(), err)
+		return nil
+	}
+	var waiting []primitive.ObjectID
+	for i := range todos {
+		written, _, err := rewriteTodo(ctx, h.todos, &todos[i], func(todo *models.Todo) bool {
+			if !slices.Contains(todo.BlockedBy, deleted.ID) {
+				return false
+			}
+			todo.BlockedBy = slices.DeleteFunc(slices.Clone(todo.BlockedBy), func(id primitive.ObjectID) bool {
+				return id == deleted.ID
+			})
+			return true
+		}, updateIfUnchanged(h.todos))
+		if err != nil {
+			log.Printf("Failed to remove todo %s from dependencies: %v", deleted.ID.Hex(), err)
+			return waiting
+		}
+		if written != nil {
+			waiting = append(waiting, written.ID)
+		}
+	}
+	return waiting
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"todo-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestWaitsFor(t *testing.T) {
+	a, b, c, d := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	// a waits for b, which waits for c and d; d waits for c
+	blockers := map[primitive.ObjectID][]primitive.ObjectID{
+		a: {b},
+		b: {c, d},
+		d: {c},
+	}
+
+	tests := []struct {
+		name         string
+		from, target primitive.ObjectID
+		want         bool
+	}{
+		{"directly", a, b, true},
+		{"through another todo", a, c, true},
+		{"through two paths", a, d, true},
+		{"the other way round", c, a, false},
+		{"unrelated", d, b, false},
+		{"a todo with no blockers", c, d, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := waitsFor(blockers, tt.from, tt.target); got != tt.want {
+				t.Errorf("waitsFor = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddBlocker(t *testing.T) {
+	const (
+		cycleMessage    = "already waits for this todo; the dependency would form a cycle"
+		sameListMessage = "must be another todo in the same list"
+	)
+
+	// first waits for second, which waits for third
+	first, second, third := testTodo("Paint the fence"), testTodo("Buy paint"), testTodo("Go to the shop")
+	first.BlockedBy = []primitive.ObjectID{second.ID}
+	second.BlockedBy = []primitive.ObjectID{third.ID}
+	other := testTodo("Someone else's")
+	other.UserID = "user-2"
+
+	tests := []struct {
+		name        string
+		todo        primitive.ObjectID
+		blocker     primitive.ObjectID
+		wantStatus  int
+		wantMessage string
+		wantBlocked []primitive.ObjectID
+	}{
+		{"a todo further down the chain", first.ID, third.ID, http.StatusOK, "", []primitive.ObjectID{second.ID, third.ID}},
+		{"a blocker already there", first.ID, second.ID, http.StatusOK, "", []primitive.ObjectID{second.ID}},
+		{"a direct cycle", second.ID, first.ID, http.StatusBadRequest, cycleMessage, []primitive.ObjectID{third.ID}},
+		{"a cycle through another todo", third.ID, first.ID, http.StatusBadRequest, cycleMessage, nil},
+		{"the todo itself", first.ID, first.ID, http.StatusBadRequest, sameListMessage, []primitive.ObjectID{second.ID}},
+		{"another user's todo", third.ID, other.ID, http.StatusBadRequest, sameListMessage, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todos := newFakeTodos(first, second, third, other)
+			body := fmt.Sprintf(`{"todo_id":%q}`, tt.blocker.Hex())
+			status, resp := serve(t, newTodoRouter(todos), http.MethodPost, "/todos/"+tt.todo.Hex()+"/blockers", body)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %v", status, tt.wantStatus, resp)
+			}
+			if tt.wantMessage != "" {
+				errs, _ := resp["errors"].([]any)
+				if resp["code"] != "VALIDATION_FAILED" || len(errs) != 1 || errs[0].(map[string]any)["message"] != tt.wantMessage {
+					t.Errorf("body = %v, want VALIDATION_FAILED: %s", resp, tt.wantMessage)
+				}
+			}
+			stored, err := todos.MemoryTodoRepository.Get(context.Background(), repository.Personal(testUser), tt.todo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(stored.BlockedBy, tt.wantBlocked) {
+				t.Errorf("stored blocked_by = %v, want %v", stored.BlockedBy, tt.wantBlocked)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"todo-api/apierrors"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dryRunSampleSize is how many IDs a dry run lists of what it would change
+const dryRunSampleSize = 20
+
+// isDryRun reads ?dry_run=. A dry run goes through the request's checks and
+// responds with what it would change, counts and up to dryRunSampleSize
+// IDs, without writing anything. Anything but "true" or "false" gets a
+// validation error and ok is false.
+func isDryRun(c *gin.Context) (dryRun, ok bool) {
+	switch c.Query("dry_run") {
+	case "", "false":
+		return false, true
+	case "true":
+		return true, true
+	}
+	apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "dry_run", Message: `must be "true" or "false"`}})
+	return false, false
+}
+
+// sampleTodoIDs returns the IDs of up to dryRunSampleSize of todos
+func sampleTodoIDs(todos []models.Todo) []string {
+	ids := []string{}
+	for i := 0; i < len(todos) && i < dryRunSampleSize; i++ {
+		ids = append(ids, todos[i].ID.Hex())
+	}
+	return ids
+}
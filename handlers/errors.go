@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/logging"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// statusClientClosedRequest is the non-standard status (borrowed from nginx)
+// logged when the client went away before we could respond
+const statusClientClosedRequest = 499
+
+// unavailableRetryAfter is the Retry-After hint, in seconds, sent when the
+// storage backend is temporarily unavailable
+const unavailableRetryAfter = "5"
+
+// throttledRetryAfter returns the Retry-After hint, in whole seconds, for
+// storage that asked to wait retryAfter, at least a second
+func throttledRetryAfter(retryAfter time.Duration) string {
+	return strconv.Itoa(max(1, int((retryAfter+time.Second-1)/time.Second)))
+}
+
+// respondStorageError reports a repository failure. When the backend is only
+// temporarily unavailable the client gets a 503 telling it to back off and
+// retry rather than a generic 500; when it's throttling requests, the wait
+// it asked for.
+func respondStorageError(c *gin.Context, err error, message string) {
+	if errors.Is(err, context.Canceled) && c.Request.Context().Err() != nil {
+		// Nobody is listening for a response any more
+		c.AbortWithStatus(statusClientClosedRequest)
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		apierrors.Respond(c, apierrors.CodeTimeout, "Request timed out")
+		return
+	}
+	var throttled *repository.ThrottledError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", throttledRetryAfter(throttled.RetryAfter))
+		apierrors.Respond(c, apierrors.CodeStorageThrottled, "Too many requests to the database, please retry shortly")
+		return
+	}
+	if errors.Is(err, repository.ErrUnavailable) {
+		c.Header("Retry-After", unavailableRetryAfter)
+		apierrors.Respond(c, apierrors.CodeStorageUnavailable, "Service temporarily unavailable, please retry")
+		return
+	}
+	apierrors.Respond(c, apierrors.CodeInternal, message)
+}
+
+// respondBindError reports a request body that couldn't be bound, telling
+// malformed JSON apart from well-formed bodies that fail validation
+func respondBindError(c *gin.Context, err error) {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fieldErrs := make([]apierrors.FieldError, len(validationErrs))
+		for i, fe := range validationErrs {
+			fieldErrs[i] = apierrors.FieldError{
+				Field:   fe.Field(),
+				Message: "failed the " + fe.Tag() + " rule",
+			}
+		}
+		apierrors.RespondValidation(c, fieldErrs)
+		return
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var tooLargeErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLargeErr):
+		apierrors.Respond(c, apierrors.CodePayloadTooLarge,
+			fmt.Sprintf("Request body must not exceed %d bytes", tooLargeErr.Limit))
+	case errors.As(err, &typeErr):
+		apierrors.RespondValidation(c, []apierrors.FieldError{{
+			Field:   typeErr.Field,
+			Message: "must be a " + typeErr.Type.String(),
+		}})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		apierrors.Respond(c, apierrors.CodeInvalidRequest, "Request body must be valid JSON")
+	default:
+		apierrors.Respond(c, apierrors.CodeInvalidRequest, logging.Error(err))
+	}
+}
+
+func init() {
+	// Report validation failures using the JSON field names clients send
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
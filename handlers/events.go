@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+
+	"todo-api/events"
+	"todo-api/models"
+)
+
+// commit runs write and records the events describe returns in one
+// transaction, so events are recorded for every change saved and for no
+// other. describe runs after write, which may assign the IDs the events
+// name. Without transactions the events are recorded right after write,
+// and a failure to record them is returned though the change stays saved.
+func (h *TodoHandler) commit(ctx context.Context, write func(ctx context.Context) error, describe func() []events.Event) error {
+	return h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := write(ctx); err != nil {
+			return err
+		}
+		return h.publisher.Publish(ctx, describe()...)
+	})
+}
+
+// createTodo stores a new todo, recording todo.created with it
+func (h *TodoHandler) createTodo(ctx context.Context, todo *models.Todo) error {
+	return h.commit(ctx, func(ctx context.Context) error {
+		return h.todos.Create(ctx, todo)
+	}, func() []events.Event {
+		return []events.Event{todoEvent(events.TodoCreated, todo)}
+	})
+}
+
+// saveEdit writes after if the todo wasn't changed since before was read,
+// as UpdateIfUnchanged does, recording the events describing the edit with
+// it
+func (h *TodoHandler) saveEdit(ctx context.Context, before, after *models.Todo) error {
+	return h.commit(ctx, func(ctx context.Context) error {
+		return h.todos.UpdateIfUnchanged(ctx, after, before.UpdatedAt)
+	}, func() []events.Event {
+		return updateEvents(before, after)
+	})
+}
+
+// todoEvent describes a change to todo. The event carries a copy, so the
+// handler may keep using todo while the event is sent.
+func todoEvent(eventType string, todo *models.Todo) events.Event {
+	data := *todo
+	data.DescriptionHTML = ""
+	data.Links = nil
+	return events.New(eventType, "todos/"+todo.ID.Hex(), data)
+}
+
+// updateEvents describes an edit of a todo, adding todo.completed when the
+// edit completed it
+func updateEvents(before, after *models.Todo) []events.Event {
+	batch := []events.Event{todoEvent(events.TodoUpdated, after)}
+	if after.Completed && !before.Completed {
+		batch = append(batch, todoEvent(events.TodoCompleted, after))
+	}
+	return batch
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/duedate"
+	"todo-api/models"
+	"todo-api/pdf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxExportTodos caps how many todos one export prints
+const maxExportTodos = 2000
+
+// ExportTodos renders the todos matching the same filters as GetTodos into
+// a printable checklist. ?format=pdf is the only format, and the default.
+// Dates are shown in the user's time zone.
+func (h *TodoHandler) ExportTodos(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := authz.CanRead(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to export todos")
+		return
+	}
+	if format := c.DefaultQuery("format", "pdf"); format != "pdf" {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "format", Message: `must be "pdf"`}})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	loc, ok := h.settings.location(ctx, c)
+	if !ok {
+		return
+	}
+	scope := todoScope(c, userID.(string))
+	filters, ok := h.readFilters(ctx, c, scope)
+	if !ok {
+		return
+	}
+	todos, ok := h.filteredTodos(ctx, c, scope, filters, nil, listExtras{})
+	if !ok {
+		return
+	}
+	if len(todos) > maxExportTodos {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{
+			Field:   "q",
+			Message: fmt.Sprintf("matches %d todos; narrow the list to %d or fewer to export it", len(todos), maxExportTodos),
+		}})
+		return
+	}
+
+	title := "Todos"
+	if workspace, ok := c.Get("workspace"); ok {
+		title = workspace.(*models.Workspace).Name
+	}
+	now := time.Now().In(loc)
+	var buf bytes.Buffer
+	if err := checklist(title, todos, now).Write(&buf); err != nil {
+		apierrors.Respond(c, apierrors.CodeInternal, "Failed to export todos")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="todos-%s.pdf"`, now.Format(time.DateOnly)))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// checklist lays todos out as a checklist under title, as of now
+func checklist(title string, todos []models.Todo, now time.Time) *pdf.Document {
+	doc := pdf.New(title, now)
+	doc.Text(pdf.Bold, 18, 0, 0, title)
+
+	done := 0
+	for _, todo := range todos {
+		if todo.Completed {
+			done++
+		}
+	}
+	doc.Text(pdf.Regular, 9, 0, 0.45, fmt.Sprintf("%d todos, %d done · Exported %s (%s)",
+		len(todos), done, now.Format("Mon 2 Jan 2006 15:04"), now.Location()))
+	doc.Space(12)
+
+	if len(todos) == 0 {
+		doc.Text(pdf.Regular, 11, 0, 0.45, "No todos match.")
+	}
+	for _, todo := range todos {
+		// Done todos are printed in gray, so the open ones stand out
+		gray := 0.0
+		if todo.Completed {
+			gray = 0.5
+		}
+		doc.Checkbox(todo.Completed, pdf.Bold, 11, gray, todo.Title)
+		if details := todoDetails(&todo, now.Location()); details != "" {
+			doc.Text(pdf.Regular, 9, 14, 0.4, details)
+		}
+		if description := strings.TrimSpace(todo.Description); description != "" {
+			doc.Text(pdf.Regular, 9, 14, 0.2+gray/2, description)
+		}
+		doc.Space(6)
+	}
+	return doc
+}
+
+// todoDetails summarises a todo's due date, priority and flags on one line
+func todoDetails(todo *models.Todo, loc *time.Location) string {
+	var details []string
+	if todo.DueDate != nil {
+		due := todo.DueDate.In(loc)
+		if due.Equal(duedate.StartOfDay(due)) {
+			details = append(details, "Due "+due.Format("Mon 2 Jan 2006"))
+		} else {
+			details = append(details, "Due "+due.Format("Mon 2 Jan 2006 15:04"))
+		}
+	}
+	if todo.Priority != "" {
+		details = append(details, "Priority "+string(todo.Priority))
+	}
+	if todo.Pinned {
+		details = append(details, "Pinned")
+	}
+	if todo.Blocked {
+		details = append(details, "Blocked")
+	}
+	if todo.Completed && todo.CompletedAt != nil {
+		details = append(details, "Done "+todo.CompletedAt.In(loc).Format("Mon 2 Jan 2006"))
+	}
+	return strings.Join(details, " · ")
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listExtras are the extras a listing can embed on request
+type listExtras struct {
+	facets     bool
+	highlights bool
+	embeds     todoEmbeds
+}
+
+// includeExtras reads ?include=, a comma-separated list of extras to embed
+// in a listing: facets and highlights, and the resources each todo embeds.
+// Anything else gets a validation error and ok is false.
+func includeExtras(c *gin.Context) (extras listExtras, ok bool) {
+	v := c.Query("include")
+	if v == "" {
+		return extras, true
+	}
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "facets":
+			extras.facets = true
+		case name == "highlights":
+			extras.highlights = true
+		case extras.embeds.add(name):
+		default:
+			apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "include", Message: `must list "facets", "highlights", "comments", "workspace" or "attachments"`}})
+			return extras, false
+		}
+	}
+	return extras, true
+}
+
+// countFacets counts the facets of the todos that matched the request's
+// filters. The repository counts them when it can apply every filter
+// itself; otherwise the matched todos, with their Blocked flags set, are
+// counted here. It responds with an error and returns false when storage
+// fails.
+func (h *TodoHandler) countFacets(ctx context.Context, c *gin.Context, scope repository.Scope, f *todoFilters, matched []models.Todo) (*models.TodoFacets, bool) {
+	if filter, ok := f.listFilter(time.Now()); ok {
+		facets, err := h.todos.Facets(ctx, scope, filter)
+		if err != nil {
+			respondStorageError(c, err, "Failed to fetch todos")
+			return nil, false
+		}
+		return facets, true
+	}
+	facets := models.NewTodoFacets()
+	for i := range matched {
+		facets.Add(&matched[i])
+	}
+	return facets, true
+}
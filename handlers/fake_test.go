@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"todo-api/models"
+	"todo-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeTodos is the TodoRepository handler tests run against: todos are kept
+// in memory, the calls made are recorded, and any of them can be made to
+// fail
+type fakeTodos struct {
+	*repository.MemoryTodoRepository
+
+	mu sync.Mutex
+	// fail maps a method name to the error it returns instead of running
+	fail map[string]error
+	// calls lists the methods called, in order
+	calls []string
+	// interleave, if set, runs once before the next write, as a concurrent
+	// request would between a handler reading a todo and writing it back
+	interleave func()
+}
+
+func newFakeTodos(todos ...models.Todo) *fakeTodos {
+	f := &fakeTodos{MemoryTodoRepository: repository.NewMemoryTodoRepository(), fail: map[string]error{}}
+	for i := range todos {
+		if err := f.MemoryTodoRepository.Create(context.Background(), &todos[i]); err != nil {
+			panic(err)
+		}
+	}
+	return f
+}
+
+// call records a call to method and returns the error it should fail with
+func (f *fakeTodos) call(method string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, method)
+	return f.fail[method]
+}
+
+func (f *fakeTodos) List(ctx context.Context, scope repository.Scope) ([]models.Todo, error) {
+	if err := f.call("List"); err != nil {
+		return nil, err
+	}
+	return f.MemoryTodoRepository.List(ctx, scope)
+}
+
+func (f *fakeTodos) ListFields(ctx context.Context, scope repository.Scope, fields []string) ([]models.Todo, error) {
+	if err := f.call("ListFields"); err != nil {
+		return nil, err
+	}
+	return f.MemoryTodoRepository.ListFields(ctx, scope, fields)
+}
+
+// ListPage fails once ctx is done, as a database read would
+func (f *fakeTodos) ListPage(ctx context.Context, scope repository.Scope, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	if err := f.call("ListPage"); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return f.MemoryTodoRepository.ListPage(ctx, scope, after, limit)
+}
+
+func (f *fakeTodos) Get(ctx context.Context, scope repository.Scope, id primitive.ObjectID) (*models.Todo, error) {
+	if err := f.call("Get"); err != nil {
+		return nil, err
+	}
+	return f.MemoryTodoRepository.Get(ctx, scope, id)
+}
+
+func (f *fakeTodos) Create(ctx context.Context, todo *models.Todo) error {
+	if err := f.call("Create"); err != nil {
+		return err
+	}
+	return f.MemoryTodoRepository.Create(ctx, todo)
+}
+
+func (f *fakeTodos) Update(ctx context.Context, todo *models.Todo) error {
+	if err := f.call("Update"); err != nil {
+		return err
+	}
+	f.runInterleaved()
+	return f.MemoryTodoRepository.Update(ctx, todo)
+}
+
+func (f *fakeTodos) UpdateIfUnchanged(ctx context.Context, todo *models.Todo, lastUpdated time.Time) error {
+	if err := f.call("UpdateIfUnchanged"); err != nil {
+		return err
+	}
+	f.runInterleaved()
+	return f.MemoryTodoRepository.UpdateIfUnchanged(ctx, todo, lastUpdated)
+}
+
+// runInterleaved runs and clears interleave, if set
+func (f *fakeTodos) runInterleaved() {
+	f.mu.Lock()
+	interleave := f.interleave
+	f.interleave = nil
+	f.mu.Unlock()
+	if interleave != nil {
+		interleave()
+	}
+}
+
+func (f *fakeTodos) Delete(ctx context.Context, scope repository.Scope, id primitive.ObjectID) error {
+	if err := f.call("Delete"); err != nil {
+		return err
+	}
+	return f.MemoryTodoRepository.Delete(ctx, scope, id)
+}
+
+func (f *fakeTodos) CountMatching(ctx context.Context, scope repository.Scope, filter repository.ListFilter) (int64, error) {
+	if err := f.call("CountMatching"); err != nil {
+		return 0, err
+	}
+	return f.MemoryTodoRepository.CountMatching(ctx, scope, filter)
+}
+
+func (f *fakeTodos) Facets(ctx context.Context, scope repository.Scope, filter repository.ListFilter) (*models.TodoFacets, error) {
+	if err := f.call("Facets"); err != nil {
+		return nil, err
+	}
+	return f.MemoryTodoRepository.Facets(ctx, scope, filter)
+}
+
+// called reports whether method was called
+func (f *fakeTodos) called(method string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Contains(f.calls, method)
+}
+
+// count returns how many times method was called
+func (f *fakeTodos) count(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, called := range f.calls {
+		if called == method {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/auth"
+	"todo-api/authz"
+	"todo-api/markdown"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// feedAppName is the user agent feed sessions are listed with
+const feedAppName = "Activity feed"
+
+// FeedHandler serves activity feeds, which list the todos recently created
+// and completed in a scope for feed readers to follow
+type FeedHandler struct {
+	todos      repository.TodoRepository
+	workspaces repository.WorkspaceRepository
+	sessions   repository.SessionRepository
+	signer     *auth.CookieSigner
+	ttl        time.Duration
+	entries    int
+	timeout    time.Duration
+}
+
+// NewFeedHandler creates a FeedHandler. Feed URLs carry a token signed by
+// signer naming a session in sessions, which lasts ttl; feeds list the
+// latest entries of their todos.
+func NewFeedHandler(todos repository.TodoRepository, workspaces repository.WorkspaceRepository, sessions repository.SessionRepository, signer *auth.CookieSigner, ttl time.Duration, entries int, timeout time.Duration) *FeedHandler {
+	return &FeedHandler{todos: todos, workspaces: workspaces, sessions: sessions, signer: signer, ttl: ttl, entries: entries, timeout: timeout}
+}
+
+// feedEntry is a todo being created or completed. content is its
+// description, rendered as HTML.
+type feedEntry struct {
+	id      string
+	title   string
+	content string
+	at      time.Time
+}
+
+// atomFeed is an Atom (RFC 4287) feed
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// rssFeed is an RSS 2.0 feed, for readers that don't take Atom
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// CreateFeed creates the feed URL of the user's personal todos, or of the
+// workspace's when nested under one. Each URL starts a session, listed
+// among the user's sessions, which revoking ends the feed with.
+func (h *FeedHandler) CreateFeed(c *gin.Context) {
+	userID := c.GetString("user_id")
+	workspace := ""
+	if id, ok := c.Get("workspace_id"); ok {
+		workspace = id.(primitive.ObjectID).Hex()
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	now := time.Now()
+	session := &models.Session{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(h.ttl),
+		UserAgent: feedAppName,
+		IP:        c.ClientIP(),
+	}
+	if err := h.sessions.Create(ctx, session); err != nil {
+		respondStorageError(c, err, "Failed to create feed")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"feed": gin.H{
+		"url":        baseURL(c) + "/feeds/" + h.signer.FeedToken(session.ID, workspace),
+		"session_id": session.ID,
+		"expires_at": session.ExpiresAt,
+	}})
+}
+
+// GetFeed serves a feed as Atom, or as RSS with ?format=rss. It needs no
+// authentication; the token is the credential.
+func (h *FeedHandler) GetFeed(c *gin.Context) {
+	c.Header("X-Robots-Tag", "noindex")
+	format := c.DefaultQuery("format", "atom")
+	if format != "atom" && format != "rss" {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "format", Message: `must be "atom" or "rss"`}})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	title, scope, err := h.feedScope(ctx, c.Param("token"))
+	var todos []models.Todo
+	if err == nil {
+		todos, err = h.todos.List(ctx, scope)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodeFeedNotFound, "This feed doesn't exist or was revoked")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch feed")
+		return
+	}
+
+	self := baseURL(c) + c.Request.URL.RequestURI()
+	entries := h.feedEntries(todos)
+	var body []byte
+	if format == "rss" {
+		body, err = rssBody(title, self, entries)
+	} else {
+		body, err = atomBody(title, self, entries)
+	}
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInternal, "Failed to render feed")
+		return
+	}
+
+	// Readers poll, so unchanged feeds are answered without a body
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	contentType := "application/atom+xml; charset=utf-8"
+	if format == "rss" {
+		contentType = "application/rss+xml; charset=utf-8"
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// feedScope returns the title and scope of the feed a token names. Tokens
+// whose session was revoked or whose user left the workspace are reported
+// as repository.ErrNotFound.
+func (h *FeedHandler) feedScope(ctx context.Context, token string) (string, repository.Scope, error) {
+	sessionID, workspace, ok := h.signer.VerifyFeedToken(token)
+	if !ok {
+		return "", repository.Scope{}, repository.ErrNotFound
+	}
+	session, err := h.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return "", repository.Scope{}, err
+	}
+	if workspace == "" {
+		return "Your todos", repository.Personal(session.UserID), nil
+	}
+
+	id, err := primitive.ObjectIDFromHex(workspace)
+	if err != nil {
+		return "", repository.Scope{}, repository.ErrNotFound
+	}
+	w, err := h.workspaces.Get(ctx, id)
+	if err != nil {
+		return "", repository.Scope{}, err
+	}
+	if authz.CanRead(w.RoleOf(session.UserID)) != nil {
+		return "", repository.Scope{}, repository.ErrNotFound
+	}
+	return w.Name, repository.Workspace(id), nil
+}
+
+// feedEntries lists the creation and completion of each todo, latest first
+func (h *FeedHandler) feedEntries(todos []models.Todo) []feedEntry {
+	var entries []feedEntry
+	for _, todo := range todos {
+		id := todo.ID.Hex()
+		entries = append(entries, feedEntry{
+			id:      "urn:todo-api:todo:" + id + ":created",
+			title:   "Created: " + todo.Title,
+			content: todo.Description,
+			at:      todo.CreatedAt,
+		})
+		if todo.Completed && todo.CompletedAt != nil {
+			// Completing again after reopening is a new entry
+			entries = append(entries, feedEntry{
+				id:      "urn:todo-api:todo:" + id + ":completed:" + todo.CompletedAt.UTC().Format("20060102T150405Z"),
+				title:   "Completed: " + todo.Title,
+				content: todo.Description,
+				at:      *todo.CompletedAt,
+			})
+		}
+	}
+	// Ties are broken by ID, so the feed and its ETag don't change between
+	// polls
+	slices.SortFunc(entries, func(a, b feedEntry) int {
+		return cmp.Or(b.at.Compare(a.at), strings.Compare(a.id, b.id))
+	})
+	entries = entries[:min(len(entries), h.entries)]
+	// Only the entries listed are worth rendering
+	for i := range entries {
+		entries[i].content = markdown.Render(entries[i].content)
+	}
+	return entries
+}
+
+func atomBody(title, self string, entries []feedEntry) ([]byte, error) {
+	feed := atomFeed{
+		ID:      "urn:todo-api:feed:" + feedID(self),
+		Title:   title,
+		Updated: feedUpdated(entries).Format(time.RFC3339),
+		Author:  atomAuthor{Name: "Todo API"},
+		Link:    atomLink{Rel: "self", Href: self},
+	}
+	for _, e := range entries {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      e.id,
+			Title:   e.title,
+			Updated: e.at.UTC().Format(time.RFC3339),
+			Content: atomContent{Type: "html", Body: e.content},
+		})
+	}
+	return marshalFeed(feed)
+}
+
+func rssBody(title, self string, entries []feedEntry) ([]byte, error) {
+	channel := rssChannel{
+		Title:       title,
+		Link:        self,
+		Description: "Todos recently created and completed in " + title,
+	}
+	if len(entries) > 0 {
+		channel.LastBuildDate = entries[0].at.UTC().Format(time.RFC1123Z)
+	}
+	for _, e := range entries {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       e.title,
+			Description: e.content,
+			GUID:        rssGUID{Value: e.id},
+			PubDate:     e.at.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return marshalFeed(rssFeed{Version: "2.0", Channel: channel})
+}
+
+func marshalFeed(feed any) ([]byte, error) {
+	body, err := xml.Marshal(feed)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// feedUpdated is when the feed last changed: its latest entry, or the epoch
+// for an empty feed, so the time doesn't change on every poll
+func feedUpdated(entries []feedEntry) time.Time {
+	if len(entries) == 0 {
+		return time.Unix(0, 0).UTC()
+	}
+	return entries[0].at.UTC()
+}
+
+// feedID identifies a feed by its URL without putting the token in the
+// feed's ID
+func feedID(self string) string {
+	sum := sha256.Sum256([]byte(self))
+	return hex.EncodeToString(sum[:16])
+}
+
+// baseURL is the scheme and host the request was made to, for URLs handed
+// to clients that need them absolute
+func baseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
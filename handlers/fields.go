@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// todoField is a field of models.Todo as it appears in responses
+type todoField struct {
+	index     int
+	omitEmpty bool
+	// stored names the stored fields it's loaded or worked out from
+	stored []string
+}
+
+// computedFields maps the todo fields filled in for responses to the
+// stored fields they're worked out from
+var computedFields = map[string][]string{
+	"description_html": {"description"},
+	"distance":         {"location"},
+	"highlights":       {"title", "description"},
+	"blocked":          {"blocked_by", "completed"},
+	"_links":           {"workspace_id", "pinned"},
+	"_embedded":        {"workspace_id", "comments"},
+}
+
+// todoFields maps the JSON names of the todo fields clients can select to
+// the struct fields holding them
+var todoFields = func() map[string]todoField {
+	fields := map[string]todoField{}
+	t := reflect.TypeOf(models.Todo{})
+	for i := range t.NumField() {
+		tags := strings.Split(t.Field(i).Tag.Get("json"), ",")
+		if name := tags[0]; name != "" && name != "-" {
+			stored, _, _ := strings.Cut(t.Field(i).Tag.Get("bson"), ",")
+			field := todoField{index: i, omitEmpty: slices.Contains(tags[1:], "omitempty"), stored: []string{stored}}
+			if stored == "-" {
+				field.stored = computedFields[name]
+			}
+			fields[name] = field
+		}
+	}
+	return fields
+}()
+
+// listedFields are the stored fields every listing reads to filter and
+// order todos
+var listedFields = []string{"completed", "pinned", "blocked_by", "snoozed_until", "archived_at"}
+
+// storedFields returns the stored fields a listing of the selected fields
+// has to load, or nil when it needs whole todos: when every field is
+// selected, or when a saved filter, which may read any field, is applied
+func storedFields(fields []string, f *todoFilters, extras listExtras) []string {
+	if fields == nil || f.saved != nil {
+		return nil
+	}
+	stored := slices.Concat(listedFields, f.query.StoredFields())
+	for _, name := range fields {
+		stored = append(stored, todoFields[name].stored...)
+	}
+	if f.completedAfter != nil {
+		stored = append(stored, "completed_at")
+	}
+	if f.assignee != nil {
+		stored = append(stored, "assignee_id")
+	}
+	if f.tag != "" {
+		stored = append(stored, "tags")
+	}
+	if len(f.customFields) > 0 {
+		stored = append(stored, "custom_fields")
+	}
+	// Facets are counted from the matched todos unless the repository
+	// can count them
+	if _, ok := f.listFilter(time.Time{}); extras.facets && !ok {
+		stored = append(stored, "priority", "color", "tags")
+	}
+	if extras.highlights {
+		stored = append(stored, "title", "description")
+	}
+	if extras.embeds.comments {
+		stored = append(stored, "comments")
+	}
+	slices.Sort(stored)
+	return slices.Compact(stored)
+}
+
+// fieldSelection reads the ?fields= list of todo fields to respond with,
+// returning nil when every field is wanted. The id is always included so
+// clients can tell todos apart. Unknown fields get a validation error and
+// ok is false.
+func fieldSelection(c *gin.Context) (fields []string, ok bool) {
+	v := c.Query("fields")
+	if v == "" {
+		return nil, true
+	}
+	fields = []string{"id"}
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if _, known := todoFields[name]; !known {
+			names := make([]string, 0, len(todoFields))
+			for name := range todoFields {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			apierrors.RespondValidation(c, []apierrors.FieldError{{
+				Field:   "fields",
+				Message: fmt.Sprintf("unknown field %q; use any of %s", name, strings.Join(names, ", ")),
+			}})
+			return nil, false
+		}
+		if !slices.Contains(fields, name) {
+			fields = append(fields, name)
+		}
+	}
+	return fields, true
+}
+
+// selectFields returns the todos with only the selected fields, or the
+// todos as they are when fields is nil. Empty optional fields are left out
+// as they would be otherwise.
+func selectFields(todos []models.Todo, fields []string) any {
+	if fields == nil {
+		return todos
+	}
+	selected := make([]gin.H, len(todos))
+	for i := range todos {
+		selected[i] = selectTodoFields(&todos[i], fields)
+	}
+	return selected
+}
+
+// selectTodoFields returns the selected fields of one todo
+func selectTodoFields(todo *models.Todo, fields []string) gin.H {
+	v := reflect.ValueOf(todo).Elem()
+	selected := gin.H{}
+	for _, name := range fields {
+		field := v.Field(todoFields[name].index)
+		if todoFields[name].omitEmpty && isEmpty(field) {
+			continue
+		}
+		selected[name] = field.Interface()
+	}
+	return selected
+}
+
+// isEmpty reports whether encoding/json leaves out a value tagged
+// omitempty
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/duedate"
+	"todo-api/models"
+	"todo-api/repository"
+	"todo-api/search"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SavedFilterHandler serves the endpoints that save filters. Todos are
+// listed by filter through GET /todos?filter=<id>.
+type SavedFilterHandler struct {
+	filters repository.SavedFilterRepository
+	timeout time.Duration
+}
+
+// NewSavedFilterHandler creates a SavedFilterHandler
+func NewSavedFilterHandler(filters repository.SavedFilterRepository, timeout time.Duration) *SavedFilterHandler {
+	return &SavedFilterHandler{filters: filters, timeout: timeout}
+}
+
+// ListSavedFilters returns the filters saved for the request's scope
+func (h *SavedFilterHandler) ListSavedFilters(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	filters, err := h.filters.List(ctx, todoScope(c, userID.(string)))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch saved filters")
+		return
+	}
+	if filters == nil {
+		filters = []models.SavedFilter{}
+	}
+	c.JSON(http.StatusOK, gin.H{"filters": filters})
+}
+
+// GetSavedFilter returns one saved filter
+func (h *SavedFilterHandler) GetSavedFilter(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+	objectID, err := primitive.ObjectIDFromHex(c.Param("filter_id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid filter ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	filter, err := h.filters.Get(ctx, todoScope(c, userID.(string)), objectID)
+	if err != nil {
+		respondFilterError(c, err, "Failed to fetch saved filter")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"filter": filter})
+}
+
+// CreateSavedFilter saves a filter. In a workspace every member sees it, so
+// viewers may not.
+func (h *SavedFilterHandler) CreateSavedFilter(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+	if err := authz.CanWrite(scopeRole(c)); err != nil {
+		apierrors.Respond(c, apierrors.CodeForbidden, "Viewers can't change saved filters")
+		return
+	}
+
+	var req models.CreateSavedFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	scope := todoScope(c, userID.(string))
+	existing, err := h.filters.List(ctx, scope)
+	if err != nil {
+		respondStorageError(c, err, "Failed to save filter")
+		return
+	}
+	if len(existing) >= models.MaxSavedFilters {
+		apierrors.Respond(c, apierrors.CodeQuotaExceeded,
+			fmt.Sprintf("There can be at most %d saved filters; delete one to add another", models.MaxSavedFilters))
+		return
+	}
+
+	now := time.Now()
+	filter := models.SavedFilter{
+		UserID:    userID.(string),
+		Name:      req.Name,
+		Criteria:  req.Criteria,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if scope.IsWorkspace() {
+		filter.WorkspaceID = &scope.WorkspaceID
+	}
+	if err := h.filters.Create(ctx, &filter); err != nil {
+		respondStorageError(c, err, "Failed to save filter")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"filter": filter})
+}
+
+// UpdateSavedFilter renames a filter or replaces its criteria
+func (h *SavedFilterHandler) UpdateSavedFilter(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+	objectID, err := primitive.ObjectIDFromHex(c.Param("filter_id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid filter ID")
+		return
+	}
+	if err := authz.CanWrite(scopeRole(c)); err != nil {
+		apierrors.Respond(c, apierrors.CodeForbidden, "Viewers can't change saved filters")
+		return
+	}
+
+	var req models.UpdateSavedFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	filter, err := h.filters.Get(ctx, todoScope(c, userID.(string)), objectID)
+	if err != nil {
+		respondFilterError(c, err, "Failed to update saved filter")
+		return
+	}
+	if req.Name != nil {
+		filter.Name = *req.Name
+	}
+	if req.Criteria != nil {
+		filter.Criteria = *req.Criteria
+	}
+	filter.UpdatedAt = time.Now()
+	if err := h.filters.Update(ctx, filter); err != nil {
+		respondFilterError(c, err, "Failed to update saved filter")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filter": filter})
+}
+
+// DeleteSavedFilter deletes a saved filter
+func (h *SavedFilterHandler) DeleteSavedFilter(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+	objectID, err := primitive.ObjectIDFromHex(c.Param("filter_id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid filter ID")
+		return
+	}
+	if err := authz.CanWrite(scopeRole(c)); err != nil {
+		apierrors.Respond(c, apierrors.CodeForbidden, "Viewers can't change saved filters")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	if err := h.filters.Delete(ctx, todoScope(c, userID.(string)), objectID); err != nil {
+		respondFilterError(c, err, "Failed to delete saved filter")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Saved filter deleted successfully"})
+}
+
+// respondFilterError maps a repository error on a saved filter to a
+// response
+func respondFilterError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodeSavedFilterNotFound, "Saved filter not found")
+		return
+	}
+	respondStorageError(c, err, message)
+}
+
+// savedFilter looks up the filter named by the filter parameter in the
+// scope, returning nil when there's none. It responds with an error and
+// returns false if the filter doesn't exist.
+func (h *TodoHandler) savedFilter(ctx context.Context, c *gin.Context, scope repository.Scope) (*models.SavedFilter, bool) {
+	id := c.Query("filter")
+	if id == "" {
+		return nil, true
+	}
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid filter ID")
+		return nil, false
+	}
+	filter, err := h.filters.Get(ctx, scope, objectID)
+	if err != nil {
+		respondFilterError(c, err, "Failed to fetch todos")
+		return nil, false
+	}
+	return filter, true
+}
+
+// withSavedFilter keeps the todos meeting the filter's criteria. Due dates
+// are read in the user's time zone, which is only looked up when the
+// criteria need it; on failure it responds with an error and returns
+// false.
+func (h *TodoHandler) withSavedFilter(ctx context.Context, c *gin.Context, todos []models.Todo, criteria *models.TodoFilter) ([]models.Todo, bool) {
+	today := time.Time{}
+	if criteria.DueWithinDays != nil {
+		loc, ok := h.settings.location(ctx, c)
+		if !ok {
+			return nil, false
+		}
+		today = duedate.StartOfDay(time.Now().In(loc))
+	}
+	kept := todos[:0]
+	for _, todo := range todos {
+		if criteria.Matches(&todo, today) {
+			kept = append(kept, todo)
+		}
+	}
+	if criteria.Search != "" {
+		kept = withSearch(kept, search.Parse(criteria.Search))
+	}
+	return kept, true
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"todo-api/buildinfo"
+	"todo-api/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds how long a single dependency check may take
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck probes a single dependency the API needs to serve traffic
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+	// Optional dependencies (like a cache) only degrade the service when
+	// they fail, so they're reported without failing readiness
+	Optional bool
+}
+
+// CheckResult is the outcome of a single readiness check
+type CheckResult struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Healthz reports whether the process is alive. It never touches dependencies
+// so a slow database can't get the instance restarted.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Version reports the build that's serving: its commit, build time and Go
+// version
+func Version(c *gin.Context) {
+	c.JSON(http.StatusOK, buildinfo.Get())
+}
+
+// Readyz reports whether the API is ready to serve traffic by running every
+// dependency check with a short timeout
+func Readyz(checks ...ReadinessCheck) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := http.StatusOK
+		degraded := false
+		results := make(map[string]CheckResult, len(checks))
+
+		for _, check := range checks {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+			start := time.Now()
+			err := check.Check(ctx)
+			cancel()
+
+			result := CheckResult{
+				Status:    "ok",
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			switch {
+			case err != nil && check.Optional:
+				result.Status = "degraded"
+				result.Error = logging.Error(err)
+				degraded = true
+			case err != nil:
+				result.Status = "unavailable"
+				result.Error = logging.Error(err)
+				status = http.StatusServiceUnavailable
+			}
+			results[check.Name] = result
+		}
+
+		overall := "ok"
+		if status != http.StatusOK {
+			overall = "unavailable"
+		} else if degraded {
+			overall = "degraded"
+		}
+
+		c.JSON(status, gin.H{
+			"status": overall,
+			"checks": results,
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/cache"
+	"todo-api/importer"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importStateTTL is how long users have to allow access to a service
+const importStateTTL = 15 * time.Minute
+
+// importListField is the custom field imported todos keep their list in
+const importListField = "List"
+
+// ImportHandler imports tasks from other services as todos. The states
+// that tie an authorization to the import it was started for live in the
+// same cache as undo records.
+type ImportHandler struct {
+	providers map[string]*importer.Provider
+	states    cache.Cache
+	todos     *TodoHandler
+}
+
+// NewImportHandler creates an ImportHandler for the configured providers,
+// creating todos through todos, so quotas and events apply as they do in
+// the API
+func NewImportHandler(providers map[string]*importer.Provider, states cache.Cache, todos *TodoHandler) *ImportHandler {
+	return &ImportHandler{providers: providers, states: states, todos: todos}
+}
+
+// importState is what an import was started for, kept under its state
+type importState struct {
+	UserID   string `json:"user_id"`
+	Provider string `json:"provider"`
+	Scope    string `json:"scope"`
+}
+
+// importStateKey is the cache key of a state
+func importStateKey(state string) string {
+	return "import-state:" + hashLinkToken(state)
+}
+
+// provider returns the provider a route names, responding with an error if
+// it isn't configured. Importing creates todos, so viewers can't.
+func (h *ImportHandler) provider(c *gin.Context) (*importer.Provider, bool) {
+	p, ok := h.providers[c.Param("provider")]
+	if !ok {
+		apierrors.Respond(c, apierrors.CodeImportSourceNotFound, "Tasks can't be imported from "+c.Param("provider"))
+		return nil, false
+	}
+	if err := authz.CanWrite(scopeRole(c)); err != nil {
+		apierrors.Respond(c, apierrors.CodeForbidden, "Viewers can't import todos")
+		return nil, false
+	}
+	return p, true
+}
+
+// ListSources handles GET /imports, listing the services tasks can be
+// imported from
+func (h *ImportHandler) ListSources(c *gin.Context) {
+	sources := []gin.H{}
+	for _, name := range slices.Sorted(maps.Keys(h.providers)) {
+		sources = append(sources, gin.H{"name": name, "title": h.providers[name].Title})
+	}
+	c.JSON(http.StatusOK, gin.H{"sources": sources})
+}
+
+// StartImport handles POST /imports/:provider/authorize. The app sends the
+// user to the authorization URL, and once they allow access the service
+// sends them back to the app with a code to complete the import with.
+func (h *ImportHandler) StartImport(c *gin.Context) {
+	p, ok := h.provider(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.todos.timeout)
+	defer cancel()
+
+	state, err := newLinkToken()
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInternal, "Failed to start import")
+		return
+	}
+	userID := c.GetString("user_id")
+	data, _ := json.Marshal(importState{UserID: userID, Provider: p.Name, Scope: todoScope(c, userID).String()})
+	if err := h.states.Set(ctx, importStateKey(state), data, importStateTTL); err != nil {
+		respondStorageError(c, err, "Failed to start import")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"authorization_url": p.AuthorizationURL(state),
+		"state":             state,
+		"expires_at":        time.Now().Add(importStateTTL),
+	})
+}
+
+// Import handles POST /imports/:provider, completing an import with the
+// code and state the service sent the user back with. Tasks imported
+// before are skipped, so an import can be run again to bring over what's
+// new.
+func (h *ImportHandler) Import(c *gin.Context) {
+	p, ok := h.provider(c)
+	if !ok {
+		return
+	}
+	var req models.ImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+	dryRun, ok := isDryRun(c)
+	if !ok {
+		return
+	}
+
+	// Reading the service's tasks has its own timeouts, per request made
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	scope := todoScope(c, userID)
+
+	// States are used once, and only by the user and import they're for
+	data, err := h.states.Get(ctx, importStateKey(req.State))
+	var state importState
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if errors.Is(err, cache.ErrMiss) || err == nil && state != (importState{UserID: userID, Provider: p.Name, Scope: scope.String()}) {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "state", Message: "is unknown or expired; start the import again"}})
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to import todos")
+		return
+	}
+	if err := h.states.Delete(ctx, importStateKey(req.State)); err != nil {
+		respondStorageError(c, err, "Failed to import todos")
+		return
+	}
+
+	tasks, err := p.Tasks(ctx, req.Code)
+	switch {
+	case errors.Is(err, importer.ErrInvalidCode):
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "code", Message: "was rejected by " + p.Title + "; start the import again"}})
+		return
+	case errors.Is(err, importer.ErrTooManyTasks):
+		apierrors.Respond(c, apierrors.CodeImportFailed, err.Error())
+		return
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		respondStorageError(c, err, "Failed to import todos")
+		return
+	case err != nil:
+		log.Printf("Import from %s failed: %v", p.Title, err)
+		apierrors.Respond(c, apierrors.CodeImportFailed, "Couldn't read your tasks from "+p.Title+", please retry")
+		return
+	}
+
+	storeCtx, cancel := context.WithTimeout(ctx, h.todos.timeout)
+	defer cancel()
+	summary, warnings, ok := h.importTasks(storeCtx, c, p, scope, tasks, dryRun)
+	if !ok {
+		return
+	}
+	response := gin.H{"import": summary}
+	if dryRun {
+		response["dry_run"] = true
+	} else {
+		log.Printf("Imported %d todos from %s", summary.Todos.Created, p.Title)
+	}
+	if warnings != nil {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// importTasks creates a todo for each task not imported before. Either all
+// of them fit in the scope's quota or none is created, and warnings tell
+// when the quota is nearly used up after. A dry run creates nothing and
+// lists a sample of the tasks it would import. It responds with an error
+// and returns false on failure.
+func (h *ImportHandler) importTasks(ctx context.Context, c *gin.Context, p *importer.Provider, scope repository.Scope, tasks []importer.Task, dryRun bool) (summary *models.ImportSummary, warnings []quotaWarning, ok bool) {
+	summary = &models.ImportSummary{Source: p.Name}
+	existing, err := h.todos.todos.List(ctx, scope)
+	if err != nil {
+		respondStorageError(c, err, "Failed to import todos")
+		return nil, nil, false
+	}
+	imported := map[string]bool{}
+	for _, todo := range existing {
+		if todo.ImportSource != "" {
+			imported[todo.ImportSource] = true
+		}
+	}
+	loc, ok := h.todos.settings.location(ctx, c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	lists := map[string]bool{}
+	var todos []models.Todo
+	var listNames []string
+	now := time.Now()
+	for _, task := range tasks {
+		lists[task.List] = true
+		source := p.Name + ":" + task.ID
+		if imported[source] {
+			summary.Todos.Skipped++
+			continue
+		}
+		todo, ok := importedTodo(task, loc, now)
+		if !ok {
+			summary.Untitled++
+			continue
+		}
+		todo.UserID, todo.ImportSource = c.GetString("user_id"), source
+		if scope.IsWorkspace() {
+			todo.WorkspaceID = &scope.WorkspaceID
+		}
+		// Skip tasks listed twice, as happens when a page shifts under us
+		imported[source] = true
+		todos = append(todos, todo)
+		listNames = append(listNames, task.List)
+	}
+	summary.Lists = len(lists)
+
+	u, err := h.todos.quotas.todoUsage(ctx, scope)
+	if err != nil {
+		respondStorageError(c, err, "Failed to check todo quota")
+		return nil, nil, false
+	}
+	if u.Limit != nil && u.Used+int64(len(todos)) > int64(*u.Limit) {
+		apierrors.Write(c, todoQuotaProblem(scope, *u.Limit))
+		return nil, nil, false
+	}
+
+	field, err := h.listField(ctx, c, scope, len(todos) > 0, dryRun)
+	if err != nil {
+		respondStorageError(c, err, "Failed to import todos")
+		return nil, nil, false
+	}
+	if dryRun {
+		summary.Todos.Created = len(todos)
+		summary.SampleSources = []string{}
+		for i := 0; i < len(todos) && i < dryRunSampleSize; i++ {
+			summary.SampleSources = append(summary.SampleSources, todos[i].ImportSource)
+		}
+		if field != nil {
+			summary.ListField = field.Name
+		}
+		return summary, h.todos.quotas.todoWarnings(c, scope, u.add(int64(len(todos)))), true
+	}
+	for i := range todos {
+		todo := &todos[i]
+		if field != nil && listNames[i] != "" {
+			todo.CustomFields = map[string]any{field.ID.Hex(): truncateRunes(listNames[i], models.MaxCustomFieldTextLength)}
+		}
+		if err := h.todos.createTodo(ctx, todo); err != nil {
+			// What was created stays; running the import again finishes it
+			respondStorageError(c, err, "Failed to import todos")
+			return nil, nil, false
+		}
+		summary.Todos.Created++
+	}
+	if field != nil {
+		summary.ListField = field.Name
+	}
+	return summary, h.todos.quotas.todoWarnings(c, scope, u.add(int64(len(todos)))), true
+}
+
+// listField returns the text custom field imported todos keep their list
+// in, adding it when there's none and the user may add fields, or only
+// returning the field it would add in a dry run. It's nil when a field of
+// another type has the name, or the field can't be added.
+func (h *ImportHandler) listField(ctx context.Context, c *gin.Context, scope repository.Scope, create, dryRun bool) (*models.CustomField, error) {
+	fields, err := h.todos.fields.List(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	if field := fieldByKey(fields, importListField); field != nil {
+		if field.Type != models.FieldText {
+			return nil, nil
+		}
+		return field, nil
+	}
+	if !create || len(fields) >= models.MaxCustomFields || !scopeRole(c).Allows(authz.ActionManageWorkspace) {
+		return nil, nil
+	}
+	field := &models.CustomField{
+		UserID:    c.GetString("user_id"),
+		Name:      importListField,
+		Type:      models.FieldText,
+		CreatedAt: time.Now(),
+	}
+	if scope.IsWorkspace() {
+		field.WorkspaceID = &scope.WorkspaceID
+	}
+	if dryRun {
+		return field, nil
+	}
+	if err := h.todos.fields.Create(ctx, field); err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+// importedTodo converts a task into a todo, cutting text down to the
+// lengths todos allow. Tasks without a title are skipped.
+func importedTodo(task importer.Task, loc *time.Location, now time.Time) (models.Todo, bool) {
+	req := models.CreateTodoRequest{
+		Title:       truncateRunes(task.Title, models.MaxTitleLength),
+		Description: truncateRunes(task.Notes, models.MaxDescriptionLength),
+		DueDate:     task.Due,
+	}
+	req.Normalize()
+	if req.Title == "" {
+		return models.Todo{}, false
+	}
+	todo := models.Todo{
+		Title:       req.Title,
+		Description: req.Description,
+		DueDate:     dueDate(req.DueDate, loc),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if task.Important {
+		todo.Priority = models.PriorityHigh
+	}
+	if task.Completed {
+		todo.SetCompleted(true, now)
+		if task.CompletedAt != nil {
+			completedAt := task.CompletedAt.UTC()
+			todo.CompletedAt = &completedAt
+		}
+	}
+	return todo, true
+}
+
+// truncateRunes cuts s down to at most n characters
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"strings"
+
+	"todo-api/apierrors"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// todoEmbeds are the resources related to a todo that responses can embed
+// under _embedded, so a detail view needs one request instead of several
+type todoEmbeds struct {
+	comments  bool
+	workspace bool
+}
+
+// add picks the named resource, reporting whether it's one. Attachments
+// come with every todo, so asking for them changes nothing.
+func (e *todoEmbeds) add(name string) bool {
+	switch name {
+	case "comments":
+		e.comments = true
+	case "workspace":
+		e.workspace = true
+	case "attachments":
+	default:
+		return false
+	}
+	return true
+}
+
+// includeEmbeds reads ?include=, a comma-separated list of the resources to
+// embed in each todo: comments, workspace and attachments. Anything else
+// gets a validation error and ok is false.
+func includeEmbeds(c *gin.Context) (embeds todoEmbeds, ok bool) {
+	v := c.Query("include")
+	if v == "" {
+		return embeds, true
+	}
+	for _, name := range strings.Split(v, ",") {
+		if !embeds.add(strings.TrimSpace(name)) {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "include", Message: `must list "comments", "workspace" or "attachments"`}})
+			return embeds, false
+		}
+	}
+	return embeds, true
+}
+
+// embedTodo fills in the resources of a todo asked for. Comments are
+// stored with the todo and the workspace was loaded by RequireMember, so
+// nothing more is read; a todo outside the request's workspace embeds it
+// as null.
+func embedTodo(c *gin.Context, todo *models.Todo, embeds todoEmbeds) {
+	if !embeds.comments && !embeds.workspace {
+		return
+	}
+	embedded := map[string]any{}
+	if embeds.comments {
+		comments := todo.Comments
+		if comments == nil {
+			comments = []models.Comment{}
+		}
+		embedded["comments"] = comments
+	}
+	if embeds.workspace {
+		var workspace *models.Workspace
+		if w, ok := c.Get("workspace"); ok && todo.WorkspaceID != nil && w.(*models.Workspace).ID == *todo.WorkspaceID {
+			workspace = w.(*models.Workspace)
+		}
+		embedded["workspace"] = workspace
+	}
+	todo.Embedded = embedded
+}
+
+// embedTodos fills in the resources of todos asked for
+func embedTodos(c *gin.Context, todos []models.Todo, embeds todoEmbeds) {
+	for i := range todos {
+		embedTodo(c, &todos[i], embeds)
+	}
+}
+
+// cloneParts are the parts of a todo a clone can leave behind
+type cloneParts struct {
+	subtasks bool
+	tags     bool
+}
+
+// includeCloneParts reads ?include= of a clone, a comma-separated list of
+// the parts copied: subtasks, the checklist items, and tags. Without it
+// both are copied; an empty list copies neither. Anything else gets a
+// validation error and ok is false.
+func includeCloneParts(c *gin.Context) (parts cloneParts, ok bool) {
+	v, sent := c.GetQuery("include")
+	if !sent {
+		return cloneParts{subtasks: true, tags: true}, true
+	}
+	for _, name := range strings.Split(v, ",") {
+		switch strings.TrimSpace(name) {
+		case "subtasks":
+			parts.subtasks = true
+		case "tags":
+			parts.tags = true
+		case "":
+		default:
+			apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "include", Message: `must list "subtasks" or "tags"`}})
+			return parts, false
+		}
+	}
+	return parts, true
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"todo-api/integrations"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IntegrationStatus reports how each configured integration has been doing
+// lately, as seen by this instance
+func IntegrationStatus(monitor *integrations.Monitor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"integrations": monitor.Statuses(time.Now())})
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"todo-api/authz"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiBase returns the prefix of the API version the request came in on,
+// such as /api/v1, so links keep clients on the same version
+func apiBase(c *gin.Context) string {
+	path, ok := strings.CutPrefix(c.FullPath(), "/api/")
+	if !ok {
+		return "/api/v1"
+	}
+	version, _, _ := strings.Cut(path, "/")
+	return "/api/" + version
+}
+
+// todoLinks returns the links of a todo the user has the given role on.
+// Workspace todos are linked through their workspace. Viewers only get the
+// links that read.
+func todoLinks(base string, todo *models.Todo, role authz.Role) map[string]models.Link {
+	if todo.WorkspaceID != nil {
+		base += "/workspaces/" + todo.WorkspaceID.Hex()
+	}
+	self := base + "/todos/" + todo.ID.Hex()
+	links := map[string]models.Link{
+		"self":      {Href: self, Method: http.MethodGet},
+		"revisions": {Href: self + "/revisions", Method: http.MethodGet},
+		"comments":  {Href: self + "/comments", Method: http.MethodGet},
+	}
+	if authz.CanWrite(role) != nil {
+		return links
+	}
+	links["update"] = models.Link{Href: self, Method: http.MethodPut}
+	links["delete"] = models.Link{Href: self, Method: http.MethodDelete}
+	// Toggling sends the opposite of completed
+	links["toggle"] = models.Link{Href: self, Method: http.MethodPut}
+	if todo.Pinned {
+		links["unpin"] = models.Link{Href: self + "/pin", Method: http.MethodDelete}
+	} else {
+		links["pin"] = models.Link{Href: self + "/pin", Method: http.MethodPut}
+	}
+	links["clone"] = models.Link{Href: self + "/clone", Method: http.MethodPost}
+	links["add_checklist_item"] = models.Link{Href: self + "/checklist", Method: http.MethodPost}
+	if todo.WorkspaceID != nil {
+		links["assign"] = models.Link{Href: self + "/assign", Method: http.MethodPost}
+	}
+	return links
+}
+
+// linkTodo fills in the links of a todo the user has the given role on
+func linkTodo(c *gin.Context, todo *models.Todo, role authz.Role) {
+	todo.Links = todoLinks(apiBase(c), todo, role)
+}
+
+// linkTodos fills in the links of todos in the request's scope
+func linkTodos(c *gin.Context, todos []models.Todo) {
+	base, role := apiBase(c), scopeRole(c)
+	for i := range todos {
+		todos[i].Links = todoLinks(base, &todos[i], role)
+	}
+}
+
+// collectionLinks returns the links of a listing
+func collectionLinks(c *gin.Context) map[string]models.Link {
+	return map[string]models.Link{
+		"self": {Href: c.Request.URL.RequestURI(), Method: http.MethodGet},
+	}
+}
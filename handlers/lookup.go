@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LookupTodos returns the todos whose IDs are posted, in the order they
+// were asked for, so clients resolving references such as blockers don't
+// need a request per todo. IDs of todos that don't exist or are out of the
+// user's scope are listed in not_found.
+func (h *TodoHandler) LookupTodos(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := authz.CanRead(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to fetch todos")
+		return
+	}
+	var req models.LookupTodosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+	html, ok := wantsHTML(c)
+	if !ok {
+		return
+	}
+	fields, ok := fieldSelection(c)
+	if !ok {
+		return
+	}
+	embeds, ok := includeEmbeds(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	todos, err := h.todos.List(ctx, todoScope(c, userID.(string)))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch todos")
+		return
+	}
+	markBlocked(todos)
+
+	byID := make(map[primitive.ObjectID]*models.Todo, len(todos))
+	for i := range todos {
+		byID[todos[i].ID] = &todos[i]
+	}
+	found := []models.Todo{}
+	notFound := []string{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, id := range req.IDs {
+		objectID, _ := primitive.ObjectIDFromHex(id)
+		if seen[objectID] {
+			continue
+		}
+		seen[objectID] = true
+		if todo, ok := byID[objectID]; ok {
+			found = append(found, *todo)
+		} else {
+			notFound = append(notFound, objectID.Hex())
+		}
+	}
+
+	if html {
+		renderDescriptions(found)
+	}
+	linkTodos(c, found)
+	embedTodos(c, found, embeds)
+
+	c.JSON(http.StatusOK, gin.H{"todos": selectFields(found, fields), "not_found": notFound})
+}
+
+// idsFilter reads ?ids=, a comma-separated list of the todos to list,
+// returning nil when the parameter isn't sent. A list that's empty, too
+// long or holds something other than todo IDs gets a validation error and
+// ok is false.
+func idsFilter(c *gin.Context) (ids map[primitive.ObjectID]bool, ok bool) {
+	v, sent := c.GetQuery("ids")
+	if !sent {
+		return nil, true
+	}
+	var list []string
+	for _, id := range strings.Split(v, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			list = append(list, id)
+		}
+	}
+	if errs := models.ValidateLookupIDs("ids", list); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return nil, false
+	}
+	ids = make(map[primitive.ObjectID]bool, len(list))
+	for _, id := range list {
+		objectID, _ := primitive.ObjectIDFromHex(id)
+		ids[objectID] = true
+	}
+	return ids, true
+}
+
+// withIDs keeps the todos whose IDs are in ids, reusing the slice's storage
+func withIDs(todos []models.Todo, ids map[primitive.ObjectID]bool) []models.Todo {
+	kept := todos[:0]
+	for _, todo := range todos {
+		if ids[todo.ID] {
+			kept = append(kept, todo)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/models"
+	"todo-api/notify"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler serves the user's choice of channels for each kind of
+// notification
+type NotificationHandler struct {
+	users    repository.UserRepository
+	registry *notify.Registry
+	timeout  time.Duration
+}
+
+// NewNotificationHandler creates a NotificationHandler offering the
+// channels of registry
+func NewNotificationHandler(users repository.UserRepository, registry *notify.Registry, timeout time.Duration) *NotificationHandler {
+	return &NotificationHandler{users: users, registry: registry, timeout: timeout}
+}
+
+// GetPreferences returns the channels available and those each kind of
+// notification goes to
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	settings, err := h.users.GetSettings(ctx, c.GetString("user_id"))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch notification preferences")
+		return
+	}
+	h.respond(c, settings)
+}
+
+// UpdatePreferences changes the channels of the kinds of notification sent
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	var req models.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(h.registry.Channels()); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	userID := c.GetString("user_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	settings, err := h.users.GetSettings(ctx, userID)
+	if err != nil {
+		respondStorageError(c, err, "Failed to update notification preferences")
+		return
+	}
+	// Copied, as the settings read may share the map with stored ones
+	settings.Notifications = maps.Clone(settings.Notifications)
+	if settings.Notifications == nil {
+		settings.Notifications = map[string][]string{}
+	}
+	for kind, channels := range req.Preferences {
+		if channels == nil {
+			delete(settings.Notifications, kind)
+		} else {
+			settings.Notifications[kind] = channels
+		}
+	}
+	if err := h.users.SaveSettings(ctx, userID, settings); err != nil {
+		respondStorageError(c, err, "Failed to update notification preferences")
+		return
+	}
+	h.respond(c, settings)
+}
+
+// respond writes the preferences of a user with settings, with the defaults
+// filled in
+func (h *NotificationHandler) respond(c *gin.Context, settings models.UserSettings) {
+	preferences := make(map[string][]string, len(models.NotificationKinds))
+	for _, kind := range models.NotificationKinds {
+		preferences[kind] = h.registry.Enabled(settings, kind)
+	}
+	c.JSON(http.StatusOK, gin.H{"channels": h.registry.Channels(), "preferences": preferences})
+}
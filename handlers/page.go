@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"todo-api/apierrors"
+	"todo-api/middleware"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Number of todos on a page of a paged listing
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// pageRequest is the page of a listing a request asked for, numbered from 1
+type pageRequest struct {
+	number, size int
+}
+
+// pageInfo describes the page of a listing that was returned
+type pageInfo struct {
+	Number     int `json:"number"`
+	Size       int `json:"size"`
+	TotalItems int `json:"total_items"`
+	TotalPages int `json:"total_pages"`
+}
+
+// pageParams reads ?page= and ?per_page=. Listings are only paged from v2
+// on, so v1 requests get nil and the whole listing. Bad values get a
+// validation error and ok is false.
+func pageParams(c *gin.Context) (*pageRequest, bool) {
+	if c.GetString("api_version") == "v1" {
+		return nil, true
+	}
+	p := &pageRequest{number: 1, size: defaultPageSize}
+	var errs []apierrors.FieldError
+	if v := c.Query("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			errs = append(errs, apierrors.FieldError{Field: "page", Message: "must be a number of at least 1"})
+		}
+		p.number = n
+	}
+	if v := c.Query("per_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			errs = append(errs, apierrors.FieldError{Field: "per_page", Message: "must be a number between 1 and " + strconv.Itoa(maxPageSize)})
+		}
+		p.size = n
+	}
+	if len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return nil, false
+	}
+	return p, true
+}
+
+// paginate returns the requested page of todos, which is empty past the
+// last page, and puts the paging details in the response's meta and links
+func paginate(c *gin.Context, todos []models.Todo, p *pageRequest, links map[string]models.Link) []models.Todo {
+	total := len(todos)
+	info := pageInfo{Number: p.number, Size: p.size, TotalItems: total, TotalPages: (total + p.size - 1) / p.size}
+	middleware.AddMeta(c, "page", info)
+
+	link := func(number int) models.Link {
+		u := *c.Request.URL
+		q := u.Query()
+		q.Set("page", strconv.Itoa(number))
+		u.RawQuery = q.Encode()
+		return models.Link{Href: u.RequestURI(), Method: http.MethodGet}
+	}
+	links["first"] = link(1)
+	if info.TotalPages > 0 {
+		links["last"] = link(info.TotalPages)
+	}
+	if p.number > 1 {
+		links["prev"] = link(min(p.number-1, max(info.TotalPages, 1)))
+	}
+	if p.number < info.TotalPages {
+		links["next"] = link(p.number + 1)
+	}
+
+	start := min((p.number-1)*p.size, total)
+	return todos[start:min(start+p.size, total)]
+}
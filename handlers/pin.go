@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"sort"
+
+	"todo-api/apierrors"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PinTodo pins a todo so it's listed before the others
+func (h *TodoHandler) PinTodo(c *gin.Context) {
+	h.setPinned(c, true, "Failed to pin todo")
+}
+
+// UnpinTodo unpins a todo
+func (h *TodoHandler) UnpinTodo(c *gin.Context) {
+	h.setPinned(c, false, "Failed to unpin todo")
+}
+
+// setPinned pins or unpins the todo named by the :id parameter. Both are
+// idempotent, so clients can toggle without reading the todo first.
+func (h *TodoHandler) setPinned(c *gin.Context, pinned bool, message string) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	h.modifyTodo(ctx, c, objectID, message, func(todo *models.Todo) {
+		todo.Pinned = pinned
+	})
+}
+
+// pinnedFilter reads ?pinned=true or ?pinned=false, returning nil when the
+// parameter isn't sent. Any other value gets a validation error and ok is
+// false.
+func pinnedFilter(c *gin.Context) (pinned *bool, ok bool) {
+	switch v := c.Query("pinned"); v {
+	case "":
+		return nil, true
+	case "true", "false":
+		want := v == "true"
+		return &want, true
+	}
+	apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "pinned", Message: `must be "true" or "false"`}})
+	return nil, false
+}
+
+// withPinned keeps the todos whose Pinned flag matches pinned, reusing the
+// slice's storage
+func withPinned(todos []models.Todo, pinned bool) []models.Todo {
+	kept := todos[:0]
+	for _, todo := range todos {
+		if todo.Pinned == pinned {
+			kept = append(kept, todo)
+		}
+	}
+	return kept
+}
+
+// pinnedFirst moves pinned todos ahead of the rest, keeping the order within
+// each group
+func pinnedFirst(todos []models.Todo) {
+	sort.SliceStable(todos, func(i, j int) bool {
+		return todos[i].Pinned && !todos[j].Pinned
+	})
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/markdown"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PublicLinkHandler serves public read-only links to todos
+type PublicLinkHandler struct {
+	links   repository.PublicLinkRepository
+	todos   repository.TodoRepository
+	shares  repository.ShareRepository
+	timeout time.Duration
+}
+
+// NewPublicLinkHandler creates a PublicLinkHandler. shares is consulted so
+// users a todo is shared with are told they can't link it, rather than that
+// it doesn't exist.
+func NewPublicLinkHandler(links repository.PublicLinkRepository, todos repository.TodoRepository, shares repository.ShareRepository, timeout time.Duration) *PublicLinkHandler {
+	return &PublicLinkHandler{links: links, todos: todos, shares: shares, timeout: timeout}
+}
+
+// publicLinkResponse is returned once, when a link is created; the token
+// can't be shown again because only its hash is stored
+type publicLinkResponse struct {
+	models.PublicLink
+	Token string `json:"token"`
+	Path  string `json:"path"`
+}
+
+// publicTodo is the read-only view of a todo served through a public link.
+// It leaves out the IDs of the todo and its owner.
+type publicTodo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// DescriptionHTML is set when the page asks for ?render=html
+	DescriptionHTML string    `json:"description_html,omitempty"`
+	Completed       bool      `json:"completed"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// CreatePublicLink creates a public link to one of the user's personal
+// todos, revoking the previous link if there was one
+func (h *PublicLinkHandler) CreatePublicLink(c *gin.Context) {
+	userID := c.GetString("user_id")
+	todoID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	_, role, err := todoAccess(ctx, c, h.todos, h.shares, todoID)
+	if err == nil {
+		err = authz.Authorize(role, authz.ActionShare)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to create public link")
+		return
+	}
+
+	token, err := newLinkToken()
+	if err != nil {
+		respondStorageError(c, err, "Failed to create public link")
+		return
+	}
+	link := models.PublicLink{
+		TokenHash: hashLinkToken(token),
+		TodoID:    todoID,
+		OwnerID:   userID,
+		CreatedAt: time.Now(),
+	}
+	if err := h.links.Replace(ctx, &link); err != nil {
+		respondStorageError(c, err, "Failed to create public link")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"public_link": publicLinkResponse{
+		PublicLink: link,
+		Token:      token,
+		Path:       "/public/todos/" + token,
+	}})
+}
+
+// RevokePublicLink revokes the public link to one of the user's todos
+func (h *PublicLinkHandler) RevokePublicLink(c *gin.Context) {
+	todoID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	_, role, err := todoAccess(ctx, c, h.todos, h.shares, todoID)
+	if err == nil {
+		err = authz.Authorize(role, authz.ActionShare)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to revoke public link")
+		return
+	}
+
+	err = h.links.Delete(ctx, c.GetString("user_id"), todoID)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodePublicLinkNotFound, "This todo has no public link")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to revoke public link")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Public link revoked successfully"})
+}
+
+// GetPublicTodo serves the todo behind a public link. It needs no
+// authentication; the token is the credential.
+func (h *PublicLinkHandler) GetPublicTodo(c *gin.Context) {
+	// Revocation must take effect immediately, and the page shouldn't be indexed
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex")
+	html, ok := wantsHTML(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	link, err := h.links.Get(ctx, hashLinkToken(c.Param("token")))
+	var todo *models.Todo
+	if err == nil {
+		todo, err = h.todos.Get(ctx, repository.Personal(link.OwnerID), link.TodoID)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodePublicLinkNotFound, "This link doesn't exist or was revoked")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch todo")
+		return
+	}
+
+	response := publicTodo{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Completed:   todo.Completed,
+		CreatedAt:   todo.CreatedAt,
+		UpdatedAt:   todo.UpdatedAt,
+	}
+	if html {
+		response.DescriptionHTML = markdown.Render(todo.Description)
+	}
+	c.JSON(http.StatusOK, gin.H{"todo": response})
+}
+
+// newLinkToken returns a random 256-bit token, URL-safe encoded
+func newLinkToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashLinkToken returns the hash a link is stored under. The token has full
+// entropy, so a fast unsalted hash is enough.
+func hashLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
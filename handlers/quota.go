@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/config"
+	"todo-api/i18n"
+	"todo-api/middleware"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaHandler enforces the per-user caps and reports usage against them.
+// Caps are checked before creating, so concurrent requests can overshoot
+// one by a few items; they exist to stop floods, not to bill.
+type QuotaHandler struct {
+	limits     config.QuotaConfig
+	todos      repository.TodoRepository
+	workspaces repository.WorkspaceRepository
+	timeout    time.Duration
+}
+
+// NewQuotaHandler creates a QuotaHandler
+func NewQuotaHandler(limits config.QuotaConfig, todos repository.TodoRepository, workspaces repository.WorkspaceRepository, timeout time.Duration) *QuotaHandler {
+	return &QuotaHandler{limits: limits, todos: todos, workspaces: workspaces, timeout: timeout}
+}
+
+// usage is the consumption of one capped resource. Limit is nil when the
+// resource isn't capped.
+type usage struct {
+	Used  int64 `json:"used"`
+	Limit *int  `json:"limit"`
+}
+
+// newUsage builds a usage, treating a zero limit as uncapped
+func newUsage(used int64, limit int) usage {
+	if limit == 0 {
+		return usage{Used: used}
+	}
+	return usage{Used: used, Limit: &limit}
+}
+
+// exhausted reports whether nothing more may be created
+func (u usage) exhausted() bool {
+	return u.Limit != nil && u.Used >= int64(*u.Limit)
+}
+
+// add returns the usage after n more items were created
+func (u usage) add(n int64) usage {
+	u.Used += n
+	return u
+}
+
+// Quotas a warning can be about
+const (
+	quotaTodos          = "todos"
+	quotaWorkspaceTodos = "workspace_todos"
+	quotaWorkspaces     = "workspaces"
+)
+
+// quotaWarning tells the client a quota is nearly used up, so it can say so
+// before creates start failing with QUOTA_EXCEEDED
+type quotaWarning struct {
+	Quota   string `json:"quota"`
+	Used    int64  `json:"used"`
+	Limit   int    `json:"limit"`
+	Message string `json:"message"`
+}
+
+// warn returns a warning about quota, whose usage is u, once
+// QUOTA_WARN_PERCENT of it is used, and adds it to the response headers.
+// message describes it, with {1} and {2} standing for the usage and limit.
+func (h *QuotaHandler) warn(c *gin.Context, quota string, u usage, message string) []quotaWarning {
+	percent := int64(h.limits.WarnPercent)
+	if u.Limit == nil || percent == 0 || u.Used*100 < int64(*u.Limit)*percent {
+		return nil
+	}
+	used, limit := min(u.Used, int64(*u.Limit)), *u.Limit
+	c.Writer.Header().Add(middleware.QuotaWarningHeader, fmt.Sprintf("%s=%d/%d", quota, used, limit))
+	message = i18n.Translate(i18n.Negotiate(c.GetHeader("Accept-Language")), message)
+	message = strings.NewReplacer("{1}", fmt.Sprint(used), "{2}", fmt.Sprint(limit)).Replace(message)
+	return []quotaWarning{{Quota: quota, Used: used, Limit: limit, Message: message}}
+}
+
+// todoWarnings warns when the scope, whose usage is u, nearly has all the
+// todos it may
+func (h *QuotaHandler) todoWarnings(c *gin.Context, scope repository.Scope, u usage) []quotaWarning {
+	if scope.IsWorkspace() {
+		return h.warn(c, quotaWorkspaceTodos, u, "This workspace has {1} of the {2} todos it may have")
+	}
+	return h.warn(c, quotaTodos, u, "You have {1} of the {2} todos you may have")
+}
+
+// workspaceWarnings warns when the user, whose usage is u, nearly owns all
+// the workspaces they may
+func (h *QuotaHandler) workspaceWarnings(c *gin.Context, u usage) []quotaWarning {
+	return h.warn(c, quotaWorkspaces, u, "You own {1} of the {2} workspaces you may")
+}
+
+// todoUsage counts the todos in the scope against its cap
+func (h *QuotaHandler) todoUsage(ctx context.Context, scope repository.Scope) (usage, error) {
+	limit := h.limits.MaxTodos
+	if scope.IsWorkspace() {
+		limit = h.limits.MaxWorkspaceTodos
+	}
+	n, err := h.todos.Count(ctx, scope)
+	return newUsage(n, limit), err
+}
+
+// workspaceUsage counts the workspaces the user owns against its cap.
+// Workspaces the user was added to don't count, so nobody can use up
+// someone else's quota.
+func (h *QuotaHandler) workspaceUsage(ctx context.Context, userID string) (usage, error) {
+	workspaces, err := h.workspaces.ListByMember(ctx, userID)
+	if err != nil {
+		return usage{}, err
+	}
+	var owned int64
+	for _, workspace := range workspaces {
+		if workspace.OwnerID == userID {
+			owned++
+		}
+	}
+	return newUsage(owned, h.limits.MaxWorkspaces), nil
+}
+
+// maxAttachments is how many links one todo may have attached
+func (h *QuotaHandler) maxAttachments() int {
+	if h.limits.MaxAttachments > 0 {
+		return h.limits.MaxAttachments
+	}
+	return models.MaxAttachments
+}
+
+// maxTags is how many tags one todo may have
+func (h *QuotaHandler) maxTags() int {
+	if h.limits.MaxTags > 0 {
+		return h.limits.MaxTags
+	}
+	return models.MaxTags
+}
+
+// todoErrors returns the fields of a todo request with more tags or links
+// than a todo may have. Requests are validated against the most any
+// deployment allows, and this against what this one does.
+func (h *QuotaHandler) todoErrors(tags []string, attachments int) []apierrors.FieldError {
+	var errs []apierrors.FieldError
+	if n := h.maxTags(); len(tags) > n {
+		errs = append(errs, apierrors.FieldError{Field: "tags", Message: fmt.Sprintf("must have at most %d tags", n)})
+	}
+	if n := h.maxAttachments(); attachments > n {
+		errs = append(errs, apierrors.FieldError{Field: "attachments", Message: fmt.Sprintf("must have at most %d entries", n)})
+	}
+	return errs
+}
+
+// defaultTags returns as many of the user's default tags as a todo may
+// have, since they may have been saved before the cap was lowered
+func (h *QuotaHandler) defaultTags(tags []string) []string {
+	return slices.Clone(tags[:min(len(tags), h.maxTags())])
+}
+
+// allowTodo responds with QUOTA_EXCEEDED and returns false if the scope
+// can't take another todo, otherwise returning the scope's usage
+func (h *QuotaHandler) allowTodo(ctx context.Context, c *gin.Context, scope repository.Scope) (usage, bool) {
+	u, err := h.todoUsage(ctx, scope)
+	if err != nil {
+		respondStorageError(c, err, "Failed to check todo quota")
+		return usage{}, false
+	}
+	if u.exhausted() {
+		apierrors.Write(c, todoQuotaProblem(scope, *u.Limit))
+		return usage{}, false
+	}
+	return u, true
+}
+
+// todoQuotaProblem reports a scope that has reached its cap of limit todos
+func todoQuotaProblem(scope repository.Scope, limit int) *apierrors.Problem {
+	detail := "You have reached the limit of %d todos; delete some to add more"
+	if scope.IsWorkspace() {
+		detail = "This workspace has reached the limit of %d todos; delete some to add more"
+	}
+	return apierrors.New(apierrors.CodeQuotaExceeded, fmt.Sprintf(detail, limit))
+}
+
+// allowWorkspace responds with QUOTA_EXCEEDED and returns false if the user
+// can't create another workspace, otherwise returning their usage
+func (h *QuotaHandler) allowWorkspace(ctx context.Context, c *gin.Context, userID string) (usage, bool) {
+	u, err := h.workspaceUsage(ctx, userID)
+	if err != nil {
+		respondStorageError(c, err, "Failed to check workspace quota")
+		return usage{}, false
+	}
+	if u.exhausted() {
+		apierrors.Respond(c, apierrors.CodeQuotaExceeded, fmt.Sprintf("You own the maximum of %d workspaces; delete one to create another", *u.Limit))
+		return usage{}, false
+	}
+	return u, true
+}
+
+// GetUsage reports the user's consumption of each capped resource, and
+// warns of those nearly used up
+func (h *QuotaHandler) GetUsage(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	todos, err := h.todoUsage(ctx, repository.Personal(userID))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch usage")
+		return
+	}
+	workspaces, err := h.workspaceUsage(ctx, userID)
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch usage")
+		return
+	}
+
+	var workspaceTodos *int
+	if h.limits.MaxWorkspaceTodos > 0 {
+		workspaceTodos = &h.limits.MaxWorkspaceTodos
+	}
+	attachments, tags := h.maxAttachments(), h.maxTags()
+	warnings := append(h.todoWarnings(c, repository.Personal(userID), todos), h.workspaceWarnings(c, workspaces)...)
+	if warnings == nil {
+		warnings = []quotaWarning{}
+	}
+	c.JSON(http.StatusOK, gin.H{"usage": gin.H{
+		"todos":      todos,
+		"workspaces": workspaces,
+		"limits": gin.H{
+			"workspace_todos": workspaceTodos,
+			"attachments":     attachments,
+			"tags":            tags,
+		},
+	}, "warnings": warnings})
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"todo-api/apierrors"
+	"todo-api/markdown"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// wantsHTML reports whether the request asked for descriptions rendered to
+// HTML with ?render=html. Any other value gets a validation error and ok is
+// false.
+func wantsHTML(c *gin.Context) (html, ok bool) {
+	switch c.Query("render") {
+	case "":
+		return false, true
+	case "html":
+		return true, true
+	}
+	apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "render", Message: `must be "html"`}})
+	return false, false
+}
+
+// renderDescriptions fills in the sanitized HTML of each todo's Markdown
+// description
+func renderDescriptions(todos []models.Todo) {
+	for i := range todos {
+		todos[i].DescriptionHTML = markdown.Render(todos[i].Description)
+	}
+}
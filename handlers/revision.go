@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListRevisions returns a todo's revision history, oldest first. Each
+// revision lists what its edit changed and the todo as it was afterwards.
+func (h *TodoHandler) ListRevisions(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	_, role, err := todoAccess(ctx, c, h.todos, h.shares, objectID)
+	if err == nil {
+		err = authz.CanRead(role)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to fetch revisions")
+		return
+	}
+
+	revisions, err := h.revisions.List(ctx, objectID)
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch revisions")
+		return
+	}
+	if revisions == nil {
+		revisions = []models.Revision{}
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// RevertRevision sets a todo's fields back to how they were in revision
+// :rev. The revert is an edit like any other and gets a revision of its own.
+// Values of custom fields deleted since are left out.
+func (h *TodoHandler) RevertRevision(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+	number, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || number < 1 {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid revision number")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	todo, role, err := todoAccess(ctx, c, h.todos, h.shares, objectID)
+	if err == nil {
+		err = authz.CanWrite(role)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to revert todo")
+		return
+	}
+
+	revisions, err := h.revisions.List(ctx, objectID)
+	if err != nil {
+		respondStorageError(c, err, "Failed to revert todo")
+		return
+	}
+	i := slices.IndexFunc(revisions, func(r models.Revision) bool { return r.Number == number })
+	if i < 0 {
+		apierrors.Respond(c, apierrors.CodeRevisionNotFound, "Revision not found")
+		return
+	}
+	revision := revisions[i]
+
+	if revision.Todo.Completed && (!checkChecklist(c, todo) || !h.checkBlockers(ctx, c, todo)) {
+		return
+	}
+	if len(revision.Todo.CustomFields) > 0 {
+		fields, err := h.fields.List(ctx, repository.ScopeOf(todo))
+		if err != nil {
+			respondStorageError(c, err, "Failed to revert todo")
+			return
+		}
+		maps.DeleteFunc(revision.Todo.CustomFields, func(key string, _ any) bool {
+			return fieldByKey(fields, key) == nil
+		})
+	}
+
+	h.modifyTodo(ctx, c, objectID, "Failed to revert todo", func(todo *models.Todo) {
+		revision.Revert(todo, time.Now().UTC())
+	})
+}
+
+// recordRevision stores a revision for an edit editedBy made that changed
+// before into after, unless it changed none of the fields revisions track. A todo's
+// first revision records it as it was before its first edit. Revisions are
+// a convenience, so failures are logged rather than failing the edit.
+func (h *TodoHandler) recordRevision(ctx context.Context, editedBy string, before, after *models.Todo) {
+	changes := models.DiffTodos(before, after)
+	if len(changes) == 0 {
+		return
+	}
+
+	revisions, err := h.revisions.List(ctx, after.ID)
+	if err != nil {
+		log.Printf("Failed to record revision of todo %s: %v", after.ID.Hex(), err)
+		return
+	}
+	number := 1
+	if n := len(revisions); n > 0 {
+		number = revisions[n-1].Number + 1
+	} else {
+		baseline := newRevision(before, 1, before.UserID, models.DiffTodos(&models.Todo{}, before), before.UpdatedAt)
+		if err := h.revisions.Create(ctx, &baseline); err != nil {
+			log.Printf("Failed to record revision of todo %s: %v", after.ID.Hex(), err)
+			return
+		}
+		number = 2
+	}
+
+	revision := newRevision(after, number, editedBy, changes, after.UpdatedAt)
+	if err := h.revisions.Create(ctx, &revision); err != nil {
+		log.Printf("Failed to record revision of todo %s: %v", after.ID.Hex(), err)
+		return
+	}
+	if number > models.MaxRevisions {
+		if err := h.revisions.DeleteBefore(ctx, after.ID, number-models.MaxRevisions+1); err != nil {
+			log.Printf("Failed to drop old revisions of todo %s: %v", after.ID.Hex(), err)
+		}
+	}
+}
+
+// newRevision makes revision number of todo
+func newRevision(todo *models.Todo, number int, editedBy string, changes []models.FieldChange, at time.Time) models.Revision {
+	return models.Revision{
+		TodoID:      todo.ID,
+		UserID:      todo.UserID,
+		WorkspaceID: todo.WorkspaceID,
+		Number:      number,
+		EditedBy:    editedBy,
+		Changes:     changes,
+		Todo:        models.RevisionSnapshot(todo),
+		CreatedAt:   at,
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"todo-api/apierrors"
+	"todo-api/config"
+	"todo-api/models"
+	"todo-api/schema"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SchemaContentType is the media type of a JSON Schema
+const SchemaContentType = "application/schema+json"
+
+// SchemaHandler publishes the JSON Schemas of request bodies, so clients can
+// check forms with the rules the server applies
+type SchemaHandler struct {
+	catalogue *schema.Catalogue
+	cfg       config.SchemaValidationConfig
+}
+
+// NewSchemaHandler creates a SchemaHandler publishing catalogue, which
+// reports the routes cfg checks
+func NewSchemaHandler(catalogue *schema.Catalogue, cfg config.SchemaValidationConfig) *SchemaHandler {
+	return &SchemaHandler{catalogue: catalogue, cfg: cfg}
+}
+
+// schemaRoute is a route taking a request body
+type schemaRoute struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Validated is whether the server checks bodies against the schema
+	// before handling them
+	Validated bool `json:"validated"`
+}
+
+// schemaSummary describes one of the schemas listed
+type schemaSummary struct {
+	Name   string                 `json:"name"`
+	Title  string                 `json:"title"`
+	Routes []schemaRoute          `json:"routes"`
+	Links  map[string]models.Link `json:"_links"`
+}
+
+// ListSchemas lists the schemas and the routes taking their bodies
+func (h *SchemaHandler) ListSchemas(c *gin.Context) {
+	base := apiBase(c)
+	requests := h.catalogue.Requests()
+	schemas := make([]schemaSummary, len(requests))
+	for i, r := range requests {
+		routes := make([]schemaRoute, len(r.Routes))
+		for j, route := range r.Routes {
+			method, path, _ := strings.Cut(route, " ")
+			routes[j] = schemaRoute{
+				Method:    method,
+				Path:      base + path,
+				Validated: h.cfg.Validates(method, base+path),
+			}
+		}
+		schemas[i] = schemaSummary{
+			Name:   r.Name,
+			Title:  r.Schema.Title,
+			Routes: routes,
+			Links:  map[string]models.Link{"self": {Href: base + "/schemas/" + r.Name, Method: http.MethodGet}},
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"schemas": schemas})
+}
+
+// GetSchema returns a schema as a JSON Schema document
+func (h *SchemaHandler) GetSchema(c *gin.Context) {
+	r, ok := h.catalogue.Get(c.Param("name"))
+	if !ok {
+		apierrors.Respond(c, apierrors.CodeSchemaNotFound, "No schema has that name")
+		return
+	}
+	document := *r.Schema
+	document.ID = apiBase(c) + "/schemas/" + r.Name
+	c.Header("Content-Type", SchemaContentType)
+	c.JSON(http.StatusOK, &document)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"todo-api/apierrors"
+	"todo-api/models"
+	"todo-api/search"
+	"todo-api/todoquery"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchQuery reads the q parameter, responding with a validation error
+// and returning false if it's too long or has a term that can't be
+// understood
+func searchQuery(c *gin.Context) (*todoquery.Query, bool) {
+	q := c.Query("q")
+	if utf8.RuneCountInString(q) > models.MaxSearchLength {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "q", Message: fmt.Sprintf("must be at most %d characters", models.MaxSearchLength)}})
+		return nil, false
+	}
+	query, err := todoquery.Parse(q)
+	if err != nil {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "q", Message: err.Error()}})
+		return nil, false
+	}
+	return query, true
+}
+
+// withQuery keeps the todos meeting the query's terms, reading due dates in
+// the user's time zone when the query compares them; on failure it responds
+// with an error and returns false. Free text is left to withSearch.
+func (h *TodoHandler) withQuery(ctx context.Context, c *gin.Context, todos []models.Todo, query *todoquery.Query) ([]models.Todo, bool) {
+	loc := time.UTC
+	if query.UsesDue() {
+		var ok bool
+		if loc, ok = h.settings.location(ctx, c); !ok {
+			return nil, false
+		}
+	}
+	matches := query.Matcher(loc)
+	kept := todos[:0]
+	for _, todo := range todos {
+		if matches(&todo) {
+			kept = append(kept, todo)
+		}
+	}
+	return kept, true
+}
+
+// snippetWidth is about how many characters of a title or description a
+// highlight shows
+const snippetWidth = 160
+
+// highlightTodos marks where each todo matched the free text of q, in its
+// title, its description or both. Nothing is marked without free text.
+func highlightTodos(todos []models.Todo, q search.Query) {
+	if q.Empty() {
+		return
+	}
+	for i := range todos {
+		todo := &todos[i]
+		if h, ok := q.Highlight("title", todo.Title, snippetWidth); ok {
+			todo.Highlights = append(todo.Highlights, h)
+		}
+		if h, ok := q.Highlight("description", todo.Description, snippetWidth); ok {
+			todo.Highlights = append(todo.Highlights, h)
+		}
+	}
+}
+
+// withSearch keeps the todos matching the query, best matches first and
+// otherwise in their original order, reusing the slice's storage
+func withSearch(todos []models.Todo, q search.Query) []models.Todo {
+	kept := todos[:0]
+	var scores []float64
+	for _, todo := range todos {
+		if score := q.Score(todo.Title, todo.Description); score > 0 {
+			kept = append(kept, todo)
+			scores = append(scores, score)
+		}
+	}
+	sort.Stable(byScore{kept, scores})
+	return kept
+}
+
+// byScore sorts todos by descending score
+type byScore struct {
+	todos  []models.Todo
+	scores []float64
+}
+
+func (s byScore) Len() int           { return len(s.todos) }
+func (s byScore) Less(i, j int) bool { return s.scores[i] > s.scores[j] }
+func (s byScore) Swap(i, j int) {
+	s.todos[i], s.todos[j] = s.todos[j], s.todos[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
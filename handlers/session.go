@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/config"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler serves the session management endpoints
+type SessionHandler struct {
+	sessions repository.SessionRepository
+	cookie   config.CookieConfig
+	timeout  time.Duration
+}
+
+// NewSessionHandler creates a SessionHandler. cookie is needed to clear the
+// identity cookie when the current session ends.
+func NewSessionHandler(sessions repository.SessionRepository, cookie config.CookieConfig, timeout time.Duration) *SessionHandler {
+	return &SessionHandler{sessions: sessions, cookie: cookie, timeout: timeout}
+}
+
+// sessionResponse is a session as listed to its owner
+type sessionResponse struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// Logout revokes the current session and clears the identity cookie
+func (h *SessionHandler) Logout(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	err := h.sessions.Delete(ctx, c.GetString("user_id"), c.GetString("session_id"))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		respondStorageError(c, err, "Failed to log out")
+		return
+	}
+
+	h.clearCookie(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// ListSessions lists the user's active sessions
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	sessions, err := h.sessions.ListByUser(ctx, c.GetString("user_id"))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch sessions")
+		return
+	}
+
+	current := c.GetString("session_id")
+	response := make([]sessionResponse, len(sessions))
+	for i, session := range sessions {
+		response[i] = sessionResponse{Session: session, Current: session.ID == current}
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": response})
+}
+
+// RevokeSession revokes one of the user's sessions. Revoking the current
+// session is the same as logging out.
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	id := c.Param("id")
+	err := h.sessions.Delete(ctx, c.GetString("user_id"), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodeSessionNotFound, "Session not found")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to revoke session")
+		return
+	}
+
+	if id == c.GetString("session_id") {
+		h.clearCookie(c)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+func (h *SessionHandler) clearCookie(c *gin.Context) {
+	c.SetCookie(h.cookie.Name, "", -1, "/", "", h.cookie.Secure, true)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SettingsHandler serves the user's own settings and looks them up for
+// other handlers
+type SettingsHandler struct {
+	users   repository.UserRepository
+	timeout time.Duration
+}
+
+// NewSettingsHandler creates a SettingsHandler
+func NewSettingsHandler(users repository.UserRepository, timeout time.Duration) *SettingsHandler {
+	return &SettingsHandler{users: users, timeout: timeout}
+}
+
+// GetSettings returns the user's settings
+func (h *SettingsHandler) GetSettings(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	settings, err := h.users.GetSettings(ctx, c.GetString("user_id"))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"settings": settings})
+}
+
+// UpdateSettings changes the settings that are sent
+func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
+	var req models.UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	userID := c.GetString("user_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	settings, err := h.users.GetSettings(ctx, userID)
+	if err != nil {
+		respondStorageError(c, err, "Failed to update settings")
+		return
+	}
+	if req.Timezone != nil {
+		settings.Timezone = *req.Timezone
+	}
+	if req.EnforceBlockers != nil {
+		settings.EnforceBlockers = *req.EnforceBlockers
+	}
+	subscribed := settings.Digest != ""
+	if req.Digest != nil {
+		settings.Digest = *req.Digest
+	}
+	if req.Email != nil {
+		settings.Email = *req.Email
+	}
+	if req.RetentionOptOut != nil {
+		settings.RetentionOptOut = *req.RetentionOptOut
+	}
+	reset := settings.DailyReset
+	if req.DailyReset != nil {
+		settings.DailyReset = *req.DailyReset
+	}
+	if settings.Digest != "" && settings.Email == "" {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "email", Message: "is required to receive digests"}})
+		return
+	}
+	if err := h.users.SaveSettings(ctx, userID, settings); err != nil {
+		respondStorageError(c, err, "Failed to update settings")
+		return
+	}
+	// The first digest goes out at the next scheduled time, not right away
+	if !subscribed && settings.Digest != "" {
+		if _, err := h.users.ClaimDigest(ctx, userID, time.Now()); err != nil {
+			log.Printf("Failed to schedule first digest of user %s: %v", userID, err)
+		}
+	}
+	// Likewise a new reset time takes effect the next time it comes round,
+	// rather than catching up on today's
+	if settings.DailyReset != "" && settings.DailyReset != reset {
+		if _, err := h.users.ClaimDailyReset(ctx, userID, time.Now()); err != nil {
+			log.Printf("Failed to schedule first daily reset of user %s: %v", userID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"settings": settings})
+}
+
+// GetPreferences returns the defaults applied to the todos the user
+// creates
+func (h *SettingsHandler) GetPreferences(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	settings, err := h.users.GetSettings(ctx, c.GetString("user_id"))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": settings.CurrentPreferences()})
+}
+
+// UpdatePreferences changes the preferences that are sent
+func (h *SettingsHandler) UpdatePreferences(c *gin.Context) {
+	var req models.UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	userID := c.GetString("user_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	settings, err := h.users.GetSettings(ctx, userID)
+	if err != nil {
+		respondStorageError(c, err, "Failed to update preferences")
+		return
+	}
+	// Copied, as the settings read may share them with stored ones
+	var preferences models.Preferences
+	if settings.Preferences != nil {
+		preferences = *settings.Preferences
+	}
+	if req.DefaultPriority != nil {
+		preferences.DefaultPriority = *req.DefaultPriority
+	}
+	if req.DefaultTags != nil {
+		preferences.DefaultTags = *req.DefaultTags
+	}
+	if req.StartOfWeek != nil {
+		preferences.StartOfWeek = *req.StartOfWeek
+	}
+	settings.Preferences = &preferences
+	if err := h.users.SaveSettings(ctx, userID, settings); err != nil {
+		respondStorageError(c, err, "Failed to update preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": settings.CurrentPreferences()})
+}
+
+// lookup returns the user's settings, responding with an error and
+// returning false if they can't be looked up
+func (h *SettingsHandler) lookup(ctx context.Context, c *gin.Context) (models.UserSettings, bool) {
+	settings, err := h.users.GetSettings(ctx, c.GetString("user_id"))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch settings")
+		return models.UserSettings{}, false
+	}
+	return settings, true
+}
+
+// location returns the time zone the user's days are counted in,
+// responding with an error and returning false if it can't be looked up
+func (h *SettingsHandler) location(ctx context.Context, c *gin.Context) (*time.Location, bool) {
+	settings, err := h.users.GetSettings(ctx, c.GetString("user_id"))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch settings")
+		return nil, false
+	}
+	return settings.Location(), true
+}
+
+// enforceBlockers reports whether the user wants todos kept open while
+// their blockers are, responding with an error and returning false if it
+// can't be looked up
+func (h *SettingsHandler) enforceBlockers(ctx context.Context, c *gin.Context) (enforce, ok bool) {
+	settings, err := h.users.GetSettings(ctx, c.GetString("user_id"))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch settings")
+		return false, false
+	}
+	return settings.EnforceBlockers, true
+}
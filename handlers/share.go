@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/markdown"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareHandler serves the endpoints for sharing individual todos
+type ShareHandler struct {
+	shares  repository.ShareRepository
+	todos   repository.TodoRepository
+	timeout time.Duration
+}
+
+// NewShareHandler creates a ShareHandler. todos is needed to check that the
+// shared todo belongs to the user and to load todos shared with them.
+func NewShareHandler(shares repository.ShareRepository, todos repository.TodoRepository, timeout time.Duration) *ShareHandler {
+	return &ShareHandler{shares: shares, todos: todos, timeout: timeout}
+}
+
+// sharedTodo is a todo as listed to a user it was shared with
+type sharedTodo struct {
+	models.Todo
+	Role     authz.Role `json:"role"`
+	SharedBy string     `json:"shared_by"`
+}
+
+// ShareTodo shares one of the user's personal todos with another user, or
+// changes their role if it is already shared with them
+func (h *ShareHandler) ShareTodo(c *gin.Context) {
+	userID := c.GetString("user_id")
+	todoID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	var req models.ShareTodoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+	if req.UserID == userID || (req.Email != "" && req.Email == c.GetString("email")) {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "email", Message: "cannot share a todo with yourself"}})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	_, role, err := todoAccess(ctx, c, h.todos, h.shares, todoID)
+	if err == nil {
+		err = authz.Authorize(role, authz.ActionShare)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to share todo")
+		return
+	}
+
+	now := time.Now()
+	share := models.Share{
+		ID:        primitive.NewObjectID(),
+		TodoID:    todoID,
+		OwnerID:   userID,
+		Email:     req.Email,
+		UserID:    req.UserID,
+		Grantee:   models.GranteeUser(req.UserID),
+		Role:      req.Role,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.Email != "" {
+		share.Grantee = models.GranteeEmail(req.Email)
+	}
+	id := share.ID
+	if err := h.shares.Save(ctx, &share); err != nil {
+		respondStorageError(c, err, "Failed to share todo")
+		return
+	}
+
+	// Save keeps the existing share's ID when it only changed the role
+	status := http.StatusOK
+	if share.ID == id {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{"share": share})
+}
+
+// ListShares lists who one of the user's todos is shared with
+func (h *ShareHandler) ListShares(c *gin.Context) {
+	todoID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	_, role, err := todoAccess(ctx, c, h.todos, h.shares, todoID)
+	if err == nil {
+		err = authz.Authorize(role, authz.ActionShare)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to fetch shares")
+		return
+	}
+
+	shares, err := h.shares.ListByTodo(ctx, c.GetString("user_id"), todoID)
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch shares")
+		return
+	}
+	if shares == nil {
+		shares = []models.Share{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+// RevokeShare stops sharing a todo with one grantee
+func (h *ShareHandler) RevokeShare(c *gin.Context) {
+	todoID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("share_id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid share ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	_, role, err := todoAccess(ctx, c, h.todos, h.shares, todoID)
+	if err == nil {
+		err = authz.Authorize(role, authz.ActionShare)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to revoke share")
+		return
+	}
+
+	err = h.shares.Delete(ctx, todoID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodeShareNotFound, "Share not found")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to revoke share")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share revoked successfully"})
+}
+
+// SharedWithMe lists the todos other users have shared with the user
+func (h *ShareHandler) SharedWithMe(c *gin.Context) {
+	html, ok := wantsHTML(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	shares, err := h.shares.ListByGrantee(ctx, granteeKeys(c))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch shared todos")
+		return
+	}
+
+	todos := []sharedTodo{}
+	seen := make(map[primitive.ObjectID]int)
+	for _, share := range shares {
+		// A todo can be shared with both the user's ID and their email;
+		// list it once with the higher role
+		if i, ok := seen[share.TodoID]; ok {
+			if share.Role.Outranks(todos[i].Role) {
+				todos[i].Role = share.Role
+			}
+			continue
+		}
+
+		todo, err := h.todos.Get(ctx, repository.Personal(share.OwnerID), share.TodoID)
+		if errors.Is(err, repository.ErrNotFound) {
+			// The owner deleted it along with their account
+			continue
+		}
+		if err != nil {
+			respondStorageError(c, err, "Failed to fetch shared todos")
+			return
+		}
+		if html {
+			todo.DescriptionHTML = markdown.Render(todo.Description)
+		}
+		seen[share.TodoID] = len(todos)
+		todos = append(todos, sharedTodo{Todo: *todo, Role: share.Role, SharedBy: share.OwnerID})
+	}
+
+	base := apiBase(c)
+	for i := range todos {
+		todos[i].Links = todoLinks(base, &todos[i].Todo, todos[i].Role)
+	}
+	c.JSON(http.StatusOK, gin.H{"todos": todos, "_links": collectionLinks(c)})
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/cache"
+	"todo-api/duedate"
+	"todo-api/models"
+	"todo-api/repository"
+	"todo-api/slack"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// slackCodeTTL is how long a link code can be redeemed
+	slackCodeTTL = 10 * time.Minute
+	// slackCodeAlphabet leaves out letters and digits that are easily
+	// confused when typed
+	slackCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	// slackListLimit is how many todos /todo list shows
+	slackListLimit = 10
+)
+
+// SlackHandler links users to their Slack accounts and answers the /todo
+// slash command. Link codes live in the same cache as undo records.
+type SlackHandler struct {
+	links   repository.SlackLinkRepository
+	codes   cache.Cache
+	todos   *TodoHandler
+	timeout time.Duration
+}
+
+// NewSlackHandler creates a SlackHandler that creates todos through todos,
+// so quotas and events apply as they do in the API
+func NewSlackHandler(links repository.SlackLinkRepository, codes cache.Cache, todos *TodoHandler, timeout time.Duration) *SlackHandler {
+	return &SlackHandler{links: links, codes: codes, todos: todos, timeout: timeout}
+}
+
+// slackCodeKey is the cache key of the user a link code belongs to
+func slackCodeKey(code string) string {
+	return "slack-link:" + hashLinkToken(code)
+}
+
+// newSlackCode returns a random code short enough to type into Slack
+func newSlackCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = slackCodeAlphabet[int(b[i])%len(slackCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// GetSlackLink handles GET /integrations/slack
+func (h *SlackHandler) GetSlackLink(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	link, err := h.links.Get(ctx, c.GetString("user_id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodeSlackLinkNotFound, "Slack isn't linked; create a link code and run the command it comes with in Slack")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch Slack link")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"link": link})
+}
+
+// CreateSlackLinkCode handles POST /integrations/slack/link-code. The code
+// is redeemed by running /todo link CODE in Slack.
+func (h *SlackHandler) CreateSlackLinkCode(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	code, err := newSlackCode()
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInternal, "Failed to create link code")
+		return
+	}
+	if err := h.codes.Set(ctx, slackCodeKey(code), []byte(c.GetString("user_id")), slackCodeTTL); err != nil {
+		respondStorageError(c, err, "Failed to create link code")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"code":       code,
+		"command":    "/todo link " + code,
+		"expires_at": time.Now().Add(slackCodeTTL),
+	})
+}
+
+// DeleteSlackLink handles DELETE /integrations/slack
+func (h *SlackHandler) DeleteSlackLink(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	userID := c.GetString("user_id")
+	if _, err := h.links.Get(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierrors.Respond(c, apierrors.CodeSlackLinkNotFound, "Slack isn't linked")
+			return
+		}
+		respondStorageError(c, err, "Failed to unlink Slack")
+		return
+	}
+	if err := h.links.Delete(ctx, userID); err != nil {
+		respondStorageError(c, err, "Failed to unlink Slack")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Slack unlinked successfully"})
+}
+
+// slackCommand is the part of a slash command request that's used
+type slackCommand struct {
+	command     string
+	teamID      string
+	slackUserID string
+	verb        string
+	args        string
+}
+
+// slackReply is a response to a slash command. Ephemeral replies are only
+// shown to the user who ran the command.
+type slackReply struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// Command handles POST /integrations/slack/commands, which Slack calls when
+// someone runs /todo. It's routed behind middleware.VerifySignature, which
+// checks Slack's signature and turns away replays. Replies are always 200
+// with a message, since Slack shows other statuses as a bare failure.
+func (h *SlackHandler) Command(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidRequest, "Request body must be a form")
+		return
+	}
+	cmd := slackCommand{
+		command:     form.Get("command"),
+		teamID:      form.Get("team_id"),
+		slackUserID: form.Get("user_id"),
+	}
+	if cmd.command == "" {
+		cmd.command = "/todo"
+	}
+	cmd.verb, cmd.args, _ = strings.Cut(strings.TrimSpace(form.Get("text")), " ")
+	cmd.verb = strings.ToLower(cmd.verb)
+	cmd.args = strings.TrimSpace(cmd.args)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	text, err := h.run(ctx, &cmd)
+	if err != nil {
+		log.Printf("Slack command %q from %s/%s failed: %v", cmd.verb, cmd.teamID, cmd.slackUserID, err)
+		text = "Something went wrong; please try again."
+	}
+	c.JSON(http.StatusOK, slackReply{ResponseType: "ephemeral", Text: text})
+}
+
+// run carries out a command and returns the reply
+func (h *SlackHandler) run(ctx context.Context, cmd *slackCommand) (string, error) {
+	if cmd.verb == "link" {
+		return h.link(ctx, cmd)
+	}
+	if cmd.verb == "" || cmd.verb == "help" {
+		return slackHelp(cmd.command), nil
+	}
+
+	link, err := h.links.GetBySlackUser(ctx, cmd.teamID, cmd.slackUserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Sprintf("Your Slack account isn't linked yet. Create a link code in the app, then run `%s link CODE`.", cmd.command), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch cmd.verb {
+	case "add":
+		return h.add(ctx, cmd, link.UserID)
+	case "list":
+		return h.list(ctx, link.UserID)
+	case "unlink":
+		if err := h.links.Delete(ctx, link.UserID); err != nil {
+			return "", err
+		}
+		return "Unlinked. Slash commands no longer reach your todos.", nil
+	default:
+		return fmt.Sprintf("Unknown command %q.\n%s", slack.Escape(cmd.verb), slackHelp(cmd.command)), nil
+	}
+}
+
+// slackHelp lists the commands
+func slackHelp(command string) string {
+	return strings.Join([]string{
+		"`" + command + " add Buy milk tomorrow` adds a todo; a due date at the end is picked up",
+		"`" + command + " list` shows your most urgent open todos",
+		"`" + command + " link CODE` links your Slack account with a code from the app",
+		"`" + command + " unlink` unlinks it",
+	}, "\n")
+}
+
+// link redeems a link code, connecting the Slack account to the code's user
+func (h *SlackHandler) link(ctx context.Context, cmd *slackCommand) (string, error) {
+	code := strings.ToUpper(cmd.args)
+	if code == "" {
+		return fmt.Sprintf("Create a link code in the app, then run `%s link CODE`.", cmd.command), nil
+	}
+	key := slackCodeKey(code)
+	userID, err := h.codes.Get(ctx, key)
+	if errors.Is(err, cache.ErrMiss) {
+		return "That link code is wrong or has expired; create a new one in the app.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	// Codes are single use
+	if err := h.codes.Delete(ctx, key); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	link := &models.SlackLink{
+		UserID:      string(userID),
+		TeamID:      cmd.teamID,
+		SlackUserID: cmd.slackUserID,
+		CreatedAt:   now,
+		// Only todos that become overdue from now on are posted
+		OverdueCheckedAt: now,
+	}
+	if err := h.links.Save(ctx, link); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Linked! Try `%s add Buy milk tomorrow`.", cmd.command), nil
+}
+
+// add creates a personal todo from the command's text, reading a due date
+// at its end in the user's time zone
+func (h *SlackHandler) add(ctx context.Context, cmd *slackCommand, userID string) (string, error) {
+	req := models.CreateTodoRequest{Title: cmd.args}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		return fmt.Sprintf("Can't add that todo: %s %s.", errs[0].Field, errs[0].Message), nil
+	}
+
+	settings, err := h.todos.settings.users.GetSettings(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	loc := settings.Location()
+	preferences := settings.CurrentPreferences()
+	now := time.Now()
+	todo := models.Todo{
+		UserID:    userID,
+		Title:     req.Title,
+		Priority:  preferences.DefaultPriority,
+		Tags:      h.todos.quotas.defaultTags(preferences.DefaultTags),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if due, title, ok := duedate.Find(todo.Title, now.In(loc), preferences.WeekStart()); ok {
+		due = due.UTC()
+		todo.Title = title
+		todo.DueDate = &due
+	}
+
+	scope := repository.Personal(userID)
+	u, err := h.todos.quotas.todoUsage(ctx, scope)
+	if err != nil {
+		return "", err
+	}
+	if u.Limit != nil && u.Used >= int64(*u.Limit) {
+		return todoQuotaProblem(scope, *u.Limit).Detail + ".", nil
+	}
+	if err := h.todos.createTodo(ctx, &todo); err != nil {
+		return "", err
+	}
+
+	return "Added " + slackTodoLine(&todo, loc), nil
+}
+
+// list shows the user's most urgent open todos
+func (h *SlackHandler) list(ctx context.Context, userID string) (string, error) {
+	settings, err := h.todos.settings.users.GetSettings(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	todos, err := h.todos.todos.List(ctx, repository.Personal(userID))
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	open := make([]models.Todo, 0, len(todos))
+	for _, todo := range todos {
+		if !todo.Completed && !todo.Snoozed(now) {
+			open = append(open, todo)
+		}
+	}
+	if len(open) == 0 {
+		return "You have no open todos.", nil
+	}
+	sortByUrgency(open)
+
+	lines := []string{"Your open todos:"}
+	for i := range open[:min(len(open), slackListLimit)] {
+		lines = append(lines, "• "+slackTodoLine(&open[i], settings.Location()))
+	}
+	if more := len(open) - slackListLimit; more > 0 {
+		lines = append(lines, fmt.Sprintf("…and %d more", more))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// slackTodoLine formats a todo's title and due date for a reply
+func slackTodoLine(todo *models.Todo, loc *time.Location) string {
+	line := "*" + slack.Escape(todo.Title) + "*"
+	if todo.DueDate != nil {
+		line += " (due " + todo.DueDate.In(loc).Format("Mon 2 Jan") + ")"
+	}
+	return line
+}
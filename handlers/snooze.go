@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SnoozeTodo hides a todo from listings and due views for a while, or until
+// a given time. Snoozing it again replaces the earlier snooze.
+func (h *TodoHandler) SnoozeTodo(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	var req models.SnoozeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	now := time.Now()
+	var until time.Time
+	if req.Duration != "" {
+		d, _ := models.ParseSnoozeDuration(req.Duration)
+		until = now.Add(d)
+	} else {
+		loc, ok := h.settings.location(ctx, c)
+		if !ok {
+			return
+		}
+		until, _ = models.ParseDueDate(req.Until, loc)
+		if !until.After(now) || until.Sub(now) > models.MaxSnooze {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "until", Message: "must be in the future and at most 365 days away"}})
+			return
+		}
+	}
+	until = until.UTC()
+
+	h.modifyTodo(ctx, c, objectID, "Failed to snooze todo", func(todo *models.Todo) {
+		todo.SnoozedUntil = &until
+	})
+}
+
+// UnsnoozeTodo brings a snoozed todo back right away
+func (h *TodoHandler) UnsnoozeTodo(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	h.modifyTodo(ctx, c, objectID, "Failed to unsnooze todo", func(todo *models.Todo) {
+		todo.SnoozedUntil = nil
+	})
+}
+
+// includeSnoozed reports whether the request asked for snoozed todos to be
+// listed with ?snoozed=true. Any value but true or false gets a validation
+// error and ok is false.
+func includeSnoozed(c *gin.Context) (include, ok bool) {
+	switch c.Query("snoozed") {
+	case "", "false":
+		return false, true
+	case "true":
+		return true, true
+	}
+	apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "snoozed", Message: `must be "true" or "false"`}})
+	return false, false
+}
+
+// withoutSnoozed drops the todos that are snoozed at now, reusing the
+// slice's storage
+func withoutSnoozed(todos []models.Todo, now time.Time) []models.Todo {
+	awake := todos[:0]
+	for _, todo := range todos {
+		if !todo.Snoozed(now) {
+			awake = append(awake, todo)
+		}
+	}
+	return awake
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"todo-api/stale"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StaleHandler lists the todos nobody has changed for a while, so users can
+// complete, reschedule or delete them
+type StaleHandler struct {
+	todos *TodoHandler
+	after time.Duration
+}
+
+// NewStaleHandler creates a StaleHandler for todos unchanged for after,
+// reading them through todos
+func NewStaleHandler(todos *TodoHandler, after time.Duration) *StaleHandler {
+	return &StaleHandler{todos: todos, after: after}
+}
+
+// GetStale returns the open todos that haven't changed since the stale
+// period began, least recently changed first. Snoozed todos are left out
+// unless ?snoozed=true.
+func (h *StaleHandler) GetStale(c *gin.Context) {
+	fields, ok := fieldSelection(c)
+	if !ok {
+		return
+	}
+	todos, _, ok := h.todos.openTodos(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	c.JSON(http.StatusOK, gin.H{
+		"since":  now.Add(-h.after).UTC(),
+		"todos":  selectFields(stale.Filter(todos, now, h.after), fields),
+		"_links": collectionLinks(c),
+	})
+}
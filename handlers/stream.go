@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/middleware"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Media types GET /todos streams the list in rather than responding with
+// JSON
+const (
+	csvContentType    = "text/csv"
+	ndjsonContentType = "application/x-ndjson"
+)
+
+// csvColumns are the todo fields a CSV list has when ?fields= isn't sent,
+// in order
+var csvColumns = []string{
+	"id", "title", "description", "completed", "completed_at", "due_date", "priority", "tags", "pinned",
+	"workspace_id", "assignee_id", "estimated_minutes", "actual_minutes", "created_at", "updated_at",
+}
+
+// listFormat reads the media type GET /todos responds with from an Accept
+// header: CSV or NDJSON, which are streamed, or JSON for anything else. As
+// with JSON:API the first type listed that the endpoint knows wins; quality
+// values aren't weighed.
+func listFormat(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		switch mediaType = strings.ToLower(strings.TrimSpace(mediaType)); mediaType {
+		case csvContentType, ndjsonContentType:
+			return mediaType
+		case gin.MIMEJSON, middleware.JSONAPIContentType, "*/*":
+			return gin.MIMEJSON
+		}
+	}
+	return gin.MIMEJSON
+}
+
+// streamTodos sends the scope's todos that match the request's filters as
+// CSV or newline-delimited JSON, reading and sending exportPageSize of
+// them at a time so a big list is never held in memory at once. Todos come
+// oldest first; pinned todos aren't moved up and searches aren't ranked.
+// Streams aren't bound by the request's deadline, only each page is. Once
+// the first page is sent the status can't change, so a storage error after
+// that ends an NDJSON stream with an error line and drops a CSV one's
+// connection, so the client doesn't take a cut-short list for the whole.
+func (h *TodoHandler) streamTodos(c *gin.Context, scope repository.Scope, format string, fields []string, html bool) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	filters, ok := h.readFilters(ctx, c, scope)
+	cancel()
+	if !ok {
+		return
+	}
+	if format == csvContentType && fields == nil {
+		fields = csvColumns
+	}
+
+	started := false
+	rows := csv.NewWriter(c.Writer)
+	enc := json.NewEncoder(c.Writer)
+	start := func() {
+		started = true
+		if format == ndjsonContentType {
+			c.Header("Content-Type", ndjsonContentType)
+			c.Status(http.StatusOK)
+			return
+		}
+		c.Header("Content-Type", csvContentType+"; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="todos-%s.csv"`, time.Now().UTC().Format(time.DateOnly)))
+		c.Status(http.StatusOK)
+		rows.Write(fields)
+	}
+	write := func(todo *models.Todo) error {
+		switch {
+		case format == csvContentType:
+			selected := selectTodoFields(todo, fields)
+			row := make([]string, len(fields))
+			for i, name := range fields {
+				row[i] = csvValue(selected[name])
+			}
+			return rows.Write(row)
+		case fields == nil:
+			return enc.Encode(todo)
+		default:
+			return enc.Encode(selectTodoFields(todo, fields))
+		}
+	}
+	// fail responds with an error if nothing was sent yet; otherwise it
+	// marks the stream as cut short
+	fail := func(err error) {
+		if !started {
+			respondStorageError(c, err, "Failed to fetch todos")
+			return
+		}
+		log.Printf("Streaming todos failed: %v", err)
+		if format == ndjsonContentType {
+			enc.Encode(struct {
+				Error *apierrors.Problem `json:"error"`
+			}{apierrors.Localize(c, apierrors.New(apierrors.CodeInternal, "Failed to fetch todos"))})
+			c.Writer.Flush()
+			return
+		}
+		middleware.DropConnection(c)
+	}
+
+	// blockers remembers whether the blockers looked up outside the page
+	// being sent are open
+	blockers := map[primitive.ObjectID]bool{}
+	var after primitive.ObjectID
+	for {
+		ctx, cancel := streamContext(c, h.timeout)
+		page, err := h.todos.ListPage(ctx, scope, after, exportPageSize)
+		if err == nil {
+			err = h.markPageBlocked(ctx, scope, page, blockers)
+		}
+		if err != nil {
+			cancel()
+			fail(err)
+			return
+		}
+		full := len(page) == exportPageSize
+		if full {
+			after = page[len(page)-1].ID
+		}
+		// Filtering reorders the page in its own storage
+		todos, ok := h.filterTodos(ctx, c, page, filters)
+		cancel()
+		if !ok {
+			return
+		}
+		slices.SortFunc(todos, func(a, b models.Todo) int {
+			return bytes.Compare(a.ID[:], b.ID[:])
+		})
+		if html {
+			renderDescriptions(todos)
+		}
+		linkTodos(c, todos)
+
+		if !started {
+			start()
+		}
+		for i := range todos {
+			if err := write(&todos[i]); err != nil {
+				// The client has gone
+				return
+			}
+		}
+		rows.Flush()
+		c.Writer.Flush()
+		if !full {
+			return
+		}
+	}
+}
+
+// streamContext returns the context a page of a streamed response is read
+// with. It has a timeout of its own instead of the request's deadline, so
+// a long stream isn't cut off by REQUEST_TIMEOUT, but is still cancelled
+// when the client goes away.
+func streamContext(c *gin.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	request := c.Request.Context()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(request), timeout)
+	stop := context.AfterFunc(request, func() {
+		if errors.Is(request.Err(), context.Canceled) {
+			cancel()
+		}
+	})
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// markPageBlocked sets Blocked on a page of todos as markBlocked does for
+// a whole list. Blockers that aren't on the page are fetched, and whether
+// they're open is kept in blockers for the pages after.
+func (h *TodoHandler) markPageBlocked(ctx context.Context, scope repository.Scope, todos []models.Todo, blockers map[primitive.ObjectID]bool) error {
+	open := make(map[primitive.ObjectID]bool, len(todos))
+	for _, todo := range todos {
+		open[todo.ID] = !todo.Completed
+	}
+	for i := range todos {
+		for _, id := range todos[i].BlockedBy {
+			isOpen, onPage := open[id]
+			if !onPage {
+				var known bool
+				if isOpen, known = blockers[id]; !known {
+					blocker, err := h.todos.Get(ctx, scope, id)
+					switch {
+					case errors.Is(err, repository.ErrNotFound):
+					case err != nil:
+						return err
+					default:
+						isOpen = !blocker.Completed
+					}
+					blockers[id] = isOpen
+				}
+			}
+			if isOpen {
+				todos[i].Blocked = true
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// csvValue formats a todo field as a CSV cell: times in RFC 3339 UTC,
+// tags joined with ";" and anything else structured as JSON. Cells a
+// spreadsheet would take for a formula are prefixed with a quote.
+func csvValue(value any) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		s = v.UTC().Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		s = v.UTC().Format(time.RFC3339)
+	case primitive.ObjectID:
+		s = v.Hex()
+	case *primitive.ObjectID:
+		if v == nil {
+			return ""
+		}
+		s = v.Hex()
+	case []string:
+		s = strings.Join(v, ";")
+	default:
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.String:
+			s = rv.String()
+		case reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+			s = fmt.Sprint(value)
+		case reflect.Pointer, reflect.Slice, reflect.Map:
+			if rv.IsNil() {
+				return ""
+			}
+			fallthrough
+		default:
+			out, _ := json.Marshal(value)
+			s = string(out)
+		}
+	}
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		s = "'" + s
+	}
+	return s
+}
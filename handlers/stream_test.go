@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo-api/middleware"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStreamTodos(t *testing.T) {
+	// streamed sends GET /todos as NDJSON and returns its lines
+	streamed := func(t *testing.T, todos *fakeTodos, ctx context.Context) []map[string]any {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/todos", nil).WithContext(ctx)
+		req.Header.Set("Accept", ndjsonContentType)
+		rec := httptest.NewRecorder()
+		newTodoRouter(todos).ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+		}
+		var lines []map[string]any
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var line map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatalf("decoding %q: %v", scanner.Text(), err)
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+	// pages returns more todos than fit on one page, the last of them
+	// blocked by a todo that isn't stored, so sending the second page looks
+	// the blocker up
+	pages := func() []models.Todo {
+		todos := make([]models.Todo, exportPageSize+1)
+		for i := range todos {
+			todos[i] = testTodo("Todo")
+		}
+		todos[exportPageSize].BlockedBy = []primitive.ObjectID{primitive.NewObjectID()}
+		return todos
+	}
+
+	t.Run("outlives the request deadline", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		lines := streamed(t, newFakeTodos(pages()...), ctx)
+		if len(lines) != exportPageSize+1 {
+			t.Fatalf("streamed %d todos, want %d", len(lines), exportPageSize+1)
+		}
+	})
+
+	t.Run("NDJSON cut short ends with an error line", func(t *testing.T) {
+		todos := newFakeTodos(pages()...)
+		todos.fail["Get"] = errStorage
+		lines := streamed(t, todos, context.Background())
+		if len(lines) != exportPageSize+1 {
+			t.Fatalf("streamed %d lines, want the first page and an error", len(lines))
+		}
+		problem, _ := lines[exportPageSize]["error"].(map[string]any)
+		if problem["code"] != "INTERNAL_ERROR" {
+			t.Errorf("last line = %v, want an INTERNAL_ERROR problem", lines[exportPageSize])
+		}
+	})
+
+	t.Run("CSV cut short drops the connection", func(t *testing.T) {
+		todos := newFakeTodos(pages()...)
+		todos.fail["Get"] = errStorage
+		router := gin.New()
+		router.Use(middleware.RecoveryMiddleware(), func(c *gin.Context) {
+			c.Set("user_id", testUser)
+		})
+		router.GET("/todos", newTestTodoHandler(todos).GetTodos)
+		req := httptest.NewRequest(http.MethodGet, "/todos", nil)
+		req.Header.Set("Accept", csvContentType)
+		rec := httptest.NewRecorder()
+		defer func() {
+			if recovered := recover(); recovered != http.ErrAbortHandler {
+				t.Fatalf("recovered %v, want http.ErrAbortHandler", recovered)
+			}
+			if rows := bytes.Count(rec.Body.Bytes(), []byte("\n")); rows != exportPageSize+1 {
+				t.Errorf("sent %d rows, want the header and the first page", rows)
+			}
+		}()
+		router.ServeHTTP(rec, req)
+	})
+}
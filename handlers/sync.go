@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// syncOverlap is how far before a sync its token points. Writes that were
+// in flight during the sync, or made on an instance whose clock is a little
+// behind, are then picked up by the next one, at the cost of clients
+// sometimes getting a change twice.
+const syncOverlap = 5 * time.Second
+
+// SyncLog records deleted todos for clients syncing changes. Records are
+// kept for window, which is how far back a sync token can reach.
+type SyncLog struct {
+	tombstones repository.TombstoneRepository
+	window     time.Duration
+}
+
+// NewSyncLog creates a SyncLog keeping tombstones for window
+func NewSyncLog(tombstones repository.TombstoneRepository, window time.Duration) *SyncLog {
+	return &SyncLog{tombstones: tombstones, window: window}
+}
+
+// record saves a tombstone for a todo about to be deleted
+func (s *SyncLog) record(ctx context.Context, todo *models.Todo) error {
+	return s.tombstones.Save(ctx, &models.Tombstone{
+		TodoID:      todo.ID,
+		UserID:      todo.UserID,
+		WorkspaceID: todo.WorkspaceID,
+		DeletedAt:   time.Now(),
+	})
+}
+
+// syncToken encodes the time a sync covers changes up to. Clients treat it
+// as opaque.
+func syncToken(t time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(t.UnixMilli(), 10)))
+}
+
+// parseSyncToken decodes a token made by syncToken
+func parseSyncToken(token string) (time.Time, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// Sync returns what changed in the request's todos since ?since=, the token
+// of an earlier sync: the todos created or edited since, and tombstones of
+// those deleted. Without since it returns every todo. The response's
+// sync_token is what to pass as since next time.
+func (h *TodoHandler) Sync(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := authz.CanRead(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to sync todos")
+		return
+	}
+
+	var since *time.Time
+	if v := c.Query("since"); v != "" {
+		t, ok := parseSyncToken(v)
+		if !ok {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "since", Message: "is not a sync token"}})
+			return
+		}
+		if time.Since(t) > h.sync.window {
+			apierrors.Respond(c, apierrors.CodeSyncTokenExpired, "Deletes this far back are no longer known; sync again without since")
+			return
+		}
+		since = &t
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	// Taken before reading, so whatever changes during the read is included
+	// next time
+	next := time.Now().Add(-syncOverlap)
+	scope := todoScope(c, userID.(string))
+	todos, err := h.todos.List(ctx, scope)
+	if err != nil {
+		respondStorageError(c, err, "Failed to sync todos")
+		return
+	}
+	markBlocked(todos)
+
+	changed := []models.Todo{}
+	live := make(map[primitive.ObjectID]bool, len(todos))
+	for _, todo := range todos {
+		live[todo.ID] = true
+		if since == nil || !todo.UpdatedAt.Before(*since) {
+			changed = append(changed, todo)
+		}
+	}
+	deleted := []models.Tombstone{}
+	if since != nil {
+		tombstones, err := h.sync.tombstones.ListSince(ctx, scope, *since)
+		if err != nil {
+			respondStorageError(c, err, "Failed to sync todos")
+			return
+		}
+		// A todo that's back, through an undo, or whose delete failed part
+		// way is listed as changed instead
+		for _, tombstone := range tombstones {
+			if !live[tombstone.TodoID] {
+				deleted = append(deleted, tombstone)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"todos":      changed,
+		"deleted":    deleted,
+		"sync_token": syncToken(next),
+		"full":       since == nil,
+	})
+}
+
+// syncResult reports how one change of a push went. Conflicts lists the
+// fields both the client and the server edited since the change's base. On
+// a conflict Todo is the server's version and Client the todo as the change
+// would make it, or nil for a delete.
+type syncResult struct {
+	ID        string             `json:"id"`
+	Op        string             `json:"op"`
+	Status    string             `json:"status"`
+	Todo      *models.Todo       `json:"todo,omitempty"`
+	Client    *models.Todo       `json:"client,omitempty"`
+	Conflicts []string           `json:"conflicts,omitempty"`
+	Error     *apierrors.Problem `json:"error,omitempty"`
+}
+
+// Statuses of a pushed change
+const (
+	syncApplied  = "applied"
+	syncRejected = "rejected"
+	syncConflict = "conflict"
+)
+
+// syncPush holds what's looked up once per push and shared by its changes
+type syncPush struct {
+	userID     string
+	scope      repository.Scope
+	onConflict string
+	loc        *time.Location
+	fields     []models.CustomField
+	// enforce is the user's enforce_blockers setting
+	enforce bool
+	// limit is the scope's todo cap, and room how many more todos it may
+	// take; room is nil when the scope is uncapped
+	limit int
+	room  *int64
+}
+
+// PushSync applies the changes an offline client made, in order, and
+// reports how each one went. A change that's invalid, or no longer
+// possible, is rejected without affecting the others, and one to a todo
+// edited on the server since the client synced it is resolved as the push's
+// on_conflict says. Pushes can be retried: a todo that already exists isn't
+// created again, and deleting one that's gone succeeds.
+func (h *TodoHandler) PushSync(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := authz.CanWrite(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to sync changes")
+		return
+	}
+
+	var req models.SyncPushRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	push := &syncPush{userID: userID.(string), scope: todoScope(c, userID.(string)), onConflict: req.OnConflict}
+	var ok bool
+	if push.loc, ok = h.settings.location(ctx, c); !ok {
+		return
+	}
+	if push.enforce, ok = h.settings.enforceBlockers(ctx, c); !ok {
+		return
+	}
+	var err error
+	if push.fields, err = h.fields.List(ctx, push.scope); err != nil {
+		respondStorageError(c, err, "Failed to sync changes")
+		return
+	}
+	u, err := h.quotas.todoUsage(ctx, push.scope)
+	if err != nil {
+		respondStorageError(c, err, "Failed to sync changes")
+		return
+	}
+	if u.Limit != nil {
+		room := int64(*u.Limit) - u.Used
+		push.limit, push.room = *u.Limit, &room
+	}
+
+	results := make([]syncResult, len(req.Changes))
+	for i := range req.Changes {
+		change := &req.Changes[i]
+		result, problem, err := h.applyChange(ctx, c, push, change)
+		if err != nil {
+			// Changes so far stay applied; retrying the push is safe
+			respondStorageError(c, err, "Failed to sync changes")
+			return
+		}
+		if problem != nil {
+			result = syncResult{Status: syncRejected, Error: apierrors.Localize(c, problem)}
+		}
+		result.ID, result.Op = change.ID, change.Op
+		results[i] = result
+	}
+
+	response := gin.H{"results": results}
+	if push.room != nil {
+		after := newUsage(int64(push.limit)-*push.room, push.limit)
+		if warnings := h.quotas.todoWarnings(c, push.scope, after); warnings != nil {
+			response["warnings"] = warnings
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// applyChange applies one pushed change and reports the outcome. A change
+// that can't be applied is reported as a problem; err is only set when
+// storage fails.
+func (h *TodoHandler) applyChange(ctx context.Context, c *gin.Context, push *syncPush, change *models.SyncChange) (syncResult, *apierrors.Problem, error) {
+	id, _ := primitive.ObjectIDFromHex(change.ID)
+	todo, err := h.todos.Get(ctx, push.scope, id)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return syncResult{}, nil, err
+	}
+
+	if change.Op == models.SyncDelete {
+		if todo == nil {
+			return syncResult{Status: syncApplied}, nil, nil
+		}
+		return h.deleteFromChange(ctx, push, todo, change.Base)
+	}
+
+	errs := change.ValidateTodo()
+	if change.Todo.Tags != nil {
+		errs = append(errs, h.quotas.todoErrors(*change.Todo.Tags, 0)...)
+	}
+	if len(errs) > 0 {
+		return syncResult{}, apierrors.NewValidation(errs), nil
+	}
+	if change.Op == models.SyncCreate {
+		if todo != nil {
+			// Created by an earlier attempt at this push
+			return syncResult{Status: syncApplied, Todo: todo}, nil, nil
+		}
+		todo, problem, err := h.createFromChange(ctx, push, id, change.Todo)
+		return syncResult{Status: syncApplied, Todo: todo}, problem, err
+	}
+
+	// A todo changed between being read and written is read again, so the
+	// change is checked and resolved against the edit made meanwhile
+	for attempt := 1; ; attempt++ {
+		if todo == nil {
+			return syncResult{}, apierrors.New(apierrors.CodeTodoNotFound, "Todo not found"), nil
+		}
+		result, problem, err := h.updateFromChange(ctx, c, push, todo, change)
+		if !errors.Is(err, repository.ErrConflict) {
+			return result, problem, err
+		}
+		if attempt == maxWriteAttempts {
+			return syncResult{}, apierrors.New(apierrors.CodeTodoConflict, "The todo kept changing while it was being saved; try again"), nil
+		}
+		todo, err = h.todos.Get(ctx, push.scope, id)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return syncResult{}, nil, err
+		}
+	}
+}
+
+// editedSince returns the fields of todo edited on the server since base, as
+// DiffTodos names them, from its revisions. all is set when the revisions
+// kept don't reach back to base, so any field may have been edited.
+func (h *TodoHandler) editedSince(ctx context.Context, todo *models.Todo, base time.Time) (edited map[string]bool, all bool, err error) {
+	// Storage may keep times to the millisecond only, so base is compared at
+	// that precision
+	base = base.Truncate(time.Millisecond)
+	if !todo.UpdatedAt.Truncate(time.Millisecond).After(base) {
+		return nil, false, nil
+	}
+	revisions, err := h.revisions.List(ctx, todo.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(revisions) > 0 && revisions[0].Number > 1 && revisions[0].CreatedAt.Truncate(time.Millisecond).After(base) {
+		return nil, true, nil
+	}
+	edited = map[string]bool{}
+	for _, revision := range revisions {
+		if revision.Number > 1 && revision.CreatedAt.Truncate(time.Millisecond).After(base) {
+			for _, change := range revision.Changes {
+				edited[change.Field] = true
+			}
+		}
+	}
+	return edited, false, nil
+}
+
+// deleteFromChange deletes a todo, unless it was edited on the server since
+// base and the push doesn't let the client's write win
+func (h *TodoHandler) deleteFromChange(ctx context.Context, push *syncPush, todo *models.Todo, base *time.Time) (syncResult, *apierrors.Problem, error) {
+	if base != nil && push.onConflict != models.ConflictLastWriteWins {
+		edited, all, err := h.editedSince(ctx, todo, *base)
+		if err != nil {
+			return syncResult{}, nil, err
+		}
+		if all || len(edited) > 0 {
+			return syncResult{Status: syncConflict, Todo: todo, Conflicts: slices.Sorted(maps.Keys(edited))}, nil, nil
+		}
+	}
+
+	_, err := h.removeTodo(ctx, todo)
+	if errors.Is(err, repository.ErrNotFound) {
+		err = nil
+	}
+	return syncResult{Status: syncApplied}, nil, err
+}
+
+// createFromChange creates a todo with the client's ID
+func (h *TodoHandler) createFromChange(ctx context.Context, push *syncPush, id primitive.ObjectID, req *models.UpdateTodoRequest) (*models.Todo, *apierrors.Problem, error) {
+	if push.room != nil && *push.room <= 0 {
+		return nil, todoQuotaProblem(push.scope, push.limit), nil
+	}
+	now := time.Now()
+	todo := &models.Todo{ID: id, UserID: push.userID, CreatedAt: now, UpdatedAt: now}
+	if push.scope.IsWorkspace() {
+		todo.WorkspaceID = &push.scope.WorkspaceID
+	}
+	applyUpdate(todo, req, push.loc)
+	if req.CustomFields != nil {
+		var errs []apierrors.FieldError
+		if todo.CustomFields, errs = mergeCustomFields(push.fields, nil, req.CustomFields); len(errs) > 0 {
+			return nil, apierrors.NewValidation(errs), nil
+		}
+	}
+
+	err := h.createTodo(ctx, todo)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return nil, apierrors.New(apierrors.CodeInvalidID, "This ID is taken; create the todo with a new one"), nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if push.room != nil {
+		*push.room--
+	}
+	return todo, nil, nil
+}
+
+// updateFromChange applies the fields of a change to an existing todo,
+// resolving fields also edited on the server since the change's base. The
+// todo is only written if it wasn't changed since it was read; if it was,
+// ErrConflict is returned for applyChange to read it again.
+func (h *TodoHandler) updateFromChange(ctx context.Context, c *gin.Context, push *syncPush, todo *models.Todo, change *models.SyncChange) (syncResult, *apierrors.Problem, error) {
+	server := *todo
+	before := models.RevisionSnapshot(todo)
+	req := change.Todo
+	applyUpdate(todo, req, push.loc)
+	if req.CustomFields != nil {
+		var errs []apierrors.FieldError
+		if todo.CustomFields, errs = mergeCustomFields(push.fields, todo.CustomFields, req.CustomFields); len(errs) > 0 {
+			return syncResult{}, apierrors.NewValidation(errs), nil
+		}
+	}
+
+	var conflicts []string
+	if change.Base != nil {
+		edited, all, err := h.editedSince(ctx, &server, *change.Base)
+		if err != nil {
+			return syncResult{}, nil, err
+		}
+		for _, diff := range models.DiffTodos(&before, todo) {
+			if all || edited[diff.Field] {
+				conflicts = append(conflicts, diff.Field)
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		switch push.onConflict {
+		case models.ConflictManual:
+			return syncResult{Status: syncConflict, Todo: &server, Client: todo, Conflicts: conflicts}, nil, nil
+		case models.ConflictMerge:
+			models.CopyFields(todo, &server, conflicts)
+		}
+	}
+
+	if todo.Completed && !server.Completed {
+		if problem := checklistProblem(todo); problem != nil {
+			return syncResult{}, problem, nil
+		}
+		if push.enforce {
+			open, err := h.openBlockers(ctx, todo)
+			if err != nil {
+				return syncResult{}, nil, err
+			}
+			if len(open) > 0 {
+				return syncResult{}, blockedProblem(open), nil
+			}
+		}
+	}
+	if req.ChecklistRule != nil {
+		todo.ApplyChecklistRule(time.Now().UTC())
+	}
+	todo.UpdatedAt = time.Now()
+
+	err := h.saveEdit(ctx, &before, todo)
+	if errors.Is(err, repository.ErrNotFound) {
+		return syncResult{}, apierrors.New(apierrors.CodeTodoNotFound, "Todo not found"), nil
+	}
+	if err != nil {
+		return syncResult{}, nil, err
+	}
+	h.recordRevision(ctx, c.GetString("user_id"), &before, todo)
+	return syncResult{Status: syncApplied, Todo: todo, Conflicts: conflicts}, nil, nil
+}
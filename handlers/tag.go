@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListTags returns the tags used in the request's scope, by name, with how
+// many todos have each
+func (h *TodoHandler) ListTags(c *gin.Context) {
+	if err := authz.CanRead(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to fetch tags")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	todos, err := h.todos.List(ctx, todoScope(c, c.GetString("user_id")))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch tags")
+		return
+	}
+	counts := map[string]int{}
+	for _, todo := range todos {
+		for _, tag := range todo.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]models.TagCount, 0, len(counts))
+	for name, n := range counts {
+		tags = append(tags, models.TagCount{Name: name, Todos: n})
+	}
+	slices.SortFunc(tags, func(a, b models.TagCount) int { return strings.Compare(a.Name, b.Name) })
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// RenameTag handles PUT /tags/:tag, renaming a tag on every todo in the
+// request's scope. Renaming it to a tag that's in use merges the two.
+func (h *TodoHandler) RenameTag(c *gin.Context) {
+	var req models.RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+	h.replaceTag(c, req.Name, "Failed to rename tag")
+}
+
+// MergeTag handles POST /tags/:tag/merge, moving every todo in the request's
+// scope tagged :tag over to the tag it's merged into
+func (h *TodoHandler) MergeTag(c *gin.Context) {
+	var req models.MergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+	h.replaceTag(c, req.Into, "Failed to merge tags")
+}
+
+// DeleteTag handles DELETE /tags/:tag, removing a tag from every todo in the
+// request's scope. Deleting a tag no todo has is a no-op.
+func (h *TodoHandler) DeleteTag(c *gin.Context) {
+	h.replaceTag(c, "", "Failed to delete tag")
+}
+
+// replaceTag puts to in the place of the tag named by :tag on the todos in
+// the request's scope, or removes it when to is empty, in one bulk update,
+// and responds with how many todos changed. Those todos aren't published
+// one by one. A dry run counts the todos that would change instead.
+func (h *TodoHandler) replaceTag(c *gin.Context, to, message string) {
+	from := models.NormalizeTag(c.Param("tag"))
+	if errs := models.ValidateTag("tag", from); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+	if err := authz.CanWrite(scopeRole(c)); err != nil {
+		respondTodoError(c, err, message)
+		return
+	}
+
+	dryRun, ok := isDryRun(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	if dryRun {
+		todos, err := h.todos.List(ctx, todoScope(c, c.GetString("user_id")))
+		if err != nil {
+			respondStorageError(c, err, message)
+			return
+		}
+		todos = withTag(todos, from)
+		response := gin.H{"dry_run": true, "todos_updated": len(todos), "sample_todo_ids": sampleTodoIDs(todos)}
+		if to != "" {
+			response["tag"] = to
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	updated, err := h.todos.ReplaceTag(ctx, todoScope(c, c.GetString("user_id")), from, to, time.Now())
+	if err != nil {
+		respondStorageError(c, err, message)
+		return
+	}
+	response := gin.H{"todos_updated": updated}
+	if to != "" {
+		response["tag"] = to
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// tagFilter reads ?tag=, returning "" when it isn't sent
+func tagFilter(c *gin.Context) string {
+	return models.NormalizeTag(c.Query("tag"))
+}
+
+// withTag keeps the todos tagged with tag, reusing the slice's storage
+func withTag(todos []models.Todo, tag string) []models.Todo {
+	kept := todos[:0]
+	for _, todo := range todos {
+		if todo.HasTag(tag) {
+			kept = append(kept, todo)
+		}
+	}
+	return kept
+}
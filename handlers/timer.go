@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxTimeReportDays is the longest range a time report covers at once
+const maxTimeReportDays = 366
+
+// StartTimer starts timing work on a todo. Starting a running timer leaves
+// it running from when it was first started.
+func (h *TodoHandler) StartTimer(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	h.modifyTodo(ctx, c, objectID, "Failed to start timer", func(todo *models.Todo) {
+		if todo.TimerStartedAt == nil {
+			now := time.Now().UTC()
+			todo.TimerStartedAt = &now
+			todo.TimerStartedBy = c.GetString("user_id")
+		}
+	})
+}
+
+// StopTimer stops a todo's timer, recording the session and adding it to
+// the todo's tracked time. Stopping a timer that isn't running does nothing.
+func (h *TodoHandler) StopTimer(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	h.modifyTodo(ctx, c, objectID, "Failed to stop timer", func(todo *models.Todo) {
+		stopTimer(todo, time.Now().UTC())
+	})
+}
+
+// stopTimer ends the running session, if there is one, at end
+func stopTimer(todo *models.Todo, end time.Time) {
+	if todo.TimerStartedAt == nil {
+		return
+	}
+	entry := models.TimeEntry{UserID: todo.TimerStartedBy, Start: *todo.TimerStartedAt, End: end}
+	if entry.End.Before(entry.Start) {
+		entry.End = entry.Start
+	}
+	todo.TrackedSeconds += int64(entry.End.Sub(entry.Start) / time.Second)
+	todo.TimeEntries = append(todo.TimeEntries, entry)
+	if n := len(todo.TimeEntries); n > models.MaxTimeEntries {
+		todo.TimeEntries = todo.TimeEntries[n-models.MaxTimeEntries:]
+	}
+	todo.TimerStartedAt = nil
+	todo.TimerStartedBy = ""
+}
+
+// timeReportDay is the time tracked on one day
+type timeReportDay struct {
+	Date    string           `json:"date"`
+	Seconds int64            `json:"seconds"`
+	Todos   []timeReportTodo `json:"todos"`
+}
+
+// timeReportTodo is the time tracked on one todo in a day
+type timeReportTodo struct {
+	ID      primitive.ObjectID `json:"id"`
+	Title   string             `json:"title"`
+	Seconds int64              `json:"seconds"`
+}
+
+// GetTimeReport sums the time tracked on the scope's todos for each day from
+// ?from= to ?to=, both included, counting days in the user's time zone.
+// Sessions that cross midnight are split between the days, and running
+// timers count up to now. Every day of the range is listed, with its todos
+// by most time first.
+func (h *TodoHandler) GetTimeReport(c *gin.Context) {
+	if err := authz.CanRead(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to fetch time report")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	loc, ok := h.settings.location(ctx, c)
+	if !ok {
+		return
+	}
+	from, to, ok := dateRange(c, loc, maxTimeReportDays)
+	if !ok {
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+
+	todos, err := h.todos.List(ctx, todoScope(c, c.GetString("user_id")))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch time report")
+		return
+	}
+
+	// Durations are summed per day and todo, then rounded once
+	tracked := map[string]map[int]time.Duration{}
+	now := time.Now()
+	for i, todo := range todos {
+		sessions := todo.TimeEntries
+		if todo.TimerStartedAt != nil {
+			sessions = append(slices.Clone(sessions), models.TimeEntry{Start: *todo.TimerStartedAt, End: now})
+		}
+		for _, s := range sessions {
+			start, stop := maxTime(s.Start, from), minTime(s.End, end)
+			for day := startOfDay(start.In(loc)); day.Before(stop); day = day.AddDate(0, 0, 1) {
+				next := day.AddDate(0, 0, 1)
+				d := minTime(stop, next).Sub(maxTime(start, day))
+				if d <= 0 {
+					continue
+				}
+				date := day.Format(time.DateOnly)
+				if tracked[date] == nil {
+					tracked[date] = map[int]time.Duration{}
+				}
+				tracked[date][i] += d
+			}
+		}
+	}
+
+	var total int64
+	var days []timeReportDay
+	for day := from; day.Before(end); day = day.AddDate(0, 0, 1) {
+		report := timeReportDay{Date: day.Format(time.DateOnly), Todos: []timeReportTodo{}}
+		for i, d := range tracked[report.Date] {
+			seconds := int64(d / time.Second)
+			report.Todos = append(report.Todos, timeReportTodo{ID: todos[i].ID, Title: todos[i].Title, Seconds: seconds})
+			report.Seconds += seconds
+		}
+		sort.Slice(report.Todos, func(a, b int) bool {
+			if report.Todos[a].Seconds != report.Todos[b].Seconds {
+				return report.Todos[a].Seconds > report.Todos[b].Seconds
+			}
+			return report.Todos[a].Title < report.Todos[b].Title
+		})
+		total += report.Seconds
+		days = append(days, report)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":          from.Format(time.DateOnly),
+		"to":            to.Format(time.DateOnly),
+		"total_seconds": total,
+		"days":          days,
+	})
+}
+
+// startOfDay returns midnight at the start of t's day, in t's location
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// minTime returns the earlier of a and b
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// maxTime returns the later of a and b
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
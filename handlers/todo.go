@@ -2,212 +2,1143 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
 	"net/http"
-	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	"todo-api/database"
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/duedate"
+	"todo-api/events"
+	"todo-api/markdown"
 	"todo-api/models"
+	"todo-api/repository"
+	"todo-api/todoquery"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// GetTodos retrieves all todos for the authenticated user
-func GetTodos(c *gin.Context) {
+// TodoHandler serves the todo endpoints
+type TodoHandler struct {
+	todos     repository.TodoRepository
+	shares    repository.ShareRepository
+	links     repository.PublicLinkRepository
+	fields    repository.CustomFieldRepository
+	filters   repository.SavedFilterRepository
+	revisions repository.RevisionRepository
+	quotas    *QuotaHandler
+	settings  *SettingsHandler
+	undo      *UndoLog
+	sync      *SyncLog
+	publisher events.Publisher
+	tx        repository.Transactor
+	timeout   time.Duration
+}
+
+// NewTodoHandler creates a TodoHandler backed by the given repositories.
+// shares lets users change todos shared with them; shares and links are
+// cleaned up when a todo is deleted. fields holds the custom fields values
+// are checked against, filters the saved filters todos can be listed by,
+// and revisions the history of each todo's edits.
+// quotas caps how many todos can be created, and settings holds the time
+// zone dates are read in. undo keeps deleted todos for a while so deletes
+// can be undone, sync records deletes for clients syncing changes, and
+// publisher tells other services about changes, recording them through tx
+// in the same transaction as the change. timeout bounds the storage work
+// done for each request.
+func NewTodoHandler(todos repository.TodoRepository, shares repository.ShareRepository, links repository.PublicLinkRepository, fields repository.CustomFieldRepository, filters repository.SavedFilterRepository, revisions repository.RevisionRepository, quotas *QuotaHandler, settings *SettingsHandler, undo *UndoLog, sync *SyncLog, publisher events.Publisher, tx repository.Transactor, timeout time.Duration) *TodoHandler {
+	return &TodoHandler{todos: todos, shares: shares, links: links, fields: fields, filters: filters, revisions: revisions, quotas: quotas, settings: settings, undo: undo, sync: sync, publisher: publisher, tx: tx, timeout: timeout}
+}
+
+// todoScope returns the todos a request works on: the workspace's when the
+// route is nested under one (RequireMember has checked membership), else the
+// user's personal todos
+func todoScope(c *gin.Context, userID string) repository.Scope {
+	if id, ok := c.Get("workspace_id"); ok {
+		return repository.Workspace(id.(primitive.ObjectID))
+	}
+	return repository.Personal(userID)
+}
+
+// dueDate converts a validated due date from a request, where empty means
+// none, into UTC. Plain dates are read in loc.
+func dueDate(s string, loc *time.Location) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t, err := models.ParseDueDate(s, loc)
+	if err != nil {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// GetTodos retrieves all todos for the authenticated user, as JSON or,
+// going by the Accept header, streamed as CSV or NDJSON
+func (h *TodoHandler) GetTodos(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	collectionName := os.Getenv("COLLECTION_NAME")
-	if collectionName == "" {
-		collectionName = "todos"
+	if err := authz.CanRead(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to fetch todos")
+		return
+	}
+	html, ok := wantsHTML(c)
+	if !ok {
+		return
+	}
+	fields, ok := fieldSelection(c)
+	if !ok {
+		return
+	}
+	page, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	extras, ok := includeExtras(c)
+	if !ok {
+		return
+	}
+	// CSV and NDJSON lists are streamed whole, however long
+	if format := listFormat(c.GetHeader("Accept")); format != gin.MIMEJSON {
+		h.streamTodos(c, todoScope(c, userID.(string)), format, fields, html)
+		return
 	}
 
-	collection := database.GetCollection(collectionName)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
 	defer cancel()
 
-	filter := bson.M{"user_id": userID}
-	cursor, err := collection.Find(ctx, filter)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
+	scope := todoScope(c, userID.(string))
+	filters, ok := h.readFilters(ctx, c, scope)
+	if !ok {
+		return
+	}
+	todos, ok := h.filteredTodos(ctx, c, scope, filters, fields, extras)
+	if !ok {
 		return
 	}
-	defer cursor.Close(ctx)
 
-	var todos []models.Todo
-	if err = cursor.All(ctx, &todos); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode todos"})
+	links := collectionLinks(c)
+	response := gin.H{"_links": links}
+	// Facets count every todo that matched, not just the page
+	if extras.facets {
+		if response["facets"], ok = h.countFacets(ctx, c, scope, filters, todos); !ok {
+			return
+		}
+	}
+	if page != nil {
+		todos = paginate(c, todos, page, links)
+	}
+	if extras.highlights {
+		// q was checked while filtering
+		query, _ := todoquery.Parse(c.Query("q"))
+		highlightTodos(todos, query.Text)
+	}
+	if html {
+		renderDescriptions(todos)
+	}
+	linkTodos(c, todos)
+	embedTodos(c, todos, extras.embeds)
+	response["todos"] = selectFields(todos, fields)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CountTodos returns how many todos GET /todos would list with the same
+// filters, for badge counters that don't need the todos themselves
+func (h *TodoHandler) CountTodos(c *gin.Context) {
+	if err := authz.CanRead(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to count todos")
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	scope := todoScope(c, c.GetString("user_id"))
+	filters, ok := h.readFilters(ctx, c, scope)
+	if !ok {
+		return
+	}
+	if filter, ok := filters.listFilter(time.Now()); ok {
+		n, err := h.todos.CountMatching(ctx, scope, filter)
+		if err != nil {
+			respondStorageError(c, err, "Failed to count todos")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"count": n})
+		return
+	}
+	// Counting selects no fields, so only the ones filters read are loaded
+	todos, ok := h.filteredTodos(ctx, c, scope, filters, []string{}, listExtras{})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(todos)})
+}
+
+// filteredTodos returns the scope's todos that match the filters read from
+// the request, pinned first, never nil. Only what the selected fields and
+// the extras need is loaded when fields isn't nil. It responds with an
+// error and returns false when storage fails.
+func (h *TodoHandler) filteredTodos(ctx context.Context, c *gin.Context, scope repository.Scope, filters *todoFilters, fields []string, extras listExtras) ([]models.Todo, bool) {
+	var todos []models.Todo
+	var err error
+	if stored := storedFields(fields, filters, extras); stored != nil {
+		todos, err = h.todos.ListFields(ctx, scope, stored)
+	} else {
+		todos, err = h.todos.List(ctx, scope)
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch todos")
+		return nil, false
+	}
+
+	// Blockers are looked up before filtering, which may hide them
+	markBlocked(todos)
+	var ok bool
+	if todos, ok = h.filterTodos(ctx, c, todos, filters); !ok {
+		return nil, false
+	}
+
 	// If no todos found, return empty array instead of null
 	if todos == nil {
 		todos = []models.Todo{}
 	}
+	return todos, true
+}
+
+// todoFilters are the filters a todo listing was asked for
+type todoFilters struct {
+	snoozed, archived bool
+	pinned, blocked   *bool
+	query             *todoquery.Query
+	ids               map[primitive.ObjectID]bool
+	completedAfter    *time.Time
+	assignee          *string
+	tag               string
+	customFields      []customFieldFilter
+	saved             *models.SavedFilter
+}
+
+// listFilter returns the filters as one the repository applies itself, or
+// false when a filter reads what only this package works out, such as
+// whether a todo is blocked
+func (f *todoFilters) listFilter(now time.Time) (repository.ListFilter, bool) {
+	if f.blocked != nil || len(f.customFields) > 0 || f.saved != nil || !f.query.Empty() {
+		return repository.ListFilter{}, false
+	}
+	filter := repository.ListFilter{Pinned: f.pinned, CompletedAfter: f.completedAfter, AssigneeID: f.assignee, Tag: f.tag}
+	if f.ids != nil {
+		filter.IDs = slices.Collect(maps.Keys(f.ids))
+	} else {
+		// Todos asked for by ID are counted even while snoozed or archived
+		if !f.snoozed {
+			filter.AwakeAt = now
+		}
+		filter.Unarchived = !f.archived
+	}
+	return filter, true
+}
+
+// readFilters reads the request's filters on the scope's todos. It
+// responds with an error and returns false when a filter is invalid or
+// storage fails.
+func (h *TodoHandler) readFilters(ctx context.Context, c *gin.Context, scope repository.Scope) (*todoFilters, bool) {
+	f := &todoFilters{assignee: assigneeFilter(c), tag: tagFilter(c)}
+	var ok bool
+	if f.snoozed, ok = includeSnoozed(c); !ok {
+		return nil, false
+	}
+	if f.archived, ok = includeArchived(c); !ok {
+		return nil, false
+	}
+	if f.pinned, ok = pinnedFilter(c); !ok {
+		return nil, false
+	}
+	if f.blocked, ok = blockedFilter(c); !ok {
+		return nil, false
+	}
+	if f.query, ok = searchQuery(c); !ok {
+		return nil, false
+	}
+	if f.ids, ok = idsFilter(c); !ok {
+		return nil, false
+	}
+	if f.completedAfter, ok = h.completedAfterFilter(ctx, c); !ok {
+		return nil, false
+	}
+
+	if params := c.QueryMap("cf"); len(params) > 0 {
+		fields, err := h.fields.List(ctx, scope)
+		if err != nil {
+			respondStorageError(c, err, "Failed to fetch todos")
+			return nil, false
+		}
+		if f.customFields, ok = customFieldFilters(c, fields, params); !ok {
+			return nil, false
+		}
+	}
+
+	if f.saved, ok = h.savedFilter(ctx, c, scope); !ok {
+		return nil, false
+	}
+	return f, true
+}
+
+// filterTodos keeps the todos that match f, pinned first, reusing the
+// slice's storage. Their Blocked flags have to be set already.
+func (h *TodoHandler) filterTodos(ctx context.Context, c *gin.Context, todos []models.Todo, f *todoFilters) ([]models.Todo, bool) {
+	if f.blocked != nil {
+		todos = withBlocked(todos, *f.blocked)
+	}
+	if f.ids != nil {
+		todos = withIDs(todos, f.ids)
+	}
+	// Todos asked for by ID are returned even while snoozed or archived
+	if !f.snoozed && f.ids == nil {
+		todos = withoutSnoozed(todos, time.Now())
+	}
+	if !f.archived && f.ids == nil {
+		todos = withoutArchived(todos)
+	}
+	if f.pinned != nil {
+		todos = withPinned(todos, *f.pinned)
+	}
+	if f.completedAfter != nil {
+		todos = withCompletedAfter(todos, *f.completedAfter)
+	}
+	if f.assignee != nil {
+		todos = withAssignee(todos, *f.assignee)
+	}
+	if f.tag != "" {
+		todos = withTag(todos, f.tag)
+	}
+	if len(f.customFields) > 0 {
+		todos = withCustomFields(todos, f.customFields)
+	}
+	pinnedFirst(todos)
+	var ok bool
+	if f.saved != nil {
+		if todos, ok = h.withSavedFilter(ctx, c, todos, &f.saved.Criteria); !ok {
+			return nil, false
+		}
+	}
+	if todos, ok = h.withQuery(ctx, c, todos, f.query); !ok {
+		return nil, false
+	}
+	if !f.query.Text.Empty() {
+		todos = withSearch(todos, f.query.Text)
+	}
+	return todos, true
+}
+
+// Periods the stats endpoint can cover, in weeks
+const (
+	defaultStatsWeeks = 4
+	maxStatsWeeks     = 52
+)
+
+// GetStats summarises the todos for dashboards: counts by status, how many
+// open todos are overdue, and for the last ?weeks= weeks (4 by default) the
+// todos completed each day and how long they took. Days are counted in the
+// user's time zone and every day of the period is listed.
+func (h *TodoHandler) GetStats(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := authz.CanRead(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to fetch stats")
+		return
+	}
+
+	weeks := defaultStatsWeeks
+	if v := c.Query("weeks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxStatsWeeks {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{
+				Field:   "weeks",
+				Message: "must be a number between 1 and " + strconv.Itoa(maxStatsWeeks),
+			}})
+			return
+		}
+		weeks = n
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	loc, ok := h.settings.location(ctx, c)
+	if !ok {
+		return
+	}
+	today := duedate.StartOfDay(time.Now().In(loc))
+	since := today.AddDate(0, 0, 1-7*weeks)
 
-	c.JSON(http.StatusOK, gin.H{"todos": todos})
+	stats, err := h.todos.Stats(ctx, todoScope(c, userID.(string)), since, today, loc)
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch stats")
+		return
+	}
+
+	// The repository only reports days with completions
+	counts := make(map[string]int64, len(stats.CompletedPerDay))
+	for _, d := range stats.CompletedPerDay {
+		counts[d.Date] = d.Count
+	}
+	stats.CompletedPerDay = make([]models.DayCount, 0, 7*weeks)
+	for day := since; !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format(time.DateOnly)
+		stats.CompletedPerDay = append(stats.CompletedPerDay, models.DayCount{Date: date, Count: counts[date]})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
 }
 
 // CreateTodo creates a new todo for the authenticated user
-func CreateTodo(c *gin.Context) {
+func (h *TodoHandler) CreateTodo(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := authz.CanWrite(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to create todo")
 		return
 	}
 
 	var req models.CreateTodoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
+		return
+	}
+	// Normalizing turns tags that weren't sent into none
+	tagsSent := req.Tags != nil
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+	html, ok := wantsHTML(c)
+	if !ok {
 		return
 	}
 
-	collectionName := os.Getenv("COLLECTION_NAME")
-	if collectionName == "" {
-		collectionName = "todos"
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	// Dates are read in the user's time zone, and what the todo is sent
+	// without comes from their preferences
+	settings, ok := h.settings.lookup(ctx, c)
+	if !ok {
+		return
+	}
+	loc := settings.Location()
+	preferences := settings.CurrentPreferences()
+	if req.Priority == "" {
+		req.Priority = preferences.DefaultPriority
+	}
+	if !tagsSent {
+		req.Tags = h.quotas.defaultTags(preferences.DefaultTags)
+	}
+	if errs := h.quotas.todoErrors(req.Tags, len(req.Attachments)); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
 	}
 
 	todo := models.Todo{
-		UserID:      userID.(string),
-		Title:       req.Title,
-		Description: req.Description,
-		Completed:   false,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		UserID:           userID.(string),
+		Title:            req.Title,
+		Description:      req.Description,
+		Completed:        false,
+		DueDate:          dueDate(req.DueDate, loc),
+		Priority:         req.Priority,
+		Pinned:           req.Pinned,
+		Color:            req.Color,
+		EstimatedMinutes: req.EstimatedMinutes,
+		ActualMinutes:    req.ActualMinutes,
+		Location:         req.Location,
+		ChecklistRule:    req.ChecklistRule,
+		Tags:             req.Tags,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+	scope := todoScope(c, todo.UserID)
+	if scope.IsWorkspace() {
+		todo.WorkspaceID = &scope.WorkspaceID
+	}
+	for _, item := range req.Checklist {
+		todo.Checklist = append(todo.Checklist, models.ChecklistItem{ID: primitive.NewObjectID(), Title: item.Title})
+	}
+	// Links are attached without previews, which would hold up creating
+	// the todo; a link sent twice is attached once
+	for _, attachment := range req.Attachments {
+		if !attached(&todo, attachment.URL) {
+			todo.Attachments = append(todo.Attachments, models.Attachment{
+				ID:      primitive.NewObjectID(),
+				URL:     attachment.URL,
+				AddedBy: todo.UserID,
+				AddedAt: todo.CreatedAt.UTC(),
+			})
+		}
 	}
 
-	collection := database.GetCollection(collectionName)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	if len(req.CustomFields) > 0 {
+		fields, err := h.fields.List(ctx, scope)
+		if err != nil {
+			respondStorageError(c, err, "Failed to create todo")
+			return
+		}
+		if todo.CustomFields, ok = customFieldValues(c, fields, nil, req.CustomFields); !ok {
+			return
+		}
+	}
 
-	result, err := collection.InsertOne(ctx, todo)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create todo"})
+	var parsed *parsedDue
+	switch now := time.Now().In(loc); {
+	case req.Due != "":
+		if parsed = resolveDue(c, req.Due, now, preferences.WeekStart()); parsed == nil {
+			return
+		}
+		todo.DueDate = &parsed.DueDate
+	case req.DetectDue && req.DueDate == "":
+		if due, title, ok := duedate.Find(todo.Title, now, preferences.WeekStart()); ok {
+			parsed = &parsedDue{Input: strings.TrimSpace(strings.TrimPrefix(todo.Title, title)), DueDate: due.UTC()}
+			todo.Title = title
+			todo.DueDate = &parsed.DueDate
+		}
+	}
+
+	u, ok := h.quotas.allowTodo(ctx, c, scope)
+	if !ok {
+		return
+	}
+	if err := h.createTodo(ctx, &todo); err != nil {
+		respondStorageError(c, err, "Failed to create todo")
 		return
 	}
 
-	todo.ID = result.InsertedID.(primitive.ObjectID)
-	c.JSON(http.StatusCreated, gin.H{"todo": todo})
+	if html {
+		todo.DescriptionHTML = markdown.Render(todo.Description)
+	}
+	linkTodo(c, &todo, scopeRole(c))
+	response := gin.H{"todo": todo}
+	if parsed != nil {
+		response["parsed_due"] = parsed
+	}
+	if warnings := h.quotas.todoWarnings(c, scope, u.add(1)); warnings != nil {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// parsedDue reports how a due date in words was understood, so clients can
+// show it for confirmation
+type parsedDue struct {
+	Input   string    `json:"input"`
+	DueDate time.Time `json:"due_date"`
+}
+
+// resolveDue resolves a due date in words, with weeks starting on
+// weekStart, responding with a validation error and returning nil if it
+// can't be understood
+func resolveDue(c *gin.Context, input string, now time.Time, weekStart time.Weekday) *parsedDue {
+	due, err := duedate.Parse(input, now, weekStart)
+	if errors.Is(err, duedate.ErrOutOfRange) {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{
+			Field:   "due",
+			Message: fmt.Sprintf("must be at most %d years ahead", duedate.MaxYearsAhead),
+		}})
+		return nil
+	}
+	if err != nil {
+		apierrors.RespondValidation(c, []apierrors.FieldError{{
+			Field:   "due",
+			Message: `isn't a date we understand; try e.g. "tomorrow 5pm", "next friday" or "in 3 days"`,
+		}})
+		return nil
+	}
+	return &parsedDue{Input: input, DueDate: due.UTC()}
 }
 
 // UpdateTodo updates an existing todo for the authenticated user
-func UpdateTodo(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+func (h *TodoHandler) UpdateTodo(c *gin.Context) {
+	if _, exists := c.Get("user_id"); !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
 		return
 	}
 
 	todoID := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(todoID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid todo ID"})
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
 		return
 	}
 
 	var req models.UpdateTodoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
-
-	collectionName := os.Getenv("COLLECTION_NAME")
-	if collectionName == "" {
-		collectionName = "todos"
+	req.Normalize()
+	errs := req.Validate()
+	if req.Tags != nil {
+		errs = append(errs, h.quotas.todoErrors(*req.Tags, 0)...)
+	}
+	if len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+	html, ok := wantsHTML(c)
+	if !ok {
+		return
 	}
 
-	collection := database.GetCollection(collectionName)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
 	defer cancel()
 
-	// Build update document
-	update := bson.M{
-		"$set": bson.M{
-			"updated_at": time.Now(),
-		},
+	loc, weekStart := time.UTC, time.Monday
+	if req.DueDate != nil || req.Due != nil {
+		settings, ok := h.settings.lookup(ctx, c)
+		if !ok {
+			return
+		}
+		loc, weekStart = settings.Location(), settings.CurrentPreferences().WeekStart()
+	}
+	var parsed *parsedDue
+	if req.Due != nil && *req.Due != "" {
+		if parsed = resolveDue(c, *req.Due, time.Now().In(loc), weekStart); parsed == nil {
+			return
+		}
+	}
+
+	todo, role, err := todoAccess(ctx, c, h.todos, h.shares, objectID)
+	if err == nil {
+		err = authz.CanWrite(role)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to update todo")
+		return
+	}
+	if req.Completed != nil && *req.Completed && !h.checkBlockers(ctx, c, todo) {
+		return
+	}
+	before := models.RevisionSnapshot(todo)
+
+	applyUpdate(todo, &req, loc)
+	if req.Due != nil {
+		todo.DueDate = nil
+		if parsed != nil {
+			todo.DueDate = &parsed.DueDate
+		}
 	}
+	// The checklist is checked against the rule the update leaves, and a
+	// new rule applies to the checklist as it is
+	if req.Completed != nil && *req.Completed && !checkChecklist(c, todo) {
+		return
+	}
+	if req.ChecklistRule != nil {
+		todo.ApplyChecklistRule(time.Now().UTC())
+	}
+	if req.CustomFields != nil {
+		// Values are checked against the fields of the todo's own scope,
+		// which for a shared todo is its owner's
+		fields, err := h.fields.List(ctx, repository.ScopeOf(todo))
+		if err != nil {
+			respondStorageError(c, err, "Failed to update todo")
+			return
+		}
+		if todo.CustomFields, ok = customFieldValues(c, fields, todo.CustomFields, req.CustomFields); !ok {
+			return
+		}
+	}
+	todo.UpdatedAt = time.Now()
+
+	// The update was checked against the todo as read, so it isn't
+	// applied again to a todo changed since; the client gets a conflict
+	err = h.saveEdit(ctx, &before, todo)
+	if errors.Is(err, repository.ErrConflict) {
+		apierrors.Respond(c, apierrors.CodeTodoConflict, "The todo was changed while it was being updated; fetch it and try again")
+		return
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodeTodoNotFound, "Todo not found")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to update todo")
+		return
+	}
+	h.recordRevision(ctx, c.GetString("user_id"), &before, todo)
 
+	if html {
+		todo.DescriptionHTML = markdown.Render(todo.Description)
+	}
+	linkTodo(c, todo, role)
+	response := gin.H{"todo": todo}
+	if parsed != nil {
+		response["parsed_due"] = parsed
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// applyUpdate sets the fields of an update request that were provided,
+// except due dates in words and custom fields, which callers resolve and
+// check first. Plain due dates are read in loc.
+func applyUpdate(todo *models.Todo, req *models.UpdateTodoRequest, loc *time.Location) {
 	if req.Title != nil {
-		update["$set"].(bson.M)["title"] = *req.Title
+		todo.Title = *req.Title
 	}
 	if req.Description != nil {
-		update["$set"].(bson.M)["description"] = *req.Description
+		todo.Description = *req.Description
 	}
 	if req.Completed != nil {
-		update["$set"].(bson.M)["completed"] = *req.Completed
+		todo.SetCompleted(*req.Completed, time.Now().UTC())
+	}
+	if req.DueDate != nil {
+		todo.DueDate = dueDate(*req.DueDate, loc)
+	}
+	if req.Priority != nil {
+		todo.Priority = *req.Priority
 	}
+	if req.Pinned != nil {
+		todo.Pinned = *req.Pinned
+	}
+	if req.Color != nil {
+		todo.Color = *req.Color
+	}
+	if req.EstimatedMinutes != nil {
+		todo.EstimatedMinutes = *req.EstimatedMinutes
+	}
+	if req.ActualMinutes != nil {
+		todo.ActualMinutes = *req.ActualMinutes
+	}
+	if req.Location != nil {
+		todo.Location = req.Location
+		if req.Location.Empty() {
+			todo.Location = nil
+		}
+	}
+	if req.ChecklistRule != nil {
+		todo.ChecklistRule = *req.ChecklistRule
+	}
+	if req.Tags != nil {
+		todo.Tags = *req.Tags
+		if len(todo.Tags) == 0 {
+			todo.Tags = nil
+		}
+	}
+	if req.Archived != nil {
+		todo.SetArchived(*req.Archived, time.Now().UTC())
+	}
+}
 
-	filter := bson.M{
-		"_id":     objectID,
-		"user_id": userID,
+// maxWriteAttempts bounds how many times a change is applied to a todo
+// that keeps being changed by other requests between being read and written
+const maxWriteAttempts = 3
+
+// modifyTodo applies change to a todo the user may edit, saves it and
+// responds with the updated todo. message describes the operation in errors.
+func (h *TodoHandler) modifyTodo(ctx context.Context, c *gin.Context, id primitive.ObjectID, message string, change func(*models.Todo)) {
+	todo, role, ok := h.saveTodo(ctx, c, id, message, change)
+	if !ok {
+		return
 	}
+	linkTodo(c, todo, role)
+	c.JSON(http.StatusOK, gin.H{"todo": todo})
+}
+
+// saveTodo applies change to a todo the user may edit and saves it,
+// returning the todo saved and the user's role on it. The todo is only
+// written if it wasn't changed since it was read; if it was, it's read
+// again and change applied afresh, up to maxWriteAttempts times. It
+// responds with an error and returns false when the todo can't be saved.
+func (h *TodoHandler) saveTodo(ctx context.Context, c *gin.Context, id primitive.ObjectID, message string, change func(*models.Todo)) (*models.Todo, authz.Role, bool) {
+	return h.saveTodoIf(ctx, c, id, message, func(todo *models.Todo) bool {
+		change(todo)
+		return true
+	})
+}
+
+// saveTodoIf is saveTodo for changes that depend on the todo as read, such
+// as ones limited in size. change may refuse by responding with an error
+// and returning false, in which case nothing is saved.
+func (h *TodoHandler) saveTodoIf(ctx context.Context, c *gin.Context, id primitive.ObjectID, message string, change func(*models.Todo) bool) (*models.Todo, authz.Role, bool) {
+	todo, before, role, ok := h.writeTodo(ctx, c, id, message, authz.CanWrite, func(todo *models.Todo, _ authz.Role) bool {
+		return change(todo)
+	}, updateEvents)
+	if !ok {
+		return nil, "", false
+	}
+	h.recordRevision(ctx, c.GetString("user_id"), &before, todo)
+	return todo, role, true
+}
+
+// writeTodo reads a todo, checks the user's role on it with can, applies
+// change and writes the todo back if it wasn't changed since it was read.
+// If it was, it's read again and change applied afresh, up to
+// maxWriteAttempts times. The events describe returns for the write, if
+// it's set, are recorded along with it. It returns the todo written, a snapshot of it as
+// read and the user's role on it; it responds with an error and returns
+// false when the todo can't be written. Recording a revision is left to
+// the caller.
+func (h *TodoHandler) writeTodo(ctx context.Context, c *gin.Context, id primitive.ObjectID, message string, can func(authz.Role) error, change func(*models.Todo, authz.Role) bool, describe func(before, after *models.Todo) []events.Event) (*models.Todo, models.Todo, authz.Role, bool) {
+	for attempt := 1; ; attempt++ {
+		todo, role, err := todoAccess(ctx, c, h.todos, h.shares, id)
+		if err == nil {
+			err = can(role)
+		}
+		if err != nil {
+			respondTodoError(c, err, message)
+			return nil, models.Todo{}, "", false
+		}
 
-	result, err := collection.UpdateOne(ctx, filter, update)
+		before := models.RevisionSnapshot(todo)
+		if !change(todo, role) {
+			return nil, models.Todo{}, "", false
+		}
+		todo.UpdatedAt = time.Now()
+		err = h.commit(ctx, func(ctx context.Context) error {
+			return h.todos.UpdateIfUnchanged(ctx, todo, before.UpdatedAt)
+		}, func() []events.Event {
+			if describe == nil {
+				return nil
+			}
+			return describe(&before, todo)
+		})
+		switch {
+		case errors.Is(err, repository.ErrConflict) && attempt < maxWriteAttempts:
+			continue
+		case errors.Is(err, repository.ErrConflict):
+			apierrors.Respond(c, apierrors.CodeTodoConflict, "The todo kept changing while it was being saved; try again")
+			return nil, models.Todo{}, "", false
+		case errors.Is(err, repository.ErrNotFound):
+			apierrors.Respond(c, apierrors.CodeTodoNotFound, "Todo not found")
+			return nil, models.Todo{}, "", false
+		case err != nil:
+			respondStorageError(c, err, message)
+			return nil, models.Todo{}, "", false
+		}
+		return todo, before, role, true
+	}
+}
+
+// rewriteTodo applies change to a todo read outside a request for it, such
+// as one of a list, and writes it back with write, which fails with
+// ErrConflict if the todo was changed since it was read. If it was, it's
+// read again and change applied afresh, up to maxWriteAttempts times.
+// change returns false when the todo doesn't need changing. It returns the
+// todo written and a snapshot of it as read, or nil when nothing was
+// written, including when the todo was deleted.
+func rewriteTodo(ctx context.Context, todos repository.TodoRepository, todo *models.Todo, change func(*models.Todo) bool, write func(ctx context.Context, before, after *models.Todo) error) (*models.Todo, models.Todo, error) {
+	for attempt := 1; ; attempt++ {
+		before := models.RevisionSnapshot(todo)
+		if !change(todo) {
+			return nil, models.Todo{}, nil
+		}
+		todo.UpdatedAt = time.Now()
+		err := write(ctx, &before, todo)
+		switch {
+		case errors.Is(err, repository.ErrConflict) && attempt < maxWriteAttempts:
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, models.Todo{}, nil
+		case err != nil:
+			return nil, models.Todo{}, err
+		default:
+			return todo, before, nil
+		}
+		todo, err = todos.Get(ctx, repository.ScopeOf(todo), todo.ID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, models.Todo{}, nil
+		}
+		if err != nil {
+			return nil, models.Todo{}, err
+		}
+	}
+}
+
+// updateIfUnchanged is the write of rewriteTodo for edits no event is
+// published for
+func updateIfUnchanged(todos repository.TodoRepository) func(ctx context.Context, before, after *models.Todo) error {
+	return func(ctx context.Context, before, after *models.Todo) error {
+		return todos.UpdateIfUnchanged(ctx, after, before.UpdatedAt)
+	}
+}
+
+// HeadTodo answers HEAD /todos/:id with 200 if the user can read the todo
+// and 404 if not, without a body, for clients checking that a todo still
+// exists
+func (h *TodoHandler) HeadTodo(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update todo"})
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	_, role, err := todoAccess(ctx, c, h.todos, h.shares, objectID)
+	if err == nil {
+		err = authz.CanRead(role)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to fetch todo")
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// GetTodo returns one todo the user can read, including one shared with
+// them
+func (h *TodoHandler) GetTodo(c *gin.Context) {
+	if _, exists := c.Get("user_id"); !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
+		return
+	}
+	html, ok := wantsHTML(c)
+	if !ok {
+		return
+	}
+	fields, ok := fieldSelection(c)
+	if !ok {
+		return
+	}
+	embeds, ok := includeEmbeds(c)
+	if !ok {
 		return
 	}
 
-	// Fetch and return the updated todo
-	var updatedTodo models.Todo
-	err = collection.FindOne(ctx, filter).Decode(&updatedTodo)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	todo, role, err := todoAccess(ctx, c, h.todos, h.shares, objectID)
+	if err == nil {
+		err = authz.CanRead(role)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated todo"})
+		respondTodoError(c, err, "Failed to fetch todo")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"todo": updatedTodo})
+	if html {
+		todo.DescriptionHTML = markdown.Render(todo.Description)
+	}
+	linkTodo(c, todo, role)
+	embedTodo(c, todo, embeds)
+	if fields != nil {
+		c.JSON(http.StatusOK, gin.H{"todo": selectTodoFields(todo, fields)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"todo": todo})
 }
 
-// DeleteTodo deletes a todo for the authenticated user
-func DeleteTodo(c *gin.Context) {
+// CloneTodo copies a todo the user can read into the request's scope, for
+// checklists that are repeated by hand. The copy starts out open and
+// unpinned, with its checklist items open, fresh timestamps and no shares
+// or public link. ?include= can leave the checklist or tags behind.
+func (h *TodoHandler) CloneTodo(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	todoID := c.Param("id")
-	objectID, err := primitive.ObjectIDFromHex(todoID)
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid todo ID"})
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
 		return
 	}
-
-	collectionName := os.Getenv("COLLECTION_NAME")
-	if collectionName == "" {
-		collectionName = "todos"
+	if err := authz.CanWrite(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to clone todo")
+		return
+	}
+	parts, ok := includeCloneParts(c)
+	if !ok {
+		return
+	}
+	html, ok := wantsHTML(c)
+	if !ok {
+		return
 	}
 
-	collection := database.GetCollection(collectionName)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
 	defer cancel()
 
-	filter := bson.M{
-		"_id":     objectID,
-		"user_id": userID,
+	source, role, err := todoAccess(ctx, c, h.todos, h.shares, objectID)
+	if err == nil {
+		err = authz.CanRead(role)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to clone todo")
+		return
+	}
+
+	now := time.Now()
+	todo := models.Todo{
+		UserID:           userID.(string),
+		Title:            source.Title,
+		Description:      source.Description,
+		DueDate:          source.DueDate,
+		Priority:         source.Priority,
+		Color:            source.Color,
+		EstimatedMinutes: source.EstimatedMinutes,
+		Location:         source.Location,
+		ChecklistRule:    source.ChecklistRule,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	scope := todoScope(c, todo.UserID)
+	if scope.IsWorkspace() {
+		todo.WorkspaceID = &scope.WorkspaceID
+	}
+	if parts.tags {
+		todo.Tags = slices.Clone(source.Tags)
+	}
+	if parts.subtasks {
+		for _, item := range source.Checklist {
+			todo.Checklist = append(todo.Checklist, models.ChecklistItem{ID: primitive.NewObjectID(), Title: item.Title})
+		}
+	}
+	// Custom fields only apply within their scope
+	if repository.ScopeOf(source) == scope {
+		todo.CustomFields = maps.Clone(source.CustomFields)
 	}
 
-	result, err := collection.DeleteOne(ctx, filter)
+	u, ok := h.quotas.allowTodo(ctx, c, scope)
+	if !ok {
+		return
+	}
+	if err := h.createTodo(ctx, &todo); err != nil {
+		respondStorageError(c, err, "Failed to clone todo")
+		return
+	}
+
+	if html {
+		todo.DescriptionHTML = markdown.Render(todo.Description)
+	}
+	linkTodo(c, &todo, scopeRole(c))
+	response := gin.H{"todo": todo, "cloned_from": source.ID}
+	if warnings := h.quotas.todoWarnings(c, scope, u.add(1)); warnings != nil {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// DeleteTodo deletes a todo for the authenticated user
+func (h *TodoHandler) DeleteTodo(c *gin.Context) {
+	if _, exists := c.Get("user_id"); !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	todoID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(todoID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete todo"})
+		apierrors.Respond(c, apierrors.CodeInvalidID, "Invalid todo ID")
 		return
 	}
 
-	if result.DeletedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	todo, role, err := todoAccess(ctx, c, h.todos, h.shares, objectID)
+	if err == nil {
+		err = authz.CanWrite(role)
+	}
+	if err != nil {
+		respondTodoError(c, err, "Failed to delete todo")
 		return
 	}
+	// Shares are read first so an undo can bring them back
+	record := undoRecord{UserID: c.GetString("user_id"), Todos: []models.Todo{*todo}}
+	if todo.WorkspaceID == nil {
+		if record.Shares, err = h.shares.ListByTodo(ctx, todo.UserID, objectID); err != nil {
+			log.Printf("Failed to read shares of todo %s: %v", objectID.Hex(), err)
+		}
+	}
+	waiting, err := h.removeTodo(ctx, todo)
+	if err != nil {
+		respondTodoError(c, err, "Failed to delete todo")
+		return
+	}
+	if len(waiting) > 0 {
+		record.Waiting = map[string][]primitive.ObjectID{objectID.Hex(): waiting}
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Todo deleted successfully"})
+	h.respondDeleted(ctx, c, "Todo deleted successfully", record)
+}
+
+// SaveTodo applies change to a todo read outside a request, such as by the
+// daily reset, and saves it the way rewriteTodo does, recording a revision
+// by editedBy and publishing the update the way an edit through the API
+// does. change returns false when the todo doesn't need changing. It
+// reports whether the todo was saved; one deleted since it was read isn't.
+func (h *TodoHandler) SaveTodo(ctx context.Context, todo *models.Todo, editedBy string, change func(*models.Todo) bool) (bool, error) {
+	saved, before, err := rewriteTodo(ctx, h.todos, todo, change, h.saveEdit)
+	if err != nil || saved == nil {
+		return false, err
+	}
+	h.recordRevision(ctx, editedBy, &before, saved)
+	return true, nil
+}
+
+// RemoveTodo deletes a todo the way DeleteTodo does, for the retention
+// rules. It can't be undone.
+func (h *TodoHandler) RemoveTodo(ctx context.Context, todo *models.Todo) error {
+	_, err := h.removeTodo(ctx, todo)
+	return err
+}
+
+// removeTodo deletes a todo along with its revisions, shares and public
+// link, and returns the todos that were waiting for it. The tombstone goes
+// first, so syncing clients hear of the delete even if it fails part way.
+func (h *TodoHandler) removeTodo(ctx context.Context, todo *models.Todo) ([]primitive.ObjectID, error) {
+	if err := h.sync.record(ctx, todo); err != nil {
+		return nil, err
+	}
+	err := h.commit(ctx, func(ctx context.Context) error {
+		return h.todos.Delete(ctx, repository.ScopeOf(todo), todo.ID)
+	}, func() []events.Event {
+		return []events.Event{todoEvent(events.TodoDeleted, todo)}
+	})
+	if err != nil {
+		return nil, err
+	}
+	waiting := h.dropBlocker(ctx, todo)
+	if err := h.revisions.DeleteByTodo(ctx, todo.ID); err != nil {
+		log.Printf("Failed to delete revisions of todo %s: %v", todo.ID.Hex(), err)
+	}
+	if todo.WorkspaceID == nil {
+		// The todo is gone, so leftovers only take up space: shares of it
+		// are skipped when listed and its public link serves a 404
+		if err := h.shares.DeleteByTodo(ctx, todo.ID); err != nil {
+			log.Printf("Failed to delete shares of todo %s: %v", todo.ID.Hex(), err)
+		}
+		if err := h.links.Delete(ctx, todo.UserID, todo.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			log.Printf("Failed to delete public link of todo %s: %v", todo.ID.Hex(), err)
+		}
+	}
+	return waiting, nil
 }
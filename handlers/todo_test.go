@@ -0,0 +1,671 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-api/cache"
+	"todo-api/config"
+	"todo-api/events"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testUser = "user-1"
+
+// newTestTodoHandler creates a TodoHandler on todos, with everything else
+// kept in memory
+func newTestTodoHandler(todos repository.TodoRepository) *TodoHandler {
+	timeout := time.Second
+	quotas := NewQuotaHandler(config.QuotaConfig{MaxTags: 3}, todos, repository.NewMemoryWorkspaceRepository(), timeout)
+	settings := NewSettingsHandler(repository.NewMemoryUserRepository(), timeout)
+	return NewTodoHandler(todos, repository.NewMemoryShareRepository(), repository.NewMemoryPublicLinkRepository(),
+		repository.NewMemoryCustomFieldRepository(), repository.NewMemorySavedFilterRepository(), repository.NewMemoryRevisionRepository(),
+		quotas, settings, NewUndoLog(cache.NewMemoryCache(), time.Minute), NewSyncLog(repository.NewMemoryTombstoneRepository(), time.Hour),
+		events.Nop{}, repository.WithoutTransactions(), timeout)
+}
+
+// newTodoRouter serves the todo routes from a TodoHandler on todos, signed
+// in as testUser
+func newTodoRouter(todos repository.TodoRepository) *gin.Engine {
+	return todoRouter(newTestTodoHandler(todos))
+}
+
+// todoRouter serves the todo routes from h, signed in as testUser
+func todoRouter(h *TodoHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", testUser)
+	})
+	router.GET("/todos", h.GetTodos)
+	router.GET("/todos/count", h.CountTodos)
+	router.GET("/todos/:id", h.GetTodo)
+	router.POST("/todos", h.CreateTodo)
+	router.PUT("/todos/:id", h.UpdateTodo)
+	router.PUT("/todos/:id/pin", h.PinTodo)
+	router.POST("/todos/:id/clone", h.CloneTodo)
+	router.POST("/todos/:id/blockers", h.AddBlocker)
+	router.POST("/todos/:id/checklist", h.AddChecklistItem)
+	router.PUT("/todos/:id/checklist/:item_id", h.UpdateChecklistItem)
+	comments := NewCommentHandler(h)
+	router.POST("/todos/:id/comments", comments.AddComment)
+	router.DELETE("/todos/:id/comments/:comment_id", comments.DeleteComment)
+	router.DELETE("/todos/:id", h.DeleteTodo)
+	router.POST("/sync", h.PushSync)
+	return router
+}
+
+// testTodo returns a stored todo of testUser's
+func testTodo(title string) models.Todo {
+	now := time.Now()
+	return models.Todo{ID: primitive.NewObjectID(), UserID: testUser, Title: title, CreatedAt: now, UpdatedAt: now}
+}
+
+// serve sends a request with an optional JSON body and decodes the
+// response body, if any, into a map
+func serve(t *testing.T, router http.Handler, method, target, body string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var decoded map[string]any
+	if rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, target, rec.Body.String(), err)
+		}
+	}
+	return rec.Code, decoded
+}
+
+var errStorage = errors.New("storage is down")
+
+func TestTodoHandlers(t *testing.T) {
+	existing := testTodo("Buy milk")
+	existing.Checklist = []models.ChecklistItem{{ID: primitive.NewObjectID(), Title: "Oat milk"}}
+	other := testTodo("Someone else's")
+	other.UserID = "user-2"
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		// fail makes a repository method fail
+		fail       map[string]error
+		wantStatus int
+		wantCode   string
+		// check looks at the decoded response and the repository afterwards
+		check func(t *testing.T, body map[string]any, todos *fakeTodos)
+	}{
+		{
+			name: "list", method: http.MethodGet, target: "/todos",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				if listed := body["todos"].([]any); len(listed) != 1 {
+					t.Errorf("listed %d todos, want only the user's 1", len(listed))
+				}
+			},
+		},
+		{
+			name: "list selected fields", method: http.MethodGet, target: "/todos?fields=title,tags",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				listed := body["todos"].([]any)
+				if len(listed) != 1 || len(listed[0].(map[string]any)) != 2 || listed[0].(map[string]any)["title"] != "Buy milk" {
+					t.Errorf("listed %v, want the id and title of the user's todo", listed)
+				}
+				if !todos.called("ListFields") || todos.called("List") {
+					t.Error("whole todos were loaded for a few fields")
+				}
+			},
+		},
+		{
+			name: "list with facets", method: http.MethodGet, target: "/todos?include=facets",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				status := body["facets"].(map[string]any)["status"].(map[string]any)
+				if status["open"] != 1.0 || status["completed"] != 0.0 {
+					t.Errorf("status facet = %v, want the user's 1 todo open", status)
+				}
+				if !todos.called("Facets") {
+					t.Error("facets the repository can count were counted from the listed todos")
+				}
+			},
+		},
+		{
+			name: "count", method: http.MethodGet, target: "/todos/count?pinned=false",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				if body["count"] != 1.0 || !todos.called("CountMatching") || todos.called("List") {
+					t.Errorf("count = %v after calls %v; want 1 counted by the repository", body["count"], todos.calls)
+				}
+			},
+		},
+		{
+			name: "list with storage down", method: http.MethodGet, target: "/todos",
+			fail:       map[string]error{"List": repository.ErrUnavailable},
+			wantStatus: http.StatusServiceUnavailable, wantCode: "STORAGE_UNAVAILABLE",
+		},
+		{
+			name: "list with a bad filter", method: http.MethodGet, target: "/todos?pinned=maybe",
+			wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED",
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				if todos.called("List") {
+					t.Error("listed todos for an invalid filter")
+				}
+			},
+		},
+		{
+			name: "get", method: http.MethodGet, target: "/todos/" + existing.ID.Hex(),
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				todo := body["todo"].(map[string]any)
+				if todo["title"] != "Buy milk" {
+					t.Errorf("title = %v, want Buy milk", todo["title"])
+				}
+				if links, _ := todo["_links"].(map[string]any); links["self"] == nil {
+					t.Errorf("_links = %v, want a self link", todo["_links"])
+				}
+			},
+		},
+		{
+			name: "get with a malformed ID", method: http.MethodGet, target: "/todos/nope",
+			wantStatus: http.StatusBadRequest, wantCode: "INVALID_ID",
+		},
+		{
+			name: "get someone else's", method: http.MethodGet, target: "/todos/" + other.ID.Hex(),
+			wantStatus: http.StatusNotFound, wantCode: "TODO_NOT_FOUND",
+		},
+		{
+			name: "get with storage failing", method: http.MethodGet, target: "/todos/" + existing.ID.Hex(),
+			fail:       map[string]error{"Get": errStorage},
+			wantStatus: http.StatusInternalServerError, wantCode: "INTERNAL_ERROR",
+		},
+		{
+			name: "create", method: http.MethodPost, target: "/todos", body: `{"title":"  Walk the dog ","priority":"high"}`,
+			wantStatus: http.StatusCreated,
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				todo := body["todo"].(map[string]any)
+				if todo["title"] != "Walk the dog" || todo["priority"] != "high" || todo["user_id"] != testUser {
+					t.Errorf("created %v", todo)
+				}
+				id, _ := primitive.ObjectIDFromHex(todo["id"].(string))
+				if _, err := todos.MemoryTodoRepository.Get(context.Background(), repository.Personal(testUser), id); err != nil {
+					t.Errorf("created todo wasn't stored: %v", err)
+				}
+			},
+		},
+		{
+			name: "create without a title", method: http.MethodPost, target: "/todos", body: `{"title":"  "}`,
+			wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED",
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				if todos.called("Create") {
+					t.Error("stored an invalid todo")
+				}
+			},
+		},
+		{
+			name: "create with malformed JSON", method: http.MethodPost, target: "/todos", body: `{"title":`,
+			wantStatus: http.StatusBadRequest, wantCode: "INVALID_REQUEST",
+		},
+		{
+			name: "create with storage failing", method: http.MethodPost, target: "/todos", body: `{"title":"Walk the dog"}`,
+			fail:       map[string]error{"Create": errStorage},
+			wantStatus: http.StatusInternalServerError, wantCode: "INTERNAL_ERROR",
+		},
+		{
+			name: "update", method: http.MethodPut, target: "/todos/" + existing.ID.Hex(), body: `{"completed":true}`,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				stored, err := todos.MemoryTodoRepository.Get(context.Background(), repository.Personal(testUser), existing.ID)
+				if err != nil || !stored.Completed || stored.CompletedAt == nil || stored.Title != "Buy milk" {
+					t.Errorf("stored todo = %+v, %v; want it completed now", stored, err)
+				}
+			},
+		},
+		{
+			name: "update with an invalid priority", method: http.MethodPut, target: "/todos/" + existing.ID.Hex(), body: `{"priority":"urgent"}`,
+			wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED",
+		},
+		{
+			name: "update with a malformed ID", method: http.MethodPut, target: "/todos/nope", body: `{"completed":true}`,
+			wantStatus: http.StatusBadRequest, wantCode: "INVALID_ID",
+		},
+		{
+			name: "update with more tags than the quota allows", method: http.MethodPut, target: "/todos/" + existing.ID.Hex(), body: `{"tags":["a","b","c","d"]}`,
+			wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED",
+		},
+		{
+			name: "update a todo changed since it was read", method: http.MethodPut, target: "/todos/" + existing.ID.Hex(), body: `{"completed":true}`,
+			fail:       map[string]error{"UpdateIfUnchanged": repository.ErrConflict},
+			wantStatus: http.StatusConflict, wantCode: "TODO_CONFLICT",
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				if n := todos.count("UpdateIfUnchanged"); n != 1 {
+					t.Errorf("tried to save %d times, want the update checked against the todo read once", n)
+				}
+			},
+		},
+		{
+			name: "pin", method: http.MethodPut, target: "/todos/" + existing.ID.Hex() + "/pin",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				stored, err := todos.MemoryTodoRepository.Get(context.Background(), repository.Personal(testUser), existing.ID)
+				if err != nil || !stored.Pinned {
+					t.Errorf("stored todo = %+v, %v; want it pinned", stored, err)
+				}
+			},
+		},
+		{
+			name: "pin a todo that keeps changing", method: http.MethodPut, target: "/todos/" + existing.ID.Hex() + "/pin",
+			fail:       map[string]error{"UpdateIfUnchanged": repository.ErrConflict},
+			wantStatus: http.StatusConflict, wantCode: "TODO_CONFLICT",
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				if n := todos.count("UpdateIfUnchanged"); n != maxWriteAttempts {
+					t.Errorf("tried to save %d times, want %d", n, maxWriteAttempts)
+				}
+			},
+		},
+		{
+			name: "add a checklist item to a todo that keeps changing", method: http.MethodPost, target: "/todos/" + existing.ID.Hex() + "/checklist", body: `{"title":"Bread"}`,
+			fail:       map[string]error{"UpdateIfUnchanged": repository.ErrConflict},
+			wantStatus: http.StatusConflict, wantCode: "TODO_CONFLICT",
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				if n := todos.count("UpdateIfUnchanged"); n != maxWriteAttempts || todos.called("Update") {
+					t.Errorf("calls %v, want %d conditional writes and no blind one", todos.calls, maxWriteAttempts)
+				}
+			},
+		},
+		{
+			name: "clone", method: http.MethodPost, target: "/todos/" + existing.ID.Hex() + "/clone",
+			wantStatus: http.StatusCreated,
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				clone := body["todo"].(map[string]any)
+				if checklist, _ := clone["checklist"].([]any); clone["title"] != "Buy milk" || len(checklist) != 1 {
+					t.Errorf("clone = %v, want the title and checklist copied", clone)
+				}
+			},
+		},
+		{
+			name: "clone without subtasks", method: http.MethodPost, target: "/todos/" + existing.ID.Hex() + "/clone?include=tags",
+			wantStatus: http.StatusCreated,
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				if clone := body["todo"].(map[string]any); clone["checklist"] != nil {
+					t.Errorf("clone = %v, want no checklist", clone)
+				}
+			},
+		},
+		{
+			name: "clone with an unknown part", method: http.MethodPost, target: "/todos/" + existing.ID.Hex() + "/clone?include=comments",
+			wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED",
+		},
+		{
+			name: "update someone else's", method: http.MethodPut, target: "/todos/" + other.ID.Hex(), body: `{"completed":true}`,
+			wantStatus: http.StatusNotFound, wantCode: "TODO_NOT_FOUND",
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				if todos.called("Update") || todos.called("UpdateIfUnchanged") {
+					t.Error("updated another user's todo")
+				}
+			},
+		},
+		{
+			name: "delete", method: http.MethodDelete, target: "/todos/" + existing.ID.Hex(),
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				if _, err := todos.MemoryTodoRepository.Get(context.Background(), repository.Personal(testUser), existing.ID); !errors.Is(err, repository.ErrNotFound) {
+					t.Errorf("todo still stored after delete: %v", err)
+				}
+			},
+		},
+		{
+			name: "delete someone else's", method: http.MethodDelete, target: "/todos/" + other.ID.Hex(),
+			wantStatus: http.StatusNotFound, wantCode: "TODO_NOT_FOUND",
+			check: func(t *testing.T, body map[string]any, todos *fakeTodos) {
+				if _, err := todos.MemoryTodoRepository.Get(context.Background(), repository.Personal("user-2"), other.ID); err != nil {
+					t.Errorf("another user's todo was deleted: %v", err)
+				}
+			},
+		},
+		{
+			name: "delete with storage down", method: http.MethodDelete, target: "/todos/" + existing.ID.Hex(),
+			fail:       map[string]error{"Delete": repository.ErrUnavailable},
+			wantStatus: http.StatusServiceUnavailable, wantCode: "STORAGE_UNAVAILABLE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todos := newFakeTodos(existing, other)
+			for method, err := range tt.fail {
+				todos.fail[method] = err
+			}
+			status, body := serve(t, newTodoRouter(todos), tt.method, tt.target, tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %v", status, tt.wantStatus, body)
+			}
+			if tt.wantCode != "" && body["code"] != tt.wantCode {
+				t.Errorf("code = %v, want %s", body["code"], tt.wantCode)
+			}
+			if tt.check != nil {
+				tt.check(t, body, todos)
+			}
+		})
+	}
+}
+
+// TestWritesKeepConcurrentChanges changes a todo's checklist or comments
+// while another request changes the todo between the handler reading it
+// and writing it back. Neither change may be lost.
+func TestWritesKeepConcurrentChanges(t *testing.T) {
+	item := models.ChecklistItem{ID: primitive.NewObjectID(), Title: "Oat milk"}
+	comment := models.Comment{ID: primitive.NewObjectID(), AuthorID: testUser, Body: "Which brand?"}
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		// want checks the todo stored in the end
+		want func(todo *models.Todo) bool
+	}{
+		{
+			name: "add a checklist item", method: http.MethodPost, target: "/checklist", body: `{"title":"Bread"}`,
+			wantStatus: http.StatusOK,
+			want: func(todo *models.Todo) bool {
+				return len(todo.Checklist) == 2 && todo.Checklist[1].Title == "Bread"
+			},
+		},
+		{
+			name: "complete a checklist item", method: http.MethodPut, target: "/checklist/" + item.ID.Hex(), body: `{"completed":true}`,
+			wantStatus: http.StatusOK,
+			want: func(todo *models.Todo) bool {
+				return len(todo.Checklist) == 1 && todo.Checklist[0].Completed
+			},
+		},
+		{
+			name: "add a comment", method: http.MethodPost, target: "/comments", body: `{"body":"The usual"}`,
+			wantStatus: http.StatusCreated,
+			want: func(todo *models.Todo) bool {
+				return len(todo.Comments) == 2 && todo.Comments[1].Body == "The usual"
+			},
+		},
+		{
+			name: "delete a comment", method: http.MethodDelete, target: "/comments/" + comment.ID.Hex(),
+			wantStatus: http.StatusOK,
+			want: func(todo *models.Todo) bool {
+				return len(todo.Comments) == 0
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo := testTodo("Buy milk")
+			todo.Checklist = []models.ChecklistItem{item}
+			todo.Comments = []models.Comment{comment}
+			todos := newFakeTodos(todo)
+			todos.interleave = func() {
+				stored, _ := todos.MemoryTodoRepository.Get(context.Background(), repository.Personal(testUser), todo.ID)
+				stored.Title = "Buy groceries"
+				// Any other time tells the handler the todo changed
+				stored.UpdatedAt = stored.UpdatedAt.Add(-time.Second)
+				if err := todos.MemoryTodoRepository.Update(context.Background(), stored); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			status, body := serve(t, newTodoRouter(todos), tt.method, "/todos/"+todo.ID.Hex()+tt.target, tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %v", status, tt.wantStatus, body)
+			}
+			stored, err := todos.MemoryTodoRepository.Get(context.Background(), repository.Personal(testUser), todo.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Title != "Buy groceries" {
+				t.Errorf("title = %q; the concurrent change was overwritten", stored.Title)
+			}
+			if !tt.want(stored) {
+				t.Errorf("stored %+v; the change was lost", stored)
+			}
+			if !stored.UpdatedAt.After(todo.UpdatedAt) {
+				t.Errorf("updated_at = %v, want it moved on", stored.UpdatedAt)
+			}
+		})
+	}
+}
+
+func TestUnassignMemberKeepsConcurrentChanges(t *testing.T) {
+	workspaceID := primitive.NewObjectID()
+	todo := testTodo("Buy milk")
+	todo.WorkspaceID = &workspaceID
+	todo.AssigneeID = "bob"
+	todos := newFakeTodos(todo)
+	todos.interleave = func() {
+		stored, _ := todos.MemoryTodoRepository.Get(context.Background(), repository.Workspace(workspaceID), todo.ID)
+		stored.Title = "Buy groceries"
+		stored.UpdatedAt = stored.UpdatedAt.Add(-time.Second)
+		if err := todos.MemoryTodoRepository.Update(context.Background(), stored); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := unassignMember(context.Background(), todos, workspaceID, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := todos.MemoryTodoRepository.Get(context.Background(), repository.Workspace(workspaceID), todo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "Buy groceries" {
+		t.Errorf("title = %q; the concurrent change was overwritten", stored.Title)
+	}
+	if stored.AssigneeID != "" {
+		t.Errorf("assignee = %q, want none", stored.AssigneeID)
+	}
+}
+
+func TestSaveTodoKeepsConcurrentChanges(t *testing.T) {
+	todo := testTodo("Buy milk")
+	todo.Completed = true
+	todos := newFakeTodos(todo)
+	todos.interleave = func() {
+		stored, _ := todos.MemoryTodoRepository.Get(context.Background(), repository.Personal(testUser), todo.ID)
+		stored.Title = "Buy groceries"
+		stored.UpdatedAt = stored.UpdatedAt.Add(-time.Second)
+		if err := todos.MemoryTodoRepository.Update(context.Background(), stored); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := todo
+	saved, err := newTestTodoHandler(todos).SaveTodo(context.Background(), &read, "daily_reset", func(todo *models.Todo) bool {
+		if todo.Archived() {
+			return false
+		}
+		todo.SetArchived(true, time.Now())
+		return true
+	})
+	if err != nil || !saved {
+		t.Fatalf("SaveTodo = %v, %v; want saved", saved, err)
+	}
+	stored, err := todos.MemoryTodoRepository.Get(context.Background(), repository.Personal(testUser), todo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "Buy groceries" {
+		t.Errorf("title = %q; the concurrent change was overwritten", stored.Title)
+	}
+	if !stored.Archived() {
+		t.Error("todo wasn't archived")
+	}
+}
+
+func TestPushSyncUpdates(t *testing.T) {
+	tests := []struct {
+		name   string
+		todo   func(todo *models.Todo)
+		change string
+		// interleave, if set, changes the todo while the push is applied
+		interleave func(todo *models.Todo)
+		wantStatus string
+		want       func(todo *models.Todo) bool
+	}{
+		{
+			name: "complete a todo whose checklist blocks it",
+			todo: func(todo *models.Todo) {
+				todo.ChecklistRule = models.ChecklistRuleBlock
+				todo.Checklist = []models.ChecklistItem{{ID: primitive.NewObjectID(), Title: "Oat milk"}}
+			},
+			change:     `{"completed":true}`,
+			wantStatus: syncRejected,
+			want:       func(todo *models.Todo) bool { return !todo.Completed },
+		},
+		{
+			name: "set a checklist rule that completes the todo",
+			todo: func(todo *models.Todo) {
+				todo.Checklist = []models.ChecklistItem{{ID: primitive.NewObjectID(), Title: "Oat milk", Completed: true}}
+			},
+			change:     `{"checklist_rule":"auto_complete"}`,
+			wantStatus: syncApplied,
+			want:       func(todo *models.Todo) bool { return todo.Completed },
+		},
+		{
+			name:   "update a todo that changed meanwhile",
+			change: `{"priority":"high"}`,
+			interleave: func(todo *models.Todo) {
+				todo.Title = "Buy groceries"
+			},
+			wantStatus: syncApplied,
+			want: func(todo *models.Todo) bool {
+				return todo.Title == "Buy groceries" && todo.Priority == "high"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo := testTodo("Buy milk")
+			if tt.todo != nil {
+				tt.todo(&todo)
+			}
+			todos := newFakeTodos(todo)
+			if tt.interleave != nil {
+				todos.interleave = func() {
+					stored, _ := todos.MemoryTodoRepository.Get(context.Background(), repository.Personal(testUser), todo.ID)
+					tt.interleave(stored)
+					stored.UpdatedAt = stored.UpdatedAt.Add(-time.Second)
+					if err := todos.MemoryTodoRepository.Update(context.Background(), stored); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			push := `{"changes":[{"op":"update","id":"` + todo.ID.Hex() + `","todo":` + tt.change + `}]}`
+			status, body := serve(t, newTodoRouter(todos), http.MethodPost, "/sync", push)
+			if status != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body %v", status, body)
+			}
+			results, _ := body["results"].([]any)
+			if len(results) != 1 {
+				t.Fatalf("results = %v, want one", body["results"])
+			}
+			if got := results[0].(map[string]any)["status"]; got != tt.wantStatus {
+				t.Errorf("status = %v, want %s; result %v", got, tt.wantStatus, results[0])
+			}
+			stored, err := todos.MemoryTodoRepository.Get(context.Background(), repository.Personal(testUser), todo.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.want(stored) {
+				t.Errorf("stored %+v", stored)
+			}
+		})
+	}
+}
+
+// recordingPublisher keeps the types of the events published, or fails with
+// err
+type recordingPublisher struct {
+	types []string
+	err   error
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, batch ...events.Event) error {
+	if p.err != nil {
+		return p.err
+	}
+	for _, event := range batch {
+		p.types = append(p.types, event.Type)
+	}
+	return nil
+}
+
+func TestWritesRecordTheirEvents(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		publishErr error
+		wantStatus int
+		wantEvents []string
+	}{
+		{
+			name: "create a todo", method: http.MethodPost, target: "/todos", body: `{"title":"Buy bread"}`,
+			wantStatus: http.StatusCreated, wantEvents: []string{events.TodoCreated},
+		},
+		{
+			name: "complete a todo", method: http.MethodPut, target: "/todos/{id}", body: `{"completed":true}`,
+			wantStatus: http.StatusOK, wantEvents: []string{events.TodoUpdated, events.TodoCompleted},
+		},
+		{
+			name: "pin a todo", method: http.MethodPut, target: "/todos/{id}/pin", body: `{"pinned":true}`,
+			wantStatus: http.StatusOK, wantEvents: []string{events.TodoUpdated},
+		},
+		{
+			name: "comment on a todo", method: http.MethodPost, target: "/todos/{id}/comments", body: `{"body":"Which brand?"}`,
+			wantStatus: http.StatusCreated, wantEvents: []string{events.CommentCreated},
+		},
+		{
+			name: "delete a todo", method: http.MethodDelete, target: "/todos/{id}",
+			wantStatus: http.StatusOK, wantEvents: []string{events.TodoDeleted},
+		},
+		{
+			name: "create a todo while events can't be recorded", method: http.MethodPost, target: "/todos", body: `{"title":"Buy bread"}`,
+			publishErr: repository.ErrUnavailable, wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "complete a todo while events can't be recorded", method: http.MethodPut, target: "/todos/{id}", body: `{"completed":true}`,
+			publishErr: repository.ErrUnavailable, wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo := testTodo("Buy milk")
+			h := newTestTodoHandler(newFakeTodos(todo))
+			publisher := &recordingPublisher{err: tt.publishErr}
+			h.publisher = publisher
+
+			target := strings.ReplaceAll(tt.target, "{id}", todo.ID.Hex())
+			status, body := serve(t, todoRouter(h), tt.method, target, tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %v", status, tt.wantStatus, body)
+			}
+			if !slices.Equal(publisher.types, tt.wantEvents) {
+				t.Errorf("events = %v, want %v", publisher.types, tt.wantEvents)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/cache"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UndoLog keeps what a delete removed for a short window, under a token the
+// user can redeem to put it back. Records live in a cache, which is Redis
+// when configured so any instance can undo.
+type UndoLog struct {
+	cache  cache.Cache
+	window time.Duration
+}
+
+// NewUndoLog creates an UndoLog keeping records for window. A zero window
+// turns undo off.
+func NewUndoLog(c cache.Cache, window time.Duration) *UndoLog {
+	return &UndoLog{cache: c, window: window}
+}
+
+// undoRecord is what's needed to reverse a delete. BSON keeps every field
+// of the todos, including ones hidden from JSON.
+type undoRecord struct {
+	UserID string         `bson:"user_id"`
+	Todos  []models.Todo  `bson:"todos"`
+	Shares []models.Share `bson:"shares,omitempty"`
+	// Waiting maps the hex ID of each deleted todo to the todos that
+	// waited for it
+	Waiting map[string][]primitive.ObjectID `bson:"waiting,omitempty"`
+}
+
+// undoKey is the cache key of the record a token redeems
+func undoKey(token string) string {
+	return "undo:" + hashLinkToken(token)
+}
+
+// record stores the record and returns its token and when it expires. It
+// returns an empty token when undo is off.
+func (u *UndoLog) record(ctx context.Context, record undoRecord) (string, time.Time, error) {
+	if u.window <= 0 {
+		return "", time.Time{}, nil
+	}
+	data, err := bson.Marshal(record)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	token, err := newLinkToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expires := time.Now().Add(u.window)
+	if err := u.cache.Set(ctx, undoKey(token), data, u.window); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expires, nil
+}
+
+// take removes the user's record for the token and returns it, or
+// cache.ErrMiss if the token doesn't exist, has expired, was redeemed
+// already or belongs to someone else
+func (u *UndoLog) take(ctx context.Context, token, userID string) (*undoRecord, error) {
+	record, err := u.peek(ctx, token, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.cache.Delete(ctx, undoKey(token)); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// peek returns the user's record for the token like take, leaving the
+// token to be redeemed
+func (u *UndoLog) peek(ctx context.Context, token, userID string) (*undoRecord, error) {
+	data, err := u.cache.Get(ctx, undoKey(token))
+	if err != nil {
+		return nil, err
+	}
+	var record undoRecord
+	if err := bson.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	if record.UserID != userID {
+		return nil, cache.ErrMiss
+	}
+	return &record, nil
+}
+
+// respondDeleted reports a delete, offering an undo token when the record
+// could be kept. Undo is a convenience, so failing to keep it doesn't fail
+// the delete.
+func (h *TodoHandler) respondDeleted(ctx context.Context, c *gin.Context, message string, record undoRecord) {
+	response := gin.H{"message": message}
+	token, expires, err := h.undo.record(ctx, record)
+	if err != nil {
+		log.Printf("Failed to keep undo record: %v", err)
+	} else if token != "" {
+		response["undo_token"] = token
+		response["undo_expires_at"] = expires.UTC()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Undo reverses the delete a token was issued for, restoring the todos along
+// with their shares and the dependencies on them. Only the user who deleted
+// them can undo, and each token works once; a dry run leaves it unused.
+func (h *TodoHandler) Undo(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierrors.Respond(c, apierrors.CodeUnauthenticated, "User not authenticated")
+		return
+	}
+	dryRun, ok := isDryRun(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	load := h.undo.take
+	if dryRun {
+		load = h.undo.peek
+	}
+	record, err := load(ctx, c.Param("token"), userID.(string))
+	if errors.Is(err, cache.ErrMiss) {
+		apierrors.Respond(c, apierrors.CodeUndoNotFound, "This undo token doesn't exist or has expired")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to undo")
+		return
+	}
+	switch err := h.authorizeUndo(ctx, userID.(string), record.Todos); {
+	case errors.Is(err, authz.ErrNotFound):
+		apierrors.Respond(c, apierrors.CodeUndoNotFound, "This undo token doesn't exist or has expired")
+		return
+	case errors.Is(err, authz.ErrForbidden):
+		apierrors.Respond(c, apierrors.CodeForbidden, "Your role in the workspace no longer allows restoring these todos")
+		return
+	case err != nil:
+		respondStorageError(c, err, "Failed to undo")
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"dry_run":         true,
+			"todos_restored":  len(record.Todos),
+			"shares_restored": len(record.Shares),
+			"sample_todo_ids": sampleTodoIDs(record.Todos),
+		})
+		return
+	}
+
+	restored := make([]models.Todo, 0, len(record.Todos))
+	for _, todo := range record.Todos {
+		// Bringing a todo back counts as a change, so syncing clients that
+		// saw it deleted pick it up again
+		todo.UpdatedAt = time.Now()
+		// A todo that's back already, e.g. after a retried request, is
+		// left as it is
+		if err := h.createTodo(ctx, &todo); err != nil && !errors.Is(err, repository.ErrDuplicate) {
+			respondStorageError(c, err, "Failed to undo")
+			return
+		}
+		restored = append(restored, todo)
+	}
+	for _, share := range record.Shares {
+		if err := h.shares.Save(ctx, &share); err != nil {
+			log.Printf("Failed to restore share %s: %v", share.ID.Hex(), err)
+		}
+	}
+	for _, todo := range record.Todos {
+		if waiting := record.Waiting[todo.ID.Hex()]; len(waiting) > 0 {
+			h.restoreBlockers(ctx, &todo, waiting)
+		}
+	}
+
+	linkTodos(c, restored)
+	c.JSON(http.StatusOK, gin.H{"todos": restored})
+}
+
+// authorizeUndo checks the user may still write to the workspaces the todos
+// were deleted from, as their role may have changed since the delete. Other
+// todos come back with the shares that let the user delete them.
+func (h *TodoHandler) authorizeUndo(ctx context.Context, userID string, todos []models.Todo) error {
+	checked := map[primitive.ObjectID]bool{}
+	for _, todo := range todos {
+		if todo.WorkspaceID == nil || checked[*todo.WorkspaceID] {
+			continue
+		}
+		checked[*todo.WorkspaceID] = true
+		workspace, err := h.quotas.workspaces.Get(ctx, *todo.WorkspaceID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return authz.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := authz.CanWrite(workspace.RoleOf(userID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreBlockers makes the waiting todos wait for a restored one again.
+// It's best effort, like dropBlocker: waiting todos deleted in the meantime
+// are skipped.
+func (h *TodoHandler) restoreBlockers(ctx context.Context, restored *models.Todo, waiting []primitive.ObjectID) {
+	todos, err := h.todos.List(ctx, repository.ScopeOf(restored))
+	if err != nil {
+		log.Printf("Failed to restore dependencies on todo %s: %v", restored.ID.Hex(), err)
+		return
+	}
+	for i := range todos {
+		if !slices.Contains(waiting, todos[i].ID) {
+			continue
+		}
+		_, _, err := rewriteTodo(ctx, h.todos, &todos[i], func(todo *models.Todo) bool {
+			if slices.Contains(todo.BlockedBy, restored.ID) {
+				return false
+			}
+			todo.BlockedBy = append(slices.Clone(todo.BlockedBy), restored.ID)
+			return true
+		}, updateIfUnchanged(h.todos))
+		if err != nil {
+			log.Printf("Failed to restore dependencies on todo %s: %v", restored.ID.Hex(), err)
+			return
+		}
+	}
+}
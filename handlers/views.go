@@ -0,0 +1,441 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/duedate"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Longest period the upcoming view covers, in days
+const (
+	defaultUpcomingDays = 7
+	maxUpcomingDays     = 90
+)
+
+// maxCalendarDays is the longest range the calendar returns at once
+const maxCalendarDays = 366
+
+// Working time a day holds before the workload view calls it overcommitted,
+// in minutes
+const (
+	defaultCapacityMinutes = 8 * 60
+	maxCapacityMinutes     = 24 * 60
+)
+
+// Distance around a point the nearby view searches, in meters
+const (
+	defaultNearbyRadius = 500
+	maxNearbyRadius     = 50000
+)
+
+// dayTodos are the todos due on one day. Todos holds a []models.Todo, or
+// the todos' selected fields.
+type dayTodos struct {
+	Date  string `json:"date"`
+	Todos any    `json:"todos"`
+}
+
+// GetToday returns the open todos that need attention today: the overdue
+// ones and the ones due today, each sorted by urgency. Days are counted in
+// the user's time zone.
+func (h *TodoHandler) GetToday(c *gin.Context) {
+	fields, ok := fieldSelection(c)
+	if !ok {
+		return
+	}
+	todos, loc, ok := h.openTodos(c)
+	if !ok {
+		return
+	}
+
+	start := duedate.StartOfDay(time.Now().In(loc))
+	end := start.AddDate(0, 0, 1)
+	overdue, today := []models.Todo{}, []models.Todo{}
+	for _, todo := range todos {
+		switch {
+		case todo.DueDate == nil || !todo.DueDate.Before(end):
+		case todo.DueDate.Before(start):
+			overdue = append(overdue, todo)
+		default:
+			today = append(today, todo)
+		}
+	}
+	sortByUrgency(overdue)
+	sortByUrgency(today)
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":    start.Format(time.DateOnly),
+		"overdue": selectFields(overdue, fields),
+		"today":   selectFields(today, fields),
+		"_links":  collectionLinks(c),
+	})
+}
+
+// GetUpcoming returns the open todos due in the ?days= days after today (7
+// by default), grouped by day. Every day is listed, with its todos sorted
+// by urgency.
+func (h *TodoHandler) GetUpcoming(c *gin.Context) {
+	days := defaultUpcomingDays
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxUpcomingDays {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{
+				Field:   "days",
+				Message: "must be a number between 1 and " + strconv.Itoa(maxUpcomingDays),
+			}})
+			return
+		}
+		days = n
+	}
+	fields, ok := fieldSelection(c)
+	if !ok {
+		return
+	}
+
+	todos, loc, ok := h.openTodos(c)
+	if !ok {
+		return
+	}
+
+	start := duedate.StartOfDay(time.Now().In(loc)).AddDate(0, 0, 1)
+	due := make([][]models.Todo, days)
+	index := make(map[string]int, days)
+	for i := range due {
+		due[i] = []models.Todo{}
+		index[start.AddDate(0, 0, i).Format(time.DateOnly)] = i
+	}
+	for _, todo := range todos {
+		if todo.DueDate == nil {
+			continue
+		}
+		if i, ok := index[todo.DueDate.In(loc).Format(time.DateOnly)]; ok {
+			due[i] = append(due[i], todo)
+		}
+	}
+	result := make([]dayTodos, days)
+	for i, day := range due {
+		sortByUrgency(day)
+		result[i] = dayTodos{Date: start.AddDate(0, 0, i).Format(time.DateOnly), Todos: selectFields(day, fields)}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"days": result, "_links": collectionLinks(c)})
+}
+
+// GetCalendar returns the todos due between ?from= and ?to= (YYYY-MM-DD,
+// both inclusive) as a map from each date to the todos due that day, for
+// calendar UIs. Completed todos are included; days without todos are left
+// out. Days are counted in the user's time zone.
+func (h *TodoHandler) GetCalendar(c *gin.Context) {
+	if err := authz.CanRead(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to fetch todos")
+		return
+	}
+	html, ok := wantsHTML(c)
+	if !ok {
+		return
+	}
+	snoozed, ok := includeSnoozed(c)
+	if !ok {
+		return
+	}
+	fields, ok := fieldSelection(c)
+	if !ok {
+		return
+	}
+	embeds, ok := includeEmbeds(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	loc, ok := h.settings.location(ctx, c)
+	if !ok {
+		return
+	}
+
+	from, to, ok := dateRange(c, loc, maxCalendarDays)
+	if !ok {
+		return
+	}
+
+	days, err := h.todos.ListDueByDay(ctx, todoScope(c, c.GetString("user_id")), from, to.AddDate(0, 0, 1), loc)
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch todos")
+		return
+	}
+	now := time.Now()
+	selected := make(map[string]any, len(days))
+	for date, day := range days {
+		if !snoozed {
+			if day = withoutSnoozed(day, now); len(day) == 0 {
+				continue
+			}
+		}
+		if html {
+			renderDescriptions(day)
+		}
+		linkTodos(c, day)
+		embedTodos(c, day, embeds)
+		pinnedFirst(day)
+		selected[date] = selectFields(day, fields)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":   from.Format(time.DateOnly),
+		"to":     to.Format(time.DateOnly),
+		"days":   selected,
+		"_links": collectionLinks(c),
+	})
+}
+
+// GetWorkload sums the estimates of the open todos due on ?date= (today by
+// default) and compares them with the ?capacity= minutes available that day
+// (8 hours by default), so users can spot overcommitted days. Todos without
+// an estimate are counted separately, as they could take any time.
+func (h *TodoHandler) GetWorkload(c *gin.Context) {
+	date := c.Query("date")
+	if date != "" {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{Field: "date", Message: "must be a date (YYYY-MM-DD)"}})
+			return
+		}
+	}
+	capacity := defaultCapacityMinutes
+	if v := c.Query("capacity"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxCapacityMinutes {
+			apierrors.RespondValidation(c, []apierrors.FieldError{{
+				Field:   "capacity",
+				Message: "must be a number between 1 and " + strconv.Itoa(maxCapacityMinutes),
+			}})
+			return
+		}
+		capacity = n
+	}
+
+	todos, loc, ok := h.openTodos(c)
+	if !ok {
+		return
+	}
+
+	start := duedate.StartOfDay(time.Now().In(loc))
+	if date != "" {
+		start, _ = time.ParseInLocation(time.DateOnly, date, loc)
+	}
+	end := start.AddDate(0, 0, 1)
+	due := []models.Todo{}
+	estimated, unestimated := 0, 0
+	for _, todo := range todos {
+		if todo.DueDate == nil || todo.DueDate.Before(start) || !todo.DueDate.Before(end) {
+			continue
+		}
+		due = append(due, todo)
+		if todo.EstimatedMinutes == 0 {
+			unestimated++
+		}
+		estimated += todo.EstimatedMinutes
+	}
+	sortByUrgency(due)
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":              start.Format(time.DateOnly),
+		"estimated_minutes": estimated,
+		"capacity_minutes":  capacity,
+		"overcommitted":     estimated > capacity,
+		"unestimated":       unestimated,
+		"todos":             due,
+	})
+}
+
+// GetNearby returns the open todos located within ?radius= meters (500 by
+// default) of the point at ?lat= and ?lng=, nearest first, so location-aware
+// clients can bring up a todo when the user gets near where it's done. Each
+// todo carries its distance from the point.
+func (h *TodoHandler) GetNearby(c *gin.Context) {
+	center, radius, ok := nearbyQuery(c)
+	if !ok {
+		return
+	}
+	if err := authz.CanRead(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to fetch todos")
+		return
+	}
+	html, ok := wantsHTML(c)
+	if !ok {
+		return
+	}
+	snoozed, ok := includeSnoozed(c)
+	if !ok {
+		return
+	}
+	fields, ok := fieldSelection(c)
+	if !ok {
+		return
+	}
+	embeds, ok := includeEmbeds(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	todos, err := h.todos.ListNear(ctx, todoScope(c, c.GetString("user_id")), center, radius)
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch todos")
+		return
+	}
+
+	now := time.Now()
+	near := []models.Todo{}
+	for _, todo := range todos {
+		if todo.Completed || !snoozed && todo.Snoozed(now) {
+			continue
+		}
+		distance := math.Round(center.DistanceTo(todo.Location))
+		todo.Distance = &distance
+		near = append(near, todo)
+	}
+	if html {
+		renderDescriptions(near)
+	}
+	linkTodos(c, near)
+	embedTodos(c, near, embeds)
+
+	c.JSON(http.StatusOK, gin.H{"todos": selectFields(near, fields), "_links": collectionLinks(c)})
+}
+
+// nearbyQuery reads the point and radius of a nearby query. Invalid ones get
+// a validation error and ok is false.
+func nearbyQuery(c *gin.Context) (center *models.Location, radius float64, ok bool) {
+	var errs []apierrors.FieldError
+	lat, err := strconv.ParseFloat(c.Query("lat"), 64)
+	if err != nil || !(lat >= -90 && lat <= 90) {
+		errs = append(errs, apierrors.FieldError{Field: "lat", Message: "must be a latitude between -90 and 90"})
+	}
+	lng, err := strconv.ParseFloat(c.Query("lng"), 64)
+	if err != nil || !(lng >= -180 && lng <= 180) {
+		errs = append(errs, apierrors.FieldError{Field: "lng", Message: "must be a longitude between -180 and 180"})
+	}
+	radius = defaultNearbyRadius
+	if v := c.Query("radius"); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(r >= 1 && r <= maxNearbyRadius) {
+			errs = append(errs, apierrors.FieldError{Field: "radius", Message: fmt.Sprintf("must be a distance in meters between 1 and %d", maxNearbyRadius)})
+		}
+		radius = r
+	}
+	if len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return nil, 0, false
+	}
+	return models.NewLocation(lat, lng), radius, true
+}
+
+// dateRange reads the ?from= and ?to= dates, both included, as the start of
+// those days in loc. The range may cover at most maxDays days. An invalid
+// range gets a validation error and ok is false.
+func dateRange(c *gin.Context, loc *time.Location, maxDays int) (from, to time.Time, ok bool) {
+	var errs []apierrors.FieldError
+	from, err := time.ParseInLocation(time.DateOnly, c.Query("from"), loc)
+	if err != nil {
+		errs = append(errs, apierrors.FieldError{Field: "from", Message: "must be a date (YYYY-MM-DD)"})
+	}
+	to, err = time.ParseInLocation(time.DateOnly, c.Query("to"), loc)
+	if err != nil {
+		errs = append(errs, apierrors.FieldError{Field: "to", Message: "must be a date (YYYY-MM-DD)"})
+	}
+	if len(errs) == 0 {
+		switch days := int(to.Sub(from).Round(24*time.Hour)/(24*time.Hour)) + 1; {
+		case days < 1:
+			errs = append(errs, apierrors.FieldError{Field: "to", Message: "must not be before from"})
+		case days > maxDays:
+			errs = append(errs, apierrors.FieldError{Field: "to", Message: fmt.Sprintf("must be at most %d days after from", maxDays-1)})
+		}
+	}
+	if len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// openTodos lists the incomplete todos in the request's scope along with
+// the user's time zone, responding with an error and returning false if
+// they can't be read
+func (h *TodoHandler) openTodos(c *gin.Context) ([]models.Todo, *time.Location, bool) {
+	if err := authz.CanRead(scopeRole(c)); err != nil {
+		respondTodoError(c, err, "Failed to fetch todos")
+		return nil, nil, false
+	}
+	html, ok := wantsHTML(c)
+	if !ok {
+		return nil, nil, false
+	}
+	snoozed, ok := includeSnoozed(c)
+	if !ok {
+		return nil, nil, false
+	}
+	embeds, ok := includeEmbeds(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	loc, ok := h.settings.location(ctx, c)
+	if !ok {
+		return nil, nil, false
+	}
+	todos, err := h.todos.List(ctx, todoScope(c, c.GetString("user_id")))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch todos")
+		return nil, nil, false
+	}
+
+	now := time.Now()
+	open := todos[:0]
+	for _, todo := range todos {
+		if !todo.Completed && (snoozed || !todo.Snoozed(now)) {
+			open = append(open, todo)
+		}
+	}
+	if html {
+		renderDescriptions(open)
+	}
+	linkTodos(c, open)
+	embedTodos(c, open, embeds)
+	return open, loc, true
+}
+
+// sortByUrgency orders todos by priority, highest first, then by due date,
+// soonest first. Pinned todos come before all the others.
+func sortByUrgency(todos []models.Todo) {
+	sort.SliceStable(todos, func(i, j int) bool {
+		a, b := todos[i], todos[j]
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+		if a.Priority.Rank() != b.Priority.Rank() {
+			return a.Priority.Rank() > b.Priority.Rank()
+		}
+		if a.DueDate == nil || b.DueDate == nil {
+			return a.DueDate != nil
+		}
+		return a.DueDate.Before(*b.DueDate)
+	})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetCalendar(t *testing.T) {
+	due := func(title, at string) models.Todo {
+		todo := testTodo(title)
+		date, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		todo.DueDate = &date
+		return todo
+	}
+	later := time.Now().Add(time.Hour)
+	snoozed := due("Snoozed", "2026-10-19T09:00:00Z")
+	snoozed.SnoozedUntil = &later
+	pinned := due("Pinned", "2026-10-17T20:00:00Z")
+	pinned.Pinned = true
+	todos := newFakeTodos(
+		due("Morning", "2026-10-17T09:00:00Z"),
+		pinned,
+		due("Next day", "2026-10-18T00:00:00Z"),
+		snoozed,
+		due("Too late", "2026-10-21T00:00:00Z"),
+		testTodo("Undated"),
+	)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", testUser)
+	})
+	router.GET("/todos/calendar", newTestTodoHandler(todos).GetCalendar)
+
+	titles := func(target string) map[string][]string {
+		t.Helper()
+		status, body := serve(t, router, http.MethodGet, target, "")
+		if status != http.StatusOK {
+			t.Fatalf("GET %s = %d, want 200: %v", target, status, body)
+		}
+		days := map[string][]string{}
+		for date, day := range body["days"].(map[string]any) {
+			for _, todo := range day.([]any) {
+				days[date] = append(days[date], todo.(map[string]any)["title"].(string))
+			}
+		}
+		return days
+	}
+
+	days := titles("/todos/calendar?from=2026-10-17&to=2026-10-20")
+	want := map[string][]string{
+		"2026-10-17": {"Pinned", "Morning"},
+		"2026-10-18": {"Next day"},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("days = %v, want %v", days, want)
+	}
+	for date, titles := range want {
+		if !slices.Equal(days[date], titles) {
+			t.Errorf("%s = %v, want %v", date, days[date], titles)
+		}
+	}
+
+	if days := titles("/todos/calendar?from=2026-10-17&to=2026-10-20&snoozed=true"); len(days["2026-10-19"]) != 1 {
+		t.Errorf("with snoozed, days = %v, want the snoozed todo on 2026-10-19", days)
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// XML namespaces of the WebDAV and CalDAV properties served
+const (
+	nsDAV            = "DAV:"
+	nsCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	nsCalendarServer = "http://calendarserver.org/ns/"
+)
+
+// davPrefixes are the prefixes multistatus responses declare for the
+// namespaces above; property values use them
+var davPrefixes = map[string]string{nsDAV: "d", nsCalDAV: "c", nsCalendarServer: "cs"}
+
+// davProp is a property of a WebDAV resource, with its value as XML
+type davProp struct {
+	name  xml.Name
+	value string
+}
+
+// davResponse is one resource in a multistatus response. status is set for
+// resources that couldn't be found, which have no properties.
+type davResponse struct {
+	href    string
+	props   []davProp
+	missing []xml.Name
+	status  int
+}
+
+// xmlNode is an element of a request body, with its text and children
+type xmlNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	text     string
+	children []*xmlNode
+}
+
+// parseXML reads a request body into a tree of elements
+func parseXML(data []byte) (*xmlNode, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		token, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name, attrs: t.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("no XML element was sent")
+	}
+	return root, nil
+}
+
+// child returns the first child element with the given name, or nil
+func (n *xmlNode) child(space, local string) *xmlNode {
+	for _, c := range n.children {
+		if c.name.Space == space && c.name.Local == local {
+			return c
+		}
+	}
+	return nil
+}
+
+// attr returns the value of an attribute, or ""
+func (n *xmlNode) attr(local string) string {
+	for _, a := range n.attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// requestedProps returns the properties a PROPFIND or REPORT body asks for.
+// all is set when it asks for every property, or doesn't say.
+func requestedProps(root *xmlNode) (names []xml.Name, all bool) {
+	if root == nil {
+		return nil, true
+	}
+	prop := root.child(nsDAV, "prop")
+	if prop == nil {
+		return nil, true
+	}
+	for _, c := range prop.children {
+		names = append(names, c.name)
+	}
+	return names, false
+}
+
+// pickProps builds the response for a resource with the given properties,
+// listing the requested ones it lacks as missing. Properties in hidden are
+// only included when asked for by name, as calendar data is.
+func pickProps(href string, props []davProp, names []xml.Name, all bool, hidden ...xml.Name) davResponse {
+	r := davResponse{href: href}
+	if all {
+		for _, p := range props {
+			if !slices.Contains(hidden, p.name) {
+				r.props = append(r.props, p)
+			}
+		}
+		return r
+	}
+	for _, name := range names {
+		i := slices.IndexFunc(props, func(p davProp) bool { return p.name == name })
+		if i < 0 {
+			r.missing = append(r.missing, name)
+			continue
+		}
+		r.props = append(r.props, props[i])
+	}
+	return r
+}
+
+// respondMultistatus writes a 207 Multi-Status response
+func respondMultistatus(c *gin.Context, responses []davResponse) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="` + nsCalDAV + `" xmlns:cs="` + nsCalendarServer + `">`)
+	for _, r := range responses {
+		b.WriteString("<d:response><d:href>" + xmlText(r.href) + "</d:href>")
+		if r.status != 0 {
+			b.WriteString("<d:status>" + statusLine(r.status) + "</d:status>")
+		}
+		if len(r.props) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, p := range r.props {
+				b.WriteString(davElement(p.name, p.value))
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusOK) + "</d:status></d:propstat>")
+		}
+		if len(r.missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range r.missing {
+				b.WriteString(davElement(name, ""))
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusNotFound) + "</d:status></d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>")
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", []byte(b.String()))
+}
+
+// davElement writes an element holding value, which is XML already.
+// Elements of namespaces without a declared prefix declare their own.
+func davElement(name xml.Name, value string) string {
+	tag, attrs := name.Local, ""
+	if prefix, ok := davPrefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		attrs = ` xmlns="` + xmlText(name.Space) + `"`
+	}
+	if value == "" {
+		return "<" + tag + attrs + "/>"
+	}
+	return "<" + tag + attrs + ">" + value + "</" + tag + ">"
+}
+
+// hrefValue is the value of properties that point at a resource
+func hrefValue(href string) string {
+	return "<d:href>" + xmlText(href) + "</d:href>"
+}
+
+// xmlText escapes text for use in XML
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func statusLine(status int) string {
+	return "HTTP/1.1 " + strconv.Itoa(status) + " " + http.StatusText(status)
+}
@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkspaceHandler serves the workspace and membership endpoints
+type WorkspaceHandler struct {
+	workspaces repository.WorkspaceRepository
+	todos      repository.TodoRepository
+	fields     repository.CustomFieldRepository
+	filters    repository.SavedFilterRepository
+	revisions  repository.RevisionRepository
+	tombstones repository.TombstoneRepository
+	tx         repository.Transactor
+	quotas     *QuotaHandler
+	timeout    time.Duration
+}
+
+// NewWorkspaceHandler creates a WorkspaceHandler. todos, fields, filters,
+// revisions and tombstones are needed to delete a workspace's todos, custom
+// fields, saved filters, revision history and deletion records along with
+// it, in one transaction where tx supports them; quotas caps how many
+// workspaces a user can own.
+func NewWorkspaceHandler(workspaces repository.WorkspaceRepository, todos repository.TodoRepository, fields repository.CustomFieldRepository, filters repository.SavedFilterRepository, revisions repository.RevisionRepository, tombstones repository.TombstoneRepository, tx repository.Transactor, quotas *QuotaHandler, timeout time.Duration) *WorkspaceHandler {
+	return &WorkspaceHandler{workspaces: workspaces, todos: todos, fields: fields, filters: filters, revisions: revisions, tombstones: tombstones, tx: tx, quotas: quotas, timeout: timeout}
+}
+
+// RequireMember loads the workspace named by the :workspace_id parameter,
+// along with the user's role in it, and rejects users who don't belong to
+// it. Non-members get a 404 so workspace IDs can't be probed.
+func (h *WorkspaceHandler) RequireMember(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("workspace_id"))
+	if err != nil {
+		apierrors.Abort(c, apierrors.CodeInvalidID, "Invalid workspace ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	workspace, err := h.workspaces.Get(ctx, id)
+	var role authz.Role
+	if err == nil {
+		role = workspace.RoleOf(c.GetString("user_id"))
+		err = authz.CanRead(role)
+	}
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, authz.ErrNotFound) {
+		apierrors.Abort(c, apierrors.CodeWorkspaceNotFound, "Workspace not found")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch workspace")
+		c.Abort()
+		return
+	}
+
+	c.Set("workspace", workspace)
+	c.Set("workspace_id", workspace.ID)
+	c.Set("role", role)
+	c.Next()
+}
+
+// ListWorkspaces lists the workspaces the user belongs to
+func (h *WorkspaceHandler) ListWorkspaces(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	workspaces, err := h.workspaces.ListByMember(ctx, c.GetString("user_id"))
+	if err != nil {
+		respondStorageError(c, err, "Failed to fetch workspaces")
+		return
+	}
+	if workspaces == nil {
+		workspaces = []models.Workspace{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workspaces": workspaces})
+}
+
+// CreateWorkspace creates a workspace owned by the user, who becomes its
+// first member
+func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
+	var req models.CreateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	userID := c.GetString("user_id")
+	now := time.Now()
+	workspace := models.Workspace{
+		Name:      req.Name,
+		Color:     req.Color,
+		OwnerID:   userID,
+		Members:   []models.Member{{UserID: userID, Role: authz.RoleOwner, JoinedAt: now}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	u, ok := h.quotas.allowWorkspace(ctx, c, userID)
+	if !ok {
+		return
+	}
+	if err := h.workspaces.Create(ctx, &workspace); err != nil {
+		respondStorageError(c, err, "Failed to create workspace")
+		return
+	}
+
+	response := gin.H{"workspace": workspace}
+	if warnings := h.quotas.workspaceWarnings(c, u.add(1)); warnings != nil {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetWorkspace returns the workspace loaded by RequireMember
+func (h *WorkspaceHandler) GetWorkspace(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"workspace": c.MustGet("workspace")})
+}
+
+// UpdateWorkspace renames or recolors the workspace. Owners and admins may
+// do this.
+func (h *WorkspaceHandler) UpdateWorkspace(c *gin.Context) {
+	workspace := c.MustGet("workspace").(*models.Workspace)
+	if err := authz.Authorize(scopeRole(c), authz.ActionManageWorkspace); err != nil {
+		apierrors.Respond(c, apierrors.CodeForbidden, "Only owners and admins can change the workspace")
+		return
+	}
+
+	var req models.UpdateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	if req.Name != nil {
+		workspace.Name = *req.Name
+	}
+	if req.Color != nil {
+		workspace.Color = *req.Color
+	}
+	workspace.UpdatedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	err := h.workspaces.Update(ctx, workspace)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodeWorkspaceNotFound, "Workspace not found")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to update workspace")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workspace": workspace})
+}
+
+// DeleteWorkspace deletes the workspace with all of its todos, custom fields
+// and saved filters. Only the owner may do this. ?dry_run=true reports what
+// would be deleted instead.
+func (h *WorkspaceHandler) DeleteWorkspace(c *gin.Context) {
+	workspace := c.MustGet("workspace").(*models.Workspace)
+	if err := authz.Authorize(scopeRole(c), authz.ActionDelete); err != nil {
+		apierrors.Respond(c, apierrors.CodeForbidden, "Only the workspace owner can delete it")
+		return
+	}
+	dryRun, ok := isDryRun(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	scope := repository.Workspace(workspace.ID)
+	if dryRun {
+		h.previewDelete(ctx, c, scope)
+		return
+	}
+	err := h.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		// Without transactions, deleting the todos first means a failure
+		// leaves the workspace in place to retry, rather than orphaning its
+		// todos
+		if _, err := h.todos.DeleteAll(ctx, scope); err != nil {
+			return err
+		}
+		if err := h.fields.DeleteAll(ctx, scope); err != nil {
+			return err
+		}
+		if err := h.filters.DeleteAll(ctx, scope); err != nil {
+			return err
+		}
+		if err := h.revisions.DeleteAll(ctx, scope); err != nil {
+			return err
+		}
+		if err := h.tombstones.DeleteAll(ctx, scope); err != nil {
+			return err
+		}
+		if err := h.workspaces.Delete(ctx, workspace.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		respondStorageError(c, err, "Failed to delete workspace")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workspace deleted successfully"})
+}
+
+// previewDelete responds with what deleting the workspace would remove
+func (h *WorkspaceHandler) previewDelete(ctx context.Context, c *gin.Context, scope repository.Scope) {
+	todos, err := h.todos.List(ctx, scope)
+	var fields []models.CustomField
+	if err == nil {
+		fields, err = h.fields.List(ctx, scope)
+	}
+	var filters []models.SavedFilter
+	if err == nil {
+		filters, err = h.filters.List(ctx, scope)
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to delete workspace")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run":               true,
+		"todos_deleted":         len(todos),
+		"custom_fields_deleted": len(fields),
+		"filters_deleted":       len(filters),
+		"sample_todo_ids":       sampleTodoIDs(todos),
+	})
+}
+
+// AddMember adds a user to the workspace. Owners and admins may invite
+// members, with a role below their own.
+func (h *WorkspaceHandler) AddMember(c *gin.Context) {
+	workspace := c.MustGet("workspace").(*models.Workspace)
+	role := scopeRole(c)
+	if err := authz.Authorize(role, authz.ActionManageMembers); err != nil {
+		apierrors.Respond(c, apierrors.CodeForbidden, "Only owners and admins can add members")
+		return
+	}
+
+	var req models.AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Normalize()
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+	if !role.Outranks(req.Role) {
+		apierrors.Respond(c, apierrors.CodeForbidden, "You can only grant roles below your own")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	member := models.Member{UserID: req.UserID, Role: req.Role, JoinedAt: time.Now()}
+	err := h.workspaces.AddMember(ctx, workspace.ID, member)
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierrors.Respond(c, apierrors.CodeMemberExists, "User is already a member of this workspace")
+		return
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodeWorkspaceNotFound, "Workspace not found")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to add member")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"member": member})
+}
+
+// UpdateMember changes a member's role. Owners and admins may change the
+// roles of members below them, to a role below their own.
+func (h *WorkspaceHandler) UpdateMember(c *gin.Context) {
+	workspace := c.MustGet("workspace").(*models.Workspace)
+	role := scopeRole(c)
+	target := c.Param("user_id")
+	if err := authz.Authorize(role, authz.ActionManageMembers); err != nil {
+		apierrors.Respond(c, apierrors.CodeForbidden, "Only owners and admins can change roles")
+		return
+	}
+
+	var req models.UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		apierrors.RespondValidation(c, errs)
+		return
+	}
+
+	targetRole := workspace.RoleOf(target)
+	switch {
+	case targetRole == "":
+		apierrors.Respond(c, apierrors.CodeMemberNotFound, "User is not a member of this workspace")
+		return
+	case !role.Outranks(targetRole) || !role.Outranks(req.Role):
+		apierrors.Respond(c, apierrors.CodeForbidden, "You can only change roles below your own")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	err := h.workspaces.SetMemberRole(ctx, workspace.ID, target, req.Role)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodeMemberNotFound, "User is not a member of this workspace")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to update member")
+		return
+	}
+
+	member := models.Member{UserID: target, Role: req.Role}
+	for _, m := range workspace.Members {
+		if m.UserID == target {
+			member.JoinedAt = m.JoinedAt
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"member": member})
+}
+
+// RemoveMember removes a user from the workspace. Owners and admins can
+// remove members below them; anyone but the owner can remove themselves,
+// i.e. leave.
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	workspace := c.MustGet("workspace").(*models.Workspace)
+	role := scopeRole(c)
+	target := c.Param("user_id")
+
+	if target == c.GetString("user_id") {
+		if role == authz.RoleOwner {
+			apierrors.Respond(c, apierrors.CodeForbidden, "The owner can't leave the workspace; delete it instead")
+			return
+		}
+	} else {
+		if err := authz.Authorize(role, authz.ActionManageMembers); err != nil {
+			apierrors.Respond(c, apierrors.CodeForbidden, "Only owners and admins can remove other members")
+			return
+		}
+		targetRole := workspace.RoleOf(target)
+		if targetRole == "" {
+			apierrors.Respond(c, apierrors.CodeMemberNotFound, "User is not a member of this workspace")
+			return
+		}
+		if !role.Outranks(targetRole) {
+			apierrors.Respond(c, apierrors.CodeForbidden, "You can only remove members below your own role")
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	if err := unassignMember(ctx, h.todos, workspace.ID, target); err != nil {
+		respondStorageError(c, err, "Failed to remove member")
+		return
+	}
+	err := h.workspaces.RemoveMember(ctx, workspace.ID, target)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierrors.Respond(c, apierrors.CodeMemberNotFound, "User is not a member of this workspace")
+		return
+	}
+	if err != nil {
+		respondStorageError(c, err, "Failed to remove member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
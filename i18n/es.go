@@ -0,0 +1,342 @@
+package i18n
+
+// spanish is the Spanish catalogue
+var spanish = catalogue{
+	titles: map[string]string{
+		"INVALID_REQUEST":          "Solicitud mal formada",
+		"VALIDATION_FAILED":        "Error de validación",
+		"INVALID_ID":               "ID no válido",
+		"UNAUTHENTICATED":          "No autenticado",
+		"CSRF_TOKEN_INVALID":       "Token CSRF ausente o no válido",
+		"TODO_NOT_FOUND":           "Tarea no encontrada",
+		"TODO_BLOCKED":             "Tarea bloqueada",
+		"TODO_CONFLICT":            "Tarea modificada simultáneamente",
+		"CHECKLIST_INCOMPLETE":     "Lista de verificación incompleta",
+		"CHECKLIST_ITEM_NOT_FOUND": "Elemento de la lista de verificación no encontrado",
+		"SESSION_NOT_FOUND":        "Sesión no encontrada",
+		"WORKSPACE_NOT_FOUND":      "Espacio de trabajo no encontrado",
+		"MEMBER_NOT_FOUND":         "Miembro no encontrado",
+		"MEMBER_EXISTS":            "Ya es miembro",
+		"SHARE_NOT_FOUND":          "Compartición no encontrada",
+		"PUBLIC_LINK_NOT_FOUND":    "Enlace público no encontrado",
+		"FEED_NOT_FOUND":           "Feed no encontrado",
+		"IMPORT_SOURCE_NOT_FOUND":  "Origen de importación no encontrado",
+		"CUSTOM_FIELD_NOT_FOUND":   "Campo personalizado no encontrado",
+		"SAVED_FILTER_NOT_FOUND":   "Filtro guardado no encontrado",
+		"UNDO_NOT_FOUND":           "Token de deshacer no encontrado",
+		"REVISION_NOT_FOUND":       "Revisión no encontrada",
+		"SYNC_TOKEN_EXPIRED":       "Token de sincronización caducado",
+		"SLACK_LINK_NOT_FOUND":     "Vínculo de Slack no encontrado",
+		"SCHEMA_NOT_FOUND":         "Esquema no encontrado",
+		"TENANT_NOT_FOUND":         "Organización no encontrada",
+		"FORBIDDEN":                "Prohibido",
+		"QUOTA_EXCEEDED":           "Cuota superada",
+		"ROUTE_NOT_FOUND":          "Ruta no encontrada",
+		"METHOD_NOT_ALLOWED":       "Método no permitido",
+		"PAYLOAD_TOO_LARGE":        "Cuerpo de la solicitud demasiado grande",
+		"UNSUPPORTED_MEDIA_TYPE":   "Tipo de contenido no admitido",
+		"PRECONDITION_FAILED":      "Condición previa no cumplida",
+		"RATE_LIMITED":             "Demasiadas solicitudes",
+		"INTERNAL_ERROR":           "Error interno del servidor",
+		"STORAGE_UNAVAILABLE":      "Almacenamiento no disponible temporalmente",
+		"STORAGE_THROTTLED":        "Almacenamiento limitado",
+		"TIMEOUT":                  "La solicitud excedió el tiempo de espera",
+		"AUTH_UNAVAILABLE":         "Autenticación no disponible temporalmente",
+		"IMPORT_FAILED":            "La importación falló",
+		"OVERLOADED":               "Servidor ocupado",
+	},
+	messages: map[string]string{
+		// Details
+		"fields_invalid":                         "Uno o más campos no son válidos",
+		"user_not_authenticated":                 "Usuario no autenticado",
+		"bearer_token_required":                  "Se requiere un token bearer",
+		"admin_token_required":                   "Se requiere un token de administrador válido",
+		"bearer_token_invalid":                   "El token bearer no es válido o ha caducado",
+		"bearer_token_without_oid":               "El token bearer no tiene el claim oid",
+		"token_validation_unavailable":           "No se puede validar el token en este momento; inténtalo de nuevo",
+		"session_verification_failed":            "No se pudo verificar la sesión; inténtalo de nuevo",
+		"session_start_failed":                   "No se pudo iniciar la sesión; inténtalo de nuevo",
+		"service_unavailable":                    "Servicio no disponible temporalmente; inténtalo de nuevo",
+		"database_throttled":                     "Demasiadas solicitudes a la base de datos; inténtalo de nuevo en breve",
+		"server_busy":                            "El servidor está ocupado; inténtalo de nuevo en breve",
+		"too_many_requests_in_progress":          "Tienes demasiadas solicitudes en curso; espera a que terminen",
+		"unexpected_error":                       "Se produjo un error inesperado",
+		"todo_kept_changing":                     "La tarea siguió cambiando mientras se guardaba; inténtalo de nuevo",
+		"todo_changed_during_update":             "La tarea cambió mientras se actualizaba; obtenla de nuevo y vuelve a intentarlo",
+		"concurrent_dependency_cycle":            "Una dependencia añadida al mismo tiempo habría formado un ciclo; inténtalo de nuevo",
+		"body_not_form":                          "El cuerpo de la solicitud debe ser un formulario",
+		"body_not_json":                          "El cuerpo de la solicitud debe ser JSON válido",
+		"body_not_sent_as_json":                  "El cuerpo de la solicitud debe enviarse como application/json",
+		"body_too_large":                         "El cuerpo de la solicitud no debe superar {1} bytes",
+		"body_not_json_object":                   "El cuerpo de la solicitud debe ser un objeto JSON",
+		"body_unreadable":                        "No se pudo leer el cuerpo de la solicitud",
+		"schema_unknown":                         "Ningún esquema tiene ese nombre",
+		"request_deadline_exceeded":              "La solicitud no se completó en {1}",
+		"route_unknown":                          "Ninguna ruta coincide con {1}",
+		"method_not_allowed":                     "{1} no está permitido en {2}",
+		"csrf_header_invalid":                    "Falta la cabecera {1} o no es válida; obtén una con GET /api/v1/csrf-token",
+		"rate_limited":                           "Se permiten como máximo {1} solicitudes cada {2}; inténtalo de nuevo en {3} segundos",
+		"session_rate_limited":                   "Demasiadas sesiones nuevas desde tu dirección; inténtalo de nuevo en {1} segundos",
+		"address_blocked":                        "No se permiten solicitudes desde tu dirección",
+		"tenant_missing":                         "Indica la organización en la solicitud",
+		"tenant_unknown":                         "Esta organización no se atiende aquí",
+		"failed_fetch_notification_preferences":  "No se pudieron obtener las preferencias de notificación",
+		"failed_update_notification_preferences": "No se pudieron actualizar las preferencias de notificación",
+		"failed_fetch_preferences":               "No se pudieron obtener las preferencias",
+		"failed_update_preferences":              "No se pudieron actualizar las preferencias",
+
+		"invalid_todo_id":           "ID de tarea no válido",
+		"invalid_share_id":          "ID de compartición no válido",
+		"invalid_workspace_id":      "ID de espacio de trabajo no válido",
+		"invalid_custom_field_id":   "ID de campo personalizado no válido",
+		"invalid_filter_id":         "ID de filtro no válido",
+		"invalid_revision_number":   "Número de revisión no válido",
+		"invalid_attachment_id":     "ID de adjunto no válido",
+		"invalid_checklist_item_id": "ID de elemento de la lista de verificación no válido",
+		"invalid_comment_id":        "ID de comentario no válido",
+
+		"todo_not_found":           "Tarea no encontrada",
+		"checklist_item_not_found": "Elemento de la lista de verificación no encontrado",
+		"session_not_found":        "Sesión no encontrada",
+		"workspace_not_found":      "Espacio de trabajo no encontrado",
+		"share_not_found":          "Compartición no encontrada",
+		"custom_field_not_found":   "Campo personalizado no encontrado",
+		"saved_filter_not_found":   "Filtro guardado no encontrado",
+		"revision_not_found":       "Revisión no encontrada",
+		"request_timed_out":        "La solicitud excedió el tiempo de espera",
+
+		"public_link_unknown":          "Este enlace no existe o fue revocado",
+		"todo_without_public_link":     "Esta tarea no tiene enlace público",
+		"undo_token_unknown":           "Este token de deshacer no existe o ha caducado",
+		"todo_id_taken":                "Este ID ya está en uso; crea la tarea con uno nuevo",
+		"sync_since_too_old":           "Ya no se conocen las eliminaciones tan antiguas; sincroniza de nuevo sin since",
+		"export_since_too_old":         "Ya no se conocen las eliminaciones tan antiguas; exporta de nuevo sin since",
+		"invalid_request_signature":    "Firma de la solicitud no válida",
+		"request_replayed":             "Esta solicitud ya se recibió",
+		"failed_check_request":         "No se pudo comprobar la solicitud",
+		"slack_not_linked":             "Slack no está vinculado",
+		"slack_not_linked_hint":        "Slack no está vinculado; crea un código de vinculación y ejecuta en Slack el comando que lo acompaña",
+		"complete_blockers_first":      "Completa primero las tareas de las que depende esta: {1}",
+		"complete_checklist_first":     "Completa primero los elementos pendientes de la lista de verificación: {1}",
+		"todo_role_forbids":            "Tu rol en esta tarea no lo permite",
+		"already_member":               "El usuario ya es miembro de este espacio de trabajo",
+		"not_member":                   "El usuario no es miembro de este espacio de trabajo",
+		"admins_add_members":           "Solo los propietarios y administradores pueden añadir miembros",
+		"admins_change_roles":          "Solo los propietarios y administradores pueden cambiar roles",
+		"admins_remove_members":        "Solo los propietarios y administradores pueden quitar a otros miembros",
+		"admins_change_workspace":      "Solo los propietarios y administradores pueden cambiar el espacio de trabajo",
+		"admins_change_custom_fields":  "Solo los propietarios y administradores pueden cambiar los campos personalizados",
+		"viewers_change_saved_filters": "Los lectores no pueden cambiar los filtros guardados",
+		"owner_deletes_workspace":      "Solo el propietario del espacio de trabajo puede eliminarlo",
+		"owner_cant_leave":             "El propietario no puede abandonar el espacio de trabajo; elimínalo en su lugar",
+		"grant_lower_roles":            "Solo puedes otorgar roles inferiores al tuyo",
+		"change_lower_roles":           "Solo puedes cambiar roles inferiores al tuyo",
+		"remove_lower_members":         "Solo puedes quitar miembros con un rol inferior al tuyo",
+
+		"viewers_import":             "Los lectores no pueden importar tareas",
+		"undo_role_forbids":          "Tu rol en el espacio de trabajo ya no permite restaurar estas tareas",
+		"comment_delete_forbidden":   "Solo el autor, los administradores y los propietarios pueden eliminar un comentario",
+		"feed_unknown":               "Este feed no existe o fue revocado",
+		"retention_off":              "La retención está desactivada",
+		"retention_rules_missing":    "No hay reglas de retención configuradas",
+		"app_password_missing":       "Se requieren un nombre de usuario y una contraseña de aplicación",
+		"app_password_invalid":       "El nombre de usuario o la contraseña de aplicación son incorrectos, o la contraseña fue revocada",
+		"caldav_report_unsupported":  "Solo se admiten los informes calendar-query y calendar-multiget",
+		"caldav_name_invalid":        "Los nombres de recurso de las tareas deben terminar en .ics y tener como máximo 255 bytes",
+		"caldav_not_vtodo":           "El calendario solo contiene tareas; envía un VTODO",
+		"todo_changed_since_fetched": "La tarea cambió o se eliminó desde que se obtuvo",
+		"caldav_name_taken":          "Ya existe una tarea con este nombre",
+		"caldav_report_missing":      "El cuerpo de la solicitud debe indicar un informe",
+		"body_not_xml":               "El cuerpo de la solicitud debe ser XML válido",
+
+		"todo_quota_reached":           "Has alcanzado el límite de {1} tareas; elimina algunas para añadir más",
+		"workspace_todo_quota_reached": "Este espacio de trabajo ha alcanzado el límite de {1} tareas; elimina algunas para añadir más",
+		"workspace_quota_reached":      "Ya tienes el máximo de {1} espacios de trabajo; elimina uno para crear otro",
+		"todo_quota_warning":           "Tienes {1} de las {2} tareas permitidas",
+		"workspace_todo_quota_warning": "Este espacio de trabajo tiene {1} de las {2} tareas permitidas",
+		"workspace_quota_warning":      "Tienes {1} de los {2} espacios de trabajo permitidos",
+		"custom_field_limit_reached":   "Puede haber como máximo {1} campos personalizados; elimina uno para añadir otro",
+		"saved_filter_limit_reached":   "Puede haber como máximo {1} filtros guardados; elimina uno para añadir otro",
+
+		"failed_add_dependency":           "No se pudo añadir la dependencia",
+		"failed_add_member":               "No se pudo añadir el miembro",
+		"failed_check_todo_quota":         "No se pudo comprobar la cuota de tareas",
+		"failed_check_workspace_quota":    "No se pudo comprobar la cuota de espacios de trabajo",
+		"failed_count_todos":              "No se pudieron contar las tareas",
+		"failed_count_users":              "No se pudieron contar los usuarios",
+		"failed_clone_todo":               "No se pudo clonar la tarea",
+		"failed_create_custom_field":      "No se pudo crear el campo personalizado",
+		"failed_create_public_link":       "No se pudo crear el enlace público",
+		"failed_create_link_code":         "No se pudo crear el código de vinculación",
+		"failed_create_todo":              "No se pudo crear la tarea",
+		"failed_create_workspace":         "No se pudo crear el espacio de trabajo",
+		"failed_delete_custom_field":      "No se pudo eliminar el campo personalizado",
+		"failed_delete_saved_filter":      "No se pudo eliminar el filtro guardado",
+		"failed_delete_todo":              "No se pudo eliminar la tarea",
+		"failed_delete_workspace":         "No se pudo eliminar el espacio de trabajo",
+		"failed_export_changes":           "No se pudieron exportar los cambios",
+		"failed_fetch_custom_fields":      "No se pudieron obtener los campos personalizados",
+		"failed_fetch_revisions":          "No se pudieron obtener las revisiones",
+		"failed_fetch_saved_filters":      "No se pudieron obtener los filtros guardados",
+		"failed_fetch_saved_filter":       "No se pudo obtener el filtro guardado",
+		"failed_fetch_sessions":           "No se pudieron obtener las sesiones",
+		"failed_fetch_settings":           "No se pudo obtener la configuración",
+		"failed_fetch_shared_todos":       "No se pudieron obtener las tareas compartidas",
+		"failed_fetch_shares":             "No se pudieron obtener las comparticiones",
+		"failed_fetch_slack_link":         "No se pudo obtener el vínculo de Slack",
+		"failed_fetch_audit_entries":      "No se pudieron obtener las entradas de auditoría",
+		"failed_fetch_stats":              "No se pudieron obtener las estadísticas",
+		"failed_fetch_time_report":        "No se pudo obtener el informe de tiempo",
+		"failed_fetch_todo":               "No se pudo obtener la tarea",
+		"failed_fetch_todos":              "No se pudieron obtener las tareas",
+		"failed_fetch_usage":              "No se pudo obtener el uso",
+		"failed_fetch_users":              "No se pudieron obtener los usuarios",
+		"failed_fetch_workspace":          "No se pudo obtener el espacio de trabajo",
+		"failed_fetch_workspaces":         "No se pudieron obtener los espacios de trabajo",
+		"failed_log_out":                  "No se pudo cerrar la sesión",
+		"failed_pin_todo":                 "No se pudo fijar la tarea",
+		"failed_unpin_todo":               "No se pudo desfijar la tarea",
+		"failed_purge_user":               "No se pudo purgar el usuario",
+		"failed_remove_dependency":        "No se pudo quitar la dependencia",
+		"failed_remove_member":            "No se pudo quitar el miembro",
+		"failed_revert_todo":              "No se pudo revertir la tarea",
+		"failed_revoke_public_link":       "No se pudo revocar el enlace público",
+		"failed_revoke_session":           "No se pudo revocar la sesión",
+		"failed_revoke_share":             "No se pudo revocar la compartición",
+		"failed_save_filter":              "No se pudo guardar el filtro",
+		"failed_share_todo":               "No se pudo compartir la tarea",
+		"failed_snooze_todo":              "No se pudo posponer la tarea",
+		"failed_start_timer":              "No se pudo iniciar el temporizador",
+		"failed_stop_timer":               "No se pudo detener el temporizador",
+		"failed_sync_changes":             "No se pudieron sincronizar los cambios",
+		"failed_sync_todos":               "No se pudieron sincronizar las tareas",
+		"failed_undo":                     "No se pudo deshacer",
+		"failed_unlink_slack":             "No se pudo desvincular Slack",
+		"failed_unsnooze_todo":            "No se pudo reactivar la tarea",
+		"failed_update_member":            "No se pudo actualizar el miembro",
+		"failed_update_saved_filter":      "No se pudo actualizar el filtro guardado",
+		"failed_update_workspace":         "No se pudo actualizar el espacio de trabajo",
+		"failed_update_settings":          "No se pudo actualizar la configuración",
+		"failed_update_todo":              "No se pudo actualizar la tarea",
+		"failed_export_user":              "No se pudo exportar el usuario",
+		"failed_import_user":              "No se pudo importar el usuario",
+		"failed_create_indexes":           "No se pudieron crear los índices",
+		"failed_purge_inactive_users":     "No se pudieron purgar los usuarios inactivos",
+		"failed_evaluate_retention_rules": "No se pudieron evaluar las reglas de retención",
+		"failed_assign_todo":              "No se pudo asignar la tarea",
+		"failed_fetch_assigned_todos":     "No se pudieron obtener las tareas asignadas",
+		"failed_attach_link":              "No se pudo adjuntar el enlace",
+		"failed_remove_link":              "No se pudo quitar el enlace",
+		"failed_create_app_password":      "No se pudo crear la contraseña de aplicación",
+		"failed_add_checklist_item":       "No se pudo añadir el elemento de la lista de verificación",
+		"failed_update_checklist_item":    "No se pudo actualizar el elemento de la lista de verificación",
+		"failed_remove_checklist_item":    "No se pudo quitar el elemento de la lista de verificación",
+		"failed_fetch_comments":           "No se pudieron obtener los comentarios",
+		"failed_add_comment":              "No se pudo añadir el comentario",
+		"failed_delete_comment":           "No se pudo eliminar el comentario",
+		"failed_fetch_mentions":           "No se pudieron obtener las menciones",
+		"failed_export_todos":             "No se pudieron exportar las tareas",
+		"failed_create_feed":              "No se pudo crear el feed",
+		"failed_fetch_feed":               "No se pudo obtener el feed",
+		"failed_render_feed":              "No se pudo generar el feed",
+		"failed_start_import":             "No se pudo iniciar la importación",
+		"failed_import_todos":             "No se pudieron importar las tareas",
+		"failed_fetch_tags":               "No se pudieron obtener las etiquetas",
+		"failed_rename_tag":               "No se pudo renombrar la etiqueta",
+		"failed_merge_tags":               "No se pudieron combinar las etiquetas",
+		"failed_delete_tag":               "No se pudo eliminar la etiqueta",
+
+		// Field messages
+		"empty":                    "no debe estar vacío",
+		"blank":                    "no debe estar vacío ni contener solo espacios",
+		"not_email":                "debe ser una dirección de correo válida",
+		"not_todo_id":              "debe ser un ID de tarea",
+		"not_other_todo":           "debe ser otra tarea de la misma lista",
+		"too_many_blockers":        "no se puede añadir; una tarea puede depender de como máximo {1} otras",
+		"dependency_cycle":         "ya depende de esta tarea; la dependencia formaría un ciclo",
+		"not_date":                 "debe ser una fecha (AAAA-MM-DD)",
+		"not_date_or_timestamp":    "debe ser una fecha (AAAA-MM-DD) o una marca de tiempo RFC 3339",
+		"year_out_of_range":        "debe estar entre los años 0000 y 9999 en UTC",
+		"before_from":              "no debe ser anterior a from",
+		"sent_with_due_date":       "no debe enviarse junto con due_date",
+		"share_with_self":          "no puedes compartir una tarea contigo mismo",
+		"email_or_user_id":         "se requiere exactamente uno de email o user_id",
+		"not_time_zone":            `debe ser un nombre de zona horaria IANA como "Europe/Madrid"`,
+		"not_time_of_day":          `debe ser una hora en formato de 24 horas como "23:30", o ""`,
+		"not_weekday":              `debe ser un día de la semana como "monday"`,
+		"due_not_understood":       `no es una fecha que entendamos; prueba p. ej. "tomorrow 5pm", "next friday" o "in 3 days"`,
+		"duration_or_until":        "se requiere exactamente uno de duration o until",
+		"sent_with_duration":       "no debe enviarse junto con duration",
+		"not_snooze_duration":      `debe ser una duración positiva de como máximo 365 días, como "90m", "48h" o "3d"`,
+		"not_snooze_time":          "debe estar en el futuro y a como máximo 365 días",
+		"not_color":                `debe ser un color hexadecimal como "#1e90ff" o uno de {1}`,
+		"too_long":                 "debe tener como máximo {1} caracteres",
+		"too_far_ahead":            "debe estar como máximo a {1} años vista",
+		"too_long_after_from":      "debe ser como máximo {1} días después de from",
+		"not_number_up_to":         "debe ser un número entre 1 y {1}",
+		"not_status":               "debe ser un código de estado como 404 o una clase como 4xx",
+		"not_timestamp":            "debe ser una hora RFC 3339 como 2026-10-16T09:30:00Z",
+		"not_cursor":               "debe ser el next_before de una página anterior",
+		"days_out_of_range":        "debe estar entre 0 y {1} días",
+		"minutes_out_of_range":     "debe estar entre 0 y {1} minutos",
+		"not_one_of_1":             "debe ser {1}",
+		"not_one_of_2":             "debe ser {1} o {2}",
+		"not_one_of_3":             "debe ser {1}, {2} o {3}",
+		"not_one_of_4":             "debe ser {1}, {2}, {3} o {4}",
+		"not_string":               "debe ser una cadena de texto",
+		"not_number":               "debe ser un número",
+		"not_integer":              "debe ser un número entero",
+		"not_boolean":              "debe ser true o false",
+		"not_object":               "debe ser un objeto",
+		"not_array":                "debe ser una lista",
+		"pattern_mismatch":         "debe coincidir con el patrón {1}",
+		"too_short":                "debe tener al menos {1} caracteres",
+		"out_of_range":             "debe estar entre {1} y {2}",
+		"too_small":                "debe ser como mínimo {1}",
+		"too_large":                "debe ser como máximo {1}",
+		"not_one_of":               "debe ser uno de {1}",
+		"no_notification_channels": "debe indicar los canales de al menos un tipo de notificación",
+		"not_notification_channel": "{1} no es un canal por el que se puedan enviar notificaciones",
+		"too_many_entries":         "debe tener como máximo {1} elementos",
+		"too_few_entries":          "debe tener al menos {1} elementos",
+		"repeated_option":          "no debe repetir una opción anterior",
+		"options_without_select":   "solo se permiten en campos de selección",
+		"custom_field_name_taken":  "ya lo usa otro campo personalizado",
+		"not_custom_field":         "no es un campo personalizado",
+		"required_for_digests":     "es obligatorio para recibir resúmenes",
+		"due_not_synced":           "no se admite al sincronizar; envía due_date",
+		"required":                 "es obligatorio",
+		"not_sync_token":           "no es un token de sincronización",
+		"unknown_field":            "no es un campo que entendamos",
+		"not_type":                 "debe ser de tipo {1}",
+		"not_id":                   "debe ser un ID",
+		"rule_failed":              "no cumple la regla {1}",
+		"not_one_of_2_or_empty":    "debe ser {1}, {2} o vacío",
+		"too_many_tags":            "debe tener como máximo {1} etiquetas",
+		"not_tag":                  "debe contener letras, dígitos, '_', '.', ':' o '-', empezando por una letra o un dígito",
+		"tag_too_long":             "{1} debe tener como máximo {2} caracteres",
+		"tag_not_tag":              "{1} debe contener letras, dígitos, '_', '.', ':' o '-', empezando por una letra o un dígito",
+		"not_geojson_point":        `debe ser un punto GeoJSON como {"type": "Point", "coordinates": [-0.1276, 51.5072]}, con la longitud primero`,
+		"not_http_url":             "debe ser una URL http o https absoluta",
+		"url_with_credentials":     "no debe incluir un nombre de usuario ni una contraseña",
+		"too_many_attachments":     "no se puede adjuntar; una tarea puede tener como máximo {1} enlaces",
+		"too_many_checklist_items": "no se puede añadir; una lista de verificación tiene como máximo {1} elementos",
+		"too_many_comments":        "no se puede añadir; una tarea guarda como máximo {1} comentarios",
+		"too_many_mentions":        "debe mencionar como máximo {1} usuarios",
+		"not_assignable":           "debe ser un miembro del espacio de trabajo que pueda editar tareas",
+		"lookup_ids_out_of_range":  "debe enumerar entre 1 y {1} ID de tarea",
+		"not_listing_include":      `debe enumerar "facets", "highlights", "comments", "workspace" o "attachments"`,
+		"not_todo_include":         `debe enumerar "comments", "workspace" o "attachments"`,
+		"not_clone_include":        `debe enumerar "subtasks" o "tags"`,
+		"fields_param_unknown":     "campo desconocido {1}; usa cualquiera de {2}",
+		"export_too_large":         "coincide con {1} tareas; reduce la lista a {2} o menos para exportarla",
+		"import_state_unknown":     "es desconocido o ha caducado; vuelve a iniciar la importación",
+		"not_import_code":          "debe ser el código que devolvió el servicio, de como máximo 2048 caracteres",
+		"not_page_number":          "debe ser un número mayor o igual que 1",
+		"not_latitude":             "debe ser una latitud entre -90 y 90",
+		"not_longitude":            "debe ser una longitud entre -180 y 180",
+		"not_distance":             "debe ser una distancia en metros entre 1 y {1}",
+		"not_ical_date":            "debe ser un DATE o DATE-TIME",
+		"not_ical_priority":        "debe ser un número de 0 a 9",
+	},
+}
@@ -0,0 +1,167 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// English is the language messages are written in, and the fallback when a
+// client accepts nothing we have a bundle for
+const English = "en"
+
+// catalogue holds the translations into one language: problem titles by
+// error code, and the other messages by their ID in english. Dynamic parts
+// are written {1}, {2}, ... as in english and carried over as-is.
+type catalogue struct {
+	titles   map[string]string
+	messages map[string]string
+}
+
+// bundles maps a language tag to its catalogue
+var bundles = map[string]catalogue{
+	"es": spanish,
+	"si": sinhala,
+}
+
+// Supported returns the languages messages can be translated into,
+// English first
+func Supported() []string {
+	langs := []string{English}
+	for lang := range bundles {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs[1:])
+	return langs
+}
+
+// Negotiate picks the best supported language for an Accept-Language header
+// such as "es-ES,es;q=0.9,en;q=0.8". Regional variants match their base
+// language. Anything unsupported, or an empty header, gives English.
+func Negotiate(acceptLanguage string) string {
+	best, bestQ := English, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if base == "*" {
+			base = English
+		}
+		if _, ok := bundles[base]; !ok && base != English {
+			continue
+		}
+		// Ties go to the language listed first
+		if q > bestQ {
+			best, bestQ = base, q
+		}
+	}
+	return best
+}
+
+// Translate returns msg in the given language. Messages without a
+// translation, and every message in English, are returned unchanged.
+func Translate(lang, msg string) string {
+	catalogue, ok := bundles[lang]
+	if !ok || msg == "" {
+		return msg
+	}
+	id, args, ok := identify(msg)
+	if !ok {
+		return msg
+	}
+	if t, ok := catalogue.messages[id]; ok {
+		return fill(t, args)
+	}
+	return msg
+}
+
+// TranslateTitle returns the title of problems with the error code in the
+// given language, or title when it has no translation
+func TranslateTitle(lang, code, title string) string {
+	if t, ok := bundles[lang].titles[code]; ok {
+		return t
+	}
+	return title
+}
+
+// identify finds the ID of the english message msg is, or was built from,
+// and the values filled in for its placeholders
+func identify(msg string) (id string, args []string, ok bool) {
+	indexOnce.Do(buildIndex)
+	if id, ok := ids[msg]; ok {
+		return id, nil, true
+	}
+	for _, p := range patterns {
+		if m := p.re.FindStringSubmatch(msg); m != nil {
+			return p.id, m[1:], true
+		}
+	}
+	return "", nil, false
+}
+
+// pattern matches messages built from an english message with placeholders
+type pattern struct {
+	re *regexp.Regexp
+	id string
+}
+
+var (
+	indexOnce sync.Once
+	// ids maps the text of each english message to its ID
+	ids map[string]string
+	// patterns match the english messages with placeholders
+	patterns []pattern
+)
+
+var (
+	// placeholder matches {1}, {2}, ... in messages
+	placeholder = regexp.MustCompile(`\{\d+\}`)
+	// quotedPlaceholder matches a placeholder escaped by regexp.QuoteMeta
+	quotedPlaceholder = regexp.MustCompile(`\\\{\d+\\\}`)
+)
+
+// buildIndex indexes the english messages, compiling those with
+// placeholders most specific first so "must be {1}, {2} or {3}" wins over
+// "must be {1} or {2}"
+func buildIndex() {
+	ids = make(map[string]string, len(english))
+	var templates []string
+	for id, text := range english {
+		ids[text] = id
+		if strings.Contains(text, "{1}") {
+			templates = append(templates, text)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if li, lj := literalLen(templates[i]), literalLen(templates[j]); li != lj {
+			return li > lj
+		}
+		return templates[i] < templates[j]
+	})
+	for _, text := range templates {
+		expr := quotedPlaceholder.ReplaceAllString(regexp.QuoteMeta(text), `(.+?)`)
+		patterns = append(patterns, pattern{re: regexp.MustCompile("^" + expr + "$"), id: ids[text]})
+	}
+}
+
+// literalLen is the length of key without its placeholders
+func literalLen(key string) int {
+	return len(placeholder.ReplaceAllString(key, ""))
+}
+
+// fill substitutes args for the placeholders in s
+func fill(s string, args []string) string {
+	for i, arg := range args {
+		s = strings.ReplaceAll(s, "{"+strconv.Itoa(i+1)+"}", arg)
+	}
+	return s
+}
@@ -0,0 +1,225 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		lang, msg, want string
+	}{
+		{"es", "Invalid todo ID", "ID de tarea no válido"},
+		{"es", "must be at most 200 characters", "debe tener como máximo 200 caracteres"},
+		{"es", `must be "low", "medium" or "high"`, `debe ser "low", "medium" o "high"`},
+		{"si", "Invalid comment ID", "වලංගු නොවන අදහස් හැඳුනුම්පතකි"},
+		{"es", "Something nobody wrote", "Something nobody wrote"},
+		{English, "Invalid todo ID", "Invalid todo ID"},
+		{"fr", "Invalid todo ID", "Invalid todo ID"},
+	}
+	for _, tt := range tests {
+		if got := Translate(tt.lang, tt.msg); got != tt.want {
+			t.Errorf("Translate(%q, %q) = %q, want %q", tt.lang, tt.msg, got, tt.want)
+		}
+	}
+	if got := TranslateTitle("es", "TODO_NOT_FOUND", "Todo not found"); got != "Tarea no encontrada" {
+		t.Errorf("TranslateTitle(es, TODO_NOT_FOUND) = %q", got)
+	}
+}
+
+// TestCataloguesComplete checks every catalogue translates every message,
+// with the same placeholders, and nothing that isn't one
+func TestCataloguesComplete(t *testing.T) {
+	texts := map[string]string{}
+	for id, text := range english {
+		if other, ok := texts[text]; ok {
+			t.Errorf("messages %s and %s are both %q", id, other, text)
+		}
+		texts[text] = id
+	}
+	for lang, catalogue := range bundles {
+		for id, text := range english {
+			translation, ok := catalogue.messages[id]
+			if !ok {
+				t.Errorf("%s: no translation of %s (%q)", lang, id, text)
+				continue
+			}
+			if got, want := placeholders(translation), placeholders(text); !slices.Equal(got, want) {
+				t.Errorf("%s: %s has placeholders %v, want %v", lang, id, got, want)
+			}
+		}
+		for id := range catalogue.messages {
+			if _, ok := english[id]; !ok {
+				t.Errorf("%s: %s translates no message", lang, id)
+			}
+		}
+	}
+}
+
+// placeholders returns the placeholders in s, sorted
+func placeholders(s string) []string {
+	found := placeholder.FindAllString(s, -1)
+	slices.Sort(found)
+	return found
+}
+
+// messageArgs are the functions taking a message that's sent to clients,
+// and the index of that argument
+var messageArgs = map[string]int{
+	"Respond":             2,
+	"Abort":               2,
+	"respondStorageError": 2,
+	"respondTodoError":    2,
+	"respondFilterError":  2,
+	"modifyTodo":          3,
+	"saveTodo":            3,
+	"setPinned":           2,
+	"replaceTag":          2,
+	"saveChecklist":       5,
+	"warn":                3,
+}
+
+// verb matches a fmt verb, which a message fills a placeholder with
+var verb = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z]`)
+
+// TestSourceMessagesTranslated checks that every message the API sends
+// from a string literal or fmt.Sprintf format is one of the english
+// messages, so it gets translated
+func TestSourceMessagesTranslated(t *testing.T) {
+	found := 0
+	err := filepath.WalkDir("..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != ".." && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		for _, msg := range sentMessages(file) {
+			found++
+			// Messages made only of placeholders join others, such as a
+			// tag and what's wrong with it
+			if strings.TrimSpace(placeholder.ReplaceAllString(msg.text, "")) == "" {
+				continue
+			}
+			if _, _, ok := identify(msg.text); !ok {
+				t.Errorf("%s: %q isn't in english, so it's never translated", fset.Position(msg.pos), msg.text)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found < 100 {
+		t.Fatalf("found only %d messages in the source; is the scan still finding them?", found)
+	}
+}
+
+// sentMessage is a message found in the source
+type sentMessage struct {
+	text string
+	pos  token.Pos
+}
+
+// sentMessages finds the messages a file sends: problem details, field
+// error messages and the messages passed on to be sent. Formats have their
+// verbs turned into placeholders.
+func sentMessages(file *ast.File) []sentMessage {
+	var found []sentMessage
+	add := func(e ast.Expr) {
+		if text, ok := messageText(e); ok {
+			found = append(found, sentMessage{text, e.Pos()})
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			var name string
+			switch fun := n.Fun.(type) {
+			case *ast.Ident:
+				name = fun.Name
+			case *ast.SelectorExpr:
+				name = fun.Sel.Name
+				if x, ok := fun.X.(*ast.Ident); ok && x.Name == "apierrors" && name == "New" {
+					add(n.Args[1])
+				}
+			}
+			if i, ok := messageArgs[name]; ok && i < len(n.Args) {
+				add(n.Args[i])
+			}
+		case *ast.CompositeLit:
+			// Elements of a []FieldError literal may leave out their type
+			if array, ok := n.Type.(*ast.ArrayType); ok {
+				for _, elt := range n.Elts {
+					if lit, ok := elt.(*ast.CompositeLit); ok && lit.Type == nil {
+						lit.Type = array.Elt
+					}
+				}
+			}
+			if !isFieldError(n.Type) {
+				return true
+			}
+			for i, elt := range n.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Message" {
+						add(kv.Value)
+					}
+				} else if i == 1 {
+					add(elt)
+				}
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// isFieldError reports whether t names apierrors.FieldError
+func isFieldError(t ast.Expr) bool {
+	switch t := t.(type) {
+	case *ast.SelectorExpr:
+		return t.Sel.Name == "FieldError"
+	case *ast.Ident:
+		return t.Name == "FieldError"
+	}
+	return false
+}
+
+// messageText returns the message a string literal or fmt.Sprintf call
+// with a literal format sends, with placeholders for what's filled in
+func messageText(e ast.Expr) (string, bool) {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		if e.Kind == token.STRING {
+			s, err := strconv.Unquote(e.Value)
+			return s, err == nil
+		}
+	case *ast.CallExpr:
+		if fun, ok := e.Fun.(*ast.SelectorExpr); ok && fun.Sel.Name == "Sprintf" && len(e.Args) > 0 {
+			if format, ok := messageText(e.Args[0]); ok {
+				n := 0
+				return verb.ReplaceAllStringFunc(format, func(string) string {
+					n++
+					return "{" + strconv.Itoa(n) + "}"
+				}), true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,301 @@
+package i18n
+
+// english is the text of every message sent besides problem titles, by
+// message ID. Catalogues translate messages by ID rather than by their
+// text, so a message can be reworded here without losing its
+// translations; dynamic parts are written {1}, {2}, ...
+var english = map[string]string{
+	// Details
+	"fields_invalid":                         "One or more fields are invalid",
+	"user_not_authenticated":                 "User not authenticated",
+	"bearer_token_required":                  "A bearer token is required",
+	"admin_token_required":                   "A valid admin token is required",
+	"bearer_token_invalid":                   "The bearer token is invalid or expired",
+	"bearer_token_without_oid":               "The bearer token has no oid claim",
+	"token_validation_unavailable":           "Unable to validate the token right now, please retry",
+	"session_verification_failed":            "Failed to verify session, please retry",
+	"session_start_failed":                   "Failed to start session, please retry",
+	"service_unavailable":                    "Service temporarily unavailable, please retry",
+	"database_throttled":                     "Too many requests to the database, please retry shortly",
+	"server_busy":                            "The server is busy, please retry shortly",
+	"too_many_requests_in_progress":          "Too many of your requests are in progress; wait for them to finish",
+	"unexpected_error":                       "An unexpected error occurred",
+	"todo_kept_changing":                     "The todo kept changing while it was being saved; try again",
+	"todo_changed_during_update":             "The todo was changed while it was being updated; fetch it and try again",
+	"concurrent_dependency_cycle":            "A dependency added at the same time would have formed a cycle; try again",
+	"body_not_form":                          "Request body must be a form",
+	"body_not_json":                          "Request body must be valid JSON",
+	"body_not_sent_as_json":                  "Request body must be sent as application/json",
+	"body_too_large":                         "Request body must not exceed {1} bytes",
+	"body_not_json_object":                   "Request body must be a JSON object",
+	"body_unreadable":                        "Failed to read the request body",
+	"schema_unknown":                         "No schema has that name",
+	"request_deadline_exceeded":              "Request did not complete within {1}",
+	"route_unknown":                          "No route matches {1}",
+	"method_not_allowed":                     "{1} is not allowed on {2}",
+	"csrf_header_invalid":                    "Missing or invalid {1} header; fetch one from GET /api/v1/csrf-token",
+	"rate_limited":                           "At most {1} requests are allowed every {2}; try again in {3} seconds",
+	"session_rate_limited":                   "Too many new sessions from your address; try again in {1} seconds",
+	"address_blocked":                        "Requests from your address are not allowed",
+	"tenant_missing":                         "Name the organization in the request",
+	"tenant_unknown":                         "This organization isn't served here",
+	"failed_fetch_notification_preferences":  "Failed to fetch notification preferences",
+	"failed_update_notification_preferences": "Failed to update notification preferences",
+	"failed_fetch_preferences":               "Failed to fetch preferences",
+	"failed_update_preferences":              "Failed to update preferences",
+
+	"invalid_todo_id":           "Invalid todo ID",
+	"invalid_share_id":          "Invalid share ID",
+	"invalid_workspace_id":      "Invalid workspace ID",
+	"invalid_custom_field_id":   "Invalid custom field ID",
+	"invalid_filter_id":         "Invalid filter ID",
+	"invalid_revision_number":   "Invalid revision number",
+	"invalid_attachment_id":     "Invalid attachment ID",
+	"invalid_checklist_item_id": "Invalid checklist item ID",
+	"invalid_comment_id":        "Invalid comment ID",
+
+	"todo_not_found":           "Todo not found",
+	"checklist_item_not_found": "Checklist item not found",
+	"session_not_found":        "Session not found",
+	"workspace_not_found":      "Workspace not found",
+	"share_not_found":          "Share not found",
+	"custom_field_not_found":   "Custom field not found",
+	"saved_filter_not_found":   "Saved filter not found",
+	"revision_not_found":       "Revision not found",
+	"request_timed_out":        "Request timed out",
+
+	"public_link_unknown":          "This link doesn't exist or was revoked",
+	"todo_without_public_link":     "This todo has no public link",
+	"undo_token_unknown":           "This undo token doesn't exist or has expired",
+	"todo_id_taken":                "This ID is taken; create the todo with a new one",
+	"sync_since_too_old":           "Deletes this far back are no longer known; sync again without since",
+	"export_since_too_old":         "Deletes this far back are no longer known; export again without since",
+	"invalid_request_signature":    "Invalid request signature",
+	"request_replayed":             "This request was already received",
+	"failed_check_request":         "Failed to check the request",
+	"slack_not_linked":             "Slack isn't linked",
+	"slack_not_linked_hint":        "Slack isn't linked; create a link code and run the command it comes with in Slack",
+	"complete_blockers_first":      "Complete the todos this one waits for first: {1}",
+	"complete_checklist_first":     "Complete the open checklist items first: {1}",
+	"todo_role_forbids":            "Your role on this todo doesn't allow this",
+	"already_member":               "User is already a member of this workspace",
+	"not_member":                   "User is not a member of this workspace",
+	"admins_add_members":           "Only owners and admins can add members",
+	"admins_change_roles":          "Only owners and admins can change roles",
+	"admins_remove_members":        "Only owners and admins can remove other members",
+	"admins_change_workspace":      "Only owners and admins can change the workspace",
+	"admins_change_custom_fields":  "Only owners and admins can change custom fields",
+	"viewers_change_saved_filters": "Viewers can't change saved filters",
+	"owner_deletes_workspace":      "Only the workspace owner can delete it",
+	"owner_cant_leave":             "The owner can't leave the workspace; delete it instead",
+	"grant_lower_roles":            "You can only grant roles below your own",
+	"change_lower_roles":           "You can only change roles below your own",
+	"remove_lower_members":         "You can only remove members below your own role",
+
+	"viewers_import":             "Viewers can't import todos",
+	"undo_role_forbids":          "Your role in the workspace no longer allows restoring these todos",
+	"comment_delete_forbidden":   "Only the author, admins and owners can delete a comment",
+	"feed_unknown":               "This feed doesn't exist or was revoked",
+	"retention_off":              "Retention is turned off",
+	"retention_rules_missing":    "No retention rules are configured",
+	"app_password_missing":       "A user name and app password are required",
+	"app_password_invalid":       "The user name or app password is wrong, or the password was revoked",
+	"caldav_report_unsupported":  "Only the calendar-query and calendar-multiget reports are supported",
+	"caldav_name_invalid":        "Todo resource names must end in .ics and be at most 255 bytes",
+	"caldav_not_vtodo":           "The calendar only holds todos; send a VTODO",
+	"todo_changed_since_fetched": "The todo was changed or deleted since it was fetched",
+	"caldav_name_taken":          "A todo with this name exists already",
+	"caldav_report_missing":      "Request body must name a report",
+	"body_not_xml":               "Request body must be valid XML",
+
+	"todo_quota_reached":           "You have reached the limit of {1} todos; delete some to add more",
+	"workspace_todo_quota_reached": "This workspace has reached the limit of {1} todos; delete some to add more",
+	"workspace_quota_reached":      "You own the maximum of {1} workspaces; delete one to create another",
+	"todo_quota_warning":           "You have {1} of the {2} todos you may have",
+	"workspace_todo_quota_warning": "This workspace has {1} of the {2} todos it may have",
+	"workspace_quota_warning":      "You own {1} of the {2} workspaces you may",
+	"custom_field_limit_reached":   "There can be at most {1} custom fields; delete one to add another",
+	"saved_filter_limit_reached":   "There can be at most {1} saved filters; delete one to add another",
+
+	"failed_add_dependency":           "Failed to add dependency",
+	"failed_add_member":               "Failed to add member",
+	"failed_check_todo_quota":         "Failed to check todo quota",
+	"failed_check_workspace_quota":    "Failed to check workspace quota",
+	"failed_count_todos":              "Failed to count todos",
+	"failed_count_users":              "Failed to count users",
+	"failed_clone_todo":               "Failed to clone todo",
+	"failed_create_custom_field":      "Failed to create custom field",
+	"failed_create_public_link":       "Failed to create public link",
+	"failed_create_link_code":         "Failed to create link code",
+	"failed_create_todo":              "Failed to create todo",
+	"failed_create_workspace":         "Failed to create workspace",
+	"failed_delete_custom_field":      "Failed to delete custom field",
+	"failed_delete_saved_filter":      "Failed to delete saved filter",
+	"failed_delete_todo":              "Failed to delete todo",
+	"failed_delete_workspace":         "Failed to delete workspace",
+	"failed_export_changes":           "Failed to export changes",
+	"failed_fetch_custom_fields":      "Failed to fetch custom fields",
+	"failed_fetch_revisions":          "Failed to fetch revisions",
+	"failed_fetch_saved_filters":      "Failed to fetch saved filters",
+	"failed_fetch_saved_filter":       "Failed to fetch saved filter",
+	"failed_fetch_sessions":           "Failed to fetch sessions",
+	"failed_fetch_settings":           "Failed to fetch settings",
+	"failed_fetch_shared_todos":       "Failed to fetch shared todos",
+	"failed_fetch_shares":             "Failed to fetch shares",
+	"failed_fetch_slack_link":         "Failed to fetch Slack link",
+	"failed_fetch_audit_entries":      "Failed to fetch audit entries",
+	"failed_fetch_stats":              "Failed to fetch stats",
+	"failed_fetch_time_report":        "Failed to fetch time report",
+	"failed_fetch_todo":               "Failed to fetch todo",
+	"failed_fetch_todos":              "Failed to fetch todos",
+	"failed_fetch_usage":              "Failed to fetch usage",
+	"failed_fetch_users":              "Failed to fetch users",
+	"failed_fetch_workspace":          "Failed to fetch workspace",
+	"failed_fetch_workspaces":         "Failed to fetch workspaces",
+	"failed_log_out":                  "Failed to log out",
+	"failed_pin_todo":                 "Failed to pin todo",
+	"failed_unpin_todo":               "Failed to unpin todo",
+	"failed_purge_user":               "Failed to purge user",
+	"failed_remove_dependency":        "Failed to remove dependency",
+	"failed_remove_member":            "Failed to remove member",
+	"failed_revert_todo":              "Failed to revert todo",
+	"failed_revoke_public_link":       "Failed to revoke public link",
+	"failed_revoke_session":           "Failed to revoke session",
+	"failed_revoke_share":             "Failed to revoke share",
+	"failed_save_filter":              "Failed to save filter",
+	"failed_share_todo":               "Failed to share todo",
+	"failed_snooze_todo":              "Failed to snooze todo",
+	"failed_start_timer":              "Failed to start timer",
+	"failed_stop_timer":               "Failed to stop timer",
+	"failed_sync_changes":             "Failed to sync changes",
+	"failed_sync_todos":               "Failed to sync todos",
+	"failed_undo":                     "Failed to undo",
+	"failed_unlink_slack":             "Failed to unlink Slack",
+	"failed_unsnooze_todo":            "Failed to unsnooze todo",
+	"failed_update_member":            "Failed to update member",
+	"failed_update_saved_filter":      "Failed to update saved filter",
+	"failed_update_workspace":         "Failed to update workspace",
+	"failed_update_settings":          "Failed to update settings",
+	"failed_update_todo":              "Failed to update todo",
+	"failed_export_user":              "Failed to export user",
+	"failed_import_user":              "Failed to import user",
+	"failed_create_indexes":           "Failed to create indexes",
+	"failed_purge_inactive_users":     "Failed to purge inactive users",
+	"failed_evaluate_retention_rules": "Failed to evaluate the retention rules",
+	"failed_assign_todo":              "Failed to assign todo",
+	"failed_fetch_assigned_todos":     "Failed to fetch assigned todos",
+	"failed_attach_link":              "Failed to attach link",
+	"failed_remove_link":              "Failed to remove link",
+	"failed_create_app_password":      "Failed to create app password",
+	"failed_add_checklist_item":       "Failed to add checklist item",
+	"failed_update_checklist_item":    "Failed to update checklist item",
+	"failed_remove_checklist_item":    "Failed to remove checklist item",
+	"failed_fetch_comments":           "Failed to fetch comments",
+	"failed_add_comment":              "Failed to add comment",
+	"failed_delete_comment":           "Failed to delete comment",
+	"failed_fetch_mentions":           "Failed to fetch mentions",
+	"failed_export_todos":             "Failed to export todos",
+	"failed_create_feed":              "Failed to create feed",
+	"failed_fetch_feed":               "Failed to fetch feed",
+	"failed_render_feed":              "Failed to render feed",
+	"failed_start_import":             "Failed to start import",
+	"failed_import_todos":             "Failed to import todos",
+	"failed_fetch_tags":               "Failed to fetch tags",
+	"failed_rename_tag":               "Failed to rename tag",
+	"failed_merge_tags":               "Failed to merge tags",
+	"failed_delete_tag":               "Failed to delete tag",
+
+	// Field messages
+	"empty":                    "must not be empty",
+	"blank":                    "must not be empty or only whitespace",
+	"not_email":                "must be a valid email address",
+	"not_todo_id":              "must be a todo ID",
+	"not_other_todo":           "must be another todo in the same list",
+	"too_many_blockers":        "can't be added; a todo can wait for at most {1} others",
+	"dependency_cycle":         "already waits for this todo; the dependency would form a cycle",
+	"not_date":                 "must be a date (YYYY-MM-DD)",
+	"not_date_or_timestamp":    "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp",
+	"year_out_of_range":        "must be between the years 0000 and 9999 in UTC",
+	"before_from":              "must not be before from",
+	"sent_with_due_date":       "must not be sent together with due_date",
+	"share_with_self":          "cannot share a todo with yourself",
+	"email_or_user_id":         "exactly one of email or user_id is required",
+	"not_time_zone":            `must be an IANA time zone name such as "Europe/London"`,
+	"not_time_of_day":          `must be a 24-hour time such as "23:30", or ""`,
+	"not_weekday":              `must be a day of the week such as "monday"`,
+	"due_not_understood":       `isn't a date we understand; try e.g. "tomorrow 5pm", "next friday" or "in 3 days"`,
+	"duration_or_until":        "exactly one of duration or until is required",
+	"sent_with_duration":       "must not be sent together with duration",
+	"not_snooze_duration":      `must be a positive duration of at most 365 days, such as "90m", "48h" or "3d"`,
+	"not_snooze_time":          "must be in the future and at most 365 days away",
+	"not_color":                `must be a hex color such as "#1e90ff" or one of {1}`,
+	"too_long":                 "must be at most {1} characters",
+	"too_far_ahead":            "must be at most {1} years ahead",
+	"too_long_after_from":      "must be at most {1} days after from",
+	"not_number_up_to":         "must be a number between 1 and {1}",
+	"not_status":               "must be a status code like 404 or a class like 4xx",
+	"not_timestamp":            "must be an RFC 3339 time like 2026-10-16T09:30:00Z",
+	"not_cursor":               "must be the next_before of an earlier page",
+	"days_out_of_range":        "must be between 0 and {1} days",
+	"minutes_out_of_range":     "must be between 0 and {1} minutes",
+	"not_one_of_1":             "must be {1}",
+	"not_one_of_2":             "must be {1} or {2}",
+	"not_one_of_3":             "must be {1}, {2} or {3}",
+	"not_one_of_4":             "must be {1}, {2}, {3} or {4}",
+	"not_string":               "must be a string",
+	"not_number":               "must be a number",
+	"not_integer":              "must be an integer",
+	"not_boolean":              "must be true or false",
+	"not_object":               "must be an object",
+	"not_array":                "must be an array",
+	"pattern_mismatch":         "must match the pattern {1}",
+	"too_short":                "must be at least {1} characters",
+	"out_of_range":             "must be between {1} and {2}",
+	"too_small":                "must be at least {1}",
+	"too_large":                "must be at most {1}",
+	"not_one_of":               "must be one of {1}",
+	"no_notification_channels": "must set the channels of at least one kind of notification",
+	"not_notification_channel": "{1} isn't a channel notifications can be sent on",
+	"too_many_entries":         "must have at most {1} entries",
+	"too_few_entries":          "must have at least {1} entries",
+	"repeated_option":          "must not repeat an earlier option",
+	"options_without_select":   "are only allowed for select fields",
+	"custom_field_name_taken":  "is already used by another custom field",
+	"not_custom_field":         "is not a custom field",
+	"required_for_digests":     "is required to receive digests",
+	"due_not_synced":           "isn't supported when syncing; send due_date",
+	"required":                 "is required",
+	"not_sync_token":           "is not a sync token",
+	"unknown_field":            "is not a field we understand",
+	"not_type":                 "must be a {1}",
+	"not_id":                   "must be an ID",
+	"rule_failed":              "failed the {1} rule",
+	"not_one_of_2_or_empty":    "must be {1}, {2} or empty",
+	"too_many_tags":            "must have at most {1} tags",
+	"not_tag":                  "must be letters, digits, '_', '.', ':' or '-', starting with a letter or digit",
+	// A tag quoted, and what's wrong with it
+	"tag_too_long":             "{1} must be at most {2} characters",
+	"tag_not_tag":              "{1} must be letters, digits, '_', '.', ':' or '-', starting with a letter or digit",
+	"not_geojson_point":        `must be a GeoJSON point such as {"type": "Point", "coordinates": [-0.1276, 51.5072]}, longitude first`,
+	"not_http_url":             "must be an absolute http or https URL",
+	"url_with_credentials":     "must not include a user name or password",
+	"too_many_attachments":     "can't be attached; a todo can have at most {1} links",
+	"too_many_checklist_items": "can't be added; a checklist has at most {1} items",
+	"too_many_comments":        "can't be added; a todo keeps at most {1} comments",
+	"too_many_mentions":        "must mention at most {1} users",
+	"not_assignable":           "must be a member of the workspace who can edit todos",
+	"lookup_ids_out_of_range":  "must list between 1 and {1} todo IDs",
+	"not_listing_include":      `must list "facets", "highlights", "comments", "workspace" or "attachments"`,
+	"not_todo_include":         `must list "comments", "workspace" or "attachments"`,
+	"not_clone_include":        `must list "subtasks" or "tags"`,
+	"fields_param_unknown":     "unknown field {1}; use any of {2}",
+	"export_too_large":         "matches {1} todos; narrow the list to {2} or fewer to export it",
+	"import_state_unknown":     "is unknown or expired; start the import again",
+	"not_import_code":          "must be the code the service sent back, at most 2048 characters",
+	"not_page_number":          "must be a number of at least 1",
+	"not_latitude":             "must be a latitude between -90 and 90",
+	"not_longitude":            "must be a longitude between -180 and 180",
+	"not_distance":             "must be a distance in meters between 1 and {1}",
+	"not_ical_date":            "must be a DATE or DATE-TIME",
+	"not_ical_priority":        "must be a number from 0 to 9",
+}
@@ -0,0 +1,342 @@
+package i18n
+
+// sinhala is the Sinhala catalogue
+var sinhala = catalogue{
+	titles: map[string]string{
+		"INVALID_REQUEST":          "වැරදි ආකෘතියේ ඉල්ලීමකි",
+		"VALIDATION_FAILED":        "වලංගුකරණය අසාර්ථක විය",
+		"INVALID_ID":               "වලංගු නොවන හැඳුනුම්පතකි",
+		"UNAUTHENTICATED":          "සත්‍යාපනය කර නැත",
+		"CSRF_TOKEN_INVALID":       "CSRF ටෝකනය නැත හෝ වලංගු නැත",
+		"TODO_NOT_FOUND":           "කාර්යය හමු නොවීය",
+		"TODO_BLOCKED":             "කාර්යය අවහිර කර ඇත",
+		"TODO_CONFLICT":            "කාර්යය එකවර වෙනස් කරන ලදී",
+		"CHECKLIST_INCOMPLETE":     "පිරික්සුම් ලැයිස්තුව අසම්පූර්ණයි",
+		"CHECKLIST_ITEM_NOT_FOUND": "පිරික්සුම් ලැයිස්තු අයිතමය හමු නොවීය",
+		"SESSION_NOT_FOUND":        "සැසිය හමු නොවීය",
+		"WORKSPACE_NOT_FOUND":      "වැඩබිම හමු නොවීය",
+		"MEMBER_NOT_FOUND":         "සාමාජිකයා හමු නොවීය",
+		"MEMBER_EXISTS":            "දැනටමත් සාමාජිකයෙකි",
+		"SHARE_NOT_FOUND":          "බෙදාගැනීම හමු නොවීය",
+		"PUBLIC_LINK_NOT_FOUND":    "පොදු සබැඳිය හමු නොවීය",
+		"FEED_NOT_FOUND":           "සංග්‍රහය හමු නොවීය",
+		"IMPORT_SOURCE_NOT_FOUND":  "ආයාත මූලාශ්‍රය හමු නොවීය",
+		"CUSTOM_FIELD_NOT_FOUND":   "අභිරුචි ක්ෂේත්‍රය හමු නොවීය",
+		"SAVED_FILTER_NOT_FOUND":   "සුරකින ලද පෙරහන හමු නොවීය",
+		"UNDO_NOT_FOUND":           "අහෝසි කිරීමේ ටෝකනය හමු නොවීය",
+		"REVISION_NOT_FOUND":       "සංශෝධනය හමු නොවීය",
+		"SYNC_TOKEN_EXPIRED":       "සමමුහුර්ත ටෝකනය කල් ඉකුත් වී ඇත",
+		"SLACK_LINK_NOT_FOUND":     "Slack සබැඳිය හමු නොවීය",
+		"SCHEMA_NOT_FOUND":         "ක්‍රමලේඛනය හමු නොවීය",
+		"TENANT_NOT_FOUND":         "සංවිධානය හමු නොවීය",
+		"FORBIDDEN":                "තහනම්",
+		"QUOTA_EXCEEDED":           "සීමාව ඉක්මවා ඇත",
+		"ROUTE_NOT_FOUND":          "මාර්ගය හමු නොවීය",
+		"METHOD_NOT_ALLOWED":       "ක්‍රමයට අවසර නැත",
+		"PAYLOAD_TOO_LARGE":        "ඉල්ලීමේ අන්තර්ගතය ඉතා විශාලයි",
+		"UNSUPPORTED_MEDIA_TYPE":   "සහාය නොදක්වන මාධ්‍ය වර්ගයකි",
+		"PRECONDITION_FAILED":      "පූර්ව කොන්දේසිය සපුරා නැත",
+		"RATE_LIMITED":             "ඉල්ලීම් වැඩියි",
+		"INTERNAL_ERROR":           "අභ්‍යන්තර සේවාදායක දෝෂයකි",
+		"STORAGE_UNAVAILABLE":      "ගබඩාව තාවකාලිකව ලබාගත නොහැක",
+		"STORAGE_THROTTLED":        "ගබඩාව සීමා කර ඇත",
+		"TIMEOUT":                  "ඉල්ලීමේ කාලය ඉකුත් විය",
+		"AUTH_UNAVAILABLE":         "සත්‍යාපනය තාවකාලිකව ලබාගත නොහැක",
+		"IMPORT_FAILED":            "ආයාත කිරීම අසාර්ථක විය",
+		"OVERLOADED":               "සේවාදායකය කාර්යබහුලයි",
+	},
+	messages: map[string]string{
+		// Details
+		"fields_invalid":                         "ක්ෂේත්‍ර එකක් හෝ කිහිපයක් වලංගු නැත",
+		"user_not_authenticated":                 "පරිශීලකයා සත්‍යාපනය කර නැත",
+		"bearer_token_required":                  "bearer ටෝකනයක් අවශ්‍යයි",
+		"admin_token_required":                   "වලංගු පරිපාලක ටෝකනයක් අවශ්‍යයි",
+		"bearer_token_invalid":                   "bearer ටෝකනය වලංගු නැත හෝ කල් ඉකුත් වී ඇත",
+		"bearer_token_without_oid":               "bearer ටෝකනයේ oid claim එකක් නැත",
+		"token_validation_unavailable":           "දැන් ටෝකනය තහවුරු කළ නොහැක, කරුණාකර නැවත උත්සාහ කරන්න",
+		"session_verification_failed":            "සැසිය තහවුරු කිරීමට නොහැකි විය, කරුණාකර නැවත උත්සාහ කරන්න",
+		"session_start_failed":                   "සැසිය ආරම්භ කිරීමට නොහැකි විය, කරුණාකර නැවත උත්සාහ කරන්න",
+		"service_unavailable":                    "සේවාව තාවකාලිකව ලබාගත නොහැක, කරුණාකර නැවත උත්සාහ කරන්න",
+		"database_throttled":                     "දත්ත සමුදායට ඉල්ලීම් වැඩියි, කෙටි වේලාවකින් නැවත උත්සාහ කරන්න",
+		"server_busy":                            "සේවාදායකය කාර්යබහුලයි, කෙටි වේලාවකින් නැවත උත්සාහ කරන්න",
+		"too_many_requests_in_progress":          "ඔබගේ ඉල්ලීම් වැඩි ගණනක් ක්‍රියාත්මක වෙමින් පවතී; ඒවා අවසන් වන තුරු රැඳී සිටින්න",
+		"unexpected_error":                       "අනපේක්ෂිත දෝෂයක් ඇති විය",
+		"todo_kept_changing":                     "සුරකිමින් සිටියදී කාර්යය දිගටම වෙනස් විය; නැවත උත්සාහ කරන්න",
+		"todo_changed_during_update":             "යාවත්කාලීන කරමින් සිටියදී කාර්යය වෙනස් විය; එය නැවත ලබාගෙන නැවත උත්සාහ කරන්න",
+		"concurrent_dependency_cycle":            "එකම වේලාවේ එක් කළ පරායත්තතාවයක් චක්‍රයක් සාදනු ඇත; නැවත උත්සාහ කරන්න",
+		"body_not_form":                          "ඉල්ලීමේ අන්තර්ගතය පෝරමයක් විය යුතුය",
+		"body_not_json":                          "ඉල්ලීමේ අන්තර්ගතය වලංගු JSON විය යුතුය",
+		"body_not_sent_as_json":                  "ඉල්ලීමේ අන්තර්ගතය application/json ලෙස එවිය යුතුය",
+		"body_too_large":                         "ඉල්ලීමේ අන්තර්ගතය බයිට් {1} ඉක්මවිය නොයුතුය",
+		"body_not_json_object":                   "ඉල්ලීමේ අන්තර්ගතය JSON වස්තුවක් විය යුතුය",
+		"body_unreadable":                        "ඉල්ලීමේ අන්තර්ගතය කියවීමට නොහැකි විය",
+		"schema_unknown":                         "එම නමින් ක්‍රමලේඛනයක් නැත",
+		"request_deadline_exceeded":              "ඉල්ලීම {1} ඇතුළත සම්පූර්ණ නොවීය",
+		"route_unknown":                          "{1} ට ගැළපෙන මාර්ගයක් නැත",
+		"method_not_allowed":                     "{2} මත {1} සඳහා අවසර නැත",
+		"csrf_header_invalid":                    "{1} ශීර්ෂය නැත හෝ වලංගු නැත; GET /api/v1/csrf-token මගින් එකක් ලබාගන්න",
+		"rate_limited":                           "සෑම {2} කට වරක් උපරිම ඉල්ලීම් {1} කට ඉඩ ඇත; තත්පර {3} කින් නැවත උත්සාහ කරන්න",
+		"session_rate_limited":                   "ඔබේ ලිපිනයෙන් නව සැසි වැඩියි; තත්පර {1} කින් නැවත උත්සාහ කරන්න",
+		"address_blocked":                        "ඔබේ ලිපිනයෙන් ඉල්ලීම් සඳහා අවසර නැත",
+		"tenant_missing":                         "ඉල්ලීමේ සංවිධානය සඳහන් කරන්න",
+		"tenant_unknown":                         "මෙම සංවිධානයට මෙහි සේවා නොදක්වයි",
+		"failed_fetch_notification_preferences":  "දැනුම්දීම් මනාප ලබාගැනීමට නොහැකි විය",
+		"failed_update_notification_preferences": "දැනුම්දීම් මනාප යාවත්කාලීන කිරීමට නොහැකි විය",
+		"failed_fetch_preferences":               "මනාප ලබාගැනීමට නොහැකි විය",
+		"failed_update_preferences":              "මනාප යාවත්කාලීන කිරීමට නොහැකි විය",
+
+		"invalid_todo_id":           "වලංගු නොවන කාර්ය හැඳුනුම්පතකි",
+		"invalid_share_id":          "වලංගු නොවන බෙදාගැනීම් හැඳුනුම්පතකි",
+		"invalid_workspace_id":      "වලංගු නොවන වැඩබිම් හැඳුනුම්පතකි",
+		"invalid_custom_field_id":   "වලංගු නොවන අභිරුචි ක්ෂේත්‍ර හැඳුනුම්පතකි",
+		"invalid_filter_id":         "වලංගු නොවන පෙරහන් හැඳුනුම්පතකි",
+		"invalid_revision_number":   "වලංගු නොවන සංශෝධන අංකයකි",
+		"invalid_attachment_id":     "වලංගු නොවන ඇමුණුම් හැඳුනුම්පතකි",
+		"invalid_checklist_item_id": "වලංගු නොවන පිරික්සුම් ලැයිස්තු අයිතම හැඳුනුම්පතකි",
+		"invalid_comment_id":        "වලංගු නොවන අදහස් හැඳුනුම්පතකි",
+
+		"todo_not_found":           "කාර්යය හමු නොවීය",
+		"checklist_item_not_found": "පිරික්සුම් ලැයිස්තු අයිතමය හමු නොවීය",
+		"session_not_found":        "සැසිය හමු නොවීය",
+		"workspace_not_found":      "වැඩබිම හමු නොවීය",
+		"share_not_found":          "බෙදාගැනීම හමු නොවීය",
+		"custom_field_not_found":   "අභිරුචි ක්ෂේත්‍රය හමු නොවීය",
+		"saved_filter_not_found":   "සුරකින ලද පෙරහන හමු නොවීය",
+		"revision_not_found":       "සංශෝධනය හමු නොවීය",
+		"request_timed_out":        "ඉල්ලීමේ කාලය ඉකුත් විය",
+
+		"public_link_unknown":          "මෙම සබැඳිය නොපවතී හෝ අවලංගු කර ඇත",
+		"todo_without_public_link":     "මෙම කාර්යයට පොදු සබැඳියක් නැත",
+		"undo_token_unknown":           "මෙම අහෝසි කිරීමේ ටෝකනය නොපවතී හෝ කල් ඉකුත් වී ඇත",
+		"todo_id_taken":                "මෙම හැඳුනුම්පත දැනටමත් භාවිතයේ ඇත; නව එකක් සමඟ කාර්යය සාදන්න",
+		"sync_since_too_old":           "මෙතරම් පැරණි මකාදැමීම් තවදුරටත් නොදනී; since නොමැතිව නැවත සමමුහුර්ත කරන්න",
+		"export_since_too_old":         "මෙතරම් පැරණි මකාදැමීම් තවදුරටත් නොදනී; since නොමැතිව නැවත අපනයනය කරන්න",
+		"invalid_request_signature":    "වලංගු නොවන ඉල්ලීම් අත්සන",
+		"request_replayed":             "මෙම ඉල්ලීම දැනටමත් ලැබී ඇත",
+		"failed_check_request":         "ඉල්ලීම පරීක්ෂා කිරීමට අසමත් විය",
+		"slack_not_linked":             "Slack සම්බන්ධ කර නැත",
+		"slack_not_linked_hint":        "Slack සම්බන්ධ කර නැත; සම්බන්ධ කිරීමේ කේතයක් සාදා එය සමඟ එන විධානය Slack හි ධාවනය කරන්න",
+		"complete_blockers_first":      "පළමුව මෙය රඳා පවතින කාර්ය සම්පූර්ණ කරන්න: {1}",
+		"complete_checklist_first":     "පළමුව විවෘත පිරික්සුම් ලැයිස්තු අයිතම සම්පූර්ණ කරන්න: {1}",
+		"todo_role_forbids":            "මෙම කාර්යයේ ඔබේ භූමිකාව මෙයට ඉඩ නොදේ",
+		"already_member":               "පරිශීලකයා දැනටමත් මෙම වැඩබිමේ සාමාජිකයෙකි",
+		"not_member":                   "පරිශීලකයා මෙම වැඩබිමේ සාමාජිකයෙකු නොවේ",
+		"admins_add_members":           "සාමාජිකයන් එක් කළ හැක්කේ හිමිකරුවන්ට සහ පරිපාලකයන්ට පමණි",
+		"admins_change_roles":          "භූමිකා වෙනස් කළ හැක්කේ හිමිකරුවන්ට සහ පරිපාලකයන්ට පමණි",
+		"admins_remove_members":        "වෙනත් සාමාජිකයන් ඉවත් කළ හැක්කේ හිමිකරුවන්ට සහ පරිපාලකයන්ට පමණි",
+		"admins_change_workspace":      "වැඩබිම වෙනස් කළ හැක්කේ හිමිකරුවන්ට සහ පරිපාලකයන්ට පමණි",
+		"admins_change_custom_fields":  "අභිරුචි ක්ෂේත්‍ර වෙනස් කළ හැක්කේ හිමිකරුවන්ට සහ පරිපාලකයන්ට පමණි",
+		"viewers_change_saved_filters": "නරඹන්නන්ට සුරකින ලද පෙරහන් වෙනස් කළ නොහැක",
+		"owner_deletes_workspace":      "වැඩබිම මකා දැමිය හැක්කේ එහි හිමිකරුට පමණි",
+		"owner_cant_leave":             "හිමිකරුට වැඩබිමෙන් ඉවත් විය නොහැක; ඒ වෙනුවට එය මකා දමන්න",
+		"grant_lower_roles":            "ඔබට ලබාදිය හැක්කේ ඔබේ භූමිකාවට පහළ භූමිකා පමණි",
+		"change_lower_roles":           "ඔබට වෙනස් කළ හැක්කේ ඔබේ භූමිකාවට පහළ භූමිකා පමණි",
+		"remove_lower_members":         "ඔබට ඉවත් කළ හැක්කේ ඔබේ භූමිකාවට පහළ සාමාජිකයන් පමණි",
+
+		"viewers_import":             "නරඹන්නන්ට කාර්ය ආයාත කළ නොහැක",
+		"undo_role_forbids":          "වැඩබිමේ ඔබේ භූමිකාව තවදුරටත් මෙම කාර්ය ප්‍රතිසාධනය කිරීමට ඉඩ නොදේ",
+		"comment_delete_forbidden":   "අදහසක් මකා දැමිය හැක්කේ එහි කතුවරයාට, පරිපාලකයන්ට සහ හිමිකරුවන්ට පමණි",
+		"feed_unknown":               "මෙම සංග්‍රහය නොපවතී හෝ අවලංගු කර ඇත",
+		"retention_off":              "රඳවා තබාගැනීම අක්‍රිය කර ඇත",
+		"retention_rules_missing":    "රඳවා තබාගැනීමේ නීති කිසිවක් සකසා නැත",
+		"app_password_missing":       "පරිශීලක නාමයක් සහ යෙදුම් මුරපදයක් අවශ්‍යයි",
+		"app_password_invalid":       "පරිශීලක නාමය හෝ යෙදුම් මුරපදය වැරදියි, නැතහොත් මුරපදය අවලංගු කර ඇත",
+		"caldav_report_unsupported":  "සහය දක්වන්නේ calendar-query සහ calendar-multiget වාර්තා පමණි",
+		"caldav_name_invalid":        "කාර්ය සම්පත් නාම .ics න් අවසන් විය යුතු අතර බයිට් 255 කට වඩා වැඩි නොවිය යුතුය",
+		"caldav_not_vtodo":           "දින දර්ශනයේ ඇත්තේ කාර්ය පමණි; VTODO එකක් යවන්න",
+		"todo_changed_since_fetched": "කාර්යය ලබාගත් පසු එය වෙනස් කර හෝ මකා දමා ඇත",
+		"caldav_name_taken":          "මෙම නමින් කාර්යයක් දැනටමත් පවතී",
+		"caldav_report_missing":      "ඉල්ලීමේ අන්තර්ගතය වාර්තාවක් නම් කළ යුතුය",
+		"body_not_xml":               "ඉල්ලීමේ අන්තර්ගතය වලංගු XML විය යුතුය",
+
+		"todo_quota_reached":           "ඔබ කාර්ය {1} සීමාවට ළඟා වී ඇත; තවත් එක් කිරීමට සමහරක් මකා දමන්න",
+		"workspace_todo_quota_reached": "මෙම වැඩබිම කාර්ය {1} සීමාවට ළඟා වී ඇත; තවත් එක් කිරීමට සමහරක් මකා දමන්න",
+		"workspace_quota_reached":      "ඔබට උපරිම වැඩබිම් {1} ක් ඇත; තවත් එකක් සෑදීමට එකක් මකා දමන්න",
+		"todo_quota_warning":           "ඔබට ඉඩ දී ඇති කාර්ය {2} න් {1} ක් ඔබ සතුව ඇත",
+		"workspace_todo_quota_warning": "මෙම වැඩබිමට ඉඩ දී ඇති කාර්ය {2} න් {1} ක් එහි ඇත",
+		"workspace_quota_warning":      "ඔබට ඉඩ දී ඇති වැඩබිම් {2} න් {1} ක් ඔබ සතුව ඇත",
+		"custom_field_limit_reached":   "උපරිම අභිරුචි ක්ෂේත්‍ර {1} ක් තිබිය හැක; තවත් එකක් එක් කිරීමට එකක් මකා දමන්න",
+		"saved_filter_limit_reached":   "උපරිම සුරකින ලද පෙරහන් {1} ක් තිබිය හැක; තවත් එකක් එක් කිරීමට එකක් මකා දමන්න",
+
+		"failed_add_dependency":           "පරායත්තතාව එක් කිරීමට නොහැකි විය",
+		"failed_add_member":               "සාමාජිකයා එක් කිරීමට නොහැකි විය",
+		"failed_check_todo_quota":         "කාර්ය සීමාව පරීක්ෂා කිරීමට නොහැකි විය",
+		"failed_check_workspace_quota":    "වැඩබිම් සීමාව පරීක්ෂා කිරීමට නොහැකි විය",
+		"failed_count_todos":              "කාර්ය ගණන් කිරීමට නොහැකි විය",
+		"failed_count_users":              "පරිශීලකයන් ගණන් කිරීමට නොහැකි විය",
+		"failed_clone_todo":               "කාර්යයේ පිටපතක් සෑදීමට නොහැකි විය",
+		"failed_create_custom_field":      "අභිරුචි ක්ෂේත්‍රය සෑදීමට නොහැකි විය",
+		"failed_create_public_link":       "පොදු සබැඳිය සෑදීමට නොහැකි විය",
+		"failed_create_link_code":         "සම්බන්ධ කිරීමේ කේතය සෑදීමට නොහැකි විය",
+		"failed_create_todo":              "කාර්යය සෑදීමට නොහැකි විය",
+		"failed_create_workspace":         "වැඩබිම සෑදීමට නොහැකි විය",
+		"failed_delete_custom_field":      "අභිරුචි ක්ෂේත්‍රය මකා දැමීමට නොහැකි විය",
+		"failed_delete_saved_filter":      "සුරකින ලද පෙරහන මකා දැමීමට නොහැකි විය",
+		"failed_delete_todo":              "කාර්යය මකා දැමීමට නොහැකි විය",
+		"failed_delete_workspace":         "වැඩබිම මකා දැමීමට නොහැකි විය",
+		"failed_export_changes":           "වෙනස්කම් අපනයනය කිරීමට නොහැකි විය",
+		"failed_fetch_custom_fields":      "අභිරුචි ක්ෂේත්‍ර ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_revisions":          "සංශෝධන ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_saved_filters":      "සුරකින ලද පෙරහන් ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_saved_filter":       "සුරකින ලද පෙරහන ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_sessions":           "සැසි ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_settings":           "සැකසුම් ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_shared_todos":       "බෙදාගත් කාර්ය ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_shares":             "බෙදාගැනීම් ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_slack_link":         "Slack සබැඳිය ලබා ගැනීමට නොහැකි විය",
+		"failed_fetch_audit_entries":      "විගණන සටහන් ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_stats":              "සංඛ්‍යාලේඛන ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_time_report":        "කාල වාර්තාව ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_todo":               "කාර්යය ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_todos":              "කාර්ය ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_usage":              "භාවිතය ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_users":              "පරිශීලකයන් ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_workspace":          "වැඩබිම ලබාගැනීමට නොහැකි විය",
+		"failed_fetch_workspaces":         "වැඩබිම් ලබාගැනීමට නොහැකි විය",
+		"failed_log_out":                  "පිටවීමට නොහැකි විය",
+		"failed_pin_todo":                 "කාර්යය අමුණා තැබීමට නොහැකි විය",
+		"failed_unpin_todo":               "කාර්යය ගලවා දැමීමට නොහැකි විය",
+		"failed_purge_user":               "පරිශීලකයා ඉවත් කිරීමට නොහැකි විය",
+		"failed_remove_dependency":        "පරායත්තතාව ඉවත් කිරීමට නොහැකි විය",
+		"failed_remove_member":            "සාමාජිකයා ඉවත් කිරීමට නොහැකි විය",
+		"failed_revert_todo":              "කාර්යය ප්‍රතිවර්තනය කිරීමට නොහැකි විය",
+		"failed_revoke_public_link":       "පොදු සබැඳිය අවලංගු කිරීමට නොහැකි විය",
+		"failed_revoke_session":           "සැසිය අවලංගු කිරීමට නොහැකි විය",
+		"failed_revoke_share":             "බෙදාගැනීම අවලංගු කිරීමට නොහැකි විය",
+		"failed_save_filter":              "පෙරහන සුරැකීමට නොහැකි විය",
+		"failed_share_todo":               "කාර්යය බෙදාගැනීමට නොහැකි විය",
+		"failed_snooze_todo":              "කාර්යය කල් දැමීමට නොහැකි විය",
+		"failed_start_timer":              "කාල ගණකය ආරම්භ කිරීමට නොහැකි විය",
+		"failed_stop_timer":               "කාල ගණකය නැවැත්වීමට නොහැකි විය",
+		"failed_sync_changes":             "වෙනස්කම් සමමුහුර්ත කිරීමට නොහැකි විය",
+		"failed_sync_todos":               "කාර්යයන් සමමුහුර්ත කිරීමට නොහැකි විය",
+		"failed_undo":                     "අහෝසි කිරීමට නොහැකි විය",
+		"failed_unlink_slack":             "Slack විසන්ධි කිරීමට නොහැකි විය",
+		"failed_unsnooze_todo":            "කාර්යය නැවත සක්‍රිය කිරීමට නොහැකි විය",
+		"failed_update_member":            "සාමාජිකයා යාවත්කාලීන කිරීමට නොහැකි විය",
+		"failed_update_saved_filter":      "සුරකින ලද පෙරහන යාවත්කාලීන කිරීමට නොහැකි විය",
+		"failed_update_workspace":         "වැඩබිම යාවත්කාලීන කිරීමට නොහැකි විය",
+		"failed_update_settings":          "සැකසුම් යාවත්කාලීන කිරීමට නොහැකි විය",
+		"failed_update_todo":              "කාර්යය යාවත්කාලීන කිරීමට නොහැකි විය",
+		"failed_export_user":              "පරිශීලකයා අපනයනය කිරීමට නොහැකි විය",
+		"failed_import_user":              "පරිශීලකයා ආයාත කිරීමට නොහැකි විය",
+		"failed_create_indexes":           "දර්ශක සෑදීමට නොහැකි විය",
+		"failed_purge_inactive_users":     "අක්‍රිය පරිශීලකයන් ඉවත් කිරීමට නොහැකි විය",
+		"failed_evaluate_retention_rules": "රඳවා තබාගැනීමේ නීති ඇගයීමට නොහැකි විය",
+		"failed_assign_todo":              "කාර්යය පැවරීමට නොහැකි විය",
+		"failed_fetch_assigned_todos":     "පවරන ලද කාර්ය ලබාගැනීමට නොහැකි විය",
+		"failed_attach_link":              "සබැඳිය අමුණා ගැනීමට නොහැකි විය",
+		"failed_remove_link":              "සබැඳිය ඉවත් කිරීමට නොහැකි විය",
+		"failed_create_app_password":      "යෙදුම් මුරපදය සෑදීමට නොහැකි විය",
+		"failed_add_checklist_item":       "පිරික්සුම් ලැයිස්තු අයිතමය එක් කිරීමට නොහැකි විය",
+		"failed_update_checklist_item":    "පිරික්සුම් ලැයිස්තු අයිතමය යාවත්කාලීන කිරීමට නොහැකි විය",
+		"failed_remove_checklist_item":    "පිරික්සුම් ලැයිස්තු අයිතමය ඉවත් කිරීමට නොහැකි විය",
+		"failed_fetch_comments":           "අදහස් ලබාගැනීමට නොහැකි විය",
+		"failed_add_comment":              "අදහස එක් කිරීමට නොහැකි විය",
+		"failed_delete_comment":           "අදහස මකා දැමීමට නොහැකි විය",
+		"failed_fetch_mentions":           "සඳහන් කිරීම් ලබාගැනීමට නොහැකි විය",
+		"failed_export_todos":             "කාර්ය අපනයනය කිරීමට නොහැකි විය",
+		"failed_create_feed":              "සංග්‍රහය සෑදීමට නොහැකි විය",
+		"failed_fetch_feed":               "සංග්‍රහය ලබාගැනීමට නොහැකි විය",
+		"failed_render_feed":              "සංග්‍රහය ජනනය කිරීමට නොහැකි විය",
+		"failed_start_import":             "ආයාත කිරීම ආරම්භ කිරීමට නොහැකි විය",
+		"failed_import_todos":             "කාර්ය ආයාත කිරීමට නොහැකි විය",
+		"failed_fetch_tags":               "ටැග ලබාගැනීමට නොහැකි විය",
+		"failed_rename_tag":               "ටැගය නැවත නම් කිරීමට නොහැකි විය",
+		"failed_merge_tags":               "ටැග ඒකාබද්ධ කිරීමට නොහැකි විය",
+		"failed_delete_tag":               "ටැගය මකා දැමීමට නොහැකි විය",
+
+		// Field messages
+		"empty":                    "හිස් නොවිය යුතුය",
+		"blank":                    "හිස් හෝ හිස්තැන් පමණක් නොවිය යුතුය",
+		"not_email":                "වලංගු විද්‍යුත් තැපැල් ලිපිනයක් විය යුතුය",
+		"not_todo_id":              "කාර්ය හැඳුනුම්පතක් විය යුතුය",
+		"not_other_todo":           "එම ලැයිස්තුවේම වෙනත් කාර්යයක් විය යුතුය",
+		"too_many_blockers":        "එක් කළ නොහැක; කාර්යයකට උපරිම වෙනත් කාර්ය {1} ක් මත රඳා පැවතිය හැක",
+		"dependency_cycle":         "දැනටමත් මෙම කාර්යය මත රඳා පවතී; පරායත්තතාව චක්‍රයක් සාදයි",
+		"not_date":                 "දිනයක් (YYYY-MM-DD) විය යුතුය",
+		"not_date_or_timestamp":    "දිනයක් (YYYY-MM-DD) හෝ RFC 3339 කාල මුද්‍රාවක් විය යුතුය",
+		"year_out_of_range":        "UTC හි 0000 සහ 9999 වසර අතර විය යුතුය",
+		"before_from":              "from ට පෙර නොවිය යුතුය",
+		"sent_with_due_date":       "due_date සමඟ එකට එවිය නොයුතුය",
+		"share_with_self":          "ඔබ සමඟම කාර්යයක් බෙදාගත නොහැක",
+		"email_or_user_id":         "email හෝ user_id වලින් හරියටම එකක් අවශ්‍යයි",
+		"not_time_zone":            `"Asia/Colombo" වැනි IANA වේලා කලාප නාමයක් විය යුතුය`,
+		"not_time_of_day":          `"23:30" වැනි පැය 24 ආකෘතියේ වේලාවක් හෝ "" විය යුතුය`,
+		"not_weekday":              `"monday" වැනි සතියේ දිනයක් විය යුතුය`,
+		"due_not_understood":       `අපට තේරෙන දිනයක් නොවේ; උදා. "tomorrow 5pm", "next friday" හෝ "in 3 days" උත්සාහ කරන්න`,
+		"duration_or_until":        "duration හෝ until වලින් හරියටම එකක් අවශ්‍යයි",
+		"sent_with_duration":       "duration සමඟ එකට එවිය නොයුතුය",
+		"not_snooze_duration":      `"90m", "48h" හෝ "3d" වැනි දින 365 කට නොවැඩි ධන කාලසීමාවක් විය යුතුය`,
+		"not_snooze_time":          "අනාගතයේ සහ දින 365 ක් ඇතුළත විය යුතුය",
+		"not_color":                `"#1e90ff" වැනි hex වර්ණයක් හෝ {1} වලින් එකක් විය යුතුය`,
+		"too_long":                 "අක්ෂර {1} කට වඩා වැඩි නොවිය යුතුය",
+		"too_far_ahead":            "උපරිම වසර {1} ක් ඉදිරියෙන් විය යුතුය",
+		"too_long_after_from":      "from ට පසු දින {1} කට වඩා වැඩි නොවිය යුතුය",
+		"not_number_up_to":         "1 සහ {1} අතර අංකයක් විය යුතුය",
+		"not_status":               "404 වැනි තත්ව කේතයක් හෝ 4xx වැනි පන්තියක් විය යුතුය",
+		"not_timestamp":            "2026-10-16T09:30:00Z වැනි RFC 3339 වේලාවක් විය යුතුය",
+		"not_cursor":               "පෙර පිටුවක next_before විය යුතුය",
+		"days_out_of_range":        "දින 0 සහ {1} අතර විය යුතුය",
+		"minutes_out_of_range":     "මිනිත්තු 0 සහ {1} අතර විය යුතුය",
+		"not_one_of_1":             "{1} විය යුතුය",
+		"not_one_of_2":             "{1} හෝ {2} විය යුතුය",
+		"not_one_of_3":             "{1}, {2} හෝ {3} විය යුතුය",
+		"not_one_of_4":             "{1}, {2}, {3} හෝ {4} විය යුතුය",
+		"not_string":               "පෙළ අගයක් විය යුතුය",
+		"not_number":               "සංඛ්‍යාවක් විය යුතුය",
+		"not_integer":              "පූර්ණ සංඛ්‍යාවක් විය යුතුය",
+		"not_boolean":              "true හෝ false විය යුතුය",
+		"not_object":               "වස්තුවක් විය යුතුය",
+		"not_array":                "ලැයිස්තුවක් විය යුතුය",
+		"pattern_mismatch":         "{1} රටාවට ගැළපිය යුතුය",
+		"too_short":                "අවම වශයෙන් අක්ෂර {1} ක් විය යුතුය",
+		"out_of_range":             "{1} සහ {2} අතර විය යුතුය",
+		"too_small":                "අවම වශයෙන් {1} විය යුතුය",
+		"too_large":                "උපරිම වශයෙන් {1} විය යුතුය",
+		"not_one_of":               "{1} වලින් එකක් විය යුතුය",
+		"no_notification_channels": "අවම වශයෙන් එක් දැනුම්දීම් වර්ගයක නාලිකා සැකසිය යුතුය",
+		"not_notification_channel": "{1} දැනුම්දීම් යැවිය හැකි නාලිකාවක් නොවේ",
+		"too_many_entries":         "උපරිම අයිතම {1} ක් තිබිය යුතුය",
+		"too_few_entries":          "අවම අයිතම {1} ක් තිබිය යුතුය",
+		"repeated_option":          "පෙර විකල්පයක් නැවත නොවිය යුතුය",
+		"options_without_select":   "තේරීම් ක්ෂේත්‍ර සඳහා පමණක් ඉඩ දෙනු ලැබේ",
+		"custom_field_name_taken":  "වෙනත් අභිරුචි ක්ෂේත්‍රයක් විසින් දැනටමත් භාවිතා කරයි",
+		"not_custom_field":         "අභිරුචි ක්ෂේත්‍රයක් නොවේ",
+		"required_for_digests":     "සාරාංශ ලැබීමට අවශ්‍ය වේ",
+		"due_not_synced":           "සමමුහුර්ත කිරීමේදී සහය නොදක්වයි; due_date යවන්න",
+		"required":                 "අවශ්‍ය වේ",
+		"not_sync_token":           "සමමුහුර්ත ටෝකනයක් නොවේ",
+		"unknown_field":            "අපට තේරෙන ක්ෂේත්‍රයක් නොවේ",
+		"not_type":                 "{1} වර්ගයේ විය යුතුය",
+		"not_id":                   "හැඳුනුම්පතක් විය යුතුය",
+		"rule_failed":              "{1} නීතිය සපුරාලන්නේ නැත",
+		"not_one_of_2_or_empty":    "{1}, {2} හෝ හිස් විය යුතුය",
+		"too_many_tags":            "උපරිම ටැග {1} ක් තිබිය යුතුය",
+		"not_tag":                  "අකුරු, ඉලක්කම්, '_', '.', ':' හෝ '-' විය යුතු අතර අකුරකින් හෝ ඉලක්කමකින් ආරම්භ විය යුතුය",
+		"tag_too_long":             "{1} අක්ෂර {2} කට වඩා වැඩි නොවිය යුතුය",
+		"tag_not_tag":              "{1} අකුරු, ඉලක්කම්, '_', '.', ':' හෝ '-' විය යුතු අතර අකුරකින් හෝ ඉලක්කමකින් ආරම්භ විය යුතුය",
+		"not_geojson_point":        `{"type": "Point", "coordinates": [-0.1276, 51.5072]} වැනි GeoJSON ලක්ෂ්‍යයක් විය යුතුය, දේශාංශය පළමුව`,
+		"not_http_url":             "නිරපේක්ෂ http හෝ https URL එකක් විය යුතුය",
+		"url_with_credentials":     "පරිශීලක නාමයක් හෝ මුරපදයක් ඇතුළත් නොවිය යුතුය",
+		"too_many_attachments":     "අමුණා ගත නොහැක; කාර්යයකට උපරිම සබැඳි {1} ක් තිබිය හැක",
+		"too_many_checklist_items": "එක් කළ නොහැක; පිරික්සුම් ලැයිස්තුවක උපරිම අයිතම {1} ක් ඇත",
+		"too_many_comments":        "එක් කළ නොහැක; කාර්යයක් උපරිම අදහස් {1} ක් තබා ගනී",
+		"too_many_mentions":        "උපරිම පරිශීලකයන් {1} දෙනෙකු සඳහන් කළ යුතුය",
+		"not_assignable":           "කාර්ය සංස්කරණය කළ හැකි වැඩබිමේ සාමාජිකයෙකු විය යුතුය",
+		"lookup_ids_out_of_range":  "කාර්ය හැඳුනුම්පත් 1 ත් {1} ත් අතර ලැයිස්තුගත කළ යුතුය",
+		"not_listing_include":      `"facets", "highlights", "comments", "workspace" හෝ "attachments" ලැයිස්තුගත කළ යුතුය`,
+		"not_todo_include":         `"comments", "workspace" හෝ "attachments" ලැයිස්තුගත කළ යුතුය`,
+		"not_clone_include":        `"subtasks" හෝ "tags" ලැයිස්තුගත කළ යුතුය`,
+		"fields_param_unknown":     "නොදන්නා ක්ෂේත්‍රය {1}; {2} වලින් ඕනෑම එකක් භාවිතා කරන්න",
+		"export_too_large":         "කාර්ය {1} කට ගැළපේ; අපනයනය කිරීමට ලැයිස්තුව {2} හෝ ඊට අඩු දක්වා සීමා කරන්න",
+		"import_state_unknown":     "නොදන්නා හෝ කල් ඉකුත් වී ඇත; ආයාත කිරීම නැවත අරඹන්න",
+		"not_import_code":          "සේවාව ආපසු එවූ කේතය විය යුතුය, අක්ෂර 2048 කට නොවැඩි",
+		"not_page_number":          "අවම වශයෙන් 1 වන අංකයක් විය යුතුය",
+		"not_latitude":             "-90 සහ 90 අතර අක්ෂාංශයක් විය යුතුය",
+		"not_longitude":            "-180 සහ 180 අතර දේශාංශයක් විය යුතුය",
+		"not_distance":             "මීටර 1 සහ {1} අතර දුරක් විය යුතුය",
+		"not_ical_date":            "DATE හෝ DATE-TIME විය යුතුය",
+		"not_ical_priority":        "0 සිට 9 දක්වා අංකයක් විය යුතුය",
+	},
+}
@@ -0,0 +1,316 @@
+// Package ical reads and writes iCalendar (RFC 5545) objects, as much of
+// the format as the tasks CalDAV clients sync need: components, properties
+// with their parameters, escaped text and dates. Recurrence and time zone
+// definitions are kept as they are but not interpreted.
+package ical
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the media type of iCalendar objects
+const ContentType = "text/calendar"
+
+// maxLineOctets is the longest content line written before it's folded
+const maxLineOctets = 75
+
+// maxDepth bounds how deeply components may nest when parsing
+const maxDepth = 8
+
+// Time formats of DATE-TIME and DATE values
+const (
+	utcFormat      = "20060102T150405Z"
+	floatingFormat = "20060102T150405"
+	dateFormat     = "20060102"
+)
+
+// Property is one content line of a component, such as SUMMARY:Buy milk.
+// Value is as written, so text is still escaped; Component.Text unescapes
+// it.
+type Property struct {
+	Name string
+	// Params holds the parameters by uppercase name. Quotes around values
+	// are removed.
+	Params map[string]string
+	Value  string
+}
+
+// Component is a block between BEGIN and END lines, such as VCALENDAR or
+// the VTODO in it
+type Component struct {
+	Name       string
+	Props      []Property
+	Components []*Component
+}
+
+// NewComponent returns an empty component
+func NewComponent(name string) *Component {
+	return &Component{Name: name}
+}
+
+// Prop returns the first property named name, or nil
+func (c *Component) Prop(name string) *Property {
+	for i := range c.Props {
+		if c.Props[i].Name == name {
+			return &c.Props[i]
+		}
+	}
+	return nil
+}
+
+// Text returns the unescaped value of the first property named name, or ""
+func (c *Component) Text(name string) string {
+	if p := c.Prop(name); p != nil {
+		return UnescapeText(p.Value)
+	}
+	return ""
+}
+
+// Add adds a property with a value written as it is. params are name and
+// value pairs.
+func (c *Component) Add(name, value string, params ...string) {
+	p := Property{Name: name, Value: value}
+	if len(params) > 0 {
+		p.Params = map[string]string{}
+		for i := 0; i+1 < len(params); i += 2 {
+			p.Params[params[i]] = params[i+1]
+		}
+	}
+	c.Props = append(c.Props, p)
+}
+
+// AddText adds a text property, escaping its value
+func (c *Component) AddText(name, text string) {
+	c.Add(name, EscapeText(text))
+}
+
+// AddTime adds a DATE-TIME property in UTC
+func (c *Component) AddTime(name string, t time.Time) {
+	c.Add(name, t.UTC().Format(utcFormat))
+}
+
+// Child returns the first nested component named name, or nil
+func (c *Component) Child(name string) *Component {
+	for _, child := range c.Components {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// Time reads the property as a DATE-TIME or DATE. Times in UTC are read
+// as such, times with a TZID in that zone, and floating times and dates in
+// loc; dateOnly reports a DATE, which is read as the start of that day.
+// Unknown zones, such as Windows zone names, fall back to loc.
+func (p *Property) Time(loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	value := p.Value
+	if p.Params["VALUE"] == "DATE" || len(value) == len(dateFormat) {
+		t, err = time.ParseInLocation(dateFormat, value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse(utcFormat, value)
+		return t, false, err
+	}
+	if tzid := strings.TrimPrefix(p.Params["TZID"], "/"); tzid != "" {
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			loc = zone
+		}
+	}
+	t, err = time.ParseInLocation(floatingFormat, value, loc)
+	return t, false, err
+}
+
+// Encode writes the component as content lines, folded and ending in CRLF
+// as RFC 5545 asks
+func (c *Component) Encode() []byte {
+	var buf bytes.Buffer
+	c.encode(&buf)
+	return buf.Bytes()
+}
+
+func (c *Component) encode(buf *bytes.Buffer) {
+	writeLine(buf, "BEGIN:"+c.Name)
+	for _, p := range c.Props {
+		var line strings.Builder
+		line.WriteString(p.Name)
+		for _, name := range slices.Sorted(maps.Keys(p.Params)) {
+			value := p.Params[name]
+			if strings.ContainsAny(value, ";:,") {
+				value = `"` + value + `"`
+			}
+			line.WriteString(";" + name + "=" + value)
+		}
+		line.WriteString(":" + p.Value)
+		writeLine(buf, line.String())
+	}
+	for _, child := range c.Components {
+		child.encode(buf)
+	}
+	writeLine(buf, "END:"+c.Name)
+}
+
+// writeLine writes a content line, folding it every 75 octets without
+// splitting characters
+func writeLine(buf *bytes.Buffer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Continuation lines start with the space, which counts
+		limit = maxLineOctets - 1
+	}
+	buf.WriteString(line + "\r\n")
+}
+
+// Parse reads an iCalendar object, which must be a single VCALENDAR
+func Parse(data []byte) (*Component, error) {
+	if !utf8.Valid(data) {
+		return nil, errors.New("calendar isn't valid UTF-8")
+	}
+	var root *Component
+	var stack []*Component
+	for n, line := range unfold(string(data)) {
+		if line == "" {
+			continue
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		switch p.Name {
+		case "BEGIN":
+			if root != nil && len(stack) == 0 {
+				return nil, errors.New("only one calendar may be sent")
+			}
+			if len(stack) == maxDepth {
+				return nil, errors.New("components are nested too deeply")
+			}
+			child := NewComponent(strings.ToUpper(p.Value))
+			if len(stack) == 0 {
+				root = child
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, child)
+			}
+			stack = append(stack, child)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return nil, fmt.Errorf("END:%s doesn't close the open component", p.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("%s is outside any component", p.Name)
+			}
+			current := stack[len(stack)-1]
+			current.Props = append(current.Props, p)
+		}
+	}
+	switch {
+	case root == nil:
+		return nil, errors.New("no calendar was sent")
+	case len(stack) > 0:
+		return nil, fmt.Errorf("%s isn't closed", stack[len(stack)-1].Name)
+	case root.Name != "VCALENDAR":
+		return nil, fmt.Errorf("expected a VCALENDAR, not a %s", root.Name)
+	}
+	return root, nil
+}
+
+// unfold splits data into content lines, joining folded lines back up.
+// Bare LF line endings are accepted as well as CRLF.
+func unfold(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseLine splits a content line into its name, parameters and value
+func parseLine(line string) (Property, error) {
+	end := strings.IndexAny(line, ";:")
+	if end <= 0 {
+		return Property{}, errors.New("expected NAME:value")
+	}
+	p := Property{Name: strings.ToUpper(line[:end])}
+	rest := line[end:]
+	for strings.HasPrefix(rest, ";") {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return Property{}, fmt.Errorf("parameter of %s has no value", p.Name)
+		}
+		name := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quote := strings.IndexByte(rest[1:], '"')
+			if quote < 0 {
+				return Property{}, fmt.Errorf("parameter %s of %s isn't closed", name, p.Name)
+			}
+			value, rest = rest[1:quote+1], rest[quote+2:]
+		} else {
+			stop := strings.IndexAny(rest, ";:")
+			if stop < 0 {
+				return Property{}, fmt.Errorf("%s has no value", p.Name)
+			}
+			value, rest = rest[:stop], rest[stop:]
+		}
+		if p.Params == nil {
+			p.Params = map[string]string{}
+		}
+		p.Params[name] = value
+	}
+	if !strings.HasPrefix(rest, ":") {
+		return Property{}, fmt.Errorf("%s has no value", p.Name)
+	}
+	p.Value = rest[1:]
+	return p, nil
+}
+
+// EscapeText escapes a TEXT value
+func EscapeText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "").Replace(s)
+}
+
+// UnescapeText decodes an escaped TEXT value
+func UnescapeText(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// googleAPI is the base URL of the Google Tasks API
+const googleAPI = "https://tasks.googleapis.com/tasks/v1"
+
+type googleLists struct {
+	Items []struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+type googleTasks struct {
+	Items []struct {
+		ID     string `json:"id"`
+		Title  string `json:"title"`
+		Notes  string `json:"notes"`
+		Status string `json:"status"`
+		// Due is a timestamp whose time is always midnight UTC; only the
+		// date is used
+		Due       string `json:"due"`
+		Completed string `json:"completed"`
+		Deleted   bool   `json:"deleted"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// fetchGoogle reads the tasks of every list, including completed ones that
+// were cleared from view. Subtasks come in as tasks of their own.
+func fetchGoogle(ctx context.Context, c *apiClient) ([]Task, error) {
+	var tasks []Task
+	pageToken := ""
+	for {
+		var lists googleLists
+		query := url.Values{"maxResults": {"100"}, "pageToken": {pageToken}}
+		if err := c.get(ctx, googleAPI+"/users/@me/lists?"+query.Encode(), &lists); err != nil {
+			return nil, err
+		}
+		for _, list := range lists.Items {
+			var err error
+			if tasks, err = fetchGoogleList(ctx, c, list.ID, list.Title, tasks); err != nil {
+				return nil, err
+			}
+		}
+		if pageToken = lists.NextPageToken; pageToken == "" {
+			return tasks, nil
+		}
+	}
+}
+
+// fetchGoogleList appends the tasks of one list to tasks
+func fetchGoogleList(ctx context.Context, c *apiClient, id, name string, tasks []Task) ([]Task, error) {
+	pageToken := ""
+	for {
+		var page googleTasks
+		query := url.Values{
+			"maxResults":    {"100"},
+			"showCompleted": {"true"},
+			"showHidden":    {"true"},
+			"pageToken":     {pageToken},
+		}
+		if err := c.get(ctx, googleAPI+"/lists/"+url.PathEscape(id)+"/tasks?"+query.Encode(), &page); err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			if item.Deleted {
+				continue
+			}
+			task := Task{
+				ID:        item.ID,
+				List:      name,
+				Title:     item.Title,
+				Notes:     item.Notes,
+				Due:       datePart(item.Due),
+				Completed: item.Status == "completed",
+			}
+			if t, err := time.Parse(time.RFC3339, item.Completed); err == nil && task.Completed {
+				task.CompletedAt = &t
+			}
+			if len(tasks) == MaxTasks {
+				return nil, ErrTooManyTasks
+			}
+			tasks = append(tasks, task)
+		}
+		if pageToken = page.NextPageToken; pageToken == "" {
+			return tasks, nil
+		}
+	}
+}
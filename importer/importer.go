@@ -0,0 +1,209 @@
+// Package importer reads a user's tasks from other task services, Google
+// Tasks and Microsoft To Do, so they can be brought over as todos. Users
+// allow access with OAuth 2.0: they're sent to the service's consent page,
+// which sends them back to the app with a code the server exchanges for a
+// short-lived access token. Tokens are only used for the one import and
+// never stored.
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"todo-api/config"
+)
+
+var (
+	// ErrInvalidCode is returned when the service rejects an authorization
+	// code, which happens once it expired or was already used
+	ErrInvalidCode = errors.New("authorization code was rejected")
+	// ErrTooManyTasks is returned for accounts with more than MaxTasks tasks
+	ErrTooManyTasks = fmt.Errorf("account has more than %d tasks", MaxTasks)
+)
+
+// MaxTasks is the most tasks read in one import
+const MaxTasks = 5000
+
+// maxResponseBytes bounds each response read from a service
+const maxResponseBytes = 4 << 20
+
+// Task is a task read from a service
+type Task struct {
+	// ID identifies the task within its service
+	ID string
+	// List is the name of the list the task is in
+	List  string
+	Title string
+	Notes string
+	// Due is the day the task is due, as YYYY-MM-DD. Neither service gives
+	// due tasks a time that apps can set.
+	Due       string
+	Important bool
+	Completed bool
+	// CompletedAt is set for completed tasks when the service says when
+	CompletedAt *time.Time
+}
+
+// Provider is a service tasks are imported from
+type Provider struct {
+	// Name is how the provider is named in URLs and in the todos it
+	// imports, such as "google"
+	Name string
+	// Title is the service's name as users know it
+	Title string
+
+	client      *http.Client
+	clientID    string
+	secret      string
+	redirectURL string
+	authURL     string
+	tokenURL    string
+	scope       string
+	fetch       func(ctx context.Context, c *apiClient) ([]Task, error)
+}
+
+// New returns the providers whose OAuth clients are configured, by name
+func New(cfg config.ImportConfig) map[string]*Provider {
+	client := &http.Client{Timeout: cfg.Timeout}
+	providers := map[string]*Provider{}
+	if cfg.Google.Configured() {
+		providers["google"] = &Provider{
+			Name:        "google",
+			Title:       "Google Tasks",
+			client:      client,
+			clientID:    cfg.Google.ID,
+			secret:      cfg.Google.Secret,
+			redirectURL: cfg.RedirectURL,
+			authURL:     "https://accounts.google.com/o/oauth2/v2/auth",
+			tokenURL:    "https://oauth2.googleapis.com/token",
+			scope:       "https://www.googleapis.com/auth/tasks.readonly",
+			fetch:       fetchGoogle,
+		}
+	}
+	if cfg.Microsoft.Configured() {
+		providers["microsoft"] = &Provider{
+			Name:        "microsoft",
+			Title:       "Microsoft To Do",
+			client:      client,
+			clientID:    cfg.Microsoft.ID,
+			secret:      cfg.Microsoft.Secret,
+			redirectURL: cfg.RedirectURL,
+			authURL:     "https://login.microsoftonline.com/common/oauth2/v2.0/authorize",
+			tokenURL:    "https://login.microsoftonline.com/common/oauth2/v2.0/token",
+			scope:       "Tasks.Read",
+			fetch:       fetchMicrosoft,
+		}
+	}
+	return providers
+}
+
+// AuthorizationURL is the consent page users are sent to. state comes back
+// with the code, tying it to the import it was asked for.
+func (p *Provider) AuthorizationURL(state string) string {
+	query := url.Values{
+		"client_id":     {p.clientID},
+		"redirect_uri":  {p.redirectURL},
+		"response_type": {"code"},
+		"scope":         {p.scope},
+		"state":         {state},
+	}
+	return p.authURL + "?" + query.Encode()
+}
+
+// Tasks exchanges an authorization code for an access token and reads
+// every task with it
+func (p *Provider) Tasks(ctx context.Context, code string) ([]Task, error) {
+	token, err := p.exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	return p.fetch(ctx, &apiClient{http: p.client, token: token})
+}
+
+// exchange redeems an authorization code at the token endpoint
+func (p *Provider) exchange(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"client_id":     {p.clientID},
+		"client_secret": {p.secret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&body); err != nil {
+		return "", fmt.Errorf("token endpoint responded with status %d", resp.StatusCode)
+	}
+	if body.Error == "invalid_grant" {
+		return "", ErrInvalidCode
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return "", fmt.Errorf("token endpoint responded with status %d: %s", resp.StatusCode, body.Error)
+	}
+	return body.AccessToken, nil
+}
+
+// apiClient calls a service's API with an access token
+type apiClient struct {
+	http  *http.Client
+	token string
+}
+
+// get reads the JSON at link into v
+func (c *apiClient) get(ctx context.Context, link string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// url.Error quotes the whole link; the path is enough
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s: %w", req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %d", req.URL.Path, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(v)
+}
+
+// datePart returns the YYYY-MM-DD date at the start of a timestamp, or ""
+func datePart(s string) string {
+	if len(s) < len("2006-01-02") {
+		return ""
+	}
+	if _, err := time.Parse(time.DateOnly, s[:10]); err != nil {
+		return ""
+	}
+	return s[:10]
+}
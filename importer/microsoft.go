@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"context"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// graphAPI is the base URL of Microsoft Graph
+const graphAPI = "https://graph.microsoft.com/v1.0"
+
+// graphTimeFormat is how Graph writes the dateTime of a dateTimeTimeZone
+const graphTimeFormat = "2006-01-02T15:04:05.9999999"
+
+// Tags of HTML notes: those that end a line, and the rest
+var (
+	htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|h[1-6])>`)
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+)
+
+type graphLists struct {
+	Value []struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"displayName"`
+	} `json:"value"`
+	NextLink string `json:"@odata.nextLink"`
+}
+
+type graphTasks struct {
+	Value []struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+		Body  struct {
+			Content     string `json:"content"`
+			ContentType string `json:"contentType"`
+		} `json:"body"`
+		Importance        string             `json:"importance"`
+		Status            string             `json:"status"`
+		DueDateTime       *graphDateTimeZone `json:"dueDateTime"`
+		CompletedDateTime *graphDateTimeZone `json:"completedDateTime"`
+	} `json:"value"`
+	NextLink string `json:"@odata.nextLink"`
+}
+
+// graphDateTimeZone is a time without an offset and the zone it's in
+type graphDateTimeZone struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+// time reads the value, falling back to UTC for zones Go doesn't know, such
+// as Windows zone names
+func (d *graphDateTimeZone) time() (time.Time, error) {
+	loc, err := time.LoadLocation(d.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return time.ParseInLocation(graphTimeFormat, d.DateTime, loc)
+}
+
+// fetchMicrosoft reads the tasks of every list, following Graph's paging
+// links
+func fetchMicrosoft(ctx context.Context, c *apiClient) ([]Task, error) {
+	var tasks []Task
+	link := graphAPI + "/me/todo/lists"
+	for link != "" {
+		var lists graphLists
+		if err := c.get(ctx, link, &lists); err != nil {
+			return nil, err
+		}
+		for _, list := range lists.Value {
+			var err error
+			if tasks, err = fetchMicrosoftList(ctx, c, list.ID, list.DisplayName, tasks); err != nil {
+				return nil, err
+			}
+		}
+		link = lists.NextLink
+	}
+	return tasks, nil
+}
+
+// fetchMicrosoftList appends the tasks of one list to tasks
+func fetchMicrosoftList(ctx context.Context, c *apiClient, id, name string, tasks []Task) ([]Task, error) {
+	link := graphAPI + "/me/todo/lists/" + url.PathEscape(id) + "/tasks?$top=100"
+	for link != "" {
+		var page graphTasks
+		if err := c.get(ctx, link, &page); err != nil {
+			return nil, err
+		}
+		for _, item := range page.Value {
+			task := Task{
+				ID:        item.ID,
+				List:      name,
+				Title:     item.Title,
+				Notes:     item.Body.Content,
+				Important: item.Importance == "high",
+				Completed: item.Status == "completed",
+			}
+			if strings.EqualFold(item.Body.ContentType, "html") {
+				task.Notes = plainText(task.Notes)
+			}
+			// Due dates are set as a day, at midnight in the user's zone
+			if item.DueDateTime != nil {
+				task.Due = datePart(item.DueDateTime.DateTime)
+			}
+			if item.CompletedDateTime != nil && task.Completed {
+				if t, err := item.CompletedDateTime.time(); err == nil {
+					task.CompletedAt = &t
+				}
+			}
+			if len(tasks) == MaxTasks {
+				return nil, ErrTooManyTasks
+			}
+			tasks = append(tasks, task)
+		}
+		link = page.NextLink
+	}
+	return tasks, nil
+}
+
+// plainText turns HTML notes into text, keeping line breaks
+func plainText(s string) string {
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlTag.ReplaceAllString(s, "")
+	return strings.TrimSpace(html.UnescapeString(s))
+}
@@ -0,0 +1,139 @@
+// Package integrations keeps track of how the external services the API
+// talks to, such as Slack, the mailer and Redis, have been doing, so users
+// and operators can see why notifications or caching stopped working
+package integrations
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"todo-api/logging"
+)
+
+// recentWindow is how far back errors count as recent
+const recentWindow = time.Hour
+
+// maxRecentErrors caps the error times kept per integration
+const maxRecentErrors = 1000
+
+// Statuses of an integration, by the outcome of its latest call
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+	// StatusIdle means it hasn't been called since the instance started
+	StatusIdle = "idle"
+)
+
+// Monitor holds the integrations that are configured, in the order they
+// were added. Outcomes are kept in the instance, so each instance reports
+// the calls it made itself.
+type Monitor struct {
+	mu      sync.Mutex
+	tracked []*Tracker
+}
+
+// NewMonitor creates a Monitor without integrations
+func NewMonitor() *Monitor {
+	return &Monitor{}
+}
+
+// Track adds an integration of the given name and kind, such as "slack" of
+// kind "notifications", and returns the Tracker its calls are recorded in
+func (m *Monitor) Track(name, kind string) *Tracker {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := &Tracker{name: name, kind: kind}
+	m.tracked = append(m.tracked, t)
+	return t
+}
+
+// Status is how an integration has been doing
+type Status struct {
+	Name          string     `json:"name"`
+	Kind          string     `json:"kind"`
+	Status        string     `json:"status"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	LastFailureAt *time.Time `json:"last_failure_at"`
+	// LastError is the error of the latest failure, with secrets redacted
+	LastError string `json:"last_error,omitempty"`
+	// RecentErrors counts the failures in the last hour
+	RecentErrors int `json:"recent_errors"`
+}
+
+// Statuses reports every integration as of now
+func (m *Monitor) Statuses(now time.Time) []Status {
+	m.mu.Lock()
+	tracked := append([]*Tracker(nil), m.tracked...)
+	m.mu.Unlock()
+
+	statuses := make([]Status, len(tracked))
+	for i, t := range tracked {
+		statuses[i] = t.status(now)
+	}
+	return statuses
+}
+
+// Tracker records the outcomes of calls to one integration
+type Tracker struct {
+	name, kind string
+
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   string
+	// failures are the times of the failures in the recent window, oldest
+	// first
+	failures []time.Time
+}
+
+// Record records the outcome of a call made now: a success when err is nil.
+// Calls cancelled by their caller don't count.
+func (t *Tracker) Record(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err == nil {
+		t.lastSuccess = now
+		return
+	}
+	t.lastFailure, t.lastError = now, logging.Error(err)
+	t.failures = append(t.prune(now), now)
+	if len(t.failures) > maxRecentErrors {
+		t.failures = t.failures[len(t.failures)-maxRecentErrors:]
+	}
+}
+
+// prune drops the failures from before the recent window. Call with mu
+// held.
+func (t *Tracker) prune(now time.Time) []time.Time {
+	cutoff := now.Add(-recentWindow)
+	i := 0
+	for i < len(t.failures) && !t.failures[i].After(cutoff) {
+		i++
+	}
+	return t.failures[i:]
+}
+
+func (t *Tracker) status(now time.Time) Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.failures = t.prune(now)
+	s := Status{Name: t.name, Kind: t.kind, Status: StatusIdle, RecentErrors: len(t.failures)}
+	if !t.lastSuccess.IsZero() {
+		success := t.lastSuccess.UTC()
+		s.LastSuccessAt, s.Status = &success, StatusOK
+	}
+	if !t.lastFailure.IsZero() {
+		failure := t.lastFailure.UTC()
+		s.LastFailureAt, s.LastError = &failure, t.lastError
+		if failure.After(t.lastSuccess) {
+			s.Status = StatusFailing
+		}
+	}
+	return s
+}
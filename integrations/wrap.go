@@ -0,0 +1,84 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"todo-api/cache"
+	"todo-api/events"
+	"todo-api/notify"
+)
+
+// Notifier records the outcome of each notification sent through n. Users
+// the channel can't reach don't count either way.
+func Notifier(n notify.Notifier, t *Tracker) notify.Notifier {
+	return &trackedNotifier{Notifier: n, t: t}
+}
+
+type trackedNotifier struct {
+	notify.Notifier
+	t *Tracker
+}
+
+func (n *trackedNotifier) Notify(ctx context.Context, to notify.Recipient, msg notify.Notification) error {
+	err := n.Notifier.Notify(ctx, to, msg)
+	if !errors.Is(err, notify.ErrUnreachable) {
+		n.t.Record(err)
+	}
+	return err
+}
+
+// Publisher records the outcome of each batch published through p
+func Publisher(p events.Publisher, t *Tracker) events.Publisher {
+	return &trackedPublisher{next: p, t: t}
+}
+
+type trackedPublisher struct {
+	next events.Publisher
+	t    *Tracker
+}
+
+func (p *trackedPublisher) Publish(ctx context.Context, batch ...events.Event) error {
+	err := p.next.Publish(ctx, batch...)
+	p.t.Record(err)
+	return err
+}
+
+// Cache records the outcome of each call to c. Misses are successes.
+func Cache(c cache.Cache, t *Tracker) cache.Cache {
+	return &trackedCache{next: c, t: t}
+}
+
+type trackedCache struct {
+	next cache.Cache
+	t    *Tracker
+}
+
+func (c *trackedCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.next.Get(ctx, key)
+	if errors.Is(err, cache.ErrMiss) {
+		c.t.Record(nil)
+	} else {
+		c.t.Record(err)
+	}
+	return value, err
+}
+
+func (c *trackedCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	err := c.next.Set(ctx, key, value, ttl)
+	c.t.Record(err)
+	return err
+}
+
+func (c *trackedCache) Delete(ctx context.Context, keys ...string) error {
+	err := c.next.Delete(ctx, keys...)
+	c.t.Record(err)
+	return err
+}
+
+func (c *trackedCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	n, err := c.next.Incr(ctx, key, ttl)
+	c.t.Record(err)
+	return n, err
+}
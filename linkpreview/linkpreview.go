@@ -0,0 +1,219 @@
+// Package linkpreview fetches the OpenGraph metadata of pages linked from
+// todos, so clients can show link cards without each of them fetching
+// every page.
+//
+// Pages are fetched on behalf of users, who choose the links, so requests
+// are kept away from anything that isn't the public web: every connection,
+// including those made by redirects, is checked against the address it
+// actually dials, which also defeats DNS names that resolve to internal
+// addresses. Loopback, private, link-local (cloud metadata endpoints among
+// them) and other special-purpose addresses are refused, no proxy is used,
+// and only the usual web ports are allowed.
+package linkpreview
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"todo-api/cache"
+	"todo-api/config"
+	"todo-api/models"
+)
+
+var (
+	// ErrBlocked is returned for links to addresses previews aren't
+	// fetched from
+	ErrBlocked = errors.New("link points to a blocked address")
+	// ErrNoPreview is returned when a page isn't HTML or has nothing to
+	// show
+	ErrNoPreview = errors.New("page has no preview")
+)
+
+// maxRedirects is how many redirects are followed from a link
+const maxRedirects = 5
+
+// userAgent identifies preview requests to the sites they go to
+const userAgent = "todo-api-linkpreview/1.0"
+
+// allowedPorts are the ports pages are fetched from
+var allowedPorts = map[string]bool{"80": true, "443": true, "8080": true, "8443": true}
+
+// blockedNetworks are special-purpose ranges that net/netip doesn't already
+// classify as private, loopback, link-local or multicast
+var blockedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, which can reach internal IPv4 addresses
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4, which embeds IPv4 addresses
+	netip.MustParsePrefix("fec0::/10"),       // deprecated site-local
+}
+
+// Fetcher fetches link previews, caching them by link
+type Fetcher struct {
+	client   *http.Client
+	cache    cache.Cache
+	ttl      time.Duration
+	maxBytes int64
+}
+
+// New creates a Fetcher that caches previews in c
+func New(cfg config.LinkPreviewConfig, c cache.Cache) *Fetcher {
+	dialer := &net.Dialer{Timeout: cfg.Timeout, Control: checkAddress}
+	transport := &http.Transport{
+		// A proxy would dial on our behalf, out of reach of the checks
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    cfg.Timeout,
+		ResponseHeaderTimeout:  cfg.Timeout,
+		MaxResponseHeaderBytes: 64 << 10,
+		MaxIdleConns:           10,
+		IdleConnTimeout:        30 * time.Second,
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to a %s URL", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+	return &Fetcher{client: client, cache: c, ttl: cfg.CacheTTL, maxBytes: int64(cfg.MaxBytes)}
+}
+
+// Fetch returns the preview of the page at link, from the cache when it was
+// fetched recently
+func (f *Fetcher) Fetch(ctx context.Context, link string) (*models.LinkPreview, error) {
+	key := cacheKey(link)
+	if data, err := f.cache.Get(ctx, key); err == nil {
+		var preview models.LinkPreview
+		if err := json.Unmarshal(data, &preview); err == nil {
+			return &preview, nil
+		}
+	} else if !errors.Is(err, cache.ErrMiss) {
+		log.Println("Link preview cache read failed:", err)
+	}
+
+	preview, err := f.fetch(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(preview); err == nil {
+		if err := f.cache.Set(ctx, key, data, f.ttl); err != nil {
+			log.Println("Link preview cache write failed:", err)
+		}
+	}
+	return preview, nil
+}
+
+// fetch reads the head of the page at link and parses its tags
+func (f *Fetcher) fetch(ctx context.Context, link string) (*models.LinkPreview, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		// url.Error quotes the whole link, which mustn't reach the logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("page responded with status %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNoPreview
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	preview := parse(page, resp.Request.URL)
+	if preview.Title == "" && preview.Description == "" && preview.Image == "" {
+		return nil, ErrNoPreview
+	}
+	preview.FetchedAt = time.Now().UTC()
+	return preview, nil
+}
+
+// checkAddress refuses connections to anything but public addresses on the
+// allowed ports. It runs for every connection the client makes, after the
+// host name is resolved.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !allowedPorts[port] || !public(addr) {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// public reports whether addr is a public unicast address previews may be
+// fetched from
+func public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Host returns the host of link, which is all that's logged of it, since
+// paths and queries can carry tokens
+func Host(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	return u.Hostname()
+}
+
+// cacheKey is the key a link's preview is cached under. Links are hashed to
+// keep keys short.
+func cacheKey(link string) string {
+	sum := sha256.Sum256([]byte(link))
+	return "linkpreview:" + hex.EncodeToString(sum[:])
+}
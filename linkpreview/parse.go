@@ -0,0 +1,117 @@
+package linkpreview
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"todo-api/models"
+)
+
+// Longest values kept from a page, in characters
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+	maxSiteNameLength    = 100
+)
+
+var (
+	headEnd   = regexp.MustCompile(`(?i)</head\s*>|<body[\s>]`)
+	metaTag   = regexp.MustCompile(`(?is)<meta\s([^>]*)>`)
+	titleTag  = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title\s*>`)
+	attribute = regexp.MustCompile(`(?is)([a-z][a-z0-9_:.-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// parse reads a preview from the tags in the head of page, which was served
+// from base. OpenGraph tags come first, then Twitter card tags, then the
+// page's own title and description.
+func parse(page []byte, base *url.URL) *models.LinkPreview {
+	if loc := headEnd.FindIndex(page); loc != nil {
+		page = page[:loc[0]]
+	}
+
+	tags := map[string]string{}
+	for _, m := range metaTag.FindAllSubmatch(page, -1) {
+		attrs := attributes(m[1])
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(key)
+		// The first of repeated tags wins, as with og:image
+		if _, seen := tags[key]; key != "" && !seen {
+			tags[key] = attrs["content"]
+		}
+	}
+	title := ""
+	if m := titleTag.FindSubmatch(page); m != nil {
+		title = string(m[1])
+	}
+
+	preview := &models.LinkPreview{
+		Title:       clean(first(tags["og:title"], tags["twitter:title"], title), maxTitleLength),
+		Description: clean(first(tags["og:description"], tags["twitter:description"], tags["description"]), maxDescriptionLength),
+		SiteName:    clean(tags["og:site_name"], maxSiteNameLength),
+		Image:       resolve(base, first(tags["og:image:secure_url"], tags["og:image"], tags["twitter:image"])),
+		URL:         resolve(base, tags["og:url"]),
+	}
+	if preview.URL == "" {
+		preview.URL = base.String()
+	}
+	return preview
+}
+
+// attributes reads the attributes of a tag, with lowercase names and
+// entities decoded in the values
+func attributes(tag []byte) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range attribute.FindAllSubmatch(tag, -1) {
+		value := m[2]
+		if value == nil {
+			value = m[3]
+		}
+		if value == nil {
+			value = m[4]
+		}
+		attrs[strings.ToLower(string(m[1]))] = html.UnescapeString(string(bytes.TrimSpace(value)))
+	}
+	return attrs
+}
+
+// first returns the first value that isn't empty
+func first(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clean decodes entities, collapses whitespace and shortens text to max
+// characters. Invalid UTF-8, as from pages in other encodings, is dropped.
+func clean(text string, max int) string {
+	text = strings.Join(strings.Fields(html.UnescapeString(strings.ToValidUTF8(text, ""))), " ")
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
+
+// resolve makes ref absolute against base, returning "" unless the result
+// is an http or https URL
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || len(ref) > models.MaxAttachmentURLLength {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
@@ -1,75 +1,1035 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	_ "time/tzdata" // user time zones must resolve in minimal images without zoneinfo
 
+	"todo-api/apierrors"
+	"todo-api/audit"
+	"todo-api/auth"
+	"todo-api/azure"
+	"todo-api/buildinfo"
+	"todo-api/cache"
+	"todo-api/config"
+	"todo-api/dailyreset"
 	"todo-api/database"
+	"todo-api/digest"
+	"todo-api/events"
+	"todo-api/fieldcrypt"
 	"todo-api/handlers"
+	"todo-api/importer"
+	"todo-api/integrations"
+	"todo-api/linkpreview"
+	"todo-api/logging"
 	"todo-api/middleware"
+	"todo-api/migrations"
+	"todo-api/models"
+	"todo-api/notify"
+	"todo-api/repository"
+	"todo-api/resilience"
+	"todo-api/retention"
+	"todo-api/schema"
+	"todo-api/secrets"
+	"todo-api/seed"
+	"todo-api/slack"
+	"todo-api/stale"
+	"todo-api/tenancy"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	// Keep secrets out of the logs, before anything is logged
+	log.SetOutput(logging.NewWriter(os.Stderr))
+	gin.DefaultWriter = logging.NewWriter(os.Stdout)
+	gin.DefaultErrorWriter = logging.NewWriter(os.Stderr)
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using system environment variables")
 	}
+	build := buildinfo.Get()
+	log.Printf("Todo API build %s (built %s, %s)", build.Version, orUnknown(build.BuildTime), build.GoVersion)
 
-	// Connect to database
-	database.Connect()
+	// Pull secrets from Key Vault when configured, falling back to env vars
+	secretsCfg, err := config.LoadSecrets()
+	if err != nil {
+		log.Fatal(err)
+	}
+	getenv := os.Getenv
+	var vault *secrets.KeyVault
+	if secretsCfg.KeyVaultURL != "" {
+		vault = secrets.NewKeyVault(secretsCfg.KeyVaultURL, azure.NewManagedIdentity(secretsCfg.ClientID))
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := vault.Load(ctx, config.SecretKeys...)
+		cancel()
+		if err != nil {
+			log.Fatal("Failed to load secrets:", err)
+		}
+		getenv = vault.Getenv(os.Getenv)
+		log.Printf("Loaded secrets from %s", secretsCfg.KeyVaultURL)
+	}
+	addSecrets(getenv)
 
-	// Setup Gin router
-	router := gin.Default()
-
-	// Setup CORS to allow specific origins (required when using credentials)
-	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{
-		"http://localhost:3000", // React default
-		"http://localhost:5173", // Vite default
-		"http://localhost:8080", // Same origin
-		"http://127.0.0.1:3000",
-		"http://127.0.0.1:5173",
-		"http://127.0.0.1:8080",
-		"https://todo-backend-app-2024.azurewebsites.net", // Azure App Service
-		"https://*.azurewebsites.net",                     // All Azure App Service domains
-	}
-	config.AllowCredentials = true
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	router.Use(cors.New(config))
+	// Load and validate configuration
+	cfg, err := config.LoadFrom(getenv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	preflight("configuration", checkConfig(cfg))
 
-	// Apply authentication middleware to all routes
-	router.Use(middleware.AuthMiddleware())
+	// Connect to the configured storage backend, and check that its indexes
+	// are in place and it can be written to before taking traffic. Startup
+	// migrations are applied in the background, which can take a while on
+	// large collections; todos they haven't reached yet, or that older
+	// instances write during a rolling deployment, are upgraded as they're
+	// read.
+	stores, readinessChecks, ensureIndexes, migrate := openStorage(cfg)
+	preflight("storage", checkStorage(cfg, stores, ensureIndexes))
+	stores.Todos = repository.NewUpgradingTodoRepository(stores.Todos)
+	runInBackground(cfg, func(ctx context.Context) {
+		if err := migrate(ctx); err != nil {
+			log.Println("Startup migrations failed:", err)
+		}
+	})
 
-	// API routes
-	api := router.Group("/api/v1")
-	{
-		api.GET("/todos", handlers.GetTodos)
-		api.POST("/todos", handlers.CreateTodo)
-		api.PUT("/todos/:id", handlers.UpdateTodo)
-		api.DELETE("/todos/:id", handlers.DeleteTodo)
+	// Calls to external services are tracked as they're wired up below,
+	// for GET /integrations/status
+	monitor := integrations.NewMonitor()
+
+	// Cache todo listings in Redis when configured. Undo records and Slack
+	// link codes are kept there too, or in the process without Redis. Each
+	// tenant's keys are kept apart.
+	var tokenCache cache.Cache = cache.NewMemoryCache()
+	if cfg.Cache.RedisURL != "" {
+		redisCache, err := cache.NewRedisCache(cfg.Cache.RedisURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tokenCache = integrations.Cache(redisCache, monitor.Track("redis", "cache"))
+		listings := tokenCache
+		if cfg.Tenancy.Enabled() {
+			listings = tenancy.ScopedCache(listings)
+		}
+		stores.Todos = repository.NewCachedTodoRepository(stores.Todos, listings, cfg.Cache.TTL)
+		readinessChecks = append(readinessChecks, handlers.ReadinessCheck{Name: "cache", Check: redisCache.Ping, Optional: true})
+		log.Println("Caching todo listings in Redis")
+	}
+	if cfg.Tenancy.Enabled() {
+		tokenCache = tenancy.ScopedCache(tokenCache)
+	}
+
+	// Encrypt todo titles and descriptions, their revisions, the events
+	// about them and the undo records of deleted ones before they're stored
+	// or cached
+	var cipher *fieldcrypt.Cipher
+	undoCache := tokenCache
+	if cfg.Encryption.Enabled() {
+		cipher, err = fieldcrypt.NewCipher(cfg.Encryption.Key, cfg.Encryption.PreviousKeys...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		stores.Todos = repository.NewEncryptedTodoRepository(stores.Todos, cipher)
+		stores.Revisions = repository.NewEncryptedRevisionRepository(stores.Revisions, cipher)
+		stores.Outbox = repository.NewEncryptedOutboxRepository(stores.Outbox, cipher)
+		undoCache = cache.Encrypted(tokenCache, cipher)
+		if cfg.Encryption.Key != nil {
+			log.Printf("Encrypting todo titles and descriptions with key %s", fieldcrypt.KeyID(cfg.Encryption.Key))
+		} else {
+			log.Println("Decrypting todo titles and descriptions; changes are stored in plain text")
+		}
+	}
+
+	// Todo events are written to the outbox in the same transaction as each
+	// change and relayed to the broker and notifications in the background
+	var relayed events.Publisher
+	if broker := openBroker(cfg); broker != nil {
+		relayed = integrations.Publisher(broker, monitor.Track(cfg.Events.Backend, "events"))
+	}
+
+	// Notifications go out on the channels configured, each user picking
+	// which for each kind. Email gets digests and Slack the rest unless
+	// users say otherwise.
+	notifications := notify.NewRegistry(stores.Users)
+	if mailer := openMailer(cfg); mailer != nil {
+		channel := integrations.Notifier(digest.NewEmailChannel(mailer), monitor.Track(cfg.Digest.Mailer, "notifications"))
+		notifications.Register(channel, models.NotifyDigest)
+	}
+	if cfg.Slack.WebhookURL != "" {
+		webhook := slack.NewWebhook(cfg.Slack.WebhookURL)
+		channel := integrations.Notifier(slack.NewChannel(stores.SlackLinks, webhook), monitor.Track("slack", "notifications"))
+		notifications.Register(channel, models.NotifyAssigned, models.NotifyMentioned, models.NotifyOverdue, models.NotifyStale)
+	}
+	if len(notifications.Channels()) > 0 {
+		// Notify users when todos are assigned to them or comments
+		// mention them
+		if relayed == nil {
+			relayed = events.Nop{}
+		}
+		relayed = notify.NewPublisher(relayed, notifications)
+
+		// Send digests to the users who opted in
+		scheduler := digest.NewScheduler(stores.Users, stores.Todos, notifications, cfg.Digest.Hour, cfg.Digest.Weekday, cfg.Digest.Interval)
+		runInBackground(cfg, scheduler.Run)
 	}
 
-	// Health check endpoint
+	var publisher events.Publisher = events.Nop{}
+	if relayed != nil {
+		publisher = events.NewOutbox(stores.Outbox)
+		relay := events.NewRelay(stores.Outbox, relayed, cfg.Events.RelayInterval)
+		runInBackground(cfg, relay.Run)
+	}
+
+	// Tell users who linked Slack when their todos become overdue
+	if cfg.Slack.WebhookURL != "" {
+		notifier := slack.NewOverdueNotifier(stores.SlackLinks, stores.Users, stores.Todos, notifications, cfg.Slack.OverdueInterval)
+		runInBackground(cfg, notifier.Run)
+	}
+
+	// Forget deletes once no sync token can reach back to them
+	runInBackground(cfg, retention.NewTombstonePruner(stores.Tombstones, cfg.SyncWindow, cfg.Retention.SweepInterval).Run)
+
+	// Purge todos of anonymous users that haven't been seen for a while.
+	// Signed-in Entra ID users can always come back, so their data is kept.
+	var sweeper *retention.Sweeper
+	if cfg.Retention.InactiveAfter > 0 && cfg.Auth.Mode == config.AuthModeCookie {
+		sweeper = retention.NewSweeper(stores.Todos, stores.CustomFields, stores.Filters, stores.Revisions, stores.Tombstones, stores.SlackLinks, stores.Users, stores.Tx, cfg.Retention.InactiveAfter, cfg.Retention.SweepInterval)
+		runInBackground(cfg, sweeper.Run)
+	}
+
+	// Setup Gin router
+	router := gin.New()
+	router.Use(middleware.LoggerMiddleware(), middleware.RecoveryMiddleware())
+	router.HandleMethodNotAllowed = true
+	if err := middleware.TrustProxies(router, cfg.IPFilter); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	router.NoRoute(func(c *gin.Context) {
+		apierrors.Respond(c, apierrors.CodeRouteNotFound, "No route matches "+c.Request.URL.Path)
+	})
+	router.NoMethod(func(c *gin.Context) {
+		apierrors.Respond(c, apierrors.CodeMethodNotAllowed, c.Request.Method+" is not allowed on "+c.Request.URL.Path)
+	})
+
+	// Tag every request with an ID so error responses can be traced in logs
+	router.Use(middleware.RequestIDMiddleware())
+
+	// Name the build on every response, so it's clear which one each slot runs
+	router.Use(middleware.ServerVersionMiddleware(buildinfo.Get().Version))
+
+	// Setup CORS to allow the configured origins (required when using credentials)
+	router.Use(middleware.CORSMiddleware(cfg.CORS))
+
+	// Liveness and readiness probes and the build details are registered
+	// before the auth middleware so they don't mint a cookie on every probe
+	router.GET("/healthz", handlers.Healthz)
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status":  "ok",
 			"message": "Todo API is running",
 		})
 	})
+	router.GET("/readyz", handlers.Readyz(readinessChecks...))
+	router.GET("/version", handlers.Version)
+
+	// Refuse denied addresses everywhere but the probes
+	if len(cfg.IPFilter.Deny) > 0 {
+		router.Use(middleware.IPFilterMiddleware(cfg.IPFilter))
+	}
 
-	// Get port from environment or use default
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Turn requests away once the instance is handling as many as it can,
+	// before any work is done for them. Each user gets a share of that too,
+	// counted once they're known, the same for every route signed in to.
+	if cfg.Concurrency.Global > 0 {
+		router.Use(middleware.ConcurrencyLimitMiddleware(cfg.Concurrency.Global))
+	}
+	var userConcurrency []gin.HandlerFunc
+	if cfg.Concurrency.PerUser > 0 {
+		userConcurrency = append(userConcurrency, middleware.UserConcurrencyLimitMiddleware(cfg.Concurrency.PerUser))
 	}
 
-	log.Printf("Starting server on port %s", port)
-	if err := router.Run(":" + port); err != nil {
+	// Name the tenant of every request from here on, which the repositories
+	// pick its data by
+	if cfg.Tenancy.Enabled() {
+		router.Use(middleware.TenantMiddleware(cfg.Tenancy))
+	}
+
+	// Record every request from here on for security reviews, written in
+	// the background and kept for AUDIT_RETENTION
+	if cfg.Audit.Enabled {
+		recorder := audit.NewRecorder(stores.Audit)
+		go recorder.Run(context.Background())
+		runInBackground(cfg, retention.NewAuditPruner(stores.Audit, cfg.Audit.Retention, cfg.Retention.SweepInterval).Run)
+		router.Use(middleware.AuditMiddleware(recorder, cfg.Audit))
+		log.Printf("Recording an audit trail, kept for %s", cfg.Audit.Retention)
+	}
+
+	// Profiles and runtime statistics for the operators. They're routed
+	// before compression, the envelope and the request timeout, as CPU
+	// profiles and traces run for as long as asked.
+	if cfg.Admin.Token != "" {
+		debug := router.Group("/debug", middleware.AdminAuthMiddleware(cfg.Admin.Token))
+		debug.GET("/vars", handlers.DebugVars)
+		debug.GET("/pprof/*profile", handlers.Pprof)
+		debug.POST("/pprof/*profile", handlers.Pprof)
+	}
+
+	// Gzip larger responses for clients that accept it
+	router.Use(middleware.CompressMiddleware(cfg.CompressMinBytes))
+
+	// Wrap JSON responses in the standard envelope. It goes inside the
+	// compression, which needs the final body, and outside the timeout, so
+	// timeouts are wrapped too.
+	router.Use(middleware.EnvelopeMiddleware(cfg.ResponseEnvelope))
+
+	// Bound how long any single request may take
+	router.Use(middleware.TimeoutMiddleware(cfg.RequestTimeout))
+
+	// Reject oversized bodies before doing any work
+	router.Use(middleware.BodyLimitMiddleware(cfg.MaxBodyBytes))
+
+	quotaHandler := handlers.NewQuotaHandler(cfg.Quota, stores.Todos, stores.Workspaces, cfg.Storage.OperationTimeout)
+	settingsHandler := handlers.NewSettingsHandler(stores.Users, cfg.Storage.OperationTimeout)
+	notificationHandler := handlers.NewNotificationHandler(stores.Users, notifications, cfg.Storage.OperationTimeout)
+	todoHandler := handlers.NewTodoHandler(stores.Todos, stores.Shares, stores.PublicLinks, stores.CustomFields, stores.Filters, stores.Revisions, quotaHandler, settingsHandler, handlers.NewUndoLog(undoCache, cfg.UndoWindow), handlers.NewSyncLog(stores.Tombstones, cfg.SyncWindow), publisher, stores.Tx, cfg.Storage.OperationTimeout)
+
+	// Links attached to todos get a preview of the page they point to,
+	// cached alongside the undo records
+	var previews *linkpreview.Fetcher
+	if cfg.LinkPreview.Enabled {
+		previews = linkpreview.New(cfg.LinkPreview, tokenCache)
+	}
+	attachmentHandler := handlers.NewAttachmentHandler(todoHandler, previews, cfg.LinkPreview.Timeout)
+	commentHandler := handlers.NewCommentHandler(todoHandler)
+
+	// Tasks can be imported from the services whose OAuth clients are set
+	var importHandler *handlers.ImportHandler
+	if providers := importer.New(cfg.Import); len(providers) > 0 {
+		importHandler = handlers.NewImportHandler(providers, tokenCache, todoHandler)
+	}
+
+	// Purge old data by the configured rules, such as completed todos,
+	// deleting todos the way the API does so syncing clients hear of it
+	var policy *retention.Policy
+	if len(cfg.Retention.Rules) > 0 {
+		policy = retention.NewPolicy(cfg.Retention.Rules, stores.Users, stores.Todos, stores.Revisions, todoHandler, cfg.Retention.SweepInterval)
+		runInBackground(cfg, policy.Run)
+		log.Printf("Applying retention rules %v", cfg.Retention.Rules)
+	}
+
+	// Nudge users about personal todos that went stale, once a day
+	staleHandler := handlers.NewStaleHandler(todoHandler, cfg.Stale.After)
+	if cfg.Stale.Nudge && len(notifications.Channels()) > 0 {
+		nudger := stale.NewNudger(stores.Users, stores.Todos, notifications, cfg.Stale.After, cfg.Stale.Hour, cfg.Stale.Interval)
+		runInBackground(cfg, nudger.Run)
+	}
+
+	// Roll over and archive todos at the daily reset time users set,
+	// saving each change the way the API does so it shows in the todo's
+	// revisions and events
+	runInBackground(cfg, dailyreset.NewScheduler(stores.Users, stores.Todos, todoHandler, cfg.DailyResetInterval).Run)
+
+	// Users sign in with signed cookies, or with Entra ID tokens
+	var signer *auth.CookieSigner
+	var validator *auth.TokenValidator
+	switch cfg.Auth.Mode {
+	case config.AuthModeAAD:
+		validator = auth.NewTokenValidator(auth.NewJWKS(cfg.Auth.AAD.KeysURL), cfg.Auth.AAD.Issuers, cfg.Auth.AAD.Audiences)
+	default:
+		signer = auth.NewCookieSigner(cfg.Cookie.Secret, cfg.Cookie.PreviousSecrets...)
+	}
+	tracker := retention.NewTracker(stores.Users, cfg.Retention.TouchInterval)
+
+	// Slack posts slash commands as forms signed with the app's secret, so
+	// they're routed before JSON is required and before authentication.
+	// Signatures seen within the tolerance either side of now are remembered
+	// in the token cache, so a captured command can't be sent again.
+	var slackHandler *handlers.SlackHandler
+	if cfg.Slack.SigningSecret != "" {
+		slackHandler = handlers.NewSlackHandler(stores.SlackLinks, tokenCache, todoHandler, cfg.Storage.OperationTimeout)
+		verifySlack := middleware.VerifySignature("slack", slack.Verifier(cfg.Slack.SigningSecret, cfg.Slack.RequestTolerance), tokenCache, 2*cfg.Slack.RequestTolerance)
+		router.POST("/integrations/slack/commands", middleware.NoEnvelope(), verifySlack, slackHandler.Command)
+	}
+
+	// CalDAV clients such as Apple Reminders sync todos as tasks. They send
+	// XML and iCalendar bodies and can't keep cookies, so they're routed
+	// before JSON is required and sign in with app passwords, or with
+	// bearer tokens in Entra ID mode.
+	var calDAVHandler *handlers.CalDAVHandler
+	if cfg.CalDAV.Enabled {
+		calDAVHandler = handlers.NewCalDAVHandler(todoHandler, stores.Sessions, signer, cfg.CalDAV.PasswordTTL)
+		calDAVAuth := []gin.HandlerFunc{middleware.NoEnvelope()}
+		if signer != nil {
+			calDAVAuth = append(calDAVAuth, middleware.BasicAuthMiddleware(signer, stores.Sessions, "Todo API"))
+		} else {
+			calDAVAuth = append(calDAVAuth, middleware.BearerAuthMiddleware(validator))
+		}
+		calDAVAuth = append(calDAVAuth, middleware.ActivityMiddleware(tracker))
+		if cfg.RateLimit.Enabled() {
+			calDAVAuth = append(calDAVAuth, middleware.RateLimitMiddleware(cfg.RateLimit, tokenCache))
+		}
+		calDAVAuth = append(calDAVAuth, userConcurrency...)
+		router.GET("/.well-known/caldav", calDAVHandler.WellKnown)
+		router.Handle("PROPFIND", "/.well-known/caldav", calDAVHandler.WellKnown)
+		caldav := router.Group("/caldav", calDAVAuth...)
+		for _, path := range []string{"/", "/todos/", "/todos/:name"} {
+			caldav.OPTIONS(path, calDAVHandler.Options)
+		}
+		caldav.Handle("PROPFIND", "/", calDAVHandler.PropfindRoot)
+		caldav.Handle("PROPFIND", "/todos/", calDAVHandler.PropfindCalendar)
+		caldav.Handle("REPORT", "/todos/", calDAVHandler.Report)
+		caldav.Handle("PROPFIND", "/todos/:name", calDAVHandler.PropfindTodo)
+		caldav.GET("/todos/:name", calDAVHandler.GetTodo)
+		caldav.HEAD("/todos/:name", calDAVHandler.GetTodo)
+		caldav.PUT("/todos/:name", calDAVHandler.PutTodo)
+		caldav.DELETE("/todos/:name", calDAVHandler.DeleteTodo)
+		log.Println("Serving todos to CalDAV clients at /caldav/")
+	}
+
+	// Reject non-JSON bodies
+	router.Use(middleware.RequireJSONMiddleware())
+
+	// Public links are their own credential, so they're served before the
+	// auth middleware and don't mint a cookie for anonymous readers
+	publicLinkHandler := handlers.NewPublicLinkHandler(stores.PublicLinks, stores.Todos, stores.Shares, cfg.Storage.OperationTimeout)
+	publicRoute := []gin.HandlerFunc{publicLinkHandler.GetPublicTodo}
+	if cfg.RateLimit.Enabled() {
+		// Budgeted by address, as readers have no identity
+		publicRoute = append([]gin.HandlerFunc{middleware.RateLimitMiddleware(cfg.RateLimit, tokenCache)}, publicRoute...)
+	}
+	router.GET("/public/todos/:token", publicRoute...)
+
+	// Activity feeds are read by feed readers, which can't sign in either;
+	// their URLs carry a signed token, so they need cookie mode
+	var feedHandler *handlers.FeedHandler
+	if signer != nil {
+		feedHandler = handlers.NewFeedHandler(stores.Todos, stores.Workspaces, stores.Sessions, signer, cfg.Feed.TTL, cfg.Feed.Entries, cfg.Storage.OperationTimeout)
+		feedRoute := []gin.HandlerFunc{feedHandler.GetFeed}
+		if cfg.RateLimit.Enabled() {
+			feedRoute = append([]gin.HandlerFunc{middleware.RateLimitMiddleware(cfg.RateLimit, tokenCache)}, feedRoute...)
+		}
+		router.GET("/feeds/:token", feedRoute...)
+	}
+
+	// The operator API has its own token and never sees user identities
+	if cfg.Admin.Token != "" {
+		adminHandler := handlers.NewAdminHandler(stores, cfg.Storage.Backend, sweeper, policy, ensureIndexes, cfg.SyncWindow, cfg.Storage.OperationTimeout)
+		admin := router.Group("/admin/v1", middleware.AdminAuthMiddleware(cfg.Admin.Token))
+		admin.GET("/stats", adminHandler.Stats)
+		admin.GET("/users", adminHandler.ListUsers)
+		admin.DELETE("/users/:user_id", adminHandler.PurgeUser)
+		admin.GET("/users/:user_id/export", adminHandler.ExportUser)
+		admin.POST("/users/:user_id/import", adminHandler.ImportUser)
+		admin.POST("/indexes", adminHandler.EnsureIndexes)
+		admin.POST("/retention/sweep", adminHandler.Sweep)
+		admin.GET("/retention/report", adminHandler.RetentionReport)
+		admin.GET("/audit", adminHandler.ListAudit)
+		// Analytics pipelines pull todo changes with the operator token,
+		// though the export lives under the API's path
+		router.GET("/api/v1/export/changes", middleware.AdminAuthMiddleware(cfg.Admin.Token), adminHandler.ExportChanges)
+	}
+
+	// Apply authentication middleware to all routes
+	switch cfg.Auth.Mode {
+	case config.AuthModeAAD:
+		router.Use(middleware.BearerAuthMiddleware(validator))
+		log.Println("Authenticating with Microsoft Entra ID tokens")
+	default:
+		router.Use(middleware.AuthMiddleware(cfg.Cookie, signer, stores.Sessions, middleware.NewSessionThrottle(cfg.IPFilter, tokenCache)))
+		if cfg.Cookie.CSRF {
+			router.Use(middleware.CSRFMiddleware(signer))
+		}
+	}
+	if vault != nil && (signer != nil || cipher != nil) {
+		go refreshKeys(vault, getenv, signer, cipher, secretsCfg.RefreshInterval)
+	}
+	router.Use(middleware.ActivityMiddleware(tracker))
+
+	// Budget requests per user, with stricter budgets for costly routes.
+	// Counters are kept with the undo records, so in Redis when it's
+	// configured and shared between instances.
+	if cfg.RateLimit.Enabled() {
+		router.Use(middleware.RateLimitMiddleware(cfg.RateLimit, tokenCache))
+	}
+	router.Use(userConcurrency...)
+
+	// Fill a development database with sample todos, and log cookies to
+	// sign in as the sample users
+	if cfg.Seed.Enabled {
+		seedSampleData(cfg, stores, signer)
+	}
+
+	// API routes
+	shareHandler := handlers.NewShareHandler(stores.Shares, stores.Todos, cfg.Storage.OperationTimeout)
+	sessionHandler := handlers.NewSessionHandler(stores.Sessions, cfg.Cookie, cfg.Storage.OperationTimeout)
+	workspaceHandler := handlers.NewWorkspaceHandler(stores.Workspaces, stores.Todos, stores.CustomFields, stores.Filters, stores.Revisions, stores.Tombstones, stores.Tx, quotaHandler, cfg.Storage.OperationTimeout)
+	customFieldHandler := handlers.NewCustomFieldHandler(stores.CustomFields, todoHandler, cfg.Storage.OperationTimeout)
+	filterHandler := handlers.NewSavedFilterHandler(stores.Filters, cfg.Storage.OperationTimeout)
+	schemas := schema.API()
+	schemaHandler := handlers.NewSchemaHandler(schemas, cfg.API.SchemaValidation)
+
+	// v1 keeps the response shapes its clients were built against and is
+	// marked deprecated; v2 wraps every response in the envelope and pages
+	// listings. Both serve the same routes.
+	versions := []struct {
+		prefix     string
+		middleware []gin.HandlerFunc
+	}{
+		{"/api/v1", []gin.HandlerFunc{
+			middleware.APIVersionMiddleware("v1", cfg.ResponseEnvelope),
+			middleware.DeprecationMiddleware("/api/v1", "/api/v2", cfg.API.V1DeprecatedAt, cfg.API.V1Sunset),
+		}},
+		{"/api/v2", []gin.HandlerFunc{middleware.APIVersionMiddleware("v2", true)}},
+	}
+	for _, version := range versions {
+		api := router.Group(version.prefix, version.middleware...)
+		if cfg.API.SchemaValidation.Enabled() {
+			api.Use(middleware.SchemaValidationMiddleware(schemas, version.prefix, cfg.API.SchemaValidation))
+		}
+		{
+			// Cookie sessions and CSRF tokens only exist in cookie mode
+			if signer != nil {
+				api.GET("/csrf-token", handlers.CSRFToken(signer))
+				api.POST("/auth/logout", sessionHandler.Logout)
+				api.GET("/auth/sessions", sessionHandler.ListSessions)
+				api.DELETE("/auth/sessions/:id", sessionHandler.RevokeSession)
+			}
+			api.GET("/todos", todoHandler.GetTodos)
+			api.GET("/todos/count", todoHandler.CountTodos)
+			api.GET("/todos/stale", staleHandler.GetStale)
+			api.GET("/todos/today", todoHandler.GetToday)
+			api.GET("/todos/upcoming", todoHandler.GetUpcoming)
+			api.GET("/todos/calendar", todoHandler.GetCalendar)
+			api.GET("/todos/export", todoHandler.ExportTodos)
+			api.GET("/todos/nearby", todoHandler.GetNearby)
+			api.GET("/todos/assigned", todoHandler.GetAssigned)
+			api.GET("/todos/:id", todoHandler.GetTodo)
+			api.HEAD("/todos/:id", todoHandler.HeadTodo)
+			api.POST("/todos/lookup", todoHandler.LookupTodos)
+			api.GET("/stats", todoHandler.GetStats)
+			api.GET("/time-report", todoHandler.GetTimeReport)
+			api.POST("/undo/:token", todoHandler.Undo)
+			api.GET("/workload", todoHandler.GetWorkload)
+			api.GET("/sync", todoHandler.Sync)
+			api.POST("/sync", todoHandler.PushSync)
+			if slackHandler != nil {
+				api.GET("/integrations/slack", slackHandler.GetSlackLink)
+				api.DELETE("/integrations/slack", slackHandler.DeleteSlackLink)
+				api.POST("/integrations/slack/link-code", slackHandler.CreateSlackLinkCode)
+			}
+			// App passwords are sessions, which only exist in cookie mode
+			if calDAVHandler != nil && signer != nil {
+				api.POST("/integrations/caldav/passwords", calDAVHandler.CreatePassword)
+			}
+			api.POST("/todos", todoHandler.CreateTodo)
+			api.PUT("/todos/:id", todoHandler.UpdateTodo)
+			api.DELETE("/todos/:id", todoHandler.DeleteTodo)
+			api.POST("/todos/:id/clone", todoHandler.CloneTodo)
+			api.POST("/todos/:id/snooze", todoHandler.SnoozeTodo)
+			api.DELETE("/todos/:id/snooze", todoHandler.UnsnoozeTodo)
+			api.PUT("/todos/:id/pin", todoHandler.PinTodo)
+			api.DELETE("/todos/:id/pin", todoHandler.UnpinTodo)
+			api.POST("/todos/:id/blockers", todoHandler.AddBlocker)
+			api.POST("/todos/:id/timer/start", todoHandler.StartTimer)
+			api.POST("/todos/:id/timer/stop", todoHandler.StopTimer)
+			api.GET("/todos/:id/revisions", todoHandler.ListRevisions)
+			api.POST("/todos/:id/revisions/:rev/revert", todoHandler.RevertRevision)
+			api.DELETE("/todos/:id/blockers/:blocker_id", todoHandler.RemoveBlocker)
+			api.POST("/todos/:id/attachments", attachmentHandler.AddAttachment)
+			api.DELETE("/todos/:id/attachments/:attachment_id", attachmentHandler.RemoveAttachment)
+			api.POST("/todos/:id/checklist", todoHandler.AddChecklistItem)
+			api.PUT("/todos/:id/checklist/:item_id", todoHandler.UpdateChecklistItem)
+			api.DELETE("/todos/:id/checklist/:item_id", todoHandler.RemoveChecklistItem)
+			api.GET("/todos/:id/comments", commentHandler.ListComments)
+			api.POST("/todos/:id/comments", commentHandler.AddComment)
+			api.DELETE("/todos/:id/comments/:comment_id", commentHandler.DeleteComment)
+			api.GET("/mentions", commentHandler.GetMentions)
+			api.POST("/todos/:id/share", shareHandler.ShareTodo)
+			api.GET("/todos/:id/shares", shareHandler.ListShares)
+			api.DELETE("/todos/:id/shares/:share_id", shareHandler.RevokeShare)
+			api.GET("/shared-with-me", shareHandler.SharedWithMe)
+			api.GET("/usage", quotaHandler.GetUsage)
+			api.GET("/settings", settingsHandler.GetSettings)
+			api.PUT("/settings", settingsHandler.UpdateSettings)
+			api.GET("/preferences", settingsHandler.GetPreferences)
+			api.PUT("/preferences", settingsHandler.UpdatePreferences)
+			api.GET("/integrations/status", handlers.IntegrationStatus(monitor))
+			api.GET("/notifications/preferences", notificationHandler.GetPreferences)
+			api.PUT("/notifications/preferences", notificationHandler.UpdatePreferences)
+			api.GET("/schemas", schemaHandler.ListSchemas)
+			// Served as they are, so clients can hand them to a validator
+			api.GET("/schemas/:name", middleware.NoEnvelope(), schemaHandler.GetSchema)
+			api.POST("/todos/:id/public-link", publicLinkHandler.CreatePublicLink)
+			api.DELETE("/todos/:id/public-link", publicLinkHandler.RevokePublicLink)
+			api.GET("/tags", todoHandler.ListTags)
+			api.PUT("/tags/:tag", todoHandler.RenameTag)
+			api.POST("/tags/:tag/merge", todoHandler.MergeTag)
+			api.DELETE("/tags/:tag", todoHandler.DeleteTag)
+			api.GET("/custom-fields", customFieldHandler.ListCustomFields)
+			api.POST("/custom-fields", customFieldHandler.CreateCustomField)
+			api.DELETE("/custom-fields/:field_id", customFieldHandler.DeleteCustomField)
+			api.GET("/filters", filterHandler.ListSavedFilters)
+			api.POST("/filters", filterHandler.CreateSavedFilter)
+			api.GET("/filters/:filter_id", filterHandler.GetSavedFilter)
+			api.PUT("/filters/:filter_id", filterHandler.UpdateSavedFilter)
+			api.DELETE("/filters/:filter_id", filterHandler.DeleteSavedFilter)
+
+			if feedHandler != nil {
+				api.POST("/feeds", feedHandler.CreateFeed)
+			}
+			if importHandler != nil {
+				api.GET("/imports", importHandler.ListSources)
+				api.POST("/imports/:provider/authorize", importHandler.StartImport)
+				api.POST("/imports/:provider", importHandler.Import)
+			}
+
+			api.GET("/workspaces", workspaceHandler.ListWorkspaces)
+			api.POST("/workspaces", workspaceHandler.CreateWorkspace)
+
+			// Everything under a workspace requires membership
+			workspace := api.Group("/workspaces/:workspace_id", workspaceHandler.RequireMember)
+			workspace.GET("", workspaceHandler.GetWorkspace)
+			workspace.PUT("", workspaceHandler.UpdateWorkspace)
+			workspace.DELETE("", workspaceHandler.DeleteWorkspace)
+			workspace.POST("/members", workspaceHandler.AddMember)
+			workspace.PUT("/members/:user_id", workspaceHandler.UpdateMember)
+			workspace.DELETE("/members/:user_id", workspaceHandler.RemoveMember)
+			workspace.GET("/todos", todoHandler.GetTodos)
+			workspace.GET("/todos/count", todoHandler.CountTodos)
+			workspace.GET("/todos/stale", staleHandler.GetStale)
+			workspace.GET("/todos/today", todoHandler.GetToday)
+			workspace.GET("/todos/upcoming", todoHandler.GetUpcoming)
+			workspace.GET("/todos/calendar", todoHandler.GetCalendar)
+			workspace.GET("/todos/export", todoHandler.ExportTodos)
+			workspace.GET("/todos/nearby", todoHandler.GetNearby)
+			workspace.GET("/todos/:id", todoHandler.GetTodo)
+			workspace.HEAD("/todos/:id", todoHandler.HeadTodo)
+			workspace.POST("/todos/lookup", todoHandler.LookupTodos)
+			workspace.GET("/stats", todoHandler.GetStats)
+			workspace.GET("/time-report", todoHandler.GetTimeReport)
+			workspace.GET("/workload", todoHandler.GetWorkload)
+			workspace.GET("/sync", todoHandler.Sync)
+			workspace.POST("/sync", todoHandler.PushSync)
+			workspace.POST("/todos", todoHandler.CreateTodo)
+			workspace.PUT("/todos/:id", todoHandler.UpdateTodo)
+			workspace.DELETE("/todos/:id", todoHandler.DeleteTodo)
+			workspace.POST("/todos/:id/clone", todoHandler.CloneTodo)
+			workspace.POST("/todos/:id/snooze", todoHandler.SnoozeTodo)
+			workspace.DELETE("/todos/:id/snooze", todoHandler.UnsnoozeTodo)
+			workspace.PUT("/todos/:id/pin", todoHandler.PinTodo)
+			workspace.POST("/todos/:id/assign", todoHandler.AssignTodo)
+			workspace.DELETE("/todos/:id/pin", todoHandler.UnpinTodo)
+			workspace.POST("/todos/:id/blockers", todoHandler.AddBlocker)
+			workspace.POST("/todos/:id/timer/start", todoHandler.StartTimer)
+			workspace.POST("/todos/:id/timer/stop", todoHandler.StopTimer)
+			workspace.GET("/todos/:id/revisions", todoHandler.ListRevisions)
+			workspace.POST("/todos/:id/revisions/:rev/revert", todoHandler.RevertRevision)
+			workspace.DELETE("/todos/:id/blockers/:blocker_id", todoHandler.RemoveBlocker)
+			workspace.POST("/todos/:id/attachments", attachmentHandler.AddAttachment)
+			workspace.DELETE("/todos/:id/attachments/:attachment_id", attachmentHandler.RemoveAttachment)
+			workspace.POST("/todos/:id/checklist", todoHandler.AddChecklistItem)
+			workspace.PUT("/todos/:id/checklist/:item_id", todoHandler.UpdateChecklistItem)
+			workspace.DELETE("/todos/:id/checklist/:item_id", todoHandler.RemoveChecklistItem)
+			workspace.GET("/todos/:id/comments", commentHandler.ListComments)
+			workspace.POST("/todos/:id/comments", commentHandler.AddComment)
+			workspace.DELETE("/todos/:id/comments/:comment_id", commentHandler.DeleteComment)
+			workspace.GET("/tags", todoHandler.ListTags)
+			workspace.PUT("/tags/:tag", todoHandler.RenameTag)
+			workspace.POST("/tags/:tag/merge", todoHandler.MergeTag)
+			workspace.DELETE("/tags/:tag", todoHandler.DeleteTag)
+			workspace.GET("/custom-fields", customFieldHandler.ListCustomFields)
+			workspace.POST("/custom-fields", customFieldHandler.CreateCustomField)
+			workspace.DELETE("/custom-fields/:field_id", customFieldHandler.DeleteCustomField)
+			workspace.GET("/filters", filterHandler.ListSavedFilters)
+			workspace.POST("/filters", filterHandler.CreateSavedFilter)
+			workspace.GET("/filters/:filter_id", filterHandler.GetSavedFilter)
+			workspace.PUT("/filters/:filter_id", filterHandler.UpdateSavedFilter)
+			workspace.DELETE("/filters/:filter_id", filterHandler.DeleteSavedFilter)
+			if feedHandler != nil {
+				workspace.POST("/feeds", feedHandler.CreateFeed)
+			}
+			if importHandler != nil {
+				workspace.POST("/imports/:provider/authorize", importHandler.StartImport)
+				workspace.POST("/imports/:provider", importHandler.Import)
+			}
+		}
+	}
+
+	if err := serve(cfg, router); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// seedSampleData seeds the configured users, in each tenant, and starts a
+// session for each, logging the cookie that signs in to it
+func seedSampleData(cfg *config.Config, stores *repository.Stores, signer *auth.CookieSigner) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	err := forEachTenant(ctx, cfg, func(ctx context.Context) error {
+		now := time.Now()
+		seeded, err := seed.Seed(ctx, stores, cfg.Seed.Users, cfg.Seed.Todos, now)
+		if err != nil {
+			return err
+		}
+		where := ""
+		if tenant, ok := tenancy.FromContext(ctx); ok {
+			where = " of tenant " + tenant
+		}
+		log.Printf("Seeded %d of %d sample users%s with %d todos each; users who had todos were left alone", len(seeded), len(cfg.Seed.Users), where, cfg.Seed.Todos)
+
+		for _, userID := range cfg.Seed.Users {
+			session := &models.Session{
+				ID:        uuid.New().String(),
+				UserID:    userID,
+				CreatedAt: now,
+				LastSeen:  now,
+				ExpiresAt: now.Add(cfg.Cookie.MaxAge),
+				UserAgent: "seed",
+			}
+			if err := stores.Sessions.Create(ctx, session); err != nil {
+				return fmt.Errorf("start a session for a sample user: %w", err)
+			}
+			log.Printf("Sign in as sample user %s%s with the cookie %s=%s", userID, where, cfg.Cookie.Name, middleware.SessionCookie(signer, session))
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal("Failed to seed sample data:", err)
+	}
+}
+
+// refreshKeys periodically re-reads secrets from Key Vault so a rotated
+// cookie secret or encryption key takes effect without a restart. signer or
+// cipher may be nil when not in use. Other secrets, such as connection
+// strings, are only used at startup.
+func refreshKeys(vault *secrets.KeyVault, getenv func(string) string, signer *auth.CookieSigner, cipher *fieldcrypt.Cipher, interval time.Duration) {
+	for range time.Tick(interval) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := vault.Load(ctx, config.SecretKeys...)
+		cancel()
+		if err != nil {
+			log.Println("Failed to refresh secrets:", err)
+			continue
+		}
+		addSecrets(getenv)
+
+		// Validate the refreshed secrets the same way as at startup
+		cfg, err := config.LoadFrom(getenv)
+		if err != nil {
+			log.Println("Ignoring refreshed secrets:", err)
+			continue
+		}
+		if signer != nil {
+			signer.SetKeys(cfg.Cookie.Secret, cfg.Cookie.PreviousSecrets...)
+		}
+		if cipher != nil {
+			if !cfg.Encryption.Enabled() {
+				log.Println("Ignoring refreshed secrets: ENCRYPTION_KEY was removed; restart to turn encryption off")
+				continue
+			}
+			if err := cipher.SetKeys(cfg.Encryption.Key, cfg.Encryption.PreviousKeys...); err != nil {
+				log.Println("Ignoring refreshed encryption keys:", err)
+			}
+		}
+	}
+}
+
+// addSecrets registers the values of the secret settings, so they're
+// redacted wherever they'd be logged. List settings are registered item by
+// item.
+func addSecrets(getenv func(string) string) {
+	for _, key := range config.SecretKeys {
+		logging.AddSecrets(strings.Split(getenv(key), ",")...)
+	}
+}
+
+// openBroker returns the publisher that sends todo events to the configured
+// broker, or nil when no event backend is configured
+func openBroker(cfg *config.Config) events.Publisher {
+	switch cfg.Events.Backend {
+	case config.EventsServiceBus:
+		log.Printf("Publishing todo events to Service Bus entity %s", cfg.Events.ServiceBusEntity)
+		return events.NewServiceBus(cfg.Events.ServiceBusNamespace, cfg.Events.ServiceBusEntity, azure.NewManagedIdentity(cfg.Azure.ClientID))
+	case config.EventsEventGrid:
+		log.Println("Publishing todo events to Event Grid")
+		return events.NewEventGrid(cfg.Events.EventGridEndpoint, cfg.Events.EventGridKey, azure.NewManagedIdentity(cfg.Azure.ClientID))
+	default:
+		return nil
+	}
+}
+
+// openMailer returns the configured digest mailer, or nil when digests are
+// off
+func openMailer(cfg *config.Config) digest.Mailer {
+	switch cfg.Digest.Mailer {
+	case config.MailerSMTP:
+		log.Printf("Sending digests through SMTP server %s", cfg.Digest.SMTPAddr)
+		return digest.NewSMTP(cfg.Digest.SMTPAddr, cfg.Digest.From, cfg.Digest.SMTPUsername, cfg.Digest.SMTPPassword)
+	case config.MailerACS:
+		log.Println("Sending digests through Azure Communication Services")
+		return digest.NewACS(cfg.Digest.ACSEndpoint, cfg.Digest.ACSKey, cfg.Digest.From, azure.NewManagedIdentity(cfg.Azure.ClientID))
+	default:
+		return nil
+	}
+}
+
+// indexer is a repository that creates the indexes it needs
+type indexer interface {
+	EnsureIndexes(ctx context.Context) error
+}
+
+// noIndexes is the ensureIndexes of backends whose indexes are created by
+// their migrations, or that have none
+func noIndexes(context.Context) error {
+	return nil
+}
+
+// noMigrations is the migrate of backends without startup migrations
+func noMigrations(context.Context) error {
+	return nil
+}
+
+// openStorage connects to the configured storage backend and returns its
+// repositories along with the readiness checks for its dependencies, a
+// function that creates its indexes and one that applies its startup
+// migrations to the tenant in its context. SQL backends migrate their
+// schema as they're opened instead.
+func openStorage(cfg *config.Config) (*repository.Stores, []handlers.ReadinessCheck, func(context.Context) error, func(context.Context) error) {
+	switch cfg.Storage.Backend {
+	case config.BackendMemory:
+		log.Println("Using in-memory storage; data will be lost on restart")
+		stores, ensureIndexes := openTenants(cfg, func(string) (*repository.Stores, func(context.Context) error) {
+			return &repository.Stores{
+				Todos:        repository.NewMemoryTodoRepository(),
+				Users:        repository.NewMemoryUserRepository(),
+				Sessions:     repository.NewMemorySessionRepository(),
+				Workspaces:   repository.NewMemoryWorkspaceRepository(),
+				Shares:       repository.NewMemoryShareRepository(),
+				PublicLinks:  repository.NewMemoryPublicLinkRepository(),
+				CustomFields: repository.NewMemoryCustomFieldRepository(),
+				Filters:      repository.NewMemorySavedFilterRepository(),
+				Revisions:    repository.NewMemoryRevisionRepository(),
+				Outbox:       repository.NewMemoryOutboxRepository(),
+				Tombstones:   repository.NewMemoryTombstoneRepository(),
+				SlackLinks:   repository.NewMemorySlackLinkRepository(),
+				Audit:        repository.NewMemoryAuditRepository(),
+				Tx:           repository.WithoutTransactions(),
+			}, noIndexes
+		})
+		return stores, nil, ensureIndexes, noMigrations
+	case config.BackendPostgres:
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		// Each tenant's tables are kept in a schema named after it
+		var pings []func(context.Context) error
+		stores, ensureIndexes := openTenants(cfg, func(tenant string) (*repository.Stores, func(context.Context) error) {
+			store, err := repository.NewPostgresStore(ctx, cfg.Postgres.URL, tenant)
+			if err != nil {
+				log.Fatal("Failed to connect to PostgreSQL:", err)
+			}
+			pings = append(pings, store.Ping)
+			return store.Stores(), noIndexes
+		})
+		log.Println("Successfully connected to PostgreSQL!")
+		return stores, []handlers.ReadinessCheck{{Name: "database", Check: pingAll(pings)}}, ensureIndexes, noMigrations
+	case config.BackendSQLite:
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		// Each tenant has a database file of its own next to SQLITE_PATH
+		var pings []func(context.Context) error
+		stores, ensureIndexes := openTenants(cfg, func(tenant string) (*repository.Stores, func(context.Context) error) {
+			path := tenantPath(cfg.SQLite.Path, tenant)
+			store, err := repository.NewSQLiteStore(ctx, path)
+			if err != nil {
+				log.Fatal("Failed to open SQLite database:", err)
+			}
+			log.Printf("Using SQLite database at %s", path)
+			pings = append(pings, store.Ping)
+			return store.Stores(), noIndexes
+		})
+		return stores, []handlers.ReadinessCheck{{Name: "database", Check: pingAll(pings)}}, ensureIndexes, noMigrations
+	default:
+		// Keep account keys out of app settings by looking the connection
+		// string up with the app's managed identity
+		if cfg.Mongo.Auth == config.MongoAuthManagedIdentity {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			uri, err := azure.CosmosConnectionString(ctx, azure.NewManagedIdentity(cfg.Azure.ClientID), azure.CosmosAccount{
+				SubscriptionID: cfg.Mongo.Cosmos.SubscriptionID,
+				ResourceGroup:  cfg.Mongo.Cosmos.ResourceGroup,
+				Name:           cfg.Mongo.Cosmos.Account,
+			})
+			cancel()
+			if err != nil {
+				log.Fatal("Failed to get Cosmos DB connection string with managed identity:", err)
+			}
+			cfg.Mongo.URI = uri
+			logging.AddSecrets(uri)
+			log.Printf("Resolved Cosmos DB account %s using managed identity", cfg.Mongo.Cosmos.Account)
+		}
+		db := database.Connect(cfg.Mongo)
+
+		// Retry transient errors such as Cosmos throttling, and fail fast
+		// once the database keeps failing. Tenants share the cluster, so
+		// they share the breaker too.
+		breaker := resilience.NewBreaker(cfg.Mongo.Breaker.FailureThreshold, cfg.Mongo.Breaker.OpenTimeout)
+		resilient := repository.NewResilience(resilience.RetryPolicy{
+			MaxAttempts: cfg.Mongo.Retry.MaxAttempts,
+			BaseDelay:   cfg.Mongo.Retry.BaseDelay,
+			MaxDelay:    cfg.Mongo.Retry.MaxDelay,
+		}, breaker, repository.IsRetryableMongoError, repository.MongoThrottle)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		migrators := make(map[string]func(context.Context) error)
+		stores, ensureIndexes := openTenants(cfg, func(tenant string) (*repository.Stores, func(context.Context) error) {
+			db := db
+			if tenant != "" {
+				db = db.Tenant(tenant, cfg.Tenancy.Isolation)
+			}
+			users := repository.NewMongoUserRepository(db.Users)
+			sessions := repository.NewMongoSessionRepository(db.Sessions)
+			workspaces := repository.NewMongoWorkspaceRepository(db.Workspaces)
+			shares := repository.NewMongoShareRepository(db.Shares)
+			publicLinks := repository.NewMongoPublicLinkRepository(db.PublicLinks)
+			customFields := repository.NewMongoCustomFieldRepository(db.CustomFields)
+			filters := repository.NewMongoSavedFilterRepository(db.SavedFilters)
+			revisions := repository.NewMongoRevisionRepository(db.Revisions)
+			outbox := repository.NewMongoOutboxRepository(db.Outbox)
+			tombstones := repository.NewMongoTombstoneRepository(db.Tombstones)
+			slackLinks := repository.NewMongoSlackLinkRepository(db.SlackLinks)
+			auditLog := repository.NewMongoAuditRepository(db.AuditLog)
+			todos := repository.NewMongoTodoRepository(db.Todos)
+			ensureIndexes := func(ctx context.Context) error {
+				for _, r := range []indexer{users, sessions, workspaces, shares, publicLinks, customFields, filters, revisions, outbox, tombstones, slackLinks, auditLog, todos} {
+					if err := r.EnsureIndexes(ctx); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			migrationStore := repository.NewMongoMigrationStore(db.Migrations)
+			migrators[tenant] = func(ctx context.Context) error {
+				_, err := migrations.Run(ctx, migrationStore, todos.Migrations())
+				return err
+			}
+			return resilient.Wrap(&repository.Stores{
+				Todos:        todos,
+				Users:        users,
+				Sessions:     sessions,
+				Workspaces:   workspaces,
+				Shares:       shares,
+				PublicLinks:  publicLinks,
+				CustomFields: customFields,
+				Filters:      filters,
+				Revisions:    revisions,
+				Outbox:       outbox,
+				Tombstones:   tombstones,
+				SlackLinks:   slackLinks,
+				Audit:        auditLog,
+				Tx:           repository.NewMongoTransactor(ctx, db.Client()),
+			}), ensureIndexes
+		})
+		return stores, []handlers.ReadinessCheck{
+			{Name: "database", Check: db.Ping},
+			{Name: "database_circuit", Check: breaker.Check},
+		}, ensureIndexes, func(ctx context.Context) error {
+			tenant, _ := tenancy.FromContext(ctx)
+			return migrators[tenant](ctx)
+		}
+	}
+}
+
+// openTenants opens the stores of each tenant with open, and returns stores
+// that route every call to the tenant in its context. The returned
+// ensureIndexes creates the indexes of the tenant in its context, or of every
+// tenant without one. With tenancy off, the stores are opened once.
+func openTenants(cfg *config.Config, open func(tenant string) (*repository.Stores, func(context.Context) error)) (*repository.Stores, func(context.Context) error) {
+	if !cfg.Tenancy.Enabled() {
+		return open("")
+	}
+	stores := make(map[string]*repository.Stores, len(cfg.Tenancy.Tenants))
+	indexes := make(map[string]func(context.Context) error, len(cfg.Tenancy.Tenants))
+	for _, tenant := range cfg.Tenancy.Tenants {
+		stores[tenant], indexes[tenant] = open(tenant)
+	}
+	log.Printf("Serving tenants %s, isolated by %s", strings.Join(cfg.Tenancy.Tenants, ", "), cfg.Tenancy.Isolation)
+	ensureIndexes := func(ctx context.Context) error {
+		if tenant, ok := tenancy.FromContext(ctx); ok {
+			if ensure, ok := indexes[tenant]; ok {
+				return ensure(ctx)
+			}
+			return repository.ErrNoTenant
+		}
+		return forEachTenant(ctx, cfg, func(ctx context.Context) error {
+			tenant, _ := tenancy.FromContext(ctx)
+			return indexes[tenant](ctx)
+		})
+	}
+	return repository.NewTenantStores(stores), ensureIndexes
+}
+
+// forEachTenant calls fn with ctx carrying each tenant in turn, stopping at
+// the first error. With tenancy off, fn is called once with ctx as it is.
+func forEachTenant(ctx context.Context, cfg *config.Config, fn func(ctx context.Context) error) error {
+	if !cfg.Tenancy.Enabled() {
+		return fn(ctx)
+	}
+	for _, tenant := range cfg.Tenancy.Tenants {
+		if err := fn(tenancy.WithTenant(ctx, tenant)); err != nil {
+			return fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+	}
+	return nil
+}
+
+// runInBackground starts run in the background for each tenant, with the
+// tenant in its context, or once with tenancy off
+func runInBackground(cfg *config.Config, run func(ctx context.Context)) {
+	forEachTenant(context.Background(), cfg, func(ctx context.Context) error {
+		go run(ctx)
+		return nil
+	})
+}
+
+// tenantPath returns the SQLite database file of a tenant: path with the
+// tenant before its extension, e.g. todos-acme.db for todos.db. Every
+// in-memory database is separate already.
+func tenantPath(path, tenant string) string {
+	if tenant == "" || path == ":memory:" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + tenant + ext
+}
+
+// pingAll checks every database with pings
+func pingAll(pings []func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		for _, ping := range pings {
+			if err := ping(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// orUnknown returns s, or "unknown" when it's empty
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,260 @@
+package markdown
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Render converts Markdown to HTML that is safe to insert into a page. It
+// covers the common subset people write in notes: paragraphs, headings,
+// emphasis, inline and fenced code, lists, blockquotes, rules and links.
+//
+// Sanitizing is built in rather than bolted on: every piece of the source
+// is HTML-escaped, so raw HTML comes out as text, and the only markup in
+// the output is what Render writes itself. Links are kept only for http,
+// https and mailto URLs, and images are turned into links so a description
+// can't make clients load remote content.
+func Render(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var out []string
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "```"):
+			lang := strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+			i++
+			var code []string
+			for i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+				code = append(code, lines[i])
+				i++
+			}
+			i++ // the closing fence, if there is one
+			open := "<pre><code>"
+			if languageName.MatchString(lang) {
+				open = `<pre><code class="language-` + lang + `">`
+			}
+			out = append(out, open+escape(strings.Join(code, "\n"))+"</code></pre>")
+
+		case heading.MatchString(trimmed):
+			m := heading.FindStringSubmatch(trimmed)
+			tag := "h" + string(rune('0'+len(m[1])))
+			out = append(out, "<"+tag+">"+inline(strings.TrimRight(m[2], " #"))+"</"+tag+">")
+			i++
+
+		case rule.MatchString(trimmed):
+			out = append(out, "<hr>")
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			var quoted []string
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">") {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(q, " "))
+				i++
+			}
+			out = append(out, "<blockquote>\n"+Render(strings.Join(quoted, "\n"))+"\n</blockquote>")
+
+		case bullet.MatchString(trimmed), numbered.MatchString(trimmed):
+			marker, tag := bullet, "ul"
+			if numbered.MatchString(trimmed) {
+				marker, tag = numbered, "ol"
+			}
+			var items []string
+			for i < len(lines) {
+				t := strings.TrimSpace(lines[i])
+				if m := marker.FindStringSubmatch(t); m != nil {
+					items = append(items, m[1])
+				} else if t != "" && len(items) > 0 && startsIndented(lines[i]) {
+					// A continuation of the previous item
+					items[len(items)-1] += "\n" + t
+				} else {
+					break
+				}
+				i++
+			}
+			var b strings.Builder
+			b.WriteString("<" + tag + ">\n")
+			for _, item := range items {
+				b.WriteString("<li>" + inline(item) + "</li>\n")
+			}
+			b.WriteString("</" + tag + ">")
+			out = append(out, b.String())
+
+		default:
+			var para []string
+			for i < len(lines) && startsParagraph(lines[i], len(para) == 0) {
+				para = append(para, strings.TrimSpace(lines[i]))
+				i++
+			}
+			out = append(out, "<p>"+strings.ReplaceAll(inline(strings.Join(para, "\n")), "\n", "<br>\n")+"</p>")
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+var (
+	heading      = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	rule         = regexp.MustCompile(`^(-\s*){3,}$|^(\*\s*){3,}$|^(_\s*){3,}$`)
+	bullet       = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	numbered     = regexp.MustCompile(`^\d{1,9}[.)]\s+(.*)$`)
+	languageName = regexp.MustCompile(`^[A-Za-z0-9_+-]{1,32}$`)
+)
+
+// startsIndented reports whether a line begins with whitespace
+func startsIndented(line string) bool {
+	return line != "" && (line[0] == ' ' || line[0] == '\t')
+}
+
+// startsParagraph reports whether line continues, or for the first line
+// starts, a paragraph rather than beginning some other block
+func startsParagraph(line string, first bool) bool {
+	t := strings.TrimSpace(line)
+	if t == "" {
+		return false
+	}
+	if first {
+		return true
+	}
+	return !strings.HasPrefix(t, "```") && !strings.HasPrefix(t, ">") &&
+		!heading.MatchString(t) && !rule.MatchString(t) &&
+		!bullet.MatchString(t) && !numbered.MatchString(t)
+}
+
+// inline renders the spans within a block: code, emphasis and links. Text
+// is escaped as it's copied, so nothing in s is passed through as markup.
+func inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		ch := s[i]
+		switch {
+		case ch == '\\' && i+1 < len(s) && strings.IndexByte(punctuation, s[i+1]) >= 0:
+			b.WriteString(escape(s[i+1 : i+2]))
+			i += 2
+			continue
+
+		case ch == '`':
+			if j := strings.IndexByte(s[i+1:], '`'); j >= 0 {
+				b.WriteString("<code>" + escape(s[i+1:i+1+j]) + "</code>")
+				i += j + 2
+				continue
+			}
+
+		case ch == '*' || ch == '_':
+			// Underscores inside words, as in snake_case, aren't emphasis
+			if ch == '_' && i > 0 && isWordByte(s[i-1]) {
+				break
+			}
+			if strings.HasPrefix(s[i:], string([]byte{ch, ch})) {
+				if j := strings.Index(s[i+2:], string([]byte{ch, ch})); j > 0 {
+					b.WriteString("<strong>" + inline(s[i+2:i+2+j]) + "</strong>")
+					i += j + 4
+					continue
+				}
+			}
+			if i+1 < len(s) && s[i+1] != ' ' {
+				if j := strings.IndexByte(s[i+1:], ch); j > 0 && s[i+j] != ' ' {
+					b.WriteString("<em>" + inline(s[i+1:i+1+j]) + "</em>")
+					i += j + 2
+					continue
+				}
+			}
+
+		case ch == '[' || (ch == '!' && strings.HasPrefix(s[i:], "![")):
+			start := i + 1
+			if ch == '!' {
+				start++
+			}
+			if text, href, n, ok := link(s[start:]); ok {
+				if safe, ok := safeURL(href); ok {
+					b.WriteString(`<a href="` + escape(safe) + `" rel="nofollow noopener noreferrer">` + inline(text) + "</a>")
+				} else {
+					b.WriteString(inline(text))
+				}
+				i = start + n
+				continue
+			}
+		}
+		b.WriteString(escape(s[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// punctuation lists the characters a backslash can escape
+const punctuation = "\\`*_{}[]()#+-.!<>|~\""
+
+// isWordByte reports whether c is an ASCII letter or digit
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// link parses `text](url "title")` at the start of s, returning the text,
+// the URL and how many bytes were consumed
+func link(s string) (text, href string, n int, ok bool) {
+	mid := strings.IndexByte(s, ']')
+	if mid < 0 || !strings.HasPrefix(s[mid:], "](") || strings.ContainsAny(s[:mid], "[\n") {
+		return "", "", 0, false
+	}
+	// URLs may contain balanced parentheses, as Wikipedia's often do
+	end, depth := -1, 0
+	for j, ch := range s[mid+2:] {
+		if ch == '\n' || (ch == ')' && depth == 0) {
+			if ch == ')' {
+				end = j
+			}
+			break
+		}
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+	}
+	if end < 0 {
+		return "", "", 0, false
+	}
+	target := strings.TrimSpace(s[mid+2 : mid+2+end])
+	// Drop an optional title
+	if sp := strings.IndexAny(target, " \t"); sp >= 0 {
+		target = target[:sp]
+	}
+	return s[:mid], strings.Trim(target, "<>"), mid + 3 + end, true
+}
+
+// safeURL returns href if it's an absolute http, https or mailto URL.
+// Anything else, notably javascript: and data: URLs, is refused.
+func safeURL(href string) (string, bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return "", false
+		}
+	case "mailto":
+	default:
+		return "", false
+	}
+	return u.String(), true
+}
+
+// escape makes s safe to use as HTML text or a quoted attribute value
+func escape(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+var htmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&#34;",
+	"'", "&#39;",
+)
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"log"
+
+	"todo-api/retention"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActivityMiddleware records that the authenticated user is active so their
+// data isn't purged by the retention sweeper. It must run after AuthMiddleware.
+func ActivityMiddleware(tracker *retention.Tracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID := c.GetString("user_id"); userID != "" {
+			// Activity tracking is best effort and never fails the request
+			if err := tracker.Touch(c.Request.Context(), userID); err != nil {
+				log.Println("Failed to record user activity:", err)
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"strings"
+
+	"todo-api/apierrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthMethodAdmin marks requests authenticated by the operator token
+const AuthMethodAdmin = "admin"
+
+// AdminAuthMiddleware requires the operator token as a bearer token. It is
+// separate from user authentication, so no user identity can reach the
+// admin API.
+func AdminAuthMiddleware(token string) gin.HandlerFunc {
+	// Compare fixed-length hashes so the comparison doesn't leak the
+	// token's length
+	want := sha256.Sum256([]byte(token))
+	return func(c *gin.Context) {
+		scheme, got, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		sum := sha256.Sum256([]byte(strings.TrimSpace(got)))
+		if !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare(sum[:], want[:]) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			apierrors.Abort(c, apierrors.CodeUnauthenticated, "A valid admin token is required")
+			return
+		}
+		c.Set("auth_method", AuthMethodAdmin)
+		c.Next()
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"todo-api/audit"
+	"todo-api/config"
+	"todo-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditMiddleware records every request in the audit trail once it's been
+// handled: who made it, what it did, how it ended and how long it took.
+// The JSON bodies of mutations are recorded with secret fields redacted.
+// It must run before the authentication middleware, so requests that fail
+// authentication are recorded too.
+func AuditMiddleware(recorder *audit.Recorder, cfg config.AuditConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		var body []byte
+		if isMutation(c.Request.Method) && c.Request.Body != nil && cfg.MaxBodyBytes > 0 {
+			// Read up to one byte past the limit to tell whether the body
+			// fits, and hand the handlers the whole body all the same
+			buf, err := io.ReadAll(io.LimitReader(c.Request.Body, int64(cfg.MaxBodyBytes)+1))
+			c.Request.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(buf), c.Request.Body), Closer: c.Request.Body}
+			if err == nil && len(buf) <= cfg.MaxBodyBytes {
+				body = buf
+			}
+		}
+
+		c.Next()
+
+		entry := &models.AuditEntry{
+			Time:       start.UTC(),
+			RequestID:  c.GetString("request_id"),
+			Tenant:     c.GetString("tenant"),
+			Method:     c.Request.Method,
+			Path:       redactedPath(c),
+			Route:      c.FullPath(),
+			UserID:     c.GetString("user_id"),
+			AuthMethod: c.GetString("auth_method"),
+			ClientIP:   c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			Status:     c.Writer.Status(),
+			LatencyMs:  time.Since(start).Milliseconds(),
+		}
+		if len(body) > 0 || isMutation(c.Request.Method) && c.Request.ContentLength > 0 {
+			entry.BodyBytes = max(c.Request.ContentLength, int64(len(body)))
+			if redacted, ok := audit.RedactJSON(body, cfg.RedactFields); ok {
+				entry.Body = redacted
+			}
+		}
+		recorder.Record(entry)
+	}
+}
+
+// isMutation reports whether the method changes data
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// readCloser reads from one reader and closes another
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
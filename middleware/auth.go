@@ -1,39 +1,149 @@
 package middleware
 
 import (
-	"os"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/auth"
+	"todo-api/config"
+	"todo-api/models"
+	"todo-api/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMethodCookie marks requests authenticated by the identity cookie
+const AuthMethodCookie = "cookie"
+
+// sessionTouchInterval limits how often a session's last_seen is written
+const sessionTouchInterval = 5 * time.Minute
+
+// AuthMiddleware identifies the user from the signed identity cookie, which
+// names a server-side session. Requests without a valid, unrevoked session
+// get a fresh identity, as many as the throttle allows their address; a nil
+// throttle allows any number.
+func AuthMiddleware(cfg config.CookieConfig, signer *auth.CookieSigner, sessions repository.SessionRepository, throttle *SessionThrottle) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		cookieName := os.Getenv("COOKIE_NAME")
-		if cookieName == "" {
-			cookieName = "todo_user_id"
-		}
+		ctx := c.Request.Context()
+		userID, sessionID, reissue := identify(c, cfg, signer)
 
-		userID, err := c.Cookie(cookieName)
+		var session *models.Session
+		if sessionID != "" {
+			var err error
+			session, err = sessions.Get(ctx, sessionID)
+			switch {
+			case errors.Is(err, repository.ErrNotFound):
+				// Revoked or expired. The identity goes with it, otherwise
+				// a leaked cookie could simply be upgraded to a new session.
+				userID = ""
+			case err != nil:
+				log.Println("Failed to look up session:", err)
+				apierrors.Abort(c, apierrors.CodeStorageUnavailable, "Failed to verify session, please retry")
+				return
+			case session.UserID != userID:
+				session, userID = nil, ""
+			}
+		}
 
-		// If no cookie exists or cookie is invalid, generate a new user ID
-		if err != nil || userID == "" {
+		// If no cookie exists or it failed verification, generate a new user ID.
+		// A tampered cookie never grants access to the identity it names.
+		if userID == "" {
 			userID = uuid.New().String()
+		}
 
-			// Set cookie with 24-hour expiration
+		now := time.Now()
+		if session == nil {
+			if !throttle.allow(c) {
+				return
+			}
+			session = &models.Session{
+				ID:        uuid.New().String(),
+				UserID:    userID,
+				CreatedAt: now,
+				LastSeen:  now,
+				ExpiresAt: now.Add(cfg.MaxAge),
+				UserAgent: c.Request.UserAgent(),
+				IP:        c.ClientIP(),
+			}
+			if err := sessions.Create(ctx, session); err != nil {
+				log.Println("Failed to create session:", err)
+				apierrors.Abort(c, apierrors.CodeStorageUnavailable, "Failed to start session, please retry")
+				return
+			}
+			reissue = true
+		} else if now.Sub(session.LastSeen) > sessionTouchInterval {
+			// Best effort; the session list just shows a staler time
+			if err := sessions.Touch(ctx, session.ID, now); err != nil {
+				log.Println("Failed to record session activity:", err)
+			}
+		}
+
+		if reissue {
+			// The cookie lives exactly as long as its session
+			value := SessionCookie(signer, session)
+			maxAge := int(time.Until(session.ExpiresAt).Seconds())
 			c.SetCookie(
-				cookieName, // name
-				userID,     // value
-				24*60*60,   // max age in seconds (24 hours)
+				cfg.Name,   // name
+				value,      // value
+				maxAge,     // max age in seconds
 				"/",        // path
 				"",         // domain
-				false,      // secure (set to true in production with HTTPS)
+				cfg.Secure, // secure (set to true in production with HTTPS)
 				true,       // httpOnly
 			)
 		}
 
 		// Add user ID to the context
 		c.Set("user_id", userID)
+		c.Set("session_id", session.ID)
+		c.Set("auth_method", AuthMethodCookie)
 		c.Next()
 	}
 }
+
+// sessionSeparator joins the user ID and session ID in the cookie value
+const sessionSeparator = ":"
+
+// SessionCookie returns the value of the identity cookie naming a session
+func SessionCookie(signer *auth.CookieSigner, session *models.Session) string {
+	return signer.Sign(session.UserID + sessionSeparator + session.ID)
+}
+
+// identify returns the user and session IDs from a validly signed cookie, or
+// "" if there is none. reissue is true when the cookie should be replaced,
+// e.g. because it was signed with a previous key. Cookies issued before
+// sessions existed yield a user ID without a session when legacy cookies are
+// accepted.
+func identify(c *gin.Context, cfg config.CookieConfig, signer *auth.CookieSigner) (userID, sessionID string, reissue bool) {
+	raw, err := c.Cookie(cfg.Name)
+	if err != nil || raw == "" {
+		return "", "", true
+	}
+
+	if value, current, ok := signer.Verify(raw); ok {
+		userID, sessionID, found := strings.Cut(value, sessionSeparator)
+		if _, err := uuid.Parse(userID); err != nil {
+			return "", "", true
+		}
+		if found {
+			return userID, sessionID, !current
+		}
+		if cfg.AcceptLegacy {
+			return userID, "", true
+		}
+		return "", "", true
+	}
+
+	// Cookies issued before signing was introduced are bare UUIDs. Accepting
+	// them lets existing users keep their todos during the transition.
+	if cfg.AcceptLegacy {
+		if _, err := uuid.Parse(raw); err == nil {
+			return raw, "", true
+		}
+	}
+	return "", "", true
+}
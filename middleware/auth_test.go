@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-api/auth"
+	"todo-api/config"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testUserID   = "0b4c9c1e-5f0e-4a43-9d4f-2d8f8f1d3a6e"
+	otherUserID  = "7d1e3c52-8a9b-4f06-b1d2-3e4f5a6b7c8d"
+	testSession  = "session-1"
+	testSecret   = "0123456789abcdef0123456789abcdef"
+	formerSecret = "fedcba9876543210fedcba9876543210"
+)
+
+// authenticate sends a request carrying cookie, if any, through
+// AuthMiddleware and returns the user ID it settled on and the cookie it set
+func authenticate(t *testing.T, cfg config.CookieConfig, sessions repository.SessionRepository, cookie string) (userID, setCookie string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(AuthMiddleware(cfg, auth.NewCookieSigner(cfg.Secret, cfg.PreviousSecrets...), sessions, nil))
+	router.GET("/", func(c *gin.Context) {
+		userID = c.GetString("user_id")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: cfg.Name, Value: cookie})
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == cfg.Name {
+			setCookie, _ = url.QueryUnescape(c.Value)
+		}
+	}
+	return userID, setCookie
+}
+
+func TestAuthMiddlewareCookies(t *testing.T) {
+	cfg := config.CookieConfig{Name: "user_id", MaxAge: time.Hour, Secret: testSecret, PreviousSecrets: []string{formerSecret}}
+	signer := auth.NewCookieSigner(testSecret)
+	signed := signer.Sign(testUserID + sessionSeparator + testSession)
+
+	tests := []struct {
+		name   string
+		cookie string
+		legacy bool
+		// wantUser is the identity the request gets; "" means a new one
+		wantUser    string
+		wantReissue bool
+	}{
+		{name: "signed cookie", cookie: signed, wantUser: testUserID},
+		{name: "cookie signed with a previous secret", cookie: auth.NewCookieSigner(formerSecret).Sign(testUserID + sessionSeparator + testSession), wantUser: testUserID, wantReissue: true},
+		{name: "forged signature", cookie: testUserID + sessionSeparator + testSession + ".forged", wantReissue: true},
+		{name: "signature copied onto another user ID", cookie: otherUserID + sessionSeparator + testSession + signed[strings.LastIndexByte(signed, '.'):], wantReissue: true},
+		{name: "signed with an unknown secret", cookie: auth.NewCookieSigner(strings.Repeat("x", 32)).Sign(testUserID + sessionSeparator + testSession), wantReissue: true},
+		{name: "another user's session", cookie: signer.Sign(otherUserID + sessionSeparator + testSession), wantReissue: true},
+		{name: "revoked session", cookie: signer.Sign(testUserID + sessionSeparator + "revoked"), wantReissue: true},
+		{name: "unsigned cookie", cookie: testUserID, wantReissue: true},
+		{name: "unsigned cookie accepted while upgrading", cookie: testUserID, legacy: true, wantUser: testUserID, wantReissue: true},
+		{name: "cookie from before sessions", cookie: signer.Sign(testUserID), wantReissue: true},
+		{name: "cookie from before sessions accepted while upgrading", cookie: signer.Sign(testUserID), legacy: true, wantUser: testUserID, wantReissue: true},
+		{name: "no cookie", wantReissue: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := repository.NewMemorySessionRepository()
+			now := time.Now()
+			session := &models.Session{ID: testSession, UserID: testUserID, CreatedAt: now, LastSeen: now, ExpiresAt: now.Add(time.Hour)}
+			if err := sessions.Create(context.Background(), session); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := cfg
+			cfg.AcceptLegacy = tt.legacy
+			userID, setCookie := authenticate(t, cfg, sessions, tt.cookie)
+			if tt.wantUser != "" && userID != tt.wantUser {
+				t.Errorf("user = %q, want %q", userID, tt.wantUser)
+			}
+			if tt.wantUser == "" && (userID == "" || userID == testUserID || userID == otherUserID) {
+				t.Errorf("user = %q, want a new identity", userID)
+			}
+			if reissued := setCookie != ""; reissued != tt.wantReissue {
+				t.Errorf("reissued cookie %q, want reissue %v", setCookie, tt.wantReissue)
+			}
+			if setCookie != "" {
+				value, current, ok := signer.Verify(setCookie)
+				if !ok || !current || !strings.HasPrefix(value, userID+sessionSeparator) {
+					t.Errorf("set cookie %q isn't a session of %q signed with the current secret", setCookie, userID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/auth"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthMethodAppPassword marks requests authenticated by an app password
+const AuthMethodAppPassword = "app_password"
+
+// BasicAuthMiddleware identifies the user by an app password sent with HTTP
+// Basic authentication, for apps such as CalDAV clients that can't keep a
+// cookie. The user name is the ID of the user the password was created for.
+// realm names the service in the password prompts apps show.
+func BasicAuthMiddleware(signer *auth.CookieSigner, sessions repository.SessionRepository, realm string) gin.HandlerFunc {
+	challenge := `Basic realm="` + realm + `", charset="UTF-8"`
+	return func(c *gin.Context) {
+		username, password, ok := c.Request.BasicAuth()
+		if !ok {
+			c.Header("WWW-Authenticate", challenge)
+			apierrors.Abort(c, apierrors.CodeUnauthenticated, "A user name and app password are required")
+			return
+		}
+
+		var session *models.Session
+		if sessionID, ok := signer.VerifyAppPassword(password); ok {
+			var err error
+			session, err = sessions.Get(c.Request.Context(), sessionID)
+			switch {
+			case errors.Is(err, repository.ErrNotFound):
+				session = nil
+			case err != nil:
+				log.Println("Failed to look up session:", err)
+				apierrors.Abort(c, apierrors.CodeStorageUnavailable, "Failed to verify session, please retry")
+				return
+			}
+		}
+		if session == nil || session.UserID != username {
+			c.Header("WWW-Authenticate", challenge)
+			apierrors.Abort(c, apierrors.CodeUnauthenticated, "The user name or app password is wrong, or the password was revoked")
+			return
+		}
+
+		if now := time.Now(); now.Sub(session.LastSeen) > sessionTouchInterval {
+			// Best effort; the session list just shows a staler time
+			if err := sessions.Touch(c.Request.Context(), session.ID, now); err != nil {
+				log.Println("Failed to record session activity:", err)
+			}
+		}
+
+		c.Set("user_id", session.UserID)
+		c.Set("session_id", session.ID)
+		c.Set("auth_method", AuthMethodAppPassword)
+		c.Next()
+	}
+}
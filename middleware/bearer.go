@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"todo-api/apierrors"
+	"todo-api/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthMethodBearer marks requests authenticated by a bearer token
+const AuthMethodBearer = "bearer"
+
+// BearerAuthMiddleware requires a valid bearer token, such as an Entra ID
+// access token, and identifies the user by its oid claim
+func BearerAuthMiddleware(validator *auth.TokenValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.Header("WWW-Authenticate", `Bearer`)
+			apierrors.Abort(c, apierrors.CodeUnauthenticated, "A bearer token is required")
+			return
+		}
+
+		claims, err := validator.Validate(c.Request.Context(), strings.TrimSpace(token))
+		if errors.Is(err, auth.ErrKeysUnavailable) {
+			log.Println("Failed to fetch token signing keys:", err)
+			apierrors.Abort(c, apierrors.CodeAuthUnavailable, "Unable to validate the token right now, please retry")
+			return
+		}
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			apierrors.Abort(c, apierrors.CodeUnauthenticated, "The bearer token is invalid or expired")
+			return
+		}
+		if claims.ObjectID == "" {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			apierrors.Abort(c, apierrors.CodeUnauthenticated, "The bearer token has no oid claim")
+			return
+		}
+
+		c.Set("user_id", claims.ObjectID)
+		c.Set("auth_method", AuthMethodBearer)
+		if email := claims.EmailAddress(); email != "" {
+			c.Set("email", email)
+		}
+		c.Next()
+	}
+}
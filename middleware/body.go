@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"todo-api/apierrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimitMiddleware rejects request bodies larger than maxBytes with 413.
+// Bodies that declare their size are rejected up front; chunked bodies are
+// cut off while being read, which handlers report through respondBindError.
+func BodyLimitMiddleware(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			apierrors.Abort(c, apierrors.CodePayloadTooLarge,
+				fmt.Sprintf("Request body must not exceed %d bytes", maxBytes))
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		}
+		c.Next()
+	}
+}
+
+// RequireJSONMiddleware rejects mutating requests whose body isn't JSON with
+// 415. Requests without a body, such as a bare POST to an action endpoint,
+// are let through.
+func RequireJSONMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength == 0 && len(c.Request.TransferEncoding) == 0 {
+			c.Next()
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || !isJSONMediaType(mediaType) {
+			apierrors.Abort(c, apierrors.CodeUnsupportedMediaType, "Request body must be sent as application/json")
+			return
+		}
+		c.Next()
+	}
+}
+
+// isJSONMediaType accepts application/json and structured +json types
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" ||
+		(strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json"))
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"sync"
+
+	"todo-api/apierrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// overloadedRetryAfter is the Retry-After hint, in seconds, sent with
+// requests turned away for being over a concurrency cap. Requests are short,
+// so slots free up quickly.
+const overloadedRetryAfter = "1"
+
+// ConcurrencyLimitMiddleware caps the requests handled at once by the
+// instance. Past the cap, requests get 503 with Retry-After straight away
+// instead of waiting for a slot, so under a burst the requests let in keep
+// their usual latency rather than all of them queueing into timeouts.
+func ConcurrencyLimitMiddleware(limit int) gin.HandlerFunc {
+	slots := make(chan struct{}, limit)
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			c.Header("Retry-After", overloadedRetryAfter)
+			apierrors.Abort(c, apierrors.CodeOverloaded, "The server is busy, please retry shortly")
+			return
+		}
+		defer func() { <-slots }()
+		c.Next()
+	}
+}
+
+// UserConcurrencyLimitMiddleware caps the requests handled at once for each
+// user, or each client address for requests without one, so a single
+// client can't take every slot of the instance. Past the cap, the client's
+// requests get 503 with Retry-After. Counts are kept in the instance. It
+// must run after the authentication middleware.
+func UserConcurrencyLimitMiddleware(limit int) gin.HandlerFunc {
+	var (
+		mu       sync.Mutex
+		inFlight = map[string]int{}
+	)
+	return func(c *gin.Context) {
+		client := c.GetString("user_id")
+		if client == "" {
+			client = "ip:" + c.ClientIP()
+		}
+
+		mu.Lock()
+		if inFlight[client] >= limit {
+			mu.Unlock()
+			c.Header("Retry-After", overloadedRetryAfter)
+			apierrors.Abort(c, apierrors.CodeOverloaded, "Too many of your requests are in progress; wait for them to finish")
+			return
+		}
+		inFlight[client]++
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			// Clients without requests in flight aren't kept
+			if inFlight[client]--; inFlight[client] == 0 {
+				delete(inFlight, client)
+			}
+			mu.Unlock()
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"todo-api/config"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaWarningHeader repeats each quota warning of a response as
+// "<quota>=<used>/<limit>", for clients that don't read the body
+const QuotaWarningHeader = "X-Quota-Warning"
+
+// CORSMiddleware allows cross-origin requests from the configured origins.
+// Origins are matched with config.MatchOrigin so wildcard subdomain patterns
+// work, which gin-contrib/cors does not do for patterns like https://*.host.
+func CORSMiddleware(cfg config.CORSConfig) gin.HandlerFunc {
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOriginFunc = func(origin string) bool {
+		for _, pattern := range cfg.AllowOrigins {
+			if config.MatchOrigin(pattern, origin) {
+				return true
+			}
+		}
+		return false
+	}
+	// Credentials are required for the identity cookie
+	corsConfig.AllowCredentials = true
+	corsConfig.AllowHeaders = cfg.AllowHeaders
+	corsConfig.AllowMethods = cfg.AllowMethods
+	// Let browser clients read the request ID of failed requests, the build
+	// that served them, their rate limit budget and quota warnings, and
+	// when the API version they call is deprecated and what replaces it
+	corsConfig.ExposeHeaders = []string{
+		RequestIDHeader, ServerVersionHeader,
+		RateLimitLimitHeader, RateLimitRemainingHeader, RateLimitResetHeader, "Retry-After",
+		QuotaWarningHeader,
+		"Deprecation", "Sunset", "Link",
+	}
+	return cors.New(corsConfig)
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo-api/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	signer := auth.NewCookieSigner(testSecret)
+	token := signer.CSRFToken(testUserID)
+
+	tests := []struct {
+		name       string
+		method     string
+		authMethod string
+		token      string
+		wantStatus int
+	}{
+		{"read without a token", http.MethodGet, AuthMethodCookie, "", http.StatusOK},
+		{"write with the user's token", http.MethodPost, AuthMethodCookie, token, http.StatusOK},
+		{"write without a token", http.MethodPost, AuthMethodCookie, "", http.StatusForbidden},
+		{"delete without a token", http.MethodDelete, AuthMethodCookie, "", http.StatusForbidden},
+		{"write with another user's token", http.MethodPut, AuthMethodCookie, signer.CSRFToken("someone-else"), http.StatusForbidden},
+		{"write with a made up token", http.MethodPatch, AuthMethodCookie, "not-a-token", http.StatusForbidden},
+		{"write authenticated without a cookie", http.MethodPost, "bearer", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("user_id", testUserID)
+				c.Set("auth_method", tt.authMethod)
+			}, CSRFMiddleware(signer))
+			router.Handle(tt.method, "/todos", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, "/todos", nil)
+			if tt.token != "" {
+				req.Header.Set(CSRFHeader, tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"todo-api/apierrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSONAPIContentType is the media type of JSON:API documents. Clients that
+// accept it get responses in that shape.
+const JSONAPIContentType = "application/vnd.api+json"
+
+// envelopeKey overrides, for one request, whether its responses are
+// wrapped
+const envelopeKey = "envelope"
+
+// metaKey holds what handlers add to the meta of a response
+const metaKey = "response_meta"
+
+// Envelope is the standard shape of every JSON response. Data holds what
+// the handler responded with, Error the problem of a failed request, and
+// Meta details about the response itself.
+type Envelope struct {
+	Data  any                `json:"data"`
+	Meta  map[string]any     `json:"meta"`
+	Error *apierrors.Problem `json:"error"`
+}
+
+// EnvelopeMiddleware wraps JSON responses in an Envelope, or in a JSON:API
+// document when the client accepts one. Handlers keep responding with
+// plain objects: a "message" moves into meta and the rest of the object
+// becomes data. Other content types go out untouched. wrap says whether
+// responses are wrapped unless the route says otherwise, as API versions
+// do; unwrapped responses keep their old shape unless JSON:API is asked
+// for.
+func EnvelopeMiddleware(wrap bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept")
+		if c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &envelopeWriter{ResponseWriter: c.Writer, wrap: wrap}
+		c.Writer = w
+		defer w.finish(c)
+
+		c.Next()
+	}
+}
+
+// NoEnvelope sends a route's responses as the handler writes them, for
+// callers such as Slack that expect a fixed shape
+func NoEnvelope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(envelopeKey, false)
+		c.Set(noJSONAPIKey, true)
+		c.Next()
+	}
+}
+
+// noJSONAPIKey marks requests whose responses are never turned into
+// JSON:API documents
+const noJSONAPIKey = "no_jsonapi"
+
+// AddMeta adds a member to the meta of the request's response, such as
+// paging details. It's left out of responses that aren't wrapped.
+func AddMeta(c *gin.Context, key string, value any) {
+	meta, _ := c.Get(metaKey)
+	m, ok := meta.(map[string]any)
+	if !ok {
+		m = map[string]any{}
+		c.Set(metaKey, m)
+	}
+	m[key] = value
+}
+
+// wantsJSONAPI reports whether an Accept header asks for JSON:API
+func wantsJSONAPI(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(mediaType, JSONAPIContentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// envelopeWriter holds back the status and body until the handler is done,
+// so the body can be wrapped. A flush, as when streaming, sends what's been
+// written so far and the rest goes out as it is.
+type envelopeWriter struct {
+	gin.ResponseWriter
+	wrap bool
+
+	status      int
+	buf         bytes.Buffer
+	passthrough bool
+}
+
+func (w *envelopeWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+// WriteHeaderNow is a no-op until the body has been wrapped
+func (w *envelopeWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *envelopeWriter) Status() int {
+	if !w.passthrough && w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *envelopeWriter) Written() bool {
+	return w.passthrough || w.status != 0 || w.buf.Len() > 0
+}
+
+func (w *envelopeWriter) Write(p []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+func (w *envelopeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *envelopeWriter) Flush() {
+	if !w.passthrough {
+		w.send(w.buf.Bytes())
+	}
+	w.ResponseWriter.Flush()
+}
+
+// send writes the held back status and body and lets later writes through
+func (w *envelopeWriter) send(body []byte) {
+	w.passthrough = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(body) > 0 {
+		w.ResponseWriter.Write(body)
+	}
+	w.buf.Reset()
+}
+
+// finish wraps a JSON response and sends it
+func (w *envelopeWriter) finish(c *gin.Context) {
+	if w.passthrough {
+		return
+	}
+	if w.status == 0 && w.buf.Len() == 0 {
+		// Nothing was written; let gin write its defaults
+		w.passthrough = true
+		return
+	}
+
+	wrap := w.wrap
+	if v, ok := c.Get(envelopeKey); ok {
+		wrap = v.(bool)
+	}
+	jsonAPI := wantsJSONAPI(c.GetHeader("Accept")) && !c.GetBool(noJSONAPIKey)
+
+	header := w.ResponseWriter.Header()
+	contentType := header.Get("Content-Type")
+	var body any
+	if !wrap && !jsonAPI || w.buf.Len() == 0 ||
+		!strings.Contains(contentType, "json") || strings.HasPrefix(contentType, JSONAPIContentType) ||
+		decode(w.buf.Bytes(), &body) != nil {
+		w.send(w.buf.Bytes())
+		return
+	}
+
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	meta := map[string]any{}
+	if added, ok := c.Get(metaKey); ok {
+		for k, v := range added.(map[string]any) {
+			meta[k] = v
+		}
+	}
+	if id := c.GetString("request_id"); id != "" {
+		meta["request_id"] = id
+	}
+
+	var wrapped any
+	if jsonAPI {
+		header.Set("Content-Type", JSONAPIContentType)
+		wrapped = jsonAPIDocument(c, status, body, meta)
+	} else {
+		header.Set("Content-Type", gin.MIMEJSON+"; charset=utf-8")
+		wrapped = envelope(status, body, meta)
+	}
+	out, err := json.Marshal(wrapped)
+	if err != nil {
+		w.send(w.buf.Bytes())
+		return
+	}
+	header.Del("Content-Length")
+	w.send(out)
+}
+
+// decode parses a JSON body, keeping numbers as they were written
+func decode(data []byte, v *any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// problemOf returns the problem in the body of an error response
+func problemOf(status int, body any) *apierrors.Problem {
+	p := &apierrors.Problem{Status: status, Title: http.StatusText(status)}
+	if data, err := json.Marshal(body); err == nil {
+		json.Unmarshal(data, p)
+	}
+	return p
+}
+
+// splitBody takes the message out of a response object, leaving the rest
+// as data. Bodies that aren't objects are all data.
+func splitBody(body any, meta map[string]any) any {
+	fields, ok := body.(map[string]any)
+	if !ok {
+		return body
+	}
+	if message, ok := fields["message"]; ok {
+		meta["message"] = message
+		delete(fields, "message")
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// envelope wraps a response body in an Envelope
+func envelope(status int, body any, meta map[string]any) Envelope {
+	if status >= http.StatusBadRequest {
+		return Envelope{Meta: meta, Error: problemOf(status, body)}
+	}
+	return Envelope{Data: splitBody(body, meta), Meta: meta}
+}
+
+// jsonAPIDocument turns a response body into a JSON:API document. The
+// member holding resources, objects with an id or a list of them, becomes
+// the primary data, typed by its name; the other members go in meta. A body
+// with several such members has them all in meta, as JSON:API allows only
+// one kind of primary data.
+func jsonAPIDocument(c *gin.Context, status int, body any, meta map[string]any) gin.H {
+	if status >= http.StatusBadRequest {
+		return gin.H{"errors": jsonAPIErrors(c, problemOf(status, body)), "meta": meta}
+	}
+
+	fields, ok := splitBody(body, meta).(map[string]any)
+	if !ok {
+		return gin.H{"meta": meta}
+	}
+	primary := ""
+	for name, value := range fields {
+		if isResources(value) {
+			if primary != "" {
+				primary = ""
+				break
+			}
+			primary = name
+		}
+	}
+	doc := gin.H{"meta": meta}
+	for name, value := range fields {
+		if name == primary {
+			doc["data"] = resources(resourceType(name), value)
+			continue
+		}
+		meta[name] = value
+	}
+	return doc
+}
+
+// isResources reports whether a value is an object with an id, or a list
+// of them
+func isResources(value any) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		_, ok := v["id"]
+		return ok
+	case []any:
+		for _, item := range v {
+			if object, ok := item.(map[string]any); !ok || !isResources(object) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// resourceType names the type of resources from the response member
+// holding them: "todo" and "todos" are both of type "todos"
+func resourceType(member string) string {
+	if strings.HasSuffix(member, "s") {
+		return member
+	}
+	return member + "s"
+}
+
+// resources converts objects with an id into JSON:API resource objects
+func resources(kind string, value any) any {
+	if list, ok := value.([]any); ok {
+		out := make([]any, len(list))
+		for i, item := range list {
+			out[i] = resources(kind, item)
+		}
+		return out
+	}
+	attributes := value.(map[string]any)
+	id := attributes["id"]
+	delete(attributes, "id")
+	return gin.H{"type": kind, "id": id, "attributes": attributes}
+}
+
+// jsonAPIErrors converts a problem into JSON:API error objects, one per
+// offending field of a validation problem
+func jsonAPIErrors(c *gin.Context, p *apierrors.Problem) []gin.H {
+	base := gin.H{"status": strconv.Itoa(p.Status), "code": p.Code, "title": p.Title}
+	if len(p.Errors) == 0 {
+		if p.Detail != "" {
+			base["detail"] = p.Detail
+		}
+		return []gin.H{base}
+	}
+	// Fields of query requests are parameters; those of other requests
+	// are in the body
+	query := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodDelete
+	errs := make([]gin.H, len(p.Errors))
+	for i, fe := range p.Errors {
+		e := gin.H{"detail": fe.Message}
+		for k, v := range base {
+			e[k] = v
+		}
+		if query {
+			e["source"] = gin.H{"parameter": fe.Field}
+		} else {
+			e["source"] = gin.H{"pointer": "/" + strings.ReplaceAll(fe.Field, ".", "/")}
+		}
+		errs[i] = e
+	}
+	return errs
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/cache"
+	"todo-api/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrustProxies has router read the client address from X-Forwarded-For
+// only on requests that come from cfg's trusted proxies. Without any, the
+// header is ignored and the client is whoever connected, as otherwise any
+// caller could name an address of their choosing and dodge bans and
+// throttles.
+func TrustProxies(router *gin.Engine, cfg config.IPFilterConfig) error {
+	return router.SetTrustedProxies(cfg.TrustedProxies)
+}
+
+// IPFilterMiddleware refuses requests from the denied addresses with 403,
+// unless the allow list covers them too
+func IPFilterMiddleware(cfg config.IPFilterConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ip := net.ParseIP(c.ClientIP()); ip != nil && cfg.Denied(ip) {
+			apierrors.Abort(c, apierrors.CodeForbidden, "Requests from your address are not allowed")
+			return
+		}
+		c.Next()
+	}
+}
+
+// SessionThrottle limits how many identities each client address is given,
+// so bots can't create anonymous users without bound. Addresses on the
+// allow list aren't throttled. Counters live in the cache, so instances
+// sharing Redis share the budget.
+type SessionThrottle struct {
+	cfg      config.IPFilterConfig
+	counters cache.Cache
+}
+
+// NewSessionThrottle creates a SessionThrottle
+func NewSessionThrottle(cfg config.IPFilterConfig, counters cache.Cache) *SessionThrottle {
+	return &SessionThrottle{cfg: cfg, counters: counters}
+}
+
+// allow counts a new identity against the client's address. Once the
+// address has had its share for the window, it responds 429 and returns
+// false. If counting fails the identity is allowed.
+func (t *SessionThrottle) allow(c *gin.Context) bool {
+	if t == nil || t.cfg.NewSessions.Requests == 0 {
+		return true
+	}
+	rate := t.cfg.NewSessions
+	clientIP := c.ClientIP()
+	if ip := net.ParseIP(clientIP); ip != nil && t.cfg.Allowed(ip) {
+		return true
+	}
+
+	now := time.Now()
+	window := now.Truncate(rate.Per)
+	count, err := t.counters.Incr(c.Request.Context(), fmt.Sprintf("sessions:%s:%d", clientIP, window.Unix()), rate.Per)
+	if err != nil {
+		log.Println("Failed to count new session against its address:", err)
+		return true
+	}
+	if count <= int64(rate.Requests) {
+		return true
+	}
+	retryAfter := strconv.Itoa(int((window.Add(rate.Per).Sub(now) + time.Second - 1) / time.Second))
+	c.Header("Retry-After", retryAfter)
+	apierrors.Abort(c, apierrors.CodeRateLimited, "Too many new sessions from your address; try again in "+retryAfter+" seconds")
+	return false
+}
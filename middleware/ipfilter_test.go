@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo-api/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIPFilterMiddleware(t *testing.T) {
+	network := func(s string) *net.IPNet {
+		n, err := config.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	// status sends a request from peer naming forwardedFor as the client,
+	// if set, and returns the response status
+	status := func(t *testing.T, cfg config.IPFilterConfig, peer, forwardedFor string) int {
+		t.Helper()
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		if err := TrustProxies(router, cfg); err != nil {
+			t.Fatal(err)
+		}
+		router.Use(IPFilterMiddleware(cfg))
+		router.GET("/", func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = net.JoinHostPort(peer, "40000")
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	cfg := config.IPFilterConfig{
+		Deny:  []*net.IPNet{network("198.51.100.0/24")},
+		Allow: []*net.IPNet{network("198.51.100.7")},
+	}
+	proxied := cfg
+	proxied.TrustedProxies = []string{"10.0.0.0/8"}
+
+	tests := []struct {
+		name         string
+		cfg          config.IPFilterConfig
+		peer         string
+		forwardedFor string
+		want         int
+	}{
+		{"denied peer", cfg, "198.51.100.8", "", http.StatusForbidden},
+		{"allowed peer", cfg, "198.51.100.7", "", http.StatusNoContent},
+		{"denied peer naming another client", cfg, "198.51.100.8", "203.0.113.1", http.StatusForbidden},
+		{"denied peer naming an allowed client", cfg, "198.51.100.8", "198.51.100.7", http.StatusForbidden},
+		{"peer naming a denied client without trusted proxies", cfg, "203.0.113.1", "198.51.100.8", http.StatusNoContent},
+		{"trusted proxy naming a denied client", proxied, "10.1.2.3", "198.51.100.8", http.StatusForbidden},
+		{"trusted proxy naming an allowed client", proxied, "10.1.2.3", "198.51.100.7", http.StatusNoContent},
+		{"untrusted peer naming another client", proxied, "198.51.100.8", "203.0.113.1", http.StatusForbidden},
+		{"denied client spoofing past a trusted proxy", proxied, "10.1.2.3", "203.0.113.1, 198.51.100.8", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status(t, tt.cfg, tt.peer, tt.forwardedFor); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
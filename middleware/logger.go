@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-api/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// credentialParams are route parameters that are credentials themselves,
+// so they're replaced in logged and recorded paths
+var credentialParams = []string{"token"}
+
+// logPathKey is the context key of the path the access log shows
+const logPathKey = "log_path"
+
+// LoggerMiddleware writes gin's access log without anything sensitive in
+// the paths: credentials in route parameters are redacted, and query
+// parameters are logged by name only, since their values can be search
+// terms quoting titles and descriptions
+func LoggerMiddleware() gin.HandlerFunc {
+	logger := gin.LoggerWithConfig(gin.LoggerConfig{Formatter: formatLogEntry})
+	return func(c *gin.Context) {
+		// Route parameters are known before any handler runs
+		c.Set(logPathKey, loggedPath(c))
+		logger(c)
+	}
+}
+
+// formatLogEntry formats an entry the way gin's default logger does,
+// without colors
+func formatLogEntry(p gin.LogFormatterParams) string {
+	path, _ := p.Keys[logPathKey].(string)
+	if p.Latency > time.Minute {
+		p.Latency = p.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		p.StatusCode,
+		p.Latency,
+		p.ClientIP,
+		p.Method,
+		path,
+		logging.Redact(p.ErrorMessage),
+	)
+}
+
+// loggedPath is the request's path as redactedPath gives it, followed by
+// the names of its query parameters
+func loggedPath(c *gin.Context) string {
+	path := redactedPath(c)
+	query := c.Request.URL.Query()
+	if len(query) == 0 {
+		return path
+	}
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return path + "?" + strings.Join(names, "&")
+}
+
+// redactedPath is the request's path with credentials in route parameters,
+// such as public link tokens, replaced
+func redactedPath(c *gin.Context) string {
+	path := c.Request.URL.Path
+	for _, name := range credentialParams {
+		if v := c.Param(name); v != "" {
+			path = strings.Replace(path, v, logging.Redacted, 1)
+		}
+	}
+	return path
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/cache"
+	"todo-api/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers describing the tightest budget a request counted against, in the
+// style of the IETF RateLimit header fields draft
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// budget is one rate a request is counted against
+type budget struct {
+	name string
+	rate config.Rate
+}
+
+// RateLimitMiddleware counts requests in fixed windows, per user or, for
+// requests without one, per client address, against the default budget and
+// the first route budget that matches. Once a budget is spent, requests get
+// 429 with Retry-After until its window ends. Counters live in c, so
+// instances sharing Redis share budgets. If counting fails the request is
+// let through. It must run after the authentication middleware.
+func RateLimitMiddleware(cfg config.RateLimitConfig, counters cache.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := c.GetString("user_id")
+		if client == "" {
+			client = "ip:" + c.ClientIP()
+		}
+
+		budgets := []budget{{name: "default", rate: cfg.Default}}
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		hasParam := func(name string) bool {
+			_, ok := c.GetQuery(name)
+			return ok
+		}
+		for _, r := range cfg.Routes {
+			if r.Matches(c.Request.Method, route, hasParam) {
+				budgets = append(budgets, budget{name: r.Pattern(), rate: r.Rate})
+				break
+			}
+		}
+
+		now := time.Now()
+		var (
+			tightest  budget
+			remaining int64 = -1
+			reset     time.Duration
+			exceeded  bool
+		)
+		for _, b := range budgets {
+			window := now.Truncate(b.rate.Per)
+			key := fmt.Sprintf("ratelimit:%s:%s:%d", b.name, client, window.Unix())
+			count, err := counters.Incr(c.Request.Context(), key, b.rate.Per)
+			if err != nil {
+				log.Println("Failed to count request against rate limit:", err)
+				c.Next()
+				return
+			}
+			left := int64(b.rate.Requests) - count
+			if left < 0 || remaining < 0 || left < remaining {
+				tightest, remaining, reset = b, max(left, 0), window.Add(b.rate.Per).Sub(now)
+			}
+			if left < 0 {
+				exceeded = true
+				break
+			}
+		}
+
+		resetSeconds := strconv.Itoa(int((reset + time.Second - 1) / time.Second))
+		c.Header(RateLimitLimitHeader, strconv.Itoa(tightest.rate.Requests))
+		c.Header(RateLimitRemainingHeader, strconv.FormatInt(remaining, 10))
+		c.Header(RateLimitResetHeader, resetSeconds)
+		if exceeded {
+			c.Header("Retry-After", resetSeconds)
+			apierrors.Abort(c, apierrors.CodeRateLimited, fmt.Sprintf("At most %d requests are allowed every %s; try again in %s seconds", tightest.rate.Requests, tightest.rate.Per, resetSeconds))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"todo-api/apierrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dropConnectionKey is the context key DropConnection sets
+const dropConnectionKey = "drop_connection"
+
+// DropConnection has the connection closed once the handler returns, so a
+// response that was started but can't be finished fails on the client
+// instead of looking complete. It needs RecoveryMiddleware.
+func DropConnection(c *gin.Context) {
+	c.Set(dropConnectionKey, true)
+}
+
+// RecoveryMiddleware turns panics into a problem response instead of the
+// empty 500 gin sends by default. gin logs the panic and stack trace. It
+// also closes the connections handlers asked to with DropConnection.
+func RecoveryMiddleware() gin.HandlerFunc {
+	recovery := gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		apierrors.Abort(c, apierrors.CodeInternal, "An unexpected error occurred")
+	})
+	return func(c *gin.Context) {
+		recovery(c)
+		if c.GetBool(dropConnectionKey) {
+			// net/http closes the connection without logging this one
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
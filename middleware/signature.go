@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"todo-api/apierrors"
+	"todo-api/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SignatureVerifier checks that a webhook request was signed by its sender
+// and sent recently, returning an ID that a replay of the request shares,
+// such as its signature. body is the raw request body.
+type SignatureVerifier func(header http.Header, body []byte, now time.Time) (id string, err error)
+
+// VerifySignature lets through webhook requests from the sender called name
+// that verify accepts and that haven't been received before. The IDs of
+// requests are remembered in replays for window, which must be at least
+// twice the timestamp tolerance, as timestamps may be that far ahead or
+// behind; older replays fail verify. replays is shared with other
+// instances when it's Redis. A request that can't be checked against
+// replays is turned away, since it could be one. The body is kept for the
+// handler.
+func VerifySignature(name string, verify SignatureVerifier, replays cache.Cache, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var tooLargeErr *http.MaxBytesError
+			if errors.As(err, &tooLargeErr) {
+				apierrors.Abort(c, apierrors.CodePayloadTooLarge,
+					fmt.Sprintf("Request body must not exceed %d bytes", tooLargeErr.Limit))
+				return
+			}
+			apierrors.Abort(c, apierrors.CodeInvalidRequest, "Failed to read the request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		id, err := verify(c.Request.Header, body, time.Now())
+		if err != nil {
+			apierrors.Abort(c, apierrors.CodeUnauthenticated, "Invalid request signature")
+			return
+		}
+		sum := sha256.Sum256([]byte(id))
+		seen, err := replays.Incr(c.Request.Context(), "replay:"+name+":"+hex.EncodeToString(sum[:]), window)
+		if err != nil {
+			log.Printf("Checking a %s request for replays failed: %v", name, err)
+			apierrors.Abort(c, apierrors.CodeStorageUnavailable, "Failed to check the request")
+			return
+		}
+		if seen > 1 {
+			apierrors.Abort(c, apierrors.CodeUnauthenticated, "This request was already received")
+			return
+		}
+		c.Next()
+	}
+}
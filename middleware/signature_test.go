@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-api/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testVerifier accepts requests signed "good", identifying them by their
+// X-Request-Sig header
+func testVerifier(header http.Header, body []byte, now time.Time) (string, error) {
+	if !strings.HasPrefix(header.Get("X-Request-Sig"), "good") {
+		return "", errors.New("bad signature")
+	}
+	return header.Get("X-Request-Sig"), nil
+}
+
+// failingCache is a cache.Cache whose counters can't be reached
+type failingCache struct {
+	cache.Cache
+}
+
+func (failingCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 0, errors.New("cache is down")
+}
+
+func TestVerifySignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		// signatures are sent in turn; the last one's response is checked
+		signatures []string
+		replays    cache.Cache
+		wantStatus int
+		wantCode   string
+	}{
+		{"signed", []string{"good-1"}, cache.NewMemoryCache(), http.StatusOK, ""},
+		{"forged", []string{"forged"}, cache.NewMemoryCache(), http.StatusUnauthorized, "UNAUTHENTICATED"},
+		{"unsigned", []string{""}, cache.NewMemoryCache(), http.StatusUnauthorized, "UNAUTHENTICATED"},
+		{"replayed", []string{"good-1", "good-1"}, cache.NewMemoryCache(), http.StatusUnauthorized, "UNAUTHENTICATED"},
+		{"another signed request", []string{"good-1", "good-2"}, cache.NewMemoryCache(), http.StatusOK, ""},
+		{"replays unchecked", []string{"good-1"}, failingCache{}, http.StatusServiceUnavailable, "STORAGE_UNAVAILABLE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled string
+			router := gin.New()
+			router.POST("/", VerifySignature("test", testVerifier, tt.replays, time.Minute), func(c *gin.Context) {
+				body, _ := io.ReadAll(c.Request.Body)
+				handled = string(body)
+				c.Status(http.StatusOK)
+			})
+
+			var rec *httptest.ResponseRecorder
+			for _, signature := range tt.signatures {
+				handled = ""
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("text=hello"))
+				req.Header.Set("X-Request-Sig", signature)
+				rec = httptest.NewRecorder()
+				router.ServeHTTP(rec, req)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantCode == "" {
+				if handled != "text=hello" {
+					t.Errorf("handler read body %q, want it kept", handled)
+				}
+				return
+			}
+			if handled != "" {
+				t.Error("a rejected request reached the handler")
+			}
+			var problem map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil || problem["code"] != tt.wantCode {
+				t.Errorf("body = %s, want code %s", rec.Body, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"todo-api/apierrors"
+	"todo-api/config"
+	"todo-api/tenancy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantMiddleware resolves the tenant each request names and carries it in
+// the request's context, where the repositories pick the tenant's data from.
+// Requests that name no tenant are refused with 400, and those naming one
+// that isn't served with 404.
+func TenantMiddleware(cfg config.TenancyConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.Mode == config.TenantModeHeader {
+			// Responses differ by tenant, so caches mustn't share them
+			c.Writer.Header().Add("Vary", cfg.Header)
+		}
+		tenant, ok := tenancy.Resolve(cfg, c.Request)
+		if !ok {
+			if tenant == "" {
+				apierrors.Abort(c, apierrors.CodeInvalidRequest, "Name the organization in the request")
+			} else {
+				apierrors.Abort(c, apierrors.CodeTenantNotFound, "This organization isn't served here")
+			}
+			return
+		}
+		c.Set("tenant", tenant)
+		c.Request = c.Request.WithContext(tenancy.WithTenant(c.Request.Context(), tenant))
+		c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"todo-api/apierrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimeoutMiddleware gives every request an overall deadline. The deadline is
+// carried by the request context, so database calls and anything else
+// derived from it are cancelled when it passes. If the handler hasn't
+// responded by then, the client gets a 504.
+func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if !c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			apierrors.Abort(c, apierrors.CodeTimeout, fmt.Sprintf("Request did not complete within %s", timeout))
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServerVersionHeader names the build that served a response
+const ServerVersionHeader = "X-Server-Version"
+
+// ServerVersionMiddleware sets the X-Server-Version header on every
+// response, so the build serving each deployment slot can be told apart
+func ServerVersionMiddleware(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(ServerVersionHeader, version)
+		c.Next()
+	}
+}
+
+// APIVersionMiddleware tags requests to a version of the API, which
+// handlers read as "api_version", and sets whether its JSON responses are
+// wrapped in the Envelope
+func APIVersionMiddleware(version string, envelope bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("api_version", version)
+		c.Set(envelopeKey, envelope)
+		c.Next()
+	}
+}
+
+// DeprecationMiddleware marks responses of a deprecated API version with
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers, and links to the
+// same path under the successor's prefix. A zero sunset sends no Sunset
+// header.
+func DeprecationMiddleware(prefix, successor string, deprecatedAt, sunset time.Time) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if rest, ok := strings.CutPrefix(c.Request.URL.Path, prefix); ok {
+			c.Writer.Header().Add("Link", "<"+successor+rest+`>; rel="successor-version"`)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,99 @@
+// Package migrations evolves stored data as the schema changes, in two
+// ways. Startup migrations run once per database when an instance starts,
+// for changes that have to reach every document, such as new indexes or
+// backfilled fields; each is recorded once applied, so it isn't run again.
+// Document upgrades bring documents written by older versions up to date as
+// they're read, so instances can serve them before, or without, a startup
+// migration rewriting them, and while older instances still write them
+// during a rolling deployment.
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Lease is how long a startup migration stays claimed by the instance
+// running it. An instance that stops partway loses its claim after that, so
+// another can run the migration again; migrations must be safe to repeat.
+const Lease = 10 * time.Minute
+
+// Migration is a change made once to a database at startup
+type Migration struct {
+	// ID names the migration and is recorded once it's applied, so it must
+	// never change. Migrations are run in the order they're listed.
+	ID          string
+	Description string
+	Up          func(ctx context.Context) error
+}
+
+// Record is a startup migration an instance has claimed
+type Record struct {
+	ID        string    `json:"id" bson:"_id"`
+	StartedAt time.Time `json:"started_at" bson:"started_at"`
+	// AppliedAt is unset while the migration runs
+	AppliedAt *time.Time `json:"applied_at,omitempty" bson:"applied_at,omitempty"`
+}
+
+// Store records which startup migrations were applied
+type Store interface {
+	// List returns the migrations claimed so far, applied or not
+	List(ctx context.Context) ([]Record, error)
+	// Claim records that the migration is being run from now. It returns
+	// false if it was applied already, or claimed within lease by another
+	// instance.
+	Claim(ctx context.Context, id string, now time.Time, lease time.Duration) (bool, error)
+	// Complete records that the claimed migration was applied at now
+	Complete(ctx context.Context, id string, now time.Time) error
+	// Release gives up the claim on a migration that failed, so it's run
+	// again by the next instance to start
+	Release(ctx context.Context, id string) error
+}
+
+// Run applies the migrations that haven't been yet, in order, and returns
+// how many it applied. It stops at a migration another instance is
+// running, since later ones may depend on it; they're applied when an
+// instance next starts. A migration that fails is released and its error
+// returned.
+func Run(ctx context.Context, store Store, migrations []Migration) (int, error) {
+	records, err := store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	applied := make(map[string]bool, len(records))
+	for _, record := range records {
+		applied[record.ID] = record.AppliedAt != nil
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if applied[m.ID] {
+			continue
+		}
+		claimed, err := store.Claim(ctx, m.ID, time.Now(), Lease)
+		if err != nil {
+			return count, err
+		}
+		if !claimed {
+			log.Printf("Migration %s is being applied by another instance", m.ID)
+			return count, nil
+		}
+		if err := m.Up(ctx); err != nil {
+			// Released with a context of its own, as ctx may be why it failed
+			releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			if releaseErr := store.Release(releaseCtx, m.ID); releaseErr != nil {
+				log.Printf("Failed to release migration %s: %v", m.ID, releaseErr)
+			}
+			cancel()
+			return count, fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+		if err := store.Complete(ctx, m.ID, time.Now()); err != nil {
+			return count, err
+		}
+		log.Printf("Applied migration %s: %s", m.ID, m.Description)
+		count++
+	}
+	return count, nil
+}
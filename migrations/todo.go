@@ -0,0 +1,59 @@
+package migrations
+
+import "todo-api/models"
+
+// TodoUpgrade brings a todo written before Version up to it
+type TodoUpgrade struct {
+	Version     int
+	Description string
+	// Apply changes the todo in place. It's also applied to todos that
+	// already had the change made by a startup migration, so it must leave
+	// them as they are.
+	Apply func(todo *models.Todo)
+}
+
+// todoUpgrades are the changes to the todo schema, oldest first. Versions
+// count up from 1 and a change is only ever added at the end.
+var todoUpgrades = []TodoUpgrade{
+	{
+		Version:     1,
+		Description: "Give todos completed before completed_at was recorded their last update instead",
+		Apply: func(todo *models.Todo) {
+			if todo.Completed && todo.CompletedAt == nil {
+				completedAt := todo.UpdatedAt
+				todo.CompletedAt = &completedAt
+			}
+		},
+	},
+}
+
+// TodoFieldsRead are the stored todo fields the upgrades read, which have to
+// be loaded for a todo to be upgraded. An upgrade reading other fields adds
+// them here.
+var TodoFieldsRead = []string{"completed", "completed_at", "updated_at"}
+
+// TodoVersion is the version of the todo schema this build writes
+var TodoVersion = todoUpgrades[len(todoUpgrades)-1].Version
+
+// UpgradeTodo applies the upgrades the todo is missing and stamps it with
+// TodoVersion, returning whether it was behind. Todos written by a newer
+// build are left as they are.
+func UpgradeTodo(todo *models.Todo) bool {
+	if todo.SchemaVersion >= TodoVersion {
+		return false
+	}
+	for _, upgrade := range todoUpgrades {
+		if upgrade.Version > todo.SchemaVersion {
+			upgrade.Apply(todo)
+		}
+	}
+	todo.SchemaVersion = TodoVersion
+	return true
+}
+
+// UpgradeTodos upgrades each of todos
+func UpgradeTodos(todos []models.Todo) {
+	for i := range todos {
+		UpgradeTodo(&todos[i])
+	}
+}
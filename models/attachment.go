@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Attachment is a link attached to a todo, with a preview of the page it
+// points to when one could be fetched
+type Attachment struct {
+	ID      primitive.ObjectID `json:"id" bson:"id"`
+	URL     string             `json:"url" bson:"url"`
+	Preview *LinkPreview       `json:"preview,omitempty" bson:"preview,omitempty"`
+	// AddedBy is the user who attached the link
+	AddedBy string    `json:"added_by" bson:"added_by"`
+	AddedAt time.Time `json:"added_at" bson:"added_at"`
+}
+
+// LinkPreview is what a page says about itself in its OpenGraph tags, or
+// failing those its title and description, for clients to render a link
+// card. Any of the fields may be empty.
+type LinkPreview struct {
+	Title       string `json:"title,omitempty" bson:"title,omitempty"`
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+	// Image is an absolute http or https URL
+	Image    string `json:"image,omitempty" bson:"image,omitempty"`
+	SiteName string `json:"site_name,omitempty" bson:"site_name,omitempty"`
+	// URL is the page's canonical address, after any redirects
+	URL       string    `json:"url,omitempty" bson:"url,omitempty"`
+	FetchedAt time.Time `json:"fetched_at" bson:"fetched_at"`
+}
+
+// AddAttachmentRequest attaches a link to a todo
+type AddAttachmentRequest struct {
+	URL string `json:"url"`
+}
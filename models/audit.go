@@ -0,0 +1,71 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEntry records one request for security reviews. The IDs order
+// entries by time.
+type AuditEntry struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	Time      time.Time          `json:"time" bson:"time"`
+	RequestID string             `json:"request_id" bson:"request_id"`
+	// Tenant is the organization the request was made to, when the
+	// deployment hosts several
+	Tenant string `json:"tenant,omitempty" bson:"tenant,omitempty"`
+	Method string `json:"method" bson:"method"`
+	// Path is the requested path, with credentials such as public link
+	// tokens replaced
+	Path string `json:"path" bson:"path"`
+	// Route is the pattern the request was routed to, empty when none
+	// matched
+	Route  string `json:"route,omitempty" bson:"route,omitempty"`
+	UserID string `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	// AuthMethod is how the caller was identified: cookie, bearer or admin
+	AuthMethod string `json:"auth_method,omitempty" bson:"auth_method,omitempty"`
+	ClientIP   string `json:"client_ip" bson:"client_ip"`
+	UserAgent  string `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	Status     int    `json:"status" bson:"status"`
+	LatencyMs  int64  `json:"latency_ms" bson:"latency_ms"`
+	// Body is the JSON body of a mutation with secret fields redacted.
+	// Bodies that aren't JSON or are too large to redact are left out, and
+	// only BodyBytes is recorded.
+	Body      string `json:"body,omitempty" bson:"body,omitempty"`
+	BodyBytes int64  `json:"body_bytes,omitempty" bson:"body_bytes,omitempty"`
+}
+
+// AuditQuery selects audit entries. Zero fields match everything.
+type AuditQuery struct {
+	UserID string
+	Method string
+	// PathPrefix matches entries whose path starts with it
+	PathPrefix string
+	// MinStatus and MaxStatus bound the status, inclusive
+	MinStatus int
+	MaxStatus int
+	// Since and Until bound the time, inclusive and exclusive
+	Since time.Time
+	Until time.Time
+	// Before pages backwards: only entries older than this ID match
+	Before primitive.ObjectID
+	Limit  int
+}
+
+// Matches reports whether the entry is selected by q, apart from the limit
+func (q AuditQuery) Matches(entry *AuditEntry) bool {
+	switch {
+	case q.UserID != "" && entry.UserID != q.UserID,
+		q.Method != "" && entry.Method != q.Method,
+		q.PathPrefix != "" && !strings.HasPrefix(entry.Path, q.PathPrefix),
+		q.MinStatus != 0 && entry.Status < q.MinStatus,
+		q.MaxStatus != 0 && entry.Status > q.MaxStatus,
+		!q.Since.IsZero() && entry.Time.Before(q.Since),
+		!q.Until.IsZero() && !entry.Time.Before(q.Until),
+		!q.Before.IsZero() && entry.ID.Hex() >= q.Before.Hex():
+		return false
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChecklistRule decides how a todo's completion follows its checklist
+type ChecklistRule string
+
+const (
+	// ChecklistRuleNone leaves the todo and its checklist independent
+	ChecklistRuleNone ChecklistRule = ""
+	// ChecklistRuleAutoComplete completes the todo once every item is done
+	ChecklistRuleAutoComplete ChecklistRule = "auto_complete"
+	// ChecklistRuleBlock refuses to complete the todo while items are open
+	ChecklistRuleBlock ChecklistRule = "block"
+)
+
+// Valid reports whether r is a known rule. The empty rule is valid.
+func (r ChecklistRule) Valid() bool {
+	switch r {
+	case ChecklistRuleNone, ChecklistRuleAutoComplete, ChecklistRuleBlock:
+		return true
+	}
+	return false
+}
+
+// ChecklistItem is a subtask of a todo
+type ChecklistItem struct {
+	ID          primitive.ObjectID `json:"id" bson:"id"`
+	Title       string             `json:"title" bson:"title"`
+	Completed   bool               `json:"completed" bson:"completed"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+// SetCompleted completes or reopens the item, as Todo.SetCompleted does
+func (i *ChecklistItem) SetCompleted(completed bool, now time.Time) {
+	switch {
+	case !completed:
+		i.CompletedAt = nil
+	case !i.Completed || i.CompletedAt == nil:
+		i.CompletedAt = &now
+	}
+	i.Completed = completed
+}
+
+// AddChecklistItemRequest adds an item to a todo's checklist
+type AddChecklistItemRequest struct {
+	Title string `json:"title"`
+}
+
+// UpdateChecklistItemRequest changes the fields of an item that are sent
+type UpdateChecklistItemRequest struct {
+	Title     *string `json:"title"`
+	Completed *bool   `json:"completed"`
+}
+
+// OpenChecklistItems counts the items of the todo's checklist not done yet
+func (t *Todo) OpenChecklistItems() int {
+	open := 0
+	for _, item := range t.Checklist {
+		if !item.Completed {
+			open++
+		}
+	}
+	return open
+}
+
+// ApplyChecklistRule brings the todo's completion in line with its rule
+// after its checklist or rule changed: an open item reopens a completed
+// todo, and with ChecklistRuleAutoComplete the todo is completed once every
+// item is done. Todos without a rule or without items are left alone.
+func (t *Todo) ApplyChecklistRule(now time.Time) {
+	if t.ChecklistRule == ChecklistRuleNone || len(t.Checklist) == 0 {
+		return
+	}
+	open := t.OpenChecklistItems()
+	switch {
+	case open > 0 && t.Completed:
+		t.SetCompleted(false, now)
+	case open == 0 && t.ChecklistRule == ChecklistRuleAutoComplete:
+		t.SetCompleted(true, now)
+	}
+}
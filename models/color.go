@@ -0,0 +1,27 @@
+package models
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Color styles a todo or workspace: a hex code such as "#1e90ff" or "#f80",
+// or the name of a palette color that each client maps to its own shade
+type Color string
+
+// Palette lists the named colors
+var Palette = []string{"red", "orange", "yellow", "green", "teal", "blue", "purple", "pink", "gray"}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+// NormalizeColor trims and lowercases a color so equal colors compare equal
+func NormalizeColor(c Color) Color {
+	return Color(strings.ToLower(strings.TrimSpace(string(c))))
+}
+
+// Valid reports whether c is a hex code or a palette name. Call
+// NormalizeColor first.
+func (c Color) Valid() bool {
+	return hexColor.MatchString(string(c)) || slices.Contains(Palette, string(c))
+}
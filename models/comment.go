@@ -0,0 +1,58 @@
+package models
+
+import (
+	"regexp"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mentionPattern matches @<user ID> where it doesn't follow a word, so
+// email addresses aren't read as mentions. IDs end in a letter or digit,
+// leaving out the full stop of a sentence.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@])@([A-Za-z0-9](?:[\w.-]*[A-Za-z0-9])?)`)
+
+// Comment is a note left on a todo. Mentions are the users it mentions,
+// who find it in their mentions inbox.
+type Comment struct {
+	ID       primitive.ObjectID `json:"id" bson:"id"`
+	AuthorID string             `json:"author_id" bson:"author_id"`
+	// Body is plain text; users are mentioned as @<user ID>
+	Body      string    `json:"body" bson:"body"`
+	Mentions  []string  `json:"mentions,omitempty" bson:"mentions,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Mentioned reports whether the comment mentions the user
+func (c *Comment) Mentioned(userID string) bool {
+	return slices.Contains(c.Mentions, userID)
+}
+
+// ParseMentions returns the user IDs mentioned in text, each once, in the
+// order they first appear
+func ParseMentions(text string) []string {
+	var ids []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		if !slices.Contains(ids, match[1]) {
+			ids = append(ids, match[1])
+		}
+	}
+	return ids
+}
+
+// TodoComment is a comment along with the todo it's on. Comment events
+// carry one, and the mentions inbox lists them.
+type TodoComment struct {
+	TodoID      primitive.ObjectID  `json:"todo_id"`
+	WorkspaceID *primitive.ObjectID `json:"workspace_id,omitempty"`
+	TodoTitle   string              `json:"todo_title"`
+	Comment     Comment             `json:"comment"`
+	// Links point at the todo. They're filled in by handlers.
+	Links map[string]Link `json:"_links,omitempty"`
+}
+
+// AddCommentRequest leaves a comment on a todo
+type AddCommentRequest struct {
+	Body string `json:"body"`
+}
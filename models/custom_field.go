@@ -0,0 +1,172 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"todo-api/apierrors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CustomField is a user-defined field todos can have a value for. Fields
+// belong to a user's personal todos or to a workspace, and only apply to
+// todos in the same place.
+type CustomField struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID string             `json:"user_id" bson:"user_id"`
+	// WorkspaceID is set for workspace fields; UserID is then the member
+	// who defined it
+	WorkspaceID *primitive.ObjectID `json:"workspace_id,omitempty" bson:"workspace_id,omitempty"`
+	Name        string              `json:"name" bson:"name"`
+	Type        FieldType           `json:"type" bson:"type"`
+	// Options lists the values a select field can take
+	Options   []string  `json:"options,omitempty" bson:"options,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// FieldType is the kind of value a custom field holds
+type FieldType string
+
+const (
+	FieldText   FieldType = "text"
+	FieldNumber FieldType = "number"
+	FieldDate   FieldType = "date"
+	FieldSelect FieldType = "select"
+)
+
+// Valid reports whether t is a known field type
+func (t FieldType) Valid() bool {
+	return t == FieldText || t == FieldNumber || t == FieldDate || t == FieldSelect
+}
+
+// Custom field limits, counted in characters rather than bytes
+const (
+	MaxCustomFields            = 50
+	MaxCustomFieldNameLength   = 50
+	MaxCustomFieldOptions      = 50
+	MaxCustomFieldOptionLength = 100
+	MaxCustomFieldTextLength   = 1000
+)
+
+// Value checks a value sent for the field and returns it in the form it's
+// stored in: text and select values as trimmed strings, numbers as float64
+// and dates as YYYY-MM-DD strings. Numbers and dates may also be sent as
+// strings, which is how they arrive in query parameters. The message
+// explains why a value was refused.
+func (f *CustomField) Value(v any) (value any, message string) {
+	s, isString := v.(string)
+	if isString {
+		s = strings.TrimSpace(s)
+	}
+	switch f.Type {
+	case FieldText:
+		switch {
+		case !isString:
+			return nil, "must be a string"
+		case utf8.RuneCountInString(s) > MaxCustomFieldTextLength:
+			return nil, fmt.Sprintf("must be at most %d characters", MaxCustomFieldTextLength)
+		}
+		return s, ""
+	case FieldNumber:
+		n, ok := v.(float64)
+		if isString {
+			parsed, err := strconv.ParseFloat(s, 64)
+			n, ok = parsed, err == nil
+		}
+		if !ok || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, "must be a number"
+		}
+		return n, ""
+	case FieldDate:
+		if !isString {
+			return nil, "must be a date (YYYY-MM-DD)"
+		}
+		if _, err := time.Parse(time.DateOnly, s); err != nil {
+			return nil, "must be a date (YYYY-MM-DD)"
+		}
+		return s, ""
+	case FieldSelect:
+		if !isString || !slices.Contains(f.Options, s) {
+			return nil, "must be one of " + strings.Join(f.Options, ", ")
+		}
+		return s, ""
+	}
+	return nil, "is not a field we understand"
+}
+
+// Matches reports whether a stored value equals a value checked by Value.
+// Text is compared case-insensitively.
+func (f *CustomField) Matches(stored, want any) bool {
+	if f.Type == FieldText {
+		s, ok := stored.(string)
+		return ok && strings.EqualFold(s, want.(string))
+	}
+	// Numbers read back from some backends are integers
+	switch n := stored.(type) {
+	case int32:
+		stored = float64(n)
+	case int64:
+		stored = float64(n)
+	}
+	return stored == want
+}
+
+// CreateCustomFieldRequest defines a custom field
+type CreateCustomFieldRequest struct {
+	Name    string    `json:"name"`
+	Type    FieldType `json:"type"`
+	Options []string  `json:"options"`
+}
+
+// Normalize trims surrounding whitespace from the name and options
+func (r *CreateCustomFieldRequest) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Type = FieldType(strings.ToLower(strings.TrimSpace(string(r.Type))))
+	for i, option := range r.Options {
+		r.Options[i] = strings.TrimSpace(option)
+	}
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *CreateCustomFieldRequest) Validate() []apierrors.FieldError {
+	var errs []apierrors.FieldError
+	switch {
+	case r.Name == "":
+		errs = append(errs, apierrors.FieldError{Field: "name", Message: "must not be empty or only whitespace"})
+	case utf8.RuneCountInString(r.Name) > MaxCustomFieldNameLength:
+		errs = append(errs, apierrors.FieldError{Field: "name", Message: fmt.Sprintf("must be at most %d characters", MaxCustomFieldNameLength)})
+	}
+	if !r.Type.Valid() {
+		errs = append(errs, apierrors.FieldError{Field: "type", Message: fmt.Sprintf("must be %q, %q, %q or %q", FieldText, FieldNumber, FieldDate, FieldSelect)})
+	}
+
+	switch {
+	case r.Type != FieldSelect:
+		if len(r.Options) > 0 {
+			errs = append(errs, apierrors.FieldError{Field: "options", Message: "are only allowed for select fields"})
+		}
+	case len(r.Options) == 0:
+		errs = append(errs, apierrors.FieldError{Field: "options", Message: "must not be empty"})
+	case len(r.Options) > MaxCustomFieldOptions:
+		errs = append(errs, apierrors.FieldError{Field: "options", Message: fmt.Sprintf("must have at most %d entries", MaxCustomFieldOptions)})
+	default:
+		for i, option := range r.Options {
+			field := fmt.Sprintf("options[%d]", i)
+			switch {
+			case option == "":
+				errs = append(errs, apierrors.FieldError{Field: field, Message: "must not be empty or only whitespace"})
+			case utf8.RuneCountInString(option) > MaxCustomFieldOptionLength:
+				errs = append(errs, apierrors.FieldError{Field: field, Message: fmt.Sprintf("must be at most %d characters", MaxCustomFieldOptionLength)})
+			case slices.Contains(r.Options[:i], option):
+				errs = append(errs, apierrors.FieldError{Field: field, Message: "must not repeat an earlier option"})
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// UserExport is everything in a user's personal list, as the admin API
+// exports and imports it. Timer sessions and revision history aren't
+// included.
+type UserExport struct {
+	UserID       string        `json:"user_id"`
+	ExportedAt   time.Time     `json:"exported_at"`
+	Settings     UserSettings  `json:"settings"`
+	Todos        []Todo        `json:"todos"`
+	CustomFields []CustomField `json:"custom_fields"`
+	Filters      []SavedFilter `json:"filters"`
+}
+
+// ImportCounts says how many items of one kind an import created, and how
+// many it skipped because an item with the same ID was already stored
+type ImportCounts struct {
+	Created int `json:"created"`
+	Skipped int `json:"skipped"`
+}
@@ -0,0 +1,76 @@
+package models
+
+// TodoFacets counts the todos of a listing by the values of the fields
+// clients filter on, so filter UIs can show counts next to each choice
+// without a request per choice
+type TodoFacets struct {
+	// Status counts "open" and "completed" todos
+	Status map[string]int64 `json:"status"`
+	// Priority counts todos by priority, with "none" for those without
+	Priority map[string]int64 `json:"priority"`
+	// Color counts todos by color, with "none" for those without
+	Color map[string]int64 `json:"color"`
+	// Tag counts todos by each of their tags
+	Tag     map[string]int64 `json:"tag"`
+	Pinned  int64            `json:"pinned"`
+	Blocked int64            `json:"blocked"`
+}
+
+// NewTodoFacets returns facets counting no todos, with every status and
+// priority listed
+func NewTodoFacets() *TodoFacets {
+	facets := &TodoFacets{
+		Status:   map[string]int64{"open": 0, "completed": 0},
+		Priority: map[string]int64{"none": 0},
+		Color:    map[string]int64{"none": 0},
+		Tag:      map[string]int64{},
+	}
+	for _, p := range []Priority{PriorityLow, PriorityMedium, PriorityHigh} {
+		facets.Priority[string(p)] = 0
+	}
+	return facets
+}
+
+// Add counts a todo, whose Blocked flag has to be set already
+func (f *TodoFacets) Add(todo *Todo) {
+	f.AddStatus(todo.Completed, 1)
+	f.AddPriority(todo.Priority, 1)
+	f.AddColor(todo.Color, 1)
+	for _, tag := range todo.Tags {
+		f.Tag[tag]++
+	}
+	if todo.Pinned {
+		f.Pinned++
+	}
+	if todo.Blocked {
+		f.Blocked++
+	}
+}
+
+// AddStatus counts n todos completed or not
+func (f *TodoFacets) AddStatus(completed bool, n int64) {
+	if completed {
+		f.Status["completed"] += n
+	} else {
+		f.Status["open"] += n
+	}
+}
+
+// AddPriority counts n todos with the priority, which may be missing or
+// invalid
+func (f *TodoFacets) AddPriority(p Priority, n int64) {
+	if p.Valid() {
+		f.Priority[string(p)] += n
+	} else {
+		f.Priority["none"] += n
+	}
+}
+
+// AddColor counts n todos with the color, which may be missing
+func (f *TodoFacets) AddColor(c Color, n int64) {
+	if c != "" {
+		f.Color[string(c)] += n
+	} else {
+		f.Color["none"] += n
+	}
+}
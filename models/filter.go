@@ -0,0 +1,71 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SavedFilter is a named filter over todos, such as "High priority this
+// week", kept on the server so every device shows the same lists. Like
+// custom fields, filters belong to a user's personal todos or to a
+// workspace, where every member sees them.
+type SavedFilter struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID string             `json:"user_id" bson:"user_id"`
+	// WorkspaceID is set for workspace filters; UserID is then the member
+	// who saved it
+	WorkspaceID *primitive.ObjectID `json:"workspace_id,omitempty" bson:"workspace_id,omitempty"`
+	Name        string              `json:"name" bson:"name"`
+	Criteria    TodoFilter          `json:"criteria" bson:"criteria"`
+	CreatedAt   time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// TodoFilter selects todos. A todo has to meet every criterion that's set;
+// one with none set selects every todo.
+type TodoFilter struct {
+	Completed *bool `json:"completed,omitempty" bson:"completed,omitempty"`
+	Pinned    *bool `json:"pinned,omitempty" bson:"pinned,omitempty"`
+	// Priorities keeps todos with any of these priorities
+	Priorities []Priority `json:"priorities,omitempty" bson:"priorities,omitempty"`
+	// DueWithinDays keeps todos due by the end of the day this many days
+	// from today, overdue ones included: 0 keeps the todos due today or
+	// overdue, and 7 adds those due in the coming week
+	DueWithinDays *int `json:"due_within_days,omitempty" bson:"due_within_days,omitempty"`
+	// Search keeps todos matching the text, as the q parameter of listings
+	// does
+	Search string `json:"search,omitempty" bson:"search,omitempty"`
+}
+
+// Matches reports whether the todo meets the criteria other than Search,
+// which needs the search package. today is the start of the current day in
+// the user's time zone.
+func (f *TodoFilter) Matches(todo *Todo, today time.Time) bool {
+	if f.Completed != nil && todo.Completed != *f.Completed {
+		return false
+	}
+	if f.Pinned != nil && todo.Pinned != *f.Pinned {
+		return false
+	}
+	if len(f.Priorities) > 0 && !slices.Contains(f.Priorities, todo.Priority) {
+		return false
+	}
+	if f.DueWithinDays != nil && (todo.DueDate == nil || !todo.DueDate.Before(today.AddDate(0, 0, *f.DueWithinDays+1))) {
+		return false
+	}
+	return true
+}
+
+// CreateSavedFilterRequest saves a filter
+type CreateSavedFilterRequest struct {
+	Name     string     `json:"name"`
+	Criteria TodoFilter `json:"criteria"`
+}
+
+// UpdateSavedFilterRequest renames a filter or replaces its criteria
+type UpdateSavedFilterRequest struct {
+	Name     *string     `json:"name"`
+	Criteria *TodoFilter `json:"criteria"`
+}
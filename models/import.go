@@ -0,0 +1,26 @@
+package models
+
+// ImportRequest completes an import with what the service sent the user
+// back to the app with
+type ImportRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// ImportSummary says what an import from another service did
+type ImportSummary struct {
+	Source string `json:"source"`
+	// Lists is how many of the service's lists had tasks
+	Lists int `json:"lists"`
+	// Todos counts the tasks imported, and those skipped because an
+	// earlier import brought them over already
+	Todos ImportCounts `json:"todos"`
+	// Untitled counts tasks skipped because they had no title
+	Untitled int `json:"untitled"`
+	// ListField is the custom field holding the list each todo came from,
+	// or empty when there's no such field and it couldn't be added
+	ListField string `json:"list_field,omitempty"`
+	// SampleSources lists some of the tasks a dry run would import, as
+	// "<source>:<task ID>"
+	SampleSources []string `json:"sample_sources,omitempty"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import "math"
+
+// GeoJSONPoint is the GeoJSON type of a Location
+const GeoJSONPoint = "Point"
+
+// earthRadius is the mean radius of the Earth in meters
+const earthRadius = 6371008.8
+
+// Location is a place on the Earth as a GeoJSON point, the form MongoDB
+// indexes for proximity queries. Coordinates are longitude then latitude,
+// in that order, as GeoJSON has them.
+type Location struct {
+	Type        string    `json:"type" bson:"type"`
+	Coordinates []float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// NewLocation returns the point at lat, lng
+func NewLocation(lat, lng float64) *Location {
+	return &Location{Type: GeoJSONPoint, Coordinates: []float64{lng, lat}}
+}
+
+// Lat returns the point's latitude
+func (l *Location) Lat() float64 {
+	return l.Coordinates[1]
+}
+
+// Lng returns the point's longitude
+func (l *Location) Lng() float64 {
+	return l.Coordinates[0]
+}
+
+// Empty reports whether l is the empty object updates clear a location with
+func (l *Location) Empty() bool {
+	return l.Type == "" && len(l.Coordinates) == 0
+}
+
+// Valid reports whether l is a point with a longitude between -180 and 180
+// and a latitude between -90 and 90
+func (l *Location) Valid() bool {
+	if l.Type != GeoJSONPoint || len(l.Coordinates) != 2 {
+		return false
+	}
+	lat, lng := l.Lat(), l.Lng()
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// DistanceTo returns the great-circle distance between l and other in
+// meters, treating the Earth as a sphere
+func (l *Location) DistanceTo(other *Location) float64 {
+	lat1, lat2 := radians(l.Lat()), radians(other.Lat())
+	dLat, dLng := lat2-lat1, radians(other.Lng()-l.Lng())
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// BoundingBox returns the latitudes and longitudes of a box holding every
+// point within radius meters of l. A box that would cross a pole or the
+// antimeridian spans every longitude instead.
+func (l *Location) BoundingBox(radius float64) (minLat, maxLat, minLng, maxLng float64) {
+	dLat := degrees(radius / earthRadius)
+	minLat, maxLat = l.Lat()-dLat, l.Lat()+dLat
+	if minLat <= -90 || maxLat >= 90 {
+		return math.Max(minLat, -90), math.Min(maxLat, 90), -180, 180
+	}
+	dLng := degrees(math.Asin(math.Min(1, math.Sin(radius/earthRadius)/math.Cos(radians(l.Lat())))))
+	minLng, maxLng = l.Lng()-dLng, l.Lng()+dLng
+	if minLng < -180 || maxLng > 180 {
+		return minLat, maxLat, -180, 180
+	}
+	return minLat, maxLat, minLng, maxLng
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,26 @@
+package models
+
+// Kinds of notification users can pick the channels of
+const (
+	// NotifyDigest is the daily or weekly digest of todos due
+	NotifyDigest = "digest"
+	// NotifyAssigned is a todo being assigned to the user
+	NotifyAssigned = "assigned"
+	// NotifyMentioned is a comment mentioning the user
+	NotifyMentioned = "mentioned"
+	// NotifyOverdue is todos of the user becoming overdue
+	NotifyOverdue = "overdue"
+	// NotifyStale is todos of the user going untouched for a while
+	NotifyStale = "stale"
+)
+
+// NotificationKinds are the kinds of notification, in the order they're
+// listed in
+var NotificationKinds = []string{NotifyDigest, NotifyAssigned, NotifyMentioned, NotifyOverdue, NotifyStale}
+
+// UpdateNotificationPreferencesRequest changes the channels of the kinds of
+// notification sent. A kind set to null goes back to its default channels,
+// and one set to [] is turned off.
+type UpdateNotificationPreferencesRequest struct {
+	Preferences map[string][]string `json:"preferences"`
+}
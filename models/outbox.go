@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxEntry is an event waiting in the outbox to be published. Payload is
+// the event encoded as JSON, so entries don't depend on the type of its data.
+type OutboxEntry struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Payload   []byte             `bson:"payload"`
+	CreatedAt time.Time          `bson:"created_at"`
+	// DeliveredAt is set once the event has been published
+	DeliveredAt *time.Time `bson:"delivered_at,omitempty"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PublicLink lets anyone holding its token read one todo without signing in.
+// Only a hash of the token is stored, so the link can't be recovered from
+// the database.
+type PublicLink struct {
+	TokenHash string             `json:"-" bson:"_id"`
+	TodoID    primitive.ObjectID `json:"todo_id" bson:"todo_id"`
+	OwnerID   string             `json:"-" bson:"owner_id"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
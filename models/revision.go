@@ -0,0 +1,194 @@
+package models
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxRevisions is how many revisions are kept per todo; older ones are
+// dropped as new ones are recorded
+const MaxRevisions = 100
+
+// Revision records a todo as it was after one edit, along with what the
+// edit changed. UserID and WorkspaceID are the todo's, so a scope's
+// revisions can be deleted with its todos.
+type Revision struct {
+	ID          primitive.ObjectID  `json:"-" bson:"_id,omitempty"`
+	TodoID      primitive.ObjectID  `json:"todo_id" bson:"todo_id"`
+	UserID      string              `json:"-" bson:"user_id"`
+	WorkspaceID *primitive.ObjectID `json:"-" bson:"workspace_id,omitempty"`
+	// Number counts the todo's revisions from 1
+	Number int `json:"rev" bson:"rev"`
+	// EditedBy is the user who made the edit
+	EditedBy string        `json:"edited_by" bson:"edited_by"`
+	Changes  []FieldChange `json:"changes" bson:"changes"`
+	// Todo is the todo after the edit, as made by RevisionSnapshot
+	Todo      Todo      `json:"todo" bson:"todo"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// FieldChange is one field an edit changed. From and To are null when the
+// field was unset.
+type FieldChange struct {
+	Field string `json:"field" bson:"field"`
+	From  any    `json:"from" bson:"from"`
+	To    any    `json:"to" bson:"to"`
+}
+
+// DiffTodos lists the fields users edit that differ between before and
+// after. Custom fields are listed as custom_fields.<field ID>. Timers,
+// dependencies, snoozes and assignments aren't part of a todo's revisions.
+func DiffTodos(before, after *Todo) []FieldChange {
+	changes := []FieldChange{}
+	add := func(field string, from, to any) {
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+	add("title", optional(before.Title), optional(after.Title))
+	add("description", optional(before.Description), optional(after.Description))
+	add("completed", before.Completed, after.Completed)
+	add("due_date", timeValue(before.DueDate), timeValue(after.DueDate))
+	add("priority", optional(before.Priority), optional(after.Priority))
+	add("pinned", before.Pinned, after.Pinned)
+	add("archived", before.Archived(), after.Archived())
+	add("color", optional(before.Color), optional(after.Color))
+	add("estimated_minutes", optional(before.EstimatedMinutes), optional(after.EstimatedMinutes))
+	add("actual_minutes", optional(before.ActualMinutes), optional(after.ActualMinutes))
+	add("location", locationValue(before.Location), locationValue(after.Location))
+
+	keys := slices.Sorted(maps.Keys(before.CustomFields))
+	for key := range after.CustomFields {
+		if _, ok := before.CustomFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		add("custom_fields."+key, before.CustomFields[key], after.CustomFields[key])
+	}
+	return changes
+}
+
+// RevisionSnapshot copies the parts of a todo a revision keeps: the fields
+// DiffTodos compares, plus what identifies the todo
+func RevisionSnapshot(todo *Todo) Todo {
+	return Todo{
+		ID:               todo.ID,
+		UserID:           todo.UserID,
+		WorkspaceID:      todo.WorkspaceID,
+		Title:            todo.Title,
+		Description:      todo.Description,
+		Completed:        todo.Completed,
+		CompletedAt:      todo.CompletedAt,
+		Pinned:           todo.Pinned,
+		ArchivedAt:       todo.ArchivedAt,
+		DueDate:          todo.DueDate,
+		Priority:         todo.Priority,
+		Color:            todo.Color,
+		EstimatedMinutes: todo.EstimatedMinutes,
+		ActualMinutes:    todo.ActualMinutes,
+		Location:         todo.Location,
+		CustomFields:     maps.Clone(todo.CustomFields),
+		CreatedAt:        todo.CreatedAt,
+		UpdatedAt:        todo.UpdatedAt,
+	}
+}
+
+// Revert sets the fields DiffTodos compares to their values in the
+// revision, completing or reopening the todo at now if needed
+func (r *Revision) Revert(todo *Todo, now time.Time) {
+	todo.Title = r.Todo.Title
+	todo.Description = r.Todo.Description
+	todo.SetCompleted(r.Todo.Completed, now)
+	todo.DueDate = r.Todo.DueDate
+	todo.Priority = r.Todo.Priority
+	todo.Pinned = r.Todo.Pinned
+	todo.ArchivedAt = r.Todo.ArchivedAt
+	todo.Color = r.Todo.Color
+	todo.EstimatedMinutes = r.Todo.EstimatedMinutes
+	todo.ActualMinutes = r.Todo.ActualMinutes
+	todo.Location = r.Todo.Location
+	todo.CustomFields = maps.Clone(r.Todo.CustomFields)
+}
+
+// CopyFields sets the fields of todo named as DiffTodos names them to their
+// values in from
+func CopyFields(todo, from *Todo, fields []string) {
+	for _, field := range fields {
+		switch field {
+		case "title":
+			todo.Title = from.Title
+		case "description":
+			todo.Description = from.Description
+		case "completed":
+			todo.Completed, todo.CompletedAt = from.Completed, from.CompletedAt
+		case "due_date":
+			todo.DueDate = from.DueDate
+		case "priority":
+			todo.Priority = from.Priority
+		case "pinned":
+			todo.Pinned = from.Pinned
+		case "archived":
+			todo.ArchivedAt = from.ArchivedAt
+		case "color":
+			todo.Color = from.Color
+		case "estimated_minutes":
+			todo.EstimatedMinutes = from.EstimatedMinutes
+		case "actual_minutes":
+			todo.ActualMinutes = from.ActualMinutes
+		case "location":
+			todo.Location = from.Location
+		default:
+			key, ok := strings.CutPrefix(field, "custom_fields.")
+			if !ok {
+				continue
+			}
+			values := maps.Clone(todo.CustomFields)
+			if values == nil {
+				values = map[string]any{}
+			}
+			if v, ok := from.CustomFields[key]; ok {
+				values[key] = v
+			} else {
+				delete(values, key)
+			}
+			todo.CustomFields = values
+			if len(values) == 0 {
+				todo.CustomFields = nil
+			}
+		}
+	}
+}
+
+// optional returns nil for the zero value, so unset fields show as null
+func optional[T comparable](v T) any {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return v
+}
+
+// timeValue returns t as an RFC 3339 string in UTC, or nil if unset
+func timeValue(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// locationValue returns the location's coordinates, longitude first, or nil
+// if unset. Plain arrays read back from storage the way they were written,
+// where a document would come back as key-value pairs.
+func locationValue(l *Location) any {
+	if l == nil {
+		return nil
+	}
+	return l.Coordinates
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Session is one signed-in browser or device. Identity cookies name a
+// session, so revoking it invalidates the cookie before it expires.
+type Session struct {
+	ID        string    `json:"id" bson:"_id"`
+	UserID    string    `json:"-" bson:"user_id"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	LastSeen  time.Time `json:"last_seen" bson:"last_seen"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+	UserAgent string    `json:"user_agent" bson:"user_agent"`
+	IP        string    `json:"ip" bson:"ip"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"todo-api/authz"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Share grants another user access to one of the owner's personal todos.
+// The grantee is named either by email, matched against the email of
+// signed-in Entra ID users, or by user ID.
+type Share struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TodoID    primitive.ObjectID `json:"todo_id" bson:"todo_id"`
+	OwnerID   string             `json:"owner_id" bson:"owner_id"`
+	Email     string             `json:"email,omitempty" bson:"email,omitempty"`
+	UserID    string             `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Grantee   string             `json:"-" bson:"grantee"`
+	Role      authz.Role         `json:"role" bson:"role"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// GranteeUser is the grantee key of a share with a user ID
+func GranteeUser(userID string) string {
+	return "user:" + userID
+}
+
+// GranteeEmail is the grantee key of a share with an email address
+func GranteeEmail(email string) string {
+	return "email:" + email
+}
+
+type ShareTodoRequest struct {
+	Email  string     `json:"email"`
+	UserID string     `json:"user_id"`
+	Role   authz.Role `json:"role"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SlackLink connects a user to their Slack account, so their slash commands
+// act on the user's todos and notifications can mention them. A user links
+// at most one Slack account, and a Slack account one user.
+type SlackLink struct {
+	UserID      string    `json:"-" bson:"_id"`
+	TeamID      string    `json:"team_id" bson:"team_id"`
+	SlackUserID string    `json:"slack_user_id" bson:"slack_user_id"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+	// OverdueCheckedAt is when the user's todos were last checked for ones
+	// that became overdue
+	OverdueCheckedAt time.Time `json:"-" bson:"overdue_checked_at"`
+}
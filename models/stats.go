@@ -0,0 +1,24 @@
+package models
+
+// TodoStats summarises a set of todos for dashboard widgets. Todos don't
+// record when they were completed, so a completed todo's last update is
+// taken as its completion time.
+type TodoStats struct {
+	Total     int64 `json:"total"`
+	Completed int64 `json:"completed"`
+	Open      int64 `json:"open"`
+	// Overdue counts the open todos due before today in the user's time zone
+	Overdue int64 `json:"overdue"`
+	// CompletedPerDay counts the todos completed on each day of the period,
+	// oldest first
+	CompletedPerDay []DayCount `json:"completed_per_day"`
+	// AverageCompletionSeconds is the mean time from creation to completion
+	// of the todos completed in the period, or nil if there are none
+	AverageCompletionSeconds *float64 `json:"average_completion_seconds"`
+}
+
+// DayCount is a count for one day, formatted YYYY-MM-DD
+type DayCount struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"todo-api/apierrors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxSyncChanges caps the changes one sync push may carry
+const MaxSyncChanges = 100
+
+// Operations a sync push can carry
+const (
+	SyncCreate = "create"
+	SyncUpdate = "update"
+	SyncDelete = "delete"
+)
+
+// How a push resolves a change to a todo that was also edited on the server
+// since the client last synced it
+const (
+	// ConflictLastWriteWins applies the change, overwriting the server's
+	// edits to the same fields
+	ConflictLastWriteWins = "last_write_wins"
+	// ConflictMerge applies the fields of the change the server didn't edit
+	// and keeps the server's values of the others
+	ConflictMerge = "merge"
+	// ConflictManual applies nothing and returns both versions for the
+	// client to resolve
+	ConflictManual = "manual"
+)
+
+// SyncPushRequest carries the changes an offline client made, in the order
+// it made them. OnConflict picks how conflicting changes are resolved and
+// defaults to ConflictMerge.
+type SyncPushRequest struct {
+	Changes    []SyncChange `json:"changes"`
+	OnConflict string       `json:"on_conflict"`
+}
+
+// SyncChange is one change to a todo. Clients pick the ID of a todo they
+// create, so a push that's retried doesn't create it twice. Todo holds the
+// fields to set, as in an update; a create without a title is invalid.
+// Base is the updated_at of the todo the client changed; without it a
+// change is never taken to conflict.
+type SyncChange struct {
+	Op   string             `json:"op"`
+	ID   string             `json:"id"`
+	Base *time.Time         `json:"base"`
+	Todo *UpdateTodoRequest `json:"todo"`
+}
+
+// Validate returns every change that can't be applied at all, and defaults
+// OnConflict. Changes are only checked for their shape; their todo fields
+// are checked as each one is applied, by ValidateTodo.
+func (r *SyncPushRequest) Validate() []apierrors.FieldError {
+	var errs []apierrors.FieldError
+	switch r.OnConflict {
+	case "":
+		r.OnConflict = ConflictMerge
+	case ConflictLastWriteWins, ConflictMerge, ConflictManual:
+	default:
+		errs = append(errs, apierrors.FieldError{Field: "on_conflict", Message: fmt.Sprintf("must be %s, %s or %s", ConflictLastWriteWins, ConflictMerge, ConflictManual)})
+	}
+	switch {
+	case len(r.Changes) == 0:
+		return append(errs, apierrors.FieldError{Field: "changes", Message: "must not be empty"})
+	case len(r.Changes) > MaxSyncChanges:
+		return append(errs, apierrors.FieldError{Field: "changes", Message: fmt.Sprintf("must have at most %d entries", MaxSyncChanges)})
+	}
+	for i, change := range r.Changes {
+		field := fmt.Sprintf("changes[%d]", i)
+		switch change.Op {
+		case SyncCreate, SyncUpdate:
+			if change.Todo == nil {
+				errs = append(errs, apierrors.FieldError{Field: field + ".todo", Message: "is required"})
+			}
+		case SyncDelete:
+		default:
+			errs = append(errs, apierrors.FieldError{Field: field + ".op", Message: fmt.Sprintf("must be %s, %s or %s", SyncCreate, SyncUpdate, SyncDelete)})
+		}
+		if _, err := primitive.ObjectIDFromHex(change.ID); err != nil {
+			errs = append(errs, apierrors.FieldError{Field: field + ".id", Message: "must be a todo ID"})
+		}
+	}
+	return errs
+}
+
+// ValidateTodo normalizes the change's todo fields and returns every one
+// that breaks the rules. Due dates in words aren't resolved when syncing,
+// since the client knows best when it was offline.
+func (c *SyncChange) ValidateTodo() []apierrors.FieldError {
+	if c.Op == SyncCreate && c.Todo.Title == nil {
+		title := ""
+		c.Todo.Title = &title
+	}
+	c.Todo.Normalize()
+	errs := c.Todo.Validate()
+	if c.Todo.Due != nil {
+		errs = append(errs, apierrors.FieldError{Field: "due", Message: "isn't supported when syncing; send due_date"})
+	}
+	return errs
+}
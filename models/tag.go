@@ -0,0 +1,71 @@
+package models
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// tagPattern is what a tag looks like once normalized: letters, digits and
+// a few separators, starting with a letter or digit
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}_.:-]*$`)
+
+// TagCount is a tag in use and how many todos have it
+type TagCount struct {
+	Name  string `json:"name"`
+	Todos int    `json:"todos"`
+}
+
+// RenameTagRequest renames a tag on every todo that has it
+type RenameTagRequest struct {
+	Name string `json:"name"`
+}
+
+// MergeTagRequest merges a tag into another one
+type MergeTagRequest struct {
+	Into string `json:"into"`
+}
+
+// NormalizeTag trims a tag, drops a leading "#" and lowercases it, so a tag
+// matches however it's typed
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// NormalizeTags normalizes each tag, dropping empty and repeated ones and
+// keeping the order they came in
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = NormalizeTag(tag); tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// HasTag reports whether the todo is tagged with tag
+func (t *Todo) HasTag(tag string) bool {
+	return slices.Contains(t.Tags, tag)
+}
+
+// ReplaceTag puts to in the place of from among the todo's tags, or removes
+// from when to is empty or already there, and reports whether the tags
+// changed. The todo's tags are copied before they're changed, since they
+// may share their array with a stored todo.
+func (t *Todo) ReplaceTag(from, to string) bool {
+	i := slices.Index(t.Tags, from)
+	if i < 0 || from == to {
+		return false
+	}
+	t.Tags = slices.Clone(t.Tags)
+	if to == "" || t.HasTag(to) {
+		t.Tags = slices.Delete(t.Tags, i, i+1)
+	} else {
+		t.Tags[i] = to
+	}
+	if len(t.Tags) == 0 {
+		t.Tags = nil
+	}
+	return true
+}
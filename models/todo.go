@@ -1,28 +1,295 @@
 package models
 
 import (
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 
+	"todo-api/search"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Todo struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	UserID      string             `json:"user_id" bson:"user_id"`
-	Title       string             `json:"title" bson:"title"`
-	Description string             `json:"description" bson:"description"`
-	Completed   bool               `json:"completed" bson:"completed"`
-	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID string             `json:"user_id" bson:"user_id"`
+	// WorkspaceID is set for todos shared in a workspace; UserID is then the
+	// member who created it
+	WorkspaceID *primitive.ObjectID `json:"workspace_id,omitempty" bson:"workspace_id,omitempty"`
+	// AssigneeID is the member a workspace todo is assigned to, if anyone
+	AssigneeID  string `json:"assignee_id,omitempty" bson:"assignee_id,omitempty"`
+	Title       string `json:"title" bson:"title"`
+	Description string `json:"description" bson:"description"`
+	// DescriptionHTML is the Markdown description rendered to sanitized
+	// HTML. It isn't stored; handlers fill it in when asked to.
+	DescriptionHTML string `json:"description_html,omitempty" bson:"-"`
+	Completed       bool   `json:"completed" bson:"completed"`
+	// CompletedAt is when the todo was last completed. Todos completed
+	// before it was recorded are given their last update instead.
+	CompletedAt *time.Time `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	// Pinned todos are listed before the others
+	Pinned   bool       `json:"pinned" bson:"pinned"`
+	DueDate  *time.Time `json:"due_date,omitempty" bson:"due_date,omitempty"`
+	Priority Priority   `json:"priority,omitempty" bson:"priority,omitempty"`
+	Color    Color      `json:"color,omitempty" bson:"color,omitempty"`
+	// EstimatedMinutes is how long the todo is expected to take and
+	// ActualMinutes how long it took, both as entered by users
+	EstimatedMinutes int `json:"estimated_minutes,omitempty" bson:"estimated_minutes,omitempty"`
+	ActualMinutes    int `json:"actual_minutes,omitempty" bson:"actual_minutes,omitempty"`
+	// Location is where the todo is done, such as the shop to buy milk at
+	Location *Location `json:"location,omitempty" bson:"location,omitempty"`
+	// Distance is how far Location is from the point of a nearby query, in
+	// meters. It isn't stored; the nearby view fills it in.
+	Distance *float64 `json:"distance,omitempty" bson:"-"`
+	// Highlights mark where the todo matched the free text of a search. They
+	// aren't stored; listings fill them in when asked to.
+	Highlights []search.Highlight `json:"highlights,omitempty" bson:"-"`
+	// Attachments are the links attached to the todo, oldest first
+	Attachments []Attachment `json:"attachments,omitempty" bson:"attachments,omitempty"`
+	// SnoozedUntil hides the todo from listings and due views until then
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty" bson:"snoozed_until,omitempty"`
+	// ArchivedAt is when the todo was archived, which hides it from the
+	// todo list
+	ArchivedAt *time.Time `json:"archived_at,omitempty" bson:"archived_at,omitempty"`
+	// BlockedBy lists the todos that have to be done before this one
+	BlockedBy []primitive.ObjectID `json:"blocked_by,omitempty" bson:"blocked_by,omitempty"`
+	// Blocked reports whether any of BlockedBy is still open. It isn't
+	// stored; listings fill it in.
+	Blocked bool `json:"blocked,omitempty" bson:"-"`
+	// Tags label the todo, normalized by NormalizeTag
+	Tags []string `json:"tags,omitempty" bson:"tags,omitempty"`
+	// Checklist is the todo's subtasks, in order. ChecklistRule decides
+	// whether completing them completes the todo, or has to come first.
+	Checklist     []ChecklistItem `json:"checklist,omitempty" bson:"checklist,omitempty"`
+	ChecklistRule ChecklistRule   `json:"checklist_rule,omitempty" bson:"checklist_rule,omitempty"`
+	// TrackedSeconds is the time recorded by the todo's timer, not counting
+	// the session running now
+	TrackedSeconds int64 `json:"tracked_seconds,omitempty" bson:"tracked_seconds,omitempty"`
+	// TimerStartedAt and TimerStartedBy are set while the timer runs
+	TimerStartedAt *time.Time `json:"timer_started_at,omitempty" bson:"timer_started_at,omitempty"`
+	TimerStartedBy string     `json:"timer_started_by,omitempty" bson:"timer_started_by,omitempty"`
+	// TimeEntries are the recorded work sessions, oldest first. Only the
+	// time reports read them.
+	TimeEntries []TimeEntry `json:"-" bson:"time_entries,omitempty"`
+	// ICalUID and ICalName are the UID and resource name a CalDAV client
+	// gave the todo when it created it. Other todos go by their ID.
+	ICalUID  string `json:"-" bson:"ical_uid,omitempty"`
+	ICalName string `json:"-" bson:"ical_name,omitempty"`
+	// ImportSource names the task the todo was imported from, as
+	// "google:<task ID>", so importing again doesn't add it twice
+	ImportSource string `json:"import_source,omitempty" bson:"import_source,omitempty"`
+	// Comments are the notes left on the todo, oldest first. They're listed
+	// on their own rather than with the todo.
+	Comments []Comment `json:"-" bson:"comments,omitempty"`
+	// CustomFields holds the values of custom fields, keyed by field ID
+	CustomFields map[string]any `json:"custom_fields,omitempty" bson:"custom_fields,omitempty"`
+	CreatedAt    time.Time      `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at" bson:"updated_at"`
+	// SchemaVersion is the version of the todo schema the todo was written
+	// with; older todos are upgraded as they're read
+	SchemaVersion int `json:"-" bson:"schema_version,omitempty"`
+	// Links are the actions the user can take on the todo, keyed by name.
+	// They aren't stored; handlers fill them in.
+	Links map[string]Link `json:"_links,omitempty" bson:"-"`
+	// Embedded holds the related resources asked for with ?include=, keyed
+	// by name. It isn't stored; handlers fill it in.
+	Embedded map[string]any `json:"_embedded,omitempty" bson:"-"`
+}
+
+// Link points clients at a request they can make
+type Link struct {
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"`
+}
+
+// Priority ranks how urgent a todo is. Todos without one sort below low.
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+)
+
+// Rank orders priorities from 0 (none) to 3 (high)
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityLow:
+		return 1
+	case PriorityMedium:
+		return 2
+	case PriorityHigh:
+		return 3
+	}
+	return 0
+}
+
+// Valid reports whether p is a known priority
+func (p Priority) Valid() bool {
+	return p.Rank() > 0
 }
 
 type CreateTodoRequest struct {
-	Title       string `json:"title" binding:"required"`
+	Title       string `json:"title"`
 	Description string `json:"description"`
+	// DueDate is an RFC 3339 timestamp, or a YYYY-MM-DD date meaning the
+	// start of that day in the user's time zone
+	DueDate string `json:"due_date"`
+	// Due is a due date in words, such as "tomorrow 5pm" or "next friday",
+	// for the server to resolve. It can't be combined with DueDate.
+	Due string `json:"due"`
+	// DetectDue moves a due date phrase at the end of the title, as in "Pay
+	// rent by friday", into the due date
+	DetectDue    bool           `json:"detect_due"`
+	Priority     Priority       `json:"priority"`
+	Pinned       bool           `json:"pinned"`
+	Color        Color          `json:"color"`
+	CustomFields map[string]any `json:"custom_fields"`
+	// EstimatedMinutes and ActualMinutes are left unset by 0
+	EstimatedMinutes int           `json:"estimated_minutes"`
+	ActualMinutes    int           `json:"actual_minutes"`
+	Location         *Location     `json:"location"`
+	ChecklistRule    ChecklistRule `json:"checklist_rule"`
+	Tags             []string      `json:"tags"`
+	// Checklist and Attachments are saved with the todo, in the same write,
+	// so a todo is never left with only some of them
+	Checklist   []AddChecklistItemRequest `json:"checklist"`
+	Attachments []AddAttachmentRequest    `json:"attachments"`
 }
 
+// UpdateTodoRequest changes the fields that are sent. An empty due_date,
+// priority, color or checklist_rule, 0 minutes or an empty location object
+// ({}) clears it. Custom field values are merged into the
+// todo's, with null or "" clearing one.
 type UpdateTodoRequest struct {
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
-	Completed   *bool   `json:"completed"`
+	Title        *string        `json:"title"`
+	Description  *string        `json:"description"`
+	Completed    *bool          `json:"completed"`
+	DueDate      *string        `json:"due_date"`
+	Due          *string        `json:"due"`
+	Priority     *Priority      `json:"priority"`
+	Pinned       *bool          `json:"pinned"`
+	Color        *Color         `json:"color"`
+	CustomFields map[string]any `json:"custom_fields"`
+	// EstimatedMinutes and ActualMinutes are cleared by 0
+	EstimatedMinutes *int           `json:"estimated_minutes"`
+	ActualMinutes    *int           `json:"actual_minutes"`
+	Location         *Location      `json:"location"`
+	ChecklistRule    *ChecklistRule `json:"checklist_rule"`
+	// Tags replaces the todo's tags; [] removes them all
+	Tags     *[]string `json:"tags"`
+	Archived *bool     `json:"archived"`
+}
+
+// TimeEntry is one work session on a todo
+type TimeEntry struct {
+	UserID string    `json:"user_id" bson:"user_id"`
+	Start  time.Time `json:"start" bson:"start"`
+	End    time.Time `json:"end" bson:"end"`
+}
+
+// AddBlockerRequest makes a todo wait for another one
+type AddBlockerRequest struct {
+	TodoID string `json:"todo_id"`
+}
+
+// LookupTodosRequest asks for several todos by ID at once
+type LookupTodosRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// SetCompleted completes or reopens the todo, recording when it was
+// completed. Completing a todo that's already done keeps its CompletedAt;
+// reopening one takes it out of the archive.
+func (t *Todo) SetCompleted(completed bool, now time.Time) {
+	switch {
+	case !completed:
+		t.CompletedAt = nil
+		t.ArchivedAt = nil
+	case !t.Completed || t.CompletedAt == nil:
+		t.CompletedAt = &now
+	}
+	t.Completed = completed
+}
+
+// Archived reports whether the todo is archived
+func (t *Todo) Archived() bool {
+	return t.ArchivedAt != nil
+}
+
+// SetArchived archives the todo or takes it out of the archive. Archiving
+// one that's archived already keeps its ArchivedAt.
+func (t *Todo) SetArchived(archived bool, now time.Time) {
+	switch {
+	case !archived:
+		t.ArchivedAt = nil
+	case t.ArchivedAt == nil:
+		t.ArchivedAt = &now
+	}
+}
+
+// Snoozed reports whether the todo is snoozed at now
+func (t *Todo) Snoozed(now time.Time) bool {
+	return t.SnoozedUntil != nil && t.SnoozedUntil.After(now)
+}
+
+// SnoozeRequest snoozes a todo for a while, or until a given time. Exactly
+// one of the two is required.
+type SnoozeRequest struct {
+	// Duration is a Go duration such as "90m" or "48h", or a number of days
+	// such as "3d"
+	Duration string `json:"duration"`
+	// Until takes the same forms as a due date
+	Until string `json:"until"`
+}
+
+// ParseSnoozeDuration parses SnoozeRequest.Duration
+func ParseSnoozeDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// ErrDueDateOutOfRange is returned for due dates outside the years 0000 to
+// 9999 in UTC, which can't be stored and sent back as RFC 3339
+var ErrDueDateOutOfRange = errors.New("due date out of range")
+
+// ParseDueDate parses a due date in either of the forms CreateTodoRequest
+// accepts, reading plain dates in loc
+func ParseDueDate(s string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation(time.DateOnly, s, loc)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339, s); err != nil {
+			return time.Time{}, err
+		}
+	}
+	if year := t.UTC().Year(); year < 0 || year > 9999 {
+		return time.Time{}, ErrDueDateOutOfRange
+	}
+	return t, nil
+}
+
+// Zones at the ends of the range of UTC offsets, which move plain dates the
+// furthest in UTC
+var (
+	earliestZone = time.FixedZone("UTC+14", 14*60*60)
+	latestZone   = time.FixedZone("UTC-12", -12*60*60)
+)
+
+// checkDueDate reports whether s parses as a due date whatever zone plain
+// dates are read in, returning ErrDueDateOutOfRange for one that would be
+// out of range in some
+func checkDueDate(s string) error {
+	for _, loc := range []*time.Location{time.UTC, earliestZone, latestZone} {
+		if _, err := ParseDueDate(s, loc); err != nil {
+			return err
+		}
+	}
+	return nil
 }
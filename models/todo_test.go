@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDueDateRange(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr error
+	}{
+		{"2026-10-16", nil},
+		{"2026-10-16T09:30:00Z", nil},
+		{"9999-12-31T23:59:59Z", nil},
+		{"0000-01-01T00:00:00Z", nil},
+		{"9999-12-31T23:59:59-05:00", ErrDueDateOutOfRange},
+		{"0000-01-01T00:00:00+01:00", ErrDueDateOutOfRange},
+	}
+	for _, tt := range tests {
+		if _, err := ParseDueDate(tt.input, time.UTC); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseDueDate(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateDueDate(t *testing.T) {
+	tests := []struct {
+		dueDate string
+		valid   bool
+	}{
+		{"", true},
+		{"2026-10-16", true},
+		{"9999-12-31", true},
+		{"tomorrow", false},
+		{"9999-12-31T23:59:59-05:00", false},
+		// Read in a zone ahead of UTC, this falls in the year -1 in UTC
+		{"0000-01-01", false},
+	}
+	for _, tt := range tests {
+		errs := validateDueDate(nil, tt.dueDate)
+		if valid := len(errs) == 0; valid != tt.valid {
+			t.Errorf("validateDueDate(%q) = %v, want valid %v", tt.dueDate, errs, tt.valid)
+		}
+	}
+}
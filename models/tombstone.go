@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tombstone records that a todo was deleted, so clients syncing changes
+// learn to remove it. UserID and WorkspaceID are the todo's.
+type Tombstone struct {
+	TodoID      primitive.ObjectID  `json:"id" bson:"_id"`
+	UserID      string              `json:"-" bson:"user_id"`
+	WorkspaceID *primitive.ObjectID `json:"-" bson:"workspace_id,omitempty"`
+	DeletedAt   time.Time           `json:"deleted_at" bson:"deleted_at"`
+}
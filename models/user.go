@@ -0,0 +1,143 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// User records when an anonymous identity was first and last seen, so data
+// belonging to identities that are never coming back can be purged
+type User struct {
+	ID        string       `json:"id" bson:"_id"`
+	CreatedAt time.Time    `json:"created_at" bson:"created_at"`
+	LastSeen  time.Time    `json:"last_seen" bson:"last_seen"`
+	Settings  UserSettings `json:"settings" bson:"settings,omitempty"`
+}
+
+// UserSettings are preferences users set for themselves
+type UserSettings struct {
+	// Timezone is an IANA name such as "Europe/London"; empty means UTC
+	Timezone string `json:"timezone" bson:"timezone,omitempty"`
+	// EnforceBlockers refuses to complete a todo while a todo it's
+	// blocked by is still open
+	EnforceBlockers bool `json:"enforce_blockers" bson:"enforce_blockers,omitempty"`
+	// Digest is how often the user is emailed a digest of overdue todos and
+	// those due soon: DigestDaily, DigestWeekly, or empty for never
+	Digest string `json:"digest" bson:"digest,omitempty"`
+	// Email is where digests are sent
+	Email string `json:"email" bson:"email,omitempty"`
+	// RetentionOptOut keeps the user's data from the retention rules, such
+	// as the purge of old completed todos
+	RetentionOptOut bool `json:"retention_opt_out" bson:"retention_opt_out,omitempty"`
+	// DailyReset is the local time, as "15:04", at which open todos due in
+	// the day before roll over to the next day and completed todos are
+	// archived; empty turns it off
+	DailyReset string `json:"daily_reset" bson:"daily_reset,omitempty"`
+	// Notifications lists, by kind of notification, the channels the user
+	// wants it on. Kinds left out go to their default channels; an empty
+	// list turns a kind off.
+	Notifications map[string][]string `json:"notifications,omitempty" bson:"notifications,omitempty"`
+	// Preferences fill in what the user leaves out of the todos they
+	// create; nil until they're first set
+	Preferences *Preferences `json:"preferences,omitempty" bson:"preferences,omitempty"`
+}
+
+// Preferences are defaults applied server-side to the todos a user
+// creates, so every client they use creates todos alike
+type Preferences struct {
+	// DefaultPriority is given to new todos sent without a priority
+	DefaultPriority Priority `json:"default_priority" bson:"default_priority,omitempty"`
+	// DefaultTags are given to new todos sent without tags. Todos have no
+	// projects; tags are what splits lists up.
+	DefaultTags []string `json:"default_tags" bson:"default_tags,omitempty"`
+	// StartOfWeek is the lowercase name of the day weeks start on, which
+	// "next week" in due dates in words means; empty means Monday
+	StartOfWeek string `json:"start_of_week" bson:"start_of_week,omitempty"`
+}
+
+// DefaultStartOfWeek is the day weeks start on unless users choose another
+const DefaultStartOfWeek = "monday"
+
+// CurrentPreferences returns the user's preferences with the defaults
+// filled in. The tags may share their array with stored settings.
+func (s UserSettings) CurrentPreferences() Preferences {
+	var p Preferences
+	if s.Preferences != nil {
+		p = *s.Preferences
+	}
+	if p.DefaultTags == nil {
+		p.DefaultTags = []string{}
+	}
+	if p.StartOfWeek == "" {
+		p.StartOfWeek = DefaultStartOfWeek
+	}
+	return p
+}
+
+// WeekStart returns the day StartOfWeek names, Monday if it names none
+func (p Preferences) WeekStart() time.Weekday {
+	if day, ok := weekday(p.StartOfWeek); ok {
+		return day
+	}
+	return time.Monday
+}
+
+// weekday returns the day of the week with the given English name, in any
+// case
+func weekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// UpdatePreferencesRequest changes the preferences that are sent. An empty
+// default_priority or start_of_week resets it, and [] default_tags
+// removes them.
+type UpdatePreferencesRequest struct {
+	DefaultPriority *Priority `json:"default_priority"`
+	DefaultTags     *[]string `json:"default_tags"`
+	StartOfWeek     *string   `json:"start_of_week"`
+}
+
+// DailyResetLayout is the time layout of UserSettings.DailyReset
+const DailyResetLayout = "15:04"
+
+// How often digests can be sent
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// IsZero reports whether nothing is set
+func (s UserSettings) IsZero() bool {
+	return reflect.ValueOf(s).IsZero()
+}
+
+// Location returns the user's time zone. Zones are validated when saved, so
+// UTC is only a fallback for zones the server no longer knows.
+func (s UserSettings) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// UpdateSettingsRequest changes the settings that are sent. An empty
+// timezone resets it to UTC, and an empty digest or daily reset turns it
+// off.
+type UpdateSettingsRequest struct {
+	Timezone        *string `json:"timezone"`
+	EnforceBlockers *bool   `json:"enforce_blockers"`
+	Digest          *string `json:"digest"`
+	Email           *string `json:"email"`
+	RetentionOptOut *bool   `json:"retention_opt_out"`
+	DailyReset      *string `json:"daily_reset"`
+}
@@ -0,0 +1,801 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"todo-api/apierrors"
+	"todo-api/authz"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Field limits, counted in characters rather than bytes
+const (
+	MaxTitleLength         = 200
+	MaxDescriptionLength   = 5000
+	MaxWorkspaceNameLength = 100
+	MaxUserIDLength        = 128
+	MaxDueLength           = 100
+	MaxSearchLength        = 200
+	MaxFilterNameLength    = 100
+	MaxCommentLength       = 2000
+	MaxTagLength           = 50
+)
+
+// MaxSavedFilters is how many filters a user or workspace can save
+const MaxSavedFilters = 100
+
+// MaxDueWithinDays caps how far ahead a saved filter can look for due todos
+const MaxDueWithinDays = 366
+
+// MaxMinutes caps estimated_minutes and actual_minutes at a month
+const MaxMinutes = 31 * 24 * 60
+
+// MaxBlockers is how many todos a todo can wait for
+const MaxBlockers = 50
+
+// MaxLookupIDs is how many todos can be fetched by ID in one request
+const MaxLookupIDs = 100
+
+// MaxTimeEntries is how many work sessions a todo keeps; older ones are
+// dropped from time reports but still count towards its tracked time
+const MaxTimeEntries = 1000
+
+// MaxAttachments is how many links a todo can have attached at most;
+// QUOTA_MAX_ATTACHMENTS can lower it
+const MaxAttachments = 20
+
+// MaxAttachmentURLLength caps the length of an attached link, in bytes
+const MaxAttachmentURLLength = 2048
+
+// MaxTags is how many tags a todo can have at most; QUOTA_MAX_TAGS can
+// lower it
+const MaxTags = 20
+
+// MaxChecklistItems is how many items a todo's checklist can have
+const MaxChecklistItems = 100
+
+// MaxComments is how many comments a todo keeps
+const MaxComments = 500
+
+// MaxMentions is how many users a comment can mention
+const MaxMentions = 20
+
+// MaxSnooze is the longest a todo can be snoozed for
+const MaxSnooze = 365 * 24 * time.Hour
+
+// Normalize trims surrounding whitespace from the text fields and
+// normalizes the tags
+func (r *CreateTodoRequest) Normalize() {
+	r.Title = strings.TrimSpace(r.Title)
+	r.Description = strings.TrimSpace(r.Description)
+	r.DueDate = strings.TrimSpace(r.DueDate)
+	r.Due = strings.TrimSpace(r.Due)
+	r.Color = NormalizeColor(r.Color)
+	r.Tags = NormalizeTags(r.Tags)
+	for i := range r.Checklist {
+		r.Checklist[i].Normalize()
+	}
+	for i := range r.Attachments {
+		r.Attachments[i].Normalize()
+	}
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *CreateTodoRequest) Validate() []apierrors.FieldError {
+	var errs []apierrors.FieldError
+	errs = validateTitle(errs, r.Title)
+	errs = validateDescription(errs, r.Description)
+	errs = validateDueDate(errs, r.DueDate)
+	errs = validateDue(errs, r.Due, r.DueDate != "")
+	errs = validatePriority(errs, r.Priority)
+	errs = validateColor(errs, r.Color)
+	errs = validateMinutes(errs, "estimated_minutes", r.EstimatedMinutes)
+	errs = validateMinutes(errs, "actual_minutes", r.ActualMinutes)
+	if r.Location != nil {
+		errs = validateLocation(errs, r.Location)
+	}
+	errs = validateChecklistRule(errs, r.ChecklistRule)
+	errs = validateTags(errs, r.Tags)
+	if len(r.Checklist) > MaxChecklistItems {
+		errs = append(errs, apierrors.FieldError{Field: "checklist", Message: fmt.Sprintf("must have at most %d entries", MaxChecklistItems)})
+	}
+	for i := range r.Checklist {
+		errs = nested(errs, fmt.Sprintf("checklist[%d]", i), r.Checklist[i].Validate())
+	}
+	if len(r.Attachments) > MaxAttachments {
+		errs = append(errs, apierrors.FieldError{Field: "attachments", Message: fmt.Sprintf("must have at most %d entries", MaxAttachments)})
+	}
+	for i := range r.Attachments {
+		errs = nested(errs, fmt.Sprintf("attachments[%d]", i), r.Attachments[i].Validate())
+	}
+	return errs
+}
+
+// nested appends the errors of a part of a request, such as an item of a
+// list, with their fields named within it
+func nested(errs []apierrors.FieldError, field string, partErrs []apierrors.FieldError) []apierrors.FieldError {
+	for _, e := range partErrs {
+		errs = append(errs, apierrors.FieldError{Field: field + "." + e.Field, Message: e.Message})
+	}
+	return errs
+}
+
+// Normalize trims surrounding whitespace from the text fields that were
+// sent and normalizes the tags
+func (r *UpdateTodoRequest) Normalize() {
+	if r.Title != nil {
+		title := strings.TrimSpace(*r.Title)
+		r.Title = &title
+	}
+	if r.Description != nil {
+		description := strings.TrimSpace(*r.Description)
+		r.Description = &description
+	}
+	if r.DueDate != nil {
+		dueDate := strings.TrimSpace(*r.DueDate)
+		r.DueDate = &dueDate
+	}
+	if r.Due != nil {
+		due := strings.TrimSpace(*r.Due)
+		r.Due = &due
+	}
+	if r.Color != nil {
+		color := NormalizeColor(*r.Color)
+		r.Color = &color
+	}
+	if r.Tags != nil {
+		tags := NormalizeTags(*r.Tags)
+		r.Tags = &tags
+	}
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *UpdateTodoRequest) Validate() []apierrors.FieldError {
+	var errs []apierrors.FieldError
+	if r.Title != nil {
+		errs = validateTitle(errs, *r.Title)
+	}
+	if r.Description != nil {
+		errs = validateDescription(errs, *r.Description)
+	}
+	if r.DueDate != nil {
+		errs = validateDueDate(errs, *r.DueDate)
+	}
+	if r.Due != nil {
+		errs = validateDue(errs, *r.Due, r.DueDate != nil)
+	}
+	if r.Priority != nil {
+		errs = validatePriority(errs, *r.Priority)
+	}
+	if r.Color != nil {
+		errs = validateColor(errs, *r.Color)
+	}
+	if r.EstimatedMinutes != nil {
+		errs = validateMinutes(errs, "estimated_minutes", *r.EstimatedMinutes)
+	}
+	if r.ActualMinutes != nil {
+		errs = validateMinutes(errs, "actual_minutes", *r.ActualMinutes)
+	}
+	if r.Location != nil && !r.Location.Empty() {
+		errs = validateLocation(errs, r.Location)
+	}
+	if r.ChecklistRule != nil {
+		errs = validateChecklistRule(errs, *r.ChecklistRule)
+	}
+	if r.Tags != nil {
+		errs = validateTags(errs, *r.Tags)
+	}
+	return errs
+}
+
+func validateTitle(errs []apierrors.FieldError, title string) []apierrors.FieldError {
+	switch {
+	case title == "":
+		return append(errs, apierrors.FieldError{Field: "title", Message: "must not be empty or only whitespace"})
+	case utf8.RuneCountInString(title) > MaxTitleLength:
+		return append(errs, apierrors.FieldError{Field: "title", Message: fmt.Sprintf("must be at most %d characters", MaxTitleLength)})
+	}
+	return errs
+}
+
+func validateDescription(errs []apierrors.FieldError, description string) []apierrors.FieldError {
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		return append(errs, apierrors.FieldError{Field: "description", Message: fmt.Sprintf("must be at most %d characters", MaxDescriptionLength)})
+	}
+	return errs
+}
+
+// validateDueDate accepts an empty (unset) due date or one ParseDueDate
+// understands
+func validateDueDate(errs []apierrors.FieldError, dueDate string) []apierrors.FieldError {
+	if dueDate == "" {
+		return errs
+	}
+	if err := checkDueDate(dueDate); errors.Is(err, ErrDueDateOutOfRange) {
+		return append(errs, apierrors.FieldError{Field: "due_date", Message: "must be between the years 0000 and 9999 in UTC"})
+	} else if err != nil {
+		return append(errs, apierrors.FieldError{Field: "due_date", Message: "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"})
+	}
+	return errs
+}
+
+// validateDue checks the shape of a due date in words; whether it can be
+// understood is only known once it's resolved against the current time
+func validateDue(errs []apierrors.FieldError, due string, hasDueDate bool) []apierrors.FieldError {
+	switch {
+	case due == "":
+	case hasDueDate:
+		return append(errs, apierrors.FieldError{Field: "due", Message: "must not be sent together with due_date"})
+	case utf8.RuneCountInString(due) > MaxDueLength:
+		return append(errs, apierrors.FieldError{Field: "due", Message: fmt.Sprintf("must be at most %d characters", MaxDueLength)})
+	}
+	return errs
+}
+
+func validatePriority(errs []apierrors.FieldError, priority Priority) []apierrors.FieldError {
+	if priority != "" && !priority.Valid() {
+		return append(errs, apierrors.FieldError{Field: "priority", Message: fmt.Sprintf("must be %q, %q or %q", PriorityLow, PriorityMedium, PriorityHigh)})
+	}
+	return errs
+}
+
+func validateChecklistRule(errs []apierrors.FieldError, rule ChecklistRule) []apierrors.FieldError {
+	if !rule.Valid() {
+		return append(errs, apierrors.FieldError{Field: "checklist_rule", Message: fmt.Sprintf("must be %q, %q or empty", ChecklistRuleAutoComplete, ChecklistRuleBlock)})
+	}
+	return errs
+}
+
+func validateTags(errs []apierrors.FieldError, tags []string) []apierrors.FieldError {
+	if len(tags) > MaxTags {
+		return append(errs, apierrors.FieldError{Field: "tags", Message: fmt.Sprintf("must have at most %d tags", MaxTags)})
+	}
+	for _, tag := range tags {
+		if message := tagProblem(tag); message != "" {
+			return append(errs, apierrors.FieldError{Field: "tags", Message: fmt.Sprintf("%q %s", tag, message)})
+		}
+	}
+	return errs
+}
+
+// tagProblem says what's wrong with a normalized tag, or returns ""
+func tagProblem(tag string) string {
+	switch {
+	case utf8.RuneCountInString(tag) > MaxTagLength:
+		return fmt.Sprintf("must be at most %d characters", MaxTagLength)
+	case !tagPattern.MatchString(tag):
+		return "must be letters, digits, '_', '.', ':' or '-', starting with a letter or digit"
+	}
+	return ""
+}
+
+// ValidateTag checks a normalized tag sent as field, such as one named in
+// a path
+func ValidateTag(field, tag string) []apierrors.FieldError {
+	if message := tagProblem(tag); message != "" {
+		return []apierrors.FieldError{{Field: field, Message: message}}
+	}
+	return nil
+}
+
+// validateMinutes accepts 0 (unset) up to MaxMinutes
+func validateMinutes(errs []apierrors.FieldError, field string, minutes int) []apierrors.FieldError {
+	if minutes < 0 || minutes > MaxMinutes {
+		return append(errs, apierrors.FieldError{Field: field, Message: fmt.Sprintf("must be between 0 and %d minutes", MaxMinutes)})
+	}
+	return errs
+}
+
+func validateLocation(errs []apierrors.FieldError, location *Location) []apierrors.FieldError {
+	if !location.Valid() {
+		return append(errs, apierrors.FieldError{Field: "location", Message: `must be a GeoJSON point such as {"type": "Point", "coordinates": [-0.1276, 51.5072]}, longitude first`})
+	}
+	return errs
+}
+
+// validateColor accepts an empty (unset) color or a valid one
+func validateColor(errs []apierrors.FieldError, color Color) []apierrors.FieldError {
+	if color != "" && !color.Valid() {
+		return append(errs, apierrors.FieldError{Field: "color", Message: `must be a hex color such as "#1e90ff" or one of ` + strings.Join(Palette, ", ")})
+	}
+	return errs
+}
+
+// Normalize trims surrounding whitespace from the fields
+func (r *SnoozeRequest) Normalize() {
+	r.Duration = strings.TrimSpace(r.Duration)
+	r.Until = strings.TrimSpace(r.Until)
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+// Whether until is in the future is only known once it's read in the user's
+// time zone.
+func (r *SnoozeRequest) Validate() []apierrors.FieldError {
+	switch {
+	case r.Duration == "" && r.Until == "":
+		return []apierrors.FieldError{{Field: "duration", Message: "exactly one of duration or until is required"}}
+	case r.Duration != "" && r.Until != "":
+		return []apierrors.FieldError{{Field: "until", Message: "must not be sent together with duration"}}
+	case r.Duration != "":
+		d, err := ParseSnoozeDuration(r.Duration)
+		if err != nil || d <= 0 || d > MaxSnooze {
+			return []apierrors.FieldError{{Field: "duration", Message: `must be a positive duration of at most 365 days, such as "90m", "48h" or "3d"`}}
+		}
+	default:
+		if err := checkDueDate(r.Until); errors.Is(err, ErrDueDateOutOfRange) {
+			return []apierrors.FieldError{{Field: "until", Message: "must be between the years 0000 and 9999 in UTC"}}
+		} else if err != nil {
+			return []apierrors.FieldError{{Field: "until", Message: "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"}}
+		}
+	}
+	return nil
+}
+
+// Normalize trims surrounding whitespace from the todo ID
+func (r *AddBlockerRequest) Normalize() {
+	r.TodoID = strings.TrimSpace(r.TodoID)
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *AddBlockerRequest) Validate() []apierrors.FieldError {
+	if !primitive.IsValidObjectID(r.TodoID) {
+		return []apierrors.FieldError{{Field: "todo_id", Message: "must be a todo ID"}}
+	}
+	return nil
+}
+
+// Normalize trims surrounding whitespace from the link
+func (r *AddAttachmentRequest) Normalize() {
+	r.URL = strings.TrimSpace(r.URL)
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+// Whether the link is somewhere the server may fetch is only known once its
+// host is resolved.
+func (r *AddAttachmentRequest) Validate() []apierrors.FieldError {
+	if len(r.URL) > MaxAttachmentURLLength {
+		return []apierrors.FieldError{{Field: "url", Message: fmt.Sprintf("must be at most %d characters", MaxAttachmentURLLength)}}
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return []apierrors.FieldError{{Field: "url", Message: "must be an absolute http or https URL"}}
+	}
+	if u.User != nil {
+		return []apierrors.FieldError{{Field: "url", Message: "must not include a user name or password"}}
+	}
+	return nil
+}
+
+// Normalize trims surrounding whitespace from the title
+func (r *AddChecklistItemRequest) Normalize() {
+	r.Title = strings.TrimSpace(r.Title)
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *AddChecklistItemRequest) Validate() []apierrors.FieldError {
+	return validateTitle(nil, r.Title)
+}
+
+// Normalize trims surrounding whitespace from the title, if it was sent
+func (r *UpdateChecklistItemRequest) Normalize() {
+	if r.Title != nil {
+		title := strings.TrimSpace(*r.Title)
+		r.Title = &title
+	}
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *UpdateChecklistItemRequest) Validate() []apierrors.FieldError {
+	if r.Title != nil {
+		return validateTitle(nil, *r.Title)
+	}
+	return nil
+}
+
+// Normalize normalizes the new name
+func (r *RenameTagRequest) Normalize() {
+	r.Name = NormalizeTag(r.Name)
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *RenameTagRequest) Validate() []apierrors.FieldError {
+	return ValidateTag("name", r.Name)
+}
+
+// Normalize normalizes the tag merged into
+func (r *MergeTagRequest) Normalize() {
+	r.Into = NormalizeTag(r.Into)
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *MergeTagRequest) Validate() []apierrors.FieldError {
+	return ValidateTag("into", r.Into)
+}
+
+// Normalize trims surrounding whitespace from the body
+func (r *AddCommentRequest) Normalize() {
+	r.Body = strings.TrimSpace(r.Body)
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+// Whether the users mentioned can see the todo is checked by the handler.
+func (r *AddCommentRequest) Validate() []apierrors.FieldError {
+	switch {
+	case r.Body == "":
+		return []apierrors.FieldError{{Field: "body", Message: "must not be empty or only whitespace"}}
+	case utf8.RuneCountInString(r.Body) > MaxCommentLength:
+		return []apierrors.FieldError{{Field: "body", Message: fmt.Sprintf("must be at most %d characters", MaxCommentLength)}}
+	case len(ParseMentions(r.Body)) > MaxMentions:
+		return []apierrors.FieldError{{Field: "body", Message: fmt.Sprintf("must mention at most %d users", MaxMentions)}}
+	}
+	return nil
+}
+
+// Normalize trims surrounding whitespace from the code and state
+func (r *ImportRequest) Normalize() {
+	r.Code = strings.TrimSpace(r.Code)
+	r.State = strings.TrimSpace(r.State)
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *ImportRequest) Validate() []apierrors.FieldError {
+	var errs []apierrors.FieldError
+	if r.Code == "" || len(r.Code) > 2048 {
+		errs = append(errs, apierrors.FieldError{Field: "code", Message: "must be the code the service sent back, at most 2048 characters"})
+	}
+	if r.State == "" {
+		errs = append(errs, apierrors.FieldError{Field: "state", Message: "must not be empty"})
+	}
+	return errs
+}
+
+// Normalize trims surrounding whitespace from the IDs
+func (r *LookupTodosRequest) Normalize() {
+	for i, id := range r.IDs {
+		r.IDs[i] = strings.TrimSpace(id)
+	}
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *LookupTodosRequest) Validate() []apierrors.FieldError {
+	return ValidateLookupIDs("ids", r.IDs)
+}
+
+// ValidateLookupIDs checks a list of todos to fetch by ID: between 1 and
+// MaxLookupIDs todo IDs
+func ValidateLookupIDs(field string, ids []string) []apierrors.FieldError {
+	if len(ids) == 0 || len(ids) > MaxLookupIDs {
+		return []apierrors.FieldError{{Field: field, Message: fmt.Sprintf("must list between 1 and %d todo IDs", MaxLookupIDs)}}
+	}
+	var errs []apierrors.FieldError
+	for i, id := range ids {
+		if !primitive.IsValidObjectID(id) {
+			errs = append(errs, apierrors.FieldError{Field: fmt.Sprintf("%s[%d]", field, i), Message: "must be a todo ID"})
+		}
+	}
+	return errs
+}
+
+// Normalize trims the fields that were sent and lowercases the digest and
+// email
+func (r *UpdateSettingsRequest) Normalize() {
+	if r.Timezone != nil {
+		timezone := strings.TrimSpace(*r.Timezone)
+		r.Timezone = &timezone
+	}
+	if r.Digest != nil {
+		digest := strings.ToLower(strings.TrimSpace(*r.Digest))
+		r.Digest = &digest
+	}
+	if r.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*r.Email))
+		r.Email = &email
+	}
+	if r.DailyReset != nil {
+		reset := strings.TrimSpace(*r.DailyReset)
+		r.DailyReset = &reset
+	}
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *UpdateSettingsRequest) Validate() []apierrors.FieldError {
+	var errs []apierrors.FieldError
+	if r.Timezone != nil && *r.Timezone != "" {
+		// "Local" is the server's zone, which users have no business with
+		if _, err := time.LoadLocation(*r.Timezone); err != nil || *r.Timezone == "Local" {
+			errs = append(errs, apierrors.FieldError{Field: "timezone", Message: `must be an IANA time zone name such as "Europe/London"`})
+		}
+	}
+	if r.Digest != nil && *r.Digest != "" && *r.Digest != DigestDaily && *r.Digest != DigestWeekly {
+		errs = append(errs, apierrors.FieldError{Field: "digest", Message: fmt.Sprintf("must be %q, %q or %q", DigestDaily, DigestWeekly, "")})
+	}
+	if r.Email != nil && *r.Email != "" {
+		if addr, err := mail.ParseAddress(*r.Email); err != nil || addr.Address != *r.Email {
+			errs = append(errs, apierrors.FieldError{Field: "email", Message: "must be a valid email address"})
+		}
+	}
+	if r.DailyReset != nil && *r.DailyReset != "" {
+		if t, err := time.Parse(DailyResetLayout, *r.DailyReset); err != nil || t.Format(DailyResetLayout) != *r.DailyReset {
+			errs = append(errs, apierrors.FieldError{Field: "daily_reset", Message: `must be a 24-hour time such as "23:30", or ""`})
+		}
+	}
+	return errs
+}
+
+// Normalize normalizes the tags and lowercases the priority and day that
+// were sent
+func (r *UpdatePreferencesRequest) Normalize() {
+	if r.DefaultPriority != nil {
+		priority := Priority(strings.ToLower(strings.TrimSpace(string(*r.DefaultPriority))))
+		r.DefaultPriority = &priority
+	}
+	if r.DefaultTags != nil {
+		tags := NormalizeTags(*r.DefaultTags)
+		r.DefaultTags = &tags
+	}
+	if r.StartOfWeek != nil {
+		day := strings.ToLower(strings.TrimSpace(*r.StartOfWeek))
+		r.StartOfWeek = &day
+	}
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *UpdatePreferencesRequest) Validate() []apierrors.FieldError {
+	var errs []apierrors.FieldError
+	if r.DefaultPriority != nil {
+		for _, err := range validatePriority(nil, *r.DefaultPriority) {
+			errs = append(errs, apierrors.FieldError{Field: "default_priority", Message: err.Message})
+		}
+	}
+	if r.DefaultTags != nil {
+		for _, err := range validateTags(nil, *r.DefaultTags) {
+			errs = append(errs, apierrors.FieldError{Field: "default_tags", Message: err.Message})
+		}
+	}
+	if r.StartOfWeek != nil && *r.StartOfWeek != "" {
+		if _, ok := weekday(*r.StartOfWeek); !ok {
+			errs = append(errs, apierrors.FieldError{Field: "start_of_week", Message: `must be a day of the week such as "monday"`})
+		}
+	}
+	return errs
+}
+
+// Normalize lowercases the channels and drops repeated ones
+func (r *UpdateNotificationPreferencesRequest) Normalize() {
+	for kind, channels := range r.Preferences {
+		if channels == nil {
+			continue
+		}
+		normalized := []string{}
+		for _, channel := range channels {
+			if channel = strings.ToLower(strings.TrimSpace(channel)); !slices.Contains(normalized, channel) {
+				normalized = append(normalized, channel)
+			}
+		}
+		r.Preferences[kind] = normalized
+	}
+}
+
+// Validate returns every field that breaks the rules, given the channels
+// available. Call Normalize first.
+func (r *UpdateNotificationPreferencesRequest) Validate(channels []string) []apierrors.FieldError {
+	if len(r.Preferences) == 0 {
+		return []apierrors.FieldError{{Field: "preferences", Message: "must set the channels of at least one kind of notification"}}
+	}
+	var errs []apierrors.FieldError
+	for _, kind := range slices.Sorted(maps.Keys(r.Preferences)) {
+		if !slices.Contains(NotificationKinds, kind) {
+			errs = append(errs, apierrors.FieldError{Field: "preferences." + kind, Message: "must be one of " + strings.Join(NotificationKinds, ", ")})
+			continue
+		}
+		for _, channel := range r.Preferences[kind] {
+			if !slices.Contains(channels, channel) {
+				errs = append(errs, apierrors.FieldError{Field: "preferences." + kind, Message: fmt.Sprintf("%q isn't a channel notifications can be sent on", channel)})
+			}
+		}
+	}
+	return errs
+}
+
+// Normalize trims surrounding whitespace from the name
+func (r *CreateWorkspaceRequest) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Color = NormalizeColor(r.Color)
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *CreateWorkspaceRequest) Validate() []apierrors.FieldError {
+	errs := validateWorkspaceName(nil, r.Name)
+	return validateColor(errs, r.Color)
+}
+
+// Normalize trims surrounding whitespace from the fields that were sent
+func (r *UpdateWorkspaceRequest) Normalize() {
+	if r.Name != nil {
+		name := strings.TrimSpace(*r.Name)
+		r.Name = &name
+	}
+	if r.Color != nil {
+		color := NormalizeColor(*r.Color)
+		r.Color = &color
+	}
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *UpdateWorkspaceRequest) Validate() []apierrors.FieldError {
+	var errs []apierrors.FieldError
+	if r.Name != nil {
+		errs = validateWorkspaceName(errs, *r.Name)
+	}
+	if r.Color != nil {
+		errs = validateColor(errs, *r.Color)
+	}
+	return errs
+}
+
+func validateWorkspaceName(errs []apierrors.FieldError, name string) []apierrors.FieldError {
+	switch {
+	case name == "":
+		return append(errs, apierrors.FieldError{Field: "name", Message: "must not be empty or only whitespace"})
+	case utf8.RuneCountInString(name) > MaxWorkspaceNameLength:
+		return append(errs, apierrors.FieldError{Field: "name", Message: fmt.Sprintf("must be at most %d characters", MaxWorkspaceNameLength)})
+	}
+	return errs
+}
+
+// Normalize trims surrounding whitespace from the user ID and defaults the
+// role to editor
+func (r *AddMemberRequest) Normalize() {
+	r.UserID = strings.TrimSpace(r.UserID)
+	if r.Role == "" {
+		r.Role = authz.RoleEditor
+	}
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *AddMemberRequest) Validate() []apierrors.FieldError {
+	var errs []apierrors.FieldError
+	switch {
+	case r.UserID == "":
+		errs = append(errs, apierrors.FieldError{Field: "user_id", Message: "must not be empty"})
+	case len(r.UserID) > MaxUserIDLength:
+		errs = append(errs, apierrors.FieldError{Field: "user_id", Message: fmt.Sprintf("must be at most %d characters", MaxUserIDLength)})
+	}
+	return validateMemberRole(errs, r.Role)
+}
+
+// Validate returns every field that breaks the rules
+func (r *UpdateMemberRequest) Validate() []apierrors.FieldError {
+	return validateMemberRole(nil, r.Role)
+}
+
+// Normalize trims surrounding whitespace from the assignee's ID
+func (r *AssignTodoRequest) Normalize() {
+	r.AssigneeID = strings.TrimSpace(r.AssigneeID)
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *AssignTodoRequest) Validate() []apierrors.FieldError {
+	if len(r.AssigneeID) > MaxUserIDLength {
+		return []apierrors.FieldError{{Field: "assignee_id", Message: fmt.Sprintf("must be at most %d characters", MaxUserIDLength)}}
+	}
+	return nil
+}
+
+// validateMemberRole accepts the roles a member can be given. Ownership
+// can't be granted; it stays with the workspace's creator.
+func validateMemberRole(errs []apierrors.FieldError, role authz.Role) []apierrors.FieldError {
+	if role != authz.RoleViewer && role != authz.RoleEditor && role != authz.RoleAdmin {
+		return append(errs, apierrors.FieldError{Field: "role", Message: fmt.Sprintf("must be %q, %q or %q", authz.RoleViewer, authz.RoleEditor, authz.RoleAdmin)})
+	}
+	return errs
+}
+
+// Normalize trims the fields, lowercases the email, which is matched
+// case-insensitively, and defaults the role to viewer
+func (r *ShareTodoRequest) Normalize() {
+	r.Email = strings.ToLower(strings.TrimSpace(r.Email))
+	r.UserID = strings.TrimSpace(r.UserID)
+	if r.Role == "" {
+		r.Role = authz.RoleViewer
+	}
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *ShareTodoRequest) Validate() []apierrors.FieldError {
+	var errs []apierrors.FieldError
+	switch {
+	case (r.Email == "") == (r.UserID == ""):
+		errs = append(errs, apierrors.FieldError{Field: "email", Message: "exactly one of email or user_id is required"})
+	case r.Email != "":
+		if addr, err := mail.ParseAddress(r.Email); err != nil || addr.Address != r.Email {
+			errs = append(errs, apierrors.FieldError{Field: "email", Message: "must be a valid email address"})
+		}
+	case len(r.UserID) > MaxUserIDLength:
+		errs = append(errs, apierrors.FieldError{Field: "user_id", Message: fmt.Sprintf("must be at most %d characters", MaxUserIDLength)})
+	}
+	// A shared todo can be read or edited, but only its owner shares it
+	if r.Role != authz.RoleViewer && r.Role != authz.RoleEditor {
+		errs = append(errs, apierrors.FieldError{Field: "role", Message: fmt.Sprintf("must be %q or %q", authz.RoleViewer, authz.RoleEditor)})
+	}
+	return errs
+}
+
+// Normalize trims surrounding whitespace from the name and criteria
+func (r *CreateSavedFilterRequest) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Criteria.Normalize()
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *CreateSavedFilterRequest) Validate() []apierrors.FieldError {
+	errs := validateFilterName(nil, r.Name)
+	return r.Criteria.validate(errs)
+}
+
+// Normalize trims surrounding whitespace from the name and criteria
+func (r *UpdateSavedFilterRequest) Normalize() {
+	if r.Name != nil {
+		name := strings.TrimSpace(*r.Name)
+		r.Name = &name
+	}
+	if r.Criteria != nil {
+		r.Criteria.Normalize()
+	}
+}
+
+// Validate returns every field that breaks the rules. Call Normalize first.
+func (r *UpdateSavedFilterRequest) Validate() []apierrors.FieldError {
+	var errs []apierrors.FieldError
+	if r.Name != nil {
+		errs = validateFilterName(errs, *r.Name)
+	}
+	if r.Criteria != nil {
+		errs = r.Criteria.validate(errs)
+	}
+	return errs
+}
+
+func validateFilterName(errs []apierrors.FieldError, name string) []apierrors.FieldError {
+	switch {
+	case name == "":
+		return append(errs, apierrors.FieldError{Field: "name", Message: "must not be empty or only whitespace"})
+	case utf8.RuneCountInString(name) > MaxFilterNameLength:
+		return append(errs, apierrors.FieldError{Field: "name", Message: fmt.Sprintf("must be at most %d characters", MaxFilterNameLength)})
+	}
+	return errs
+}
+
+// Normalize trims the search text and lowercases the priorities
+func (f *TodoFilter) Normalize() {
+	f.Search = strings.TrimSpace(f.Search)
+	for i, p := range f.Priorities {
+		f.Priorities[i] = Priority(strings.ToLower(strings.TrimSpace(string(p))))
+	}
+}
+
+// validate appends the criteria that break the rules, named as fields of
+// criteria
+func (f *TodoFilter) validate(errs []apierrors.FieldError) []apierrors.FieldError {
+	for i, p := range f.Priorities {
+		if !p.Valid() {
+			errs = append(errs, apierrors.FieldError{Field: fmt.Sprintf("criteria.priorities[%d]", i), Message: fmt.Sprintf("must be %q, %q or %q", PriorityLow, PriorityMedium, PriorityHigh)})
+		}
+	}
+	if f.DueWithinDays != nil && (*f.DueWithinDays < 0 || *f.DueWithinDays > MaxDueWithinDays) {
+		errs = append(errs, apierrors.FieldError{Field: "criteria.due_within_days", Message: fmt.Sprintf("must be between 0 and %d days", MaxDueWithinDays)})
+	}
+	if utf8.RuneCountInString(f.Search) > MaxSearchLength {
+		errs = append(errs, apierrors.FieldError{Field: "criteria.search", Message: fmt.Sprintf("must be at most %d characters", MaxSearchLength)})
+	}
+	return errs
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"todo-api/authz"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Workspace is a todo list shared by a small team. What each member may do
+// is set by their role.
+type Workspace struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name      string             `json:"name" bson:"name"`
+	Color     Color              `json:"color,omitempty" bson:"color,omitempty"`
+	OwnerID   string             `json:"owner_id" bson:"owner_id"`
+	Members   []Member           `json:"members" bson:"members"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// Member is a user who belongs to a workspace
+type Member struct {
+	UserID   string     `json:"user_id" bson:"user_id"`
+	Role     authz.Role `json:"role" bson:"role"`
+	JoinedAt time.Time  `json:"joined_at" bson:"joined_at"`
+}
+
+// HasMember reports whether the user belongs to the workspace
+func (w *Workspace) HasMember(userID string) bool {
+	for _, m := range w.Members {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleOf returns the user's role in the workspace, or "" if they aren't a
+// member. The owner is always RoleOwner; members added before roles existed
+// are editors, which is what every member used to be.
+func (w *Workspace) RoleOf(userID string) authz.Role {
+	if userID == w.OwnerID {
+		return authz.RoleOwner
+	}
+	for _, m := range w.Members {
+		if m.UserID == userID {
+			if m.Role == "" {
+				return authz.RoleEditor
+			}
+			return m.Role
+		}
+	}
+	return ""
+}
+
+type CreateWorkspaceRequest struct {
+	Name  string `json:"name"`
+	Color Color  `json:"color"`
+}
+
+// UpdateWorkspaceRequest changes the fields that are sent. An empty color
+// clears it.
+type UpdateWorkspaceRequest struct {
+	Name  *string `json:"name"`
+	Color *Color  `json:"color"`
+}
+
+type AddMemberRequest struct {
+	UserID string     `json:"user_id"`
+	Role   authz.Role `json:"role"`
+}
+
+type UpdateMemberRequest struct {
+	Role authz.Role `json:"role"`
+}
+
+// AssignTodoRequest assigns a workspace todo to a member. An empty or null
+// assignee_id unassigns it.
+type AssignTodoRequest struct {
+	AssigneeID string `json:"assignee_id"`
+}
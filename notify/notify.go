@@ -0,0 +1,129 @@
+// Package notify sends notifications to users over the channels they pick,
+// such as email or Slack. Features that notify, like digests and overdue
+// reminders, hand a Notification to a Registry, which knows the channels, so
+// a channel can be added without changing them.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"todo-api/models"
+	"todo-api/repository"
+)
+
+// ErrUnreachable is returned by a channel that has no way of reaching the
+// user, such as email for a user without an address
+var ErrUnreachable = errors.New("user can't be reached on this channel")
+
+// Notification is something to tell a user
+type Notification struct {
+	// Kind is one of models.NotificationKinds
+	Kind string
+	// Subject sums the notification up in a line, such as an email's
+	// subject
+	Subject string
+	// Text is the body as plain text, and HTML the same as HTML for
+	// channels that can show it; either may be empty
+	Text string
+	HTML string
+}
+
+// Recipient is the user a notification is for
+type Recipient struct {
+	UserID   string
+	Settings models.UserSettings
+}
+
+// Notifier delivers notifications over one channel
+type Notifier interface {
+	// Channel names the channel, as users pick it in their preferences
+	Channel() string
+	// Notify delivers n to the user, returning ErrUnreachable if the user
+	// can't be reached on the channel
+	Notify(ctx context.Context, to Recipient, n Notification) error
+}
+
+// Registry holds the channels notifications can be sent on and sends each
+// notification on those its user picked for its kind
+type Registry struct {
+	users    repository.UserRepository
+	channels []Notifier
+	// defaults are the channels of the kinds users haven't picked them for
+	defaults map[string][]string
+}
+
+// NewRegistry creates a Registry without channels, reading users' settings
+// from users
+func NewRegistry(users repository.UserRepository) *Registry {
+	return &Registry{users: users, defaults: map[string][]string{}}
+}
+
+// Register adds a channel, which the given kinds of notification go to
+// unless users pick otherwise
+func (r *Registry) Register(n Notifier, defaultKinds ...string) {
+	r.channels = append(r.channels, n)
+	for _, kind := range defaultKinds {
+		r.defaults[kind] = append(r.defaults[kind], n.Channel())
+	}
+}
+
+// Channels returns the names of the channels, in the order they were
+// registered
+func (r *Registry) Channels() []string {
+	names := make([]string, len(r.channels))
+	for i, n := range r.channels {
+		names[i] = n.Channel()
+	}
+	return names
+}
+
+// Enabled returns the channels notifications of kind go to for a user with
+// settings: those they picked that are available, or the defaults
+func (r *Registry) Enabled(settings models.UserSettings, kind string) []string {
+	picked, ok := settings.Notifications[kind]
+	if !ok {
+		picked = r.defaults[kind]
+	}
+	enabled := []string{}
+	for _, n := range r.channels {
+		if slices.Contains(picked, n.Channel()) {
+			enabled = append(enabled, n.Channel())
+		}
+	}
+	return enabled
+}
+
+// Notify sends n on every channel enabled for its kind and returns how many
+// delivered it. Channels that can't reach the user are skipped; the others
+// are all tried, and their errors joined.
+func (r *Registry) Notify(ctx context.Context, to Recipient, n Notification) (int, error) {
+	enabled := r.Enabled(to.Settings, n.Kind)
+	delivered := 0
+	var errs []error
+	for _, channel := range r.channels {
+		if !slices.Contains(enabled, channel.Channel()) {
+			continue
+		}
+		err := channel.Notify(ctx, to, n)
+		switch {
+		case errors.Is(err, ErrUnreachable):
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", channel.Channel(), err))
+		default:
+			delivered++
+		}
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// NotifyUser sends n to the user with the given ID, as Notify does
+func (r *Registry) NotifyUser(ctx context.Context, userID string, n Notification) (int, error) {
+	settings, err := r.users.GetSettings(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return r.Notify(ctx, Recipient{UserID: userID, Settings: settings}, n)
+}
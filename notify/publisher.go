@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"todo-api/events"
+	"todo-api/models"
+	"todo-api/repository"
+)
+
+// notifyTimeout bounds the work done to notify one user of an event
+const notifyTimeout = 30 * time.Second
+
+// maxExcerptLength is how much of a comment a mention's notification quotes
+const maxExcerptLength = 200
+
+// Publisher publishes events to another publisher and notifies users of
+// the ones that concern them: todos assigned to them and comments
+// mentioning them. It sits behind the outbox relay, so users only hear of
+// changes that were saved, and are notified once the next publisher took
+// the events, so a batch the relay retries isn't notified twice.
+// Notifications are sent in the background, so the relay doesn't wait on
+// the channels.
+type Publisher struct {
+	next     events.Publisher
+	registry *Registry
+}
+
+// NewPublisher creates a Publisher in front of next, notifying through
+// registry
+func NewPublisher(next events.Publisher, registry *Registry) *Publisher {
+	return &Publisher{next: next, registry: registry}
+}
+
+// Publish sends the events to the next publisher and notifies users
+func (p *Publisher) Publish(ctx context.Context, batch ...events.Event) error {
+	if err := p.next.Publish(ctx, batch...); err != nil {
+		return err
+	}
+	for _, event := range batch {
+		switch event.Type {
+		case events.TodoAssigned:
+			var todo models.Todo
+			if decode(event, &todo) {
+				p.notify(ctx, event, todo.AssigneeID, Notification{
+					Kind:    models.NotifyAssigned,
+					Subject: "You were assigned a todo: " + todo.Title,
+				})
+			}
+		case events.CommentCreated:
+			var data models.TodoComment
+			if decode(event, &data) {
+				n := Notification{
+					Kind:    models.NotifyMentioned,
+					Subject: "You were mentioned on " + data.TodoTitle,
+					Text:    excerpt(data.Comment.Body),
+				}
+				for _, userID := range data.Comment.Mentions {
+					p.notify(ctx, event, userID, n)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// decode reads the data of an event into v. Events relayed from the outbox
+// carry their data as the JSON it was stored as.
+func decode(event events.Event, v any) bool {
+	data, ok := event.Data.(json.RawMessage)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(event.Data); err != nil {
+			log.Printf("Couldn't read %s for %s: %v", event.Type, event.Subject, err)
+			return false
+		}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		log.Printf("Couldn't read %s for %s: %v", event.Type, event.Subject, err)
+		return false
+	}
+	return true
+}
+
+// notify sends n to the user in the background
+func (p *Publisher) notify(ctx context.Context, event events.Event, userID string, n Notification) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+		defer cancel()
+
+		if _, err := p.registry.NotifyUser(ctx, userID, n); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			log.Printf("Failed to notify user %s of %s for %s: %v", userID, event.Type, event.Subject, err)
+		}
+	}()
+}
+
+// excerpt cuts text down to maxExcerptLength
+func excerpt(text string) string {
+	if runes := []rune(text); len(runes) > maxExcerptLength {
+		return string(runes[:maxExcerptLength]) + "…"
+	}
+	return text
+}
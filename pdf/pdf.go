@@ -0,0 +1,303 @@
+// Package pdf writes simple paginated text documents, such as printable todo
+// lists. Text is set in the standard Helvetica fonts every PDF reader has,
+// so nothing is embedded and files stay small. Those fonts cover Western
+// European scripts only; other characters are printed as question marks.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Font is one of the standard fonts text can be set in
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+// baseFonts are the PostScript names of the fonts
+var baseFonts = [...]string{Regular: "Helvetica", Bold: "Helvetica-Bold"}
+
+// A4 page size and the margins around the text, in points
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	margin       = 56.0
+	footerHeight = 24.0
+	textWidth    = pageWidth - 2*margin
+)
+
+// checkboxSize is the side of the boxes drawn by Checkbox
+const checkboxSize = 8.0
+
+// Document is a document being laid out. Content flows down the page and on
+// to new pages as they fill up.
+type Document struct {
+	title   string
+	created time.Time
+	pages   []*bytes.Buffer
+	// y is the top of the space left on the current page
+	y float64
+}
+
+// New starts a document. title is shown in every page's footer and in the
+// reader's window title.
+func New(title string, created time.Time) *Document {
+	d := &Document{title: title, created: created}
+	d.newPage()
+	return d
+}
+
+func (d *Document) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+// page returns the content of the current page
+func (d *Document) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// reserve moves to a new page unless height fits on this one
+func (d *Document) reserve(height float64) {
+	if d.y-height < margin+footerHeight && d.y < pageHeight-margin {
+		d.newPage()
+	}
+}
+
+// Space leaves a vertical gap
+func (d *Document) Space(height float64) {
+	d.y -= height
+}
+
+// Text sets text in font and size, indented from the left margin and
+// wrapped to the width of the page. Line breaks in text are kept.
+func (d *Document) Text(font Font, size, indent float64, gray float64, text string) {
+	for _, line := range wrap(font, size, textWidth-indent, text) {
+		d.line(font, size, margin+indent, gray, line)
+	}
+}
+
+// Checkbox sets text as an item of a checklist: a box, ticked when checked,
+// with the text wrapped beside it
+func (d *Document) Checkbox(checked bool, font Font, size float64, gray float64, text string) {
+	indent := checkboxSize + 6
+	lines := wrap(font, size, textWidth-indent, text)
+	d.reserve(lineHeight(size))
+	// The box sits on the first line's baseline
+	x, y := margin, d.y-size
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f %.2f %.2f re S\n", x, y, checkboxSize, checkboxSize)
+	if checked {
+		fmt.Fprintf(d.page(), "1.2 w %.2f %.2f m %.2f %.2f l %.2f %.2f l S\n",
+			x+1.5, y+4, x+3.5, y+1.5, x+7, y+7)
+	}
+	for _, line := range lines {
+		d.line(font, size, margin+indent, gray, line)
+	}
+}
+
+// line sets one line of text, moving to a new page if it doesn't fit
+func (d *Document) line(font Font, size, x float64, gray float64, text string) {
+	height := lineHeight(size)
+	d.reserve(height)
+	d.y -= height
+	fmt.Fprintf(d.page(), "BT %.2f g /F%d %.1f Tf %.2f %.2f Td %s Tj ET\n", gray, font, size, x, d.y+height-size, literal(text))
+}
+
+// lineHeight is the height of a line of text in size
+func lineHeight(size float64) float64 {
+	return size * 1.35
+}
+
+// Write finishes the document, adding page numbers to the footers, and
+// writes it to w
+func (d *Document) Write(w io.Writer) error {
+	// Objects are numbered in the order they're written: the catalog, the
+	// page tree, the fonts, the info dictionary, then each page and its
+	// content
+	const (
+		catalogObj = 1
+		pagesObj   = 2
+		fontObj    = 3 // one per font
+		infoObj    = fontObj + len(baseFonts)
+		firstPage  = infoObj + 1
+	)
+
+	var out bytes.Buffer
+	offsets := []int{0}
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets)-1, body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj))
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Count %d /Kids [%s] >>", len(d.pages), strings.Join(kids, " ")))
+	for _, name := range baseFonts {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	object(fmt.Sprintf("<< /Title %s /Producer (todo-api) /CreationDate (D:%s) >>", literal(d.title), d.created.UTC().Format("20060102150405Z")))
+
+	fonts := make([]string, len(baseFonts))
+	for i := range baseFonts {
+		fonts[i] = fmt.Sprintf("/F%d %d 0 R", i, fontObj+i)
+	}
+	for i, content := range d.pages {
+		d.footer(content, i+1)
+		object(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			pagesObj, pageWidth, pageHeight, strings.Join(fonts, " "), firstPage+2*i+1))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write(content.Bytes())
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets))
+	for _, offset := range offsets[1:] {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets), catalogObj, infoObj, xref)
+
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// footer sets the document's title and the page number at the foot of a
+// page
+func (d *Document) footer(content *bytes.Buffer, number int) {
+	const size = 8
+	y := margin - size
+	fmt.Fprintf(content, "BT 0.45 g /F%d %d Tf %.2f %.2f Td %s Tj ET\n", Regular, size, margin, y, literal(truncate(Regular, size, textWidth*0.7, d.title)))
+	label := fmt.Sprintf("Page %d of %d", number, len(d.pages))
+	x := pageWidth - margin - width(Regular, size, label)
+	fmt.Fprintf(content, "BT 0.45 g /F%d %d Tf %.2f %.2f Td %s Tj ET\n", Regular, size, x, y, literal(label))
+}
+
+// wrap breaks text into lines no wider than max, at spaces where it can.
+// Line breaks in text are kept.
+func wrap(font Font, size, max float64, text string) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if width(font, size, candidate) <= max {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			// Words too long for a line of their own are broken anywhere
+			for width(font, size, word) > max {
+				n := fit(font, size, max, word)
+				lines = append(lines, word[:n])
+				word = word[n:]
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fit returns how many bytes of s, cut at a character boundary, fit in max.
+// At least one character is returned, so wrapping always moves on.
+func fit(font Font, size, max float64, s string) int {
+	n := 0
+	for n < len(s) {
+		_, w := utf8.DecodeRuneInString(s[n:])
+		if n > 0 && width(font, size, s[:n+w]) > max {
+			break
+		}
+		n += w
+	}
+	return n
+}
+
+// truncate shortens s with an ellipsis to fit in max
+func truncate(font Font, size, max float64, s string) string {
+	if width(font, size, s) <= max {
+		return s
+	}
+	return s[:fit(font, size, max-width(font, size, "…"), s)] + "…"
+}
+
+// width returns the width of s set in font and size, in points
+func width(font Font, size float64, s string) float64 {
+	total := 0
+	for _, b := range encode(s) {
+		total += glyphWidth(font, b)
+	}
+	return float64(total) * size / 1000
+}
+
+// literal returns s as a PDF string in WinAnsiEncoding. Bytes outside
+// printable ASCII are escaped, so content streams stay plain text.
+func literal(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, c := range encode(s) {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c > 0x7e:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// encode converts s to WinAnsiEncoding, replacing characters it lacks with
+// question marks and control characters with spaces
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			out = append(out, ' ')
+		case r < 0x20 || r == 0x7f:
+			continue
+		case r < 0x80 || r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		default:
+			if b, ok := winAnsi[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// winAnsi maps the characters WinAnsiEncoding places in 0x80 to 0x9f
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
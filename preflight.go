@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"todo-api/config"
+	"todo-api/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+// preflightTimeout bounds the storage checks
+const preflightTimeout = 30 * time.Second
+
+// checkConfig parses the connection strings and certificates the way their
+// clients will, and checks the paths the API writes to, so a typo stops the
+// start naming the setting instead of surfacing on the first request that
+// needs it. config.Load has checked everything that needs no I/O, such as
+// the cookie secret's length and the CORS origins.
+func checkConfig(cfg *config.Config) error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch cfg.Storage.Backend {
+	case config.BackendMongo:
+		// With a managed identity the connection string is looked up later
+		if cfg.Mongo.Auth == config.MongoAuthConnectionString {
+			if _, err := connstring.ParseAndValidate(cfg.Mongo.URI); err != nil {
+				fail("MONGODB_URI isn't a valid MongoDB connection string (%v); special characters in the password must be percent-encoded", err)
+			}
+		}
+	case config.BackendPostgres:
+		// pgx hides the password in its errors
+		if _, err := pgx.ParseConfig(cfg.Postgres.URL); err != nil {
+			fail("POSTGRES_URL isn't a valid PostgreSQL connection string: %v", err)
+		}
+	case config.BackendSQLite:
+		if dir := filepath.Dir(cfg.SQLite.Path); !isDir(dir) {
+			fail("SQLITE_PATH %q is in %q, which isn't a directory; create it first", cfg.SQLite.Path, dir)
+		}
+	}
+
+	if cfg.Cache.RedisURL != "" {
+		if _, err := redis.ParseURL(cfg.Cache.RedisURL); err != nil {
+			fail("REDIS_URL isn't a valid Redis URL like rediss://:password@host:6380/0: %v", withoutURL(err))
+		}
+	}
+
+	if cfg.TLS.CertFile != "" {
+		if _, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile); err != nil {
+			fail("TLS_CERT_FILE and TLS_KEY_FILE must be a PEM certificate and its private key: %v", err)
+		}
+	}
+	if len(cfg.TLS.AutocertDomains) > 0 {
+		if err := os.MkdirAll(cfg.TLS.AutocertCacheDir, 0o700); err != nil {
+			fail("TLS_AUTOCERT_CACHE_DIR %q can't be created, so certificates couldn't be kept: %v", cfg.TLS.AutocertCacheDir, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkStorage creates any missing indexes and writes, then deletes, a
+// probe user record, so a database user without the right permissions stops
+// the start rather than failing the first sign-in
+func checkStorage(cfg *config.Config, stores *repository.Stores, ensureIndexes func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := ensureIndexes(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("can't create the storage indexes (%w); %s", err, storageHint(cfg, "create indexes")))
+	}
+
+	err := forEachTenant(ctx, cfg, func(ctx context.Context) error {
+		probe := "preflight-" + uuid.NewString()
+		if err := stores.Users.Touch(ctx, probe, time.Now()); err != nil {
+			return err
+		}
+		return stores.Users.Delete(ctx, probe)
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("can't write to the database (%w); %s", err, storageHint(cfg, "write")))
+	}
+	return errors.Join(errs...)
+}
+
+// storageHint says what to check when the backend refuses an action
+func storageHint(cfg *config.Config, action string) string {
+	switch cfg.Storage.Backend {
+	case config.BackendPostgres:
+		return "check that the role in POSTGRES_URL owns the API's tables or was granted INSERT, UPDATE and DELETE on them"
+	case config.BackendSQLite:
+		return fmt.Sprintf("check that %s and its directory are writable by the API's user", cfg.SQLite.Path)
+	case config.BackendMongo:
+		if cfg.Mongo.Auth == config.MongoAuthManagedIdentity {
+			return "check that the managed identity may list the Cosmos DB account's read-write keys"
+		}
+		return fmt.Sprintf("check that the user in MONGODB_URI has the readWrite role on %s and may %s, and that a Cosmos DB connection string uses a read-write key", cfg.Mongo.Database, action)
+	default:
+		return "check the storage backend's permissions"
+	}
+}
+
+// preflight stops the process, listing every problem, when err is set
+func preflight(phase string, err error) {
+	if err == nil {
+		return
+	}
+	log.Printf("Preflight checks of the %s failed:", phase)
+	for _, line := range splitErrors(err) {
+		log.Printf("  - %s", line)
+	}
+	os.Exit(1)
+}
+
+// splitErrors returns the messages of the errors joined in err
+func splitErrors(err error) []string {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var lines []string
+		for _, e := range joined.Unwrap() {
+			lines = append(lines, splitErrors(e)...)
+		}
+		return lines
+	}
+	return []string{err.Error()}
+}
+
+// withoutURL drops the URL from url.Parse errors, which would otherwise
+// print the password it holds
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// isDir reports whether path is an existing directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"todo-api/cache"
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CachedTodoRepository caches the todo list of each scope in front of another
+// repository and drops the cached list whenever a todo in it is written. The cache
+// is strictly best effort: if it fails, reads and writes go straight to the
+// underlying repository.
+type CachedTodoRepository struct {
+	TodoRepository
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// NewCachedTodoRepository wraps todos with a list cache. ttl bounds how long
+// a list can be stale if an invalidation is lost.
+func NewCachedTodoRepository(todos TodoRepository, c cache.Cache, ttl time.Duration) *CachedTodoRepository {
+	return &CachedTodoRepository{TodoRepository: todos, cache: c, ttl: ttl}
+}
+
+// cachedList is the cached form of a scope's list. BSON keeps every field of
+// the todo document, including ones hidden from JSON.
+type cachedList struct {
+	Todos []models.Todo `bson:"todos"`
+}
+
+// List returns the scope's todos from the cache, filling it on a miss
+func (r *CachedTodoRepository) List(ctx context.Context, scope Scope) ([]models.Todo, error) {
+	key := listKey(scope)
+
+	data, err := r.cache.Get(ctx, key)
+	if err == nil {
+		var cached cachedList
+		if err := bson.Unmarshal(data, &cached); err == nil {
+			return cached.Todos, nil
+		}
+	} else if !errors.Is(err, cache.ErrMiss) {
+		log.Println("Todo cache read failed:", err)
+	}
+
+	todos, err := r.TodoRepository.List(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := bson.Marshal(cachedList{Todos: todos}); err == nil {
+		if err := r.cache.Set(ctx, key, data, r.ttl); err != nil {
+			log.Println("Todo cache write failed:", err)
+		}
+	}
+	return todos, nil
+}
+
+// Create stores a todo and invalidates its scope's cached list
+func (r *CachedTodoRepository) Create(ctx context.Context, todo *models.Todo) error {
+	err := r.TodoRepository.Create(ctx, todo)
+	r.invalidate(ctx, ScopeOf(todo))
+	return err
+}
+
+// Update replaces a todo and invalidates its scope's cached list
+func (r *CachedTodoRepository) Update(ctx context.Context, todo *models.Todo) error {
+	err := r.TodoRepository.Update(ctx, todo)
+	r.invalidate(ctx, ScopeOf(todo))
+	return err
+}
+
+// UpdateIfUnchanged stores a todo unless it changed since lastUpdated and
+// invalidates its scope's cached list
+func (r *CachedTodoRepository) UpdateIfUnchanged(ctx context.Context, todo *models.Todo, lastUpdated time.Time) error {
+	err := r.TodoRepository.UpdateIfUnchanged(ctx, todo, lastUpdated)
+	r.invalidate(ctx, ScopeOf(todo))
+	return err
+}
+
+// Delete removes a todo and invalidates the scope's cached list
+func (r *CachedTodoRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	err := r.TodoRepository.Delete(ctx, scope, id)
+	r.invalidate(ctx, scope)
+	return err
+}
+
+// DeleteAll removes the scope's todos and its cached list
+func (r *CachedTodoRepository) DeleteAll(ctx context.Context, scope Scope) (int64, error) {
+	n, err := r.TodoRepository.DeleteAll(ctx, scope)
+	r.invalidate(ctx, scope)
+	return n, err
+}
+
+// ReplaceTag replaces a tag on the scope's todos and drops its cached list
+func (r *CachedTodoRepository) ReplaceTag(ctx context.Context, scope Scope, from, to string, now time.Time) (int64, error) {
+	n, err := r.TodoRepository.ReplaceTag(ctx, scope, from, to, now)
+	r.invalidate(ctx, scope)
+	return n, err
+}
+
+// invalidate drops the cached list. It runs even when the write failed,
+// since a failed write may still have been applied.
+func (r *CachedTodoRepository) invalidate(ctx context.Context, scope Scope) {
+	// Use a fresh context so a cancelled request can't leave a stale list
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+
+	if err := r.cache.Delete(ctx, listKey(scope)); err != nil {
+		log.Println("Todo cache invalidation failed:", err)
+	}
+}
+
+func listKey(scope Scope) string {
+	return "todos:list:" + scope.String()
+}
@@ -0,0 +1,348 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"todo-api/fieldcrypt"
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// encryptedFields are the todo fields encrypted at rest. Nothing is filtered
+// or sorted on them in storage, so every backend can store them encrypted.
+var encryptedFields = []string{"title", "description"}
+
+// EncryptedTodoRepository encrypts the titles, descriptions, checklists and
+// comments of todos before they reach another repository and decrypts them
+// on the way back, so callers only ever see plain text. Todos stored before encryption was
+// turned on are read as they are and encrypted when next saved.
+type EncryptedTodoRepository struct {
+	TodoRepository
+	cipher *fieldcrypt.Cipher
+}
+
+// NewEncryptedTodoRepository wraps todos with field encryption
+func NewEncryptedTodoRepository(todos TodoRepository, cipher *fieldcrypt.Cipher) *EncryptedTodoRepository {
+	return &EncryptedTodoRepository{TodoRepository: todos, cipher: cipher}
+}
+
+// List returns the scope's todos decrypted
+func (r *EncryptedTodoRepository) List(ctx context.Context, scope Scope) ([]models.Todo, error) {
+	todos, err := r.TodoRepository.List(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	return todos, r.openAll(todos)
+}
+
+// ListFields returns the scope's todos with the named fields decrypted
+func (r *EncryptedTodoRepository) ListFields(ctx context.Context, scope Scope, fields []string) ([]models.Todo, error) {
+	todos, err := r.TodoRepository.ListFields(ctx, scope, fields)
+	if err != nil {
+		return nil, err
+	}
+	return todos, r.openAll(todos)
+}
+
+// Get returns a todo decrypted
+func (r *EncryptedTodoRepository) Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.Todo, error) {
+	todo, err := r.TodoRepository.Get(ctx, scope, id)
+	if err != nil {
+		return nil, err
+	}
+	return todo, openTodo(r.cipher, todo.ID, todo)
+}
+
+// ListPage returns a page of the scope's todos decrypted
+func (r *EncryptedTodoRepository) ListPage(ctx context.Context, scope Scope, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	todos, err := r.TodoRepository.ListPage(ctx, scope, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	return todos, r.openAll(todos)
+}
+
+// ListChanged returns a page of changed todos decrypted
+func (r *EncryptedTodoRepository) ListChanged(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	todos, err := r.TodoRepository.ListChanged(ctx, since, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	return todos, r.openAll(todos)
+}
+
+// ListDueByDay returns the scope's todos due in [from, to) by day
+// decrypted
+func (r *EncryptedTodoRepository) ListDueByDay(ctx context.Context, scope Scope, from, to time.Time, loc *time.Location) (map[string][]models.Todo, error) {
+	days, err := r.TodoRepository.ListDueByDay(ctx, scope, from, to, loc)
+	if err != nil {
+		return nil, err
+	}
+	for _, todos := range days {
+		if err := r.openAll(todos); err != nil {
+			return nil, err
+		}
+	}
+	return days, nil
+}
+
+// ListNear returns the scope's todos near center decrypted
+func (r *EncryptedTodoRepository) ListNear(ctx context.Context, scope Scope, center *models.Location, radius float64) ([]models.Todo, error) {
+	todos, err := r.TodoRepository.ListNear(ctx, scope, center, radius)
+	if err != nil {
+		return nil, err
+	}
+	return todos, r.openAll(todos)
+}
+
+// Create stores a todo encrypted. The ID is assigned here, since values are
+// encrypted for the todo they belong to.
+func (r *EncryptedTodoRepository) Create(ctx context.Context, todo *models.Todo) error {
+	if todo.ID.IsZero() {
+		todo.ID = primitive.NewObjectID()
+	}
+	return r.sealed(todo, func() error { return r.TodoRepository.Create(ctx, todo) })
+}
+
+// Update replaces a todo, encrypted
+func (r *EncryptedTodoRepository) Update(ctx context.Context, todo *models.Todo) error {
+	return r.sealed(todo, func() error { return r.TodoRepository.Update(ctx, todo) })
+}
+
+// UpdateIfUnchanged stores a todo encrypted unless it changed since
+// lastUpdated, leaving the caller's todo as it was
+func (r *EncryptedTodoRepository) UpdateIfUnchanged(ctx context.Context, todo *models.Todo, lastUpdated time.Time) error {
+	return r.sealed(todo, func() error { return r.TodoRepository.UpdateIfUnchanged(ctx, todo, lastUpdated) })
+}
+
+// sealed runs write with the todo's fields encrypted and puts the plain text
+// back afterwards, so the caller's todo is left as it was
+func (r *EncryptedTodoRepository) sealed(todo *models.Todo, write func() error) error {
+	title, description, checklist, comments := todo.Title, todo.Description, todo.Checklist, todo.Comments
+	defer func() {
+		todo.Title, todo.Description, todo.Checklist, todo.Comments = title, description, checklist, comments
+	}()
+	if err := sealTodo(r.cipher, todo.ID, todo); err != nil {
+		return err
+	}
+	return write()
+}
+
+func (r *EncryptedTodoRepository) openAll(todos []models.Todo) error {
+	for i := range todos {
+		if err := openTodo(r.cipher, todos[i].ID, &todos[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncryptedRevisionRepository encrypts the titles and descriptions in
+// revisions, both in the snapshot of the todo and in the changes, the same
+// way as EncryptedTodoRepository
+type EncryptedRevisionRepository struct {
+	RevisionRepository
+	cipher *fieldcrypt.Cipher
+}
+
+// NewEncryptedRevisionRepository wraps revisions with field encryption
+func NewEncryptedRevisionRepository(revisions RevisionRepository, cipher *fieldcrypt.Cipher) *EncryptedRevisionRepository {
+	return &EncryptedRevisionRepository{RevisionRepository: revisions, cipher: cipher}
+}
+
+// List returns the todo's revisions decrypted
+func (r *EncryptedRevisionRepository) List(ctx context.Context, todoID primitive.ObjectID) ([]models.Revision, error) {
+	revisions, err := r.RevisionRepository.List(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		rev := &revisions[i]
+		if err := openTodo(r.cipher, rev.TodoID, &rev.Todo); err != nil {
+			return nil, err
+		}
+		if err := r.transformChanges(rev, r.cipher.Decrypt); err != nil {
+			return nil, decryptFailed(rev.TodoID, err)
+		}
+	}
+	return revisions, nil
+}
+
+// Create stores a revision encrypted, leaving the caller's revision as it
+// was apart from its assigned ID
+func (r *EncryptedRevisionRepository) Create(ctx context.Context, revision *models.Revision) error {
+	sealed := *revision
+	sealed.Changes = slices.Clone(revision.Changes)
+	if err := sealTodo(r.cipher, sealed.TodoID, &sealed.Todo); err != nil {
+		return err
+	}
+	if err := r.transformChanges(&sealed, r.cipher.Encrypt); err != nil {
+		return err
+	}
+	err := r.RevisionRepository.Create(ctx, &sealed)
+	revision.ID = sealed.ID
+	return err
+}
+
+// transformChanges encrypts or decrypts the values of the revision's
+// changes to encrypted fields
+func (r *EncryptedRevisionRepository) transformChanges(rev *models.Revision, transform func(value, context string) (string, error)) error {
+	for i := range rev.Changes {
+		change := &rev.Changes[i]
+		if !slices.Contains(encryptedFields, change.Field) {
+			continue
+		}
+		for _, v := range []*any{&change.From, &change.To} {
+			s, ok := (*v).(string)
+			if !ok {
+				continue
+			}
+			out, err := transform(s, fieldContext(rev.TodoID, change.Field))
+			if err != nil {
+				return err
+			}
+			*v = out
+		}
+	}
+	return nil
+}
+
+// EncryptedOutboxRepository encrypts the payloads of outbox entries, which
+// hold the todos their events are about, before they reach another
+// repository, and decrypts them for the relay. Entries stored before
+// encryption was turned on are read as they are.
+type EncryptedOutboxRepository struct {
+	OutboxRepository
+	cipher *fieldcrypt.Cipher
+}
+
+// NewEncryptedOutboxRepository wraps entries with payload encryption
+func NewEncryptedOutboxRepository(entries OutboxRepository, cipher *fieldcrypt.Cipher) *EncryptedOutboxRepository {
+	return &EncryptedOutboxRepository{OutboxRepository: entries, cipher: cipher}
+}
+
+// Add stores entries encrypted, leaving the caller's entries as they were
+// apart from their assigned IDs. IDs are assigned first, as each payload is
+// encrypted for its entry.
+func (r *EncryptedOutboxRepository) Add(ctx context.Context, entries ...*models.OutboxEntry) error {
+	sealed := make([]*models.OutboxEntry, len(entries))
+	for i, entry := range entries {
+		if entry.ID.IsZero() {
+			entry.ID = primitive.NewObjectID()
+		}
+		payload, err := r.cipher.Encrypt(string(entry.Payload), outboxContext(entry.ID))
+		if err != nil {
+			return err
+		}
+		copied := *entry
+		copied.Payload = []byte(payload)
+		sealed[i] = &copied
+	}
+	return r.OutboxRepository.Add(ctx, sealed...)
+}
+
+// Pending returns up to limit undelivered entries, oldest first, decrypted
+func (r *EncryptedOutboxRepository) Pending(ctx context.Context, limit int) ([]models.OutboxEntry, error) {
+	entries, err := r.OutboxRepository.Pending(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entry := &entries[i]
+		payload, err := r.cipher.Decrypt(string(entry.Payload), outboxContext(entry.ID))
+		if err != nil {
+			err = fmt.Errorf("outbox entry %s: %w", entry.ID.Hex(), err)
+			log.Println("Couldn't decrypt", err)
+			return nil, err
+		}
+		entry.Payload = []byte(payload)
+	}
+	return entries, nil
+}
+
+// sealTodo encrypts the todo's fields for the todo with the given ID
+func sealTodo(cipher *fieldcrypt.Cipher, id primitive.ObjectID, todo *models.Todo) error {
+	var err error
+	if todo.Title, err = cipher.Encrypt(todo.Title, fieldContext(id, "title")); err != nil {
+		return err
+	}
+	if todo.Description, err = cipher.Encrypt(todo.Description, fieldContext(id, "description")); err != nil {
+		return err
+	}
+	// The caller's checklist and comments are left as they were
+	todo.Checklist = slices.Clone(todo.Checklist)
+	for i := range todo.Checklist {
+		item := &todo.Checklist[i]
+		if item.Title, err = cipher.Encrypt(item.Title, checklistContext(id, item.ID)); err != nil {
+			return err
+		}
+	}
+	todo.Comments = slices.Clone(todo.Comments)
+	for i := range todo.Comments {
+		comment := &todo.Comments[i]
+		if comment.Body, err = cipher.Encrypt(comment.Body, commentContext(id, comment.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openTodo decrypts the todo's fields
+func openTodo(cipher *fieldcrypt.Cipher, id primitive.ObjectID, todo *models.Todo) error {
+	var err error
+	if todo.Title, err = cipher.Decrypt(todo.Title, fieldContext(id, "title")); err != nil {
+		return decryptFailed(id, err)
+	}
+	if todo.Description, err = cipher.Decrypt(todo.Description, fieldContext(id, "description")); err != nil {
+		return decryptFailed(id, err)
+	}
+	// Checklists and comments may share their array with the store's copy
+	todo.Checklist = slices.Clone(todo.Checklist)
+	for i := range todo.Checklist {
+		item := &todo.Checklist[i]
+		if item.Title, err = cipher.Decrypt(item.Title, checklistContext(id, item.ID)); err != nil {
+			return decryptFailed(id, err)
+		}
+	}
+	todo.Comments = slices.Clone(todo.Comments)
+	for i := range todo.Comments {
+		comment := &todo.Comments[i]
+		if comment.Body, err = cipher.Decrypt(comment.Body, commentContext(id, comment.ID)); err != nil {
+			return decryptFailed(id, err)
+		}
+	}
+	return nil
+}
+
+// decryptFailed logs a value that couldn't be decrypted, which takes an
+// operator to fix, such as by configuring a key again
+func decryptFailed(id primitive.ObjectID, err error) error {
+	err = fmt.Errorf("todo %s: %w", id.Hex(), err)
+	log.Println("Couldn't decrypt", err)
+	return err
+}
+
+// fieldContext binds an encrypted value to its todo and field
+func fieldContext(id primitive.ObjectID, field string) string {
+	return "todos/" + id.Hex() + "/" + field
+}
+
+// checklistContext binds an encrypted checklist item to its todo and item
+func checklistContext(id, itemID primitive.ObjectID) string {
+	return fieldContext(id, "checklist/"+itemID.Hex())
+}
+
+// commentContext binds an encrypted comment to its todo and comment
+func commentContext(id, commentID primitive.ObjectID) string {
+	return fieldContext(id, "comments/"+commentID.Hex())
+}
+
+// outboxContext binds an encrypted payload to its outbox entry
+func outboxContext(id primitive.ObjectID) string {
+	return "outbox/" + id.Hex()
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"slices"
+	"time"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListFilter narrows the todos of a scope down to the ones a listing shows,
+// for the filters the backends can apply themselves. The zero value keeps
+// every todo.
+type ListFilter struct {
+	// AwakeAt leaves out the todos snoozed past it, unless it's zero
+	AwakeAt time.Time
+	// Unarchived leaves out archived todos
+	Unarchived bool
+	// Pinned keeps the todos pinned or not, unless it's nil
+	Pinned *bool
+	// CompletedAfter keeps the todos completed at or after it, unless it's
+	// nil
+	CompletedAfter *time.Time
+	// AssigneeID keeps the todos assigned to it, or the unassigned ones
+	// when it's empty, unless it's nil
+	AssigneeID *string
+	// Tag keeps the todos with the tag, unless it's empty
+	Tag string
+	// IDs keeps the todos with these IDs, unless it's nil
+	IDs []primitive.ObjectID
+}
+
+// matches reports whether the filter keeps todo, for the backends that
+// filter todos once they're read
+func (f *ListFilter) matches(todo *models.Todo) bool {
+	switch {
+	case !f.AwakeAt.IsZero() && todo.Snoozed(f.AwakeAt):
+	case f.Unarchived && todo.Archived():
+	case f.Pinned != nil && todo.Pinned != *f.Pinned:
+	case f.CompletedAfter != nil && (!todo.Completed || todo.CompletedAt == nil || todo.CompletedAt.Before(*f.CompletedAfter)):
+	case f.AssigneeID != nil && todo.AssigneeID != *f.AssigneeID:
+	case f.Tag != "" && !todo.HasTag(f.Tag):
+	case f.IDs != nil && !slices.Contains(f.IDs, todo.ID):
+	default:
+		return true
+	}
+	return false
+}
+
+// filterIn builds a Mongo filter matching the todos in the scope that the
+// filter keeps
+func (f *ListFilter) filterIn(scope Scope) bson.M {
+	filter := inScope(scope)
+	if !f.AwakeAt.IsZero() {
+		filter["snoozed_until"] = bson.M{"$not": bson.M{"$gt": f.AwakeAt}}
+	}
+	if f.Unarchived {
+		filter["archived_at"] = nil
+	}
+	if f.Pinned != nil {
+		// Todos stored before pinning existed have no pinned field
+		filter["pinned"] = bson.M{"$ne": !*f.Pinned}
+	}
+	if f.CompletedAfter != nil {
+		filter["completed"] = true
+		filter["completed_at"] = bson.M{"$gte": *f.CompletedAfter}
+	}
+	if f.AssigneeID != nil {
+		if *f.AssigneeID == "" {
+			filter["assignee_id"] = nil
+		} else {
+			filter["assignee_id"] = *f.AssigneeID
+		}
+	}
+	if f.Tag != "" {
+		filter["tags"] = f.Tag
+	}
+	if f.IDs != nil {
+		filter["_id"] = bson.M{"$in": f.IDs}
+	}
+	return filter
+}
+
+// groupByDueDay keys todos sorted by due date by their due date in loc,
+// for the backends that can't group by day in the database
+func groupByDueDay(todos []models.Todo, loc *time.Location) map[string][]models.Todo {
+	days := map[string][]models.Todo{}
+	for _, todo := range todos {
+		date := todo.DueDate.In(loc).Format(time.DateOnly)
+		days[date] = append(days[date], todo)
+	}
+	return days
+}
+
+// countFacets counts the facets of the todos of one scope that the filter
+// keeps, for the backends that count in Go. A todo is blocked by open
+// todos among all of them, kept or not.
+func countFacets(todos []models.Todo, filter ListFilter) *models.TodoFacets {
+	open := make(map[primitive.ObjectID]bool, len(todos))
+	for _, todo := range todos {
+		open[todo.ID] = !todo.Completed
+	}
+	facets := models.NewTodoFacets()
+	for _, todo := range todos {
+		if !filter.matches(&todo) {
+			continue
+		}
+		todo.Blocked = slices.ContainsFunc(todo.BlockedBy, func(id primitive.ObjectID) bool {
+			return open[id]
+		})
+		facets.Add(&todo)
+	}
+	return facets
+}
@@ -0,0 +1,1460 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-api/authz"
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryTodoRepository keeps todos in process memory. It's meant for local
+// development and tests; everything is lost when the process exits.
+type MemoryTodoRepository struct {
+	mu    sync.RWMutex
+	todos map[primitive.ObjectID]models.Todo
+}
+
+// NewMemoryTodoRepository creates an empty in-memory repository
+func NewMemoryTodoRepository() *MemoryTodoRepository {
+	return &MemoryTodoRepository{todos: make(map[primitive.ObjectID]models.Todo)}
+}
+
+// List returns all todos in the scope in creation order
+func (r *MemoryTodoRepository) List(ctx context.Context, scope Scope) ([]models.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var todos []models.Todo
+	for _, todo := range r.todos {
+		if scope.Contains(&todo) {
+			todos = append(todos, todo)
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		return todos[i].CreatedAt.Before(todos[j].CreatedAt)
+	})
+	return todos, nil
+}
+
+// ListFields returns the todos in the scope in creation order with only
+// the named fields
+func (r *MemoryTodoRepository) ListFields(ctx context.Context, scope Scope, fields []string) ([]models.Todo, error) {
+	todos, err := r.List(ctx, scope)
+	project(todos, fields)
+	return todos, err
+}
+
+// Get returns a single todo in the scope
+func (r *MemoryTodoRepository) Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todo, ok := r.todos[id]
+	if !ok || !scope.Contains(&todo) {
+		return nil, ErrNotFound
+	}
+	return &todo, nil
+}
+
+// Create stores a new todo, assigning an ID unless one is already set
+func (r *MemoryTodoRepository) Create(ctx context.Context, todo *models.Todo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if todo.ID.IsZero() {
+		todo.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.todos[todo.ID]; exists {
+		return ErrDuplicate
+	}
+	r.todos[todo.ID] = *todo
+	return nil
+}
+
+// Update replaces an existing todo within its own scope
+func (r *MemoryTodoRepository) Update(ctx context.Context, todo *models.Todo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.todos[todo.ID]
+	if !ok || !ScopeOf(todo).Contains(&existing) {
+		return ErrNotFound
+	}
+	r.todos[todo.ID] = *todo
+	return nil
+}
+
+// UpdateIfUnchanged replaces a todo unless it was updated since lastUpdated
+func (r *MemoryTodoRepository) UpdateIfUnchanged(ctx context.Context, todo *models.Todo, lastUpdated time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.todos[todo.ID]
+	if !ok || !ScopeOf(todo).Contains(&existing) {
+		return ErrNotFound
+	}
+	if !existing.UpdatedAt.Equal(lastUpdated) {
+		return ErrConflict
+	}
+	r.todos[todo.ID] = *todo
+	return nil
+}
+
+// Delete removes a todo in the scope
+func (r *MemoryTodoRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, ok := r.todos[id]
+	if !ok || !scope.Contains(&todo) {
+		return ErrNotFound
+	}
+	delete(r.todos, id)
+	return nil
+}
+
+// DeleteAll removes every todo in the scope
+func (r *MemoryTodoRepository) DeleteAll(ctx context.Context, scope Scope) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, todo := range r.todos {
+		if scope.Contains(&todo) {
+			delete(r.todos, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// ReplaceTag replaces a tag on every todo in the scope
+func (r *MemoryTodoRepository) ReplaceTag(ctx context.Context, scope Scope, from, to string, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var changed int64
+	for id, todo := range r.todos {
+		if scope.Contains(&todo) && todo.ReplaceTag(from, to) {
+			todo.UpdatedAt = now
+			r.todos[id] = todo
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// Count returns the number of todos in the scope
+func (r *MemoryTodoRepository) Count(ctx context.Context, scope Scope) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var n int64
+	for _, todo := range r.todos {
+		if scope.Contains(&todo) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// CountMatching returns the number of todos in the scope the filter keeps
+func (r *MemoryTodoRepository) CountMatching(ctx context.Context, scope Scope, filter ListFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var n int64
+	for _, todo := range r.todos {
+		if scope.Contains(&todo) && filter.matches(&todo) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Facets counts the todos in the scope the filter keeps
+func (r *MemoryTodoRepository) Facets(ctx context.Context, scope Scope, filter ListFilter) (*models.TodoFacets, error) {
+	todos, err := r.List(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	return countFacets(todos, filter), nil
+}
+
+// CountAll returns the number of todos of all users and workspaces
+func (r *MemoryTodoRepository) CountAll(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.todos)), nil
+}
+
+// ListPage returns up to limit todos in the scope after the given ID, by ID
+func (r *MemoryTodoRepository) ListPage(ctx context.Context, scope Scope, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var todos []models.Todo
+	for id, todo := range r.todos {
+		if id.Hex() > after.Hex() && scope.Contains(&todo) {
+			todos = append(todos, todo)
+		}
+	}
+	slices.SortFunc(todos, func(a, b models.Todo) int { return strings.Compare(a.ID.Hex(), b.ID.Hex()) })
+	if len(todos) > limit {
+		todos = todos[:limit]
+	}
+	return todos, nil
+}
+
+// ListChanged returns up to limit todos updated at or after since, by ID
+func (r *MemoryTodoRepository) ListChanged(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var todos []models.Todo
+	for id, todo := range r.todos {
+		if id.Hex() > after.Hex() && !todo.UpdatedAt.Before(since) {
+			todos = append(todos, todo)
+		}
+	}
+	slices.SortFunc(todos, func(a, b models.Todo) int { return strings.Compare(a.ID.Hex(), b.ID.Hex()) })
+	if len(todos) > limit {
+		todos = todos[:limit]
+	}
+	return todos, nil
+}
+
+// ListDueByDay returns the todos in the scope due in [from, to) keyed by
+// their due date in loc
+func (r *MemoryTodoRepository) ListDueByDay(ctx context.Context, scope Scope, from, to time.Time, loc *time.Location) (map[string][]models.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var todos []models.Todo
+	for _, todo := range r.todos {
+		if scope.Contains(&todo) && todo.DueDate != nil && !todo.DueDate.Before(from) && todo.DueDate.Before(to) {
+			todos = append(todos, todo)
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		return todos[i].DueDate.Before(*todos[j].DueDate)
+	})
+	return groupByDueDay(todos, loc), nil
+}
+
+// ListNear returns the todos in the scope located within radius meters of
+// center, nearest first
+func (r *MemoryTodoRepository) ListNear(ctx context.Context, scope Scope, center *models.Location, radius float64) ([]models.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var todos []models.Todo
+	for _, todo := range r.todos {
+		if scope.Contains(&todo) {
+			todos = append(todos, todo)
+		}
+	}
+	return nearest(todos, center, radius), nil
+}
+
+// Stats summarises the todos in the scope
+func (r *MemoryTodoRepository) Stats(ctx context.Context, scope Scope, since, overdueBefore time.Time, loc *time.Location) (*models.TodoStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := &models.TodoStats{}
+	perDay := map[string]int64{}
+	var recent int64
+	var took time.Duration
+	for _, todo := range r.todos {
+		if !scope.Contains(&todo) {
+			continue
+		}
+		stats.Total++
+		if !todo.Completed {
+			if todo.DueDate != nil && todo.DueDate.Before(overdueBefore) {
+				stats.Overdue++
+			}
+			continue
+		}
+		stats.Completed++
+		// Todos completed before completed_at was recorded fall back to
+		// their last update
+		done := todo.UpdatedAt
+		if todo.CompletedAt != nil {
+			done = *todo.CompletedAt
+		}
+		if done.Before(since) {
+			continue
+		}
+		perDay[done.In(loc).Format(time.DateOnly)]++
+		recent++
+		took += done.Sub(todo.CreatedAt)
+	}
+	stats.Open = stats.Total - stats.Completed
+
+	for date, n := range perDay {
+		stats.CompletedPerDay = append(stats.CompletedPerDay, models.DayCount{Date: date, Count: n})
+	}
+	sort.Slice(stats.CompletedPerDay, func(i, j int) bool {
+		return stats.CompletedPerDay[i].Date < stats.CompletedPerDay[j].Date
+	})
+	if recent > 0 {
+		avg := took.Seconds() / float64(recent)
+		stats.AverageCompletionSeconds = &avg
+	}
+	return stats, nil
+}
+
+// MemoryUserRepository keeps user activity in process memory
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[string]models.User
+	// digests holds when each user's last digest was due, resets when
+	// their last daily reset was, and nudges when their last nudge about
+	// stale todos was
+	digests map[string]time.Time
+	resets  map[string]time.Time
+	nudges  map[string]time.Time
+}
+
+// NewMemoryUserRepository creates an empty in-memory user repository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[string]models.User), digests: make(map[string]time.Time), resets: make(map[string]time.Time), nudges: make(map[string]time.Time)}
+}
+
+// Touch records that the user was seen at the given time
+func (r *MemoryUserRepository) Touch(ctx context.Context, userID string, seen time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		user = models.User{ID: userID, CreatedAt: seen}
+	}
+	user.LastSeen = seen
+	r.users[userID] = user
+	return nil
+}
+
+// ListInactive returns up to limit users not seen since before
+func (r *MemoryUserRepository) ListInactive(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []string
+	for id, user := range r.users {
+		if len(ids) == limit {
+			break
+		}
+		if user.LastSeen.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// List returns up to limit users ordered by ID, starting after the given ID
+func (r *MemoryUserRepository) List(ctx context.Context, after string, limit int) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []models.User
+	for id, user := range r.users {
+		if id > after {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// Count returns the number of users
+func (r *MemoryUserRepository) Count(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.users)), nil
+}
+
+// GetSettings returns the user's settings
+func (r *MemoryUserRepository) GetSettings(ctx context.Context, userID string) (models.UserSettings, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.users[userID].Settings, nil
+}
+
+// SaveSettings replaces the user's settings, creating the record if needed
+func (r *MemoryUserRepository) SaveSettings(ctx context.Context, userID string, settings models.UserSettings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		now := time.Now()
+		user = models.User{ID: userID, CreatedAt: now, LastSeen: now}
+	}
+	user.Settings = settings
+	r.users[userID] = user
+	return nil
+}
+
+// ClaimDigest records that the user's digest due at due is being sent
+func (r *MemoryUserRepository) ClaimDigest(ctx context.Context, userID string, due time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return false, nil
+	}
+	if last, ok := r.digests[userID]; ok && !last.Before(due) {
+		return false, nil
+	}
+	r.digests[userID] = due
+	return true, nil
+}
+
+// ClaimDailyReset records that the user's daily reset due at due is being
+// run
+func (r *MemoryUserRepository) ClaimDailyReset(ctx context.Context, userID string, due time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return false, nil
+	}
+	if last, ok := r.resets[userID]; ok && !last.Before(due) {
+		return false, nil
+	}
+	r.resets[userID] = due
+	return true, nil
+}
+
+// ClaimStaleNudge records that the user's nudge about stale todos due at
+// due is being sent
+func (r *MemoryUserRepository) ClaimStaleNudge(ctx context.Context, userID string, due time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return false, nil
+	}
+	if last, ok := r.nudges[userID]; ok && !last.Before(due) {
+		return false, nil
+	}
+	r.nudges[userID] = due
+	return true, nil
+}
+
+// Delete removes the user's record
+func (r *MemoryUserRepository) Delete(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.users, userID)
+	delete(r.digests, userID)
+	delete(r.resets, userID)
+	delete(r.nudges, userID)
+	return nil
+}
+
+// MemorySessionRepository keeps sessions in process memory
+type MemorySessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[string]models.Session
+}
+
+// NewMemorySessionRepository creates an empty in-memory session repository
+func NewMemorySessionRepository() *MemorySessionRepository {
+	return &MemorySessionRepository{sessions: make(map[string]models.Session)}
+}
+
+// Create stores a new session
+func (r *MemorySessionRepository) Create(ctx context.Context, session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.sessions[session.ID]; exists {
+		return ErrDuplicate
+	}
+	r.sessions[session.ID] = *session
+	return nil
+}
+
+// Get returns an unexpired session by ID
+func (r *MemorySessionRepository) Get(ctx context.Context, id string) (*models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok || !session.ExpiresAt.After(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+// ListByUser returns the user's unexpired sessions, most recently created first
+func (r *MemorySessionRepository) ListByUser(ctx context.Context, userID string) ([]models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var sessions []models.Session
+	for id, session := range r.sessions {
+		if !session.ExpiresAt.After(now) {
+			// Nothing else removes expired sessions
+			delete(r.sessions, id)
+			continue
+		}
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// Touch records that the session was used at the given time
+func (r *MemorySessionRepository) Touch(ctx context.Context, id string, seen time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, ok := r.sessions[id]; ok {
+		session.LastSeen = seen
+		r.sessions[id] = session
+	}
+	return nil
+}
+
+// Delete revokes a session owned by the user
+func (r *MemorySessionRepository) Delete(ctx context.Context, userID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok || session.UserID != userID {
+		return ErrNotFound
+	}
+	delete(r.sessions, id)
+	return nil
+}
+
+// DeleteByUser revokes all of the user's sessions
+func (r *MemorySessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, session := range r.sessions {
+		if session.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+// MemoryWorkspaceRepository keeps workspaces in process memory
+type MemoryWorkspaceRepository struct {
+	mu         sync.RWMutex
+	workspaces map[primitive.ObjectID]models.Workspace
+}
+
+// NewMemoryWorkspaceRepository creates an empty in-memory workspace repository
+func NewMemoryWorkspaceRepository() *MemoryWorkspaceRepository {
+	return &MemoryWorkspaceRepository{workspaces: make(map[primitive.ObjectID]models.Workspace)}
+}
+
+// Create stores a new workspace, assigning its ID
+func (r *MemoryWorkspaceRepository) Create(ctx context.Context, workspace *models.Workspace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if workspace.ID.IsZero() {
+		workspace.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.workspaces[workspace.ID]; exists {
+		return ErrDuplicate
+	}
+	r.workspaces[workspace.ID] = cloneWorkspace(*workspace)
+	return nil
+}
+
+// Get returns a workspace by ID
+func (r *MemoryWorkspaceRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	workspace, ok := r.workspaces[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	workspace = cloneWorkspace(workspace)
+	return &workspace, nil
+}
+
+// ListByMember returns the workspaces the user belongs to, oldest first
+func (r *MemoryWorkspaceRepository) ListByMember(ctx context.Context, userID string) ([]models.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var workspaces []models.Workspace
+	for _, workspace := range r.workspaces {
+		if workspace.HasMember(userID) {
+			workspaces = append(workspaces, cloneWorkspace(workspace))
+		}
+	}
+	sort.Slice(workspaces, func(i, j int) bool {
+		return workspaces[i].CreatedAt.Before(workspaces[j].CreatedAt)
+	})
+	return workspaces, nil
+}
+
+// AddMember adds a member unless they already belong to the workspace
+func (r *MemoryWorkspaceRepository) AddMember(ctx context.Context, id primitive.ObjectID, member models.Member) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	workspace, ok := r.workspaces[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if workspace.HasMember(member.UserID) {
+		return ErrDuplicate
+	}
+	workspace = cloneWorkspace(workspace)
+	workspace.Members = append(workspace.Members, member)
+	workspace.UpdatedAt = time.Now()
+	r.workspaces[id] = workspace
+	return nil
+}
+
+// SetMemberRole changes a member's role
+func (r *MemoryWorkspaceRepository) SetMemberRole(ctx context.Context, id primitive.ObjectID, userID string, role authz.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	workspace, ok := r.workspaces[id]
+	if !ok {
+		return ErrNotFound
+	}
+	workspace = cloneWorkspace(workspace)
+	for i := range workspace.Members {
+		if workspace.Members[i].UserID == userID {
+			workspace.Members[i].Role = role
+			workspace.UpdatedAt = time.Now()
+			r.workspaces[id] = workspace
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// RemoveMember removes a member from the workspace
+func (r *MemoryWorkspaceRepository) RemoveMember(ctx context.Context, id primitive.ObjectID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	workspace, ok := r.workspaces[id]
+	if !ok || !workspace.HasMember(userID) {
+		return ErrNotFound
+	}
+	members := make([]models.Member, 0, len(workspace.Members)-1)
+	for _, m := range workspace.Members {
+		if m.UserID != userID {
+			members = append(members, m)
+		}
+	}
+	workspace.Members = members
+	workspace.UpdatedAt = time.Now()
+	r.workspaces[id] = workspace
+	return nil
+}
+
+// Update saves the workspace's name and color
+func (r *MemoryWorkspaceRepository) Update(ctx context.Context, workspace *models.Workspace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.workspaces[workspace.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Name = workspace.Name
+	stored.Color = workspace.Color
+	stored.UpdatedAt = workspace.UpdatedAt
+	r.workspaces[workspace.ID] = stored
+	return nil
+}
+
+// Delete removes the workspace
+func (r *MemoryWorkspaceRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.workspaces[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.workspaces, id)
+	return nil
+}
+
+// cloneWorkspace copies the members slice so callers can't modify the
+// stored workspace through it
+func cloneWorkspace(workspace models.Workspace) models.Workspace {
+	workspace.Members = append([]models.Member(nil), workspace.Members...)
+	return workspace
+}
+
+// MemoryShareRepository keeps todo shares in process memory
+type MemoryShareRepository struct {
+	mu     sync.RWMutex
+	shares map[primitive.ObjectID]models.Share
+}
+
+// NewMemoryShareRepository creates an empty in-memory share repository
+func NewMemoryShareRepository() *MemoryShareRepository {
+	return &MemoryShareRepository{shares: make(map[primitive.ObjectID]models.Share)}
+}
+
+// Save creates the share, or updates the role of the existing share
+// with the same grantee
+func (r *MemoryShareRepository) Save(ctx context.Context, share *models.Share) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, existing := range r.shares {
+		if existing.TodoID == share.TodoID && existing.Grantee == share.Grantee {
+			existing.Role = share.Role
+			existing.UpdatedAt = share.UpdatedAt
+			r.shares[id] = existing
+			*share = existing
+			return nil
+		}
+	}
+	if share.ID.IsZero() {
+		share.ID = primitive.NewObjectID()
+	}
+	r.shares[share.ID] = *share
+	return nil
+}
+
+// ListByTodo returns the shares of one of the owner's todos, oldest first
+func (r *MemoryShareRepository) ListByTodo(ctx context.Context, ownerID string, todoID primitive.ObjectID) ([]models.Share, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var shares []models.Share
+	for _, share := range r.shares {
+		if share.TodoID == todoID && share.OwnerID == ownerID {
+			shares = append(shares, share)
+		}
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].CreatedAt.Before(shares[j].CreatedAt)
+	})
+	return shares, nil
+}
+
+// ListByGrantee returns the shares granted to any of the grantee keys,
+// newest first
+func (r *MemoryShareRepository) ListByGrantee(ctx context.Context, grantees []string) ([]models.Share, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var shares []models.Share
+	for _, share := range r.shares {
+		for _, grantee := range grantees {
+			if share.Grantee == grantee {
+				shares = append(shares, share)
+				break
+			}
+		}
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].CreatedAt.After(shares[j].CreatedAt)
+	})
+	return shares, nil
+}
+
+// Delete removes one of the todo's shares
+func (r *MemoryShareRepository) Delete(ctx context.Context, todoID, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	share, ok := r.shares[id]
+	if !ok || share.TodoID != todoID {
+		return ErrNotFound
+	}
+	delete(r.shares, id)
+	return nil
+}
+
+// DeleteByTodo removes every share of a todo
+func (r *MemoryShareRepository) DeleteByTodo(ctx context.Context, todoID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, share := range r.shares {
+		if share.TodoID == todoID {
+			delete(r.shares, id)
+		}
+	}
+	return nil
+}
+
+// DeleteByOwner removes every share the owner made
+func (r *MemoryShareRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, share := range r.shares {
+		if share.OwnerID == ownerID {
+			delete(r.shares, id)
+		}
+	}
+	return nil
+}
+
+// MemoryPublicLinkRepository keeps public links in process memory
+type MemoryPublicLinkRepository struct {
+	mu    sync.RWMutex
+	links map[string]models.PublicLink
+}
+
+// NewMemoryPublicLinkRepository creates an empty in-memory public link repository
+func NewMemoryPublicLinkRepository() *MemoryPublicLinkRepository {
+	return &MemoryPublicLinkRepository{links: make(map[string]models.PublicLink)}
+}
+
+// Replace stores the link, revoking any earlier link to the same todo
+func (r *MemoryPublicLinkRepository) Replace(ctx context.Context, link *models.PublicLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, existing := range r.links {
+		if existing.TodoID == link.TodoID {
+			delete(r.links, hash)
+		}
+	}
+	if _, exists := r.links[link.TokenHash]; exists {
+		return ErrDuplicate
+	}
+	r.links[link.TokenHash] = *link
+	return nil
+}
+
+// Get returns the link with the given token hash
+func (r *MemoryPublicLinkRepository) Get(ctx context.Context, tokenHash string) (*models.PublicLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	link, ok := r.links[tokenHash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &link, nil
+}
+
+// Delete revokes the link to one of the owner's todos
+func (r *MemoryPublicLinkRepository) Delete(ctx context.Context, ownerID string, todoID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, link := range r.links {
+		if link.TodoID == todoID && link.OwnerID == ownerID {
+			delete(r.links, hash)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// DeleteByOwner revokes every link the owner made
+func (r *MemoryPublicLinkRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, link := range r.links {
+		if link.OwnerID == ownerID {
+			delete(r.links, hash)
+		}
+	}
+	return nil
+}
+
+// MemoryCustomFieldRepository keeps custom field definitions in process
+// memory
+type MemoryCustomFieldRepository struct {
+	mu     sync.RWMutex
+	fields map[primitive.ObjectID]models.CustomField
+}
+
+// NewMemoryCustomFieldRepository creates an empty in-memory custom field repository
+func NewMemoryCustomFieldRepository() *MemoryCustomFieldRepository {
+	return &MemoryCustomFieldRepository{fields: make(map[primitive.ObjectID]models.CustomField)}
+}
+
+// List returns the fields defined in the scope, oldest first
+func (r *MemoryCustomFieldRepository) List(ctx context.Context, scope Scope) ([]models.CustomField, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var fields []models.CustomField
+	for _, field := range r.fields {
+		if ScopeOfField(&field) == scope {
+			fields = append(fields, field)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].CreatedAt.Before(fields[j].CreatedAt)
+	})
+	return fields, nil
+}
+
+// Create stores a new field, assigning its ID
+func (r *MemoryCustomFieldRepository) Create(ctx context.Context, field *models.CustomField) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if field.ID.IsZero() {
+		field.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.fields[field.ID]; exists {
+		return ErrDuplicate
+	}
+	r.fields[field.ID] = *field
+	return nil
+}
+
+// Delete removes a field in the scope
+func (r *MemoryCustomFieldRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	field, ok := r.fields[id]
+	if !ok || ScopeOfField(&field) != scope {
+		return ErrNotFound
+	}
+	delete(r.fields, id)
+	return nil
+}
+
+// DeleteAll removes every field in the scope
+func (r *MemoryCustomFieldRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, field := range r.fields {
+		if ScopeOfField(&field) == scope {
+			delete(r.fields, id)
+		}
+	}
+	return nil
+}
+
+// MemorySavedFilterRepository keeps saved filters in process memory
+type MemorySavedFilterRepository struct {
+	mu      sync.RWMutex
+	filters map[primitive.ObjectID]models.SavedFilter
+}
+
+// NewMemorySavedFilterRepository creates an empty in-memory saved filter repository
+func NewMemorySavedFilterRepository() *MemorySavedFilterRepository {
+	return &MemorySavedFilterRepository{filters: make(map[primitive.ObjectID]models.SavedFilter)}
+}
+
+// List returns the filters saved in the scope, oldest first
+func (r *MemorySavedFilterRepository) List(ctx context.Context, scope Scope) ([]models.SavedFilter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var filters []models.SavedFilter
+	for _, filter := range r.filters {
+		if ScopeOfFilter(&filter) == scope {
+			filters = append(filters, cloneFilter(filter))
+		}
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		return filters[i].CreatedAt.Before(filters[j].CreatedAt)
+	})
+	return filters, nil
+}
+
+// Get returns a filter in the scope or ErrNotFound
+func (r *MemorySavedFilterRepository) Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.SavedFilter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	filter, ok := r.filters[id]
+	if !ok || ScopeOfFilter(&filter) != scope {
+		return nil, ErrNotFound
+	}
+	filter = cloneFilter(filter)
+	return &filter, nil
+}
+
+// Create stores a new filter, assigning its ID
+func (r *MemorySavedFilterRepository) Create(ctx context.Context, filter *models.SavedFilter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if filter.ID.IsZero() {
+		filter.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.filters[filter.ID]; exists {
+		return ErrDuplicate
+	}
+	r.filters[filter.ID] = cloneFilter(*filter)
+	return nil
+}
+
+// Update replaces a stored filter, returning ErrNotFound if it's gone
+func (r *MemorySavedFilterRepository) Update(ctx context.Context, filter *models.SavedFilter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.filters[filter.ID]
+	if !ok || ScopeOfFilter(&stored) != ScopeOfFilter(filter) {
+		return ErrNotFound
+	}
+	r.filters[filter.ID] = cloneFilter(*filter)
+	return nil
+}
+
+// Delete removes a filter in the scope
+func (r *MemorySavedFilterRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	filter, ok := r.filters[id]
+	if !ok || ScopeOfFilter(&filter) != scope {
+		return ErrNotFound
+	}
+	delete(r.filters, id)
+	return nil
+}
+
+// DeleteAll removes every filter in the scope
+func (r *MemorySavedFilterRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, filter := range r.filters {
+		if ScopeOfFilter(&filter) == scope {
+			delete(r.filters, id)
+		}
+	}
+	return nil
+}
+
+// cloneFilter copies a filter so callers can't change the stored one
+// through its pointers and slices
+func cloneFilter(filter models.SavedFilter) models.SavedFilter {
+	c := filter.Criteria
+	filter.Criteria.Priorities = slices.Clone(c.Priorities)
+	if c.Completed != nil {
+		v := *c.Completed
+		filter.Criteria.Completed = &v
+	}
+	if c.Pinned != nil {
+		v := *c.Pinned
+		filter.Criteria.Pinned = &v
+	}
+	if c.DueWithinDays != nil {
+		v := *c.DueWithinDays
+		filter.Criteria.DueWithinDays = &v
+	}
+	return filter
+}
+
+// MemoryRevisionRepository is an in-memory RevisionRepository, for tests and
+// single-process use
+type MemoryRevisionRepository struct {
+	mu        sync.RWMutex
+	revisions map[primitive.ObjectID][]models.Revision
+}
+
+// NewMemoryRevisionRepository creates an empty in-memory revision repository
+func NewMemoryRevisionRepository() *MemoryRevisionRepository {
+	return &MemoryRevisionRepository{revisions: make(map[primitive.ObjectID][]models.Revision)}
+}
+
+// List returns the todo's revisions, oldest first
+func (r *MemoryRevisionRepository) List(ctx context.Context, todoID primitive.ObjectID) ([]models.Revision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.revisions[todoID]), nil
+}
+
+// Create stores a revision, assigning its ID
+func (r *MemoryRevisionRepository) Create(ctx context.Context, revision *models.Revision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if revision.ID.IsZero() {
+		revision.ID = primitive.NewObjectID()
+	}
+	revisions := r.revisions[revision.TodoID]
+	if slices.ContainsFunc(revisions, func(existing models.Revision) bool { return existing.Number == revision.Number }) {
+		return ErrDuplicate
+	}
+	revisions = append(revisions, *revision)
+	slices.SortFunc(revisions, func(a, b models.Revision) int { return a.Number - b.Number })
+	r.revisions[revision.TodoID] = revisions
+	return nil
+}
+
+// DeleteBefore removes the todo's revisions numbered below number
+func (r *MemoryRevisionRepository) DeleteBefore(ctx context.Context, todoID primitive.ObjectID, number int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revisions[todoID] = slices.DeleteFunc(r.revisions[todoID], func(revision models.Revision) bool {
+		return revision.Number < number
+	})
+	return nil
+}
+
+// DeleteByTodo removes every revision of a todo
+func (r *MemoryRevisionRepository) DeleteByTodo(ctx context.Context, todoID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.revisions, todoID)
+	return nil
+}
+
+// DeleteAll removes the revisions of every todo in the scope
+func (r *MemoryRevisionRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for todoID, revisions := range r.revisions {
+		if len(revisions) > 0 && ScopeOfRevision(&revisions[0]) == scope {
+			delete(r.revisions, todoID)
+		}
+	}
+	return nil
+}
+
+// MemoryOutboxRepository is an in-memory OutboxRepository, for tests and
+// single-process use
+type MemoryOutboxRepository struct {
+	mu      sync.Mutex
+	entries []models.OutboxEntry
+}
+
+// NewMemoryOutboxRepository creates an empty in-memory outbox
+func NewMemoryOutboxRepository() *MemoryOutboxRepository {
+	return &MemoryOutboxRepository{}
+}
+
+// Add stores entries, assigning their IDs
+func (r *MemoryOutboxRepository) Add(ctx context.Context, entries ...*models.OutboxEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, entry := range entries {
+		if entry.ID.IsZero() {
+			entry.ID = primitive.NewObjectID()
+		}
+		if slices.ContainsFunc(r.entries, func(existing models.OutboxEntry) bool { return existing.ID == entry.ID }) {
+			continue
+		}
+		r.entries = append(r.entries, *entry)
+	}
+	return nil
+}
+
+// Pending returns up to limit undelivered entries, oldest first
+func (r *MemoryOutboxRepository) Pending(ctx context.Context, limit int) ([]models.OutboxEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pending []models.OutboxEntry
+	for _, entry := range r.entries {
+		if len(pending) == limit {
+			break
+		}
+		if entry.DeliveredAt == nil {
+			pending = append(pending, entry)
+		}
+	}
+	return pending, nil
+}
+
+// MarkDelivered records that the entries were published at the given time
+func (r *MemoryOutboxRepository) MarkDelivered(ctx context.Context, ids []primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.entries {
+		if slices.Contains(ids, r.entries[i].ID) {
+			r.entries[i].DeliveredAt = &at
+		}
+	}
+	return nil
+}
+
+// DeleteDelivered removes entries delivered before cutoff
+func (r *MemoryOutboxRepository) DeleteDelivered(ctx context.Context, cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before := len(r.entries)
+	r.entries = slices.DeleteFunc(r.entries, func(entry models.OutboxEntry) bool {
+		return entry.DeliveredAt != nil && entry.DeliveredAt.Before(cutoff)
+	})
+	return int64(before - len(r.entries)), nil
+}
+
+// MemoryTombstoneRepository is an in-memory TombstoneRepository, for tests
+// and single-process use
+type MemoryTombstoneRepository struct {
+	mu         sync.RWMutex
+	tombstones map[primitive.ObjectID]models.Tombstone
+}
+
+// NewMemoryTombstoneRepository creates an empty in-memory tombstone
+// repository
+func NewMemoryTombstoneRepository() *MemoryTombstoneRepository {
+	return &MemoryTombstoneRepository{tombstones: make(map[primitive.ObjectID]models.Tombstone)}
+}
+
+// Save records the deletion, replacing an earlier tombstone of the same todo
+func (r *MemoryTombstoneRepository) Save(ctx context.Context, tombstone *models.Tombstone) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tombstones[tombstone.TodoID] = *tombstone
+	return nil
+}
+
+// ListSince returns the scope's tombstones of todos deleted at or after since
+func (r *MemoryTombstoneRepository) ListSince(ctx context.Context, scope Scope, since time.Time) ([]models.Tombstone, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tombstones []models.Tombstone
+	for _, tombstone := range r.tombstones {
+		if ScopeOfTombstone(&tombstone) == scope && !tombstone.DeletedAt.Before(since) {
+			tombstones = append(tombstones, tombstone)
+		}
+	}
+	slices.SortFunc(tombstones, func(a, b models.Tombstone) int { return a.DeletedAt.Compare(b.DeletedAt) })
+	return tombstones, nil
+}
+
+// ListAllSince returns up to limit tombstones of todos deleted at or after
+// since, by todo ID
+func (r *MemoryTombstoneRepository) ListAllSince(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Tombstone, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tombstones []models.Tombstone
+	for id, tombstone := range r.tombstones {
+		if id.Hex() > after.Hex() && !tombstone.DeletedAt.Before(since) {
+			tombstones = append(tombstones, tombstone)
+		}
+	}
+	slices.SortFunc(tombstones, func(a, b models.Tombstone) int { return strings.Compare(a.TodoID.Hex(), b.TodoID.Hex()) })
+	if len(tombstones) > limit {
+		tombstones = tombstones[:limit]
+	}
+	return tombstones, nil
+}
+
+// DeleteBefore removes tombstones of todos deleted before cutoff
+func (r *MemoryTombstoneRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for id, tombstone := range r.tombstones {
+		if tombstone.DeletedAt.Before(cutoff) {
+			delete(r.tombstones, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// DeleteAll removes every tombstone in the scope
+func (r *MemoryTombstoneRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, tombstone := range r.tombstones {
+		if ScopeOfTombstone(&tombstone) == scope {
+			delete(r.tombstones, id)
+		}
+	}
+	return nil
+}
+
+// MemorySlackLinkRepository is an in-memory SlackLinkRepository, for tests
+// and single-process use
+type MemorySlackLinkRepository struct {
+	mu    sync.RWMutex
+	links map[string]models.SlackLink
+}
+
+// NewMemorySlackLinkRepository creates an empty in-memory Slack link
+// repository
+func NewMemorySlackLinkRepository() *MemorySlackLinkRepository {
+	return &MemorySlackLinkRepository{links: make(map[string]models.SlackLink)}
+}
+
+// Save stores the link, replacing the user's earlier link and any link of
+// the same Slack account
+func (r *MemorySlackLinkRepository) Save(ctx context.Context, link *models.SlackLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for userID, other := range r.links {
+		if other.TeamID == link.TeamID && other.SlackUserID == link.SlackUserID {
+			delete(r.links, userID)
+		}
+	}
+	r.links[link.UserID] = *link
+	return nil
+}
+
+// Get returns the user's link
+func (r *MemorySlackLinkRepository) Get(ctx context.Context, userID string) (*models.SlackLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	link, ok := r.links[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &link, nil
+}
+
+// GetBySlackUser returns the link of a Slack account
+func (r *MemorySlackLinkRepository) GetBySlackUser(ctx context.Context, teamID, slackUserID string) (*models.SlackLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, link := range r.links {
+		if link.TeamID == teamID && link.SlackUserID == slackUserID {
+			return &link, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// List returns up to limit links ordered by user ID, starting after the
+// given ID
+func (r *MemorySlackLinkRepository) List(ctx context.Context, after string, limit int) ([]models.SlackLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var links []models.SlackLink
+	for userID, link := range r.links {
+		if userID > after {
+			links = append(links, link)
+		}
+	}
+	slices.SortFunc(links, func(a, b models.SlackLink) int { return strings.Compare(a.UserID, b.UserID) })
+	if len(links) > limit {
+		links = links[:limit]
+	}
+	return links, nil
+}
+
+// ClaimOverdueCheck moves the user's OverdueCheckedAt forward to at
+func (r *MemorySlackLinkRepository) ClaimOverdueCheck(ctx context.Context, userID string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, ok := r.links[userID]
+	if !ok || !link.OverdueCheckedAt.Before(at) {
+		return false, nil
+	}
+	link.OverdueCheckedAt = at
+	r.links[userID] = link
+	return true, nil
+}
+
+// Delete removes the user's link
+func (r *MemorySlackLinkRepository) Delete(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.links, userID)
+	return nil
+}
+
+// MemoryAuditRepository is an in-memory AuditRepository, for tests and
+// single-process use
+type MemoryAuditRepository struct {
+	mu      sync.RWMutex
+	entries []models.AuditEntry
+}
+
+// NewMemoryAuditRepository creates an empty in-memory audit repository
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+// Add stores entries, assigning their IDs
+func (r *MemoryAuditRepository) Add(ctx context.Context, entries ...*models.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, entry := range entries {
+		if entry.ID.IsZero() {
+			entry.ID = primitive.NewObjectID()
+		}
+		r.entries = append(r.entries, *entry)
+	}
+	return nil
+}
+
+// List returns up to q.Limit entries selected by q, newest first
+func (r *MemoryAuditRepository) List(ctx context.Context, q models.AuditQuery) ([]models.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []models.AuditEntry
+	for _, entry := range r.entries {
+		if q.Matches(&entry) {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b models.AuditEntry) int { return strings.Compare(b.ID.Hex(), a.ID.Hex()) })
+	if len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+	return entries, nil
+}
+
+// DeleteBefore removes entries recorded before cutoff
+func (r *MemoryAuditRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before := len(r.entries)
+	r.entries = slices.DeleteFunc(r.entries, func(entry models.AuditEntry) bool {
+		return entry.Time.Before(cutoff)
+	})
+	return int64(before - len(r.entries)), nil
+}
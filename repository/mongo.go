@@ -0,0 +1,512 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// MongoTodoRepository stores todos in a MongoDB / Cosmos DB collection
+type MongoTodoRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoTodoRepository creates a repository backed by the given collection
+func NewMongoTodoRepository(collection *mongo.Collection) *MongoTodoRepository {
+	return &MongoTodoRepository{collection: collection}
+}
+
+// EnsureIndexes creates the indexes todos are listed by, including the
+// 2dsphere index nearby queries need
+func (r *MongoTodoRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}}},
+		{Keys: bson.D{{Key: "location", Value: "2dsphere"}}},
+		{Keys: bson.D{{Key: "updated_at", Value: 1}}},
+	})
+	return err
+}
+
+// List returns all todos in the scope
+func (r *MongoTodoRepository) List(ctx context.Context, scope Scope) ([]models.Todo, error) {
+	cursor, err := r.collection.Find(ctx, inScope(scope))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var todos []models.Todo
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// ListFields returns the todos in the scope with only the named fields
+// read from the database
+func (r *MongoTodoRepository) ListFields(ctx context.Context, scope Scope, fields []string) ([]models.Todo, error) {
+	cursor, err := r.collection.Find(ctx, inScope(scope), options.Find().SetProjection(todoProjection(fields)))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var todos []models.Todo
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// Get returns a single todo in the scope
+func (r *MongoTodoRepository) Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.Todo, error) {
+	var todo models.Todo
+	err := r.collection.FindOne(ctx, byID(scope, id)).Decode(&todo)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// Create stores a new todo, assigning an ID unless one is already set
+func (r *MongoTodoRepository) Create(ctx context.Context, todo *models.Todo) error {
+	if todo.ID.IsZero() {
+		todo.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, todo)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// Update replaces an existing todo within its own scope
+func (r *MongoTodoRepository) Update(ctx context.Context, todo *models.Todo) error {
+	result, err := r.collection.ReplaceOne(ctx, byID(ScopeOf(todo), todo.ID), todo)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateIfUnchanged replaces a todo in one write matching its last
+// update time. When nothing matches, the todo is looked up to tell a
+// deleted todo from a changed one.
+func (r *MongoTodoRepository) UpdateIfUnchanged(ctx context.Context, todo *models.Todo, lastUpdated time.Time) error {
+	filter := byID(ScopeOf(todo), todo.ID)
+	filter["updated_at"] = lastUpdated
+	result, err := r.collection.ReplaceOne(ctx, filter, todo)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+	n, err := r.collection.CountDocuments(ctx, byID(ScopeOf(todo), todo.ID))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
+// Delete removes a todo in the scope
+func (r *MongoTodoRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, byID(scope, id))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAll removes every todo in the scope
+func (r *MongoTodoRepository) DeleteAll(ctx context.Context, scope Scope) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, inScope(scope))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// ReplaceTag replaces a tag on every todo in the scope with two bulk
+// updates: one renaming the tag where the new one isn't there yet, keeping
+// its place, and one removing it from the rest
+func (r *MongoTodoRepository) ReplaceTag(ctx context.Context, scope Scope, from, to string, now time.Time) (int64, error) {
+	if from == to {
+		return 0, nil
+	}
+	var changed int64
+	if to != "" {
+		filter := inScope(scope)
+		filter["tags"] = bson.M{"$eq": from, "$ne": to}
+		result, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"tags.$": to, "updated_at": now}})
+		if err != nil {
+			return 0, err
+		}
+		changed = result.ModifiedCount
+	}
+	filter := inScope(scope)
+	filter["tags"] = from
+	result, err := r.collection.UpdateMany(ctx, filter, bson.M{"$pull": bson.M{"tags": from}, "$set": bson.M{"updated_at": now}})
+	if err != nil {
+		return changed, err
+	}
+	return changed + result.ModifiedCount, nil
+}
+
+// Count returns the number of todos in the scope
+func (r *MongoTodoRepository) Count(ctx context.Context, scope Scope) (int64, error) {
+	return r.collection.CountDocuments(ctx, inScope(scope))
+}
+
+// CountMatching returns the number of todos in the scope the filter keeps
+func (r *MongoTodoRepository) CountMatching(ctx context.Context, scope Scope, filter ListFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, filter.filterIn(scope))
+}
+
+// Facets counts the todos in the scope the filter keeps in a single
+// aggregation, a facet per field. Blockers are looked up among the
+// scope's todos, whether the filter keeps them or not.
+func (r *MongoTodoRepository) Facets(ctx context.Context, scope Scope, filter ListFilter) (*models.TodoFacets, error) {
+	count := func(field string) bson.A {
+		return bson.A{bson.M{"$group": bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}}}
+	}
+	blockers := inScope(scope)
+	blockers["$expr"] = bson.M{"$in": bson.A{"$_id", "$$blocked_by"}}
+	blockers["completed"] = false
+	pipeline := bson.A{
+		bson.M{"$match": filter.filterIn(scope)},
+		bson.M{"$facet": bson.M{
+			"status":   count("completed"),
+			"priority": count("priority"),
+			"color":    count("color"),
+			"tag":      append(bson.A{bson.M{"$unwind": "$tags"}}, count("tags")...),
+			"pinned": bson.A{
+				bson.M{"$match": bson.M{"pinned": true}},
+				bson.M{"$count": "count"},
+			},
+			"blocked": bson.A{
+				bson.M{"$match": bson.M{"blocked_by.0": bson.M{"$exists": true}}},
+				bson.M{"$lookup": bson.M{
+					"from":     r.collection.Name(),
+					"let":      bson.M{"blocked_by": "$blocked_by"},
+					"pipeline": bson.A{bson.M{"$match": blockers}, bson.M{"$limit": 1}},
+					"as":       "open_blockers",
+				}},
+				bson.M{"$match": bson.M{"open_blockers.0": bson.M{"$exists": true}}},
+				bson.M{"$count": "count"},
+			},
+		}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	type group struct {
+		Value any   `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	var result []struct {
+		Status   []group `bson:"status"`
+		Priority []group `bson:"priority"`
+		Color    []group `bson:"color"`
+		Tag      []group `bson:"tag"`
+		Pinned   []group `bson:"pinned"`
+		Blocked  []group `bson:"blocked"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	facets := models.NewTodoFacets()
+	if len(result) == 0 {
+		return facets, nil
+	}
+	// Missing fields group under nil
+	for _, g := range result[0].Status {
+		completed, _ := g.Value.(bool)
+		facets.AddStatus(completed, g.Count)
+	}
+	for _, g := range result[0].Priority {
+		p, _ := g.Value.(string)
+		facets.AddPriority(models.Priority(p), g.Count)
+	}
+	for _, g := range result[0].Color {
+		c, _ := g.Value.(string)
+		facets.AddColor(models.Color(c), g.Count)
+	}
+	for _, g := range result[0].Tag {
+		if tag, ok := g.Value.(string); ok {
+			facets.Tag[tag] += g.Count
+		}
+	}
+	if len(result[0].Pinned) > 0 {
+		facets.Pinned = result[0].Pinned[0].Count
+	}
+	if len(result[0].Blocked) > 0 {
+		facets.Blocked = result[0].Blocked[0].Count
+	}
+	return facets, nil
+}
+
+// CountAll estimates the number of todos from collection metadata, which
+// avoids scanning the whole collection
+func (r *MongoTodoRepository) CountAll(ctx context.Context) (int64, error) {
+	return r.collection.EstimatedDocumentCount(ctx)
+}
+
+// ListPage returns up to limit todos in the scope after the given ID, by ID
+func (r *MongoTodoRepository) ListPage(ctx context.Context, scope Scope, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	filter := inScope(scope)
+	filter["_id"] = bson.M{"$gt": after}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var todos []models.Todo
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// ListChanged returns up to limit todos updated at or after since, by ID
+func (r *MongoTodoRepository) ListChanged(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	filter := bson.M{"updated_at": bson.M{"$gte": since}, "_id": bson.M{"$gt": after}}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var todos []models.Todo
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// ListDueByDay returns the todos in the scope due in [from, to) keyed by
+// their due date in loc, grouped by the aggregation
+func (r *MongoTodoRepository) ListDueByDay(ctx context.Context, scope Scope, from, to time.Time, loc *time.Location) (map[string][]models.Todo, error) {
+	filter := inScope(scope)
+	filter["due_date"] = bson.M{"$gte": from, "$lt": to}
+	pipeline := bson.A{
+		bson.M{"$match": filter},
+		bson.M{"$sort": bson.D{{Key: "due_date", Value: 1}}},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$due_date", "timezone": loc.String()}},
+			"todos": bson.M{"$push": "$$ROOT"},
+		}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Date  string        `bson:"_id"`
+		Todos []models.Todo `bson:"todos"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	days := make(map[string][]models.Todo, len(result))
+	for _, day := range result {
+		days[day.Date] = day.Todos
+	}
+	return days, nil
+}
+
+// ListNear returns the todos in the scope located within radius meters of
+// center, nearest first. $nearSphere sorts by distance itself.
+func (r *MongoTodoRepository) ListNear(ctx context.Context, scope Scope, center *models.Location, radius float64) ([]models.Todo, error) {
+	filter := inScope(scope)
+	filter["location"] = bson.M{"$nearSphere": bson.M{"$geometry": center, "$maxDistance": radius}}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var todos []models.Todo
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// Stats summarises the todos in the scope in a single aggregation: one
+// facet counts todos by status, one counts overdue todos and the last
+// buckets recent completions by day
+func (r *MongoTodoRepository) Stats(ctx context.Context, scope Scope, since, overdueBefore time.Time, loc *time.Location) (*models.TodoStats, error) {
+	pipeline := bson.A{
+		bson.M{"$match": inScope(scope)},
+		bson.M{"$facet": bson.M{
+			"status": bson.A{
+				bson.M{"$group": bson.M{"_id": "$completed", "count": bson.M{"$sum": 1}}},
+			},
+			"overdue": bson.A{
+				bson.M{"$match": bson.M{"completed": false, "due_date": bson.M{"$lt": overdueBefore}}},
+				bson.M{"$count": "count"},
+			},
+			// Todos completed before completed_at was recorded fall back to
+			// their last update
+			"days": bson.A{
+				bson.M{"$match": bson.M{"completed": true}},
+				bson.M{"$addFields": bson.M{"done_at": bson.M{"$ifNull": bson.A{"$completed_at", "$updated_at"}}}},
+				bson.M{"$match": bson.M{"done_at": bson.M{"$gte": since}}},
+				bson.M{"$group": bson.M{
+					"_id":     bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$done_at", "timezone": loc.String()}},
+					"count":   bson.M{"$sum": 1},
+					"took_ms": bson.M{"$sum": bson.M{"$subtract": bson.A{"$done_at", "$created_at"}}},
+				}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+		}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Status []struct {
+			Completed bool  `bson:"_id"`
+			Count     int64 `bson:"count"`
+		} `bson:"status"`
+		Overdue []struct {
+			Count int64 `bson:"count"`
+		} `bson:"overdue"`
+		Days []struct {
+			Date   string `bson:"_id"`
+			Count  int64  `bson:"count"`
+			TookMS int64  `bson:"took_ms"`
+		} `bson:"days"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	stats := &models.TodoStats{}
+	if len(result) == 0 {
+		return stats, nil
+	}
+	for _, s := range result[0].Status {
+		stats.Total += s.Count
+		if s.Completed {
+			stats.Completed += s.Count
+		}
+	}
+	stats.Open = stats.Total - stats.Completed
+	if len(result[0].Overdue) > 0 {
+		stats.Overdue = result[0].Overdue[0].Count
+	}
+
+	var recent, tookMS int64
+	for _, d := range result[0].Days {
+		stats.CompletedPerDay = append(stats.CompletedPerDay, models.DayCount{Date: d.Date, Count: d.Count})
+		recent += d.Count
+		tookMS += d.TookMS
+	}
+	if recent > 0 {
+		avg := float64(tookMS) / 1000 / float64(recent)
+		stats.AverageCompletionSeconds = &avg
+	}
+	return stats, nil
+}
+
+// inScope builds a filter matching the todos in the scope. Personal todos
+// are the ones without a workspace.
+func inScope(scope Scope) bson.M {
+	if scope.IsWorkspace() {
+		return bson.M{"workspace_id": scope.WorkspaceID}
+	}
+	return bson.M{
+		"user_id":      scope.UserID,
+		"workspace_id": bson.M{"$exists": false},
+	}
+}
+
+// byID builds a filter matching a todo by ID within the scope
+func byID(scope Scope, id primitive.ObjectID) bson.M {
+	filter := inScope(scope)
+	filter["_id"] = id
+	return filter
+}
+
+// cosmosTooManyRequests is the error code Cosmos DB's Mongo API returns when
+// a request is throttled for exceeding the provisioned RUs
+const cosmosTooManyRequests = 16500
+
+// cosmosRetryAfter finds the wait Cosmos DB asks for in the message of a
+// throttling error, e.g. "Error=16500, RetryAfterMs=72, Details=..."
+var cosmosRetryAfter = regexp.MustCompile(`RetryAfterMs=(\d+)`)
+
+// MongoThrottle reports whether a Mongo error is Cosmos DB throttling the
+// request, and how long it asked to wait before retrying, if it said
+func MongoThrottle(err error) (retryAfter time.Duration, throttled bool) {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) || !serverErr.HasErrorCode(cosmosTooManyRequests) {
+		return 0, false
+	}
+	if m := cosmosRetryAfter.FindStringSubmatch(serverErr.Error()); m != nil {
+		if ms, err := strconv.Atoi(m[1]); err == nil {
+			retryAfter = time.Duration(ms) * time.Millisecond
+		}
+	}
+	return retryAfter, true
+}
+
+// IsRetryableMongoError reports whether a Mongo error is transient: network
+// failures, timeouts, Cosmos throttling, and errors the server labels as
+// retryable
+func IsRetryableMongoError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+	if errors.Is(err, mongo.ErrClientDisconnected) {
+		return false
+	}
+
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.HasErrorCode(cosmosTooManyRequests) ||
+			serverErr.HasErrorLabel("RetryableWriteError") ||
+			serverErr.HasErrorLabel("TransientTransactionError")
+	}
+
+	var selectionErr topology.ServerSelectionError
+	return errors.As(err, &selectionErr)
+}
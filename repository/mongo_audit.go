@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoAuditRepository stores the audit trail in a MongoDB / Cosmos DB
+// collection
+type MongoAuditRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoAuditRepository creates a repository backed by the given
+// collection
+func NewMongoAuditRepository(collection *mongo.Collection) *MongoAuditRepository {
+	return &MongoAuditRepository{collection: collection}
+}
+
+// EnsureIndexes creates the indexes used to list a user's entries and to
+// remove old ones
+func (r *MongoAuditRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "time", Value: 1}}},
+	})
+	return err
+}
+
+// Add stores entries, assigning their IDs
+func (r *MongoAuditRepository) Add(ctx context.Context, entries ...*models.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	docs := make([]any, len(entries))
+	for i, entry := range entries {
+		if entry.ID.IsZero() {
+			entry.ID = primitive.NewObjectID()
+		}
+		docs[i] = entry
+	}
+	// Unordered, so a retried insert skips the entries already stored
+	_, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr) {
+				return err
+			}
+		}
+		return nil
+	}
+	return err
+}
+
+// List returns up to q.Limit entries selected by q, newest first
+func (r *MongoAuditRepository) List(ctx context.Context, q models.AuditQuery) ([]models.AuditEntry, error) {
+	filter := bson.M{}
+	if q.UserID != "" {
+		filter["user_id"] = q.UserID
+	}
+	if q.Method != "" {
+		filter["method"] = q.Method
+	}
+	if q.PathPrefix != "" {
+		filter["path"] = bson.M{"$regex": "^" + regexp.QuoteMeta(q.PathPrefix)}
+	}
+	status := bson.M{}
+	if q.MinStatus != 0 {
+		status["$gte"] = q.MinStatus
+	}
+	if q.MaxStatus != 0 {
+		status["$lte"] = q.MaxStatus
+	}
+	if len(status) > 0 {
+		filter["status"] = status
+	}
+	at := bson.M{}
+	if !q.Since.IsZero() {
+		at["$gte"] = q.Since
+	}
+	if !q.Until.IsZero() {
+		at["$lt"] = q.Until
+	}
+	if len(at) > 0 {
+		filter["time"] = at
+	}
+	if !q.Before.IsZero() {
+		filter["_id"] = bson.M{"$lt": q.Before}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(q.Limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.AuditEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// DeleteBefore removes entries recorded before cutoff
+func (r *MongoAuditRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"time": bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoCustomFieldRepository stores custom field definitions in a MongoDB /
+// Cosmos DB collection
+type MongoCustomFieldRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoCustomFieldRepository creates a repository backed by the given collection
+func NewMongoCustomFieldRepository(collection *mongo.Collection) *MongoCustomFieldRepository {
+	return &MongoCustomFieldRepository{collection: collection}
+}
+
+// EnsureIndexes creates the indexes used to list a scope's fields
+func (r *MongoCustomFieldRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}}},
+	})
+	return err
+}
+
+// List returns the fields defined in the scope, oldest first
+func (r *MongoCustomFieldRepository) List(ctx context.Context, scope Scope) ([]models.CustomField, error) {
+	cursor, err := r.collection.Find(ctx, inScope(scope), options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var fields []models.CustomField
+	if err := cursor.All(ctx, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// Create stores a new field, assigning its ID
+func (r *MongoCustomFieldRepository) Create(ctx context.Context, field *models.CustomField) error {
+	if field.ID.IsZero() {
+		field.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, field)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// Delete removes a field in the scope
+func (r *MongoCustomFieldRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, byID(scope, id))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAll removes every field in the scope
+func (r *MongoCustomFieldRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	_, err := r.collection.DeleteMany(ctx, inScope(scope))
+	return err
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoSavedFilterRepository stores saved filters in a MongoDB / Cosmos DB
+// collection
+type MongoSavedFilterRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoSavedFilterRepository creates a repository backed by the given collection
+func NewMongoSavedFilterRepository(collection *mongo.Collection) *MongoSavedFilterRepository {
+	return &MongoSavedFilterRepository{collection: collection}
+}
+
+// EnsureIndexes creates the indexes used to list a scope's filters
+func (r *MongoSavedFilterRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}}},
+	})
+	return err
+}
+
+// List returns the filters saved in the scope, oldest first
+func (r *MongoSavedFilterRepository) List(ctx context.Context, scope Scope) ([]models.SavedFilter, error) {
+	cursor, err := r.collection.Find(ctx, inScope(scope), options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var filters []models.SavedFilter
+	if err := cursor.All(ctx, &filters); err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+// Get returns a filter in the scope or ErrNotFound
+func (r *MongoSavedFilterRepository) Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.SavedFilter, error) {
+	var filter models.SavedFilter
+	err := r.collection.FindOne(ctx, byID(scope, id)).Decode(&filter)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// Create stores a new filter, assigning its ID
+func (r *MongoSavedFilterRepository) Create(ctx context.Context, filter *models.SavedFilter) error {
+	if filter.ID.IsZero() {
+		filter.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, filter)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// Update replaces a stored filter, returning ErrNotFound if it's gone
+func (r *MongoSavedFilterRepository) Update(ctx context.Context, filter *models.SavedFilter) error {
+	result, err := r.collection.ReplaceOne(ctx, byID(ScopeOfFilter(filter), filter.ID), filter)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a filter in the scope
+func (r *MongoSavedFilterRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, byID(scope, id))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAll removes every filter in the scope
+func (r *MongoSavedFilterRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	_, err := r.collection.DeleteMany(ctx, inScope(scope))
+	return err
+}
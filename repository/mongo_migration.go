@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"todo-api/migrations"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoMigrationStore records the startup migrations applied to a MongoDB /
+// Cosmos DB database in a collection, keyed by the migration's ID
+type MongoMigrationStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoMigrationStore creates a store backed by the given collection
+func NewMongoMigrationStore(collection *mongo.Collection) *MongoMigrationStore {
+	return &MongoMigrationStore{collection: collection}
+}
+
+// List returns the migrations claimed so far
+func (s *MongoMigrationStore) List(ctx context.Context) ([]migrations.Record, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "started_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	records := []migrations.Record{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Claim records that the migration is being run, taking over a claim older
+// than lease
+func (s *MongoMigrationStore) Claim(ctx context.Context, id string, now time.Time, lease time.Duration) (bool, error) {
+	_, err := s.collection.InsertOne(ctx, migrations.Record{ID: id, StartedAt: now})
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, err
+	}
+	result, err := s.collection.UpdateOne(ctx,
+		bson.M{
+			"_id":        id,
+			"applied_at": bson.M{"$exists": false},
+			"started_at": bson.M{"$lt": now.Add(-lease)},
+		},
+		bson.M{"$set": bson.M{"started_at": now}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// Complete records that the migration was applied
+func (s *MongoMigrationStore) Complete(ctx context.Context, id string, now time.Time) error {
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"applied_at": now}})
+	return err
+}
+
+// Release removes the claim on a migration that wasn't applied
+func (s *MongoMigrationStore) Release(ctx context.Context, id string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": id, "applied_at": bson.M{"$exists": false}})
+	return err
+}
+
+// Migrations returns the startup migrations of the todos collection, oldest
+// first. Only ever add to the end.
+func (r *MongoTodoRepository) Migrations() []migrations.Migration {
+	return []migrations.Migration{
+		{
+			ID:          "todos_backfill_completed_at",
+			Description: "Give todos completed before completed_at was recorded their last update instead",
+			Up:          r.backfillCompletedAt,
+		},
+	}
+}
+
+// backfillCompletedAt sets completed_at to updated_at on completed todos
+// without one, as version 1 of the todo schema does when they're read.
+// Todos are updated one by one rather than with an update pipeline, which
+// Cosmos DB doesn't support, and only while they still have no
+// completed_at, so a todo reopened or completed in the meantime is left as
+// it is.
+func (r *MongoTodoRepository) backfillCompletedAt(ctx context.Context) error {
+	missing := bson.M{"completed": true, "completed_at": bson.M{"$exists": false}}
+	cursor, err := r.collection.Find(ctx, missing, options.Find().SetProjection(bson.M{"updated_at": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var todo struct {
+			ID        any       `bson:"_id"`
+			UpdatedAt time.Time `bson:"updated_at"`
+		}
+		if err := cursor.Decode(&todo); err != nil {
+			return err
+		}
+		_, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": todo.ID, "completed": true, "completed_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"completed_at": todo.UpdatedAt}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
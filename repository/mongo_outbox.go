@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoOutboxRepository stores the event outbox in a MongoDB / Cosmos DB
+// collection
+type MongoOutboxRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoOutboxRepository creates a repository backed by the given collection
+func NewMongoOutboxRepository(collection *mongo.Collection) *MongoOutboxRepository {
+	return &MongoOutboxRepository{collection: collection}
+}
+
+// EnsureIndexes creates the index used to find pending and old delivered
+// entries
+func (r *MongoOutboxRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "delivered_at", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	return err
+}
+
+// Add stores entries, assigning their IDs
+func (r *MongoOutboxRepository) Add(ctx context.Context, entries ...*models.OutboxEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	docs := make([]any, len(entries))
+	for i, entry := range entries {
+		if entry.ID.IsZero() {
+			entry.ID = primitive.NewObjectID()
+		}
+		docs[i] = entry
+	}
+	// Unordered, so entries after one that's already stored are still added
+	_, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr) {
+				return err
+			}
+		}
+		return nil
+	}
+	return err
+}
+
+// Pending returns up to limit undelivered entries, oldest first
+func (r *MongoOutboxRepository) Pending(ctx context.Context, limit int) ([]models.OutboxEntry, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, bson.M{"delivered_at": nil}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.OutboxEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// MarkDelivered records that the entries were published at the given time
+func (r *MongoOutboxRepository) MarkDelivered(ctx context.Context, ids []primitive.ObjectID, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"delivered_at": at}})
+	return err
+}
+
+// DeleteDelivered removes entries delivered before cutoff
+func (r *MongoOutboxRepository) DeleteDelivered(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"delivered_at": bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoPublicLinkRepository stores public links in a MongoDB / Cosmos DB
+// collection, keyed by token hash
+type MongoPublicLinkRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoPublicLinkRepository creates a repository backed by the given collection
+func NewMongoPublicLinkRepository(collection *mongo.Collection) *MongoPublicLinkRepository {
+	return &MongoPublicLinkRepository{collection: collection}
+}
+
+// EnsureIndexes creates the unique index that allows one link per todo
+func (r *MongoPublicLinkRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "todo_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Replace stores the link, revoking any earlier link to the same todo
+func (r *MongoPublicLinkRepository) Replace(ctx context.Context, link *models.PublicLink) error {
+	// The token hash is the document ID, which a replacement can't change
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"todo_id": link.TodoID}); err != nil {
+		return err
+	}
+	_, err := r.collection.InsertOne(ctx, link)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// Get returns the link with the given token hash
+func (r *MongoPublicLinkRepository) Get(ctx context.Context, tokenHash string) (*models.PublicLink, error) {
+	var link models.PublicLink
+	err := r.collection.FindOne(ctx, bson.M{"_id": tokenHash}).Decode(&link)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Delete revokes the link to one of the owner's todos
+func (r *MongoPublicLinkRepository) Delete(ctx context.Context, ownerID string, todoID primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"todo_id": todoID, "owner_id": ownerID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByOwner revokes every link the owner made
+func (r *MongoPublicLinkRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"owner_id": ownerID})
+	return err
+}
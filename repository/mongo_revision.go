@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoRevisionRepository stores todo revisions in a MongoDB / Cosmos DB
+// collection
+type MongoRevisionRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRevisionRepository creates a repository backed by the given collection
+func NewMongoRevisionRepository(collection *mongo.Collection) *MongoRevisionRepository {
+	return &MongoRevisionRepository{collection: collection}
+}
+
+// EnsureIndexes creates the indexes used to list a todo's revisions, which
+// also keeps their numbers unique, and to delete a scope's revisions
+func (r *MongoRevisionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "todo_id", Value: 1}, {Key: "rev", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}}},
+	})
+	return err
+}
+
+// List returns the todo's revisions, oldest first
+func (r *MongoRevisionRepository) List(ctx context.Context, todoID primitive.ObjectID) ([]models.Revision, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"todo_id": todoID}, options.Find().SetSort(bson.D{{Key: "rev", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []models.Revision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// Create stores a revision, assigning its ID
+func (r *MongoRevisionRepository) Create(ctx context.Context, revision *models.Revision) error {
+	if revision.ID.IsZero() {
+		revision.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, revision)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// DeleteBefore removes the todo's revisions numbered below number
+func (r *MongoRevisionRepository) DeleteBefore(ctx context.Context, todoID primitive.ObjectID, number int) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"todo_id": todoID, "rev": bson.M{"$lt": number}})
+	return err
+}
+
+// DeleteByTodo removes every revision of a todo
+func (r *MongoRevisionRepository) DeleteByTodo(ctx context.Context, todoID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"todo_id": todoID})
+	return err
+}
+
+// DeleteAll removes the revisions of every todo in the scope
+func (r *MongoRevisionRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	_, err := r.collection.DeleteMany(ctx, inScope(scope))
+	return err
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoSessionRepository stores sessions in a MongoDB / Cosmos DB collection
+type MongoSessionRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoSessionRepository creates a repository backed by the given collection
+func NewMongoSessionRepository(collection *mongo.Collection) *MongoSessionRepository {
+	return &MongoSessionRepository{collection: collection}
+}
+
+// EnsureIndexes creates the index used to list a user's sessions and a TTL
+// index that removes sessions once they expire
+func (r *MongoSessionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+// Create stores a new session
+func (r *MongoSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	_, err := r.collection.InsertOne(ctx, session)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// Get returns an unexpired session by ID
+func (r *MongoSessionRepository) Get(ctx context.Context, id string) (*models.Session, error) {
+	// The TTL monitor only runs periodically, so filter out expired
+	// sessions it hasn't removed yet
+	var session models.Session
+	err := r.collection.FindOne(ctx, bson.M{
+		"_id":        id,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListByUser returns the user's unexpired sessions, most recently created first
+func (r *MongoSessionRepository) ListByUser(ctx context.Context, userID string) ([]models.Session, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"user_id":    userID,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []models.Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Touch records that the session was used at the given time
+func (r *MongoSessionRepository) Touch(ctx context.Context, id string, seen time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_seen": seen}})
+	return err
+}
+
+// Delete revokes a session owned by the user
+func (r *MongoSessionRepository) Delete(ctx context.Context, userID, id string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByUser revokes all of the user's sessions
+func (r *MongoSessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoShareRepository stores todo shares in a MongoDB / Cosmos DB collection
+type MongoShareRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoShareRepository creates a repository backed by the given collection
+func NewMongoShareRepository(collection *mongo.Collection) *MongoShareRepository {
+	return &MongoShareRepository{collection: collection}
+}
+
+// EnsureIndexes creates the unique index that allows one share per todo and
+// grantee, and the index used to list what was shared with a user
+func (r *MongoShareRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "todo_id", Value: 1}, {Key: "grantee", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "grantee", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+// Save creates the share, or updates the role of the existing share
+// with the same grantee
+func (r *MongoShareRepository) Save(ctx context.Context, share *models.Share) error {
+	if share.ID.IsZero() {
+		share.ID = primitive.NewObjectID()
+	}
+	onInsert := bson.M{
+		"_id":        share.ID,
+		"owner_id":   share.OwnerID,
+		"created_at": share.CreatedAt,
+	}
+	if share.Email != "" {
+		onInsert["email"] = share.Email
+	}
+	if share.UserID != "" {
+		onInsert["user_id"] = share.UserID
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"todo_id": share.TodoID, "grantee": share.Grantee},
+		bson.M{
+			"$set":         bson.M{"role": share.Role, "updated_at": share.UpdatedAt},
+			"$setOnInsert": onInsert,
+		},
+		opts,
+	).Decode(share)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// ListByTodo returns the shares of one of the owner's todos, oldest first
+func (r *MongoShareRepository) ListByTodo(ctx context.Context, ownerID string, todoID primitive.ObjectID) ([]models.Share, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	return r.find(ctx, bson.M{"todo_id": todoID, "owner_id": ownerID}, opts)
+}
+
+// ListByGrantee returns the shares granted to any of the grantee keys,
+// newest first
+func (r *MongoShareRepository) ListByGrantee(ctx context.Context, grantees []string) ([]models.Share, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	return r.find(ctx, bson.M{"grantee": bson.M{"$in": grantees}}, opts)
+}
+
+// Delete removes one of the todo's shares
+func (r *MongoShareRepository) Delete(ctx context.Context, todoID, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "todo_id": todoID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByTodo removes every share of a todo
+func (r *MongoShareRepository) DeleteByTodo(ctx context.Context, todoID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"todo_id": todoID})
+	return err
+}
+
+// DeleteByOwner removes every share the owner made
+func (r *MongoShareRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"owner_id": ownerID})
+	return err
+}
+
+func (r *MongoShareRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Share, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var shares []models.Share
+	if err := cursor.All(ctx, &shares); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
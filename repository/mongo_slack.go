@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoSlackLinkRepository stores Slack links in a MongoDB / Cosmos DB
+// collection, keyed by the user's ID
+type MongoSlackLinkRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoSlackLinkRepository creates a repository backed by the given
+// collection
+func NewMongoSlackLinkRepository(collection *mongo.Collection) *MongoSlackLinkRepository {
+	return &MongoSlackLinkRepository{collection: collection}
+}
+
+// EnsureIndexes creates the unique index slash commands look links up by
+func (r *MongoSlackLinkRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "team_id", Value: 1}, {Key: "slack_user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Save stores the link, replacing the user's earlier link and any link of
+// the same Slack account
+func (r *MongoSlackLinkRepository) Save(ctx context.Context, link *models.SlackLink) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{
+		"team_id":       link.TeamID,
+		"slack_user_id": link.SlackUserID,
+		"_id":           bson.M{"$ne": link.UserID},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": link.UserID}, link, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// Get returns the user's link
+func (r *MongoSlackLinkRepository) Get(ctx context.Context, userID string) (*models.SlackLink, error) {
+	return r.findOne(ctx, bson.M{"_id": userID})
+}
+
+// GetBySlackUser returns the link of a Slack account
+func (r *MongoSlackLinkRepository) GetBySlackUser(ctx context.Context, teamID, slackUserID string) (*models.SlackLink, error) {
+	return r.findOne(ctx, bson.M{"team_id": teamID, "slack_user_id": slackUserID})
+}
+
+func (r *MongoSlackLinkRepository) findOne(ctx context.Context, filter bson.M) (*models.SlackLink, error) {
+	var link models.SlackLink
+	err := r.collection.FindOne(ctx, filter).Decode(&link)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// List returns up to limit links ordered by user ID, starting after the
+// given ID
+func (r *MongoSlackLinkRepository) List(ctx context.Context, after string, limit int) ([]models.SlackLink, error) {
+	filter := bson.M{}
+	if after != "" {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var links []models.SlackLink
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// ClaimOverdueCheck moves the user's OverdueCheckedAt forward to at. The
+// update only matches while it's earlier, so concurrent claims can't both
+// succeed.
+func (r *MongoSlackLinkRepository) ClaimOverdueCheck(ctx context.Context, userID string, at time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "overdue_checked_at": bson.M{"$lt": at}},
+		bson.M{"$set": bson.M{"overdue_checked_at": at}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// Delete removes the user's link
+func (r *MongoSlackLinkRepository) Delete(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": userID})
+	return err
+}
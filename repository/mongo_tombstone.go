@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoTombstoneRepository stores tombstones of deleted todos in a MongoDB /
+// Cosmos DB collection, keyed by the todo's ID
+type MongoTombstoneRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoTombstoneRepository creates a repository backed by the given
+// collection
+func NewMongoTombstoneRepository(collection *mongo.Collection) *MongoTombstoneRepository {
+	return &MongoTombstoneRepository{collection: collection}
+}
+
+// EnsureIndexes creates the indexes used to list a scope's recent
+// tombstones and to remove old ones
+func (r *MongoTombstoneRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "deleted_at", Value: 1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "deleted_at", Value: 1}}},
+		{Keys: bson.D{{Key: "deleted_at", Value: 1}}},
+	})
+	return err
+}
+
+// Save records the deletion, replacing an earlier tombstone of the same todo
+func (r *MongoTombstoneRepository) Save(ctx context.Context, tombstone *models.Tombstone) error {
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": tombstone.TodoID}, tombstone, options.Replace().SetUpsert(true))
+	return err
+}
+
+// ListSince returns the scope's tombstones of todos deleted at or after since
+func (r *MongoTombstoneRepository) ListSince(ctx context.Context, scope Scope, since time.Time) ([]models.Tombstone, error) {
+	filter := inScope(scope)
+	filter["deleted_at"] = bson.M{"$gte": since}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "deleted_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tombstones []models.Tombstone
+	if err := cursor.All(ctx, &tombstones); err != nil {
+		return nil, err
+	}
+	return tombstones, nil
+}
+
+// ListAllSince returns up to limit tombstones of todos deleted at or after
+// since, by todo ID
+func (r *MongoTombstoneRepository) ListAllSince(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Tombstone, error) {
+	filter := bson.M{"deleted_at": bson.M{"$gte": since}, "_id": bson.M{"$gt": after}}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tombstones []models.Tombstone
+	if err := cursor.All(ctx, &tombstones); err != nil {
+		return nil, err
+	}
+	return tombstones, nil
+}
+
+// DeleteBefore removes tombstones of todos deleted before cutoff
+func (r *MongoTombstoneRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteAll removes every tombstone in the scope
+func (r *MongoTombstoneRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	_, err := r.collection.DeleteMany(ctx, inScope(scope))
+	return err
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Server error codes returned when transactions aren't available
+const (
+	mongoIllegalOperation    = 20
+	mongoCommandNotSupported = 115
+)
+
+// MongoTransactor runs transactions in Mongo sessions. Transactions need a
+// replica set or a sharded cluster; against a standalone server, or one
+// that turns them down as some Cosmos DB accounts do, compound operations
+// run call by call instead.
+type MongoTransactor struct {
+	client    *mongo.Client
+	supported atomic.Bool
+}
+
+// NewMongoTransactor creates a MongoTransactor, asking the server whether
+// it can run transactions
+func NewMongoTransactor(ctx context.Context, client *mongo.Client) *MongoTransactor {
+	t := &MongoTransactor{client: client}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	admin := client.Database("admin")
+	err := admin.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		// Servers before 4.4.2 only know the older name
+		err = admin.RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+	}
+	switch {
+	case err != nil:
+		log.Println("Couldn't tell whether MongoDB supports transactions, running without them:", err)
+	case hello.SetName == "" && hello.Msg != "isdbgrid":
+		log.Println("MongoDB is a standalone server; compound operations run without transactions")
+	default:
+		t.supported.Store(true)
+	}
+	return t
+}
+
+// Supported reports whether compound operations run in transactions
+func (t *MongoTransactor) Supported() bool {
+	return t.supported.Load()
+}
+
+// WithTransaction runs fn in a transaction, which the driver retries as a
+// whole on transient errors. If the server turns transactions down, nothing
+// has been written, so fn runs again without one and later calls skip the
+// transaction.
+func (t *MongoTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !t.supported.Load() {
+		return fn(ctx)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(withinTransaction(ctx), func(sc mongo.SessionContext) (any, error) {
+		return nil, fn(sc)
+	})
+	if transactionsUnsupported(err) {
+		log.Println("MongoDB turned down a transaction, running compound operations without them:", err)
+		t.supported.Store(false)
+		return fn(ctx)
+	}
+	return err
+}
+
+// transactionsUnsupported reports whether an error says the server can't
+// run transactions
+func transactionsUnsupported(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) &&
+		(serverErr.HasErrorCode(mongoIllegalOperation) || serverErr.HasErrorCode(mongoCommandNotSupported))
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoUserRepository stores user activity in a MongoDB / Cosmos DB collection
+type MongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoUserRepository creates a repository backed by the given collection
+func NewMongoUserRepository(collection *mongo.Collection) *MongoUserRepository {
+	return &MongoUserRepository{collection: collection}
+}
+
+// EnsureIndexes creates the index the retention sweeper queries on
+func (r *MongoUserRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "last_seen", Value: 1}},
+	})
+	return err
+}
+
+// Touch records that the user was seen at the given time
+func (r *MongoUserRepository) Touch(ctx context.Context, userID string, seen time.Time) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$set":         bson.M{"last_seen": seen},
+			"$setOnInsert": bson.M{"created_at": seen},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// ListInactive returns up to limit users not seen since before
+func (r *MongoUserRepository) ListInactive(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, bson.M{"last_seen": bson.M{"$lt": before}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids, nil
+}
+
+// List returns up to limit users ordered by ID, starting after the given ID
+func (r *MongoUserRepository) List(ctx context.Context, after string, limit int) ([]models.User, error) {
+	filter := bson.M{}
+	if after != "" {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Count estimates the number of users from collection metadata
+func (r *MongoUserRepository) Count(ctx context.Context) (int64, error) {
+	return r.collection.EstimatedDocumentCount(ctx)
+}
+
+// GetSettings returns the user's settings
+func (r *MongoUserRepository) GetSettings(ctx context.Context, userID string) (models.UserSettings, error) {
+	var user models.User
+	err := r.collection.FindOne(ctx, bson.M{"_id": userID},
+		options.FindOne().SetProjection(bson.M{"settings": 1})).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.UserSettings{}, nil
+	}
+	return user.Settings, err
+}
+
+// SaveSettings replaces the user's settings, creating the record if needed
+func (r *MongoUserRepository) SaveSettings(ctx context.Context, userID string, settings models.UserSettings) error {
+	now := time.Now()
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$set":         bson.M{"settings": settings},
+			"$setOnInsert": bson.M{"created_at": now, "last_seen": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// ClaimDigest records that the user's digest due at due is being sent. The
+// update only matches while no later digest is recorded, so concurrent
+// claims can't both succeed.
+func (r *MongoUserRepository) ClaimDigest(ctx context.Context, userID string, due time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "$or": bson.A{
+			bson.M{"digest_sent_at": bson.M{"$exists": false}},
+			bson.M{"digest_sent_at": bson.M{"$lt": due}},
+		}},
+		bson.M{"$set": bson.M{"digest_sent_at": due}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ClaimDailyReset records that the user's daily reset due at due is being
+// run, the way ClaimDigest claims digests
+func (r *MongoUserRepository) ClaimDailyReset(ctx context.Context, userID string, due time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "$or": bson.A{
+			bson.M{"daily_reset_at": bson.M{"$exists": false}},
+			bson.M{"daily_reset_at": bson.M{"$lt": due}},
+		}},
+		bson.M{"$set": bson.M{"daily_reset_at": due}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ClaimStaleNudge records that the user's nudge about stale todos due at
+// due is being sent, the way ClaimDigest claims digests
+func (r *MongoUserRepository) ClaimStaleNudge(ctx context.Context, userID string, due time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "$or": bson.A{
+			bson.M{"stale_nudged_at": bson.M{"$exists": false}},
+			bson.M{"stale_nudged_at": bson.M{"$lt": due}},
+		}},
+		bson.M{"$set": bson.M{"stale_nudged_at": due}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// Delete removes the user's record
+func (r *MongoUserRepository) Delete(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": userID})
+	return err
+}
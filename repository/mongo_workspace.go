@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"todo-api/authz"
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoWorkspaceRepository stores workspaces in a MongoDB / Cosmos DB
+// collection, with members embedded in each workspace document
+type MongoWorkspaceRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoWorkspaceRepository creates a repository backed by the given collection
+func NewMongoWorkspaceRepository(collection *mongo.Collection) *MongoWorkspaceRepository {
+	return &MongoWorkspaceRepository{collection: collection}
+}
+
+// EnsureIndexes creates the index used to find a user's workspaces
+func (r *MongoWorkspaceRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "members.user_id", Value: 1}},
+	})
+	return err
+}
+
+// Create stores a new workspace, assigning its ID
+func (r *MongoWorkspaceRepository) Create(ctx context.Context, workspace *models.Workspace) error {
+	if workspace.ID.IsZero() {
+		workspace.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, workspace)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// Get returns a workspace by ID
+func (r *MongoWorkspaceRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&workspace)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// ListByMember returns the workspaces the user belongs to, oldest first
+func (r *MongoWorkspaceRepository) ListByMember(ctx context.Context, userID string) ([]models.Workspace, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"members.user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var workspaces []models.Workspace
+	if err := cursor.All(ctx, &workspaces); err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+// AddMember adds a member unless they already belong to the workspace
+func (r *MongoWorkspaceRepository) AddMember(ctx context.Context, id primitive.ObjectID, member models.Member) error {
+	// Matching on the member's absence makes the check and the push atomic
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "members.user_id": bson.M{"$ne": member.UserID}},
+		bson.M{
+			"$push": bson.M{"members": member},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return r.missingOrDuplicate(ctx, id)
+	}
+	return nil
+}
+
+// SetMemberRole changes a member's role
+func (r *MongoWorkspaceRepository) SetMemberRole(ctx context.Context, id primitive.ObjectID, userID string, role authz.Role) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "members.user_id": userID},
+		bson.M{"$set": bson.M{"members.$.role": role, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RemoveMember removes a member from the workspace
+func (r *MongoWorkspaceRepository) RemoveMember(ctx context.Context, id primitive.ObjectID, userID string) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "members.user_id": userID},
+		bson.M{
+			"$pull": bson.M{"members": bson.M{"user_id": userID}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Update saves the workspace's name and color
+func (r *MongoWorkspaceRepository) Update(ctx context.Context, workspace *models.Workspace) error {
+	set := bson.M{"name": workspace.Name, "updated_at": workspace.UpdatedAt}
+	update := bson.M{"$set": set}
+	if workspace.Color != "" {
+		set["color"] = workspace.Color
+	} else {
+		update["$unset"] = bson.M{"color": ""}
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": workspace.ID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes the workspace
+func (r *MongoWorkspaceRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// missingOrDuplicate tells apart the two reasons AddMember can match nothing
+func (r *MongoWorkspaceRepository) missingOrDuplicate(ctx context.Context, id primitive.ObjectID) error {
+	n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return ErrDuplicate
+}
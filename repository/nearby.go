@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"sort"
+
+	"todo-api/models"
+)
+
+// nearest keeps the todos located within radius meters of center, nearest
+// first, for the backends that can't measure distances themselves
+func nearest(todos []models.Todo, center *models.Location, radius float64) []models.Todo {
+	var near []models.Todo
+	for _, todo := range todos {
+		if todo.Location != nil && center.DistanceTo(todo.Location) <= radius {
+			near = append(near, todo)
+		}
+	}
+	sort.SliceStable(near, func(i, j int) bool {
+		return center.DistanceTo(near[i].Location) < center.DistanceTo(near[j].Location)
+	})
+	return near
+}
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver
+)
+
+// postgresMigrations are applied in order and recorded in schema_migrations
+var postgresMigrations = []string{
+	`CREATE TABLE todos (
+		id         CHAR(24) PRIMARY KEY,
+		user_id    TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL,
+		doc        JSONB NOT NULL
+	)`,
+	`CREATE INDEX todos_user_id_created_at_idx ON todos (user_id, created_at)`,
+	`CREATE TABLE users (
+		id         TEXT PRIMARY KEY,
+		created_at TIMESTAMPTZ NOT NULL,
+		last_seen  TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX users_last_seen_idx ON users (last_seen)`,
+	`CREATE TABLE sessions (
+		id         TEXT PRIMARY KEY,
+		user_id    TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		doc        JSONB NOT NULL
+	)`,
+	`CREATE INDEX sessions_user_id_idx ON sessions (user_id)`,
+	`ALTER TABLE todos ADD COLUMN workspace_id TEXT`,
+	`CREATE INDEX todos_workspace_id_created_at_idx ON todos (workspace_id, created_at)`,
+	`CREATE TABLE workspaces (
+		id         CHAR(24) PRIMARY KEY,
+		created_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL,
+		doc        JSONB NOT NULL
+	)`,
+	`CREATE TABLE workspace_members (
+		workspace_id CHAR(24) NOT NULL,
+		user_id      TEXT NOT NULL,
+		joined_at    TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (workspace_id, user_id)
+	)`,
+	`CREATE INDEX workspace_members_user_id_idx ON workspace_members (user_id)`,
+	`CREATE TABLE shares (
+		id         CHAR(24) PRIMARY KEY,
+		todo_id    CHAR(24) NOT NULL,
+		owner_id   TEXT NOT NULL,
+		grantee    TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		doc        JSONB NOT NULL,
+		UNIQUE (todo_id, grantee)
+	)`,
+	`CREATE INDEX shares_grantee_created_at_idx ON shares (grantee, created_at)`,
+	`CREATE TABLE public_links (
+		token_hash CHAR(64) PRIMARY KEY,
+		todo_id    CHAR(24) NOT NULL UNIQUE,
+		owner_id   TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	)`,
+	// Members added before roles existed are editors, which is what every
+	// member used to be; owners are recognised by the workspace's owner_id
+	`ALTER TABLE workspace_members ADD COLUMN role TEXT NOT NULL DEFAULT 'editor'`,
+	`ALTER TABLE todos ADD COLUMN completed BOOLEAN NOT NULL DEFAULT FALSE`,
+	`UPDATE todos SET completed = COALESCE((doc->>'completed')::boolean, FALSE)`,
+	`ALTER TABLE todos ADD COLUMN due_date TIMESTAMPTZ`,
+	`UPDATE todos SET due_date = (doc->'due_date'->>'$date')::timestamptz WHERE doc ? 'due_date'`,
+	`ALTER TABLE users ADD COLUMN settings JSONB NOT NULL DEFAULT '{}'`,
+	`CREATE TABLE custom_fields (
+		id           CHAR(24) PRIMARY KEY,
+		user_id      TEXT NOT NULL,
+		workspace_id TEXT,
+		created_at   TIMESTAMPTZ NOT NULL,
+		doc          JSONB NOT NULL
+	)`,
+	`CREATE INDEX custom_fields_user_id_idx ON custom_fields (user_id)`,
+	`CREATE INDEX custom_fields_workspace_id_idx ON custom_fields (workspace_id)`,
+	// No todo recorded when it was completed before this column
+	`ALTER TABLE todos ADD COLUMN completed_at TIMESTAMPTZ`,
+	`CREATE TABLE revisions (
+		id           CHAR(24) PRIMARY KEY,
+		todo_id      CHAR(24) NOT NULL,
+		rev          INTEGER NOT NULL,
+		user_id      TEXT NOT NULL,
+		workspace_id TEXT,
+		created_at   TIMESTAMPTZ NOT NULL,
+		doc          JSONB NOT NULL,
+		UNIQUE (todo_id, rev)
+	)`,
+	`CREATE INDEX revisions_user_id_idx ON revisions (user_id)`,
+	`CREATE INDEX revisions_workspace_id_idx ON revisions (workspace_id)`,
+	`CREATE TABLE outbox (
+		id           CHAR(24) PRIMARY KEY,
+		created_at   TIMESTAMPTZ NOT NULL,
+		delivered_at TIMESTAMPTZ,
+		doc          JSONB NOT NULL
+	)`,
+	`CREATE INDEX outbox_pending_idx ON outbox (delivered_at, created_at)`,
+	`CREATE TABLE tombstones (
+		todo_id      CHAR(24) PRIMARY KEY,
+		user_id      TEXT NOT NULL,
+		workspace_id TEXT,
+		deleted_at   TIMESTAMPTZ NOT NULL,
+		doc          JSONB NOT NULL
+	)`,
+	`CREATE INDEX tombstones_user_id_idx ON tombstones (user_id, deleted_at)`,
+	`CREATE INDEX tombstones_workspace_id_idx ON tombstones (workspace_id, deleted_at)`,
+	`CREATE INDEX tombstones_deleted_at_idx ON tombstones (deleted_at)`,
+	`ALTER TABLE users ADD COLUMN digest_sent_at TIMESTAMPTZ`,
+	`CREATE TABLE slack_links (
+		user_id            TEXT PRIMARY KEY,
+		team_id            TEXT NOT NULL,
+		slack_user_id      TEXT NOT NULL,
+		created_at         TIMESTAMPTZ NOT NULL,
+		overdue_checked_at TIMESTAMPTZ NOT NULL,
+		UNIQUE (team_id, slack_user_id)
+	)`,
+	`CREATE TABLE saved_filters (
+		id           TEXT PRIMARY KEY,
+		user_id      TEXT NOT NULL,
+		workspace_id TEXT,
+		created_at   TIMESTAMPTZ NOT NULL,
+		doc          TEXT NOT NULL
+	)`,
+	`CREATE INDEX saved_filters_user_id_idx ON saved_filters (user_id)`,
+	`CREATE INDEX saved_filters_workspace_id_idx ON saved_filters (workspace_id)`,
+	`CREATE TABLE audit_log (
+		id      TEXT PRIMARY KEY,
+		time    TIMESTAMPTZ NOT NULL,
+		user_id TEXT NOT NULL,
+		method  TEXT NOT NULL,
+		path    TEXT NOT NULL,
+		status  INTEGER NOT NULL,
+		doc     JSONB NOT NULL
+	)`,
+	`CREATE INDEX audit_log_user_id_idx ON audit_log (user_id, id)`,
+	`CREATE INDEX audit_log_time_idx ON audit_log (time)`,
+	// A todo's location is kept in the document as GeoJSON; these columns
+	// bound nearby queries to a box before distances are worked out
+	`ALTER TABLE todos ADD COLUMN lat DOUBLE PRECISION`,
+	`ALTER TABLE todos ADD COLUMN lng DOUBLE PRECISION`,
+	`CREATE INDEX todos_lat_lng_idx ON todos (lat, lng) WHERE lat IS NOT NULL`,
+	// Change exports look todos up by when they were last written
+	`CREATE INDEX todos_updated_at_idx ON todos (updated_at)`,
+	`ALTER TABLE users ADD COLUMN daily_reset_at TIMESTAMPTZ`,
+	`ALTER TABLE users ADD COLUMN stale_nudged_at TIMESTAMPTZ`,
+	// Completions from before completed_at count their last update, as
+	// version 1 of the todo schema has it; documents are upgraded as read
+	`UPDATE todos SET completed_at = updated_at WHERE completed AND completed_at IS NULL`,
+}
+
+// migrationLockID is an arbitrary key for the advisory lock that stops two
+// instances from migrating at the same time
+const migrationLockID = 727146
+
+var postgresDialect = sqlDialect{
+	numberedParams: true,
+	timeValue:      func(t time.Time) any { return t },
+}
+
+// NewPostgresStore opens a PostgreSQL connection pool and applies any pending
+// schema migrations. With a schema, the store's tables are kept in that
+// schema, which is created if needed; otherwise they're on the role's
+// search_path.
+func NewPostgresStore(ctx context.Context, url, schema string) (*SQLStore, error) {
+	db, err := openPostgres(ctx, url, schema)
+	if err != nil {
+		return nil, fmt.Errorf("open postgres: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping postgres: %w", err)
+	}
+	if err := migratePostgres(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate postgres: %w", err)
+	}
+	return &SQLStore{db: db, dialect: postgresDialect}, nil
+}
+
+// openPostgres opens a connection pool whose connections use schema, after
+// creating it
+func openPostgres(ctx context.Context, url, schema string) (*sql.DB, error) {
+	if schema == "" {
+		return sql.Open("pgx", url)
+	}
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+	name := pgx.Identifier{schema}.Sanitize()
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+name)
+	conn.Close(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create schema %s: %w", name, err)
+	}
+	config.RuntimeParams["search_path"] = name
+	return stdlib.OpenDB(*config), nil
+}
+
+// migratePostgres applies pending migrations while holding an advisory lock
+func migratePostgres(ctx context.Context, db *sql.DB) error {
+	// Advisory locks belong to a session, so pin a single connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	return migrateSQL(ctx, conn, postgresDialect, postgresMigrations)
+}
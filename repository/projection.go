@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// todoFieldIndexes maps the stored names of the todo fields to their index
+// in models.Todo
+var todoFieldIndexes = func() map[string]int {
+	indexes := map[string]int{}
+	t := reflect.TypeOf(models.Todo{})
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("bson"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		indexes[name] = i
+	}
+	return indexes
+}()
+
+// scopeFields are loaded whichever fields are asked for: they place a todo
+// in its scope and tell which schema version it was written with
+var scopeFields = []string{"_id", "user_id", "workspace_id", "schema_version"}
+
+// todoProjection builds a Mongo projection loading the fields and
+// scopeFields
+func todoProjection(fields []string) bson.D {
+	projection := bson.D{}
+	for _, name := range slices.Concat(scopeFields, fields) {
+		projection = append(projection, bson.E{Key: name, Value: 1})
+	}
+	return projection
+}
+
+// project clears the fields of each todo outside fields and scopeFields,
+// for the backends that can only load whole todos
+func project(todos []models.Todo, fields []string) {
+	keep := make([]bool, reflect.TypeOf(models.Todo{}).NumField())
+	for _, name := range slices.Concat(scopeFields, fields) {
+		if i, ok := todoFieldIndexes[name]; ok {
+			keep[i] = true
+		}
+	}
+	for i := range todos {
+		v := reflect.ValueOf(&todos[i]).Elem()
+		for j, kept := range keep {
+			if !kept {
+				v.Field(j).SetZero()
+			}
+		}
+	}
+}
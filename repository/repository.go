@@ -0,0 +1,349 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"todo-api/authz"
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrNotFound is returned when a todo doesn't exist or belongs to another user
+	ErrNotFound = errors.New("todo not found")
+	// ErrDuplicate is returned when creating a todo whose ID already exists
+	ErrDuplicate = errors.New("todo already exists")
+	// ErrConflict is returned when a todo changed between being read and
+	// being written back
+	ErrConflict = errors.New("todo was changed concurrently")
+	// ErrUnavailable wraps errors caused by the storage backend being
+	// temporarily unavailable (throttled, unreachable, or circuit open)
+	ErrUnavailable = errors.New("storage temporarily unavailable")
+)
+
+// ThrottledError is an error caused by the storage backend throttling
+// requests, such as Cosmos DB running out of request units. It's wrapped in
+// ErrUnavailable.
+type ThrottledError struct {
+	// RetryAfter is how long the backend asked to wait before retrying, or
+	// zero if it didn't say
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ThrottledError) Error() string {
+	return "storage throttled: " + e.Err.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return e.Err
+}
+
+// TodoRepository persists todos. Every lookup is limited to a Scope so one
+// user can never read or modify another user's todos, and workspace todos
+// are only reachable through their workspace.
+type TodoRepository interface {
+	// List returns all todos in the scope
+	List(ctx context.Context, scope Scope) ([]models.Todo, error)
+	// ListFields returns the todos in the scope like List, loading only
+	// the fields named, by their stored names, along with the ones that
+	// place each todo in its scope. Backends that can't load part of a
+	// todo clear the other fields instead.
+	ListFields(ctx context.Context, scope Scope, fields []string) ([]models.Todo, error)
+	// ListPage returns up to limit todos in the scope ordered by ID,
+	// starting after the given ID, so callers can page through lists too
+	// big to hold at once
+	ListPage(ctx context.Context, scope Scope, after primitive.ObjectID, limit int) ([]models.Todo, error)
+	// Get returns a single todo in the scope
+	Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.Todo, error)
+	// Create stores a new todo, assigning an ID unless one is already set
+	Create(ctx context.Context, todo *models.Todo) error
+	// Update replaces an existing todo within its own scope
+	Update(ctx context.Context, todo *models.Todo) error
+	// UpdateIfUnchanged replaces an existing todo like Update, provided it
+	// is still the version last updated at lastUpdated, and returns
+	// ErrConflict when it isn't. Callers pass the UpdatedAt of the todo
+	// they read.
+	UpdateIfUnchanged(ctx context.Context, todo *models.Todo, lastUpdated time.Time) error
+	// Delete removes a todo in the scope
+	Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error
+	// DeleteAll removes every todo in the scope and returns how many were
+	// deleted
+	DeleteAll(ctx context.Context, scope Scope) (int64, error)
+	// ReplaceTag puts the tag to in the place of from on every todo in the
+	// scope that has it, or removes from when to is empty, as
+	// Todo.ReplaceTag does. Changed todos are updated at now. It returns
+	// how many todos changed.
+	ReplaceTag(ctx context.Context, scope Scope, from, to string, now time.Time) (int64, error)
+	// Count returns the number of todos in the scope
+	Count(ctx context.Context, scope Scope) (int64, error)
+	// CountMatching returns the number of todos in the scope the filter
+	// keeps
+	CountMatching(ctx context.Context, scope Scope, filter ListFilter) (int64, error)
+	// Facets counts the todos in the scope the filter keeps by the values
+	// of the fields listings filter on. A todo is blocked while any todo
+	// in the scope blocking it is open.
+	Facets(ctx context.Context, scope Scope, filter ListFilter) (*models.TodoFacets, error)
+	// CountAll returns the number of todos of all users and workspaces; it
+	// may be an estimate
+	CountAll(ctx context.Context) (int64, error)
+	// ListChanged returns up to limit todos of all users and workspaces
+	// updated at or after since, ordered by ID and starting after the given
+	// ID so callers can page through them
+	ListChanged(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Todo, error)
+	// ListDueByDay returns the todos in the scope due in [from, to) keyed
+	// by their due date in loc (YYYY-MM-DD), soonest first each day. Days
+	// without todos are left out.
+	ListDueByDay(ctx context.Context, scope Scope, from, to time.Time, loc *time.Location) (map[string][]models.Todo, error)
+	// ListNear returns the todos in the scope located within radius meters
+	// of center, nearest first
+	ListNear(ctx context.Context, scope Scope, center *models.Location, radius float64) ([]models.Todo, error)
+	// Stats summarises the todos in the scope, with completions counted
+	// from since onwards by their day in loc and open todos due before
+	// overdueBefore counted as overdue. CompletedPerDay only lists days with
+	// completions.
+	Stats(ctx context.Context, scope Scope, since, overdueBefore time.Time, loc *time.Location) (*models.TodoStats, error)
+}
+
+// UserRepository tracks when each identity was last seen
+type UserRepository interface {
+	// Touch records that the user was seen at the given time, creating the
+	// record if needed
+	Touch(ctx context.Context, userID string, seen time.Time) error
+	// ListInactive returns up to limit users not seen since before
+	ListInactive(ctx context.Context, before time.Time, limit int) ([]string, error)
+	// List returns up to limit users ordered by ID, starting after the given
+	// ID so callers can page through them
+	List(ctx context.Context, after string, limit int) ([]models.User, error)
+	// Count returns the number of users; it may be an estimate
+	Count(ctx context.Context) (int64, error)
+	// GetSettings returns the user's settings, which are empty for users
+	// without a record
+	GetSettings(ctx context.Context, userID string) (models.UserSettings, error)
+	// SaveSettings replaces the user's settings, creating the record if
+	// needed
+	SaveSettings(ctx context.Context, userID string, settings models.UserSettings) error
+	// ClaimDigest records that the user's digest due at due is being sent.
+	// It returns false if that digest, or a later one, was claimed already,
+	// so only one instance sends it.
+	ClaimDigest(ctx context.Context, userID string, due time.Time) (bool, error)
+	// ClaimDailyReset records that the user's daily reset due at due is
+	// being run. Like ClaimDigest, it returns false if that reset, or a
+	// later one, was claimed already.
+	ClaimDailyReset(ctx context.Context, userID string, due time.Time) (bool, error)
+	// ClaimStaleNudge records that the user's nudge about stale todos due
+	// at due is being sent. Like ClaimDigest, it returns false if that
+	// nudge, or a later one, was claimed already.
+	ClaimStaleNudge(ctx context.Context, userID string, due time.Time) (bool, error)
+	// Delete removes the user's record
+	Delete(ctx context.Context, userID string) error
+}
+
+// SessionRepository persists identity sessions. Expired sessions behave as
+// if they didn't exist.
+type SessionRepository interface {
+	// Create stores a new session
+	Create(ctx context.Context, session *models.Session) error
+	// Get returns an unexpired session by ID
+	Get(ctx context.Context, id string) (*models.Session, error)
+	// ListByUser returns the user's unexpired sessions, most recently created
+	// first
+	ListByUser(ctx context.Context, userID string) ([]models.Session, error)
+	// Touch records that the session was used at the given time
+	Touch(ctx context.Context, id string, seen time.Time) error
+	// Delete revokes a session owned by the user
+	Delete(ctx context.Context, userID, id string) error
+	// DeleteByUser revokes all of the user's sessions
+	DeleteByUser(ctx context.Context, userID string) error
+}
+
+// WorkspaceRepository persists workspaces and their membership. Lookups are
+// not scoped; callers check membership before exposing a workspace.
+type WorkspaceRepository interface {
+	// Create stores a new workspace, assigning its ID
+	Create(ctx context.Context, workspace *models.Workspace) error
+	// Get returns a workspace by ID
+	Get(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error)
+	// ListByMember returns the workspaces the user belongs to, oldest first
+	ListByMember(ctx context.Context, userID string) ([]models.Workspace, error)
+	// AddMember adds a member, returning ErrDuplicate if they already
+	// belong to the workspace
+	AddMember(ctx context.Context, id primitive.ObjectID, member models.Member) error
+	// SetMemberRole changes a member's role, returning ErrNotFound if they
+	// don't belong to the workspace
+	SetMemberRole(ctx context.Context, id primitive.ObjectID, userID string, role authz.Role) error
+	// RemoveMember removes a member, returning ErrNotFound if they don't
+	// belong to the workspace
+	RemoveMember(ctx context.Context, id primitive.ObjectID, userID string) error
+	// Update saves the workspace's name and color; members are changed
+	// through the methods above
+	Update(ctx context.Context, workspace *models.Workspace) error
+	// Delete removes the workspace
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// ShareRepository persists shares of individual todos
+type ShareRepository interface {
+	// Save creates the share, or updates the role and returns the
+	// existing share if the todo is already shared with the same grantee
+	Save(ctx context.Context, share *models.Share) error
+	// ListByTodo returns the shares of one of the owner's todos
+	ListByTodo(ctx context.Context, ownerID string, todoID primitive.ObjectID) ([]models.Share, error)
+	// ListByGrantee returns the shares granted to any of the grantee keys,
+	// newest first
+	ListByGrantee(ctx context.Context, grantees []string) ([]models.Share, error)
+	// Delete removes one of the todo's shares
+	Delete(ctx context.Context, todoID, id primitive.ObjectID) error
+	// DeleteByTodo removes every share of a todo
+	DeleteByTodo(ctx context.Context, todoID primitive.ObjectID) error
+	// DeleteByOwner removes every share the owner made
+	DeleteByOwner(ctx context.Context, ownerID string) error
+}
+
+// PublicLinkRepository persists public read-only links to todos. A todo has
+// at most one link.
+type PublicLinkRepository interface {
+	// Replace stores the link, revoking any earlier link to the same todo
+	Replace(ctx context.Context, link *models.PublicLink) error
+	// Get returns the link with the given token hash
+	Get(ctx context.Context, tokenHash string) (*models.PublicLink, error)
+	// Delete revokes the link to one of the owner's todos
+	Delete(ctx context.Context, ownerID string, todoID primitive.ObjectID) error
+	// DeleteByOwner revokes every link the owner made
+	DeleteByOwner(ctx context.Context, ownerID string) error
+}
+
+// CustomFieldRepository persists custom field definitions. Like todos, they
+// belong to a Scope.
+type CustomFieldRepository interface {
+	// List returns the fields defined in the scope, oldest first
+	List(ctx context.Context, scope Scope) ([]models.CustomField, error)
+	// Create stores a new field, assigning its ID
+	Create(ctx context.Context, field *models.CustomField) error
+	// Delete removes a field in the scope
+	Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error
+	// DeleteAll removes every field in the scope
+	DeleteAll(ctx context.Context, scope Scope) error
+}
+
+// SavedFilterRepository persists saved filters, which belong to a scope
+// like custom fields
+type SavedFilterRepository interface {
+	// List returns the filters saved in the scope, oldest first
+	List(ctx context.Context, scope Scope) ([]models.SavedFilter, error)
+	// Get returns a filter in the scope or ErrNotFound
+	Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.SavedFilter, error)
+	// Create stores a new filter, assigning its ID
+	Create(ctx context.Context, filter *models.SavedFilter) error
+	// Update replaces a stored filter, returning ErrNotFound if it's gone
+	Update(ctx context.Context, filter *models.SavedFilter) error
+	// Delete removes a filter in the scope
+	Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error
+	// DeleteAll removes every filter in the scope
+	DeleteAll(ctx context.Context, scope Scope) error
+}
+
+// RevisionRepository persists the revision history of todos. Lookups are
+// by todo; callers check access to the todo first.
+type RevisionRepository interface {
+	// List returns the todo's revisions, oldest first
+	List(ctx context.Context, todoID primitive.ObjectID) ([]models.Revision, error)
+	// Create stores a revision, assigning its ID. It returns ErrDuplicate if
+	// the todo already has a revision with the same number.
+	Create(ctx context.Context, revision *models.Revision) error
+	// DeleteBefore removes the todo's revisions numbered below number
+	DeleteBefore(ctx context.Context, todoID primitive.ObjectID, number int) error
+	// DeleteByTodo removes every revision of a todo
+	DeleteByTodo(ctx context.Context, todoID primitive.ObjectID) error
+	// DeleteAll removes the revisions of every todo in the scope
+	DeleteAll(ctx context.Context, scope Scope) error
+}
+
+// OutboxRepository holds events from the time a change is saved until they
+// have been published
+type OutboxRepository interface {
+	// Add stores entries, assigning their IDs. Entries whose ID is already
+	// stored are left as they are, so adding can be retried.
+	Add(ctx context.Context, entries ...*models.OutboxEntry) error
+	// Pending returns up to limit undelivered entries, oldest first
+	Pending(ctx context.Context, limit int) ([]models.OutboxEntry, error)
+	// MarkDelivered records that the entries were published at the given time
+	MarkDelivered(ctx context.Context, ids []primitive.ObjectID, at time.Time) error
+	// DeleteDelivered removes entries delivered before cutoff and returns how
+	// many were removed
+	DeleteDelivered(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// TombstoneRepository records deleted todos for clients syncing changes.
+// A todo has at most one tombstone.
+type TombstoneRepository interface {
+	// Save records the deletion, replacing an earlier tombstone of the same
+	// todo
+	Save(ctx context.Context, tombstone *models.Tombstone) error
+	// ListSince returns the scope's tombstones of todos deleted at or after
+	// since
+	ListSince(ctx context.Context, scope Scope, since time.Time) ([]models.Tombstone, error)
+	// ListAllSince returns up to limit tombstones of all users and
+	// workspaces of todos deleted at or after since, ordered by todo ID and
+	// starting after the given ID so callers can page through them
+	ListAllSince(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Tombstone, error)
+	// DeleteBefore removes tombstones of todos deleted before cutoff and
+	// returns how many were removed
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	// DeleteAll removes every tombstone in the scope
+	DeleteAll(ctx context.Context, scope Scope) error
+}
+
+// SlackLinkRepository persists the links between users and their Slack
+// accounts
+type SlackLinkRepository interface {
+	// Save stores the link, replacing the user's earlier link and any link
+	// of the same Slack account to another user
+	Save(ctx context.Context, link *models.SlackLink) error
+	// Get returns the user's link or ErrNotFound
+	Get(ctx context.Context, userID string) (*models.SlackLink, error)
+	// GetBySlackUser returns the link of a Slack account or ErrNotFound
+	GetBySlackUser(ctx context.Context, teamID, slackUserID string) (*models.SlackLink, error)
+	// List returns up to limit links ordered by user ID, starting after the
+	// given ID so callers can page through them
+	List(ctx context.Context, after string, limit int) ([]models.SlackLink, error)
+	// ClaimOverdueCheck moves the user's OverdueCheckedAt forward to at. It
+	// returns false if it was at or past at already, so only one instance
+	// notifies.
+	ClaimOverdueCheck(ctx context.Context, userID string, at time.Time) (bool, error)
+	// Delete removes the user's link; a missing link is ignored
+	Delete(ctx context.Context, userID string) error
+}
+
+// AuditRepository keeps the audit trail of requests
+type AuditRepository interface {
+	// Add stores entries, assigning their IDs
+	Add(ctx context.Context, entries ...*models.AuditEntry) error
+	// List returns up to q.Limit entries selected by q, newest first
+	List(ctx context.Context, q models.AuditQuery) ([]models.AuditEntry, error)
+	// DeleteBefore removes entries recorded before cutoff and returns how
+	// many were removed
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// Stores bundles the repositories of one storage backend
+type Stores struct {
+	Todos        TodoRepository
+	Users        UserRepository
+	Sessions     SessionRepository
+	Workspaces   WorkspaceRepository
+	Shares       ShareRepository
+	PublicLinks  PublicLinkRepository
+	CustomFields CustomFieldRepository
+	Filters      SavedFilterRepository
+	Revisions    RevisionRepository
+	Outbox       OutboxRepository
+	Tombstones   TombstoneRepository
+	SlackLinks   SlackLinkRepository
+	Audit        AuditRepository
+	// Tx runs calls across the repositories above atomically
+	Tx Transactor
+}
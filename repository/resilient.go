@@ -0,0 +1,941 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"slices"
+	"time"
+
+	"todo-api/authz"
+	"todo-api/models"
+	"todo-api/resilience"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// throttledRequests counts the storage requests the backend throttled,
+// retried or not, in /debug/vars
+var throttledRequests = expvar.NewInt("storage_throttled_requests")
+
+// Resilience retries transient storage errors and trips a circuit breaker
+// when the backend keeps failing. It is shared by the repository decorators
+// so that all of them feed and respect the same breaker.
+type Resilience struct {
+	policy    resilience.RetryPolicy
+	breaker   *resilience.Breaker
+	retryable func(error) bool
+	throttled func(error) (time.Duration, bool)
+}
+
+// NewResilience creates a Resilience that retries errors for which retryable
+// returns true. throttled tells errors caused by the backend throttling
+// requests apart, and how long it asked to wait; it may be nil.
+func NewResilience(policy resilience.RetryPolicy, breaker *resilience.Breaker, retryable func(error) bool, throttled func(error) (time.Duration, bool)) *Resilience {
+	return &Resilience{policy: policy, breaker: breaker, retryable: retryable, throttled: throttled}
+}
+
+// Wrap decorates every repository in stores
+func (r *Resilience) Wrap(stores *Stores) *Stores {
+	return &Stores{
+		Todos:        &resilientTodoRepository{inner: stores.Todos, r: r},
+		Users:        &resilientUserRepository{inner: stores.Users, r: r},
+		Sessions:     &resilientSessionRepository{inner: stores.Sessions, r: r},
+		Workspaces:   &resilientWorkspaceRepository{inner: stores.Workspaces, r: r},
+		Shares:       &resilientShareRepository{inner: stores.Shares, r: r},
+		PublicLinks:  &resilientPublicLinkRepository{inner: stores.PublicLinks, r: r},
+		CustomFields: &resilientCustomFieldRepository{inner: stores.CustomFields, r: r},
+		Filters:      &resilientSavedFilterRepository{inner: stores.Filters, r: r},
+		Revisions:    &resilientRevisionRepository{inner: stores.Revisions, r: r},
+		Outbox:       &resilientOutboxRepository{inner: stores.Outbox, r: r},
+		Tombstones:   &resilientTombstoneRepository{inner: stores.Tombstones, r: r},
+		SlackLinks:   &resilientSlackLinkRepository{inner: stores.SlackLinks, r: r},
+		Audit:        &resilientAuditRepository{inner: stores.Audit, r: r},
+		Tx:           stores.Tx,
+	}
+}
+
+// do runs fn with retries and the circuit breaker. Errors that are still
+// transient once retries are exhausted, and calls rejected by an open
+// breaker, are wrapped in ErrUnavailable, throttling in a ThrottledError
+// too. Calls in a transaction aren't retried, as a failed call aborts the
+// transaction; it's retried as a whole instead.
+func (r *Resilience) do(ctx context.Context, fn func() error) error {
+	policy := r.policy
+	if InTransaction(ctx) {
+		policy.MaxAttempts = 1
+	}
+	err := resilience.Retry(ctx, policy, r.retryable, func() error {
+		if err := r.breaker.Allow(); err != nil {
+			return err
+		}
+		err := fn()
+		r.breaker.Record(r.retryable(err))
+		if err != nil && r.throttled != nil {
+			if retryAfter, ok := r.throttled(err); ok {
+				throttledRequests.Add(1)
+				err = &ThrottledError{RetryAfter: retryAfter, Err: err}
+			}
+		}
+		return err
+	})
+	if errors.Is(err, resilience.ErrOpen) || (err != nil && r.retryable(err)) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return err
+}
+
+type resilientTodoRepository struct {
+	inner TodoRepository
+	r     *Resilience
+}
+
+func (d *resilientTodoRepository) List(ctx context.Context, scope Scope) ([]models.Todo, error) {
+	var todos []models.Todo
+	err := d.r.do(ctx, func() (err error) {
+		todos, err = d.inner.List(ctx, scope)
+		return err
+	})
+	return todos, err
+}
+
+func (d *resilientTodoRepository) ListFields(ctx context.Context, scope Scope, fields []string) ([]models.Todo, error) {
+	var todos []models.Todo
+	err := d.r.do(ctx, func() (err error) {
+		todos, err = d.inner.ListFields(ctx, scope, fields)
+		return err
+	})
+	return todos, err
+}
+
+func (d *resilientTodoRepository) Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.Todo, error) {
+	var todo *models.Todo
+	err := d.r.do(ctx, func() (err error) {
+		todo, err = d.inner.Get(ctx, scope, id)
+		return err
+	})
+	return todo, err
+}
+
+func (d *resilientTodoRepository) Create(ctx context.Context, todo *models.Todo) error {
+	// Fix the ID up front so a retry after an ambiguous failure (e.g. a
+	// network error after the insert landed) can't create a second copy
+	if todo.ID.IsZero() {
+		todo.ID = primitive.NewObjectID()
+	}
+
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Create(ctx, todo)
+		if attempt > 1 && errors.Is(err, ErrDuplicate) {
+			// An earlier attempt succeeded
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientTodoRepository) Update(ctx context.Context, todo *models.Todo) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.Update(ctx, todo)
+	})
+}
+
+func (d *resilientTodoRepository) UpdateIfUnchanged(ctx context.Context, todo *models.Todo, lastUpdated time.Time) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.UpdateIfUnchanged(ctx, todo, lastUpdated)
+	})
+}
+
+func (d *resilientTodoRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Delete(ctx, scope, id)
+		if attempt > 1 && errors.Is(err, ErrNotFound) {
+			// An earlier attempt deleted it
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientTodoRepository) DeleteAll(ctx context.Context, scope Scope) (int64, error) {
+	var deleted int64
+	err := d.r.do(ctx, func() error {
+		n, err := d.inner.DeleteAll(ctx, scope)
+		deleted += n
+		return err
+	})
+	return deleted, err
+}
+
+func (d *resilientTodoRepository) ReplaceTag(ctx context.Context, scope Scope, from, to string, now time.Time) (int64, error) {
+	var changed int64
+	err := d.r.do(ctx, func() error {
+		n, err := d.inner.ReplaceTag(ctx, scope, from, to, now)
+		changed += n
+		return err
+	})
+	return changed, err
+}
+
+func (d *resilientTodoRepository) Count(ctx context.Context, scope Scope) (int64, error) {
+	var n int64
+	err := d.r.do(ctx, func() (err error) {
+		n, err = d.inner.Count(ctx, scope)
+		return err
+	})
+	return n, err
+}
+
+func (d *resilientTodoRepository) CountMatching(ctx context.Context, scope Scope, filter ListFilter) (int64, error) {
+	var n int64
+	err := d.r.do(ctx, func() (err error) {
+		n, err = d.inner.CountMatching(ctx, scope, filter)
+		return err
+	})
+	return n, err
+}
+
+func (d *resilientTodoRepository) Facets(ctx context.Context, scope Scope, filter ListFilter) (*models.TodoFacets, error) {
+	var facets *models.TodoFacets
+	err := d.r.do(ctx, func() (err error) {
+		facets, err = d.inner.Facets(ctx, scope, filter)
+		return err
+	})
+	return facets, err
+}
+
+func (d *resilientTodoRepository) CountAll(ctx context.Context) (int64, error) {
+	var n int64
+	err := d.r.do(ctx, func() (err error) {
+		n, err = d.inner.CountAll(ctx)
+		return err
+	})
+	return n, err
+}
+
+func (d *resilientTodoRepository) ListPage(ctx context.Context, scope Scope, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	var todos []models.Todo
+	err := d.r.do(ctx, func() (err error) {
+		todos, err = d.inner.ListPage(ctx, scope, after, limit)
+		return err
+	})
+	return todos, err
+}
+
+func (d *resilientTodoRepository) ListChanged(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	var todos []models.Todo
+	err := d.r.do(ctx, func() (err error) {
+		todos, err = d.inner.ListChanged(ctx, since, after, limit)
+		return err
+	})
+	return todos, err
+}
+
+func (d *resilientTodoRepository) ListDueByDay(ctx context.Context, scope Scope, from, to time.Time, loc *time.Location) (map[string][]models.Todo, error) {
+	var days map[string][]models.Todo
+	err := d.r.do(ctx, func() (err error) {
+		days, err = d.inner.ListDueByDay(ctx, scope, from, to, loc)
+		return err
+	})
+	return days, err
+}
+
+func (d *resilientTodoRepository) ListNear(ctx context.Context, scope Scope, center *models.Location, radius float64) ([]models.Todo, error) {
+	var todos []models.Todo
+	err := d.r.do(ctx, func() (err error) {
+		todos, err = d.inner.ListNear(ctx, scope, center, radius)
+		return err
+	})
+	return todos, err
+}
+
+func (d *resilientTodoRepository) Stats(ctx context.Context, scope Scope, since, overdueBefore time.Time, loc *time.Location) (*models.TodoStats, error) {
+	var stats *models.TodoStats
+	err := d.r.do(ctx, func() (err error) {
+		stats, err = d.inner.Stats(ctx, scope, since, overdueBefore, loc)
+		return err
+	})
+	return stats, err
+}
+
+type resilientUserRepository struct {
+	inner UserRepository
+	r     *Resilience
+}
+
+func (d *resilientUserRepository) Touch(ctx context.Context, userID string, seen time.Time) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.Touch(ctx, userID, seen)
+	})
+}
+
+func (d *resilientUserRepository) ListInactive(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	var ids []string
+	err := d.r.do(ctx, func() (err error) {
+		ids, err = d.inner.ListInactive(ctx, before, limit)
+		return err
+	})
+	return ids, err
+}
+
+func (d *resilientUserRepository) List(ctx context.Context, after string, limit int) ([]models.User, error) {
+	var users []models.User
+	err := d.r.do(ctx, func() (err error) {
+		users, err = d.inner.List(ctx, after, limit)
+		return err
+	})
+	return users, err
+}
+
+func (d *resilientUserRepository) Count(ctx context.Context) (int64, error) {
+	var n int64
+	err := d.r.do(ctx, func() (err error) {
+		n, err = d.inner.Count(ctx)
+		return err
+	})
+	return n, err
+}
+
+func (d *resilientUserRepository) GetSettings(ctx context.Context, userID string) (models.UserSettings, error) {
+	var settings models.UserSettings
+	err := d.r.do(ctx, func() (err error) {
+		settings, err = d.inner.GetSettings(ctx, userID)
+		return err
+	})
+	return settings, err
+}
+
+func (d *resilientUserRepository) SaveSettings(ctx context.Context, userID string, settings models.UserSettings) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.SaveSettings(ctx, userID, settings)
+	})
+}
+
+func (d *resilientUserRepository) ClaimDigest(ctx context.Context, userID string, due time.Time) (bool, error) {
+	var claimed bool
+	err := d.r.do(ctx, func() (err error) {
+		claimed, err = d.inner.ClaimDigest(ctx, userID, due)
+		return err
+	})
+	return claimed, err
+}
+
+func (d *resilientUserRepository) ClaimDailyReset(ctx context.Context, userID string, due time.Time) (bool, error) {
+	var claimed bool
+	err := d.r.do(ctx, func() (err error) {
+		claimed, err = d.inner.ClaimDailyReset(ctx, userID, due)
+		return err
+	})
+	return claimed, err
+}
+
+func (d *resilientUserRepository) ClaimStaleNudge(ctx context.Context, userID string, due time.Time) (bool, error) {
+	var claimed bool
+	err := d.r.do(ctx, func() (err error) {
+		claimed, err = d.inner.ClaimStaleNudge(ctx, userID, due)
+		return err
+	})
+	return claimed, err
+}
+
+func (d *resilientUserRepository) Delete(ctx context.Context, userID string) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.Delete(ctx, userID)
+	})
+}
+
+type resilientSessionRepository struct {
+	inner SessionRepository
+	r     *Resilience
+}
+
+func (d *resilientSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Create(ctx, session)
+		if attempt > 1 && errors.Is(err, ErrDuplicate) {
+			// An earlier attempt succeeded
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientSessionRepository) Get(ctx context.Context, id string) (*models.Session, error) {
+	var session *models.Session
+	err := d.r.do(ctx, func() (err error) {
+		session, err = d.inner.Get(ctx, id)
+		return err
+	})
+	return session, err
+}
+
+func (d *resilientSessionRepository) ListByUser(ctx context.Context, userID string) ([]models.Session, error) {
+	var sessions []models.Session
+	err := d.r.do(ctx, func() (err error) {
+		sessions, err = d.inner.ListByUser(ctx, userID)
+		return err
+	})
+	return sessions, err
+}
+
+func (d *resilientSessionRepository) Touch(ctx context.Context, id string, seen time.Time) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.Touch(ctx, id, seen)
+	})
+}
+
+func (d *resilientSessionRepository) Delete(ctx context.Context, userID, id string) error {
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Delete(ctx, userID, id)
+		if attempt > 1 && errors.Is(err, ErrNotFound) {
+			// An earlier attempt deleted it
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientSessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.DeleteByUser(ctx, userID)
+	})
+}
+
+type resilientWorkspaceRepository struct {
+	inner WorkspaceRepository
+	r     *Resilience
+}
+
+func (d *resilientWorkspaceRepository) Create(ctx context.Context, workspace *models.Workspace) error {
+	// Fix the ID up front so a retry can recognise its own earlier insert
+	if workspace.ID.IsZero() {
+		workspace.ID = primitive.NewObjectID()
+	}
+
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Create(ctx, workspace)
+		if attempt > 1 && errors.Is(err, ErrDuplicate) {
+			// An earlier attempt succeeded
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientWorkspaceRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	var workspace *models.Workspace
+	err := d.r.do(ctx, func() (err error) {
+		workspace, err = d.inner.Get(ctx, id)
+		return err
+	})
+	return workspace, err
+}
+
+func (d *resilientWorkspaceRepository) ListByMember(ctx context.Context, userID string) ([]models.Workspace, error) {
+	var workspaces []models.Workspace
+	err := d.r.do(ctx, func() (err error) {
+		workspaces, err = d.inner.ListByMember(ctx, userID)
+		return err
+	})
+	return workspaces, err
+}
+
+func (d *resilientWorkspaceRepository) AddMember(ctx context.Context, id primitive.ObjectID, member models.Member) error {
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.AddMember(ctx, id, member)
+		if attempt > 1 && errors.Is(err, ErrDuplicate) {
+			// An earlier attempt added them
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientWorkspaceRepository) SetMemberRole(ctx context.Context, id primitive.ObjectID, userID string, role authz.Role) error {
+	// Setting a role is idempotent, so it's safe to retry as is
+	return d.r.do(ctx, func() error {
+		return d.inner.SetMemberRole(ctx, id, userID, role)
+	})
+}
+
+func (d *resilientWorkspaceRepository) RemoveMember(ctx context.Context, id primitive.ObjectID, userID string) error {
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.RemoveMember(ctx, id, userID)
+		if attempt > 1 && errors.Is(err, ErrNotFound) {
+			// An earlier attempt removed them
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientWorkspaceRepository) Update(ctx context.Context, workspace *models.Workspace) error {
+	// Saving the same name and color again is harmless
+	return d.r.do(ctx, func() error {
+		return d.inner.Update(ctx, workspace)
+	})
+}
+
+func (d *resilientWorkspaceRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Delete(ctx, id)
+		if attempt > 1 && errors.Is(err, ErrNotFound) {
+			// An earlier attempt deleted it
+			return nil
+		}
+		return err
+	})
+}
+
+type resilientShareRepository struct {
+	inner ShareRepository
+	r     *Resilience
+}
+
+func (d *resilientShareRepository) Save(ctx context.Context, share *models.Share) error {
+	// Saving is an upsert, so retrying can't create a second share
+	return d.r.do(ctx, func() error {
+		return d.inner.Save(ctx, share)
+	})
+}
+
+func (d *resilientShareRepository) ListByTodo(ctx context.Context, ownerID string, todoID primitive.ObjectID) ([]models.Share, error) {
+	var shares []models.Share
+	err := d.r.do(ctx, func() (err error) {
+		shares, err = d.inner.ListByTodo(ctx, ownerID, todoID)
+		return err
+	})
+	return shares, err
+}
+
+func (d *resilientShareRepository) ListByGrantee(ctx context.Context, grantees []string) ([]models.Share, error) {
+	var shares []models.Share
+	err := d.r.do(ctx, func() (err error) {
+		shares, err = d.inner.ListByGrantee(ctx, grantees)
+		return err
+	})
+	return shares, err
+}
+
+func (d *resilientShareRepository) Delete(ctx context.Context, todoID, id primitive.ObjectID) error {
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Delete(ctx, todoID, id)
+		if attempt > 1 && errors.Is(err, ErrNotFound) {
+			// An earlier attempt deleted it
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientShareRepository) DeleteByTodo(ctx context.Context, todoID primitive.ObjectID) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.DeleteByTodo(ctx, todoID)
+	})
+}
+
+func (d *resilientShareRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.DeleteByOwner(ctx, ownerID)
+	})
+}
+
+type resilientPublicLinkRepository struct {
+	inner PublicLinkRepository
+	r     *Resilience
+}
+
+func (d *resilientPublicLinkRepository) Replace(ctx context.Context, link *models.PublicLink) error {
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Replace(ctx, link)
+		if attempt > 1 && errors.Is(err, ErrDuplicate) {
+			// An earlier attempt stored it
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientPublicLinkRepository) Get(ctx context.Context, tokenHash string) (*models.PublicLink, error) {
+	var link *models.PublicLink
+	err := d.r.do(ctx, func() (err error) {
+		link, err = d.inner.Get(ctx, tokenHash)
+		return err
+	})
+	return link, err
+}
+
+func (d *resilientPublicLinkRepository) Delete(ctx context.Context, ownerID string, todoID primitive.ObjectID) error {
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Delete(ctx, ownerID, todoID)
+		if attempt > 1 && errors.Is(err, ErrNotFound) {
+			// An earlier attempt revoked it
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientPublicLinkRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.DeleteByOwner(ctx, ownerID)
+	})
+}
+
+type resilientCustomFieldRepository struct {
+	inner CustomFieldRepository
+	r     *Resilience
+}
+
+func (d *resilientCustomFieldRepository) List(ctx context.Context, scope Scope) ([]models.CustomField, error) {
+	var fields []models.CustomField
+	err := d.r.do(ctx, func() (err error) {
+		fields, err = d.inner.List(ctx, scope)
+		return err
+	})
+	return fields, err
+}
+
+func (d *resilientCustomFieldRepository) Create(ctx context.Context, field *models.CustomField) error {
+	// Assign the ID up front so a retry can't store the field twice
+	if field.ID.IsZero() {
+		field.ID = primitive.NewObjectID()
+	}
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Create(ctx, field)
+		if attempt > 1 && errors.Is(err, ErrDuplicate) {
+			// An earlier attempt stored it
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientCustomFieldRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Delete(ctx, scope, id)
+		if attempt > 1 && errors.Is(err, ErrNotFound) {
+			// An earlier attempt deleted it
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientCustomFieldRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.DeleteAll(ctx, scope)
+	})
+}
+
+type resilientSavedFilterRepository struct {
+	inner SavedFilterRepository
+	r     *Resilience
+}
+
+func (d *resilientSavedFilterRepository) List(ctx context.Context, scope Scope) ([]models.SavedFilter, error) {
+	var filters []models.SavedFilter
+	err := d.r.do(ctx, func() (err error) {
+		filters, err = d.inner.List(ctx, scope)
+		return err
+	})
+	return filters, err
+}
+
+func (d *resilientSavedFilterRepository) Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.SavedFilter, error) {
+	var filter *models.SavedFilter
+	err := d.r.do(ctx, func() (err error) {
+		filter, err = d.inner.Get(ctx, scope, id)
+		return err
+	})
+	return filter, err
+}
+
+func (d *resilientSavedFilterRepository) Create(ctx context.Context, filter *models.SavedFilter) error {
+	// Assign the ID up front so a retry can't store the filter twice
+	if filter.ID.IsZero() {
+		filter.ID = primitive.NewObjectID()
+	}
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Create(ctx, filter)
+		if attempt > 1 && errors.Is(err, ErrDuplicate) {
+			// An earlier attempt stored it
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientSavedFilterRepository) Update(ctx context.Context, filter *models.SavedFilter) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.Update(ctx, filter)
+	})
+}
+
+func (d *resilientSavedFilterRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Delete(ctx, scope, id)
+		if attempt > 1 && errors.Is(err, ErrNotFound) {
+			// An earlier attempt deleted it
+			return nil
+		}
+		return err
+	})
+}
+
+func (d *resilientSavedFilterRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.DeleteAll(ctx, scope)
+	})
+}
+
+type resilientRevisionRepository struct {
+	inner RevisionRepository
+	r     *Resilience
+}
+
+func (d *resilientRevisionRepository) List(ctx context.Context, todoID primitive.ObjectID) ([]models.Revision, error) {
+	var revisions []models.Revision
+	err := d.r.do(ctx, func() (err error) {
+		revisions, err = d.inner.List(ctx, todoID)
+		return err
+	})
+	return revisions, err
+}
+
+func (d *resilientRevisionRepository) Create(ctx context.Context, revision *models.Revision) error {
+	// Assign the ID up front so a retry can tell its own earlier attempt
+	// apart from another revision with the same number
+	if revision.ID.IsZero() {
+		revision.ID = primitive.NewObjectID()
+	}
+	attempt := 0
+	return d.r.do(ctx, func() error {
+		attempt++
+		err := d.inner.Create(ctx, revision)
+		if attempt > 1 && errors.Is(err, ErrDuplicate) {
+			revisions, listErr := d.inner.List(ctx, revision.TodoID)
+			if listErr == nil && slices.ContainsFunc(revisions, func(r models.Revision) bool { return r.ID == revision.ID }) {
+				// An earlier attempt stored it
+				return nil
+			}
+		}
+		return err
+	})
+}
+
+func (d *resilientRevisionRepository) DeleteBefore(ctx context.Context, todoID primitive.ObjectID, number int) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.DeleteBefore(ctx, todoID, number)
+	})
+}
+
+func (d *resilientRevisionRepository) DeleteByTodo(ctx context.Context, todoID primitive.ObjectID) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.DeleteByTodo(ctx, todoID)
+	})
+}
+
+func (d *resilientRevisionRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.DeleteAll(ctx, scope)
+	})
+}
+
+type resilientOutboxRepository struct {
+	inner OutboxRepository
+	r     *Resilience
+}
+
+func (d *resilientOutboxRepository) Add(ctx context.Context, entries ...*models.OutboxEntry) error {
+	// Assign IDs up front so a retry skips entries an earlier attempt stored
+	for _, entry := range entries {
+		if entry.ID.IsZero() {
+			entry.ID = primitive.NewObjectID()
+		}
+	}
+	return d.r.do(ctx, func() error {
+		return d.inner.Add(ctx, entries...)
+	})
+}
+
+func (d *resilientOutboxRepository) Pending(ctx context.Context, limit int) ([]models.OutboxEntry, error) {
+	var entries []models.OutboxEntry
+	err := d.r.do(ctx, func() (err error) {
+		entries, err = d.inner.Pending(ctx, limit)
+		return err
+	})
+	return entries, err
+}
+
+func (d *resilientOutboxRepository) MarkDelivered(ctx context.Context, ids []primitive.ObjectID, at time.Time) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.MarkDelivered(ctx, ids, at)
+	})
+}
+
+func (d *resilientOutboxRepository) DeleteDelivered(ctx context.Context, cutoff time.Time) (int64, error) {
+	var n int64
+	err := d.r.do(ctx, func() (err error) {
+		n, err = d.inner.DeleteDelivered(ctx, cutoff)
+		return err
+	})
+	return n, err
+}
+
+type resilientTombstoneRepository struct {
+	inner TombstoneRepository
+	r     *Resilience
+}
+
+func (d *resilientTombstoneRepository) Save(ctx context.Context, tombstone *models.Tombstone) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.Save(ctx, tombstone)
+	})
+}
+
+func (d *resilientTombstoneRepository) ListSince(ctx context.Context, scope Scope, since time.Time) ([]models.Tombstone, error) {
+	var tombstones []models.Tombstone
+	err := d.r.do(ctx, func() (err error) {
+		tombstones, err = d.inner.ListSince(ctx, scope, since)
+		return err
+	})
+	return tombstones, err
+}
+
+func (d *resilientTombstoneRepository) ListAllSince(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Tombstone, error) {
+	var tombstones []models.Tombstone
+	err := d.r.do(ctx, func() (err error) {
+		tombstones, err = d.inner.ListAllSince(ctx, since, after, limit)
+		return err
+	})
+	return tombstones, err
+}
+
+func (d *resilientTombstoneRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	var n int64
+	err := d.r.do(ctx, func() (err error) {
+		n, err = d.inner.DeleteBefore(ctx, cutoff)
+		return err
+	})
+	return n, err
+}
+
+func (d *resilientTombstoneRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.DeleteAll(ctx, scope)
+	})
+}
+
+type resilientSlackLinkRepository struct {
+	inner SlackLinkRepository
+	r     *Resilience
+}
+
+func (d *resilientSlackLinkRepository) Save(ctx context.Context, link *models.SlackLink) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.Save(ctx, link)
+	})
+}
+
+func (d *resilientSlackLinkRepository) Get(ctx context.Context, userID string) (*models.SlackLink, error) {
+	var link *models.SlackLink
+	err := d.r.do(ctx, func() (err error) {
+		link, err = d.inner.Get(ctx, userID)
+		return err
+	})
+	return link, err
+}
+
+func (d *resilientSlackLinkRepository) GetBySlackUser(ctx context.Context, teamID, slackUserID string) (*models.SlackLink, error) {
+	var link *models.SlackLink
+	err := d.r.do(ctx, func() (err error) {
+		link, err = d.inner.GetBySlackUser(ctx, teamID, slackUserID)
+		return err
+	})
+	return link, err
+}
+
+func (d *resilientSlackLinkRepository) List(ctx context.Context, after string, limit int) ([]models.SlackLink, error) {
+	var links []models.SlackLink
+	err := d.r.do(ctx, func() (err error) {
+		links, err = d.inner.List(ctx, after, limit)
+		return err
+	})
+	return links, err
+}
+
+func (d *resilientSlackLinkRepository) ClaimOverdueCheck(ctx context.Context, userID string, at time.Time) (bool, error) {
+	var claimed bool
+	err := d.r.do(ctx, func() (err error) {
+		claimed, err = d.inner.ClaimOverdueCheck(ctx, userID, at)
+		return err
+	})
+	return claimed, err
+}
+
+func (d *resilientSlackLinkRepository) Delete(ctx context.Context, userID string) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.Delete(ctx, userID)
+	})
+}
+
+type resilientAuditRepository struct {
+	inner AuditRepository
+	r     *Resilience
+}
+
+func (d *resilientAuditRepository) Add(ctx context.Context, entries ...*models.AuditEntry) error {
+	return d.r.do(ctx, func() error {
+		return d.inner.Add(ctx, entries...)
+	})
+}
+
+func (d *resilientAuditRepository) List(ctx context.Context, q models.AuditQuery) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	err := d.r.do(ctx, func() (err error) {
+		entries, err = d.inner.List(ctx, q)
+		return err
+	})
+	return entries, err
+}
+
+func (d *resilientAuditRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	var n int64
+	err := d.r.do(ctx, func() (err error) {
+		n, err = d.inner.DeleteBefore(ctx, cutoff)
+		return err
+	})
+	return n, err
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scope selects a set of todos: either a user's personal todos, or every todo
+// in a workspace no matter which member created it
+type Scope struct {
+	UserID      string
+	WorkspaceID primitive.ObjectID
+}
+
+// Personal scopes to the user's own todos outside any workspace
+func Personal(userID string) Scope {
+	return Scope{UserID: userID}
+}
+
+// Workspace scopes to the todos of a workspace
+func Workspace(id primitive.ObjectID) Scope {
+	return Scope{WorkspaceID: id}
+}
+
+// ScopeOf returns the scope a todo belongs to
+func ScopeOf(todo *models.Todo) Scope {
+	if todo.WorkspaceID != nil {
+		return Workspace(*todo.WorkspaceID)
+	}
+	return Personal(todo.UserID)
+}
+
+// ScopeOfField returns the scope a custom field belongs to
+func ScopeOfField(field *models.CustomField) Scope {
+	if field.WorkspaceID != nil {
+		return Workspace(*field.WorkspaceID)
+	}
+	return Personal(field.UserID)
+}
+
+// ScopeOfFilter returns the scope a saved filter belongs to
+func ScopeOfFilter(filter *models.SavedFilter) Scope {
+	if filter.WorkspaceID != nil {
+		return Workspace(*filter.WorkspaceID)
+	}
+	return Personal(filter.UserID)
+}
+
+// ScopeOfRevision returns the scope the revision's todo belongs to
+func ScopeOfRevision(revision *models.Revision) Scope {
+	if revision.WorkspaceID != nil {
+		return Workspace(*revision.WorkspaceID)
+	}
+	return Personal(revision.UserID)
+}
+
+// ScopeOfTombstone returns the scope the deleted todo belonged to
+func ScopeOfTombstone(tombstone *models.Tombstone) Scope {
+	if tombstone.WorkspaceID != nil {
+		return Workspace(*tombstone.WorkspaceID)
+	}
+	return Personal(tombstone.UserID)
+}
+
+// IsWorkspace reports whether the scope is a workspace
+func (s Scope) IsWorkspace() bool {
+	return !s.WorkspaceID.IsZero()
+}
+
+// Contains reports whether the todo belongs to the scope
+func (s Scope) Contains(todo *models.Todo) bool {
+	if s.IsWorkspace() {
+		return todo.WorkspaceID != nil && *todo.WorkspaceID == s.WorkspaceID
+	}
+	return todo.WorkspaceID == nil && todo.UserID == s.UserID
+}
+
+// String identifies the scope, e.g. in cache keys
+func (s Scope) String() string {
+	if s.IsWorkspace() {
+		return "workspace:" + s.WorkspaceID.Hex()
+	}
+	return "user:" + s.UserID
+}
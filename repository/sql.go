@@ -0,0 +1,863 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqlDialect captures the differences between the SQL databases we support
+type sqlDialect struct {
+	// numbered placeholders ($1, $2) instead of ?
+	numberedParams bool
+	// timeValue converts a timestamp into the value stored in time columns
+	timeValue func(time.Time) any
+}
+
+// SQLStore is a connection to a SQL database that hands out the
+// repositories backed by it
+type SQLStore struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// Ping checks that the database is reachable
+func (s *SQLStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close releases the connection pool
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// Stores returns the repositories backed by this database
+func (s *SQLStore) Stores() *Stores {
+	return &Stores{
+		Todos:        &sqlTodoRepository{db: s.db, dialect: s.dialect},
+		Users:        &sqlUserRepository{db: s.db, dialect: s.dialect},
+		Sessions:     &sqlSessionRepository{db: s.db, dialect: s.dialect},
+		Workspaces:   &sqlWorkspaceRepository{db: s.db, dialect: s.dialect},
+		Shares:       &sqlShareRepository{db: s.db, dialect: s.dialect},
+		PublicLinks:  &sqlPublicLinkRepository{db: s.db, dialect: s.dialect},
+		CustomFields: &sqlCustomFieldRepository{db: s.db, dialect: s.dialect},
+		Filters:      &sqlSavedFilterRepository{db: s.db, dialect: s.dialect},
+		Revisions:    &sqlRevisionRepository{db: s.db, dialect: s.dialect},
+		Outbox:       &sqlOutboxRepository{db: s.db, dialect: s.dialect},
+		Tombstones:   &sqlTombstoneRepository{db: s.db, dialect: s.dialect},
+		SlackLinks:   &sqlSlackLinkRepository{db: s.db, dialect: s.dialect},
+		Audit:        &sqlAuditRepository{db: s.db, dialect: s.dialect},
+		// Each repository runs its own transactions, so calls across them
+		// commit one by one
+		Tx: WithoutTransactions(),
+	}
+}
+
+// sqlTodoRepository implements TodoRepository on top of database/sql. Todos
+// are stored as the same document the Mongo backend writes, encoded as
+// MongoDB Extended JSON, so new fields don't need a migration. The columns
+// next to it are the ones we filter and sort on.
+type sqlTodoRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// List returns all todos in the scope in creation order
+func (r *sqlTodoRepository) List(ctx context.Context, scope Scope) ([]models.Todo, error) {
+	where, args := scopeWhere(scope)
+	rows, err := r.db.QueryContext(ctx, r.query(
+		`SELECT doc FROM todos WHERE `+where+` ORDER BY created_at`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var todos []models.Todo
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var todo models.Todo
+		if err := bson.UnmarshalExtJSON(doc, false, &todo); err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	return todos, rows.Err()
+}
+
+// ListFields returns the todos in the scope with only the named fields.
+// Each todo is stored as one document, so it's read whole and the other
+// fields are cleared.
+func (r *sqlTodoRepository) ListFields(ctx context.Context, scope Scope, fields []string) ([]models.Todo, error) {
+	todos, err := r.List(ctx, scope)
+	project(todos, fields)
+	return todos, err
+}
+
+// Get returns a single todo in the scope
+func (r *sqlTodoRepository) Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.Todo, error) {
+	where, args := scopeWhere(scope)
+	var doc []byte
+	err := r.db.QueryRowContext(ctx, r.query(
+		`SELECT doc FROM todos WHERE id = ? AND `+where), append([]any{id.Hex()}, args...)...).Scan(&doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var todo models.Todo
+	if err := bson.UnmarshalExtJSON(doc, false, &todo); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// Create stores a new todo, assigning an ID unless one is already set
+func (r *sqlTodoRepository) Create(ctx context.Context, todo *models.Todo) error {
+	if todo.ID.IsZero() {
+		todo.ID = primitive.NewObjectID()
+	}
+	doc, err := bson.MarshalExtJSON(todo, false, false)
+	if err != nil {
+		return err
+	}
+
+	var workspaceID any
+	if todo.WorkspaceID != nil {
+		workspaceID = todo.WorkspaceID.Hex()
+	}
+	_, err = r.db.ExecContext(ctx, r.query(
+		`INSERT INTO todos (id, user_id, workspace_id, completed, completed_at, due_date, lat, lng, created_at, updated_at, doc) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		todo.ID.Hex(), todo.UserID, workspaceID, todo.Completed, r.completedValue(todo), r.dueValue(todo), latValue(todo), lngValue(todo), r.dialect.timeValue(todo.CreatedAt), r.dialect.timeValue(todo.UpdatedAt), string(doc))
+	return err
+}
+
+// Update replaces an existing todo within its own scope
+func (r *sqlTodoRepository) Update(ctx context.Context, todo *models.Todo) error {
+	doc, err := bson.MarshalExtJSON(todo, false, false)
+	if err != nil {
+		return err
+	}
+
+	where, args := scopeWhere(ScopeOf(todo))
+	result, err := r.db.ExecContext(ctx, r.query(
+		`UPDATE todos SET completed = ?, completed_at = ?, due_date = ?, lat = ?, lng = ?, updated_at = ?, doc = ? WHERE id = ? AND `+where),
+		append([]any{todo.Completed, r.completedValue(todo), r.dueValue(todo), latValue(todo), lngValue(todo), r.dialect.timeValue(todo.UpdatedAt), string(doc), todo.ID.Hex()}, args...)...)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// UpdateIfUnchanged replaces a todo in one statement matching its last
+// update time. Documents keep times to the millisecond while the
+// updated_at column may be finer, so the column is matched within that
+// millisecond. When nothing matches, the todo is looked up to tell a
+// deleted todo from a changed one.
+func (r *sqlTodoRepository) UpdateIfUnchanged(ctx context.Context, todo *models.Todo, lastUpdated time.Time) error {
+	doc, err := bson.MarshalExtJSON(todo, false, false)
+	if err != nil {
+		return err
+	}
+
+	where, args := scopeWhere(ScopeOf(todo))
+	lastUpdated = lastUpdated.Truncate(time.Millisecond)
+	result, err := r.db.ExecContext(ctx, r.query(
+		`UPDATE todos SET completed = ?, completed_at = ?, due_date = ?, lat = ?, lng = ?, updated_at = ?, doc = ?
+		WHERE id = ? AND updated_at >= ? AND updated_at < ? AND `+where),
+		append([]any{todo.Completed, r.completedValue(todo), r.dueValue(todo), latValue(todo), lngValue(todo), r.dialect.timeValue(todo.UpdatedAt), string(doc),
+			todo.ID.Hex(), r.dialect.timeValue(lastUpdated), r.dialect.timeValue(lastUpdated.Add(time.Millisecond))}, args...)...)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if _, err := r.Get(ctx, ScopeOf(todo), todo.ID); err != nil {
+		return err
+	}
+	return ErrConflict
+}
+
+// Delete removes a todo in the scope
+func (r *sqlTodoRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	where, args := scopeWhere(scope)
+	result, err := r.db.ExecContext(ctx, r.query(
+		`DELETE FROM todos WHERE id = ? AND `+where), append([]any{id.Hex()}, args...)...)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// DeleteAll removes every todo in the scope
+func (r *sqlTodoRepository) DeleteAll(ctx context.Context, scope Scope) (int64, error) {
+	where, args := scopeWhere(scope)
+	result, err := r.db.ExecContext(ctx, r.query(`DELETE FROM todos WHERE `+where), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ReplaceTag replaces a tag on every todo in the scope in one transaction.
+// Tags live in the document, so each todo in the scope is read and the ones
+// that change are written back.
+func (r *sqlTodoRepository) ReplaceTag(ctx context.Context, scope Scope, from, to string, now time.Time) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	where, args := scopeWhere(scope)
+	rows, err := tx.QueryContext(ctx, r.query(`SELECT doc FROM todos WHERE `+where), args...)
+	if err != nil {
+		return 0, err
+	}
+	var changed []models.Todo
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		var todo models.Todo
+		if err := bson.UnmarshalExtJSON(doc, false, &todo); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if todo.ReplaceTag(from, to) {
+			todo.UpdatedAt = now
+			changed = append(changed, todo)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, todo := range changed {
+		doc, err := bson.MarshalExtJSON(todo, false, false)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, r.query(`UPDATE todos SET updated_at = ?, doc = ? WHERE id = ?`),
+			r.dialect.timeValue(now), string(doc), todo.ID.Hex()); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(changed)), tx.Commit()
+}
+
+// Count returns the number of todos in the scope
+func (r *sqlTodoRepository) Count(ctx context.Context, scope Scope) (int64, error) {
+	where, args := scopeWhere(scope)
+	var n int64
+	err := r.db.QueryRowContext(ctx, r.query(`SELECT COUNT(*) FROM todos WHERE `+where), args...).Scan(&n)
+	return n, err
+}
+
+// CountMatching returns the number of todos in the scope the filter keeps.
+// Most filtered fields are only in the document, so the scope's todos are
+// read and filtered here, which avoids dialect-specific JSON functions.
+func (r *sqlTodoRepository) CountMatching(ctx context.Context, scope Scope, filter ListFilter) (int64, error) {
+	todos, err := r.List(ctx, scope)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, todo := range todos {
+		if filter.matches(&todo) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Facets counts the todos in the scope the filter keeps, reading them as
+// CountMatching does
+func (r *sqlTodoRepository) Facets(ctx context.Context, scope Scope, filter ListFilter) (*models.TodoFacets, error) {
+	todos, err := r.List(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	return countFacets(todos, filter), nil
+}
+
+// CountAll returns the number of todos of all users and workspaces
+func (r *sqlTodoRepository) CountAll(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM todos`).Scan(&n)
+	return n, err
+}
+
+// ListPage returns up to limit todos in the scope after the given ID, by ID
+func (r *sqlTodoRepository) ListPage(ctx context.Context, scope Scope, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	where, args := scopeWhere(scope)
+	rows, err := r.db.QueryContext(ctx, r.query(
+		`SELECT doc FROM todos WHERE id > ? AND `+where+` ORDER BY id LIMIT ?`),
+		append(append([]any{after.Hex()}, args...), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var todos []models.Todo
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var todo models.Todo
+		if err := bson.UnmarshalExtJSON(doc, false, &todo); err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	return todos, rows.Err()
+}
+
+// ListChanged returns up to limit todos updated at or after since, by ID
+func (r *sqlTodoRepository) ListChanged(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	rows, err := r.db.QueryContext(ctx, r.query(
+		`SELECT doc FROM todos WHERE updated_at >= ? AND id > ? ORDER BY id LIMIT ?`),
+		r.dialect.timeValue(since), after.Hex(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var todos []models.Todo
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var todo models.Todo
+		if err := bson.UnmarshalExtJSON(doc, false, &todo); err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	return todos, rows.Err()
+}
+
+// ListDueByDay returns the todos in the scope due in [from, to) keyed by
+// their due date in loc. Time zone functions differ between the dialects,
+// so the days are told apart here, as Stats does.
+func (r *sqlTodoRepository) ListDueByDay(ctx context.Context, scope Scope, from, to time.Time, loc *time.Location) (map[string][]models.Todo, error) {
+	where, args := scopeWhere(scope)
+	rows, err := r.db.QueryContext(ctx, r.query(
+		`SELECT doc FROM todos WHERE due_date >= ? AND due_date < ? AND `+where+` ORDER BY due_date`),
+		append([]any{r.dialect.timeValue(from), r.dialect.timeValue(to)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var todos []models.Todo
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var todo models.Todo
+		if err := bson.UnmarshalExtJSON(doc, false, &todo); err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return groupByDueDay(todos, loc), nil
+}
+
+// ListNear returns the todos in the scope located within radius meters of
+// center, nearest first. The database narrows them down to a box around
+// center; distances are worked out here, which avoids needing PostGIS or
+// SpatiaLite.
+func (r *sqlTodoRepository) ListNear(ctx context.Context, scope Scope, center *models.Location, radius float64) ([]models.Todo, error) {
+	where, args := scopeWhere(scope)
+	minLat, maxLat, minLng, maxLng := center.BoundingBox(radius)
+	rows, err := r.db.QueryContext(ctx, r.query(
+		`SELECT doc FROM todos WHERE lat BETWEEN ? AND ? AND lng BETWEEN ? AND ? AND `+where),
+		append([]any{minLat, maxLat, minLng, maxLng}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var todos []models.Todo
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var todo models.Todo
+		if err := bson.UnmarshalExtJSON(doc, false, &todo); err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return nearest(todos, center, radius), nil
+}
+
+// Stats summarises the todos in the scope. Statuses and overdue todos are
+// counted by the database; recent completions are few enough to bucket by day here, which
+// avoids dialect-specific date functions.
+func (r *sqlTodoRepository) Stats(ctx context.Context, scope Scope, since, overdueBefore time.Time, loc *time.Location) (*models.TodoStats, error) {
+	where, args := scopeWhere(scope)
+	rows, err := r.db.QueryContext(ctx, r.query(
+		`SELECT completed, COUNT(*), COALESCE(SUM(CASE WHEN due_date < ? THEN 1 ELSE 0 END), 0) FROM todos
+		WHERE `+where+` GROUP BY completed`),
+		append([]any{r.dialect.timeValue(overdueBefore)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &models.TodoStats{}
+	for rows.Next() {
+		var completed bool
+		var n, due int64
+		if err := rows.Scan(&completed, &n, &due); err != nil {
+			return nil, err
+		}
+		stats.Total += n
+		if completed {
+			stats.Completed += n
+		} else {
+			stats.Overdue += due
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	stats.Open = stats.Total - stats.Completed
+
+	rows, err = r.db.QueryContext(ctx, r.query(
+		`SELECT created_at, COALESCE(completed_at, updated_at) AS done_at FROM todos
+		WHERE completed = ? AND COALESCE(completed_at, updated_at) >= ? AND `+where+` ORDER BY done_at`),
+		append([]any{true, r.dialect.timeValue(since)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recent int64
+	var took time.Duration
+	for rows.Next() {
+		var createdAt, doneAt any
+		if err := rows.Scan(&createdAt, &doneAt); err != nil {
+			return nil, err
+		}
+		created, err := scanTime(createdAt)
+		if err != nil {
+			return nil, err
+		}
+		done, err := scanTime(doneAt)
+		if err != nil {
+			return nil, err
+		}
+
+		date := done.In(loc).Format(time.DateOnly)
+		if n := len(stats.CompletedPerDay); n > 0 && stats.CompletedPerDay[n-1].Date == date {
+			stats.CompletedPerDay[n-1].Count++
+		} else {
+			stats.CompletedPerDay = append(stats.CompletedPerDay, models.DayCount{Date: date, Count: 1})
+		}
+		recent++
+		took += done.Sub(created)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if recent > 0 {
+		avg := took.Seconds() / float64(recent)
+		stats.AverageCompletionSeconds = &avg
+	}
+	return stats, nil
+}
+
+// dueValue is the value of the due_date column, NULL for todos without one
+func (r *sqlTodoRepository) dueValue(todo *models.Todo) any {
+	if todo.DueDate == nil {
+		return nil
+	}
+	return r.dialect.timeValue(*todo.DueDate)
+}
+
+// completedValue returns the completed_at column's value for todo
+func (r *sqlTodoRepository) completedValue(todo *models.Todo) any {
+	if todo.CompletedAt == nil {
+		return nil
+	}
+	return r.dialect.timeValue(*todo.CompletedAt)
+}
+
+// latValue and lngValue are the values of the lat and lng columns, NULL for
+// todos without a location
+func latValue(todo *models.Todo) any {
+	if todo.Location == nil {
+		return nil
+	}
+	return todo.Location.Lat()
+}
+
+func lngValue(todo *models.Todo) any {
+	if todo.Location == nil {
+		return nil
+	}
+	return todo.Location.Lng()
+}
+
+// query rewrites ? placeholders for dialects that number their parameters
+func (r *sqlTodoRepository) query(q string) string {
+	return rebind(r.dialect, q)
+}
+
+// scopeWhere returns the condition and arguments selecting the todos in the
+// scope. Personal todos are the ones without a workspace.
+func scopeWhere(scope Scope) (string, []any) {
+	if scope.IsWorkspace() {
+		return "workspace_id = ?", []any{scope.WorkspaceID.Hex()}
+	}
+	return "user_id = ? AND workspace_id IS NULL", []any{scope.UserID}
+}
+
+// sqlUserRepository implements UserRepository on top of database/sql
+type sqlUserRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// Touch records that the user was seen at the given time
+func (r *sqlUserRepository) Touch(ctx context.Context, userID string, seen time.Time) error {
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`INSERT INTO users (id, created_at, last_seen) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET last_seen = excluded.last_seen`),
+		userID, r.dialect.timeValue(seen), r.dialect.timeValue(seen))
+	return err
+}
+
+// ListInactive returns up to limit users not seen since before
+func (r *sqlUserRepository) ListInactive(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT id FROM users WHERE last_seen < ? LIMIT ?`), r.dialect.timeValue(before), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// List returns up to limit users ordered by ID, starting after the given ID
+func (r *sqlUserRepository) List(ctx context.Context, after string, limit int) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT id, created_at, last_seen, settings FROM users WHERE id > ? ORDER BY id LIMIT ?`), after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		var createdAt, lastSeen any
+		var settings []byte
+		if err := rows.Scan(&user.ID, &createdAt, &lastSeen, &settings); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(settings, &user.Settings); err != nil {
+			return nil, err
+		}
+		if user.CreatedAt, err = scanTime(createdAt); err != nil {
+			return nil, err
+		}
+		if user.LastSeen, err = scanTime(lastSeen); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// Count returns the number of users
+func (r *sqlUserRepository) Count(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&n)
+	return n, err
+}
+
+// GetSettings returns the user's settings
+func (r *sqlUserRepository) GetSettings(ctx context.Context, userID string) (models.UserSettings, error) {
+	var settings models.UserSettings
+	var doc []byte
+	err := r.db.QueryRowContext(ctx, rebind(r.dialect,
+		`SELECT settings FROM users WHERE id = ?`), userID).Scan(&doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+	err = json.Unmarshal(doc, &settings)
+	return settings, err
+}
+
+// SaveSettings replaces the user's settings, creating the record if needed
+func (r *sqlUserRepository) SaveSettings(ctx context.Context, userID string, settings models.UserSettings) error {
+	doc, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	now := r.dialect.timeValue(time.Now())
+	_, err = r.db.ExecContext(ctx, rebind(r.dialect,
+		`INSERT INTO users (id, created_at, last_seen, settings) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET settings = excluded.settings`),
+		userID, now, now, string(doc))
+	return err
+}
+
+// ClaimDigest records that the user's digest due at due is being sent
+func (r *sqlUserRepository) ClaimDigest(ctx context.Context, userID string, due time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`UPDATE users SET digest_sent_at = ? WHERE id = ? AND (digest_sent_at IS NULL OR digest_sent_at < ?)`),
+		r.dialect.timeValue(due), userID, r.dialect.timeValue(due))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// ClaimDailyReset records that the user's daily reset due at due is being
+// run
+func (r *sqlUserRepository) ClaimDailyReset(ctx context.Context, userID string, due time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`UPDATE users SET daily_reset_at = ? WHERE id = ? AND (daily_reset_at IS NULL OR daily_reset_at < ?)`),
+		r.dialect.timeValue(due), userID, r.dialect.timeValue(due))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// ClaimStaleNudge records that the user's nudge about stale todos due at
+// due is being sent
+func (r *sqlUserRepository) ClaimStaleNudge(ctx context.Context, userID string, due time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`UPDATE users SET stale_nudged_at = ? WHERE id = ? AND (stale_nudged_at IS NULL OR stale_nudged_at < ?)`),
+		r.dialect.timeValue(due), userID, r.dialect.timeValue(due))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// Delete removes the user's record
+func (r *sqlUserRepository) Delete(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect, `DELETE FROM users WHERE id = ?`), userID)
+	return err
+}
+
+// sqlSessionRepository implements SessionRepository on top of database/sql.
+// Like todos, sessions are stored as Extended JSON documents.
+type sqlSessionRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// Create stores a new session
+func (r *sqlSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	doc, err := bson.MarshalExtJSON(session, false, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, rebind(r.dialect,
+		`INSERT INTO sessions (id, user_id, created_at, expires_at, doc) VALUES (?, ?, ?, ?, ?)`),
+		session.ID, session.UserID, r.dialect.timeValue(session.CreatedAt), r.dialect.timeValue(session.ExpiresAt), string(doc))
+	return err
+}
+
+// Get returns an unexpired session by ID
+func (r *sqlSessionRepository) Get(ctx context.Context, id string) (*models.Session, error) {
+	var doc []byte
+	err := r.db.QueryRowContext(ctx, rebind(r.dialect,
+		`SELECT doc FROM sessions WHERE id = ? AND expires_at > ?`), id, r.dialect.timeValue(time.Now())).Scan(&doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session models.Session
+	if err := bson.UnmarshalExtJSON(doc, false, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListByUser returns the user's unexpired sessions, most recently created
+// first. Expired sessions are deleted along the way.
+func (r *sqlSessionRepository) ListByUser(ctx context.Context, userID string) ([]models.Session, error) {
+	now := r.dialect.timeValue(time.Now())
+	if _, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM sessions WHERE user_id = ? AND expires_at <= ?`), userID, now); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT doc FROM sessions WHERE user_id = ? AND expires_at > ? ORDER BY created_at DESC`), userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var session models.Session
+		if err := bson.UnmarshalExtJSON(doc, false, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Touch records that the session was used at the given time
+func (r *sqlSessionRepository) Touch(ctx context.Context, id string, seen time.Time) error {
+	session, err := r.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	session.LastSeen = seen
+	doc, err := bson.MarshalExtJSON(session, false, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, rebind(r.dialect, `UPDATE sessions SET doc = ? WHERE id = ?`), string(doc), id)
+	return err
+}
+
+// Delete revokes a session owned by the user
+func (r *sqlSessionRepository) Delete(ctx context.Context, userID, id string) error {
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM sessions WHERE id = ? AND user_id = ?`), id, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// DeleteByUser revokes all of the user's sessions
+func (r *sqlSessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect, `DELETE FROM sessions WHERE user_id = ?`), userID)
+	return err
+}
+
+func rebind(dialect sqlDialect, q string) string {
+	if !dialect.numberedParams {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, ch := range q {
+		if ch == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// requireAffected maps a write that touched no rows to ErrNotFound
+func requireAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// migrateSQL applies pending migrations in order on a single connection and
+// records each one in schema_migrations. Never edit a migration once it has
+// shipped; append a new one instead.
+func migrateSQL(ctx context.Context, conn *sql.Conn, dialect sqlDialect, migrations []string) error {
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for i := current; i < len(migrations); i++ {
+		version := i + 1
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, rebind(dialect, `INSERT INTO schema_migrations (version) VALUES (?)`), version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
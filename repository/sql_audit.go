@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqlAuditRepository implements AuditRepository on top of database/sql.
+// Entries are stored as Extended JSON documents, with the fields they're
+// selected by in columns next to them.
+type sqlAuditRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Add stores entries, assigning their IDs
+func (r *sqlAuditRepository) Add(ctx context.Context, entries ...*models.AuditEntry) error {
+	for _, entry := range entries {
+		if entry.ID.IsZero() {
+			entry.ID = primitive.NewObjectID()
+		}
+		doc, err := bson.MarshalExtJSON(entry, false, false)
+		if err != nil {
+			return err
+		}
+		_, err = r.db.ExecContext(ctx, rebind(r.dialect,
+			`INSERT INTO audit_log (id, time, user_id, method, path, status, doc) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+			entry.ID.Hex(), r.dialect.timeValue(entry.Time), entry.UserID, entry.Method, entry.Path, entry.Status, string(doc))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns up to q.Limit entries selected by q, newest first
+func (r *sqlAuditRepository) List(ctx context.Context, q models.AuditQuery) ([]models.AuditEntry, error) {
+	where := []string{"1 = 1"}
+	var args []any
+	if q.UserID != "" {
+		where = append(where, "user_id = ?")
+		args = append(args, q.UserID)
+	}
+	if q.Method != "" {
+		where = append(where, "method = ?")
+		args = append(args, q.Method)
+	}
+	if q.PathPrefix != "" {
+		where = append(where, `path LIKE ? ESCAPE '\'`)
+		args = append(args, likeEscaper.Replace(q.PathPrefix)+"%")
+	}
+	if q.MinStatus != 0 {
+		where = append(where, "status >= ?")
+		args = append(args, q.MinStatus)
+	}
+	if q.MaxStatus != 0 {
+		where = append(where, "status <= ?")
+		args = append(args, q.MaxStatus)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, r.dialect.timeValue(q.Since))
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "time < ?")
+		args = append(args, r.dialect.timeValue(q.Until))
+	}
+	if !q.Before.IsZero() {
+		where = append(where, "id < ?")
+		args = append(args, q.Before.Hex())
+	}
+
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT doc FROM audit_log WHERE `+strings.Join(where, " AND ")+` ORDER BY id DESC LIMIT ?`),
+		append(args, q.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var entry models.AuditEntry
+		if err := bson.UnmarshalExtJSON(doc, false, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteBefore removes entries recorded before cutoff
+func (r *sqlAuditRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM audit_log WHERE time < ?`), r.dialect.timeValue(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqlCustomFieldRepository implements CustomFieldRepository on top of
+// database/sql. Like todos, fields are stored as Extended JSON documents
+// next to the columns their scope is selected by.
+type sqlCustomFieldRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// List returns the fields defined in the scope, oldest first
+func (r *sqlCustomFieldRepository) List(ctx context.Context, scope Scope) ([]models.CustomField, error) {
+	where, args := scopeWhere(scope)
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT doc FROM custom_fields WHERE `+where+` ORDER BY created_at`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fields []models.CustomField
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var field models.CustomField
+		if err := bson.UnmarshalExtJSON(doc, false, &field); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, rows.Err()
+}
+
+// Create stores a new field, assigning its ID
+func (r *sqlCustomFieldRepository) Create(ctx context.Context, field *models.CustomField) error {
+	if field.ID.IsZero() {
+		field.ID = primitive.NewObjectID()
+	}
+	doc, err := bson.MarshalExtJSON(field, false, false)
+	if err != nil {
+		return err
+	}
+
+	var workspaceID any
+	if field.WorkspaceID != nil {
+		workspaceID = field.WorkspaceID.Hex()
+	}
+	_, err = r.db.ExecContext(ctx, rebind(r.dialect,
+		`INSERT INTO custom_fields (id, user_id, workspace_id, created_at, doc) VALUES (?, ?, ?, ?, ?)`),
+		field.ID.Hex(), field.UserID, workspaceID, r.dialect.timeValue(field.CreatedAt), string(doc))
+	return err
+}
+
+// Delete removes a field in the scope
+func (r *sqlCustomFieldRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	where, args := scopeWhere(scope)
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM custom_fields WHERE id = ? AND `+where), append([]any{id.Hex()}, args...)...)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// DeleteAll removes every field in the scope
+func (r *sqlCustomFieldRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	where, args := scopeWhere(scope)
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect, `DELETE FROM custom_fields WHERE `+where), args...)
+	return err
+}
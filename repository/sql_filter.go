@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqlSavedFilterRepository implements SavedFilterRepository on top of
+// database/sql, storing filters as Extended JSON documents next to the
+// columns their scope is selected by
+type sqlSavedFilterRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// List returns the filters saved in the scope, oldest first
+func (r *sqlSavedFilterRepository) List(ctx context.Context, scope Scope) ([]models.SavedFilter, error) {
+	where, args := scopeWhere(scope)
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT doc FROM saved_filters WHERE `+where+` ORDER BY created_at`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filters []models.SavedFilter
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var filter models.SavedFilter
+		if err := bson.UnmarshalExtJSON(doc, false, &filter); err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, rows.Err()
+}
+
+// Get returns a filter in the scope or ErrNotFound
+func (r *sqlSavedFilterRepository) Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.SavedFilter, error) {
+	where, args := scopeWhere(scope)
+	var doc []byte
+	err := r.db.QueryRowContext(ctx, rebind(r.dialect,
+		`SELECT doc FROM saved_filters WHERE id = ? AND `+where), append([]any{id.Hex()}, args...)...).Scan(&doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var filter models.SavedFilter
+	if err := bson.UnmarshalExtJSON(doc, false, &filter); err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// Create stores a new filter, assigning its ID
+func (r *sqlSavedFilterRepository) Create(ctx context.Context, filter *models.SavedFilter) error {
+	if filter.ID.IsZero() {
+		filter.ID = primitive.NewObjectID()
+	}
+	doc, err := bson.MarshalExtJSON(filter, false, false)
+	if err != nil {
+		return err
+	}
+
+	var workspaceID any
+	if filter.WorkspaceID != nil {
+		workspaceID = filter.WorkspaceID.Hex()
+	}
+	_, err = r.db.ExecContext(ctx, rebind(r.dialect,
+		`INSERT INTO saved_filters (id, user_id, workspace_id, created_at, doc) VALUES (?, ?, ?, ?, ?)`),
+		filter.ID.Hex(), filter.UserID, workspaceID, r.dialect.timeValue(filter.CreatedAt), string(doc))
+	return err
+}
+
+// Update replaces a stored filter, returning ErrNotFound if it's gone
+func (r *sqlSavedFilterRepository) Update(ctx context.Context, filter *models.SavedFilter) error {
+	doc, err := bson.MarshalExtJSON(filter, false, false)
+	if err != nil {
+		return err
+	}
+
+	where, args := scopeWhere(ScopeOfFilter(filter))
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`UPDATE saved_filters SET doc = ? WHERE id = ? AND `+where),
+		append([]any{string(doc), filter.ID.Hex()}, args...)...)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Delete removes a filter in the scope
+func (r *sqlSavedFilterRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	where, args := scopeWhere(scope)
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM saved_filters WHERE id = ? AND `+where), append([]any{id.Hex()}, args...)...)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// DeleteAll removes every filter in the scope
+func (r *sqlSavedFilterRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	where, args := scopeWhere(scope)
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect, `DELETE FROM saved_filters WHERE `+where), args...)
+	return err
+}
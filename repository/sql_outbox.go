@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqlOutboxRepository implements OutboxRepository on top of database/sql.
+// Entries are stored as Extended JSON documents written when they're added;
+// delivery is only recorded in the delivered_at column.
+type sqlOutboxRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// Add stores entries, assigning their IDs
+func (r *sqlOutboxRepository) Add(ctx context.Context, entries ...*models.OutboxEntry) error {
+	for _, entry := range entries {
+		if entry.ID.IsZero() {
+			entry.ID = primitive.NewObjectID()
+		}
+		doc, err := bson.MarshalExtJSON(entry, false, false)
+		if err != nil {
+			return err
+		}
+		_, err = r.db.ExecContext(ctx, rebind(r.dialect,
+			`INSERT INTO outbox (id, created_at, doc) VALUES (?, ?, ?) ON CONFLICT (id) DO NOTHING`),
+			entry.ID.Hex(), r.dialect.timeValue(entry.CreatedAt), string(doc))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pending returns up to limit undelivered entries, oldest first
+func (r *sqlOutboxRepository) Pending(ctx context.Context, limit int) ([]models.OutboxEntry, error) {
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT doc FROM outbox WHERE delivered_at IS NULL ORDER BY created_at, id LIMIT ?`), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.OutboxEntry
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var entry models.OutboxEntry
+		if err := bson.UnmarshalExtJSON(doc, false, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// MarkDelivered records that the entries were published at the given time
+func (r *sqlOutboxRepository) MarkDelivered(ctx context.Context, ids []primitive.ObjectID, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders := "?"
+	args := []any{r.dialect.timeValue(at), ids[0].Hex()}
+	for _, id := range ids[1:] {
+		placeholders += ", ?"
+		args = append(args, id.Hex())
+	}
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`UPDATE outbox SET delivered_at = ? WHERE id IN (`+placeholders+`)`), args...)
+	return err
+}
+
+// DeleteDelivered removes entries delivered before cutoff
+func (r *sqlOutboxRepository) DeleteDelivered(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM outbox WHERE delivered_at < ?`), r.dialect.timeValue(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqlPublicLinkRepository implements PublicLinkRepository on top of
+// database/sql. Links have no free-form fields, so they're plain rows.
+type sqlPublicLinkRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// Replace stores the link, revoking any earlier link to the same todo
+func (r *sqlPublicLinkRepository) Replace(ctx context.Context, link *models.PublicLink) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM public_links WHERE todo_id = ?`), link.TodoID.Hex()); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, rebind(r.dialect,
+		`INSERT INTO public_links (token_hash, todo_id, owner_id, created_at) VALUES (?, ?, ?, ?)`),
+		link.TokenHash, link.TodoID.Hex(), link.OwnerID, r.dialect.timeValue(link.CreatedAt)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get returns the link with the given token hash
+func (r *sqlPublicLinkRepository) Get(ctx context.Context, tokenHash string) (*models.PublicLink, error) {
+	var todoID string
+	var createdAt any
+	link := models.PublicLink{TokenHash: tokenHash}
+	err := r.db.QueryRowContext(ctx, rebind(r.dialect,
+		`SELECT todo_id, owner_id, created_at FROM public_links WHERE token_hash = ?`), tokenHash).
+		Scan(&todoID, &link.OwnerID, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if link.TodoID, err = primitive.ObjectIDFromHex(todoID); err != nil {
+		return nil, err
+	}
+	if link.CreatedAt, err = scanTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Delete revokes the link to one of the owner's todos
+func (r *sqlPublicLinkRepository) Delete(ctx context.Context, ownerID string, todoID primitive.ObjectID) error {
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM public_links WHERE todo_id = ? AND owner_id = ?`), todoID.Hex(), ownerID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// DeleteByOwner revokes every link the owner made
+func (r *sqlPublicLinkRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect, `DELETE FROM public_links WHERE owner_id = ?`), ownerID)
+	return err
+}
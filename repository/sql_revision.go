@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqlRevisionRepository implements RevisionRepository on top of
+// database/sql. Revisions are stored as Extended JSON documents next to the
+// columns they're looked up by.
+type sqlRevisionRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// List returns the todo's revisions, oldest first
+func (r *sqlRevisionRepository) List(ctx context.Context, todoID primitive.ObjectID) ([]models.Revision, error) {
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT doc FROM revisions WHERE todo_id = ? ORDER BY rev`), todoID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []models.Revision
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var revision models.Revision
+		if err := bson.UnmarshalExtJSON(doc, false, &revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// Create stores a revision, assigning its ID
+func (r *sqlRevisionRepository) Create(ctx context.Context, revision *models.Revision) error {
+	if revision.ID.IsZero() {
+		revision.ID = primitive.NewObjectID()
+	}
+	doc, err := bson.MarshalExtJSON(revision, false, false)
+	if err != nil {
+		return err
+	}
+
+	var workspaceID any
+	if revision.WorkspaceID != nil {
+		workspaceID = revision.WorkspaceID.Hex()
+	}
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`INSERT INTO revisions (id, todo_id, rev, user_id, workspace_id, created_at, doc) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (todo_id, rev) DO NOTHING`),
+		revision.ID.Hex(), revision.TodoID.Hex(), revision.Number, revision.UserID, workspaceID, r.dialect.timeValue(revision.CreatedAt), string(doc))
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+// DeleteBefore removes the todo's revisions numbered below number
+func (r *sqlRevisionRepository) DeleteBefore(ctx context.Context, todoID primitive.ObjectID, number int) error {
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM revisions WHERE todo_id = ? AND rev < ?`), todoID.Hex(), number)
+	return err
+}
+
+// DeleteByTodo removes every revision of a todo
+func (r *sqlRevisionRepository) DeleteByTodo(ctx context.Context, todoID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect, `DELETE FROM revisions WHERE todo_id = ?`), todoID.Hex())
+	return err
+}
+
+// DeleteAll removes the revisions of every todo in the scope
+func (r *sqlRevisionRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	where, args := scopeWhere(scope)
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect, `DELETE FROM revisions WHERE `+where), args...)
+	return err
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqlShareRepository implements ShareRepository on top of database/sql.
+// Shares are Extended JSON documents like todos, with the columns we look
+// them up by next to the document.
+type sqlShareRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// Save creates the share, or updates the role of the existing share
+// with the same grantee
+func (r *sqlShareRepository) Save(ctx context.Context, share *models.Share) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var doc []byte
+	err = tx.QueryRowContext(ctx, rebind(r.dialect,
+		`SELECT doc FROM shares WHERE todo_id = ? AND grantee = ?`), share.TodoID.Hex(), share.Grantee).Scan(&doc)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if share.ID.IsZero() {
+			share.ID = primitive.NewObjectID()
+		}
+		data, err := bson.MarshalExtJSON(share, false, false)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, rebind(r.dialect,
+			`INSERT INTO shares (id, todo_id, owner_id, grantee, created_at, doc) VALUES (?, ?, ?, ?, ?, ?)`),
+			share.ID.Hex(), share.TodoID.Hex(), share.OwnerID, share.Grantee, r.dialect.timeValue(share.CreatedAt), string(data)); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		var existing models.Share
+		if err := bson.UnmarshalExtJSON(doc, false, &existing); err != nil {
+			return err
+		}
+		existing.Role = share.Role
+		existing.UpdatedAt = share.UpdatedAt
+		data, err := bson.MarshalExtJSON(existing, false, false)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, rebind(r.dialect,
+			`UPDATE shares SET doc = ? WHERE id = ?`), string(data), existing.ID.Hex()); err != nil {
+			return err
+		}
+		*share = existing
+	}
+	return tx.Commit()
+}
+
+// ListByTodo returns the shares of one of the owner's todos, oldest first
+func (r *sqlShareRepository) ListByTodo(ctx context.Context, ownerID string, todoID primitive.ObjectID) ([]models.Share, error) {
+	return r.list(ctx, `SELECT doc FROM shares WHERE todo_id = ? AND owner_id = ? ORDER BY created_at`,
+		todoID.Hex(), ownerID)
+}
+
+// ListByGrantee returns the shares granted to any of the grantee keys,
+// newest first
+func (r *sqlShareRepository) ListByGrantee(ctx context.Context, grantees []string) ([]models.Share, error) {
+	if len(grantees) == 0 {
+		return nil, nil
+	}
+	placeholders := "?"
+	args := []any{grantees[0]}
+	for _, grantee := range grantees[1:] {
+		placeholders += ", ?"
+		args = append(args, grantee)
+	}
+	return r.list(ctx, `SELECT doc FROM shares WHERE grantee IN (`+placeholders+`) ORDER BY created_at DESC`, args...)
+}
+
+// Delete removes one of the todo's shares
+func (r *sqlShareRepository) Delete(ctx context.Context, todoID, id primitive.ObjectID) error {
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM shares WHERE id = ? AND todo_id = ?`), id.Hex(), todoID.Hex())
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// DeleteByTodo removes every share of a todo
+func (r *sqlShareRepository) DeleteByTodo(ctx context.Context, todoID primitive.ObjectID) error {
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect, `DELETE FROM shares WHERE todo_id = ?`), todoID.Hex())
+	return err
+}
+
+// DeleteByOwner removes every share the owner made
+func (r *sqlShareRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect, `DELETE FROM shares WHERE owner_id = ?`), ownerID)
+	return err
+}
+
+func (r *sqlShareRepository) list(ctx context.Context, query string, args ...any) ([]models.Share, error) {
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect, query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []models.Share
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var share models.Share
+		if err := bson.UnmarshalExtJSON(doc, false, &share); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"todo-api/models"
+)
+
+// sqlSlackLinkRepository implements SlackLinkRepository on top of
+// database/sql, keyed by the user's ID
+type sqlSlackLinkRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// Save stores the link, replacing the user's earlier link and any link of
+// the same Slack account
+func (r *sqlSlackLinkRepository) Save(ctx context.Context, link *models.SlackLink) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM slack_links WHERE team_id = ? AND slack_user_id = ? AND user_id <> ?`),
+		link.TeamID, link.SlackUserID, link.UserID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, rebind(r.dialect,
+		`INSERT INTO slack_links (user_id, team_id, slack_user_id, created_at, overdue_checked_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET team_id = excluded.team_id, slack_user_id = excluded.slack_user_id,
+			created_at = excluded.created_at, overdue_checked_at = excluded.overdue_checked_at`),
+		link.UserID, link.TeamID, link.SlackUserID, r.dialect.timeValue(link.CreatedAt), r.dialect.timeValue(link.OverdueCheckedAt)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get returns the user's link
+func (r *sqlSlackLinkRepository) Get(ctx context.Context, userID string) (*models.SlackLink, error) {
+	return r.scanOne(r.db.QueryRowContext(ctx, rebind(r.dialect,
+		`SELECT user_id, team_id, slack_user_id, created_at, overdue_checked_at FROM slack_links WHERE user_id = ?`), userID))
+}
+
+// GetBySlackUser returns the link of a Slack account
+func (r *sqlSlackLinkRepository) GetBySlackUser(ctx context.Context, teamID, slackUserID string) (*models.SlackLink, error) {
+	return r.scanOne(r.db.QueryRowContext(ctx, rebind(r.dialect,
+		`SELECT user_id, team_id, slack_user_id, created_at, overdue_checked_at FROM slack_links WHERE team_id = ? AND slack_user_id = ?`),
+		teamID, slackUserID))
+}
+
+func (r *sqlSlackLinkRepository) scanOne(row *sql.Row) (*models.SlackLink, error) {
+	link, err := scanSlackLink(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return link, err
+}
+
+// scanSlackLink reads a link from a row of the columns Get selects
+func scanSlackLink(row interface{ Scan(...any) error }) (*models.SlackLink, error) {
+	var link models.SlackLink
+	var createdAt, checkedAt any
+	if err := row.Scan(&link.UserID, &link.TeamID, &link.SlackUserID, &createdAt, &checkedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if link.CreatedAt, err = scanTime(createdAt); err != nil {
+		return nil, err
+	}
+	if link.OverdueCheckedAt, err = scanTime(checkedAt); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// List returns up to limit links ordered by user ID, starting after the
+// given ID
+func (r *sqlSlackLinkRepository) List(ctx context.Context, after string, limit int) ([]models.SlackLink, error) {
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT user_id, team_id, slack_user_id, created_at, overdue_checked_at FROM slack_links
+		WHERE user_id > ? ORDER BY user_id LIMIT ?`), after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.SlackLink
+	for rows.Next() {
+		link, err := scanSlackLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, *link)
+	}
+	return links, rows.Err()
+}
+
+// ClaimOverdueCheck moves the user's OverdueCheckedAt forward to at
+func (r *sqlSlackLinkRepository) ClaimOverdueCheck(ctx context.Context, userID string, at time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`UPDATE slack_links SET overdue_checked_at = ? WHERE user_id = ? AND overdue_checked_at < ?`),
+		r.dialect.timeValue(at), userID, r.dialect.timeValue(at))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// Delete removes the user's link
+func (r *sqlSlackLinkRepository) Delete(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect, `DELETE FROM slack_links WHERE user_id = ?`), userID)
+	return err
+}
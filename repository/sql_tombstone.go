@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqlTombstoneRepository implements TombstoneRepository on top of
+// database/sql, keyed by the todo's ID
+type sqlTombstoneRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// Save records the deletion, replacing an earlier tombstone of the same todo
+func (r *sqlTombstoneRepository) Save(ctx context.Context, tombstone *models.Tombstone) error {
+	doc, err := bson.MarshalExtJSON(tombstone, false, false)
+	if err != nil {
+		return err
+	}
+
+	var workspaceID any
+	if tombstone.WorkspaceID != nil {
+		workspaceID = tombstone.WorkspaceID.Hex()
+	}
+	_, err = r.db.ExecContext(ctx, rebind(r.dialect,
+		`INSERT INTO tombstones (todo_id, user_id, workspace_id, deleted_at, doc) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (todo_id) DO UPDATE SET user_id = excluded.user_id, workspace_id = excluded.workspace_id,
+			deleted_at = excluded.deleted_at, doc = excluded.doc`),
+		tombstone.TodoID.Hex(), tombstone.UserID, workspaceID, r.dialect.timeValue(tombstone.DeletedAt), string(doc))
+	return err
+}
+
+// ListSince returns the scope's tombstones of todos deleted at or after since
+func (r *sqlTombstoneRepository) ListSince(ctx context.Context, scope Scope, since time.Time) ([]models.Tombstone, error) {
+	where, args := scopeWhere(scope)
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT doc FROM tombstones WHERE `+where+` AND deleted_at >= ? ORDER BY deleted_at`),
+		append(args, r.dialect.timeValue(since))...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tombstones []models.Tombstone
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var tombstone models.Tombstone
+		if err := bson.UnmarshalExtJSON(doc, false, &tombstone); err != nil {
+			return nil, err
+		}
+		tombstones = append(tombstones, tombstone)
+	}
+	return tombstones, rows.Err()
+}
+
+// ListAllSince returns up to limit tombstones of todos deleted at or after
+// since, by todo ID
+func (r *sqlTombstoneRepository) ListAllSince(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Tombstone, error) {
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT doc FROM tombstones WHERE deleted_at >= ? AND todo_id > ? ORDER BY todo_id LIMIT ?`),
+		r.dialect.timeValue(since), after.Hex(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tombstones []models.Tombstone
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var tombstone models.Tombstone
+		if err := bson.UnmarshalExtJSON(doc, false, &tombstone); err != nil {
+			return nil, err
+		}
+		tombstones = append(tombstones, tombstone)
+	}
+	return tombstones, rows.Err()
+}
+
+// DeleteBefore removes tombstones of todos deleted before cutoff
+func (r *sqlTombstoneRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM tombstones WHERE deleted_at < ?`), r.dialect.timeValue(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteAll removes every tombstone in the scope
+func (r *sqlTombstoneRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	where, args := scopeWhere(scope)
+	_, err := r.db.ExecContext(ctx, rebind(r.dialect, `DELETE FROM tombstones WHERE `+where), args...)
+	return err
+}
@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"todo-api/authz"
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqlWorkspaceRepository implements WorkspaceRepository on top of
+// database/sql. The workspace itself is an Extended JSON document like todos;
+// members live in their own table so membership changes don't rewrite the
+// document and concurrent changes can't overwrite each other.
+type sqlWorkspaceRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// Create stores a new workspace, assigning its ID
+func (r *sqlWorkspaceRepository) Create(ctx context.Context, workspace *models.Workspace) error {
+	if workspace.ID.IsZero() {
+		workspace.ID = primitive.NewObjectID()
+	}
+	doc, err := marshalWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, rebind(r.dialect,
+		`INSERT INTO workspaces (id, created_at, updated_at, doc) VALUES (?, ?, ?, ?)`),
+		workspace.ID.Hex(), r.dialect.timeValue(workspace.CreatedAt), r.dialect.timeValue(workspace.UpdatedAt), doc); err != nil {
+		return err
+	}
+	for _, member := range workspace.Members {
+		if _, err := tx.ExecContext(ctx, rebind(r.dialect,
+			`INSERT INTO workspace_members (workspace_id, user_id, role, joined_at) VALUES (?, ?, ?, ?)`),
+			workspace.ID.Hex(), member.UserID, member.Role, r.dialect.timeValue(member.JoinedAt)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Get returns a workspace by ID
+func (r *sqlWorkspaceRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	var doc []byte
+	var updatedAt any
+	err := r.db.QueryRowContext(ctx, rebind(r.dialect,
+		`SELECT doc, updated_at FROM workspaces WHERE id = ?`), id.Hex()).Scan(&doc, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	workspace, err := r.unmarshal(doc, updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if workspace.Members, err = r.members(ctx, id); err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+// ListByMember returns the workspaces the user belongs to, oldest first
+func (r *sqlWorkspaceRepository) ListByMember(ctx context.Context, userID string) ([]models.Workspace, error) {
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT w.doc, w.updated_at FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE m.user_id = ? ORDER BY w.created_at`), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var workspaces []models.Workspace
+	for rows.Next() {
+		var doc []byte
+		var updatedAt any
+		if err := rows.Scan(&doc, &updatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		workspace, err := r.unmarshal(doc, updatedAt)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		workspaces = append(workspaces, *workspace)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Load members once the listing query has released its connection,
+	// since SQLite only has one
+	for i := range workspaces {
+		if workspaces[i].Members, err = r.members(ctx, workspaces[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return workspaces, nil
+}
+
+// AddMember adds a member unless they already belong to the workspace
+func (r *sqlWorkspaceRepository) AddMember(ctx context.Context, id primitive.ObjectID, member models.Member) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.touch(ctx, tx, id); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, rebind(r.dialect,
+		`INSERT INTO workspace_members (workspace_id, user_id, role, joined_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (workspace_id, user_id) DO NOTHING`),
+		id.Hex(), member.UserID, member.Role, r.dialect.timeValue(member.JoinedAt))
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrDuplicate
+	}
+	return tx.Commit()
+}
+
+// SetMemberRole changes a member's role
+func (r *sqlWorkspaceRepository) SetMemberRole(ctx context.Context, id primitive.ObjectID, userID string, role authz.Role) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.touch(ctx, tx, id); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, rebind(r.dialect,
+		`UPDATE workspace_members SET role = ? WHERE workspace_id = ? AND user_id = ?`), role, id.Hex(), userID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveMember removes a member from the workspace
+func (r *sqlWorkspaceRepository) RemoveMember(ctx context.Context, id primitive.ObjectID, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.touch(ctx, tx, id); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ?`), id.Hex(), userID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update saves the workspace's name and color. The document holds no
+// members, so rewriting it can't undo membership changes.
+func (r *sqlWorkspaceRepository) Update(ctx context.Context, workspace *models.Workspace) error {
+	doc, err := marshalWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.ExecContext(ctx, rebind(r.dialect,
+		`UPDATE workspaces SET updated_at = ?, doc = ? WHERE id = ?`),
+		r.dialect.timeValue(workspace.UpdatedAt), doc, workspace.ID.Hex())
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Delete removes the workspace and its memberships
+func (r *sqlWorkspaceRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, rebind(r.dialect,
+		`DELETE FROM workspace_members WHERE workspace_id = ?`), id.Hex()); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, rebind(r.dialect, `DELETE FROM workspaces WHERE id = ?`), id.Hex())
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// touch bumps the workspace's updated_at, returning ErrNotFound if it
+// doesn't exist
+func (r *sqlWorkspaceRepository) touch(ctx context.Context, tx *sql.Tx, id primitive.ObjectID) error {
+	result, err := tx.ExecContext(ctx, rebind(r.dialect,
+		`UPDATE workspaces SET updated_at = ? WHERE id = ?`), r.dialect.timeValue(time.Now()), id.Hex())
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// members returns the workspace's members in the order they joined
+func (r *sqlWorkspaceRepository) members(ctx context.Context, id primitive.ObjectID) ([]models.Member, error) {
+	rows, err := r.db.QueryContext(ctx, rebind(r.dialect,
+		`SELECT user_id, role, joined_at FROM workspace_members WHERE workspace_id = ? ORDER BY joined_at`), id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.Member{}
+	for rows.Next() {
+		var member models.Member
+		var joinedAt any
+		if err := rows.Scan(&member.UserID, &member.Role, &joinedAt); err != nil {
+			return nil, err
+		}
+		if member.JoinedAt, err = scanTime(joinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// unmarshal decodes a workspace document. updated_at is kept in its own
+// column because membership changes update it without touching the document.
+func (r *sqlWorkspaceRepository) unmarshal(doc []byte, updatedAt any) (*models.Workspace, error) {
+	var workspace models.Workspace
+	if err := bson.UnmarshalExtJSON(doc, false, &workspace); err != nil {
+		return nil, err
+	}
+	var err error
+	if workspace.UpdatedAt, err = scanTime(updatedAt); err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// marshalWorkspace encodes the workspace document without its members
+func marshalWorkspace(workspace *models.Workspace) (string, error) {
+	doc := *workspace
+	doc.Members = nil
+	data, err := bson.MarshalExtJSON(doc, false, false)
+	return string(data), err
+}
+
+// scanTime converts a scanned time column into a time.Time. PostgreSQL
+// returns timestamps; SQLite stores Unix milliseconds.
+func scanTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case int64:
+		return time.UnixMilli(t), nil
+	default:
+		return time.Time{}, fmt.Errorf("unexpected time value %T", v)
+	}
+}
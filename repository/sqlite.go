@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the pure-Go "sqlite" database/sql driver
+)
+
+// sqliteMigrations are applied in order and recorded in schema_migrations.
+// Timestamps are stored as Unix milliseconds so they sort correctly.
+var sqliteMigrations = []string{
+	`CREATE TABLE todos (
+		id         TEXT PRIMARY KEY,
+		user_id    TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		doc        TEXT NOT NULL
+	)`,
+	`CREATE INDEX todos_user_id_created_at_idx ON todos (user_id, created_at)`,
+	`CREATE TABLE users (
+		id         TEXT PRIMARY KEY,
+		created_at INTEGER NOT NULL,
+		last_seen  INTEGER NOT NULL
+	)`,
+	`CREATE INDEX users_last_seen_idx ON users (last_seen)`,
+	`CREATE TABLE sessions (
+		id         TEXT PRIMARY KEY,
+		user_id    TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL,
+		doc        TEXT NOT NULL
+	)`,
+	`CREATE INDEX sessions_user_id_idx ON sessions (user_id)`,
+	`ALTER TABLE todos ADD COLUMN workspace_id TEXT`,
+	`CREATE INDEX todos_workspace_id_created_at_idx ON todos (workspace_id, created_at)`,
+	`CREATE TABLE workspaces (
+		id         TEXT PRIMARY KEY,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		doc        TEXT NOT NULL
+	)`,
+	`CREATE TABLE workspace_members (
+		workspace_id TEXT NOT NULL,
+		user_id      TEXT NOT NULL,
+		joined_at    INTEGER NOT NULL,
+		PRIMARY KEY (workspace_id, user_id)
+	)`,
+	`CREATE INDEX workspace_members_user_id_idx ON workspace_members (user_id)`,
+	`CREATE TABLE shares (
+		id         TEXT PRIMARY KEY,
+		todo_id    TEXT NOT NULL,
+		owner_id   TEXT NOT NULL,
+		grantee    TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		doc        TEXT NOT NULL,
+		UNIQUE (todo_id, grantee)
+	)`,
+	`CREATE INDEX shares_grantee_created_at_idx ON shares (grantee, created_at)`,
+	`CREATE TABLE public_links (
+		token_hash TEXT PRIMARY KEY,
+		todo_id    TEXT NOT NULL UNIQUE,
+		owner_id   TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	)`,
+	// Members added before roles existed are editors, which is what every
+	// member used to be; owners are recognised by the workspace's owner_id
+	`ALTER TABLE workspace_members ADD COLUMN role TEXT NOT NULL DEFAULT 'editor'`,
+	`ALTER TABLE todos ADD COLUMN completed INTEGER NOT NULL DEFAULT 0`,
+	`UPDATE todos SET completed = COALESCE(json_extract(doc, '$.completed'), 0)`,
+	`ALTER TABLE todos ADD COLUMN due_date INTEGER`,
+	// Relaxed Extended JSON writes dates as {"$date": "<ISO 8601>"}
+	`UPDATE todos SET due_date = CAST(ROUND((julianday(json_extract(doc, '$.due_date."$date"')) - 2440587.5) * 86400000) AS INTEGER)
+		WHERE json_extract(doc, '$.due_date') IS NOT NULL`,
+	`ALTER TABLE users ADD COLUMN settings TEXT NOT NULL DEFAULT '{}'`,
+	`CREATE TABLE custom_fields (
+		id           TEXT PRIMARY KEY,
+		user_id      TEXT NOT NULL,
+		workspace_id TEXT,
+		created_at   INTEGER NOT NULL,
+		doc          TEXT NOT NULL
+	)`,
+	`CREATE INDEX custom_fields_user_id_idx ON custom_fields (user_id)`,
+	`CREATE INDEX custom_fields_workspace_id_idx ON custom_fields (workspace_id)`,
+	// No todo recorded when it was completed before this column
+	`ALTER TABLE todos ADD COLUMN completed_at INTEGER`,
+	`CREATE TABLE revisions (
+		id           TEXT PRIMARY KEY,
+		todo_id      TEXT NOT NULL,
+		rev          INTEGER NOT NULL,
+		user_id      TEXT NOT NULL,
+		workspace_id TEXT,
+		created_at   INTEGER NOT NULL,
+		doc          TEXT NOT NULL,
+		UNIQUE (todo_id, rev)
+	)`,
+	`CREATE INDEX revisions_user_id_idx ON revisions (user_id)`,
+	`CREATE INDEX revisions_workspace_id_idx ON revisions (workspace_id)`,
+	`CREATE TABLE outbox (
+		id           TEXT PRIMARY KEY,
+		created_at   INTEGER NOT NULL,
+		delivered_at INTEGER,
+		doc          TEXT NOT NULL
+	)`,
+	`CREATE INDEX outbox_pending_idx ON outbox (delivered_at, created_at)`,
+	`CREATE TABLE tombstones (
+		todo_id      TEXT PRIMARY KEY,
+		user_id      TEXT NOT NULL,
+		workspace_id TEXT,
+		deleted_at   INTEGER NOT NULL,
+		doc          TEXT NOT NULL
+	)`,
+	`CREATE INDEX tombstones_user_id_idx ON tombstones (user_id, deleted_at)`,
+	`CREATE INDEX tombstones_workspace_id_idx ON tombstones (workspace_id, deleted_at)`,
+	`CREATE INDEX tombstones_deleted_at_idx ON tombstones (deleted_at)`,
+	`ALTER TABLE users ADD COLUMN digest_sent_at INTEGER`,
+	`CREATE TABLE slack_links (
+		user_id            TEXT PRIMARY KEY,
+		team_id            TEXT NOT NULL,
+		slack_user_id      TEXT NOT NULL,
+		created_at         INTEGER NOT NULL,
+		overdue_checked_at INTEGER NOT NULL,
+		UNIQUE (team_id, slack_user_id)
+	)`,
+	`CREATE TABLE saved_filters (
+		id           TEXT PRIMARY KEY,
+		user_id      TEXT NOT NULL,
+		workspace_id TEXT,
+		created_at   INTEGER NOT NULL,
+		doc          TEXT NOT NULL
+	)`,
+	`CREATE INDEX saved_filters_user_id_idx ON saved_filters (user_id)`,
+	`CREATE INDEX saved_filters_workspace_id_idx ON saved_filters (workspace_id)`,
+	`CREATE TABLE audit_log (
+		id      TEXT PRIMARY KEY,
+		time    INTEGER NOT NULL,
+		user_id TEXT NOT NULL,
+		method  TEXT NOT NULL,
+		path    TEXT NOT NULL,
+		status  INTEGER NOT NULL,
+		doc     TEXT NOT NULL
+	)`,
+	`CREATE INDEX audit_log_user_id_idx ON audit_log (user_id, id)`,
+	`CREATE INDEX audit_log_time_idx ON audit_log (time)`,
+	// A todo's location is kept in the document as GeoJSON; these columns
+	// bound nearby queries to a box before distances are worked out
+	`ALTER TABLE todos ADD COLUMN lat REAL`,
+	`ALTER TABLE todos ADD COLUMN lng REAL`,
+	`CREATE INDEX todos_lat_lng_idx ON todos (lat, lng) WHERE lat IS NOT NULL`,
+	// Change exports look todos up by when they were last written
+	`CREATE INDEX todos_updated_at_idx ON todos (updated_at)`,
+	`ALTER TABLE users ADD COLUMN daily_reset_at INTEGER`,
+	`ALTER TABLE users ADD COLUMN stale_nudged_at INTEGER`,
+	// Completions from before completed_at count their last update, as
+	// version 1 of the todo schema has it; documents are upgraded as read
+	`UPDATE todos SET completed_at = updated_at WHERE completed = 1 AND completed_at IS NULL`,
+}
+
+var sqliteDialect = sqlDialect{
+	timeValue: func(t time.Time) any { return t.UnixMilli() },
+}
+
+// NewSQLiteStore opens (creating if needed) the SQLite database file at path
+// and applies any pending schema migrations. This lets the API run as a
+// single binary without an external database.
+func NewSQLiteStore(ctx context.Context, path string) (*SQLStore, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	// SQLite allows a single writer; serializing through one connection
+	// avoids SQLITE_BUSY under concurrent requests
+	db.SetMaxOpenConns(1)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	err = migrateSQL(ctx, conn, sqliteDialect, sqliteMigrations)
+	conn.Close()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate sqlite: %w", err)
+	}
+	return &SQLStore{db: db, dialect: sqliteDialect}, nil
+}
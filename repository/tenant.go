@@ -0,0 +1,804 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"todo-api/authz"
+	"todo-api/models"
+	"todo-api/tenancy"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNoTenant is returned by tenant-routed repositories for calls whose
+// context names no tenant, or one that isn't served
+var ErrNoTenant = errors.New("no tenant in context")
+
+// tenantRouter holds the stores of each tenant
+type tenantRouter struct {
+	tenants map[string]*Stores
+}
+
+// NewTenantStores returns stores that route every call to the stores of the
+// tenant named by the call's context, as set by tenancy.WithTenant. Calls
+// without a tenant fail with ErrNoTenant rather than reach any tenant's
+// data, so a code path that forgets the tenant can't leak across tenants.
+func NewTenantStores(tenants map[string]*Stores) *Stores {
+	t := &tenantRouter{tenants: tenants}
+	return &Stores{
+		Todos:        tenantTodoRepository{t},
+		Users:        tenantUserRepository{t},
+		Sessions:     tenantSessionRepository{t},
+		Workspaces:   tenantWorkspaceRepository{t},
+		Shares:       tenantShareRepository{t},
+		PublicLinks:  tenantPublicLinkRepository{t},
+		CustomFields: tenantCustomFieldRepository{t},
+		Filters:      tenantSavedFilterRepository{t},
+		Revisions:    tenantRevisionRepository{t},
+		Outbox:       tenantOutboxRepository{t},
+		Tombstones:   tenantTombstoneRepository{t},
+		SlackLinks:   tenantSlackLinkRepository{t},
+		Audit:        tenantAuditRepository{t},
+		Tx:           tenantTransactor{t},
+	}
+}
+
+// stores returns the stores of the tenant in ctx
+func (t *tenantRouter) stores(ctx context.Context) (*Stores, error) {
+	tenant, ok := tenancy.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+	s, ok := t.tenants[tenant]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q isn't served", ErrNoTenant, tenant)
+	}
+	return s, nil
+}
+
+type tenantTransactor struct {
+	t *tenantRouter
+}
+
+func (r tenantTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Tx.WithTransaction(ctx, fn)
+}
+
+type tenantTodoRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantTodoRepository) List(ctx context.Context, scope Scope) ([]models.Todo, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Todos.List(ctx, scope)
+}
+
+func (r tenantTodoRepository) ListFields(ctx context.Context, scope Scope, fields []string) ([]models.Todo, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Todos.ListFields(ctx, scope, fields)
+}
+
+func (r tenantTodoRepository) Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.Todo, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Todos.Get(ctx, scope, id)
+}
+
+func (r tenantTodoRepository) Create(ctx context.Context, todo *models.Todo) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Todos.Create(ctx, todo)
+}
+
+func (r tenantTodoRepository) Update(ctx context.Context, todo *models.Todo) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Todos.Update(ctx, todo)
+}
+
+func (r tenantTodoRepository) UpdateIfUnchanged(ctx context.Context, todo *models.Todo, lastUpdated time.Time) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Todos.UpdateIfUnchanged(ctx, todo, lastUpdated)
+}
+
+func (r tenantTodoRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Todos.Delete(ctx, scope, id)
+}
+
+func (r tenantTodoRepository) DeleteAll(ctx context.Context, scope Scope) (int64, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.Todos.DeleteAll(ctx, scope)
+}
+
+func (r tenantTodoRepository) ReplaceTag(ctx context.Context, scope Scope, from, to string, now time.Time) (int64, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.Todos.ReplaceTag(ctx, scope, from, to, now)
+}
+
+func (r tenantTodoRepository) Count(ctx context.Context, scope Scope) (int64, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.Todos.Count(ctx, scope)
+}
+
+func (r tenantTodoRepository) CountMatching(ctx context.Context, scope Scope, filter ListFilter) (int64, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.Todos.CountMatching(ctx, scope, filter)
+}
+
+func (r tenantTodoRepository) Facets(ctx context.Context, scope Scope, filter ListFilter) (*models.TodoFacets, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Todos.Facets(ctx, scope, filter)
+}
+
+func (r tenantTodoRepository) CountAll(ctx context.Context) (int64, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.Todos.CountAll(ctx)
+}
+
+func (r tenantTodoRepository) ListPage(ctx context.Context, scope Scope, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Todos.ListPage(ctx, scope, after, limit)
+}
+
+func (r tenantTodoRepository) ListChanged(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Todos.ListChanged(ctx, since, after, limit)
+}
+
+func (r tenantTodoRepository) ListDueByDay(ctx context.Context, scope Scope, from, to time.Time, loc *time.Location) (map[string][]models.Todo, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Todos.ListDueByDay(ctx, scope, from, to, loc)
+}
+
+func (r tenantTodoRepository) ListNear(ctx context.Context, scope Scope, center *models.Location, radius float64) ([]models.Todo, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Todos.ListNear(ctx, scope, center, radius)
+}
+
+func (r tenantTodoRepository) Stats(ctx context.Context, scope Scope, since, overdueBefore time.Time, loc *time.Location) (*models.TodoStats, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Todos.Stats(ctx, scope, since, overdueBefore, loc)
+}
+
+type tenantUserRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantUserRepository) Touch(ctx context.Context, userID string, seen time.Time) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Users.Touch(ctx, userID, seen)
+}
+
+func (r tenantUserRepository) ListInactive(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Users.ListInactive(ctx, before, limit)
+}
+
+func (r tenantUserRepository) List(ctx context.Context, after string, limit int) ([]models.User, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Users.List(ctx, after, limit)
+}
+
+func (r tenantUserRepository) Count(ctx context.Context) (int64, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.Users.Count(ctx)
+}
+
+func (r tenantUserRepository) GetSettings(ctx context.Context, userID string) (models.UserSettings, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return models.UserSettings{}, err
+	}
+	return s.Users.GetSettings(ctx, userID)
+}
+
+func (r tenantUserRepository) SaveSettings(ctx context.Context, userID string, settings models.UserSettings) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Users.SaveSettings(ctx, userID, settings)
+}
+
+func (r tenantUserRepository) ClaimDigest(ctx context.Context, userID string, due time.Time) (bool, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return false, err
+	}
+	return s.Users.ClaimDigest(ctx, userID, due)
+}
+
+func (r tenantUserRepository) ClaimDailyReset(ctx context.Context, userID string, due time.Time) (bool, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return false, err
+	}
+	return s.Users.ClaimDailyReset(ctx, userID, due)
+}
+
+func (r tenantUserRepository) ClaimStaleNudge(ctx context.Context, userID string, due time.Time) (bool, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return false, err
+	}
+	return s.Users.ClaimStaleNudge(ctx, userID, due)
+}
+
+func (r tenantUserRepository) Delete(ctx context.Context, userID string) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Users.Delete(ctx, userID)
+}
+
+type tenantSessionRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Sessions.Create(ctx, session)
+}
+
+func (r tenantSessionRepository) Get(ctx context.Context, id string) (*models.Session, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sessions.Get(ctx, id)
+}
+
+func (r tenantSessionRepository) ListByUser(ctx context.Context, userID string) ([]models.Session, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sessions.ListByUser(ctx, userID)
+}
+
+func (r tenantSessionRepository) Touch(ctx context.Context, id string, seen time.Time) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Sessions.Touch(ctx, id, seen)
+}
+
+func (r tenantSessionRepository) Delete(ctx context.Context, userID, id string) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Sessions.Delete(ctx, userID, id)
+}
+
+func (r tenantSessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Sessions.DeleteByUser(ctx, userID)
+}
+
+type tenantWorkspaceRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantWorkspaceRepository) Create(ctx context.Context, workspace *models.Workspace) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Workspaces.Create(ctx, workspace)
+}
+
+func (r tenantWorkspaceRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Workspaces.Get(ctx, id)
+}
+
+func (r tenantWorkspaceRepository) ListByMember(ctx context.Context, userID string) ([]models.Workspace, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Workspaces.ListByMember(ctx, userID)
+}
+
+func (r tenantWorkspaceRepository) AddMember(ctx context.Context, id primitive.ObjectID, member models.Member) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Workspaces.AddMember(ctx, id, member)
+}
+
+func (r tenantWorkspaceRepository) SetMemberRole(ctx context.Context, id primitive.ObjectID, userID string, role authz.Role) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Workspaces.SetMemberRole(ctx, id, userID, role)
+}
+
+func (r tenantWorkspaceRepository) RemoveMember(ctx context.Context, id primitive.ObjectID, userID string) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Workspaces.RemoveMember(ctx, id, userID)
+}
+
+func (r tenantWorkspaceRepository) Update(ctx context.Context, workspace *models.Workspace) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Workspaces.Update(ctx, workspace)
+}
+
+func (r tenantWorkspaceRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Workspaces.Delete(ctx, id)
+}
+
+type tenantShareRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantShareRepository) Save(ctx context.Context, share *models.Share) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Shares.Save(ctx, share)
+}
+
+func (r tenantShareRepository) ListByTodo(ctx context.Context, ownerID string, todoID primitive.ObjectID) ([]models.Share, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Shares.ListByTodo(ctx, ownerID, todoID)
+}
+
+func (r tenantShareRepository) ListByGrantee(ctx context.Context, grantees []string) ([]models.Share, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Shares.ListByGrantee(ctx, grantees)
+}
+
+func (r tenantShareRepository) Delete(ctx context.Context, todoID, id primitive.ObjectID) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Shares.Delete(ctx, todoID, id)
+}
+
+func (r tenantShareRepository) DeleteByTodo(ctx context.Context, todoID primitive.ObjectID) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Shares.DeleteByTodo(ctx, todoID)
+}
+
+func (r tenantShareRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Shares.DeleteByOwner(ctx, ownerID)
+}
+
+type tenantPublicLinkRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantPublicLinkRepository) Replace(ctx context.Context, link *models.PublicLink) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.PublicLinks.Replace(ctx, link)
+}
+
+func (r tenantPublicLinkRepository) Get(ctx context.Context, tokenHash string) (*models.PublicLink, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.PublicLinks.Get(ctx, tokenHash)
+}
+
+func (r tenantPublicLinkRepository) Delete(ctx context.Context, ownerID string, todoID primitive.ObjectID) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.PublicLinks.Delete(ctx, ownerID, todoID)
+}
+
+func (r tenantPublicLinkRepository) DeleteByOwner(ctx context.Context, ownerID string) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.PublicLinks.DeleteByOwner(ctx, ownerID)
+}
+
+type tenantCustomFieldRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantCustomFieldRepository) List(ctx context.Context, scope Scope) ([]models.CustomField, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.CustomFields.List(ctx, scope)
+}
+
+func (r tenantCustomFieldRepository) Create(ctx context.Context, field *models.CustomField) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.CustomFields.Create(ctx, field)
+}
+
+func (r tenantCustomFieldRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.CustomFields.Delete(ctx, scope, id)
+}
+
+func (r tenantCustomFieldRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.CustomFields.DeleteAll(ctx, scope)
+}
+
+type tenantSavedFilterRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantSavedFilterRepository) List(ctx context.Context, scope Scope) ([]models.SavedFilter, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Filters.List(ctx, scope)
+}
+
+func (r tenantSavedFilterRepository) Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.SavedFilter, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Filters.Get(ctx, scope, id)
+}
+
+func (r tenantSavedFilterRepository) Create(ctx context.Context, filter *models.SavedFilter) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Filters.Create(ctx, filter)
+}
+
+func (r tenantSavedFilterRepository) Update(ctx context.Context, filter *models.SavedFilter) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Filters.Update(ctx, filter)
+}
+
+func (r tenantSavedFilterRepository) Delete(ctx context.Context, scope Scope, id primitive.ObjectID) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Filters.Delete(ctx, scope, id)
+}
+
+func (r tenantSavedFilterRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Filters.DeleteAll(ctx, scope)
+}
+
+type tenantRevisionRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantRevisionRepository) List(ctx context.Context, todoID primitive.ObjectID) ([]models.Revision, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Revisions.List(ctx, todoID)
+}
+
+func (r tenantRevisionRepository) Create(ctx context.Context, revision *models.Revision) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Revisions.Create(ctx, revision)
+}
+
+func (r tenantRevisionRepository) DeleteBefore(ctx context.Context, todoID primitive.ObjectID, number int) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Revisions.DeleteBefore(ctx, todoID, number)
+}
+
+func (r tenantRevisionRepository) DeleteByTodo(ctx context.Context, todoID primitive.ObjectID) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Revisions.DeleteByTodo(ctx, todoID)
+}
+
+func (r tenantRevisionRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Revisions.DeleteAll(ctx, scope)
+}
+
+type tenantOutboxRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantOutboxRepository) Add(ctx context.Context, entries ...*models.OutboxEntry) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Outbox.Add(ctx, entries...)
+}
+
+func (r tenantOutboxRepository) Pending(ctx context.Context, limit int) ([]models.OutboxEntry, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Outbox.Pending(ctx, limit)
+}
+
+func (r tenantOutboxRepository) MarkDelivered(ctx context.Context, ids []primitive.ObjectID, at time.Time) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Outbox.MarkDelivered(ctx, ids, at)
+}
+
+func (r tenantOutboxRepository) DeleteDelivered(ctx context.Context, cutoff time.Time) (int64, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.Outbox.DeleteDelivered(ctx, cutoff)
+}
+
+type tenantTombstoneRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantTombstoneRepository) Save(ctx context.Context, tombstone *models.Tombstone) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Tombstones.Save(ctx, tombstone)
+}
+
+func (r tenantTombstoneRepository) ListSince(ctx context.Context, scope Scope, since time.Time) ([]models.Tombstone, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Tombstones.ListSince(ctx, scope, since)
+}
+
+func (r tenantTombstoneRepository) ListAllSince(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Tombstone, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Tombstones.ListAllSince(ctx, since, after, limit)
+}
+
+func (r tenantTombstoneRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.Tombstones.DeleteBefore(ctx, cutoff)
+}
+
+func (r tenantTombstoneRepository) DeleteAll(ctx context.Context, scope Scope) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Tombstones.DeleteAll(ctx, scope)
+}
+
+type tenantSlackLinkRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantSlackLinkRepository) Save(ctx context.Context, link *models.SlackLink) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.SlackLinks.Save(ctx, link)
+}
+
+func (r tenantSlackLinkRepository) Get(ctx context.Context, userID string) (*models.SlackLink, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.SlackLinks.Get(ctx, userID)
+}
+
+func (r tenantSlackLinkRepository) GetBySlackUser(ctx context.Context, teamID, slackUserID string) (*models.SlackLink, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.SlackLinks.GetBySlackUser(ctx, teamID, slackUserID)
+}
+
+func (r tenantSlackLinkRepository) List(ctx context.Context, after string, limit int) ([]models.SlackLink, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.SlackLinks.List(ctx, after, limit)
+}
+
+func (r tenantSlackLinkRepository) ClaimOverdueCheck(ctx context.Context, userID string, at time.Time) (bool, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return false, err
+	}
+	return s.SlackLinks.ClaimOverdueCheck(ctx, userID, at)
+}
+
+func (r tenantSlackLinkRepository) Delete(ctx context.Context, userID string) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.SlackLinks.Delete(ctx, userID)
+}
+
+type tenantAuditRepository struct {
+	t *tenantRouter
+}
+
+func (r tenantAuditRepository) Add(ctx context.Context, entries ...*models.AuditEntry) error {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Audit.Add(ctx, entries...)
+}
+
+func (r tenantAuditRepository) List(ctx context.Context, q models.AuditQuery) ([]models.AuditEntry, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Audit.List(ctx, q)
+}
+
+func (r tenantAuditRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	s, err := r.t.stores(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.Audit.DeleteBefore(ctx, cutoff)
+}
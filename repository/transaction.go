@@ -0,0 +1,41 @@
+package repository
+
+import "context"
+
+// Transactor runs compound operations, such as deleting a workspace along
+// with its todos, atomically
+type Transactor interface {
+	// WithTransaction calls fn with a context that makes the repository
+	// calls fn makes with it take effect together or not at all. fn may be
+	// called again when the transaction is retried, so it shouldn't have
+	// other side effects. Backends without transactions call fn once as it
+	// is, and a failure part way leaves the calls before it done.
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// WithoutTransactions returns a Transactor for backends that can't run
+// transactions across repositories
+func WithoutTransactions() Transactor {
+	return noTransactor{}
+}
+
+type noTransactor struct{}
+
+func (noTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// inTransactionKey marks contexts of calls made in a transaction
+type inTransactionKey struct{}
+
+// withinTransaction marks ctx as belonging to a transaction
+func withinTransaction(ctx context.Context) context.Context {
+	return context.WithValue(ctx, inTransactionKey{}, true)
+}
+
+// InTransaction reports whether calls made with ctx are part of a
+// transaction
+func InTransaction(ctx context.Context) bool {
+	in, _ := ctx.Value(inTransactionKey{}).(bool)
+	return in
+}
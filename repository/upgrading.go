@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"todo-api/migrations"
+	"todo-api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UpgradingTodoRepository brings todos written by older versions up to the
+// current schema as they're read from another repository, and stamps the
+// todos it writes with the current version. Upgraded todos are stored when
+// they're next saved, so nothing is written just to read them.
+type UpgradingTodoRepository struct {
+	TodoRepository
+}
+
+// NewUpgradingTodoRepository wraps todos with document upgrades
+func NewUpgradingTodoRepository(todos TodoRepository) *UpgradingTodoRepository {
+	return &UpgradingTodoRepository{TodoRepository: todos}
+}
+
+// List returns the scope's todos upgraded
+func (r *UpgradingTodoRepository) List(ctx context.Context, scope Scope) ([]models.Todo, error) {
+	todos, err := r.TodoRepository.List(ctx, scope)
+	migrations.UpgradeTodos(todos)
+	return todos, err
+}
+
+// ListFields returns the scope's todos upgraded. The fields upgrades read
+// are loaded along with the named ones, so they aren't upgraded from
+// missing values.
+func (r *UpgradingTodoRepository) ListFields(ctx context.Context, scope Scope, fields []string) ([]models.Todo, error) {
+	todos, err := r.TodoRepository.ListFields(ctx, scope, slices.Concat(fields, migrations.TodoFieldsRead))
+	migrations.UpgradeTodos(todos)
+	return todos, err
+}
+
+// Get returns a todo upgraded
+func (r *UpgradingTodoRepository) Get(ctx context.Context, scope Scope, id primitive.ObjectID) (*models.Todo, error) {
+	todo, err := r.TodoRepository.Get(ctx, scope, id)
+	if err != nil {
+		return nil, err
+	}
+	migrations.UpgradeTodo(todo)
+	return todo, nil
+}
+
+// ListPage returns a page of the scope's todos upgraded
+func (r *UpgradingTodoRepository) ListPage(ctx context.Context, scope Scope, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	todos, err := r.TodoRepository.ListPage(ctx, scope, after, limit)
+	migrations.UpgradeTodos(todos)
+	return todos, err
+}
+
+// ListChanged returns a page of changed todos upgraded
+func (r *UpgradingTodoRepository) ListChanged(ctx context.Context, since time.Time, after primitive.ObjectID, limit int) ([]models.Todo, error) {
+	todos, err := r.TodoRepository.ListChanged(ctx, since, after, limit)
+	migrations.UpgradeTodos(todos)
+	return todos, err
+}
+
+// ListDueByDay returns the scope's todos due in [from, to) by day upgraded
+func (r *UpgradingTodoRepository) ListDueByDay(ctx context.Context, scope Scope, from, to time.Time, loc *time.Location) (map[string][]models.Todo, error) {
+	days, err := r.TodoRepository.ListDueByDay(ctx, scope, from, to, loc)
+	for _, todos := range days {
+		migrations.UpgradeTodos(todos)
+	}
+	return days, err
+}
+
+// ListNear returns the scope's todos near center upgraded
+func (r *UpgradingTodoRepository) ListNear(ctx context.Context, scope Scope, center *models.Location, radius float64) ([]models.Todo, error) {
+	todos, err := r.TodoRepository.ListNear(ctx, scope, center, radius)
+	migrations.UpgradeTodos(todos)
+	return todos, err
+}
+
+// Create stores a new todo at the current version
+func (r *UpgradingTodoRepository) Create(ctx context.Context, todo *models.Todo) error {
+	migrations.UpgradeTodo(todo)
+	return r.TodoRepository.Create(ctx, todo)
+}
+
+// Update replaces a todo at the current version
+func (r *UpgradingTodoRepository) Update(ctx context.Context, todo *models.Todo) error {
+	migrations.UpgradeTodo(todo)
+	return r.TodoRepository.Update(ctx, todo)
+}
+
+// UpdateIfUnchanged stores a todo stamped with the current version unless
+// it changed since lastUpdated
+func (r *UpgradingTodoRepository) UpdateIfUnchanged(ctx context.Context, todo *models.Todo, lastUpdated time.Time) error {
+	migrations.UpgradeTodo(todo)
+	return r.TodoRepository.UpdateIfUnchanged(ctx, todo, lastUpdated)
+}
@@ -0,0 +1,109 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned while the circuit breaker is rejecting calls
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit breaker
+type State string
+
+const (
+	// StateClosed lets every call through
+	StateClosed State = "closed"
+	// StateOpen rejects every call until the open timeout has passed
+	StateOpen State = "open"
+	// StateHalfOpen lets a single trial call through to probe for recovery
+	StateHalfOpen State = "half_open"
+)
+
+// Breaker is a circuit breaker. After threshold consecutive failures it opens
+// and fails fast for openTimeout, then lets one trial call through: success
+// closes it again, failure re-opens it.
+type Breaker struct {
+	threshold   int
+	openTimeout time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// NewBreaker creates a closed circuit breaker
+func NewBreaker(threshold int, openTimeout time.Duration) *Breaker {
+	return &Breaker{
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		state:       StateClosed,
+	}
+}
+
+// Allow reports whether a call may proceed, returning ErrOpen if not. Every
+// allowed call must be followed by Record.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return ErrOpen
+		}
+		b.state = StateHalfOpen
+		b.trial = true
+		return nil
+	case StateHalfOpen:
+		// Only one trial call at a time
+		if b.trial {
+			return ErrOpen
+		}
+		b.trial = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record reports the outcome of an allowed call. Only failures that say
+// something about the dependency's health (not e.g. "not found") should be
+// recorded as failures.
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if !failed {
+		b.state = StateClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.state = StateOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// State returns the current state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Check is a readiness check that fails while the breaker is open
+func (b *Breaker) Check(ctx context.Context) error {
+	if state := b.State(); state != StateClosed {
+		return fmt.Errorf("degraded: circuit breaker is %s", state)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package resilience
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how often and how quickly failed operations are retried
+type RetryPolicy struct {
+	// MaxAttempts includes the first try; 1 disables retries
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry, doubled on each attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff
+	MaxDelay time.Duration
+}
+
+// Retry calls fn until it succeeds, returns an error that isn't retryable, the
+// attempts run out, or ctx is done. Backoff uses full jitter so that many
+// instances hitting the same throttled database don't retry in lockstep.
+func Retry(ctx context.Context, policy RetryPolicy, retryable func(error) bool, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < max(policy.MaxAttempts, 1); attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(backoff(policy, attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+
+		err = fn()
+		if err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// backoff returns a random delay between zero and the exponential backoff
+// ceiling for the given retry attempt
+func backoff(policy RetryPolicy, attempt int) time.Duration {
+	ceiling := policy.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > policy.MaxDelay {
+		ceiling = policy.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
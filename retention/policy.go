@@ -0,0 +1,236 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"todo-api/config"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// policyBatchSize is how many users are read per query
+const policyBatchSize = 100
+
+// Remover deletes a todo along with its revisions, shares and public link,
+// leaving a tombstone for syncing clients, the way deleting it through the
+// API does
+type Remover interface {
+	RemoveTodo(ctx context.Context, todo *models.Todo) error
+}
+
+// Policy periodically applies the retention rules to the personal todos of
+// every user who hasn't opted out in their settings. Todos in workspaces
+// belong to the workspace and are left alone.
+type Policy struct {
+	rules     []config.RetentionRule
+	users     repository.UserRepository
+	todos     repository.TodoRepository
+	revisions repository.RevisionRepository
+	remover   Remover
+	interval  time.Duration
+}
+
+// NewPolicy creates a Policy that applies rules every interval, deleting
+// todos through remover
+func NewPolicy(rules []config.RetentionRule, users repository.UserRepository, todos repository.TodoRepository, revisions repository.RevisionRepository, remover Remover, interval time.Duration) *Policy {
+	return &Policy{rules: rules, users: users, todos: todos, revisions: revisions, remover: remover, interval: interval}
+}
+
+// Report is what the rules deleted, or would delete in a dry run
+type Report struct {
+	DryRun bool         `json:"dry_run"`
+	At     time.Time    `json:"at"`
+	Rules  []RuleReport `json:"rules"`
+	// UsersOptedOut counts the users whose data was left alone
+	UsersOptedOut int `json:"users_opted_out"`
+}
+
+// RuleReport is what one rule deleted
+type RuleReport struct {
+	Rule string `json:"rule"`
+	// Cutoff is the time data older than is deleted
+	Cutoff time.Time `json:"cutoff"`
+	// Todos counts the todos deleted, or those whose revisions were dropped
+	Todos     int `json:"todos"`
+	Revisions int `json:"revisions,omitempty"`
+	// Items lists what would be deleted in a dry run, up to its limit
+	Items []ReportItem `json:"items,omitempty"`
+}
+
+// ReportItem is a todo a rule would delete, or whose revisions it would
+// drop
+type ReportItem struct {
+	UserID string             `json:"user_id"`
+	TodoID primitive.ObjectID `json:"todo_id"`
+	// Time is when the todo was completed, or when the newest of the
+	// revisions to go was recorded
+	Time      time.Time `json:"time"`
+	Revisions int       `json:"revisions,omitempty"`
+}
+
+// Run applies the rules on every tick until ctx is cancelled
+func (p *Policy) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := p.Apply(ctx, time.Now())
+		if err != nil {
+			log.Println("Applying retention rules failed:", err)
+		}
+		for _, rule := range report.Rules {
+			switch {
+			case rule.Revisions > 0:
+				log.Printf("Retention rule %s dropped %d revisions of %d todos", rule.Rule, rule.Revisions, rule.Todos)
+			case rule.Todos > 0:
+				log.Printf("Retention rule %s deleted %d todos", rule.Rule, rule.Todos)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Apply deletes the data the rules cover as of now. The report counts what
+// was deleted before any error.
+func (p *Policy) Apply(ctx context.Context, now time.Time) (*Report, error) {
+	return p.evaluate(ctx, now, false, 0)
+}
+
+// DryRun reports what Apply would delete as of now without deleting
+// anything, listing up to limit items per rule
+func (p *Policy) DryRun(ctx context.Context, now time.Time, limit int) (*Report, error) {
+	return p.evaluate(ctx, now, true, limit)
+}
+
+// evaluate goes through every user's personal todos, deleting what the
+// rules cover unless dryRun is set
+func (p *Policy) evaluate(ctx context.Context, now time.Time, dryRun bool, limit int) (*Report, error) {
+	report := &Report{DryRun: dryRun, At: now, Rules: make([]RuleReport, len(p.rules))}
+	for i, rule := range p.rules {
+		report.Rules[i] = RuleReport{Rule: rule.String(), Cutoff: now.Add(-rule.After)}
+	}
+
+	after := ""
+	for {
+		users, err := p.users.List(ctx, after, policyBatchSize)
+		if err != nil {
+			return report, err
+		}
+		for _, user := range users {
+			settings, err := p.users.GetSettings(ctx, user.ID)
+			if err != nil {
+				return report, err
+			}
+			if settings.RetentionOptOut {
+				report.UsersOptedOut++
+				continue
+			}
+			if err := p.evaluateUser(ctx, user.ID, report, dryRun, limit); err != nil {
+				return report, err
+			}
+		}
+		if len(users) < policyBatchSize {
+			return report, nil
+		}
+		after = users[len(users)-1].ID
+	}
+}
+
+// evaluateUser applies the rules to one user's personal todos, in the order
+// they're configured. Todos deleted by one rule aren't seen by the next.
+func (p *Policy) evaluateUser(ctx context.Context, userID string, report *Report, dryRun bool, limit int) error {
+	todos, err := p.todos.List(ctx, repository.Personal(userID))
+	if err != nil {
+		return err
+	}
+	deleted := make(map[primitive.ObjectID]bool)
+	for i, rule := range p.rules {
+		result := &report.Rules[i]
+		for j := range todos {
+			todo := &todos[j]
+			if deleted[todo.ID] {
+				continue
+			}
+			var item *ReportItem
+			switch rule.Kind {
+			case config.RetentionCompleted:
+				item, err = p.completed(ctx, todo, result.Cutoff, dryRun)
+				if item != nil {
+					deleted[todo.ID] = true
+				}
+			case config.RetentionRevisions:
+				item, err = p.oldRevisions(ctx, todo, result.Cutoff, dryRun)
+			}
+			if err != nil {
+				return err
+			}
+			if item == nil {
+				continue
+			}
+			result.Todos++
+			result.Revisions += item.Revisions
+			if dryRun && len(result.Items) < limit {
+				result.Items = append(result.Items, *item)
+			}
+		}
+	}
+	return nil
+}
+
+// completed deletes the todo when it was completed before cutoff. Todos
+// completed before completion times were recorded go by when they were last
+// updated.
+func (p *Policy) completed(ctx context.Context, todo *models.Todo, cutoff time.Time, dryRun bool) (*ReportItem, error) {
+	if !todo.Completed {
+		return nil, nil
+	}
+	completedAt := todo.UpdatedAt
+	if todo.CompletedAt != nil {
+		completedAt = *todo.CompletedAt
+	}
+	if !completedAt.Before(cutoff) {
+		return nil, nil
+	}
+	if !dryRun {
+		if err := p.remover.RemoveTodo(ctx, todo); err != nil {
+			return nil, err
+		}
+	}
+	return &ReportItem{UserID: todo.UserID, TodoID: todo.ID, Time: completedAt}, nil
+}
+
+// oldRevisions drops the todo's revisions recorded before cutoff
+func (p *Policy) oldRevisions(ctx context.Context, todo *models.Todo, cutoff time.Time, dryRun bool) (*ReportItem, error) {
+	revisions, err := p.revisions.List(ctx, todo.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Revisions are listed oldest first
+	n := 0
+	for n < len(revisions) && revisions[n].CreatedAt.Before(cutoff) {
+		n++
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if !dryRun {
+		if n == len(revisions) {
+			err = p.revisions.DeleteByTodo(ctx, todo.ID)
+		} else {
+			err = p.revisions.DeleteBefore(ctx, todo.ID, revisions[n].Number)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &ReportItem{UserID: todo.UserID, TodoID: todo.ID, Time: revisions[n-1].CreatedAt, Revisions: n}, nil
+}
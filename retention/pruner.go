@@ -0,0 +1,52 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"todo-api/repository"
+)
+
+// Pruner periodically removes records older than they're needed
+type Pruner struct {
+	// what names the records in logs
+	what         string
+	deleteBefore func(ctx context.Context, cutoff time.Time) (int64, error)
+	keep         time.Duration
+	interval     time.Duration
+}
+
+// NewTombstonePruner creates a Pruner that runs every interval and removes
+// the tombstones of todos deleted longer ago than keep, which is as far back
+// as syncing clients can ask about
+func NewTombstonePruner(tombstones repository.TombstoneRepository, keep, interval time.Duration) *Pruner {
+	return &Pruner{what: "tombstones", deleteBefore: tombstones.DeleteBefore, keep: keep, interval: interval}
+}
+
+// NewAuditPruner creates a Pruner that runs every interval and removes
+// audit entries older than keep
+func NewAuditPruner(audit repository.AuditRepository, keep, interval time.Duration) *Pruner {
+	return &Pruner{what: "audit entries", deleteBefore: audit.DeleteBefore, keep: keep, interval: interval}
+}
+
+// Run prunes on every tick until ctx is cancelled
+func (p *Pruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := p.deleteBefore(ctx, time.Now().Add(-p.keep))
+		if err != nil {
+			log.Printf("Pruning %s failed: %v", p.what, err)
+		} else if n > 0 {
+			log.Printf("Pruned %d %s", n, p.what)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"todo-api/repository"
+)
+
+// sweepBatchSize is how many inactive users are purged per query
+const sweepBatchSize = 100
+
+// Sweeper periodically purges the personal todos, with their revisions and
+// tombstones, custom fields, saved filters and Slack links of users who
+// haven't been seen for longer than the retention window. Todos they created in shared
+// workspaces belong to the workspace and are kept.
+type Sweeper struct {
+	todos         repository.TodoRepository
+	fields        repository.CustomFieldRepository
+	filters       repository.SavedFilterRepository
+	revisions     repository.RevisionRepository
+	tombstones    repository.TombstoneRepository
+	slackLinks    repository.SlackLinkRepository
+	users         repository.UserRepository
+	tx            repository.Transactor
+	inactiveAfter time.Duration
+	interval      time.Duration
+}
+
+// NewSweeper creates a Sweeper that runs every interval and purges users
+// inactive for longer than inactiveAfter, each user in one transaction where
+// tx supports them
+func NewSweeper(todos repository.TodoRepository, fields repository.CustomFieldRepository, filters repository.SavedFilterRepository, revisions repository.RevisionRepository, tombstones repository.TombstoneRepository, slackLinks repository.SlackLinkRepository, users repository.UserRepository, tx repository.Transactor, inactiveAfter, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		todos:         todos,
+		fields:        fields,
+		filters:       filters,
+		revisions:     revisions,
+		tombstones:    tombstones,
+		slackLinks:    slackLinks,
+		users:         users,
+		tx:            tx,
+		inactiveAfter: inactiveAfter,
+		interval:      interval,
+	}
+}
+
+// Run sweeps on every tick until ctx is cancelled
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		users, todos, err := s.Sweep(ctx)
+		if err != nil {
+			log.Println("Retention sweep failed:", err)
+		} else if users > 0 {
+			log.Printf("Retention sweep purged %d todos from %d inactive users", todos, users)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep purges every user inactive beyond the retention window and returns
+// how many users and todos were removed
+func (s *Sweeper) Sweep(ctx context.Context) (int, int64, error) {
+	cutoff := time.Now().Add(-s.inactiveAfter)
+
+	var users int
+	var todos int64
+	for {
+		ids, err := s.users.ListInactive(ctx, cutoff, sweepBatchSize)
+		if err != nil {
+			return users, todos, err
+		}
+		if len(ids) == 0 {
+			return users, todos, nil
+		}
+
+		for _, id := range ids {
+			var n int64
+			err := s.tx.WithTransaction(ctx, func(ctx context.Context) (err error) {
+				n, err = s.purge(ctx, id)
+				return err
+			})
+			if err != nil {
+				return users, todos, err
+			}
+			users++
+			todos += n
+		}
+	}
+}
+
+// DryRun reports what Sweep would purge now without purging anything: how
+// many users and todos, and the IDs of up to limit of the users
+func (s *Sweeper) DryRun(ctx context.Context, limit int) (int, int64, []string, error) {
+	cutoff := time.Now().Add(-s.inactiveAfter)
+
+	var users int
+	var todos int64
+	sample := []string{}
+	after := ""
+	for {
+		// Nothing is deleted, so users are paged through rather than
+		// listed by ListInactive again
+		page, err := s.users.List(ctx, after, sweepBatchSize)
+		if err != nil {
+			return users, todos, sample, err
+		}
+		for _, user := range page {
+			if !user.LastSeen.Before(cutoff) {
+				continue
+			}
+			n, err := s.todos.Count(ctx, repository.Personal(user.ID))
+			if err != nil {
+				return users, todos, sample, err
+			}
+			users++
+			todos += n
+			if len(sample) < limit {
+				sample = append(sample, user.ID)
+			}
+		}
+		if len(page) < sweepBatchSize {
+			return users, todos, sample, nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+// purge deletes one user's data and returns how many todos were deleted
+func (s *Sweeper) purge(ctx context.Context, id string) (int64, error) {
+	// Without transactions, deleting todos first means a failure leaves the
+	// user record in place and the next sweep retries
+	n, err := s.todos.DeleteAll(ctx, repository.Personal(id))
+	if err != nil {
+		return 0, err
+	}
+	if err := s.fields.DeleteAll(ctx, repository.Personal(id)); err != nil {
+		return 0, err
+	}
+	if err := s.filters.DeleteAll(ctx, repository.Personal(id)); err != nil {
+		return 0, err
+	}
+	if err := s.revisions.DeleteAll(ctx, repository.Personal(id)); err != nil {
+		return 0, err
+	}
+	if err := s.tombstones.DeleteAll(ctx, repository.Personal(id)); err != nil {
+		return 0, err
+	}
+	if err := s.slackLinks.Delete(ctx, id); err != nil {
+		return 0, err
+	}
+	if err := s.users.Delete(ctx, id); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,72 @@
+package retention
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"todo-api/repository"
+	"todo-api/tenancy"
+)
+
+// Tracker records when users were last seen. To avoid a database write on
+// every request it only persists a user's activity once per interval.
+type Tracker struct {
+	users    repository.UserRepository
+	interval time.Duration
+
+	mu      sync.Mutex
+	written map[string]time.Time
+}
+
+// NewTracker creates a Tracker that persists activity at most once per
+// interval for each user
+func NewTracker(users repository.UserRepository, interval time.Duration) *Tracker {
+	return &Tracker{
+		users:    users,
+		interval: interval,
+		written:  make(map[string]time.Time),
+	}
+}
+
+// Touch records that the user is active now
+func (t *Tracker) Touch(ctx context.Context, userID string) error {
+	now := time.Now()
+	// The same user ID can belong to users of several tenants
+	key := userID
+	if tenant, ok := tenancy.FromContext(ctx); ok {
+		key = tenant + "/" + userID
+	}
+
+	t.mu.Lock()
+	last, ok := t.written[key]
+	if ok && now.Sub(last) < t.interval {
+		t.mu.Unlock()
+		return nil
+	}
+	t.written[key] = now
+	t.prune(now)
+	t.mu.Unlock()
+
+	if err := t.users.Touch(ctx, userID, now); err != nil {
+		// Forget the write so the next request retries it
+		t.mu.Lock()
+		delete(t.written, key)
+		t.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// prune drops entries that are old enough to be rewritten anyway, keeping the
+// map from growing with every identity ever seen. Must hold t.mu.
+func (t *Tracker) prune(now time.Time) {
+	if len(t.written) < 10000 {
+		return
+	}
+	for id, last := range t.written {
+		if now.Sub(last) >= t.interval {
+			delete(t.written, id)
+		}
+	}
+}
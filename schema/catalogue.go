@@ -0,0 +1,47 @@
+package schema
+
+// Catalogue holds the schemas of request bodies, by name and by route
+type Catalogue struct {
+	requests []*Request
+	byName   map[string]*Request
+	byRoute  map[string]*Request
+}
+
+// NewCatalogue creates a Catalogue of requests. A route must be taken by
+// one request only.
+func NewCatalogue(requests ...*Request) *Catalogue {
+	c := &Catalogue{
+		requests: requests,
+		byName:   make(map[string]*Request, len(requests)),
+		byRoute:  map[string]*Request{},
+	}
+	for _, r := range requests {
+		r.Schema.Dialect = Dialect
+		c.byName[r.Name] = r
+		for _, route := range r.Routes {
+			if _, ok := c.byRoute[route]; ok {
+				panic("schema: route " + route + " is taken by more than one request")
+			}
+			c.byRoute[route] = r
+		}
+	}
+	return c
+}
+
+// Requests returns the requests, in the order they were added
+func (c *Catalogue) Requests() []*Request {
+	return c.requests
+}
+
+// Get returns the request of the given name
+func (c *Catalogue) Get(name string) (*Request, bool) {
+	r, ok := c.byName[name]
+	return r, ok
+}
+
+// ForRoute returns the request taken by the route, such as POST and
+// /todos, under the prefix of the versioned API
+func (c *Catalogue) ForRoute(method, route string) (*Request, bool) {
+	r, ok := c.byRoute[method+" "+route]
+	return r, ok
+}
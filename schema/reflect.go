@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	objectIDType = reflect.TypeFor[primitive.ObjectID]()
+)
+
+// objectIDPattern matches the IDs of todos and other stored records
+const objectIDPattern = "^[0-9a-fA-F]{24}$"
+
+// For builds the schema of the JSON encoding/json reads into a T: structs
+// become objects of their json-tagged fields, pointers may be null, slices
+// arrays and maps objects of free keys
+func For[T any]() *Schema {
+	return fromType(reflect.TypeFor[T]())
+}
+
+func fromType(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+	s := &Schema{Nullable: nullable}
+	switch {
+	case t == timeType:
+		s.Type, s.Format = TypeString, "date-time"
+		return s
+	case t == objectIDType:
+		s.Type, s.Pattern = TypeString, objectIDPattern
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s.Type, s.Properties = TypeObject, map[string]*Schema{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			s.Properties[name] = fromType(field.Type)
+		}
+	case reflect.Map:
+		// Null maps decode as nil ones, the same as leaving them out
+		s.Type, s.Nullable = TypeObject, true
+		if values := fromType(t.Elem()); values.Type != "" {
+			s.Values = values
+		} else {
+			s.OpenValues = true
+		}
+	case reflect.Slice, reflect.Array:
+		s.Type, s.Items = TypeArray, fromType(t.Elem())
+		s.Nullable = s.Nullable || t.Kind() == reflect.Slice
+	case reflect.String:
+		s.Type = TypeString
+	case reflect.Bool:
+		s.Type = TypeBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Type = TypeInteger
+	case reflect.Float32, reflect.Float64:
+		s.Type = TypeNumber
+	}
+	// Anything else, such as an interface, takes any value
+	return s
+}
@@ -0,0 +1,187 @@
+package schema
+
+import (
+	"strings"
+
+	"todo-api/authz"
+	"todo-api/models"
+)
+
+// Request is the schema of a request body and the routes that take it
+type Request struct {
+	// Name identifies the schema, such as create_todo
+	Name string
+	// Routes are the routes that take the body, such as "POST /todos",
+	// under the prefix of the versioned API
+	Routes []string
+	Schema *Schema
+}
+
+// body starts the Request of a body read into a T
+func body[T any](name, title string, routes ...string) *Request {
+	s := For[T]()
+	s.Title = title
+	return &Request{Name: name, Routes: routes, Schema: s}
+}
+
+// require lists properties that must be sent, such as title or, within
+// each item of a list, checklist[].title
+func (r *Request) require(paths ...string) *Request {
+	for _, path := range paths {
+		s, name := r.Schema, path
+		if i := strings.LastIndex(path, "."); i >= 0 {
+			s, name = r.property(path[:i]), path[i+1:]
+		}
+		s.Required = append(s.Required, name)
+	}
+	return r
+}
+
+// length limits the characters of a string property; a min of 0 leaves it
+// unlimited
+func (r *Request) length(path string, min, max int) *Request {
+	s := r.property(path)
+	if min > 0 {
+		s.MinLength = intPtr(min)
+	}
+	s.MaxLength = intPtr(max)
+	return r
+}
+
+// entries limits the entries of an array property; a min of 0 leaves it
+// unlimited
+func (r *Request) entries(path string, min, max int) *Request {
+	s := r.property(path)
+	if min > 0 {
+		s.MinItems = intPtr(min)
+	}
+	s.MaxItems = intPtr(max)
+	return r
+}
+
+// between limits a number property to min to max, both included
+func (r *Request) between(path string, min, max float64) *Request {
+	s := r.property(path)
+	s.Minimum, s.Maximum = floatPtr(min), floatPtr(max)
+	return r
+}
+
+// oneOf limits a string property to values
+func (r *Request) oneOf(path string, values ...string) *Request {
+	s := r.property(path)
+	s.Enum = make([]any, len(values))
+	for i, value := range values {
+		s.Enum[i] = value
+	}
+	return r
+}
+
+// id makes a string property the ID of a stored record
+func (r *Request) id(path string) *Request {
+	r.property(path).Pattern = objectIDPattern
+	return r
+}
+
+// property returns the schema of a property, which must exist: the
+// catalogue is fixed, so a typo is a bug
+func (r *Request) property(path string) *Schema {
+	s := r.Schema.Property(path)
+	if s == nil {
+		panic("schema " + r.Name + " has no property " + path)
+	}
+	return s
+}
+
+// todo limits the fields of a todo, under prefix
+func (r *Request) todo(prefix string) *Request {
+	return r.length(prefix+"title", 1, models.MaxTitleLength).
+		length(prefix+"description", 0, models.MaxDescriptionLength).
+		length(prefix+"due", 0, models.MaxDueLength).
+		oneOf(prefix+"priority", "", string(models.PriorityLow), string(models.PriorityMedium), string(models.PriorityHigh)).
+		oneOf(prefix+"checklist_rule", string(models.ChecklistRuleNone), string(models.ChecklistRuleAutoComplete), string(models.ChecklistRuleBlock)).
+		between(prefix+"estimated_minutes", 0, models.MaxMinutes).
+		between(prefix+"actual_minutes", 0, models.MaxMinutes).
+		oneOf(prefix+"location.type", models.GeoJSONPoint).
+		entries(prefix+"location.coordinates", 2, 2)
+}
+
+// inWorkspaces adds the routes of the same bodies under a workspace
+func inWorkspaces(routes ...string) []string {
+	all := routes
+	for _, route := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		all = append(all, method+" /workspaces/:workspace_id"+path)
+	}
+	return all
+}
+
+// API returns the catalogue of the API's request bodies
+func API() *Catalogue {
+	return NewCatalogue(
+		body[models.CreateTodoRequest]("create_todo", "Create a todo", inWorkspaces("POST /todos")...).
+			require("title").todo("").
+			entries("checklist", 0, models.MaxChecklistItems).
+			require("checklist[].title").length("checklist[].title", 1, models.MaxTitleLength).
+			entries("attachments", 0, models.MaxAttachments).
+			require("attachments[].url").length("attachments[].url", 1, models.MaxAttachmentURLLength),
+		body[models.UpdateTodoRequest]("update_todo", "Update a todo", inWorkspaces("PUT /todos/:id")...).
+			todo(""),
+		body[models.SnoozeRequest]("snooze_todo", "Snooze a todo", inWorkspaces("POST /todos/:id/snooze")...),
+		body[models.AddBlockerRequest]("add_blocker", "Add a blocker to a todo", inWorkspaces("POST /todos/:id/blockers")...).
+			require("todo_id").id("todo_id"),
+		body[models.AddAttachmentRequest]("add_attachment", "Attach a link to a todo", inWorkspaces("POST /todos/:id/attachments")...).
+			require("url").length("url", 1, models.MaxAttachmentURLLength),
+		body[models.AddChecklistItemRequest]("add_checklist_item", "Add a checklist item", inWorkspaces("POST /todos/:id/checklist")...).
+			require("title").length("title", 1, models.MaxTitleLength),
+		body[models.UpdateChecklistItemRequest]("update_checklist_item", "Update a checklist item", inWorkspaces("PUT /todos/:id/checklist/:item_id")...).
+			length("title", 1, models.MaxTitleLength),
+		body[models.AddCommentRequest]("add_comment", "Comment on a todo", inWorkspaces("POST /todos/:id/comments")...).
+			require("body").length("body", 1, models.MaxCommentLength),
+		body[models.ShareTodoRequest]("share_todo", "Share a todo", "POST /todos/:id/share").
+			length("user_id", 0, models.MaxUserIDLength).
+			oneOf("role", "", string(authz.RoleViewer), string(authz.RoleEditor)),
+		body[models.AssignTodoRequest]("assign_todo", "Assign a todo", "POST /workspaces/:workspace_id/todos/:id/assign").
+			length("assignee_id", 0, models.MaxUserIDLength),
+		body[models.LookupTodosRequest]("lookup_todos", "Fetch todos by ID", inWorkspaces("POST /todos/lookup")...).
+			require("ids").entries("ids", 1, models.MaxLookupIDs).id("ids[]"),
+		body[models.SyncPushRequest]("sync_push", "Push changes made offline", inWorkspaces("POST /sync")...).
+			require("changes").entries("changes", 1, models.MaxSyncChanges).
+			oneOf("on_conflict", "", models.ConflictLastWriteWins, models.ConflictMerge, models.ConflictManual).
+			oneOf("changes[].op", models.SyncCreate, models.SyncUpdate, models.SyncDelete).
+			todo("changes[].todo."),
+		body[models.RenameTagRequest]("rename_tag", "Rename a tag", inWorkspaces("PUT /tags/:tag")...).
+			require("name"),
+		body[models.MergeTagRequest]("merge_tag", "Merge a tag into another", inWorkspaces("POST /tags/:tag/merge")...).
+			require("into"),
+		body[models.CreateCustomFieldRequest]("create_custom_field", "Create a custom field", inWorkspaces("POST /custom-fields")...).
+			require("name", "type").length("name", 1, models.MaxCustomFieldNameLength).
+			entries("options", 0, models.MaxCustomFieldOptions).
+			length("options[]", 0, models.MaxCustomFieldOptionLength),
+		body[models.CreateSavedFilterRequest]("create_saved_filter", "Save a filter", inWorkspaces("POST /filters")...).
+			require("name").length("name", 1, models.MaxFilterNameLength).
+			between("criteria.due_within_days", 0, models.MaxDueWithinDays).
+			length("criteria.search", 0, models.MaxSearchLength),
+		body[models.UpdateSavedFilterRequest]("update_saved_filter", "Update a saved filter", inWorkspaces("PUT /filters/:filter_id")...).
+			length("name", 1, models.MaxFilterNameLength).
+			between("criteria.due_within_days", 0, models.MaxDueWithinDays).
+			length("criteria.search", 0, models.MaxSearchLength),
+		body[models.UpdateSettingsRequest]("update_settings", "Update settings", "PUT /settings"),
+		body[models.UpdateNotificationPreferencesRequest]("update_notification_preferences", "Pick notification channels", "PUT /notifications/preferences").
+			require("preferences"),
+		body[models.UpdatePreferencesRequest]("update_preferences", "Update preferences", "PUT /preferences").
+			oneOf("default_priority", "", string(models.PriorityLow), string(models.PriorityMedium), string(models.PriorityHigh)).
+			entries("default_tags", 0, models.MaxTags).
+			oneOf("start_of_week", "", "sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"),
+		body[models.CreateWorkspaceRequest]("create_workspace", "Create a workspace", "POST /workspaces").
+			require("name").length("name", 1, models.MaxWorkspaceNameLength),
+		body[models.UpdateWorkspaceRequest]("update_workspace", "Update a workspace", "PUT /workspaces/:workspace_id").
+			length("name", 1, models.MaxWorkspaceNameLength),
+		body[models.AddMemberRequest]("add_member", "Add a workspace member", "POST /workspaces/:workspace_id/members").
+			require("user_id").length("user_id", 1, models.MaxUserIDLength).
+			oneOf("role", "", string(authz.RoleViewer), string(authz.RoleEditor), string(authz.RoleAdmin)),
+		body[models.UpdateMemberRequest]("update_member", "Change a member's role", "PUT /workspaces/:workspace_id/members/:user_id").
+			require("role").oneOf("role", string(authz.RoleViewer), string(authz.RoleEditor), string(authz.RoleAdmin)),
+		body[models.ImportRequest]("import_todos", "Import todos from another service", inWorkspaces("POST /imports/:provider")...).
+			require("code", "state").length("code", 1, 2048),
+	)
+}
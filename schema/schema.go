@@ -0,0 +1,115 @@
+// Package schema describes the request bodies of the API as JSON Schemas,
+// so clients can check forms before sending them, and checks bodies
+// against them. The schemas are built from the request types in models and
+// carry the limits those types are validated with; the handlers still run
+// the full validation, as some rules, like whether a time zone exists,
+// can't be put in a schema.
+package schema
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// Dialect is the JSON Schema draft the schemas follow
+const Dialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Types of JSON value
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+)
+
+// Schema is the part of JSON Schema the API's request bodies need
+type Schema struct {
+	Dialect     string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Type is one of the Type constants, or empty for any value
+	Type string `json:"-"`
+	// Nullable also allows null
+	Nullable bool `json:"-"`
+
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	// Values, when set, is the schema of the values of an object whose keys
+	// are free, such as a map; without it only Properties are allowed
+	Values *Schema `json:"-"`
+	// OpenValues allows any properties, of any value
+	OpenValues bool `json:"-"`
+
+	Items    *Schema `json:"items,omitempty"`
+	MinItems *int    `json:"minItems,omitempty"`
+	MaxItems *int    `json:"maxItems,omitempty"`
+
+	Enum      []any    `json:"enum,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+	Format    string   `json:"format,omitempty"`
+	Minimum   *float64 `json:"minimum,omitempty"`
+	Maximum   *float64 `json:"maximum,omitempty"`
+}
+
+// MarshalJSON writes the schema, with its type and the properties it allows
+// in JSON Schema's terms
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	out := struct {
+		Type any `json:"type,omitempty"`
+		*plain
+		AdditionalProperties any   `json:"additionalProperties,omitempty"`
+		Enum                 []any `json:"enum,omitempty"`
+	}{plain: (*plain)(s), Enum: s.Enum}
+	switch {
+	case s.Type != "" && s.Nullable:
+		out.Type = []string{s.Type, "null"}
+		// null has to be listed to be allowed alongside the values
+		if len(s.Enum) > 0 {
+			out.Enum = append(slices.Clip(s.Enum), nil)
+		}
+	case s.Type != "":
+		out.Type = s.Type
+	}
+	if s.Type == TypeObject {
+		switch {
+		case s.Values != nil:
+			out.AdditionalProperties = s.Values
+		case !s.OpenValues:
+			out.AdditionalProperties = false
+		}
+	}
+	return json.Marshal(out)
+}
+
+// Property returns the schema of a property, following dots into nested
+// objects and [] into the items of arrays, such as "changes[].todo.title",
+// or nil if there's no such property
+func (s *Schema) Property(path string) *Schema {
+	for s != nil && path != "" {
+		if rest, ok := strings.CutPrefix(path, "[]"); ok {
+			s, path = s.Items, strings.TrimPrefix(rest, ".")
+			continue
+		}
+		i := strings.IndexAny(path, ".[")
+		if i < 0 {
+			i = len(path)
+		}
+		s, path = s.Properties[path[:i]], strings.TrimPrefix(path[i:], ".")
+	}
+	return s
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,200 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"todo-api/apierrors"
+)
+
+// patterns caches the compiled Pattern of each schema
+var patterns sync.Map
+
+// Validate checks a value, decoded by a json.Decoder that uses numbers,
+// against the schema and returns every field that breaks it. Fields are
+// named as the handlers name them, such as changes[0].todo.title.
+func (s *Schema) Validate(v any) []apierrors.FieldError {
+	return s.validate(nil, "", v)
+}
+
+func (s *Schema) validate(errs []apierrors.FieldError, field string, v any) []apierrors.FieldError {
+	if v == nil {
+		if s.Nullable || s.Type == "" {
+			return errs
+		}
+		return append(errs, apierrors.FieldError{Field: field, Message: typeMessage(s.Type)})
+	}
+	switch s.Type {
+	case TypeObject:
+		object, ok := v.(map[string]any)
+		if !ok {
+			break
+		}
+		return s.validateObject(errs, field, object)
+	case TypeArray:
+		items, ok := v.([]any)
+		if !ok {
+			break
+		}
+		return s.validateArray(errs, field, items)
+	case TypeString:
+		str, ok := v.(string)
+		if !ok {
+			break
+		}
+		return s.validateString(errs, field, str)
+	case TypeInteger, TypeNumber:
+		n, ok := v.(json.Number)
+		if !ok {
+			break
+		}
+		return s.validateNumber(errs, field, n)
+	case TypeBoolean:
+		if _, ok := v.(bool); !ok {
+			break
+		}
+		return errs
+	default:
+		return errs
+	}
+	return append(errs, apierrors.FieldError{Field: field, Message: typeMessage(s.Type)})
+}
+
+func (s *Schema) validateObject(errs []apierrors.FieldError, field string, object map[string]any) []apierrors.FieldError {
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			errs = append(errs, apierrors.FieldError{Field: join(field, name), Message: "is required"})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(object)) {
+		switch property := s.Properties[name]; {
+		case property != nil:
+			errs = property.validate(errs, join(field, name), object[name])
+		case s.Values != nil:
+			errs = s.Values.validate(errs, join(field, name), object[name])
+		case !s.OpenValues:
+			errs = append(errs, apierrors.FieldError{Field: join(field, name), Message: "is not a field we understand"})
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateArray(errs []apierrors.FieldError, field string, items []any) []apierrors.FieldError {
+	switch {
+	case s.MinItems != nil && len(items) < *s.MinItems && *s.MinItems == 1:
+		return append(errs, apierrors.FieldError{Field: field, Message: "must not be empty"})
+	case s.MinItems != nil && len(items) < *s.MinItems:
+		return append(errs, apierrors.FieldError{Field: field, Message: fmt.Sprintf("must have at least %d entries", *s.MinItems)})
+	case s.MaxItems != nil && len(items) > *s.MaxItems:
+		return append(errs, apierrors.FieldError{Field: field, Message: fmt.Sprintf("must have at most %d entries", *s.MaxItems)})
+	}
+	if s.Items == nil {
+		return errs
+	}
+	for i, item := range items {
+		errs = s.Items.validate(errs, fmt.Sprintf("%s[%d]", field, i), item)
+	}
+	return errs
+}
+
+func (s *Schema) validateString(errs []apierrors.FieldError, field, str string) []apierrors.FieldError {
+	length := utf8.RuneCountInString(str)
+	switch {
+	case s.MinLength != nil && length < *s.MinLength && *s.MinLength == 1:
+		return append(errs, apierrors.FieldError{Field: field, Message: "must not be empty"})
+	case s.MinLength != nil && length < *s.MinLength:
+		return append(errs, apierrors.FieldError{Field: field, Message: fmt.Sprintf("must be at least %d characters", *s.MinLength)})
+	case s.MaxLength != nil && length > *s.MaxLength:
+		return append(errs, apierrors.FieldError{Field: field, Message: fmt.Sprintf("must be at most %d characters", *s.MaxLength)})
+	}
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, any(str)) {
+		values := make([]string, len(s.Enum))
+		for i, value := range s.Enum {
+			values[i] = fmt.Sprintf("%q", value)
+		}
+		return append(errs, apierrors.FieldError{Field: field, Message: "must be one of " + strings.Join(values, ", ")})
+	}
+	if s.Pattern != "" && !s.pattern().MatchString(str) {
+		message := "must match the pattern " + s.Pattern
+		if s.Pattern == objectIDPattern {
+			message = "must be an ID"
+		}
+		return append(errs, apierrors.FieldError{Field: field, Message: message})
+	}
+	if s.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			return append(errs, apierrors.FieldError{Field: field, Message: "must be an RFC 3339 time like 2026-10-16T09:30:00Z"})
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateNumber(errs []apierrors.FieldError, field string, n json.Number) []apierrors.FieldError {
+	if s.Type == TypeInteger {
+		if _, err := strconv.ParseInt(string(n), 10, 64); err != nil {
+			return append(errs, apierrors.FieldError{Field: field, Message: typeMessage(TypeInteger)})
+		}
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return append(errs, apierrors.FieldError{Field: field, Message: typeMessage(s.Type)})
+	}
+	tooSmall := s.Minimum != nil && f < *s.Minimum
+	tooLarge := s.Maximum != nil && f > *s.Maximum
+	switch {
+	case !tooSmall && !tooLarge:
+		return errs
+	case s.Minimum != nil && s.Maximum != nil:
+		return append(errs, apierrors.FieldError{Field: field, Message: fmt.Sprintf("must be between %s and %s", formatNumber(*s.Minimum), formatNumber(*s.Maximum))})
+	case tooSmall:
+		return append(errs, apierrors.FieldError{Field: field, Message: "must be at least " + formatNumber(*s.Minimum)})
+	default:
+		return append(errs, apierrors.FieldError{Field: field, Message: "must be at most " + formatNumber(*s.Maximum)})
+	}
+}
+
+// pattern returns the compiled Pattern
+func (s *Schema) pattern() *regexp.Regexp {
+	if re, ok := patterns.Load(s.Pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(s.Pattern)
+	patterns.Store(s.Pattern, re)
+	return re
+}
+
+// typeMessage says what a value of the type must be
+func typeMessage(typ string) string {
+	switch typ {
+	case TypeObject:
+		return "must be an object"
+	case TypeArray:
+		return "must be an array"
+	case TypeInteger:
+		return "must be an integer"
+	case TypeBoolean:
+		return "must be true or false"
+	default:
+		return "must be a " + typ
+	}
+}
+
+// join names the property of an object field
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Highlight is an excerpt of a todo's field with the words that matched a
+// query marked, so search UIs can show why a todo came up. Offsets are
+// given rather than markup, since titles and descriptions may hold any
+// text.
+type Highlight struct {
+	// Field is "title" or "description"
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+	// Matches are the matching words, as [start, end) offsets in
+	// characters into Snippet
+	Matches [][2]int `json:"matches"`
+}
+
+// ellipsis marks where a snippet cuts text short
+const ellipsis = '…'
+
+// Highlight marks the words of text matching any of the query's terms, as
+// Score matches them. Text longer than width characters is cut to about
+// width around the first match, at word boundaries. ok is false when no
+// word matches.
+func (q Query) Highlight(field, text string, width int) (highlight Highlight, ok bool) {
+	runes := []rune(text)
+	var matches [][2]int
+	for _, span := range wordSpans(runes) {
+		word := strings.ToLower(string(runes[span[0]:span[1]]))
+		for _, term := range q.terms {
+			if match(term, word) > 0 {
+				matches = append(matches, span)
+				break
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return Highlight{}, false
+	}
+
+	start, end := 0, len(runes)
+	if end > width {
+		first := matches[0]
+		// Lead in with a little of what comes before the first match
+		start = max(0, first[0]-width/4)
+		end = min(len(runes), start+width)
+		start = max(0, min(start, end-width))
+		for start < first[0] && (inWord(runes, start) || unicode.IsSpace(runes[start])) {
+			start++
+		}
+		for end > first[1] && (inWord(runes, end) || unicode.IsSpace(runes[end-1])) {
+			end--
+		}
+	}
+
+	var snippet []rune
+	offset := -start
+	if start > 0 {
+		snippet = append(snippet, ellipsis)
+		offset++
+	}
+	snippet = append(snippet, runes[start:end]...)
+	if end < len(runes) {
+		snippet = append(snippet, ellipsis)
+	}
+	highlight = Highlight{Field: field, Snippet: string(snippet), Matches: [][2]int{}}
+	for _, m := range matches {
+		if m[0] >= start && m[1] <= end {
+			highlight.Matches = append(highlight.Matches, [2]int{m[0] + offset, m[1] + offset})
+		}
+	}
+	return highlight, true
+}
+
+// wordSpans returns the [start, end) offsets of the words of text, split as
+// words splits them
+func wordSpans(runes []rune) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range runes {
+		switch {
+		case isWordRune(r) && start < 0:
+			start = i
+		case !isWordRune(r) && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(runes)})
+	}
+	return spans
+}
+
+// inWord reports whether a cut at i would split a word
+func inWord(runes []rune, i int) bool {
+	return i > 0 && i < len(runes) && isWordRune(runes[i-1]) && isWordRune(runes[i])
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,129 @@
+// Package search matches todos against free-text queries. Matching
+// tolerates typos, so "grocerys" still finds "Groceries", and works the
+// same on every storage backend because it runs in the process.
+package search
+
+import "strings"
+
+// descriptionWeight scales matches in the description, so todos that
+// match in the title rank first
+const descriptionWeight = 0.5
+
+// Query is a parsed search query. Every term must match a word of the todo.
+type Query struct {
+	terms []string
+}
+
+// Parse splits a query into terms, ignoring case and punctuation
+func Parse(q string) Query {
+	return Query{terms: words(q)}
+}
+
+// Empty reports whether the query has no terms, which matches everything
+func (q Query) Empty() bool {
+	return len(q.terms) == 0
+}
+
+// Score returns how well a todo's title and description match, between 0
+// and 1. It's 0 when some term matches neither.
+func (q Query) Score(title, description string) float64 {
+	titleWords, descriptionWords := words(title), words(description)
+	total := 0.0
+	for _, term := range q.terms {
+		best := bestMatch(term, titleWords)
+		if d := descriptionWeight * bestMatch(term, descriptionWords); d > best {
+			best = d
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total / float64(len(q.terms))
+}
+
+// words lowercases text and splits it at anything but letters and digits
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !isWordRune(r)
+	})
+}
+
+// bestMatch returns the best score of term against any of the words
+func bestMatch(term string, words []string) float64 {
+	best := 0.0
+	for _, word := range words {
+		if s := match(term, word); s > best {
+			best = s
+		}
+	}
+	return best
+}
+
+// match scores a term against one word: 1 when equal, less when the term
+// starts the word, as while typing, and less again for each typo
+func match(term, word string) float64 {
+	if term == word {
+		return 1
+	}
+	t, w := []rune(term), []rune(word)
+	if len(t) >= 2 && strings.HasPrefix(word, term) {
+		return 0.8
+	}
+	allowed := maxEdits(len(t))
+	if allowed == 0 || abs(len(t)-len(w)) > allowed {
+		return 0
+	}
+	if d := distance(t, w); d <= allowed {
+		return 0.7 - 0.1*float64(d)
+	}
+	return 0
+}
+
+// maxEdits is how many typos a term of n letters may have. Short terms
+// need to be exact, or nearly every word would match.
+func maxEdits(n int) int {
+	switch {
+	case n < 3:
+		return 0
+	case n < 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// distance returns the number of insertions, deletions, substitutions and
+// swaps of adjacent letters that turn a into b
+func distance(a, b []rune) int {
+	// Three rows of the usual dynamic programming table are enough: swaps
+	// look two rows back
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,121 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-api/azure"
+)
+
+const (
+	// vaultResource is the token audience for Key Vault
+	vaultResource   = "https://vault.azure.net"
+	vaultAPIVersion = "7.4"
+)
+
+// KeyVault serves settings from secrets stored in Azure Key Vault, read with
+// the app's managed identity. Secrets are fetched by Load and cached in
+// memory; call Load again to pick up rotated values.
+//
+// Key Vault secret names can't contain underscores, so a setting such as
+// COOKIE_SECRET is stored as the secret COOKIE-SECRET.
+type KeyVault struct {
+	url      string
+	identity *azure.ManagedIdentity
+	client   *http.Client
+
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// NewKeyVault creates a provider for the vault at vaultURL
+func NewKeyVault(vaultURL string, identity *azure.ManagedIdentity) *KeyVault {
+	return &KeyVault{
+		url:      vaultURL,
+		identity: identity,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		values:   make(map[string]string),
+	}
+}
+
+// Load fetches the named settings from the vault, replacing the cached values
+// only if every fetch succeeds. Settings without a secret in the vault are
+// left to the fallback.
+func (k *KeyVault) Load(ctx context.Context, keys ...string) error {
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, ok, err := k.fetch(ctx, secretName(key))
+		if err != nil {
+			return fmt.Errorf("read %s from Key Vault: %w", key, err)
+		}
+		if ok {
+			values[key] = value
+		}
+	}
+
+	k.mu.Lock()
+	k.values = values
+	k.mu.Unlock()
+	return nil
+}
+
+// Getenv returns a lookup function that prefers vault secrets and falls back
+// to fallback (usually os.Getenv) for everything else
+func (k *KeyVault) Getenv(fallback func(string) string) func(string) string {
+	return func(key string) string {
+		k.mu.RLock()
+		value, ok := k.values[key]
+		k.mu.RUnlock()
+		if ok {
+			return value
+		}
+		return fallback(key)
+	}
+}
+
+// fetch reads the current version of a secret. ok is false when the vault
+// has no such secret.
+func (k *KeyVault) fetch(ctx context.Context, name string) (value string, ok bool, err error) {
+	accessToken, err := k.identity.Token(ctx, vaultResource)
+	if err != nil {
+		return "", false, err
+	}
+
+	endpoint := fmt.Sprintf("%s/secrets/%s?api-version=%s", k.url, url.PathEscape(name), vaultAPIVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("Key Vault returned %s", resp.Status)
+	}
+
+	var body struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", false, err
+	}
+	return body.Value, true, nil
+}
+
+// secretName maps a setting name to its Key Vault secret name
+func secretName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
@@ -0,0 +1,185 @@
+// Package seed fills a development database with sample todos, so frontend
+// work and demos don't start from an empty list
+package seed
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"time"
+
+	"todo-api/authz"
+	"todo-api/models"
+	"todo-api/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sample is a todo the seeder picks from
+type sample struct {
+	title       string
+	description string
+	minutes     int
+}
+
+// personal are the samples of personal todos
+var personal = []sample{
+	{title: "Buy groceries", description: "- Milk\n- Eggs\n- Sourdough bread\n- Coffee beans"},
+	{title: "Book dentist appointment"},
+	{title: "Renew passport", description: "Photos are in the desk drawer. Check the **new fee** first.", minutes: 45},
+	{title: "Pay electricity bill", minutes: 10},
+	{title: "Call mum"},
+	{title: "Plan weekend hike", description: "Look at the trail map and check the [weather](https://www.example.com/weather)."},
+	{title: "Read chapter 4 of Designing Data-Intensive Applications", minutes: 60},
+	{title: "Water the plants", minutes: 10},
+	{title: "Clean out the fridge", minutes: 30},
+	{title: "Return library books"},
+	{title: "Fix the leaking kitchen tap", description: "Needs a 15mm washer from the hardware store.", minutes: 40},
+	{title: "Update CV", minutes: 90},
+	{title: "Cancel unused streaming subscription"},
+	{title: "Order birthday present for Sam", description: "Ideas: a board game or concert tickets."},
+	{title: "Back up photos to the NAS", minutes: 20},
+	{title: "Schedule car service"},
+	{title: "Write thank-you notes", minutes: 30},
+	{title: "Go for a run", minutes: 45},
+	{title: "File expense report", description: "Receipts from the Berlin trip are in the shared folder.", minutes: 25},
+	{title: "Prepare slides for Monday's standup", minutes: 60},
+	{title: "Review pull request for the billing service", minutes: 30},
+	{title: "Reply to Alex about the offsite"},
+	{title: "Set up the new laptop", description: "1. Install the dev tools\n2. Copy SSH keys\n3. Sign in to the VPN", minutes: 120},
+	{title: "Sort out the recycling"},
+	{title: "Try the new ramen place"},
+	{title: "Learn three new guitar chords", minutes: 30},
+	{title: "Donate old clothes"},
+	{title: "Check tyre pressure", minutes: 15},
+	{title: "Submit tax return", description: "Deadline is end of month. **Don't forget** the pension statement.", minutes: 180},
+	{title: "Meal prep for the week", minutes: 90},
+}
+
+// team are the samples of the shared workspace's todos
+var team = []sample{
+	{title: "Draft Q3 roadmap", description: "Collect input from support and sales first.", minutes: 240},
+	{title: "Onboard the new designer", minutes: 120},
+	{title: "Migrate CI to the new runners", minutes: 180},
+	{title: "Write release notes for 2.4"},
+	{title: "Triage open bug reports", minutes: 60},
+	{title: "Book the meeting room for the retro"},
+	{title: "Rotate the staging database password", minutes: 20},
+	{title: "Update the on-call runbook", description: "Add the steps for failing over the cache."},
+}
+
+// workspaceName names the workspace shared by the seeded users
+const workspaceName = "Demo team"
+
+// Seed gives each user without todos count personal todos with a spread of
+// priorities, colors, due dates and states, and shares a workspace with
+// sample todos among them, owned by the first user, when that user is
+// seeded. It records the users as seen now and returns the IDs of those
+// seeded. The same users always get the same samples.
+func Seed(ctx context.Context, stores *repository.Stores, userIDs []string, count int, now time.Time) ([]string, error) {
+	var seeded []string
+	for _, userID := range userIDs {
+		n, err := stores.Todos.Count(ctx, repository.Personal(userID))
+		if err != nil {
+			return seeded, err
+		}
+		if n > 0 {
+			continue
+		}
+		rnd := rand.New(rand.NewSource(int64(hash(userID))))
+		if err := createTodos(ctx, stores.Todos, rnd, userID, nil, personal, count, now); err != nil {
+			return seeded, err
+		}
+		if err := stores.Users.Touch(ctx, userID, now); err != nil {
+			return seeded, err
+		}
+		seeded = append(seeded, userID)
+	}
+
+	if len(seeded) == 0 || seeded[0] != userIDs[0] {
+		return seeded, nil
+	}
+	workspace := &models.Workspace{
+		Name:      workspaceName,
+		Color:     "teal",
+		OwnerID:   userIDs[0],
+		Members:   []models.Member{{UserID: userIDs[0], Role: authz.RoleOwner, JoinedAt: now}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, userID := range userIDs[1:] {
+		workspace.Members = append(workspace.Members, models.Member{UserID: userID, Role: authz.RoleEditor, JoinedAt: now})
+	}
+	if err := stores.Workspaces.Create(ctx, workspace); err != nil {
+		return seeded, err
+	}
+	rnd := rand.New(rand.NewSource(int64(hash(workspace.Name))))
+	return seeded, createTodos(ctx, stores.Todos, rnd, userIDs[0], &workspace.ID, team, len(team), now)
+}
+
+// createTodos creates count todos from samples, repeating them as needed
+func createTodos(ctx context.Context, todos repository.TodoRepository, rnd *rand.Rand, userID string, workspaceID *primitive.ObjectID, samples []sample, count int, now time.Time) error {
+	var created []primitive.ObjectID
+	for i := range count {
+		s := samples[i%len(samples)]
+		todo := newTodo(rnd, s, now)
+		todo.UserID = userID
+		todo.WorkspaceID = workspaceID
+		// Some todos wait for an earlier one
+		if len(created) > 0 && rnd.Intn(10) == 0 {
+			todo.BlockedBy = []primitive.ObjectID{created[rnd.Intn(len(created))]}
+		}
+		if err := todos.Create(ctx, todo); err != nil {
+			return err
+		}
+		created = append(created, todo.ID)
+	}
+	return nil
+}
+
+// newTodo makes a todo from a sample, with its other fields picked at
+// random: created within the last 30 days, most with a due date from ten
+// days ago to a month ahead, and about a third completed
+func newTodo(rnd *rand.Rand, s sample, now time.Time) *models.Todo {
+	created := now.Add(-time.Duration(rnd.Intn(30*24)) * time.Hour).Truncate(time.Minute)
+	todo := &models.Todo{
+		Title:            s.title,
+		Description:      s.description,
+		EstimatedMinutes: s.minutes,
+		CreatedAt:        created,
+		UpdatedAt:        created,
+	}
+
+	switch rnd.Intn(4) {
+	case 0:
+		todo.Priority = models.PriorityHigh
+	case 1:
+		todo.Priority = models.PriorityMedium
+	case 2:
+		todo.Priority = models.PriorityLow
+	}
+	if rnd.Intn(4) == 0 {
+		todo.Color = models.Color(models.Palette[rnd.Intn(len(models.Palette))])
+	}
+	if rnd.Intn(10) < 7 {
+		due := now.AddDate(0, 0, rnd.Intn(40)-10).Truncate(time.Hour)
+		todo.DueDate = &due
+	}
+	todo.Pinned = rnd.Intn(10) == 0
+	if rnd.Intn(3) == 0 {
+		completed := created.Add(time.Duration(rnd.Int63n(int64(now.Sub(created)) + 1))).Truncate(time.Minute)
+		todo.SetCompleted(true, completed)
+		todo.UpdatedAt = completed
+		if s.minutes > 0 {
+			todo.ActualMinutes = s.minutes * (50 + rnd.Intn(100)) / 100
+		}
+	}
+	return todo
+}
+
+// hash turns a string into a random seed
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"todo-api/config"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// serve runs the API until it fails. With TLS configured it terminates TLS
+// itself and, if a redirect port is set, redirects plain HTTP to HTTPS there
+// (also answering Let's Encrypt challenges when using autocert).
+func serve(cfg *config.Config, handler http.Handler) error {
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	if !cfg.TLS.Enabled() {
+		log.Printf("Starting server on port %s", cfg.Port)
+		return server.ListenAndServe()
+	}
+
+	var challengeHandler func(http.Handler) http.Handler
+	if len(cfg.TLS.AutocertDomains) > 0 {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLS.AutocertDomains...),
+			Cache:      autocert.DirCache(cfg.TLS.AutocertCacheDir),
+			Email:      cfg.TLS.AutocertEmail,
+		}
+		server.TLSConfig = manager.TLSConfig()
+		challengeHandler = manager.HTTPHandler
+		log.Printf("Obtaining certificates from Let's Encrypt for %v", cfg.TLS.AutocertDomains)
+	} else {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	if cfg.TLS.RedirectPort != "" {
+		var redirect http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			redirectToHTTPS(w, r, cfg.Port)
+		})
+		if challengeHandler != nil {
+			redirect = challengeHandler(redirect)
+		}
+		go func() {
+			redirectServer := &http.Server{
+				Addr:              ":" + cfg.TLS.RedirectPort,
+				Handler:           redirect,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			log.Printf("Redirecting HTTP on port %s to HTTPS", cfg.TLS.RedirectPort)
+			if err := redirectServer.ListenAndServe(); err != nil {
+				log.Println("HTTP redirect server stopped:", err)
+			}
+		}()
+	}
+
+	log.Printf("Starting HTTPS server on port %s", cfg.Port)
+	// Cert and key files are empty with autocert, which supplies certificates
+	// through TLSConfig instead
+	return server.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+}
+
+// redirectToHTTPS sends a permanent redirect to the same URL over HTTPS
+func redirectToHTTPS(w http.ResponseWriter, r *http.Request, httpsPort string) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if httpsPort != "443" {
+		host = net.JoinHostPort(host, httpsPort)
+	}
+
+	target := "https://" + host + r.URL.RequestURI()
+	http.Redirect(w, r, target, http.StatusPermanentRedirect)
+}
@@ -0,0 +1,48 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"todo-api/notify"
+	"todo-api/repository"
+)
+
+// Channel notifies users who linked Slack by mentioning them in the
+// webhook's channel
+type Channel struct {
+	links   repository.SlackLinkRepository
+	webhook *Webhook
+}
+
+// NewChannel creates a Channel posting to webhook
+func NewChannel(links repository.SlackLinkRepository, webhook *Webhook) *Channel {
+	return &Channel{links: links, webhook: webhook}
+}
+
+// Channel names the channel "slack"
+func (ch *Channel) Channel() string {
+	return "slack"
+}
+
+// Notify mentions the user with the subject, quoting the text below it
+func (ch *Channel) Notify(ctx context.Context, to notify.Recipient, n notify.Notification) error {
+	link, err := ch.links.Get(ctx, to.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return notify.ErrUnreachable
+	}
+	if err != nil {
+		return err
+	}
+	text := Mention(link.SlackUserID) + " " + Escape(n.Subject)
+	if n.Text != "" {
+		text += quote(n.Text)
+	}
+	return ch.webhook.Post(ctx, text)
+}
+
+// quote formats text as a quote
+func quote(text string) string {
+	return "\n>" + strings.ReplaceAll(Escape(text), "\n", "\n>")
+}
@@ -0,0 +1,131 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"todo-api/duedate"
+	"todo-api/models"
+	"todo-api/notify"
+	"todo-api/repository"
+)
+
+// linkBatchSize is how many links are read per query
+const linkBatchSize = 100
+
+// notifyTimeout bounds the work done for one user
+const notifyTimeout = 30 * time.Second
+
+// OverdueNotifier notifies users who linked Slack when their todos become
+// overdue, which is at the end of the day they're due in the user's time
+// zone, on the channels they picked for overdue todos. Each day is claimed
+// before it's notified, so several instances can run one without notifying
+// twice.
+type OverdueNotifier struct {
+	links    repository.SlackLinkRepository
+	users    repository.UserRepository
+	todos    repository.TodoRepository
+	registry *notify.Registry
+	interval time.Duration
+}
+
+// NewOverdueNotifier creates an OverdueNotifier that checks every interval
+func NewOverdueNotifier(links repository.SlackLinkRepository, users repository.UserRepository, todos repository.TodoRepository, registry *notify.Registry, interval time.Duration) *OverdueNotifier {
+	return &OverdueNotifier{links: links, users: users, todos: todos, registry: registry, interval: interval}
+}
+
+// Run notifies on every tick until ctx is cancelled
+func (n *OverdueNotifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := n.Notify(ctx, time.Now()); err != nil {
+			log.Println("Slack overdue check failed:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Notify notifies the todos that became overdue since each user was last
+// checked. A user that fails is logged and skipped; err is only set when
+// links can't be listed.
+func (n *OverdueNotifier) Notify(ctx context.Context, now time.Time) error {
+	after := ""
+	for {
+		links, err := n.links.List(ctx, after, linkBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			if err := n.notify(ctx, &link, now); err != nil {
+				log.Printf("Failed to notify overdue todos of user %s: %v", link.UserID, err)
+			}
+		}
+		if len(links) < linkBatchSize {
+			return nil
+		}
+		after = links[len(links)-1].UserID
+	}
+}
+
+// notify notifies the user's todos due between the start of the day they
+// were last checked and the start of today
+func (n *OverdueNotifier) notify(ctx context.Context, link *models.SlackLink, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	settings, err := n.users.GetSettings(ctx, link.UserID)
+	if err != nil {
+		return err
+	}
+	loc := settings.Location()
+	today := duedate.StartOfDay(now.In(loc))
+	from := duedate.StartOfDay(link.OverdueCheckedAt.In(loc))
+	if !from.Before(today) {
+		return nil
+	}
+	claimed, err := n.links.ClaimOverdueCheck(ctx, link.UserID, today)
+	if err != nil || !claimed {
+		return err
+	}
+	// Claimed all the same, so turning them back on doesn't bring up days
+	// gone by
+	if len(n.registry.Enabled(settings, models.NotifyOverdue)) == 0 {
+		return nil
+	}
+
+	todos, err := n.todos.List(ctx, repository.Personal(link.UserID))
+	if err != nil {
+		return err
+	}
+	var lines []string
+	for _, todo := range todos {
+		if todo.Completed || todo.Snoozed(now) || todo.DueDate == nil || todo.DueDate.Before(from) || !todo.DueDate.Before(today) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("• %s (due %s)", todo.Title, todo.DueDate.In(loc).Format("Mon 2 Jan")))
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	what := "todo is"
+	if len(lines) > 1 {
+		what = "todos are"
+	}
+	_, err = n.registry.Notify(ctx, notify.Recipient{UserID: link.UserID, Settings: settings}, notify.Notification{
+		Kind:    models.NotifyOverdue,
+		Subject: fmt.Sprintf("%d %s now overdue", len(lines), what),
+		Text:    strings.Join(lines, "\n"),
+	})
+	return err
+}
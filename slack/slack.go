@@ -0,0 +1,100 @@
+// Package slack connects the API to Slack: it verifies slash command
+// requests and posts notifications to an incoming webhook
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBadSignature is returned by Verify for requests Slack didn't sign
+var ErrBadSignature = errors.New("invalid Slack signature")
+
+// Verify checks that a request was signed by Slack with the app's signing
+// secret and sent within tolerance of now, so captured requests can't be
+// replayed later. body is the raw request body.
+func Verify(secret string, tolerance time.Duration, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || math.Abs(now.Sub(time.Unix(ts, 0)).Seconds()) > tolerance.Seconds() {
+		return ErrBadSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(header.Get("X-Slack-Signature"))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Verifier returns a check of requests for middleware.VerifySignature,
+// which Verify accepts. A request is identified by its signature, which
+// covers its timestamp and body.
+func Verifier(secret string, tolerance time.Duration) func(http.Header, []byte, time.Time) (string, error) {
+	return func(header http.Header, body []byte, now time.Time) (string, error) {
+		if err := Verify(secret, tolerance, header, body, now); err != nil {
+			return "", err
+		}
+		return header.Get("X-Slack-Signature"), nil
+	}
+}
+
+// Escape makes text safe to include in a message, where &, < and > are
+// control characters
+func Escape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// Mention formats a mention of a Slack user
+func Mention(slackUserID string) string {
+	return "<@" + slackUserID + ">"
+}
+
+// Webhook posts messages to a Slack incoming webhook, which delivers them to
+// the channel it was created for
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a Webhook posting to url
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Post sends a message in Slack's mrkdwn format
+func (w *Webhook) Post(ctx context.Context, text string) error {
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Slack webhook returned %s", resp.Status)
+	}
+	return nil
+}
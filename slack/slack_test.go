@@ -0,0 +1,85 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// signed returns the headers Slack would send with body at sent
+func signed(secret, body string, sent time.Time) http.Header {
+	timestamp := strconv.FormatInt(sent.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", timestamp)
+	header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+func TestVerify(t *testing.T) {
+	const body = "command=%2Ftodo&text=add+Buy+milk"
+	now := time.Unix(1760000000, 0)
+	tolerance := 5 * time.Minute
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   string
+		want   error
+	}{
+		{"signed by Slack", signed(testSecret, body, now), body, nil},
+		{"sent within the tolerance", signed(testSecret, body, now.Add(-4*time.Minute)), body, nil},
+		{"stale", signed(testSecret, body, now.Add(-6*time.Minute)), body, ErrBadSignature},
+		{"from the future", signed(testSecret, body, now.Add(6*time.Minute)), body, ErrBadSignature},
+		{"signed with another secret", signed("another secret", body, now), body, ErrBadSignature},
+		{"body changed", signed(testSecret, body, now), "command=%2Ftodo&text=delete+all", ErrBadSignature},
+		{"timestamp changed", func() http.Header {
+			header := signed(testSecret, body, now.Add(-time.Minute))
+			header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(now.Unix(), 10))
+			return header
+		}(), body, ErrBadSignature},
+		{"signature missing", func() http.Header {
+			header := signed(testSecret, body, now)
+			header.Del("X-Slack-Signature")
+			return header
+		}(), body, ErrBadSignature},
+		{"timestamp missing", func() http.Header {
+			header := signed(testSecret, body, now)
+			header.Del("X-Slack-Request-Timestamp")
+			return header
+		}(), body, ErrBadSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(testSecret, tolerance, tt.header, []byte(tt.body), now); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifierIdentifiesRequestsBySignature(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	verify := Verifier(testSecret, 5*time.Minute)
+
+	first, err := verify(signed(testSecret, "text=a", now), []byte("text=a"), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := verify(signed(testSecret, "text=a", now), []byte("text=a"), now)
+	other, _ := verify(signed(testSecret, "text=b", now), []byte("text=b"), now)
+	if first != again || first == other {
+		t.Errorf("IDs %q, %q and %q: want a replay to share its ID and another request not to", first, again, other)
+	}
+	if _, err := verify(signed("another secret", "text=a", now), []byte("text=a"), now); err == nil {
+		t.Error("accepted a forged request")
+	}
+}
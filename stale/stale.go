@@ -0,0 +1,183 @@
+// Package stale finds todos nobody has touched for a while and nudges
+// their users to complete, reschedule or delete them. A todo is stale while
+// it's open and unchanged for the configured period; nothing is stored, so
+// any edit makes it fresh again.
+package stale
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-api/models"
+	"todo-api/notify"
+	"todo-api/repository"
+)
+
+// userBatchSize is how many users are read per query while looking for
+// nudges that are due
+const userBatchSize = 100
+
+// nudgeTimeout bounds the work done for one user's nudge
+const nudgeTimeout = 30 * time.Second
+
+// Is reports whether todo is stale at now: open and unchanged for after
+func Is(todo *models.Todo, now time.Time, after time.Duration) bool {
+	return !todo.Completed && todo.UpdatedAt.Before(now.Add(-after))
+}
+
+// Filter returns the todos of todos that are stale at now, least recently
+// changed first
+func Filter(todos []models.Todo, now time.Time, after time.Duration) []models.Todo {
+	stale := []models.Todo{}
+	for _, todo := range todos {
+		if Is(&todo, now, after) {
+			stale = append(stale, todo)
+		}
+	}
+	slices.SortStableFunc(stale, func(a, b models.Todo) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	})
+	return stale
+}
+
+// Nudger tells users once a day, at hour in their time zone, about their
+// personal todos that went stale the day before, on the channels they
+// picked for stale todos. Snoozed todos are left out. Each nudge is claimed before it's sent, so
+// several instances can run a Nudger without nudging twice. Only the
+// latest day is nudged, so days missed while no instance was up aren't
+// caught up.
+type Nudger struct {
+	users    repository.UserRepository
+	todos    repository.TodoRepository
+	registry *notify.Registry
+	after    time.Duration
+	hour     int
+	interval time.Duration
+}
+
+// NewNudger creates a Nudger for todos unchanged for after, checking every
+// interval for nudges that are due
+func NewNudger(users repository.UserRepository, todos repository.TodoRepository, registry *notify.Registry, after time.Duration, hour int, interval time.Duration) *Nudger {
+	return &Nudger{users: users, todos: todos, registry: registry, after: after, hour: hour, interval: interval}
+}
+
+// Run sends due nudges on every tick until ctx is cancelled
+func (n *Nudger) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := n.NudgeDue(ctx, time.Now())
+		if err != nil {
+			log.Println("Nudging about stale todos failed:", err)
+		} else if sent > 0 {
+			log.Printf("Nudged %d users about stale todos", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// NudgeDue sends every nudge due by now that hasn't been sent yet and
+// returns how many were delivered. A nudge that fails is logged and
+// skipped; err is only set when users can't be listed.
+func (n *Nudger) NudgeDue(ctx context.Context, now time.Time) (int, error) {
+	sent := 0
+	after := ""
+	for {
+		users, err := n.users.List(ctx, after, userBatchSize)
+		if err != nil {
+			return sent, err
+		}
+		for _, user := range users {
+			ok, err := n.nudge(ctx, &user, now)
+			if err != nil {
+				log.Printf("Failed to nudge user %s about stale todos: %v", user.ID, err)
+			} else if ok {
+				sent++
+			}
+		}
+		if len(users) < userBatchSize {
+			return sent, nil
+		}
+		after = users[len(users)-1].ID
+	}
+}
+
+// nudge sends the user's latest nudge if no instance has yet, returning
+// whether any channel delivered it. The nudge covers the todos that went
+// stale in the day before it was due, so each is nudged about once.
+func (n *Nudger) nudge(ctx context.Context, user *models.User, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, nudgeTimeout)
+	defer cancel()
+
+	loc := user.Settings.Location()
+	due := n.Due(now.In(loc))
+	claimed, err := n.users.ClaimStaleNudge(ctx, user.ID, due)
+	if err != nil || !claimed {
+		return false, err
+	}
+	// Claimed all the same, so turning them back on doesn't bring up days
+	// gone by
+	if len(n.registry.Enabled(user.Settings, models.NotifyStale)) == 0 {
+		return false, nil
+	}
+
+	todos, err := n.todos.List(ctx, repository.Personal(user.ID))
+	if err != nil {
+		return false, err
+	}
+	since := due.AddDate(0, 0, -1).Add(-n.after)
+	var lines []string
+	for _, todo := range Filter(todos, due, n.after) {
+		if todo.UpdatedAt.Before(since) || todo.Snoozed(now) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("• %s (last changed %s)", todo.Title, todo.UpdatedAt.In(loc).Format("Mon 2 Jan")))
+	}
+	if len(lines) == 0 {
+		return false, nil
+	}
+
+	what, them := "todo hasn't", "it"
+	if len(lines) > 1 {
+		what, them = "todos haven't", "them"
+	}
+	delivered, err := n.registry.Notify(ctx, notify.Recipient{UserID: user.ID, Settings: user.Settings}, notify.Notification{
+		Kind:    models.NotifyStale,
+		Subject: fmt.Sprintf("%d %s been touched in %s", len(lines), what, Period(n.after)),
+		Text:    strings.Join(lines, "\n") + fmt.Sprintf("\n\nComplete %s if done, give %s a new due date, or delete %s if no longer needed.", them, them, them),
+	})
+	return delivered > 0, err
+}
+
+// Due returns when the latest nudge was due at or before now, in now's
+// time zone
+func (n *Nudger) Due(now time.Time) time.Time {
+	due := time.Date(now.Year(), now.Month(), now.Day(), n.hour, 0, 0, 0, now.Location())
+	if due.After(now) {
+		due = due.AddDate(0, 0, -1)
+	}
+	return due
+}
+
+// Period describes after in words, in days when it's a whole number of
+// them
+func Period(after time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case after == day:
+		return "a day"
+	case after%day == 0:
+		return fmt.Sprintf("%d days", after/day)
+	}
+	return after.String()
+}
@@ -0,0 +1,94 @@
+// Package tenancy carries the tenant a request belongs to, when one
+// deployment hosts several organizations. The repositories read the tenant
+// from the context to pick its data, so every call that reaches storage
+// must carry one.
+package tenancy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"todo-api/cache"
+	"todo-api/config"
+)
+
+// contextKey is the type of the context key the tenant is stored under
+type contextKey struct{}
+
+// WithTenant returns a copy of ctx that carries tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext returns the tenant ctx carries. ok is false without one.
+func FromContext(ctx context.Context) (tenant string, ok bool) {
+	tenant, ok = ctx.Value(contextKey{}).(string)
+	return tenant, ok
+}
+
+// Resolve returns the tenant a request names: the first label of its host in
+// subdomain mode or the configured header in header mode. ok is false when
+// the request names no tenant, or one that isn't served.
+func Resolve(cfg config.TenancyConfig, r *http.Request) (tenant string, ok bool) {
+	switch cfg.Mode {
+	case config.TenantModeSubdomain:
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		label, found := strings.CutSuffix(strings.ToLower(host), "."+cfg.Domain)
+		if !found || strings.Contains(label, ".") {
+			return "", false
+		}
+		tenant = label
+	case config.TenantModeHeader:
+		tenant = strings.ToLower(strings.TrimSpace(r.Header.Get(cfg.Header)))
+	default:
+		return "", false
+	}
+	return tenant, cfg.Known(tenant)
+}
+
+// scopedCache keeps each tenant's keys apart in a shared cache
+type scopedCache struct {
+	cache cache.Cache
+}
+
+// ScopedCache wraps c so keys used with a tenant in the context are prefixed
+// with the tenant's name, so tenants can't read each other's entries even
+// when their user IDs are the same, as with Entra ID users who belong to
+// several organizations
+func ScopedCache(c cache.Cache) cache.Cache {
+	return scopedCache{cache: c}
+}
+
+func (s scopedCache) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.cache.Get(ctx, scopedKey(ctx, key))
+}
+
+func (s scopedCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.cache.Set(ctx, scopedKey(ctx, key), value, ttl)
+}
+
+func (s scopedCache) Delete(ctx context.Context, keys ...string) error {
+	scoped := make([]string, len(keys))
+	for i, key := range keys {
+		scoped[i] = scopedKey(ctx, key)
+	}
+	return s.cache.Delete(ctx, scoped...)
+}
+
+func (s scopedCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return s.cache.Incr(ctx, scopedKey(ctx, key), ttl)
+}
+
+// scopedKey prefixes key with the tenant in ctx, if any
+func scopedKey(ctx context.Context, key string) string {
+	if tenant, ok := FromContext(ctx); ok {
+		return "tenant:" + tenant + ":" + key
+	}
+	return key
+}
@@ -0,0 +1,364 @@
+// Package todoquery parses the compact filter syntax of the q parameter,
+// such as "completed:false priority:high due<2025-01-01 groceries". Terms
+// of the form field:value or field<value select todos by their fields and
+// the remaining words are searched for in titles and descriptions.
+package todoquery
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"todo-api/models"
+	"todo-api/search"
+)
+
+// Fields lists the fields terms can select by
+var Fields = []string{"completed", "pinned", "blocked", "priority", "color", "due", "tag"}
+
+// Error describes a term that couldn't be understood. Pos is the
+// character offset of the term in the query, counting from 0.
+type Error struct {
+	Pos     int
+	Term    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%q at position %d: %s", e.Term, e.Pos, e.Message)
+}
+
+// Query is a parsed query. A todo has to meet every term and match the
+// free text.
+type Query struct {
+	Completed *bool
+	Pinned    *bool
+	Blocked   *bool
+	// priorities keeps todos whose priority satisfies every comparison
+	priorities []comparison
+	colors     []models.Color
+	due        []comparison
+	// tags keeps todos with at least one tag of each list
+	tags [][]string
+	// Text is what's left once the terms are taken out
+	Text search.Query
+}
+
+// Comparison operators. opEqual is written ":" or "="; for priorities,
+// colors and tags it takes a comma-separated list of values, any of which
+// matches.
+const (
+	opEqual        = "="
+	opLess         = "<"
+	opLessEqual    = "<="
+	opGreater      = ">"
+	opGreaterEqual = ">="
+)
+
+// comparison is one term's operator and values. Due dates are kept as
+// written until the time zone they're read in is known; due:none and
+// due:any have their value as the operator.
+type comparison struct {
+	op     string
+	values []string
+}
+
+// Parse parses a query, returning an *Error for the first term it doesn't
+// understand. Words whose part before a colon isn't a plain word, as in
+// "10:30", are searched for like any other.
+func Parse(q string) (*Query, error) {
+	query := &Query{}
+	var text []string
+	for _, t := range tokens(q) {
+		field, op, value, ok := splitTerm(t.text)
+		if !ok {
+			text = append(text, t.text)
+			continue
+		}
+		if err := query.add(field, op, value); err != "" {
+			return nil, &Error{Pos: t.pos, Term: t.text, Message: err}
+		}
+	}
+	query.Text = search.Parse(strings.Join(text, " "))
+	return query, nil
+}
+
+// token is a whitespace-separated part of a query and the character offset
+// where it starts
+type token struct {
+	text string
+	pos  int
+}
+
+func tokens(q string) []token {
+	runes := []rune(q)
+	var tokens []token
+	start := -1
+	for i, r := range runes {
+		switch {
+		case unicode.IsSpace(r) && start >= 0:
+			tokens = append(tokens, token{text: string(runes[start:i]), pos: start})
+			start = -1
+		case !unicode.IsSpace(r) && start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{text: string(runes[start:]), pos: start})
+	}
+	return tokens
+}
+
+// splitTerm splits a term into its field, operator and value. It returns
+// false for words that aren't terms.
+func splitTerm(t string) (field, op, value string, ok bool) {
+	i := strings.IndexAny(t, ":=<>")
+	if i <= 0 {
+		return "", "", "", false
+	}
+	field = t[:i]
+	for _, r := range field {
+		if !unicode.IsLetter(r) && r != '_' {
+			return "", "", "", false
+		}
+	}
+	rest := t[i:]
+	switch {
+	case strings.HasPrefix(rest, "<="), strings.HasPrefix(rest, ">="):
+		op, value = rest[:2], rest[2:]
+	case rest[0] == ':' || rest[0] == '=':
+		op, value = opEqual, rest[1:]
+	default:
+		op, value = rest[:1], rest[1:]
+	}
+	return strings.ToLower(field), op, value, true
+}
+
+// add adds a term to the query, returning what's wrong with it if anything
+func (q *Query) add(field, op, value string) string {
+	if value == "" {
+		return "is missing a value"
+	}
+	value = strings.ToLower(value)
+	switch field {
+	case "completed", "pinned", "blocked":
+		if op != opEqual {
+			return fmt.Sprintf("%s can only be compared with \":\"", field)
+		}
+		if value != "true" && value != "false" {
+			return field + ` must be "true" or "false"`
+		}
+		want := value == "true"
+		switch field {
+		case "completed":
+			q.Completed = &want
+		case "pinned":
+			q.Pinned = &want
+		default:
+			q.Blocked = &want
+		}
+	case "priority":
+		values := strings.Split(value, ",")
+		if op != opEqual && len(values) > 1 {
+			return "only \":\" takes a list of priorities"
+		}
+		for _, v := range values {
+			if v != "none" && !models.Priority(v).Valid() {
+				return `priority must be "none", "low", "medium" or "high"`
+			}
+		}
+		q.priorities = append(q.priorities, comparison{op: op, values: values})
+	case "color":
+		if op != opEqual {
+			return "color can only be compared with \":\""
+		}
+		for _, v := range strings.Split(value, ",") {
+			if !models.Color(v).Valid() {
+				return "color must be a hex code such as #1e90ff or one of " + strings.Join(models.Palette, ", ")
+			}
+			q.colors = append(q.colors, models.Color(v))
+		}
+	case "due":
+		if value == "none" || value == "any" {
+			if op != opEqual {
+				return fmt.Sprintf("due:%s can only be written with \":\"", value)
+			}
+			q.due = append(q.due, comparison{op: value})
+			break
+		}
+		// Timestamps need an upper case T and Z
+		value = strings.ToUpper(value)
+		if _, err := models.ParseDueDate(value, time.UTC); err != nil {
+			return `due must be a YYYY-MM-DD date, an RFC 3339 timestamp, "none" or "any"`
+		}
+		q.due = append(q.due, comparison{op: op, values: []string{value}})
+	case "tag":
+		if op != opEqual {
+			return "tag can only be compared with \":\""
+		}
+		var tags []string
+		for _, v := range strings.Split(value, ",") {
+			tag := models.NormalizeTag(v)
+			if len(models.ValidateTag("tag", tag)) > 0 {
+				return fmt.Sprintf("%q isn't a tag", v)
+			}
+			tags = append(tags, tag)
+		}
+		q.tags = append(q.tags, tags)
+	default:
+		return "unknown field; use one of " + strings.Join(Fields, ", ")
+	}
+	return ""
+}
+
+// UsesDue reports whether the query compares due dates, which are read in
+// the user's time zone
+func (q *Query) UsesDue() bool {
+	return len(q.due) > 0
+}
+
+// Empty reports whether the query has no terms and no free text, keeping
+// every todo
+func (q *Query) Empty() bool {
+	return len(q.StoredFields()) == 0
+}
+
+// StoredFields returns the stored todo fields the query reads, its free
+// text included
+func (q *Query) StoredFields() []string {
+	var fields []string
+	if q.Completed != nil || q.Blocked != nil {
+		fields = append(fields, "completed")
+	}
+	if q.Blocked != nil {
+		fields = append(fields, "blocked_by")
+	}
+	if q.Pinned != nil {
+		fields = append(fields, "pinned")
+	}
+	if len(q.colors) > 0 {
+		fields = append(fields, "color")
+	}
+	if len(q.tags) > 0 {
+		fields = append(fields, "tags")
+	}
+	if len(q.priorities) > 0 {
+		fields = append(fields, "priority")
+	}
+	if len(q.due) > 0 {
+		fields = append(fields, "due_date")
+	}
+	if !q.Text.Empty() {
+		fields = append(fields, "title", "description")
+	}
+	return fields
+}
+
+// Matcher returns a function reporting whether a todo meets the terms
+// other than the free text. Plain due dates are read in loc, and a date
+// stands for the whole day: due<2025-01-01 is before that day starts and
+// due:2025-01-01 any time during it.
+func (q *Query) Matcher(loc *time.Location) func(*models.Todo) bool {
+	type bound struct {
+		op       string
+		from, to time.Time
+	}
+	var due []bound
+	for _, c := range q.due {
+		b := bound{op: c.op}
+		if len(c.values) > 0 {
+			// Values were checked when parsing
+			b.from, _ = models.ParseDueDate(c.values[0], loc)
+			b.to = b.from.Add(time.Nanosecond)
+			if len(c.values[0]) == len(time.DateOnly) {
+				b.to = b.from.AddDate(0, 0, 1)
+			}
+		}
+		due = append(due, b)
+	}
+
+	return func(todo *models.Todo) bool {
+		if q.Completed != nil && todo.Completed != *q.Completed {
+			return false
+		}
+		if q.Pinned != nil && todo.Pinned != *q.Pinned {
+			return false
+		}
+		if q.Blocked != nil && todo.Blocked != *q.Blocked {
+			return false
+		}
+		if len(q.colors) > 0 && !slices.Contains(q.colors, todo.Color) {
+			return false
+		}
+		for _, tags := range q.tags {
+			if !slices.ContainsFunc(tags, todo.HasTag) {
+				return false
+			}
+		}
+		for _, c := range q.priorities {
+			if !comparePriority(todo.Priority, c) {
+				return false
+			}
+		}
+		for _, b := range due {
+			switch b.op {
+			case "none":
+				if todo.DueDate != nil {
+					return false
+				}
+			case "any":
+				if todo.DueDate == nil {
+					return false
+				}
+			default:
+				if todo.DueDate == nil || !compareDue(*todo.DueDate, b.op, b.from, b.to) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+}
+
+// comparePriority compares a todo's priority by rank, todos without one
+// ranking lowest
+func comparePriority(p models.Priority, c comparison) bool {
+	if c.op == opEqual {
+		for _, v := range c.values {
+			if v == "none" && p == "" || models.Priority(v) == p {
+				return true
+			}
+		}
+		return false
+	}
+	rank, other := p.Rank(), models.Priority(c.values[0]).Rank()
+	switch c.op {
+	case opLess:
+		return rank < other
+	case opLessEqual:
+		return rank <= other
+	case opGreater:
+		return rank > other
+	default:
+		return rank >= other
+	}
+}
+
+// compareDue compares a due date with the span [from, to) a term's value
+// stands for
+func compareDue(due time.Time, op string, from, to time.Time) bool {
+	switch op {
+	case opEqual:
+		return !due.Before(from) && due.Before(to)
+	case opLess:
+		return due.Before(from)
+	case opLessEqual:
+		return due.Before(to)
+	case opGreater:
+		return !due.Before(to)
+	default:
+		return !due.Before(from)
+	}
+}